@@ -10,7 +10,9 @@ import (
 	"github.com/kaihedrick/go-loyalty-benefits/internal/loyalty"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/migrate"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,6 +30,9 @@ func main() {
 		logger.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Export traces when enabled; flushed once the service has stopped
+	tracer := tracing.Setup(cfg.OTel, "loyalty-svc", logger)
+
 	// Debug: Print loaded configuration
 	logger.Infof("=== LOYALTY SERVICE CONFIG DEBUG ===")
 	logger.Infof("App Name: '%s'", cfg.App.Name)
@@ -46,33 +51,77 @@ func main() {
 
 	// Create HTTP server
 	serverConfig := &http.ServerConfig{
-		Addr:            cfg.App.HTTPAddr,
-		ReadTimeout:     30 * time.Second,
-		WriteTimeout:    30 * time.Second,
-		IdleTimeout:     60 * time.Second,
-		ShutdownTimeout: cfg.App.ShutdownTimeout,
+		Addr:             cfg.App.HTTPAddr,
+		ReadTimeout:      30 * time.Second,
+		WriteTimeout:     30 * time.Second,
+		IdleTimeout:      60 * time.Second,
+		ShutdownTimeout:  cfg.App.ShutdownTimeout,
+		AllowedOrigins:   cfg.Security.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.Security.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.Security.CORS.AllowedHeaders,
+		AllowCredentials: cfg.Security.CORS.AllowCredentials,
+		PreflightMaxAge:  cfg.Security.CORS.MaxAge,
+		MaxBodyBytes:     cfg.App.MaxBodyBytes,
+		Metrics:          cfg.Metrics.Enabled,
 	}
 
 	server := http.NewServer(serverConfig, logger)
 
 	// Initialize database connection
 	dbConfig := &database.PostgresConfig{
-		Host:     cfg.Database.Postgres.Host,
-		Port:     cfg.Database.Postgres.Port,
-		Database: cfg.Database.Postgres.Database,
-		Username: cfg.Database.Postgres.Username,
-		Password: cfg.Database.Postgres.Password,
-		SSLMode:  cfg.Database.Postgres.SSLMode,
-		MaxConns: cfg.Database.Postgres.MaxConns,
+		Host:              cfg.Database.Postgres.Host,
+		Port:              cfg.Database.Postgres.Port,
+		Database:          cfg.Database.Postgres.Database,
+		Username:          cfg.Database.Postgres.Username,
+		Password:          cfg.Database.Postgres.Password,
+		SSLMode:           cfg.Database.Postgres.SSLMode,
+		MaxConns:          cfg.Database.Postgres.MaxConns,
+		ConnectTimeout:    cfg.Database.Postgres.ConnectTimeout,
+		ApplicationName:   cfg.Database.Postgres.ApplicationName,
+		MaxConnLifetime:   cfg.Database.Postgres.MaxConnLifetime,
+		MaxConnIdleTime:   cfg.Database.Postgres.MaxConnIdleTime,
+		HealthCheckPeriod: cfg.Database.Postgres.HealthCheckPeriod,
+		StatsInterval:     cfg.Database.Postgres.StatsInterval,
+		ConnectAttempts:   cfg.Database.Postgres.ConnectRetry.MaxAttempts,
+		ConnectBaseDelay:  cfg.Database.Postgres.ConnectRetry.BaseDelay,
+		ConnectMaxDelay:   cfg.Database.Postgres.ConnectRetry.MaxDelay,
 	}
 
-	db, err := database.NewPostgresDB(dbConfig, logger)
+	// A required database is waited for while it starts up, until a signal
+	// stops the wait; otherwise the pool connects on first use
+	required := cfg.Dependencies.IsRequired(config.DependencyPostgres)
+	var db *database.PostgresDB
+	if required {
+		startCtx, stopStartup := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		db, err = database.NewPostgresDB(startCtx, dbConfig, logger)
+		stopStartup()
+	} else {
+		db, err = database.OpenPostgresDB(dbConfig, logger)
+	}
 	if err != nil {
 		logger.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	logger.Infof("Connected to PostgreSQL database %s on %s:%d", cfg.Database.Postgres.Database, cfg.Database.Postgres.Host, cfg.Database.Postgres.Port)
+	// Fail fast only if Postgres is required; otherwise start degraded and
+	// let the pool reconnect once it is reachable
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	dbErr := server.Readiness().Watch(watchCtx, config.DependencyPostgres, required, cfg.Dependencies.CheckInterval, db.Ping)
+	if dbErr != nil {
+		if required {
+			logger.Fatalf("Failed to connect to database: %v", dbErr)
+		}
+		logger.Warnf("Starting without database, retrying in the background: %v", dbErr)
+	}
+
+	// Bring the schema up to date, or in verify mode refuse to start on an
+	// unmigrated one; without a database they run on the next start
+	if dbErr == nil {
+		if err := migrate.Run(context.Background(), db, "loyalty", loyalty.Migrations, migrate.Mode(cfg.Database.Migrations), logger); err != nil {
+			logger.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
 
 	// Initialize loyalty service
 	loyaltyService := loyalty.NewService(cfg, logger)
@@ -83,6 +132,9 @@ func main() {
 	// Add routes
 	server.AddRoutes(loyaltyService.Routes)
 
+	// Expire abandoned point holds and unspent points in the background
+	loyaltyService.Start()
+
 	// Start server
 	go func() {
 		logger.Infof("Starting HTTP server on %s", cfg.App.HTTPAddr)
@@ -102,9 +154,18 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.App.ShutdownTimeout)
 	defer cancel()
 
+	// Stop the sweepers; a sweep cancelled mid-transaction rolls back
+	if err := loyaltyService.Shutdown(ctx); err != nil {
+		logger.Errorf("Loyalty service shutdown error: %v", err)
+	}
+
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Errorf("Error during server shutdown: %v", err)
 	}
 
+	if err := tracer.Shutdown(ctx); err != nil {
+		logger.Errorf("Failed to flush traces: %v", err)
+	}
+
 	logger.Info("Loyalty Service stopped")
 }
@@ -9,7 +9,10 @@ import (
 
 	"github.com/kaihedrick/go-loyalty-benefits/internal/notify"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/migrate"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,6 +28,9 @@ func main() {
 		logger.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Export traces when enabled; flushed once the service has stopped
+	tracer := tracing.Setup(cfg.OTel, "notify-svc", logger)
+
 	// Set log level from config
 	if level, err := logrus.ParseLevel(cfg.App.LogLevel); err == nil {
 		logger.SetLevel(level)
@@ -34,17 +40,94 @@ func main() {
 
 	// Create HTTP server
 	serverConfig := &http.ServerConfig{
-		Addr:            cfg.App.HTTPAddr,
-		ReadTimeout:     30 * time.Second,
-		WriteTimeout:    30 * time.Second,
-		IdleTimeout:     60 * time.Second,
-		ShutdownTimeout: cfg.App.ShutdownTimeout,
+		Addr:             cfg.App.HTTPAddr,
+		ReadTimeout:      30 * time.Second,
+		WriteTimeout:     30 * time.Second,
+		IdleTimeout:      60 * time.Second,
+		ShutdownTimeout:  cfg.App.ShutdownTimeout,
+		AllowedOrigins:   cfg.Security.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.Security.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.Security.CORS.AllowedHeaders,
+		AllowCredentials: cfg.Security.CORS.AllowCredentials,
+		PreflightMaxAge:  cfg.Security.CORS.MaxAge,
+		MaxBodyBytes:     cfg.App.MaxBodyBytes,
+		Metrics:          cfg.Metrics.Enabled,
 	}
 
 	server := http.NewServer(serverConfig, logger)
 
+	// Initialize database connection
+	dbConfig := &database.PostgresConfig{
+		Host:              cfg.Database.Postgres.Host,
+		Port:              cfg.Database.Postgres.Port,
+		Database:          cfg.Database.Postgres.Database,
+		Username:          cfg.Database.Postgres.Username,
+		Password:          cfg.Database.Postgres.Password,
+		SSLMode:           cfg.Database.Postgres.SSLMode,
+		MaxConns:          cfg.Database.Postgres.MaxConns,
+		ConnectTimeout:    cfg.Database.Postgres.ConnectTimeout,
+		ApplicationName:   cfg.Database.Postgres.ApplicationName,
+		MaxConnLifetime:   cfg.Database.Postgres.MaxConnLifetime,
+		MaxConnIdleTime:   cfg.Database.Postgres.MaxConnIdleTime,
+		HealthCheckPeriod: cfg.Database.Postgres.HealthCheckPeriod,
+		StatsInterval:     cfg.Database.Postgres.StatsInterval,
+		ConnectAttempts:   cfg.Database.Postgres.ConnectRetry.MaxAttempts,
+		ConnectBaseDelay:  cfg.Database.Postgres.ConnectRetry.BaseDelay,
+		ConnectMaxDelay:   cfg.Database.Postgres.ConnectRetry.MaxDelay,
+	}
+
+	// A required database is waited for while it starts up, until a signal
+	// stops the wait; otherwise the pool connects on first use
+	required := cfg.Dependencies.IsRequired(config.DependencyPostgres)
+	var db *database.PostgresDB
+	if required {
+		startCtx, stopStartup := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		db, err = database.NewPostgresDB(startCtx, dbConfig, logger)
+		stopStartup()
+	} else {
+		db, err = database.OpenPostgresDB(dbConfig, logger)
+	}
+	if err != nil {
+		logger.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Fail fast only if Postgres is required; otherwise start degraded and
+	// let the pool reconnect once it is reachable
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	dbErr := server.Readiness().Watch(watchCtx, config.DependencyPostgres, required, cfg.Dependencies.CheckInterval, db.Ping)
+	if dbErr != nil {
+		if required {
+			logger.Fatalf("Failed to connect to database: %v", dbErr)
+		}
+		logger.Warnf("Starting without database, retrying in the background: %v", dbErr)
+	}
+
+	// Bring the schema up to date, or in verify mode refuse to start on an
+	// unmigrated one; without a database they run on the next start
+	if dbErr == nil {
+		if err := migrate.Run(context.Background(), db, "notify", notify.Migrations, migrate.Mode(cfg.Database.Migrations), logger); err != nil {
+			logger.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+
 	// Initialize notification service
 	notifyService := notify.NewService(cfg, logger)
+	notifyService.SetDatabase(db)
+
+	// Kafka is optional by default: notifications are still served over HTTP
+	// while the consumer reconnects in the background
+	required = cfg.Dependencies.IsRequired(config.DependencyKafka)
+	if err := server.Readiness().Watch(watchCtx, config.DependencyKafka, required, cfg.Dependencies.CheckInterval, notifyService.PingEventBus); err != nil {
+		if required {
+			logger.Fatalf("Failed to connect to event bus: %v", err)
+		}
+		logger.Warnf("Starting without event bus, retrying in the background: %v", err)
+	}
+
+	// Start consuming Kafka events
+	notifyService.Start()
 
 	// Add routes
 	server.AddRoutes(notifyService.Routes)
@@ -67,9 +150,19 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.App.ShutdownTimeout)
 	defer cancel()
 
+	// Stop the consumers and senders; events being handled are left
+	// uncommitted and redelivered after a restart
+	if err := notifyService.Shutdown(ctx); err != nil {
+		logger.Errorf("Notification service shutdown error: %v", err)
+	}
+
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Errorf("Server shutdown error: %v", err)
 	}
 
+	if err := tracer.Shutdown(ctx); err != nil {
+		logger.Errorf("Failed to flush traces: %v", err)
+	}
+
 	logger.Info("Notification Service stopped")
 }
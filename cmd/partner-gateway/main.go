@@ -10,6 +10,7 @@ import (
 	"github.com/kaihedrick/go-loyalty-benefits/internal/partner"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,6 +26,9 @@ func main() {
 		logger.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Export traces when enabled; flushed once the service has stopped
+	tracer := tracing.Setup(cfg.OTel, "partner-gateway", logger)
+
 	// Set log level from config
 	if level, err := logrus.ParseLevel(cfg.App.LogLevel); err == nil {
 		logger.SetLevel(level)
@@ -39,6 +43,7 @@ func main() {
 		WriteTimeout:    30 * time.Second,
 		IdleTimeout:     60 * time.Second,
 		ShutdownTimeout: cfg.App.ShutdownTimeout,
+		Metrics:         cfg.Metrics.Enabled,
 	}
 
 	server := http.NewServer(serverConfig, logger)
@@ -71,5 +76,9 @@ func main() {
 		logger.Errorf("Server shutdown error: %v", err)
 	}
 
+	if err := tracer.Shutdown(ctx); err != nil {
+		logger.Errorf("Failed to flush traces: %v", err)
+	}
+
 	logger.Info("Partner Gateway Service stopped")
 }
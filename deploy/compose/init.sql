@@ -8,14 +8,41 @@ CREATE EXTENSION IF NOT EXISTS "pgcrypto";
 -- Users table
 CREATE TABLE IF NOT EXISTS users (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    email VARCHAR(255) UNIQUE NOT NULL,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    email VARCHAR(255) NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
     role VARCHAR(50) NOT NULL DEFAULT 'user',
     first_name VARCHAR(100),
     last_name VARCHAR(100),
     phone VARCHAR(20),
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, email)
+);
+
+-- Refresh tokens table; tokens are stored as SHA-256 hashes and rotated on use
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id UUID NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    revoked_at TIMESTAMPTZ,
+    replaced_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Password reset tokens; single use, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
 -- User balances table
@@ -46,6 +73,7 @@ CREATE TABLE IF NOT EXISTS transactions (
 -- Benefits table
 CREATE TABLE IF NOT EXISTS benefits (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
     name VARCHAR(255) NOT NULL,
     description TEXT,
     points INTEGER NOT NULL,
@@ -56,25 +84,65 @@ CREATE TABLE IF NOT EXISTS benefits (
     ends_at TIMESTAMPTZ,
     image_url VARCHAR(500),
     terms_conditions TEXT,
+    created_by VARCHAR(255),
+    updated_by VARCHAR(255),
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- Soft-deleted benefits keep their row so redemptions still reference them
+    deleted_at TIMESTAMPTZ
+);
+
+-- Benefit categories and partners that benefits may reference
+CREATE TABLE IF NOT EXISTS benefit_categories (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    name VARCHAR(100) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS benefit_partners (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    name VARCHAR(100) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, name)
 );
 
 -- Redemptions table
 CREATE TABLE IF NOT EXISTS redemptions (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     benefit_id UUID NOT NULL REFERENCES benefits(id) ON DELETE CASCADE,
     points INTEGER NOT NULL,
     status VARCHAR(20) NOT NULL DEFAULT 'requested',
-    idempotency_key VARCHAR(255) UNIQUE NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    benefit_type VARCHAR(32),
+    details JSONB,
     partner_ref VARCHAR(255),
+    failure_reason VARCHAR(32),
+    hold_id VARCHAR(255),
+    partner_attempts INTEGER NOT NULL DEFAULT 0,
     error_message TEXT,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     completed_at TIMESTAMPTZ
 );
 
+-- Idempotency keys of redemptions, per user, until they expire
+CREATE TABLE IF NOT EXISTS redemption_idempotency_keys (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id UUID NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    redemption_id UUID NOT NULL REFERENCES redemptions(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tenant_id, user_id, idempotency_key)
+);
+
 -- Outbox table for event sourcing
 CREATE TABLE IF NOT EXISTS outbox (
     id BIGSERIAL PRIMARY KEY,
@@ -83,27 +151,75 @@ CREATE TABLE IF NOT EXISTS outbox (
     event_type VARCHAR(100) NOT NULL,
     payload JSONB NOT NULL,
     topic VARCHAR(100) NOT NULL,
+    message_key VARCHAR(255),
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    dispatched_at TIMESTAMPTZ,
+    -- started_at is set when a relay claims the row; rows with started_at but
+    -- no sent_at long after it are stuck
+    started_at TIMESTAMPTZ,
+    sent_at TIMESTAMPTZ,
     retry_count INTEGER NOT NULL DEFAULT 0,
-    max_retries INTEGER NOT NULL DEFAULT 3
+    max_retries INTEGER NOT NULL DEFAULT 3,
+    -- W3C trace context of the saga that queued the message
+    traceparent VARCHAR(55),
+    -- ID of the request whose saga queued the message
+    request_id VARCHAR(255)
 );
 
 -- Notifications table
 CREATE TABLE IF NOT EXISTS notifications (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     type VARCHAR(20) NOT NULL, -- email, sms, push
     subject VARCHAR(255),
     message TEXT NOT NULL,
     status VARCHAR(20) NOT NULL DEFAULT 'pending',
     channel VARCHAR(20) NOT NULL, -- email, sms, push
+    segments INTEGER,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
     metadata JSONB,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     sent_at TIMESTAMPTZ,
     error TEXT
 );
 
+-- Webhooks registered for redemption events, their queued deliveries, and
+-- each delivery attempt
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events TEXT[] NOT NULL, -- redemption.completed, redemption.failed
+    created_by VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_type VARCHAR(64) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, delivered, failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ,
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id UUID NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    duration_ms INTEGER NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
 -- Partner configurations table
 CREATE TABLE IF NOT EXISTS partner_configs (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
@@ -134,27 +250,43 @@ CREATE TABLE IF NOT EXISTS activity_logs (
 );
 
 -- Create indexes for better performance
+-- Emails are unique per tenant regardless of case
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_email_lower ON users(tenant_id, LOWER(email));
+
 CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions(user_id);
 CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);
 CREATE INDEX IF NOT EXISTS idx_transactions_mcc ON transactions(mcc);
 
-CREATE INDEX IF NOT EXISTS idx_redemptions_user_id ON redemptions(user_id);
+CREATE INDEX IF NOT EXISTS idx_redemptions_user_id ON redemptions(tenant_id, user_id);
 CREATE INDEX IF NOT EXISTS idx_redemptions_status ON redemptions(status);
 CREATE INDEX IF NOT EXISTS idx_redemptions_created_at ON redemptions(created_at);
 CREATE INDEX IF NOT EXISTS idx_redemptions_idempotency_key ON redemptions(idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_redemption_idempotency_keys_expires_at ON redemption_idempotency_keys(expires_at);
 
-CREATE INDEX IF NOT EXISTS idx_benefits_active ON benefits(active);
+CREATE INDEX IF NOT EXISTS idx_benefits_tenant_active ON benefits(tenant_id, active);
 CREATE INDEX IF NOT EXISTS idx_benefits_category ON benefits(category);
 CREATE INDEX IF NOT EXISTS idx_benefits_partner ON benefits(partner);
+CREATE INDEX IF NOT EXISTS idx_benefits_tenant_created ON benefits(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_benefits_tenant_live_created ON benefits(tenant_id, created_at DESC) WHERE deleted_at IS NULL;
 
 CREATE INDEX IF NOT EXISTS idx_outbox_topic ON outbox(topic);
-CREATE INDEX IF NOT EXISTS idx_outbox_dispatched_at ON outbox(dispatched_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_unsent ON outbox(id) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_started_at ON outbox(started_at) WHERE sent_at IS NULL;
 CREATE INDEX IF NOT EXISTS idx_outbox_retry_count ON outbox(retry_count);
 
-CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(tenant_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(tenant_id, user_id, created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_notifications_status ON notifications(status);
 CREATE INDEX IF NOT EXISTS idx_notifications_created_at ON notifications(created_at);
 
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant_id ON webhooks(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts(delivery_id, attempt);
+
 CREATE INDEX IF NOT EXISTS idx_activity_logs_user_id ON activity_logs(user_id);
 CREATE INDEX IF NOT EXISTS idx_activity_logs_activity_type ON activity_logs(activity_type);
 CREATE INDEX IF NOT EXISTS idx_activity_logs_created_at ON activity_logs(created_at);
@@ -196,6 +328,15 @@ INSERT INTO balances (user_id, available_points, lifetime_points, tier) VALUES
     ('550e8400-e29b-41d4-a716-446655440002', 1500, 3000, 'bronze')
 ON CONFLICT (user_id) DO NOTHING;
 
+INSERT INTO benefit_categories (name) VALUES
+    ('Travel'), ('Retail'), ('Dining'), ('Entertainment'),
+    ('Technology'), ('Health & Wellness'), ('Charity'), ('Cash Back')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO benefit_partners (name) VALUES
+    ('GIFTCO'), ('TRAVELCO'), ('RETAILCO'), ('DININGCO'), ('ENTERTAINMENTCO')
+ON CONFLICT DO NOTHING;
+
 INSERT INTO benefits (id, name, description, points, partner, category, active) VALUES
     ('660e8400-e29b-41d4-a716-446655440000', '$25 Gift Card', 'Redeemable at major retailers', 2000, 'GIFTCO', 'Retail', true),
     ('660e8400-e29b-41d4-a716-446655440001', 'Free Movie Ticket', 'Valid at participating theaters', 1500, 'ENTERTAINMENTCO', 'Entertainment', true),
@@ -263,11 +404,16 @@ END;
 $$ LANGUAGE plpgsql;
 
 COMMENT ON TABLE users IS 'User accounts for the loyalty system';
+COMMENT ON TABLE refresh_tokens IS 'Hashed refresh tokens, rotated on each use';
 COMMENT ON TABLE balances IS 'User loyalty point balances';
 COMMENT ON TABLE transactions IS 'Loyalty point earning transactions';
 COMMENT ON TABLE benefits IS 'Available benefits and rewards';
 COMMENT ON TABLE redemptions IS 'Benefit redemption requests';
+COMMENT ON TABLE redemption_idempotency_keys IS 'Redemption idempotency keys until they expire';
 COMMENT ON TABLE outbox IS 'Event outbox for reliable message delivery';
 COMMENT ON TABLE notifications IS 'User notifications (email, SMS, push)';
+COMMENT ON TABLE webhooks IS 'Admin-registered webhooks for redemption events';
+COMMENT ON TABLE webhook_deliveries IS 'Redemption events queued for webhooks';
+COMMENT ON TABLE webhook_delivery_attempts IS 'Log of webhook delivery attempts';
 COMMENT ON TABLE partner_configs IS 'External partner service configurations';
 COMMENT ON TABLE activity_logs IS 'User activity audit trail';
@@ -4,21 +4,35 @@
 -- Create loyalty_users table
 CREATE TABLE IF NOT EXISTS loyalty_users (
     id VARCHAR(36) PRIMARY KEY,
-    email VARCHAR(255) UNIQUE NOT NULL,
+    tenant_id VARCHAR(64) DEFAULT 'default' NOT NULL,
+    email VARCHAR(255) NOT NULL,
     points INTEGER DEFAULT 0 NOT NULL,
+    lifetime_points INTEGER DEFAULT 0 NOT NULL,
     tier VARCHAR(50) DEFAULT 'Bronze' NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (tenant_id, email)
 );
 
 -- Create loyalty_transactions table
 CREATE TABLE IF NOT EXISTS loyalty_transactions (
     id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) DEFAULT 'default' NOT NULL,
     user_id VARCHAR(36) NOT NULL,
-    type VARCHAR(20) NOT NULL CHECK (type IN ('earn', 'spend')),
-    amount INTEGER NOT NULL CHECK (amount > 0),
+    type VARCHAR(20) NOT NULL CONSTRAINT loyalty_transactions_type_check
+        CHECK (type IN ('earn', 'spend', 'reversal', 'expire', 'adjustment')),
+    -- Adjustments are signed: credits are positive and debits negative
+    amount INTEGER NOT NULL CONSTRAINT loyalty_transactions_amount_check
+        CHECK (amount > 0 OR (type = 'adjustment' AND amount <> 0)),
     description TEXT NOT NULL,
+    idempotency_key VARCHAR(255),
+    redemption_id VARCHAR(36),
+    reverses_id VARCHAR(36) REFERENCES loyalty_transactions(id),
+    settled_at TIMESTAMP WITH TIME ZONE,
+    balance_after INTEGER,
+    created_by VARCHAR(255),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
     FOREIGN KEY (user_id) REFERENCES loyalty_users(id) ON DELETE CASCADE
 );
 
@@ -30,18 +44,71 @@ CREATE TABLE IF NOT EXISTS loyalty_rewards (
     points_cost INTEGER NOT NULL CHECK (points_cost > 0),
     category VARCHAR(100) NOT NULL,
     is_active BOOLEAN DEFAULT true NOT NULL,
+    created_by VARCHAR(255),
+    updated_by VARCHAR(255),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
 );
 
+-- Create loyalty_point_holds table (points authorized but not yet spent)
+CREATE TABLE IF NOT EXISTS loyalty_point_holds (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) DEFAULT 'default' NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    reference VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'held' CHECK (status IN ('held', 'captured', 'released', 'expired')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES loyalty_users(id) ON DELETE CASCADE
+);
+
+-- Create audit_log table (who made manual point adjustments, and why)
+CREATE TABLE IF NOT EXISTS audit_log (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) DEFAULT 'default' NOT NULL,
+    actor_id VARCHAR(255) NOT NULL,
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id VARCHAR(36) NOT NULL,
+    reason TEXT NOT NULL,
+    details JSONB DEFAULT '{}' NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- Create reward_redemptions table (rewards redeemed directly, each paid for by a spend transaction)
+CREATE TABLE IF NOT EXISTS reward_redemptions (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) DEFAULT 'default' NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    reward_id VARCHAR(36) NOT NULL,
+    points INTEGER NOT NULL CHECK (points > 0),
+    transaction_id VARCHAR(36) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES loyalty_users(id) ON DELETE CASCADE,
+    FOREIGN KEY (reward_id) REFERENCES loyalty_rewards(id),
+    FOREIGN KEY (transaction_id) REFERENCES loyalty_transactions(id)
+);
+
 -- Create indexes for better performance
-CREATE INDEX IF NOT EXISTS idx_loyalty_users_email ON loyalty_users(email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_point_holds_reference ON loyalty_point_holds(tenant_id, reference);
+CREATE INDEX IF NOT EXISTS idx_loyalty_point_holds_user_status ON loyalty_point_holds(user_id, status);
+CREATE INDEX IF NOT EXISTS idx_loyalty_users_tenant ON loyalty_users(tenant_id);
 CREATE INDEX IF NOT EXISTS idx_loyalty_users_tier ON loyalty_users(tier);
-CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_user_id ON loyalty_transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_user_id ON loyalty_transactions(tenant_id, user_id);
 CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_created_at ON loyalty_transactions(created_at);
+CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_user_history ON loyalty_transactions(user_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_transactions_idempotency ON loyalty_transactions(user_id, type, idempotency_key) WHERE idempotency_key IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_transactions_reverses ON loyalty_transactions(reverses_id) WHERE reverses_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_expires_at ON loyalty_transactions(expires_at) WHERE type = 'earn' AND expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_redemption ON loyalty_transactions(redemption_id) WHERE redemption_id IS NOT NULL;
 CREATE INDEX IF NOT EXISTS idx_loyalty_rewards_category ON loyalty_rewards(category);
 CREATE INDEX IF NOT EXISTS idx_loyalty_rewards_points_cost ON loyalty_rewards(points_cost);
 CREATE INDEX IF NOT EXISTS idx_loyalty_rewards_active ON loyalty_rewards(is_active);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(tenant_id, target_type, target_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_reward_redemptions_user ON reward_redemptions(tenant_id, user_id, created_at DESC);
 
 -- Insert sample rewards
 INSERT INTO loyalty_rewards (id, name, description, points_cost, category, is_active) VALUES
@@ -73,35 +140,12 @@ CREATE TRIGGER update_loyalty_rewards_updated_at
     BEFORE UPDATE ON loyalty_rewards 
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
--- Create function to calculate tier based on points
-CREATE OR REPLACE FUNCTION calculate_tier(points INTEGER)
-RETURNS VARCHAR(50) AS $$
-BEGIN
-    IF points >= 10000 THEN
-        RETURN 'Platinum';
-    ELSIF points >= 5000 THEN
-        RETURN 'Gold';
-    ELSIF points >= 1000 THEN
-        RETURN 'Silver';
-    ELSE
-        RETURN 'Bronze';
-    END IF;
-END;
-$$ LANGUAGE plpgsql;
-
--- Create function to update user tier automatically
-CREATE OR REPLACE FUNCTION update_user_tier()
-RETURNS TRIGGER AS $$
-BEGIN
-    NEW.tier = calculate_tier(NEW.points);
-    RETURN NEW;
-END;
-$$ LANGUAGE plpgsql;
+CREATE TRIGGER update_loyalty_point_holds_updated_at 
+    BEFORE UPDATE ON loyalty_point_holds 
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
--- Create trigger to automatically update tier when points change
-CREATE TRIGGER update_loyalty_users_tier 
-    BEFORE UPDATE ON loyalty_users 
-    FOR EACH ROW EXECUTE FUNCTION update_user_tier();
+-- Tiers are recalculated by the loyalty service from lifetime earned points,
+-- using the thresholds in its loyalty.tiers configuration
 
 -- Insert sample loyalty users (for testing)
 INSERT INTO loyalty_users (id, email, points, tier) VALUES
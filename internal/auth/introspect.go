@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/render"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// IntrospectionRequest names the token to introspect
+type IntrospectionRequest struct {
+	Token string `json:"token" validate:"required"`
+	// TokenTypeHint is accepted for RFC 7662 compatibility; only access
+	// tokens are introspected
+	TokenTypeHint string `json:"token_type_hint,omitempty"`
+}
+
+// IntrospectionResponse describes a token per RFC 7662. Only Active is set
+// for a token that is invalid, expired, or revoked.
+type IntrospectionResponse struct {
+	Active   bool   `json:"active"`
+	UserID   string `json:"user_id,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Role     string `json:"role,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+	Exp      int64  `json:"exp,omitempty"`
+	Iat      int64  `json:"iat,omitempty"`
+	Iss      string `json:"iss,omitempty"`
+	Jti      string `json:"jti,omitempty"`
+}
+
+// Introspect reports whether an access token is active and whom it
+// identifies, so services can check tokens without holding the signing
+// secret. It requires a service token. The token may be sent as JSON or, as
+// RFC 7662 specifies, as a form.
+func (s *Service) Introspect(w http.ResponseWriter, r *http.Request) {
+	var req IntrospectionRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" {
+		if err := r.ParseForm(); err != nil {
+			platformhttp.RequestError(w, r, err)
+			return
+		}
+		req.Token = r.PostForm.Get("token")
+		req.TokenTypeHint = r.PostForm.Get("token_type_hint")
+		if errs := platformhttp.Validate(&req); errs != nil {
+			platformhttp.ValidationError(w, r, errs)
+			return
+		}
+	} else if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	// Introspection answers must never be reused for another token check
+	w.Header().Set("Cache-Control", "no-store")
+
+	claims, err := s.jwtManager.ValidateToken(req.Token)
+	if err != nil {
+		s.logger.Debugf("Introspected token is inactive: %v", err)
+		render.JSON(w, r, IntrospectionResponse{Active: false})
+		return
+	}
+
+	// Fail closed, as RequireJWT does, when revocations cannot be checked
+	if denylist := s.jwtManager.Denylist(); denylist != nil {
+		revoked, err := denylist.IsRevoked(r.Context(), claims.ID)
+		if err != nil {
+			s.logger.Errorf("Failed to check token revocation: %v", err)
+			platformhttp.Error(w, r, http.StatusServiceUnavailable, platformhttp.ErrCodeUnavailable, "Token revocation check unavailable")
+			return
+		}
+		if revoked {
+			render.JSON(w, r, IntrospectionResponse{Active: false})
+			return
+		}
+	}
+
+	response := IntrospectionResponse{
+		Active:   true,
+		UserID:   claims.UserID,
+		Email:    claims.Email,
+		Role:     claims.Role,
+		TenantID: claims.TenantID,
+		// ValidateToken rejects tokens without an expiry
+		Exp: claims.ExpiresAt.Unix(),
+		Iat: claims.IssuedAt,
+		Iss: claims.Issuer,
+		Jti: claims.ID,
+	}
+	render.JSON(w, r, response)
+}
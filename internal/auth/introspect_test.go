@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// introspect posts body to /v1/auth/introspect with bearer as the caller's
+// token, in tenant-a, returning the response and the decoded fields of a 200 answer
+func introspect(t *testing.T, s *Service, bearer, contentType, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/introspect", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(auth.TenantHeader, "tenant-a")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var fields map[string]interface{}
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &fields); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec, fields
+}
+
+// introspectJSON introspects token with a JSON request
+func introspectJSON(t *testing.T, s *Service, bearer, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+
+	body, _ := json.Marshal(IntrospectionRequest{Token: token})
+	return introspect(t, s, bearer, "application/json", string(body))
+}
+
+func serviceToken(t *testing.T, s *Service) string {
+	t.Helper()
+
+	tok, err := s.jwtManager.GenerateServiceToken("partner-gateway")
+	if err != nil {
+		t.Fatalf("GenerateServiceToken: %v", err)
+	}
+	return tok
+}
+
+func TestIntrospectActiveToken(t *testing.T) {
+	s := newTestService(t)
+	gateway := serviceToken(t, s)
+	token, err := s.jwtManager.GenerateTenantToken("user-1", "member@example.com", "user", "tenant-a")
+	if err != nil {
+		t.Fatalf("GenerateTenantToken: %v", err)
+	}
+	claims, err := s.jwtManager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+
+	rec, fields := introspectJSON(t, s, gateway, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Fatalf("Cache-Control = %q, want no-store", cc)
+	}
+	want := map[string]interface{}{
+		"active":    true,
+		"user_id":   "user-1",
+		"email":     "member@example.com",
+		"role":      "user",
+		"tenant_id": "tenant-a",
+		"exp":       float64(claims.ExpiresAt.Unix()),
+		"iat":       float64(claims.IssuedAt),
+		"iss":       claims.Issuer,
+		"jti":       claims.ID,
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %v, want %v", key, fields[key], value)
+		}
+	}
+
+	// RFC 7662 clients send the token as a form
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}.Encode()
+	if _, fields := introspect(t, s, gateway, "application/x-www-form-urlencoded", form); fields["active"] != true || fields["user_id"] != "user-1" {
+		t.Fatalf("form introspection = %v, want the active token", fields)
+	}
+}
+
+func TestIntrospectInactiveTokens(t *testing.T) {
+	s := newTestService(t)
+	denylist := &memoryDenylist{}
+	s.jwtManager.SetDenylist(denylist)
+	gateway := serviceToken(t, s)
+
+	// A service sharing the secret but issuing tokens that are already expired
+	expiredIssuer := newTestService(t, func(cfg *config.Config) { cfg.Security.JWT.Expiration = -time.Minute })
+	expired, err := expiredIssuer.jwtManager.GenerateToken("user-1", "member@example.com", "user")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	otherSecret := newTestService(t, func(cfg *config.Config) { cfg.Security.JWT.Secret = strings.Repeat("x", 40) })
+	forged, err := otherSecret.jwtManager.GenerateToken("user-1", "member@example.com", "admin")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	revoked, err := s.jwtManager.GenerateTenantToken("user-1", "member@example.com", "user", "tenant-a")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if rec := logout(s, revoked); rec.Code != http.StatusNoContent {
+		t.Fatalf("logout: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	for name, token := range map[string]string{
+		"expired":   expired,
+		"malformed": "not.a.token",
+		"forged":    forged,
+		"revoked":   revoked,
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec, fields := introspectJSON(t, s, gateway, token)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			// Nothing about an inactive token is disclosed
+			if len(fields) != 1 || fields["active"] != false {
+				t.Fatalf("response = %v, want only active false", fields)
+			}
+		})
+	}
+}
+
+func TestIntrospectRequiresServiceToken(t *testing.T) {
+	s := newTestService(t)
+	user, err := s.jwtManager.GenerateTenantToken("user-1", "member@example.com", "user", "tenant-a")
+	if err != nil {
+		t.Fatalf("GenerateTenantToken: %v", err)
+	}
+	admin, err := s.jwtManager.GenerateTenantToken("admin-1", "admin@example.com", auth.RoleAdmin, "tenant-a")
+	if err != nil {
+		t.Fatalf("GenerateTenantToken: %v", err)
+	}
+
+	tests := []struct {
+		name, bearer, token string
+		want                int
+	}{
+		{"anonymous", "", user, http.StatusUnauthorized},
+		{"user", user, user, http.StatusForbidden},
+		{"admin", admin, user, http.StatusForbidden},
+		{"no token to introspect", serviceToken(t, s), "", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec, _ := introspectJSON(t, s, tt.bearer, tt.token); rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
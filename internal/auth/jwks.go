@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+// JWKS publishes the public keys that validate access tokens, so services
+// and clients can verify RS256 tokens themselves, selecting the key by the
+// token's kid. Retired keys stay published until the tokens they signed have
+// expired. With HS256 the set is empty.
+func (s *Service) JWKS(w http.ResponseWriter, r *http.Request) {
+	// Clients refresh on the same interval they cache keys for
+	if maxAge := s.config.Security.JWT.JWKSCacheTTL; maxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	}
+	render.JSON(w, r, s.jwtManager.JWKS())
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// rsaKeyPEMs returns a new RSA private key and its public key, PEM encoded
+func rsaKeyPEMs(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+}
+
+// fetchJWKS gets the auth service's published key set
+func fetchJWKS(t *testing.T, s *Service) (*httptest.ResponseRecorder, auth.JWKSet) {
+	t.Helper()
+
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var set auth.JWKSet
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return rec, set
+}
+
+func TestJWKSPublishesSigningKeys(t *testing.T) {
+	privateKey, publicKey := rsaKeyPEMs(t)
+	_, retiredKey := rsaKeyPEMs(t)
+	s := newTestService(t, func(cfg *config.Config) {
+		jwtConfig := &cfg.Security.JWT
+		jwtConfig.Algorithm = config.JWTAlgorithmRS256
+		jwtConfig.PrivateKey = privateKey
+		jwtConfig.PublicKey = publicKey
+		jwtConfig.PreviousPublicKeys = retiredKey
+		jwtConfig.JWKSCacheTTL = 10 * time.Minute
+	})
+
+	rec, set := fetchJWKS(t, s)
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=600" {
+		t.Fatalf("Cache-Control = %q, want public, max-age=600", cc)
+	}
+	if len(set.Keys) != 2 {
+		t.Fatalf("published %d keys, want the signing key and the retired one", len(set.Keys))
+	}
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || key.Use != "sig" || key.Alg != "RS256" || key.Kid == "" || key.N == "" || key.E == "" {
+			t.Fatalf("key = %+v, want an RS256 signing key with a kid", key)
+		}
+	}
+
+	// A token's kid names a published key that verifies it
+	token, err := s.jwtManager.GenerateToken("user-1", "member@example.com", "user")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		for _, key := range set.Keys {
+			if key.Kid == token.Header["kid"] {
+				return key.RSAPublicKey()
+			}
+		}
+		t.Fatalf("kid %v is not published", token.Header["kid"])
+		return nil, nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil || !parsed.Valid {
+		t.Fatalf("token does not verify with its published key: %v", err)
+	}
+	if parsed.Header["kid"] != set.Keys[0].Kid {
+		t.Fatalf("token kid = %v, want the first published key %s", parsed.Header["kid"], set.Keys[0].Kid)
+	}
+}
+
+func TestJWKSEmptyForHS256(t *testing.T) {
+	s := newTestService(t)
+
+	rec, set := fetchJWKS(t, s)
+	if len(set.Keys) != 0 || rec.Body.String() != "{\"keys\":[]}\n" {
+		t.Fatalf("body = %s, want an empty key set", rec.Body)
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// memoryDenylist is an in-memory Denylist recording when each token expires
+type memoryDenylist struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+func (d *memoryDenylist) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.revoked == nil {
+		d.revoked = make(map[string]time.Time)
+	}
+	d.revoked[tokenID] = expiresAt
+	return nil
+}
+
+func (d *memoryDenylist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.revoked[tokenID]
+	return ok, nil
+}
+
+// logout posts to /v1/auth/logout with token as the bearer token
+func logout(s *Service, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLogoutRevokesOnlyThatToken(t *testing.T) {
+	s := newTestService(t)
+	denylist := &memoryDenylist{}
+	s.jwtManager.SetDenylist(denylist)
+
+	loggedOut, err := s.jwtManager.GenerateTenantToken("user-1", "member@example.com", "user", "tenant-a")
+	if err != nil {
+		t.Fatalf("GenerateTenantToken: %v", err)
+	}
+	fresh, err := s.jwtManager.GenerateTenantToken("user-1", "member@example.com", "user", "tenant-a")
+	if err != nil {
+		t.Fatalf("GenerateTenantToken: %v", err)
+	}
+
+	if rec := logout(s, loggedOut); rec.Code != http.StatusNoContent {
+		t.Fatalf("logout: status = %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+
+	// The denylist entry lives only as long as the token would have
+	claims, err := s.jwtManager.ValidateToken(loggedOut)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if expiresAt := denylist.revoked[claims.ID]; !expiresAt.Equal(claims.ExpiresAt.Time) {
+		t.Fatalf("denylisted until %s, want the token's expiry %s", expiresAt, claims.ExpiresAt.Time)
+	}
+
+	if rec := logout(s, loggedOut); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("logged-out token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := logout(s, fresh); rec.Code != http.StatusNoContent {
+		t.Fatalf("fresh token: status = %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body)
+	}
+}
+
+func TestLogoutWithoutDenylist(t *testing.T) {
+	s := newTestService(t)
+	token, err := s.jwtManager.GenerateTenantToken("user-1", "member@example.com", "user", "tenant-a")
+	if err != nil {
+		t.Fatalf("GenerateTenantToken: %v", err)
+	}
+
+	if rec := logout(s, token); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotImplemented)
+	}
+}
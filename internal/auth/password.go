@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxPasswordBytes is the longest input bcrypt will accept
+const bcryptMaxPasswordBytes = 72
+
+// errPasswordTooLong is returned for passwords bcrypt cannot hash
+var errPasswordTooLong = errors.New("password exceeds 72 bytes")
+
+// passwordInput returns the bytes fed to bcrypt. Passwords over bcrypt's limit
+// are either rejected or, when configured, pre-hashed with SHA-256 so that
+// every byte of the password contributes to the hash.
+func (s *Service) passwordInput(password string) ([]byte, error) {
+	input := []byte(password)
+	if len(input) <= bcryptMaxPasswordBytes {
+		return input, nil
+	}
+
+	if !s.config.Security.Password.PrehashLongPasswords {
+		return nil, errPasswordTooLong
+	}
+
+	sum := sha256.Sum256(input)
+	return []byte(base64.StdEncoding.EncodeToString(sum[:])), nil
+}
+
+// hashPassword hashes a password for storage
+func (s *Service) hashPassword(password string) (string, error) {
+	input, err := s.passwordInput(password)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword(input, bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// comparePassword checks a password against a stored hash
+func (s *Service) comparePassword(hash, password string) error {
+	input, err := s.passwordInput(password)
+	if err != nil {
+		// No stored hash can match a password we would have refused to hash
+		return bcrypt.ErrMismatchedHashAndPassword
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), input)
+}
+
+// logCryptoError logs a password hashing failure separately from other internal errors
+func (s *Service) logCryptoError(err error, msg string) {
+	s.logger.WithError(err).WithField("error_kind", "crypto").Error(msg)
+}
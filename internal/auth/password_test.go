@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// withPrehash pre-hashes passwords over bcrypt's limit instead of rejecting them
+func withPrehash(cfg *config.Config) {
+	cfg.Security.Password.PrehashLongPasswords = true
+}
+
+// longPassword returns a policy-compliant password of n bytes
+func longPassword(n int) string {
+	return "Aa1" + strings.Repeat("x", n-3)
+}
+
+func TestHashPasswordRejectsOverLongPasswords(t *testing.T) {
+	s := newTestService(t)
+
+	if _, err := s.hashPassword(longPassword(bcryptMaxPasswordBytes)); err != nil {
+		t.Fatalf("72-byte password: %v", err)
+	}
+	if _, err := s.hashPassword(longPassword(bcryptMaxPasswordBytes + 1)); err != errPasswordTooLong {
+		t.Fatalf("73-byte password: err = %v, want %v", err, errPasswordTooLong)
+	}
+	// Multi-byte characters count by byte, not rune
+	if _, err := s.hashPassword("Aa1" + strings.Repeat("é", 35)); err != errPasswordTooLong {
+		t.Fatalf("73-byte unicode password: err = %v, want %v", err, errPasswordTooLong)
+	}
+}
+
+func TestPrehashedLongPasswords(t *testing.T) {
+	s := newTestService(t, withPrehash)
+	password := longPassword(100)
+
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+	if err := s.comparePassword(hash, password); err != nil {
+		t.Fatalf("comparePassword: %v", err)
+	}
+
+	// bcrypt alone ignores bytes past 72, so the pre-hash must not
+	differsLate := password[:90] + "y" + password[91:]
+	if err := s.comparePassword(hash, differsLate); err != bcrypt.ErrMismatchedHashAndPassword {
+		t.Fatalf("password differing at byte 91: err = %v, want a mismatch", err)
+	}
+}
+
+func TestComparePasswordRejectsOverLongPasswords(t *testing.T) {
+	s := newTestService(t)
+	hash, err := s.hashPassword(longPassword(bcryptMaxPasswordBytes))
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+
+	if err := s.comparePassword(hash, longPassword(bcryptMaxPasswordBytes+1)); err != bcrypt.ErrMismatchedHashAndPassword {
+		t.Fatalf("err = %v, want a mismatch", err)
+	}
+}
+
+func TestOverLongPasswordIsAValidationError(t *testing.T) {
+	s := newTestService(t)
+	password := longPassword(bcryptMaxPasswordBytes + 1)
+
+	tests := []struct {
+		path string
+		body interface{}
+	}{
+		{"/v1/auth/register", RegisterRequest{Email: "long@example.com", Password: password}},
+		{"/v1/auth/reset-password", ResetPasswordRequest{Token: "reset-token", Password: password}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := serve(s, http.MethodPost, tt.path, "acme", tt.body)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+			}
+			var body platformhttp.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.Fields["password"] != "must be at most 72 bytes" {
+				t.Fatalf("fields = %v, want the password length reported", body.Fields)
+			}
+		})
+	}
+}
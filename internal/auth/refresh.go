@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+var (
+	errRefreshTokenInvalid = errors.New("refresh token invalid or expired")
+	// errRefreshTokenReused is returned when a rotated or revoked refresh
+	// token is presented again, which suggests it was stolen
+	errRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// RefreshRequest exchanges a refresh token for a new token pair
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// refreshToken is a stored refresh token. Every token rotated from the same
+// login shares a family, so reuse of any of them revokes the whole chain.
+type refreshToken struct {
+	ID        string
+	TenantID  string
+	UserID    string
+	FamilyID  string
+	ExpiresAt time.Time
+	Revoked   bool
+}
+
+// Refresh rotates a refresh token and returns a new access and refresh token
+func (s *Service) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	token, refreshToken, err := s.rotateRefreshToken(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, errRefreshTokenReused) {
+			s.logger.Warnf("Revoked refresh token reused for user %s, revoking its token family", token.UserID)
+		}
+		if errors.Is(err, errRefreshTokenInvalid) || errors.Is(err, errRefreshTokenReused) {
+			platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Invalid refresh token")
+			return
+		}
+		s.logger.Errorf("Failed to rotate refresh token: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
+		return
+	}
+
+	// Load the user so the new access token carries their current role
+	ctx := auth.WithTenant(r.Context(), token.TenantID)
+	user, err := s.getUserByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Invalid refresh token")
+			return
+		}
+		s.logger.Errorf("Failed to get user for refresh: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
+		return
+	}
+
+	accessToken, err := s.jwtManager.GenerateTenantToken(user.ID, user.Email, user.Role, user.TenantID)
+	if err != nil {
+		s.logger.Errorf("Failed to generate token: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
+		return
+	}
+
+	render.JSON(w, r, &AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		User:         user,
+	})
+}
+
+// LogoutRequest optionally names the refresh token to revoke along with the
+// access token
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout revokes the caller's access token for the rest of its lifetime, and
+// the refresh token family of the refresh token in the body, if any
+func (s *Service) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
+		return
+	}
+
+	var req LogoutRequest
+	if r.ContentLength != 0 {
+		if err := platformhttp.DecodeJSON(r, &req); err != nil {
+			platformhttp.RequestError(w, r, err)
+			return
+		}
+	}
+
+	if s.jwtManager.Denylist() == nil {
+		platformhttp.Error(w, r, http.StatusNotImplemented, platformhttp.ErrCodeNotImplemented, "Token revocation is not enabled")
+		return
+	}
+
+	if err := s.jwtManager.RevokeToken(r.Context(), claims); err != nil {
+		s.logger.Errorf("Failed to revoke token for user %s: %v", claims.UserID, err)
+		platformhttp.Error(w, r, http.StatusServiceUnavailable, platformhttp.ErrCodeUnavailable, "Failed to revoke token")
+		return
+	}
+
+	if req.RefreshToken != "" {
+		if err := s.revokeRefreshTokenFamily(r.Context(), claims.UserID, req.RefreshToken); err != nil {
+			s.logger.Errorf("Failed to revoke refresh token for user %s: %v", claims.UserID, err)
+			platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeRefreshTokenFamily revokes a user's refresh token and every token
+// rotated from the same login. Tokens of other users are left alone.
+func (s *Service) revokeRefreshTokenFamily(ctx context.Context, userID, value string) error {
+	return s.db.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+		WHERE NOT revoked AND family_id = (
+			SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2
+		)`, hashRefreshToken(value), userID)
+}
+
+// issueRefreshToken stores a new refresh token starting a token family and
+// returns its plaintext value
+func (s *Service) issueRefreshToken(ctx context.Context, user *User) (string, error) {
+	value, hash, err := newRefreshTokenValue()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Exec(ctx, `
+		INSERT INTO refresh_tokens (id, tenant_id, user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		uuid.New().String(), user.TenantID, user.ID, uuid.New().String(), hash,
+		time.Now().Add(s.config.Security.JWT.RefreshExpiration))
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// rotateRefreshToken revokes the presented refresh token and issues its
+// replacement in the same family. Presenting a revoked token revokes the
+// entire family and fails with errRefreshTokenReused.
+func (s *Service) rotateRefreshToken(ctx context.Context, value string) (*refreshToken, string, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var token refreshToken
+	err = tx.QueryRow(ctx, `
+		SELECT id, tenant_id, user_id, family_id, expires_at, revoked
+		FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, hashRefreshToken(value)).Scan(
+		&token.ID, &token.TenantID, &token.UserID, &token.FamilyID, &token.ExpiresAt, &token.Revoked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", errRefreshTokenInvalid
+		}
+		return nil, "", err
+	}
+
+	if token.Revoked {
+		_, err = tx.Exec(ctx, `
+			UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+			WHERE family_id = $1 AND NOT revoked`, token.FamilyID)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, "", err
+		}
+		return &token, "", errRefreshTokenReused
+	}
+	if !time.Now().Before(token.ExpiresAt) {
+		return nil, "", errRefreshTokenInvalid
+	}
+
+	next, hash, err := newRefreshTokenValue()
+	if err != nil {
+		return nil, "", err
+	}
+	nextID := uuid.New().String()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO refresh_tokens (id, tenant_id, user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		nextID, token.TenantID, token.UserID, token.FamilyID, hash,
+		time.Now().Add(s.config.Security.JWT.RefreshExpiration))
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW(), replaced_by = $2
+		WHERE id = $1`, token.ID, nextID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, "", err
+	}
+	return &token, next, nil
+}
+
+// newRefreshTokenValue returns a random refresh token and the hash it is stored under
+func newRefreshTokenValue() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	value := base64.RawURLEncoding.EncodeToString(buf)
+	return value, hashRefreshToken(value), nil
+}
+
+// hashRefreshToken hashes a refresh token for storage. Tokens are random, so
+// an unsalted SHA-256 is enough to keep a database leak from exposing them.
+func hashRefreshToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
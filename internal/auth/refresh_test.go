@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// login signs in as email in tenantID and returns the issued tokens
+func login(t *testing.T, s *Service, tenantID, email, password string) *AuthResponse {
+	t.Helper()
+
+	rec := serve(s, http.MethodPost, "/v1/auth/login", tenantID, LoginRequest{Email: email, Password: password})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return &resp
+}
+
+// refresh exchanges a refresh token, returning the status and new tokens
+func refresh(t *testing.T, s *Service, tenantID, refreshToken string) (int, *AuthResponse) {
+	t.Helper()
+
+	rec := serve(s, http.MethodPost, "/v1/auth/refresh", tenantID, RefreshRequest{RefreshToken: refreshToken})
+	var resp AuthResponse
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, &resp
+}
+
+func TestRefreshTokenValues(t *testing.T) {
+	value, hash, err := newRefreshTokenValue()
+	if err != nil {
+		t.Fatalf("newRefreshTokenValue: %v", err)
+	}
+	if hash == value || hash != hashRefreshToken(value) {
+		t.Fatalf("hash %q does not match token %q", hash, value)
+	}
+	other, _, _ := newRefreshTokenValue()
+	if other == value {
+		t.Fatal("refresh tokens repeat")
+	}
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	s := newTestService(t)
+	tenantID := withTestDB(t, s)
+	email, password := "refresh-"+uuid.New().String()[:8]+"@example.com", "Correct-Horse-Battery-42"
+	register(t, s, tenantID, email, password)
+	issued := login(t, s, tenantID, email, password)
+	if issued.RefreshToken == "" {
+		t.Fatal("login issued no refresh token")
+	}
+
+	// The stored token is hashed
+	var stored int
+	err := s.db.QueryRow(context.Background(), `SELECT COUNT(*) FROM refresh_tokens WHERE token_hash = $1`, issued.RefreshToken).Scan(&stored)
+	if err != nil {
+		t.Fatalf("failed to look up refresh token: %v", err)
+	}
+	if stored != 0 {
+		t.Fatal("refresh token stored in plaintext")
+	}
+
+	status, rotated := refresh(t, s, tenantID, issued.RefreshToken)
+	if status != http.StatusOK || rotated.AccessToken == "" || rotated.RefreshToken == "" || rotated.RefreshToken == issued.RefreshToken {
+		t.Fatalf("refresh = %d %+v, want a new token pair", status, rotated)
+	}
+	if rotated.User == nil || rotated.User.Email != email {
+		t.Fatalf("refreshed user = %+v, want %s", rotated.User, email)
+	}
+	if status, _ := refresh(t, s, tenantID, "not-a-refresh-token"); status != http.StatusUnauthorized {
+		t.Fatalf("unknown token: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	s := newTestService(t)
+	tenantID := withTestDB(t, s)
+	email, password := "reuse-"+uuid.New().String()[:8]+"@example.com", "Correct-Horse-Battery-42"
+	register(t, s, tenantID, email, password)
+	issued := login(t, s, tenantID, email, password)
+	other := login(t, s, tenantID, email, password)
+
+	status, rotated := refresh(t, s, tenantID, issued.RefreshToken)
+	if status != http.StatusOK {
+		t.Fatalf("refresh: status = %d", status)
+	}
+
+	// Presenting the rotated token again is rejected...
+	if status, _ := refresh(t, s, tenantID, issued.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("reused token: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	// ...and revokes its replacement too, in case the reuse was the thief's
+	if status, _ := refresh(t, s, tenantID, rotated.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("replacement after reuse: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	// Sessions from other logins are unaffected
+	if status, _ := refresh(t, s, tenantID, other.RefreshToken); status != http.StatusOK {
+		t.Fatalf("other session: status = %d, want %d", status, http.StatusOK)
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"path"
+	"strings"
+)
+
+// emailDomainAllowed reports whether an email's domain may register, along
+// with the rule that decided it (for server-side logs only)
+func (s *Service) emailDomainAllowed(email string) (bool, string) {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false, "malformed email"
+	}
+	domain := strings.ToLower(email[at+1:])
+
+	policy := s.config.Security.Registration
+	for _, pattern := range policy.DeniedEmailDomains {
+		if domainMatches(pattern, domain) {
+			return false, "denylist " + pattern
+		}
+	}
+	for _, pattern := range policy.AllowedEmailDomains {
+		if domainMatches(pattern, domain) {
+			return true, "allowlist " + pattern
+		}
+	}
+
+	if strings.EqualFold(policy.DefaultPolicy, "deny") {
+		return false, "default deny"
+	}
+	return true, "default allow"
+}
+
+// domainMatches matches a domain against an exact or wildcard pattern.
+// "*.example.com" matches subdomains of example.com but not example.com itself.
+func domainMatches(pattern, domain string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return false
+	}
+	matched, err := path.Match(pattern, domain)
+	return err == nil && matched
+}
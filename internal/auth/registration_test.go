@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// withEmailPolicy sets the registration email-domain policy
+func withEmailPolicy(allowed, denied []string, defaultPolicy string) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Security.Registration = config.RegistrationConfig{
+			AllowedEmailDomains: allowed,
+			DeniedEmailDomains:  denied,
+			DefaultPolicy:       defaultPolicy,
+		}
+	}
+}
+
+func TestEmailDomainAllowed(t *testing.T) {
+	allowed := []string{"example.com", "*.corp.example"}
+	denied := []string{"*.mailinator.com", "mailinator.com", "blocked.example.com"}
+
+	tests := []struct {
+		name          string
+		defaultPolicy string
+		email         string
+		want          bool
+	}{
+		{"allowed", "deny", "jane@example.com", true},
+		{"allowed case-insensitively", "deny", "jane@EXAMPLE.com", true},
+		{"allowed by wildcard", "deny", "jane@eu.corp.example", true},
+		{"wildcard excludes its apex", "deny", "jane@corp.example", false},
+		{"denied", "allow", "jane@mailinator.com", false},
+		{"denied by wildcard", "allow", "jane@x.mailinator.com", false},
+		{"denial overrides allowlist", "allow", "jane@blocked.example.com", false},
+		{"unlisted with default allow", "allow", "jane@other.org", true},
+		{"unlisted with no default", "", "jane@other.org", true},
+		{"unlisted with default deny", "deny", "jane@other.org", false},
+		{"unlisted with default DENY", "DENY", "jane@other.org", false},
+		{"malformed", "allow", "jane", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, withEmailPolicy(allowed, denied, tt.defaultPolicy))
+
+			got, rule := s.emailDomainAllowed(tt.email)
+			if got != tt.want {
+				t.Fatalf("emailDomainAllowed(%q) = %v (%s), want %v", tt.email, got, rule, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterRejectsDisallowedDomain(t *testing.T) {
+	s := newTestService(t, withEmailPolicy(nil, []string{"*.secret-rival.com"}, "allow"))
+
+	rec := serve(s, http.MethodPost, "/v1/auth/register", "acme",
+		RegisterRequest{Email: "jane@mail.secret-rival.com", Password: "Correct-Horse-Battery-42"})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body)
+	}
+
+	var body platformhttp.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Code != platformhttp.ErrCodeForbidden {
+		t.Errorf("code = %q, want %q", body.Code, platformhttp.ErrCodeForbidden)
+	}
+	// The response must not reveal which rule matched
+	if strings.Contains(body.Message, "secret-rival") || strings.Contains(body.Message, "denylist") {
+		t.Errorf("message %q leaks the matching rule", body.Message)
+	}
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+var (
+	errResetTokenInvalid = errors.New("reset token invalid or expired")
+	errResetTokenUsed    = errors.New("reset token already used")
+	// errProducerUnavailable is returned when events cannot be emitted because
+	// the event bus failed to initialize
+	errProducerUnavailable = errors.New("event producer not initialized")
+)
+
+// ForgotPasswordRequest asks for a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// PasswordResetRequestedEvent asks the notify service to email a reset token.
+// It carries the plaintext token, which is never stored.
+type PasswordResetRequestedEvent struct {
+	EventID   string    `json:"event_id"`
+	TenantID  string    `json:"tenant_id"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// forgotPasswordMessage is returned whether or not the email has an account,
+// so the endpoint cannot be used to discover accounts
+const forgotPasswordMessage = "If an account exists for this email, a password reset link has been sent"
+
+// ForgotPassword issues a single-use reset token and emits an event for the
+// notify service to email it. Failures are logged but never reported.
+func (s *Service) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := platformhttp.DecodeJSON(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	req.Email = normalizeEmail(req.Email)
+	if errs := platformhttp.Validate(&req); errs != nil {
+		platformhttp.ValidationError(w, r, errs)
+		return
+	}
+
+	ctx, ok := s.tenantContext(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.requestPasswordReset(ctx, req.Email); err != nil {
+		s.logger.Errorf("Failed to request password reset: %v", err)
+	}
+
+	render.JSON(w, r, map[string]string{"message": forgotPasswordMessage})
+}
+
+// requestPasswordReset stores a reset token for the user with the email, if
+// there is one, and emits the event that delivers it
+func (s *Service) requestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.queryUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	// Reset tokens share the refresh token format and hashing
+	value, hash, err := newRefreshTokenValue()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.config.Security.Password.ResetTokenTTL)
+
+	err = s.db.Exec(ctx, `
+		INSERT INTO password_reset_tokens (id, tenant_id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		uuid.New().String(), user.TenantID, user.ID, hash, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	if s.kafka == nil {
+		return errProducerUnavailable
+	}
+	return s.kafka.SendJSONMessage(ctx, s.config.Kafka.Topics.PasswordResetRequested, []byte(user.ID), &PasswordResetRequestedEvent{
+		EventID:   uuid.New().String(),
+		TenantID:  user.TenantID,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Token:     value,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// ResetPassword consumes a reset token and sets the user's new password.
+// Every refresh token the user holds is revoked, signing out other sessions.
+func (s *Service) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+	if msg := s.checkPasswordPolicy(req.Password); msg != "" {
+		platformhttp.ValidationError(w, r, FieldErrors{"password": msg})
+		return
+	}
+
+	passwordHash, err := s.hashPassword(req.Password)
+	if err != nil {
+		s.logCryptoError(err, "Failed to hash password")
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
+		return
+	}
+
+	user, err := s.consumeResetToken(r.Context(), req.Token, passwordHash)
+	if err != nil {
+		if errors.Is(err, errResetTokenInvalid) || errors.Is(err, errResetTokenUsed) {
+			platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Invalid or expired reset token")
+			return
+		}
+		s.logger.Errorf("Failed to reset password: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
+		return
+	}
+
+	s.invalidateUser(user)
+	s.logger.Infof("Password reset for user %s", user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// consumeResetToken marks a reset token used and, in the same transaction,
+// sets the user's password hash, voids their other outstanding reset tokens,
+// and revokes their refresh tokens. It returns the user whose password changed.
+func (s *Service) consumeResetToken(ctx context.Context, value, passwordHash string) (*User, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var tokenID string
+	var user User
+	var expiresAt time.Time
+	var usedAt *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT t.id, t.expires_at, t.used_at, u.id, u.tenant_id, u.email
+		FROM password_reset_tokens t JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = $1 FOR UPDATE OF t`, hashRefreshToken(value)).Scan(
+		&tokenID, &expiresAt, &usedAt, &user.ID, &user.TenantID, &user.Email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errResetTokenInvalid
+		}
+		return nil, err
+	}
+	if usedAt != nil {
+		return nil, errResetTokenUsed
+	}
+	if !time.Now().Before(expiresAt) {
+		return nil, errResetTokenInvalid
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`,
+		passwordHash, user.ID, user.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE password_reset_tokens SET used_at = NOW() WHERE tenant_id = $1 AND user_id = $2 AND used_at IS NULL`,
+		user.TenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE refresh_tokens SET revoked = TRUE, revoked_at = NOW()
+		WHERE tenant_id = $1 AND user_id = $2 AND NOT revoked`, user.TenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging/messagingtest"
+)
+
+// resetPassword posts a new password with a reset token and returns the status
+func resetPassword(s *Service, tenantID, token, password string) int {
+	return serve(s, http.MethodPost, "/v1/auth/reset-password", tenantID, ResetPasswordRequest{Token: token, Password: password}).Code
+}
+
+// loginStatus attempts a login and returns the status
+func loginStatus(s *Service, tenantID, email, password string) int {
+	return serve(s, http.MethodPost, "/v1/auth/login", tenantID, LoginRequest{Email: email, Password: password}).Code
+}
+
+func TestForgotPasswordDoesNotRevealAccounts(t *testing.T) {
+	s := newTestService(t)
+	tenantID := withTestDB(t, s)
+	email := "forgot-" + uuid.New().String()[:8] + "@example.com"
+	register(t, s, tenantID, email, "Correct-Horse-Battery-42")
+	producer := messagingtest.NewFakeProducer()
+	s.SetProducer(producer)
+
+	var bodies []map[string]string
+	for _, address := range []string{email, "nobody-" + uuid.New().String()[:8] + "@example.com"} {
+		rec := serve(s, http.MethodPost, "/v1/auth/forgot-password", tenantID, ForgotPasswordRequest{Email: address})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("forgot password for %s: status = %d", address, rec.Code)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		bodies = append(bodies, body)
+	}
+
+	if bodies[0]["message"] != bodies[1]["message"] {
+		t.Fatalf("responses differ: %v and %v", bodies[0], bodies[1])
+	}
+	if sent := producer.MessagesFor(s.config.Kafka.Topics.PasswordResetRequested); len(sent) != 1 {
+		t.Fatalf("sent %d reset events, want 1 for the existing account", len(sent))
+	}
+}
+
+func TestResetPasswordIsSingleUse(t *testing.T) {
+	s := newTestService(t)
+	tenantID := withTestDB(t, s)
+	email, oldPassword, newPassword := "reset-"+uuid.New().String()[:8]+"@example.com", "Correct-Horse-Battery-42", "New-Horse-Battery-43"
+	register(t, s, tenantID, email, oldPassword)
+	session := login(t, s, tenantID, email, oldPassword)
+	token := resetToken(t, s, tenantID, email)
+
+	if status := resetPassword(s, tenantID, token, newPassword); status != http.StatusNoContent {
+		t.Fatalf("reset: status = %d, want %d", status, http.StatusNoContent)
+	}
+	if status := loginStatus(s, tenantID, email, oldPassword); status != http.StatusUnauthorized {
+		t.Fatalf("login with the old password: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	login(t, s, tenantID, email, newPassword)
+
+	// Sessions from before the reset are signed out
+	if status, _ := refresh(t, s, tenantID, session.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("refresh after reset: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	// An already-used token cannot set the password again
+	if status := resetPassword(s, tenantID, token, "Third-Horse-Battery-44"); status != http.StatusBadRequest {
+		t.Fatalf("reused token: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	login(t, s, tenantID, email, newPassword)
+}
+
+func TestResetPasswordRejectsExpiredTokens(t *testing.T) {
+	s := newTestService(t, func(cfg *config.Config) {
+		cfg.Security.Password.ResetTokenTTL = -time.Minute
+	})
+	tenantID := withTestDB(t, s)
+	email, password := "expired-"+uuid.New().String()[:8]+"@example.com", "Correct-Horse-Battery-42"
+	register(t, s, tenantID, email, password)
+	token := resetToken(t, s, tenantID, email)
+
+	if status := resetPassword(s, tenantID, token, "New-Horse-Battery-43"); status != http.StatusBadRequest {
+		t.Fatalf("expired token: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if status := resetPassword(s, tenantID, "not-a-reset-token", "New-Horse-Battery-43"); status != http.StatusBadRequest {
+		t.Fatalf("unknown token: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	login(t, s, tenantID, email, password)
+}
@@ -3,7 +3,7 @@ package auth
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -11,8 +11,12 @@ import (
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/cache"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -23,11 +27,18 @@ type Service struct {
 	logger     *logrus.Logger
 	db         *database.PostgresDB
 	jwtManager *auth.JWTManager
+	users      *cache.Cache
+	tenants    auth.TenantScope
+	kafka      messaging.Producer
+
+	// limiters throttle each credential endpoint; nil when rate limiting is disabled
+	limiters map[string]*platformhttp.RateLimiter
 }
 
 // User represents a user in the system
 type User struct {
 	ID           string    `json:"id"`
+	TenantID     string    `json:"tenant_id"`
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"`
 	Role         string    `json:"role"`
@@ -40,8 +51,9 @@ type User struct {
 
 // RegisterRequest represents a user registration request
 type RegisterRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
+	Email string `json:"email" validate:"required,email"`
+	// Password length and strength are set by the configured password policy
+	Password string `json:"password" validate:"required"`
 }
 
 // LoginRequest represents a user login request
@@ -52,26 +64,67 @@ type LoginRequest struct {
 
 // AuthResponse represents an authentication response
 type AuthResponse struct {
-	AccessToken string `json:"access_token"`
-	User        *User  `json:"user"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	User         *User  `json:"user"`
 }
 
 // NewService creates a new authentication service
 func NewService(cfg *config.Config, logger *logrus.Logger) *Service {
 	// Initialize JWT manager
-	jwtConfig := &auth.JWTConfig{
-		Secret:     cfg.Security.JWT.Secret,
-		Issuer:     cfg.Security.JWT.Issuer,
-		Audience:   cfg.Security.JWT.Audience,
-		Expiration: cfg.Security.JWT.Expiration,
+	jwtManager := auth.NewJWTManagerFromConfig(cfg)
+	if !jwtManager.CanSign() {
+		logger.Warn("No JWT signing key configured; login and token refresh will fail")
 	}
-	jwtManager := auth.NewJWTManager(jwtConfig)
 
-	return &Service{
+	service := &Service{
 		config:     cfg,
 		logger:     logger,
 		jwtManager: jwtManager,
+		users:      newUserCache(cfg.Cache.UserProfiles),
+		tenants:    auth.NewTenantScope(cfg.Security.Tenancy.Enabled, cfg.Security.Tenancy.DefaultTenant),
+	}
+
+	// Initialize event producer for password reset emails
+	kafkaConfig := &messaging.KafkaConfig{
+		Driver:   cfg.Kafka.Driver,
+		Brokers:  cfg.Kafka.Brokers,
+		ClientID: cfg.Kafka.ClientID,
 	}
+	bus, err := messaging.NewEventBus(kafkaConfig, logger)
+	if err != nil {
+		logger.Errorf("Failed to initialize event bus: %v", err)
+	} else {
+		service.kafka = bus.Producer()
+	}
+
+	// Throttle credential endpoints per client to slow down brute forcing
+	if rl := cfg.Security.RateLimit; rl.Enabled {
+		var store platformhttp.RateLimitStore
+		if rl.Redis {
+			store = platformhttp.NewRedisRateLimitStore(database.OpenRedisDB(&database.RedisConfig{
+				Addr:     cfg.Redis.Addr,
+				Password: cfg.Redis.Password,
+				DB:       cfg.Redis.DB,
+				PoolSize: cfg.Redis.PoolSize,
+			}))
+		}
+
+		service.limiters = make(map[string]*platformhttp.RateLimiter)
+		for _, route := range rateLimitedRoutes {
+			limits := rl.ForRoute(route)
+			service.limiters[route] = platformhttp.NewRateLimiter(platformhttp.RateLimitConfig{
+				Name:              route,
+				RequestsPerMinute: limits.RequestsPerMinute,
+				Burst:             limits.Burst,
+				Headers:           rl.Headers,
+				Store:             store,
+				Logger:            logger,
+			})
+		}
+	}
+
+	return service
 }
 
 // SetDatabase sets the database connection
@@ -79,57 +132,90 @@ func (s *Service) SetDatabase(db *database.PostgresDB) {
 	s.db = db
 }
 
+// SetProducer replaces the event producer, for example with a fake in tests
+func (s *Service) SetProducer(producer messaging.Producer) {
+	s.kafka = producer
+}
+
+// rateLimitedRoutes names the credential endpoints, each limited separately
+// by security.rate_limit.routes.<name>
+var rateLimitedRoutes = []string{"auth_register", "auth_login", "auth_refresh", "auth_forgot_password", "auth_reset_password"}
+
+// rateLimit returns the middleware limiting a named route
+func (s *Service) rateLimit(route string) func(http.Handler) http.Handler {
+	if limiter, ok := s.limiters[route]; ok {
+		return limiter.Middleware
+	}
+	return func(next http.Handler) http.Handler { return next }
+}
+
 // Routes returns the authentication service routes
-func (s *Service) Routes(r *chi.Mux) {
+func (s *Service) Routes(r chi.Router) {
+	r.Get("/.well-known/jwks.json", s.JWKS)
 	r.Route("/v1/auth", func(r chi.Router) {
-		r.Post("/register", s.Register)
-		r.Post("/login", s.Login)
-		r.Get("/me", s.AuthMiddleware(s.GetProfile))
+		r.With(s.rateLimit("auth_register")).Post("/register", s.Register)
+		r.With(s.rateLimit("auth_login")).Post("/login", s.Login)
+		r.With(s.rateLimit("auth_refresh")).Post("/refresh", s.Refresh)
+		r.With(s.rateLimit("auth_forgot_password")).Post("/forgot-password", s.ForgotPassword)
+		r.With(s.rateLimit("auth_reset_password")).Post("/reset-password", s.ResetPassword)
+		r.Group(func(r chi.Router) {
+			r.Use(authmw.RequireJWT(s.jwtManager, authmw.WithTenants(s.tenants), authmw.WithLogger(s.logger)))
+			r.Get("/me", s.GetProfile)
+			r.Post("/logout", s.Logout)
+			r.With(authmw.RequireRole(auth.RoleService, authmw.WithLogger(s.logger))).Post("/introspect", s.Introspect)
+		})
 	})
 }
 
 // Register handles user registration
 func (s *Service) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+	if err := platformhttp.DecodeJSON(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
 		return
 	}
 
 	// Validate request
-	if req.Email == "" || req.Password == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Email and password are required"})
+	req.Email = normalizeEmail(req.Email)
+	if errs := s.validateRegistration(&req); errs != nil {
+		platformhttp.ValidationError(w, r, errs)
+		return
+	}
+
+	ctx, ok := s.tenantContext(w, r)
+	if !ok {
+		return
+	}
+
+	if allowed, rule := s.emailDomainAllowed(req.Email); !allowed {
+		s.logger.Infof("Registration for %s rejected by email domain policy (%s)", req.Email, rule)
+		platformhttp.Error(w, r, http.StatusForbidden, platformhttp.ErrCodeForbidden, "Registration is not permitted for this email address")
 		return
 	}
 
 	// Check if user already exists
 	s.logger.Infof("Checking if user with email %s already exists", req.Email)
-	existingUser, err := s.getUserByEmail(r.Context(), req.Email)
+	existingUser, err := s.getUserByEmail(ctx, req.Email)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			s.logger.Infof("User with email %s does not exist (this is expected for new registrations)", req.Email)
 			// Continue with user creation since user doesn't exist
 		} else {
 			s.logger.Errorf("Failed to check existing user: %v", err)
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, map[string]string{"error": "Internal server error"})
+			platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
 			return
 		}
 	} else if existingUser != nil {
 		// User already exists
-		render.Status(r, http.StatusConflict)
-		render.JSON(w, r, map[string]string{"error": "User already exists"})
+		platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "User already exists")
 		return
 	}
 
 	// Hash password
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	passwordHash, err := s.hashPassword(req.Password)
 	if err != nil {
-		s.logger.Errorf("Failed to hash password: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
+		s.logCryptoError(err, "Failed to hash password")
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
 		return
 	}
 
@@ -138,32 +224,46 @@ func (s *Service) Register(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 	user := &User{
 		ID:           userID,
+		TenantID:     auth.TenantFromContext(ctx),
 		Email:        req.Email,
-		PasswordHash: string(passwordHash),
+		PasswordHash: passwordHash,
 		Role:         "user",
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
 
-	if err := s.createUser(r.Context(), user); err != nil {
+	err = s.createUser(ctx, user)
+	s.invalidateUser(user)
+	if err != nil {
+		// A concurrent registration for the same email won the race
+		if isDuplicateEmail(err) {
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "User already exists")
+			return
+		}
 		s.logger.Errorf("Failed to create user: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
 		return
 	}
 
 	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.Role)
+	token, err := s.jwtManager.GenerateTenantToken(user.ID, user.Email, user.Role, user.TenantID)
 	if err != nil {
 		s.logger.Errorf("Failed to generate token: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
+		return
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx, user)
+	if err != nil {
+		s.logger.Errorf("Failed to issue refresh token: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
 		return
 	}
 
 	response := &AuthResponse{
-		AccessToken: token,
-		User:        user,
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		User:         user,
 	}
 
 	render.Status(r, http.StatusCreated)
@@ -173,52 +273,65 @@ func (s *Service) Register(w http.ResponseWriter, r *http.Request) {
 // Login handles user login
 func (s *Service) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+	if err := platformhttp.DecodeJSON(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
 		return
 	}
 
 	// Validate request
-	if req.Email == "" || req.Password == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Email and password are required"})
+	req.Email = normalizeEmail(req.Email)
+	if errs := platformhttp.Validate(&req); errs != nil {
+		platformhttp.ValidationError(w, r, errs)
+		return
+	}
+
+	ctx, ok := s.tenantContext(w, r)
+	if !ok {
 		return
 	}
 
 	// Get user by email
-	user, err := s.getUserByEmail(r.Context(), req.Email)
+	user, err := s.getUserByEmail(ctx, req.Email)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Invalid credentials"})
+			platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Invalid credentials")
 			return
 		}
 		s.logger.Errorf("Failed to get user: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
 		return
 	}
 
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		render.Status(r, http.StatusUnauthorized)
-		render.JSON(w, r, map[string]string{"error": "Invalid credentials"})
+	if err := s.comparePassword(user.PasswordHash, req.Password); err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			s.logCryptoError(err, "Failed to verify password")
+			platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
+			return
+		}
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Invalid credentials")
 		return
 	}
 
 	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.Role)
+	token, err := s.jwtManager.GenerateTenantToken(user.ID, user.Email, user.Role, user.TenantID)
 	if err != nil {
 		s.logger.Errorf("Failed to generate token: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
+		return
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx, user)
+	if err != nil {
+		s.logger.Errorf("Failed to issue refresh token: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
 		return
 	}
 
 	response := &AuthResponse{
-		AccessToken: token,
-		User:        user,
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		User:         user,
 	}
 
 	render.JSON(w, r, response)
@@ -226,72 +339,80 @@ func (s *Service) Login(w http.ResponseWriter, r *http.Request) {
 
 // GetProfile returns the current user's profile
 func (s *Service) GetProfile(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
+	userID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
+		return
+	}
 
 	user, err := s.getUserByID(r.Context(), userID)
 	if err != nil {
 		s.logger.Errorf("Failed to get user profile: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Internal server error")
+		return
+	}
+
+	if platformhttp.CheckNotModified(w, r, platformhttp.NewValidators(user.UpdatedAt, user.ID), s.config.Cache.ClientMaxAge) {
 		return
 	}
 
 	render.JSON(w, r, user)
 }
 
-// AuthMiddleware validates JWT tokens
-func (s *Service) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Authorization header required"})
-			return
-		}
-
-		// Extract token from "Bearer <token>"
-		if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Invalid authorization header format"})
-			return
-		}
-
-		token := authHeader[7:]
-		claims, err := s.jwtManager.ValidateToken(token)
-		if err != nil {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Invalid token"})
-			return
-		}
-
-		// Add user info to context
-		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
-		ctx = context.WithValue(ctx, "user_email", claims.Email)
-		ctx = context.WithValue(ctx, "user_role", claims.Role)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
+// tenantContext scopes an unauthenticated request to the tenant named in its
+// tenant header, writing a 400 response if the tenant is missing or invalid
+func (s *Service) tenantContext(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	tenantID, err := s.tenants.FromHeader(r.Header.Get(auth.TenantHeader))
+	if err != nil {
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "A valid "+auth.TenantHeader+" header is required")
+		return nil, false
 	}
+	return auth.WithTenant(r.Context(), tenantID), true
 }
 
 // Database helper methods
 func (s *Service) createUser(ctx context.Context, user *User) error {
 	query := `
-		INSERT INTO users (id, email, password_hash, role, first_name, last_name, phone, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO users (id, tenant_id, email, password_hash, role, first_name, last_name, phone, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
-	err := s.db.Exec(ctx, query, user.ID, user.Email, user.PasswordHash, user.Role, user.FirstName, user.LastName, user.Phone, user.CreatedAt, user.UpdatedAt)
+	err := s.db.Exec(ctx, query, user.ID, user.TenantID, user.Email, user.PasswordHash, user.Role, user.FirstName, user.LastName, user.Phone, user.CreatedAt, user.UpdatedAt)
 	return err
 }
 
+// emailConstraints are the unique constraints on a tenant's user emails:
+// the exact address and its lowercase form
+var emailConstraints = map[string]bool{
+	"users_tenant_id_email_key":    true,
+	"idx_users_tenant_email_lower": true,
+}
+
+// isDuplicateEmail reports whether err is a user insert rejected because the
+// tenant already has a user with that email. Other unique violations, such
+// as a colliding ID, are not the caller's fault.
+func isDuplicateEmail(err error) bool {
+	constraint, ok := database.UniqueViolationConstraint(err)
+	return ok && emailConstraints[constraint]
+}
+
+// getUserByEmail looks a user up by email, ignoring case
 func (s *Service) getUserByEmail(ctx context.Context, email string) (*User, error) {
-	query := `SELECT id, email, password_hash, role, first_name, last_name, phone, created_at, updated_at FROM users WHERE email = $1`
+	email = normalizeEmail(email)
+	tenantID := auth.TenantFromContext(ctx)
+	return s.cachedUser(cacheUsersByEmail, userCacheKey(tenantID, email), func() (*User, error) {
+		return s.queryUserByEmail(ctx, email)
+	})
+}
+
+func (s *Service) queryUserByEmail(ctx context.Context, email string) (*User, error) {
+	query := `SELECT id, tenant_id, email, password_hash, role, first_name, last_name, phone, created_at, updated_at FROM users WHERE tenant_id = $1 AND LOWER(email) = $2`
 
 	s.logger.Infof("Executing query: %s with email: %s", query, email)
 
 	var user User
-	err := s.db.QueryRow(ctx, query, email).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.FirstName, &user.LastName, &user.Phone, &user.CreatedAt, &user.UpdatedAt,
+	err := s.db.QueryRow(ctx, query, auth.TenantFromContext(ctx), email).Scan(
+		&user.ID, &user.TenantID, &user.Email, &user.PasswordHash, &user.Role, &user.FirstName, &user.LastName, &user.Phone, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
@@ -312,11 +433,18 @@ func (s *Service) getUserByEmail(ctx context.Context, email string) (*User, erro
 }
 
 func (s *Service) getUserByID(ctx context.Context, userID string) (*User, error) {
-	query := `SELECT id, email, password_hash, role, first_name, last_name, phone, created_at, updated_at FROM users WHERE id = $1`
+	tenantID := auth.TenantFromContext(ctx)
+	return s.cachedUser(cacheUsersByID, userCacheKey(tenantID, userID), func() (*User, error) {
+		return s.queryUserByID(ctx, userID)
+	})
+}
+
+func (s *Service) queryUserByID(ctx context.Context, userID string) (*User, error) {
+	query := `SELECT id, tenant_id, email, password_hash, role, first_name, last_name, phone, created_at, updated_at FROM users WHERE tenant_id = $1 AND id = $2`
 
 	var user User
-	err := s.db.QueryRow(ctx, query, userID).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.FirstName, &user.LastName, &user.Phone, &user.CreatedAt, &user.UpdatedAt,
+	err := s.db.QueryRow(ctx, query, auth.TenantFromContext(ctx), userID).Scan(
+		&user.ID, &user.TenantID, &user.Email, &user.PasswordHash, &user.Role, &user.FirstName, &user.LastName, &user.Phone, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
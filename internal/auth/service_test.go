@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/migrate"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging/messagingtest"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestService creates an auth service without a database; configure
+// adjusts the loaded configuration first
+func newTestService(t *testing.T, configure ...func(*config.Config)) *Service {
+	t.Helper()
+
+	cfg, err := config.Load("auth-svc")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Kafka.Driver = "memory"
+	cfg.Redis.Addr = ""
+	cfg.Security.JWT.Revocation = false
+	cfg.Security.RateLimit.Enabled = false
+	cfg.Security.Tenancy.Enabled = true
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewService(cfg, logger)
+}
+
+// withTestDB migrates the test database for s and returns a fresh tenant.
+// The test is skipped without a database.
+func withTestDB(t *testing.T, s *Service) string {
+	t.Helper()
+
+	db := databasetest.Open(t)
+	if err := migrate.Run(context.Background(), db, "auth", Migrations, migrate.ModeApply, s.logger); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	s.SetDatabase(db)
+	return databasetest.Tenant(t)
+}
+
+// serve sends a request with a JSON body through s's routes as tenantID
+func serve(s *Service, method, path, tenantID string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(auth.TenantHeader, tenantID)
+
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// register creates a user with email and password in tenantID
+func register(t *testing.T, s *Service, tenantID, email, password string) {
+	t.Helper()
+
+	if rec := serve(s, http.MethodPost, "/v1/auth/register", tenantID, RegisterRequest{Email: email, Password: password}); rec.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// resetToken requests a password reset for email in tenantID and returns
+// the token its event delivers
+func resetToken(t *testing.T, s *Service, tenantID, email string) string {
+	t.Helper()
+
+	producer := messagingtest.NewFakeProducer()
+	s.SetProducer(producer)
+	if rec := serve(s, http.MethodPost, "/v1/auth/forgot-password", tenantID, ForgotPasswordRequest{Email: email}); rec.Code != http.StatusOK {
+		t.Fatalf("forgot password: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	messages := producer.MessagesFor(s.config.Kafka.Topics.PasswordResetRequested)
+	if len(messages) != 1 {
+		t.Fatalf("sent %d reset events, want 1", len(messages))
+	}
+	var event PasswordResetRequestedEvent
+	if err := json.Unmarshal(messages[0].Value, &event); err != nil {
+		t.Fatalf("decode reset event: %v", err)
+	}
+	return event.Token
+}
+
+func TestConcurrentRegistrationConflicts(t *testing.T) {
+	s := newTestService(t)
+	tenantID := withTestDB(t, s)
+	req := RegisterRequest{Email: "race-" + uuid.New().String()[:8] + "@example.com", Password: "Correct-Horse-Battery-42"}
+
+	// Every registration passes the existence check before any inserts, so
+	// the losers hit the unique constraint and must still get a 409
+	const registrations = 8
+	start := make(chan struct{})
+	codes := make(chan int, registrations)
+	var wg sync.WaitGroup
+	for i := 0; i < registrations; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			codes <- serve(s, http.MethodPost, "/v1/auth/register", tenantID, req).Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+
+	created := 0
+	for code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusConflict:
+		default:
+			t.Errorf("status = %d, want %d or %d", code, http.StatusCreated, http.StatusConflict)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("%d registrations succeeded, want 1", created)
+	}
+}
+
+func TestLoginIsScopedToTenant(t *testing.T) {
+	s := newTestService(t)
+	tenantA := withTestDB(t, s)
+	tenantB := databasetest.Tenant(t)
+	req := RegisterRequest{Email: "tenant-" + uuid.New().String()[:8] + "@example.com", Password: "Correct-Horse-Battery-42"}
+
+	if rec := serve(s, http.MethodPost, "/v1/auth/register", tenantA, req); rec.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(s, http.MethodPost, "/v1/auth/login", tenantA, LoginRequest{Email: req.Email, Password: req.Password})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login in own tenant: status = %d: %s", rec.Code, rec.Body)
+	}
+	var response AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("decode login: %v", err)
+	}
+	claims, err := s.jwtManager.ValidateToken(response.AccessToken)
+	if err != nil {
+		t.Fatalf("login token: %v", err)
+	}
+	if claims.TenantID != tenantA {
+		t.Fatalf("token tenant = %q, want %q", claims.TenantID, tenantA)
+	}
+
+	if rec := serve(s, http.MethodPost, "/v1/auth/login", tenantB, LoginRequest{Email: req.Email, Password: req.Password}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("login in other tenant: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	// Emails are unique per tenant, so the other tenant may register it too
+	if rec := serve(s, http.MethodPost, "/v1/auth/register", tenantB, req); rec.Code != http.StatusCreated {
+		t.Fatalf("register in other tenant: status = %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestProfileConditionalRequests(t *testing.T) {
+	s := newTestService(t)
+	tenantID := withTestDB(t, s)
+	email := "profile-" + uuid.New().String()[:8] + "@example.com"
+	register(t, s, tenantID, email, "Correct-Horse-Battery-42")
+
+	rec := serve(s, http.MethodPost, "/v1/auth/login", tenantID, LoginRequest{Email: email, Password: "Correct-Horse-Battery-42"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status = %d: %s", rec.Code, rec.Body)
+	}
+	var login AuthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&login); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	profile := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/auth/me", nil)
+		req.Header.Set("Authorization", "Bearer "+login.AccessToken)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		router := chi.NewRouter()
+		s.Routes(router)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := profile("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("profile: status = %d, ETag = %q", first.Code, etag)
+	}
+	if rec := profile(etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("unchanged profile: status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+
+	// Resetting the password updates the user, so the cached copy is stale
+	token := resetToken(t, s, tenantID, email)
+	if rec := serve(s, http.MethodPost, "/v1/auth/reset-password", tenantID, ResetPasswordRequest{Token: token, Password: "Staple-Battery-Horse-43"}); rec.Code != http.StatusNoContent {
+		t.Fatalf("reset: status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := profile(etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("changed profile: status = %d, ETag = %q; want a fresh 200", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestIsDuplicateEmail(t *testing.T) {
+	violation := func(constraint string) error {
+		return &pgconn.PgError{Code: "23505", ConstraintName: constraint}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"email", violation("users_tenant_id_email_key"), true},
+		{"email in another case", violation("idx_users_tenant_email_lower"), true},
+		{"wrapped", fmt.Errorf("failed to create user: %w", violation("users_tenant_id_email_key")), true},
+		{"user ID", violation("users_pkey"), false},
+		{"other constraint", &pgconn.PgError{Code: "23503", ConstraintName: "users_tenant_id_email_key"}, false},
+		{"not a Postgres error", errors.New("connection reset"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDuplicateEmail(tt.err); got != tt.want {
+				t.Fatalf("isDuplicateEmail(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/cache"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// User profile cache namespaces
+const (
+	cacheUsersByID    = "users_by_id"
+	cacheUsersByEmail = "users_by_email"
+)
+
+// newUserCache returns the user profile cache, or nil when caching is disabled
+func newUserCache(cfg config.UserProfileCacheConfig) *cache.Cache {
+	if !cfg.Enabled || cfg.TTL <= 0 {
+		return nil
+	}
+	return cache.New(cfg.TTL)
+}
+
+// cachedUser loads a user through the profile cache when it is enabled.
+// Callers get their own copy so cached entries are never mutated.
+func (s *Service) cachedUser(ns, key string, load func() (*User, error)) (*User, error) {
+	if s.users == nil {
+		return load()
+	}
+
+	value, err := s.users.GetOrLoad(ns, key, func() (interface{}, error) {
+		return load()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user := *value.(*User)
+	return &user, nil
+}
+
+// invalidateUser drops a user's cached profile. Every operation that changes
+// a user's profile, role, or password must call this so stale permissions
+// are never served.
+func (s *Service) invalidateUser(user *User) {
+	if s.users == nil {
+		return
+	}
+	s.users.Delete(cacheUsersByID, userCacheKey(user.TenantID, user.ID))
+	s.users.Delete(cacheUsersByEmail, userCacheKey(user.TenantID, normalizeEmail(user.Email)))
+}
+
+// userCacheKey scopes cache entries to a tenant so lookups never cross tenants
+func userCacheKey(tenantID, key string) string {
+	return tenantID + "/" + key
+}
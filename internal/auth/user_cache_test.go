@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// withUserCache enables the user profile cache with ttl
+func withUserCache(ttl time.Duration) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Cache.UserProfiles = config.UserProfileCacheConfig{Enabled: true, TTL: ttl}
+	}
+}
+
+// countingLoader loads user and counts the loads
+type countingLoader struct {
+	user  User
+	loads int
+}
+
+func (l *countingLoader) load() (*User, error) {
+	l.loads++
+	user := l.user
+	return &user, nil
+}
+
+func TestCachedUser(t *testing.T) {
+	s := newTestService(t, withUserCache(time.Minute))
+	loader := &countingLoader{user: User{ID: "user-1", TenantID: "acme", Email: "jane@example.com", Role: "user"}}
+	byID := func() *User {
+		t.Helper()
+		user, err := s.cachedUser(cacheUsersByID, userCacheKey("acme", "user-1"), loader.load)
+		if err != nil {
+			t.Fatalf("cachedUser: %v", err)
+		}
+		return user
+	}
+
+	first := byID()
+	// Callers get copies, so one caller's changes never reach the cache
+	first.Role = "admin"
+	if second := byID(); second.Role != "user" || loader.loads != 1 {
+		t.Fatalf("second read = %+v after %d loads, want the cached user after 1", second, loader.loads)
+	}
+
+	// A changed profile is invalidated by ID and by email
+	if _, err := s.cachedUser(cacheUsersByEmail, userCacheKey("acme", "jane@example.com"), loader.load); err != nil {
+		t.Fatalf("cachedUser by email: %v", err)
+	}
+	loader.loads = 0
+	s.invalidateUser(&User{ID: "user-1", TenantID: "acme", Email: "Jane@Example.com"})
+	byID()
+	if _, err := s.cachedUser(cacheUsersByEmail, userCacheKey("acme", "jane@example.com"), loader.load); err != nil {
+		t.Fatalf("cachedUser by email: %v", err)
+	}
+	if loader.loads != 2 {
+		t.Fatalf("loads after invalidation = %d, want 2", loader.loads)
+	}
+}
+
+func TestCachedUserExpires(t *testing.T) {
+	s := newTestService(t, withUserCache(20*time.Millisecond))
+	loader := &countingLoader{user: User{ID: "user-1", TenantID: "acme"}}
+	read := func() {
+		t.Helper()
+		if _, err := s.cachedUser(cacheUsersByID, userCacheKey("acme", "user-1"), loader.load); err != nil {
+			t.Fatalf("cachedUser: %v", err)
+		}
+	}
+
+	read()
+	read()
+	if loader.loads != 1 {
+		t.Fatalf("loads within the ttl = %d, want 1", loader.loads)
+	}
+	time.Sleep(50 * time.Millisecond)
+	read()
+	if loader.loads != 2 {
+		t.Fatalf("loads after the ttl = %d, want 2", loader.loads)
+	}
+}
+
+func TestCachedUserDisabled(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache config.UserProfileCacheConfig
+	}{
+		{"disabled", config.UserProfileCacheConfig{Enabled: false, TTL: time.Minute}},
+		{"no ttl", config.UserProfileCacheConfig{Enabled: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, func(cfg *config.Config) { cfg.Cache.UserProfiles = tt.cache })
+			loader := &countingLoader{user: User{ID: "user-1", TenantID: "acme"}}
+
+			for i := 0; i < 2; i++ {
+				if _, err := s.cachedUser(cacheUsersByID, userCacheKey("acme", "user-1"), loader.load); err != nil {
+					t.Fatalf("cachedUser: %v", err)
+				}
+			}
+			if loader.loads != 2 {
+				t.Fatalf("loads = %d, want 2", loader.loads)
+			}
+		})
+	}
+}
+
+func TestCachedUserDoesNotCacheErrors(t *testing.T) {
+	s := newTestService(t, withUserCache(time.Minute))
+	errLoad := errors.New("database unavailable")
+	loads := 0
+	load := func() (*User, error) {
+		loads++
+		return nil, errLoad
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.cachedUser(cacheUsersByID, userCacheKey("acme", "user-1"), load); !errors.Is(err, errLoad) {
+			t.Fatalf("cachedUser: err = %v, want %v", err, errLoad)
+		}
+	}
+	if loads != 2 {
+		t.Fatalf("loads = %d, want 2", loads)
+	}
+}
+
+func TestPasswordResetInvalidatesCachedUser(t *testing.T) {
+	s := newTestService(t, withUserCache(time.Minute))
+	tenantID := withTestDB(t, s)
+	email := "cached-" + uuid.New().String()[:8] + "@example.com"
+	register(t, s, tenantID, email, "Correct-Horse-Battery-42")
+
+	// Logging in caches the profile with the old password hash
+	login := func(password string) int {
+		return serve(s, http.MethodPost, "/v1/auth/login", tenantID, LoginRequest{Email: email, Password: password}).Code
+	}
+	if code := login("Correct-Horse-Battery-42"); code != http.StatusOK {
+		t.Fatalf("login: status = %d", code)
+	}
+
+	token := resetToken(t, s, tenantID, email)
+	rec := serve(s, http.MethodPost, "/v1/auth/reset-password", tenantID, ResetPasswordRequest{Token: token, Password: "Staple-Battery-Horse-43"})
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("reset: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	if code := login("Correct-Horse-Battery-42"); code != http.StatusUnauthorized {
+		t.Errorf("login with the old password: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := login("Staple-Battery-Horse-43"); code != http.StatusOK {
+		t.Errorf("login with the new password: status = %d, want %d", code, http.StatusOK)
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// FieldErrors maps request fields to validation messages
+type FieldErrors map[string]string
+
+// normalizeEmail trims and lowercases an email so that addresses differing
+// only in case or surrounding whitespace refer to the same account
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validateRegistration checks a normalized registration request against the
+// RegisterRequest tags and the configured password policy, or returns nil
+func (s *Service) validateRegistration(req *RegisterRequest) FieldErrors {
+	errs := FieldErrors{}
+	for field, msg := range platformhttp.Validate(req) {
+		errs[field] = msg
+	}
+
+	if _, ok := errs["password"]; !ok {
+		if msg := s.checkPasswordPolicy(req.Password); msg != "" {
+			errs["password"] = msg
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// checkPasswordPolicy returns why a password breaks the policy, or ""
+func (s *Service) checkPasswordPolicy(password string) string {
+	policy := s.config.Security.Password
+	if password == "" {
+		return "is required"
+	}
+
+	minLength := policy.MinLength
+	if minLength < 1 {
+		minLength = 1
+	}
+	if len([]rune(password)) < minLength {
+		return fmt.Sprintf("must be at least %d characters", minLength)
+	}
+	if len([]byte(password)) > bcryptMaxPasswordBytes && !policy.PrehashLongPasswords {
+		return fmt.Sprintf("must be at most %d bytes", bcryptMaxPasswordBytes)
+	}
+
+	var upper, lower, digit bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		}
+	}
+	if policy.RequireMixedCase && !(upper && lower) {
+		return "must contain both upper and lower case letters"
+	}
+	if policy.RequireDigit && !digit {
+		return "must contain a digit"
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// withPasswordPolicy sets the password policy for new passwords
+func withPasswordPolicy(minLength int, mixedCase, digit bool) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Security.Password.MinLength = minLength
+		cfg.Security.Password.RequireMixedCase = mixedCase
+		cfg.Security.Password.RequireDigit = digit
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	for _, email := range []string{"user@x.com", "User@X.com", "  USER@x.COM\t"} {
+		if got := normalizeEmail(email); got != "user@x.com" {
+			t.Errorf("normalizeEmail(%q) = %q, want user@x.com", email, got)
+		}
+	}
+}
+
+func TestValidateRegistration(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   func(*config.Config)
+		email    string
+		password string
+		want     FieldErrors
+	}{
+		{"valid", withPasswordPolicy(8, true, true), "jane@example.com", "Secret-42", nil},
+		{"missing email", withPasswordPolicy(8, false, false), "", "secret-password", FieldErrors{"email": ""}},
+		{"malformed email", withPasswordPolicy(8, false, false), "jane", "secret-password", FieldErrors{"email": ""}},
+		{"missing password", withPasswordPolicy(8, false, false), "jane@example.com", "", FieldErrors{"password": ""}},
+		{"too short", withPasswordPolicy(12, false, false), "jane@example.com", "Secret-4242", FieldErrors{"password": "at least 12"}},
+		{"length counts characters", withPasswordPolicy(8, false, false), "jane@example.com", "ééééééé", FieldErrors{"password": "at least 8"}},
+		{"no upper case", withPasswordPolicy(8, true, false), "jane@example.com", "secret-42", FieldErrors{"password": "upper and lower"}},
+		{"no lower case", withPasswordPolicy(8, true, false), "jane@example.com", "SECRET-42", FieldErrors{"password": "upper and lower"}},
+		{"no digit", withPasswordPolicy(8, false, true), "jane@example.com", "Secret-password", FieldErrors{"password": "digit"}},
+		{"both fields", withPasswordPolicy(8, false, false), "jane", "short", FieldErrors{"email": "", "password": "at least 8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, tt.policy)
+
+			got := s.validateRegistration(&RegisterRequest{Email: tt.email, Password: tt.password})
+			if len(got) != len(tt.want) {
+				t.Fatalf("validateRegistration = %v, want errors for %v", got, tt.want)
+			}
+			for field, contains := range tt.want {
+				if msg, ok := got[field]; !ok || !strings.Contains(msg, contains) {
+					t.Errorf("%s error = %q, want one mentioning %q", field, msg, contains)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterReturnsFieldErrors(t *testing.T) {
+	s := newTestService(t, withPasswordPolicy(8, true, true))
+
+	rec := serve(s, http.MethodPost, "/v1/auth/register", "acme", RegisterRequest{Email: "not-an-email", Password: "password"})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+	}
+	var body platformhttp.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Fields["email"] == "" || body.Fields["password"] == "" {
+		t.Fatalf("fields = %v, want errors for email and password", body.Fields)
+	}
+}
+
+func TestRegisterDetectsDuplicatesAcrossCase(t *testing.T) {
+	s := newTestService(t)
+	tenantID := withTestDB(t, s)
+	local := "dup-" + uuid.New().String()[:8]
+	password := "Correct-Horse-Battery-42"
+
+	register(t, s, tenantID, "  "+strings.ToUpper(local)+"@Example.com ", password)
+
+	for _, email := range []string{local + "@example.com", strings.ToUpper(local) + "@EXAMPLE.COM"} {
+		rec := serve(s, http.MethodPost, "/v1/auth/register", tenantID, RegisterRequest{Email: email, Password: password})
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("register %s: status = %d, want %d", email, rec.Code, http.StatusConflict)
+		}
+	}
+
+	// The address was stored normalized, so any casing signs in
+	if resp := login(t, s, tenantID, strings.ToUpper(local)+"@example.COM", password); resp.User.Email != local+"@example.com" {
+		t.Fatalf("user email = %q, want the normalized address", resp.User.Email)
+	}
+
+	// Rows written around the normalization still collide on the index
+	err := s.db.Exec(context.Background(), `
+		INSERT INTO users (id, tenant_id, email, password_hash) VALUES ($1, $2, $3, 'x')`,
+		uuid.New().String(), tenantID, strings.ToUpper(local)+"@EXAMPLE.com")
+	if err == nil {
+		t.Fatal("inserted an email differing only in case")
+	}
+}
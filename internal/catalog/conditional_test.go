@@ -0,0 +1,55 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+)
+
+func TestBenefitReadsAreConditional(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantID := databasetest.Tenant(t)
+	ctx := auth.WithTenant(context.Background(), tenantID)
+	admin := adminToken(t, s, tenantID)
+	if rec := serve(s, http.MethodPost, "/v1/partners", admin, RegisterNameRequest{Name: "GIFTCO"}); rec.Code != http.StatusCreated {
+		t.Fatalf("register partner: status = %d: %s", rec.Code, rec.Body)
+	}
+	benefit := createTestBenefit(t, ctx, s)
+
+	for _, path := range []string{"/v1/benefits/" + benefit.ID, "/v1/benefits"} {
+		t.Run(path, func(t *testing.T) {
+			rec := serve(s, http.MethodGet, path, "", nil, auth.TenantHeader, tenantID)
+			etag := rec.Header().Get("ETag")
+			if rec.Code != http.StatusOK || etag == "" {
+				t.Fatalf("status = %d, ETag %q; want 200 with an ETag", rec.Code, etag)
+			}
+			if cc := rec.Header().Get("Cache-Control"); cc == "" {
+				t.Fatal("no Cache-Control header")
+			}
+
+			// A matching ETag gets an empty 304
+			rec = serve(s, http.MethodGet, path, "", nil, auth.TenantHeader, tenantID, "If-None-Match", etag)
+			if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+				t.Fatalf("revalidation: status = %d, %d byte body; want an empty 304", rec.Code, rec.Body.Len())
+			}
+
+			// Once the benefit changes the old ETag no longer matches
+			points := benefit.Points + 1
+			benefit.Points = points
+			if rec := serve(s, http.MethodPut, "/v1/benefits/"+benefit.ID, admin, UpdateBenefitRequest{Points: &points}); rec.Code != http.StatusOK {
+				t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+			}
+			rec = serve(s, http.MethodGet, path, "", nil, auth.TenantHeader, tenantID, "If-None-Match", etag)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("after update: status = %d, want 200", rec.Code)
+			}
+			if newETag := rec.Header().Get("ETag"); newETag == "" || newETag == etag {
+				t.Fatalf("ETag after update = %q, want a new one", newETag)
+			}
+		})
+	}
+}
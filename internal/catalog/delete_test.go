@@ -0,0 +1,150 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+)
+
+func TestIncludeDeletedRequiresAdmin(t *testing.T) {
+	s := newTestService(t)
+	user, err := s.jwtManager.GenerateToken(uuid.New().String(), "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name, query, tok string
+		want             int
+	}{
+		{"anonymous", "include_deleted=true", "", http.StatusUnauthorized},
+		{"user", "include_deleted=true", user, http.StatusForbidden},
+		{"admin", "include_deleted=true", adminToken(t, s, ""), http.StatusOK},
+		{"not requested", "include_deleted=false", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(s, http.MethodGet, "/v1/benefits?"+tt.query, tt.tok, nil); rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	if rec := serve(s, http.MethodGet, "/v1/benefits/"+uuid.New().String()+"?include_deleted=true", user, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("user get with include_deleted: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := serve(s, http.MethodPost, "/v1/benefits/"+uuid.New().String()+"/restore", user, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("user restore: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantID := databasetest.Tenant(t)
+	ctx := auth.WithTenant(context.Background(), tenantID)
+	admin := adminToken(t, s, tenantID)
+	benefit := createTestBenefit(t, ctx, s)
+
+	listed := func(query, tok string) bool {
+		t.Helper()
+
+		rec := serve(s, http.MethodGet, "/v1/benefits?"+query, tok, nil, auth.TenantHeader, tenantID)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("benefits?%s: status = %d: %s", query, rec.Code, rec.Body)
+		}
+		var list BenefitListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+			t.Fatalf("decode list: %v", err)
+		}
+		for _, b := range list.Benefits {
+			if b.ID == benefit.ID {
+				return true
+			}
+		}
+		return false
+	}
+	get := func() Benefit {
+		t.Helper()
+
+		rec := serve(s, http.MethodGet, "/v1/benefits/"+benefit.ID+"?include_deleted=true", admin, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("get: status = %d: %s", rec.Code, rec.Body)
+		}
+		var b Benefit
+		if err := json.NewDecoder(rec.Body).Decode(&b); err != nil {
+			t.Fatalf("decode benefit: %v", err)
+		}
+		return b
+	}
+
+	if !listed("", "") {
+		t.Fatal("new benefit is not listed")
+	}
+
+	// Deleting hides the benefit from lists and lookups but keeps the row, so
+	// redemptions that reference it stay valid
+	if rec := serve(s, http.MethodDelete, "/v1/benefits/"+benefit.ID, admin, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d: %s", rec.Code, rec.Body)
+	}
+	if listed("", "") {
+		t.Fatal("deleted benefit is still listed")
+	}
+	if !listed("include_deleted=true", admin) {
+		t.Fatal("deleted benefit is missing with include_deleted=true")
+	}
+	deleted := get()
+	if deleted.DeletedAt == nil {
+		t.Fatal("deleted benefit has no deleted_at")
+	}
+
+	// The admin lookup cached the deleted benefit, which others still cannot see
+	if rec := serve(s, http.MethodGet, "/v1/benefits/"+benefit.ID, "", nil, auth.TenantHeader, tenantID); rec.Code != http.StatusNotFound {
+		t.Fatalf("anonymous get of deleted benefit: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// A deleted benefit cannot be updated until it is restored
+	rename := map[string]string{"name": "Renamed Gift Card"}
+	if rec := serve(s, http.MethodPut, "/v1/benefits/"+benefit.ID, admin, rename); rec.Code != http.StatusConflict {
+		t.Fatalf("update deleted benefit: status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+
+	// Deleting again keeps the original deletion time
+	if rec := serve(s, http.MethodDelete, "/v1/benefits/"+benefit.ID, admin, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("second delete: status = %d: %s", rec.Code, rec.Body)
+	}
+	if again := get(); again.DeletedAt == nil || !again.DeletedAt.Equal(*deleted.DeletedAt) {
+		t.Fatalf("deleted_at after a second delete = %v, want %v", again.DeletedAt, deleted.DeletedAt)
+	}
+
+	rec := serve(s, http.MethodPost, "/v1/benefits/"+benefit.ID+"/restore", admin, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("restore: status = %d: %s", rec.Code, rec.Body)
+	}
+	var restored Benefit
+	if err := json.NewDecoder(rec.Body).Decode(&restored); err != nil {
+		t.Fatalf("decode restored benefit: %v", err)
+	}
+	if restored.DeletedAt != nil || !listed("", "") {
+		t.Fatal("restored benefit is still deleted")
+	}
+	if rec := serve(s, http.MethodGet, "/v1/benefits/"+benefit.ID, "", nil, auth.TenantHeader, tenantID); rec.Code != http.StatusOK {
+		t.Fatalf("anonymous get of restored benefit: status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(s, http.MethodPut, "/v1/benefits/"+benefit.ID, admin, rename); rec.Code != http.StatusOK {
+		t.Fatalf("update restored benefit: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	missing := "/v1/benefits/" + uuid.New().String()
+	if rec := serve(s, http.MethodDelete, missing, admin, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("delete unknown: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := serve(s, http.MethodPost, missing+"/restore", admin, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("restore unknown: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
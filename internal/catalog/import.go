@@ -0,0 +1,357 @@
+package catalog
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/jsonutil"
+)
+
+// Import row statuses
+const (
+	ImportRowCreated = "created"
+	ImportRowUpdated = "updated"
+	ImportRowFailed  = "failed"
+)
+
+// importLockKey namespaces the advisory lock that serializes imports per
+// tenant, so concurrent imports of the same sheet cannot both create a benefit
+const importLockKey = "catalog.benefit_import:"
+
+// csvColumns are the columns a CSV import may have, matching the JSON fields
+// of CreateBenefitRequest
+var csvColumns = []string{"name", "description", "points", "partner", "category", "active", "starts_at", "ends_at"}
+
+// BenefitImportRow is the outcome of one imported benefit
+type BenefitImportRow struct {
+	// Index is the row's position in the request, not counting a CSV header
+	Index   int                         `json:"index"`
+	Name    string                      `json:"name"`
+	Partner string                      `json:"partner"`
+	Status  string                      `json:"status"`
+	ID      string                      `json:"id,omitempty"`
+	Error   *platformhttp.ErrorResponse `json:"error,omitempty"`
+}
+
+// BenefitImportResult summarizes an import
+type BenefitImportResult struct {
+	Created int                 `json:"created"`
+	Updated int                 `json:"updated"`
+	Failed  int                 `json:"failed"`
+	Rows    []*BenefitImportRow `json:"rows"`
+}
+
+// importRow is a decoded benefit definition, with any fields that could not
+// be parsed from CSV
+type importRow struct {
+	req    CreateBenefitRequest
+	fields map[string]string
+}
+
+// ImportBenefits creates benefits from a JSON array of benefit definitions,
+// or from CSV with a header row when the body is text/csv. A benefit with the
+// same name (ignoring case) and partner as an existing one updates it
+// instead. Each row succeeds or fails on its own; the valid rows are written
+// in one transaction.
+func (s *Service) ImportBenefits(w http.ResponseWriter, r *http.Request) {
+	var rows []importRow
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		rows, err = decodeImportCSV(r.Body)
+	} else {
+		rows, err = decodeImportJSON(r.Body)
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+	if err != nil {
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Invalid import body: "+err.Error())
+		return
+	}
+
+	if len(rows) == 0 {
+		platformhttp.ValidationError(w, r, map[string]string{"benefits": "must contain at least one benefit"})
+		return
+	}
+	if limit := s.config.Catalog.MaxImportRows; limit > 0 && len(rows) > limit {
+		platformhttp.ValidationError(w, r, map[string]string{"benefits": fmt.Sprintf("must contain at most %d benefits", limit)})
+		return
+	}
+
+	allowPast, _ := strconv.ParseBool(r.URL.Query().Get("allow_past"))
+
+	result, err := s.importBenefits(r.Context(), rows, !allowPast)
+	if err != nil {
+		s.logger.Errorf("Failed to import %d benefits: %v", len(rows), err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to import benefits")
+		return
+	}
+
+	render.JSON(w, r, result)
+}
+
+func decodeImportJSON(body io.Reader) ([]importRow, error) {
+	var reqs []CreateBenefitRequest
+	if err := jsonutil.DecodeStrict(body, &reqs); err != nil {
+		return nil, err
+	}
+	rows := make([]importRow, len(reqs))
+	for i, req := range reqs {
+		rows[i] = importRow{req: req}
+	}
+	return rows, nil
+}
+
+// decodeImportCSV reads benefit definitions from CSV. The header names the
+// columns, in any order; values that do not parse are reported against their
+// row rather than failing the import.
+func decodeImportCSV(body io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !containsColumn(name) {
+			return nil, fmt.Errorf("unknown column %q, expected %s", name, strings.Join(csvColumns, ", "))
+		}
+		columns[name] = i
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := importRow{fields: map[string]string{}}
+		value := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row.req.Name = value("name")
+		row.req.Description = value("description")
+		row.req.Partner = value("partner")
+		row.req.Category = value("category")
+		if v := value("points"); v != "" {
+			if row.req.Points, err = strconv.Atoi(v); err != nil {
+				row.fields["points"] = "must be a whole number"
+			}
+		}
+		if v := value("active"); v != "" {
+			if row.req.Active, err = strconv.ParseBool(v); err != nil {
+				row.fields["active"] = "must be true or false"
+			}
+		}
+		row.req.StartsAt = parseImportTime(value("starts_at"), "starts_at", row.fields)
+		row.req.EndsAt = parseImportTime(value("ends_at"), "ends_at", row.fields)
+		rows = append(rows, row)
+	}
+}
+
+func parseImportTime(value, field string, fields map[string]string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		fields[field] = "must be an RFC3339 time"
+		return nil
+	}
+	return &t
+}
+
+func containsColumn(name string) bool {
+	for _, column := range csvColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// importBenefits validates every row, then writes the valid ones in one
+// transaction. An error is returned only when the import itself could not be
+// processed, in which case nothing is written.
+func (s *Service) importBenefits(ctx context.Context, rows []importRow, rejectEnded bool) (*BenefitImportResult, error) {
+	actor := authmw.Actor(ctx)
+	now := time.Now()
+
+	result := &BenefitImportResult{Rows: make([]*BenefitImportRow, len(rows))}
+	benefits := make([]*Benefit, len(rows))
+	seen := make(map[string]int)
+	for i := range rows {
+		req := &rows[i].req
+		rowResult := &BenefitImportRow{Index: i, Name: req.Name, Partner: req.Partner}
+		result.Rows[i] = rowResult
+
+		fields := platformhttp.Validate(req)
+		for field, message := range rows[i].fields {
+			if fields == nil {
+				fields = platformhttp.FieldErrors{}
+			}
+			fields[field] = message
+		}
+		if fields != nil {
+			rowResult.fail(platformhttp.ErrCodeValidationFailed, "Validation failed", fields)
+			continue
+		}
+
+		benefit := &Benefit{
+			ID:          uuid.New().String(),
+			Name:        req.Name,
+			Description: req.Description,
+			Points:      req.Points,
+			Partner:     req.Partner,
+			Category:    req.Category,
+			Active:      req.Active,
+			StartsAt:    req.StartsAt,
+			EndsAt:      req.EndsAt,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			CreatedBy:   actor,
+			UpdatedBy:   actor,
+		}
+		normalizeBenefitTimes(benefit)
+		errs, err := s.validateBenefit(ctx, benefit, rejectEnded, now)
+		if err != nil {
+			return nil, err
+		}
+		if errs != nil {
+			rowResult.fail(platformhttp.ErrCodeValidationFailed, "Validation failed", errs)
+			continue
+		}
+
+		key := strings.ToLower(benefit.Name) + "\x00" + benefit.Partner
+		if first, ok := seen[key]; ok {
+			rowResult.fail(platformhttp.ErrCodeConflict, fmt.Sprintf("Duplicates row %d", first), nil)
+			continue
+		}
+		seen[key] = i
+		benefits[i] = benefit
+	}
+
+	if err := s.saveImportedBenefits(ctx, benefits, result); err != nil {
+		return nil, err
+	}
+
+	for _, row := range result.Rows {
+		switch row.Status {
+		case ImportRowCreated:
+			result.Created++
+		case ImportRowUpdated:
+			result.Updated++
+			s.cache.Delete(cacheBenefits, benefitCacheKey(ctx, row.ID))
+		case ImportRowFailed:
+			result.Failed++
+		}
+	}
+	if result.Created+result.Updated > 0 {
+		s.cache.InvalidateNamespace(cacheBenefitLists)
+	}
+	s.logger.Infof("Imported benefits by %s: %d created, %d updated, %d failed", actor, result.Created, result.Updated, result.Failed)
+	return result, nil
+}
+
+// saveImportedBenefits creates or updates the non-nil benefits in one
+// transaction and records each outcome in result.Rows
+func (s *Service) saveImportedBenefits(ctx context.Context, benefits []*Benefit, result *BenefitImportResult) error {
+	if s.db == nil {
+		for i, benefit := range benefits {
+			if benefit != nil {
+				s.logger.Infof("Would import benefit: %+v", benefit)
+				result.Rows[i].Status = ImportRowCreated
+				result.Rows[i].ID = benefit.ID
+			}
+		}
+		return nil
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tenantID := auth.TenantFromContext(ctx)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, importLockKey+tenantID); err != nil {
+		return fmt.Errorf("failed to lock benefit import: %w", err)
+	}
+
+	for i, benefit := range benefits {
+		if benefit == nil {
+			continue
+		}
+		created, err := upsertBenefit(ctx, tx, benefit)
+		if err != nil {
+			return fmt.Errorf("failed to import row %d: %w", i, err)
+		}
+		result.Rows[i].ID = benefit.ID
+		result.Rows[i].Status = ImportRowUpdated
+		if created {
+			result.Rows[i].Status = ImportRowCreated
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// upsertBenefit updates the benefit with the same name (ignoring case) and
+// partner, keeping its ID and creation, or creates it. It reports whether
+// the benefit was created.
+func upsertBenefit(ctx context.Context, tx pgx.Tx, benefit *Benefit) (bool, error) {
+	var existingID string
+	err := tx.QueryRow(ctx, `
+		SELECT id FROM benefits
+		WHERE tenant_id = $1 AND LOWER(name) = LOWER($2) AND partner = $3
+		ORDER BY deleted_at IS NULL DESC, created_at
+		LIMIT 1
+		FOR UPDATE
+	`, auth.TenantFromContext(ctx), benefit.Name, benefit.Partner).Scan(&existingID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		_, err := tx.Exec(ctx, insertBenefitQuery, insertBenefitArgs(ctx, benefit)...)
+		return true, err
+	case err != nil:
+		return false, err
+	}
+
+	benefit.ID = existingID
+	_, err = tx.Exec(ctx, updateBenefitQuery, updateBenefitArgs(ctx, benefit)...)
+	return false, err
+}
+
+func (r *BenefitImportRow) fail(code platformhttp.ErrorCode, message string, fields map[string]string) {
+	r.Status = ImportRowFailed
+	r.Error = &platformhttp.ErrorResponse{Code: code, Message: message, Fields: fields}
+}
@@ -0,0 +1,246 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// postImport posts body to the import endpoint as admin, returning the
+// status and the decoded report
+func postImport(t *testing.T, s *Service, admin string, body interface{}) (int, *BenefitImportResult) {
+	t.Helper()
+
+	rec := serve(s, http.MethodPost, "/v1/benefits/import", admin, body)
+	return rec.Code, decodeImportResult(t, rec)
+}
+
+// importCSV posts a CSV body to the import endpoint as admin
+func importCSV(t *testing.T, s *Service, admin, body string) (int, *BenefitImportResult) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/benefits/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+admin)
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code, decodeImportResult(t, rec)
+}
+
+func decodeImportResult(t *testing.T, rec *httptest.ResponseRecorder) *BenefitImportResult {
+	t.Helper()
+
+	if rec.Code != http.StatusOK {
+		return nil
+	}
+	var result BenefitImportResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode import result: %v", err)
+	}
+	return &result
+}
+
+// rowStatuses lists the status of each row in result
+func rowStatuses(result *BenefitImportResult) []string {
+	statuses := make([]string, len(result.Rows))
+	for i, row := range result.Rows {
+		statuses[i] = row.Status
+	}
+	return statuses
+}
+
+func TestImportBenefitsReportsEachRow(t *testing.T) {
+	s := newTestService(t)
+	admin := adminToken(t, s, "")
+
+	status, result := postImport(t, s, admin, []CreateBenefitRequest{
+		{Name: "Gift card", Points: 2000, Partner: "GIFTCO", Category: "Retail", Active: true},
+		{Name: "No points", Partner: "GIFTCO"},
+		{Name: "Unknown partner", Points: 100, Partner: "NOBODY"},
+		{Name: "GIFT CARD", Points: 1500, Partner: "GIFTCO"},
+		{Name: "Flight voucher", Points: 5000, Partner: "TRAVELCO", Category: "Travel"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+
+	want := []string{ImportRowCreated, ImportRowFailed, ImportRowFailed, ImportRowFailed, ImportRowCreated}
+	if got := rowStatuses(result); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("row statuses = %v, want %v", got, want)
+	}
+	if result.Created != 2 || result.Updated != 0 || result.Failed != 3 {
+		t.Fatalf("summary = %d created, %d updated, %d failed; want 2, 0, 3", result.Created, result.Updated, result.Failed)
+	}
+	for i, row := range result.Rows {
+		if row.Index != i {
+			t.Errorf("row %d has index %d", i, row.Index)
+		}
+		if (row.Status == ImportRowCreated) != (row.ID != "") {
+			t.Errorf("row %d: status %s with ID %q", i, row.Status, row.ID)
+		}
+	}
+
+	tests := []struct {
+		row   int
+		code  platformhttp.ErrorCode
+		field string
+	}{
+		{1, platformhttp.ErrCodeValidationFailed, "points"},
+		{2, platformhttp.ErrCodeValidationFailed, "partner"},
+		{3, platformhttp.ErrCodeConflict, ""},
+	}
+	for _, tt := range tests {
+		rowErr := result.Rows[tt.row].Error
+		if rowErr == nil || rowErr.Code != tt.code {
+			t.Errorf("row %d error = %+v, want %s", tt.row, rowErr, tt.code)
+			continue
+		}
+		if _, ok := rowErr.Fields[tt.field]; tt.field != "" && !ok {
+			t.Errorf("row %d fields = %v, want one for %s", tt.row, rowErr.Fields, tt.field)
+		}
+	}
+}
+
+func TestImportBenefitsCSV(t *testing.T) {
+	s := newTestService(t)
+	admin := adminToken(t, s, "")
+
+	// Columns may come in any order; bad values fail only their row
+	status, result := importCSV(t, s, admin, strings.Join([]string{
+		"partner, Name, points, active, ends_at",
+		"GIFTCO, Gift card, 2000, true,",
+		"GIFTCO, Movie night, lots, true,",
+		"GIFTCO, Spa day, 800, maybe, tomorrow",
+	}, "\n"))
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	want := []string{ImportRowCreated, ImportRowFailed, ImportRowFailed}
+	if got := rowStatuses(result); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("row statuses = %v, want %v", got, want)
+	}
+	if row := result.Rows[0]; row.Name != "Gift card" || row.Partner != "GIFTCO" {
+		t.Fatalf("row 0 = %+v, want the gift card from GIFTCO", row)
+	}
+	if fields := result.Rows[1].Error.Fields; fields["points"] != "must be a whole number" {
+		t.Fatalf("row 1 fields = %v, want points reported", fields)
+	}
+	if fields := result.Rows[2].Error.Fields; fields["active"] == "" || fields["ends_at"] == "" {
+		t.Fatalf("row 2 fields = %v, want active and ends_at reported", fields)
+	}
+
+	if status, _ := importCSV(t, s, admin, "name,colour\nGift card,red\n"); status != http.StatusBadRequest {
+		t.Fatalf("unknown column: status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestImportBenefitsRejectsRequest(t *testing.T) {
+	s := newTestService(t, func(cfg *config.Config) { cfg.Catalog.MaxImportRows = 2 })
+	admin := adminToken(t, s, "")
+	user, err := s.jwtManager.GenerateToken("user-1", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	valid := CreateBenefitRequest{Name: "Gift card", Points: 2000, Partner: "GIFTCO"}
+
+	tests := []struct {
+		name string
+		tok  string
+		body interface{}
+		want int
+	}{
+		{"user", user, []CreateBenefitRequest{valid}, http.StatusForbidden},
+		{"not an array", admin, valid, http.StatusBadRequest},
+		{"empty", admin, []CreateBenefitRequest{}, http.StatusUnprocessableEntity},
+		{"too many rows", admin, []CreateBenefitRequest{valid, valid, valid}, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, _ := postImport(t, s, tt.tok, tt.body); status != tt.want {
+				t.Fatalf("status = %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportBenefitsUpserts(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantID := databasetest.Tenant(t)
+	admin := adminToken(t, s, tenantID)
+	if rec := serve(s, http.MethodPost, "/v1/partners", admin, RegisterNameRequest{Name: "GIFTCO"}); rec.Code != http.StatusCreated {
+		t.Fatalf("register partner: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	status, first := postImport(t, s, admin, []CreateBenefitRequest{
+		{Name: "Gift card", Points: 2000, Partner: "GIFTCO", Active: true},
+		{Name: "Movie night", Points: 1500, Partner: "GIFTCO", Active: true},
+	})
+	if status != http.StatusOK || first.Created != 2 {
+		t.Fatalf("first import: status %d, result %+v; want 2 created", status, first)
+	}
+
+	// Re-importing the sheet updates the benefits with the same name and
+	// partner, whatever the name's case, instead of creating copies
+	status, second := postImport(t, s, admin, []CreateBenefitRequest{
+		{Name: "GIFT CARD", Points: 2500, Partner: "GIFTCO", Active: true},
+		{Name: "Spa day", Points: 800, Partner: "GIFTCO", Active: true},
+	})
+	if status != http.StatusOK || second.Created != 1 || second.Updated != 1 {
+		t.Fatalf("second import: status %d, result %+v; want 1 created, 1 updated", status, second)
+	}
+	if second.Rows[0].Status != ImportRowUpdated || second.Rows[0].ID != first.Rows[0].ID {
+		t.Fatalf("re-imported row = %+v, want an update of %s", second.Rows[0], first.Rows[0].ID)
+	}
+
+	rec := serve(s, http.MethodGet, "/v1/benefits/"+first.Rows[0].ID, "", nil, auth.TenantHeader, tenantID)
+	var updated Benefit
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil {
+		t.Fatalf("decode benefit: %v", err)
+	}
+	if updated.Points != 2500 || updated.Name != "GIFT CARD" {
+		t.Fatalf("updated benefit = %+v, want the re-imported values", updated)
+	}
+	if total := listBenefits(t, s, tenantID, "").Total; total != 3 {
+		t.Fatalf("catalog has %d benefits, want 3", total)
+	}
+}
+
+func TestImportBenefitsRollsBackOnDatabaseFailure(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantID := databasetest.Tenant(t)
+	ctx := auth.WithTenant(context.Background(), tenantID)
+	admin := adminToken(t, s, tenantID)
+	if rec := serve(s, http.MethodPost, "/v1/partners", admin, RegisterNameRequest{Name: "GIFTCO"}); rec.Code != http.StatusCreated {
+		t.Fatalf("register partner: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// The second name passes validation but is too long for its column
+	status, _ := postImport(t, s, admin, []CreateBenefitRequest{
+		{Name: "Gift card", Points: 2000, Partner: "GIFTCO"},
+		{Name: strings.Repeat("x", 300), Points: 100, Partner: "GIFTCO"},
+	})
+	if status != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", status, http.StatusInternalServerError)
+	}
+
+	var count int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM benefits WHERE tenant_id = $1`, tenantID).Scan(&count); err != nil {
+		t.Fatalf("failed to count benefits: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("%d benefits were written by a failed import, want none", count)
+	}
+}
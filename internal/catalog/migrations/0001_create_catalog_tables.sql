@@ -0,0 +1,54 @@
+-- Benefits and the categories and partners they may reference
+
+CREATE TABLE IF NOT EXISTS benefits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    points INTEGER NOT NULL,
+    partner VARCHAR(100) NOT NULL,
+    category VARCHAR(100),
+    active BOOLEAN NOT NULL DEFAULT true,
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    image_url VARCHAR(500),
+    terms_conditions TEXT,
+    created_by VARCHAR(255),
+    updated_by VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS benefit_categories (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    name VARCHAR(100) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS benefit_partners (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    name VARCHAR(100) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_benefits_tenant_active ON benefits(tenant_id, active);
+CREATE INDEX IF NOT EXISTS idx_benefits_category ON benefits(category);
+CREATE INDEX IF NOT EXISTS idx_benefits_partner ON benefits(partner);
+CREATE INDEX IF NOT EXISTS idx_benefits_tenant_created ON benefits(tenant_id, created_at DESC);
+
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE OR REPLACE TRIGGER update_benefits_updated_at BEFORE UPDATE ON benefits
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
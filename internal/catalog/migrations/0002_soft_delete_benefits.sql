@@ -0,0 +1,7 @@
+-- Soft-deleted benefits keep their row, so redemptions that reference them
+-- stay valid and the benefit can be restored
+
+ALTER TABLE benefits ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_benefits_tenant_live_created ON benefits(tenant_id, created_at DESC)
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,107 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+)
+
+// listBenefits fetches a benefit list in tenantID
+func listBenefits(t *testing.T, s *Service, tenantID, query string) BenefitListResponse {
+	t.Helper()
+
+	rec := serve(s, http.MethodGet, "/v1/benefits?"+query, "", nil, auth.TenantHeader, tenantID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("benefits?%s: status = %d: %s", query, rec.Code, rec.Body)
+	}
+	var list BenefitListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	return list
+}
+
+func TestListBenefitsRejectsInvalidCursor(t *testing.T) {
+	s := newTestService(t)
+
+	rec := serve(s, http.MethodGet, "/v1/benefits?cursor=not-a-cursor", "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestListBenefitsCursorPaging(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantID := databasetest.Tenant(t)
+	ctx := auth.WithTenant(context.Background(), tenantID)
+	admin := adminToken(t, s, tenantID)
+	if rec := serve(s, http.MethodPost, "/v1/partners", admin, RegisterNameRequest{Name: "GIFTCO"}); rec.Code != http.StatusCreated {
+		t.Fatalf("register partner: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// Seven benefits, three sharing a creation time so the ID breaks the tie
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	createdAt := []time.Duration{0, time.Minute, time.Minute, time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute}
+	seeded := make(map[string]bool)
+	for _, offset := range createdAt {
+		benefit := createTestBenefit(t, ctx, s)
+		if err := s.db.Exec(ctx, `UPDATE benefits SET created_at = $1 WHERE id = $2`, start.Add(offset), benefit.ID); err != nil {
+			t.Fatalf("failed to backdate benefit: %v", err)
+		}
+		seeded[benefit.ID] = true
+	}
+
+	seen := make(map[string]int)
+	cursor := ""
+	for page := 0; ; page++ {
+		if page > len(createdAt) {
+			t.Fatal("paging did not end")
+		}
+		list := listBenefits(t, s, tenantID, "limit=3&cursor="+url.QueryEscape(cursor))
+		if list.Page != 0 {
+			t.Fatalf("page %d: page number %d in cursor mode", page, list.Page)
+		}
+		for i, benefit := range list.Benefits {
+			seen[benefit.ID]++
+			if i > 0 {
+				prev := list.Benefits[i-1]
+				if benefit.CreatedAt.After(prev.CreatedAt) || (benefit.CreatedAt.Equal(prev.CreatedAt) && benefit.ID < prev.ID) {
+					t.Fatalf("page %d is not ordered newest first with ID tiebreaks", page)
+				}
+			}
+		}
+
+		// Benefits created while paging land ahead of the cursor, so they
+		// neither push seen rows onto later pages nor are returned
+		rec := serve(s, http.MethodPost, "/v1/benefits", admin, CreateBenefitRequest{Name: "New benefit", Points: 100, Partner: "GIFTCO", Active: true})
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create during paging: status = %d: %s", rec.Code, rec.Body)
+		}
+
+		if list.NextCursor == "" {
+			break
+		}
+		cursor = list.NextCursor
+	}
+
+	for id := range seeded {
+		if seen[id] != 1 {
+			t.Errorf("benefit %s listed %d times, want once", id, seen[id])
+		}
+	}
+	if len(seen) != len(seeded) {
+		t.Errorf("listed %d benefits, want the %d that existed when paging began", len(seen), len(seeded))
+	}
+
+	// Offset pages are still served without a cursor parameter
+	if list := listBenefits(t, s, tenantID, "page=2&limit=3"); list.Page != 2 || list.NextCursor != "" || len(list.Benefits) != 3 {
+		t.Fatalf("offset page = %+v, want page 2 of 3 benefits without a cursor", list)
+	}
+}
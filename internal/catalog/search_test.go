@@ -0,0 +1,108 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+)
+
+func TestBenefitListFilterSearchesEveryWord(t *testing.T) {
+	where, args := benefitListFilter(auth.WithTenant(context.Background(), "acme"), benefitFilter{Partner: "GIFTCO", Query: "gift  100%_off"})
+
+	if strings.Count(where, "ILIKE") != 4 {
+		t.Fatalf("where = %q, want name and description matched for each of 2 words", where)
+	}
+	want := []interface{}{"acme", "GIFTCO", "%gift%", `%100\%\_off%`}
+	if len(args) != len(want) {
+		t.Fatalf("args = %q, want %q", args, want)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Fatalf("args = %q, want %q", args, want)
+		}
+	}
+}
+
+func TestListBenefitsRejectsLongQuery(t *testing.T) {
+	s := newTestService(t)
+
+	rec := serve(s, http.MethodGet, "/v1/benefits?q="+strings.Repeat("a", maxSearchLength+1), "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestListBenefitsSearch(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantID := databasetest.Tenant(t)
+	ctx := auth.WithTenant(context.Background(), tenantID)
+
+	// Older benefits are saved first, so rank rather than age decides order
+	ids := make(map[string]string)
+	start := time.Now().Add(-time.Hour)
+	for i, b := range []struct{ name, description, partner string }{
+		{"Dinner and a movie", "Gift card for two", "GIFTCO"},
+		{"Movie gift card", "", "GIFTCO"},
+		{"Gift card", "Spend anywhere", "GIFTCO"},
+		{"Gift card bundle", "", "TRAVELCO"},
+		{"100% off_peak", "", "GIFTCO"},
+		{"Spa day", "Relax", "GIFTCO"},
+	} {
+		benefit := &Benefit{
+			ID:          uuid.New().String(),
+			Name:        b.name,
+			Description: b.description,
+			Points:      500,
+			Partner:     b.partner,
+			Active:      true,
+			CreatedAt:   start.Add(time.Duration(i) * time.Minute),
+			UpdatedAt:   start,
+		}
+		if err := s.saveBenefit(ctx, benefit); err != nil {
+			t.Fatalf("failed to save benefit: %v", err)
+		}
+		ids[b.name] = benefit.ID
+	}
+
+	tests := []struct {
+		name, query string
+		want        []string
+	}{
+		{"ranked", "q=gift+card", []string{"Gift card", "Gift card bundle", "Movie gift card", "Dinner and a movie"}},
+		{"case insensitive", "q=GIFT", []string{"Gift card bundle", "Gift card", "Movie gift card", "Dinner and a movie"}},
+		{"every word must match", "q=movie+gift", []string{"Movie gift card", "Dinner and a movie"}},
+		{"words match name or description", "q=dinner+two", []string{"Dinner and a movie"}},
+		{"combined with a filter", "q=gift+card&partner=TRAVELCO", []string{"Gift card bundle"}},
+		{"paged", "q=gift+card&limit=2&page=2", []string{"Movie gift card", "Dinner and a movie"}},
+		{"wildcards match literally", "q=100%25+off_", []string{"100% off_peak"}},
+		{"underscore is not a wildcard", "q=off_pea_", nil},
+		{"no results", "q=snorkel", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := listBenefits(t, s, tenantID, tt.query)
+			var got []string
+			for _, benefit := range list.Benefits {
+				got = append(got, benefit.Name)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("results = %q, want %q", got, tt.want)
+			}
+			if list.Benefits == nil {
+				t.Fatal("benefits is null, want an empty list")
+			}
+		})
+	}
+
+	// Totals count every match, not just the page
+	if list := listBenefits(t, s, tenantID, "q=gift+card&limit=1"); list.Total != 4 || list.Benefits[0].ID != ids["Gift card"] {
+		t.Fatalf("first of 4 matches = %+v", list)
+	}
+}
@@ -2,27 +2,58 @@ package catalog
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/cache"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
 	"github.com/sirupsen/logrus"
 )
 
 // Service represents the catalog service
 type Service struct {
-	config *config.Config
-	logger *logrus.Logger
-	db     *database.PostgresDB
+	config     *config.Config
+	logger     *logrus.Logger
+	db         *database.PostgresDB
+	cache      *cache.Cache
+	jwtManager *auth.JWTManager
+	tenants    auth.TenantScope
 }
 
+// Cache namespaces
+const (
+	cacheBenefits     = "benefits"
+	cacheBenefitLists = "benefit_lists"
+	cacheCategories   = "categories"
+	cachePartners     = "partners"
+)
+
+var cacheNamespaces = []string{cacheBenefits, cacheBenefitLists, cacheCategories, cachePartners}
+
+// Benefit statuses accepted by the status filter
+const (
+	BenefitStatusActive    = "active"
+	BenefitStatusInactive  = "inactive"
+	BenefitStatusLive      = "live"
+	BenefitStatusScheduled = "scheduled"
+	BenefitStatusExpired   = "expired"
+)
+
+var errBenefitNotFound = errors.New("benefit not found")
+
 // Benefit represents a loyalty benefit/reward
 type Benefit struct {
 	ID          string     `json:"id"`
@@ -32,10 +63,15 @@ type Benefit struct {
 	Partner     string     `json:"partner"`
 	Category    string     `json:"category"`
 	Active      bool       `json:"active"`
-	StartsAt    *time.Time `json:"starts_at,omitempty"`
-	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+	// DeletedAt is set while the benefit is soft-deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// CreatedBy and UpdatedBy identify the acting user or service
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
 }
 
 // CreateBenefitRequest represents a request to create a benefit
@@ -52,29 +88,38 @@ type CreateBenefitRequest struct {
 
 // UpdateBenefitRequest represents a request to update a benefit
 type UpdateBenefitRequest struct {
-	Name        *string     `json:"name"`
-	Description *string     `json:"description"`
-	Points      *int        `json:"points"`
-	Partner     *string     `json:"partner"`
-	Category    *string     `json:"category"`
-	Active      *bool       `json:"active"`
-	StartsAt    *time.Time  `json:"starts_at"`
-	EndsAt      *time.Time  `json:"ends_at"`
+	Name        *string    `json:"name"`
+	Description *string    `json:"description"`
+	Points      *int       `json:"points"`
+	Partner     *string    `json:"partner"`
+	Category    *string    `json:"category"`
+	Active      *bool      `json:"active"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
 }
 
 // BenefitListResponse represents a paginated list of benefits
 type BenefitListResponse struct {
 	Benefits []*Benefit `json:"benefits"`
 	Total    int        `json:"total"`
-	Page     int        `json:"page"`
-	Limit    int        `json:"limit"`
+	// Page is set in offset mode
+	Page  int `json:"page,omitempty"`
+	Limit int `json:"limit"`
+	// NextCursor continues a cursor-paged listing; it is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // NewService creates a new catalog service
 func NewService(cfg *config.Config, logger *logrus.Logger) *Service {
+	// Initialize JWT manager
+	jwtManager := auth.NewJWTManagerFromConfig(cfg)
+
 	return &Service{
-		config: cfg,
-		logger: logger,
+		config:     cfg,
+		logger:     logger,
+		cache:      cache.New(cfg.Cache.BenefitsTTL),
+		jwtManager: jwtManager,
+		tenants:    auth.NewTenantScope(cfg.Security.Tenancy.Enabled, cfg.Security.Tenancy.DefaultTenant),
 	}
 }
 
@@ -83,45 +128,115 @@ func (s *Service) SetDatabase(db *database.PostgresDB) {
 	s.db = db
 }
 
-// Routes returns the catalog service routes
+// Routes returns the catalog service routes. Reads are public; every
+// mutation requires an admin token.
 func (s *Service) Routes(r chi.Router) {
+	requireAdmin := []func(http.Handler) http.Handler{
+		authmw.RequireJWT(s.jwtManager, authmw.WithTenants(s.tenants), authmw.WithLogger(s.logger)),
+		authmw.RequireRole(auth.RoleAdmin),
+	}
+
 	r.Route("/v1", func(r chi.Router) {
 		r.Route("/benefits", func(r chi.Router) {
-			r.Get("/", s.ListBenefits)
-			r.Post("/", s.AuthMiddleware(s.CreateBenefit))
-			r.Get("/{id}", s.GetBenefit)
-			r.Put("/{id}", s.AuthMiddleware(s.UpdateBenefit))
-			r.Delete("/{id}", s.AuthMiddleware(s.DeleteBenefit))
+			r.With(requireWhen(includeDeleted, requireAdmin...), s.headerTenant).Get("/", s.ListBenefits)
+			r.With(requireWhen(includeDeleted, requireAdmin...), s.headerTenant).Get("/{id}", s.GetBenefit)
+
+			r.Group(func(r chi.Router) {
+				r.Use(requireAdmin...)
+				r.Post("/", s.CreateBenefit)
+				r.Post("/import", s.ImportBenefits)
+				r.Put("/{id}", s.UpdateBenefit)
+				r.Delete("/{id}", s.DeleteBenefit)
+				r.Post("/{id}/restore", s.RestoreBenefit)
+			})
+		})
+		r.With(s.headerTenant).Get("/categories", s.GetCategories)
+		r.With(s.headerTenant).Get("/partners", s.GetPartners)
+
+		r.Group(func(r chi.Router) {
+			r.Use(requireAdmin...)
+			r.Post("/categories", s.CreateCategory)
+			r.Post("/partners", s.CreatePartner)
+			r.Post("/admin/cache/invalidate", s.InvalidateCache)
 		})
-		r.Get("/categories", s.GetCategories)
-		r.Get("/partners", s.GetPartners)
 	})
 }
 
-// AuthMiddleware is a placeholder for JWT authentication
-func (s *Service) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Implement JWT validation
-		// For now, just check if user ID header is present
-		userID := r.Header.Get("X-User-ID")
-		if userID == "" {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "User ID required"})
+// requireWhen applies middlewares only to requests matching cond, so a
+// public endpoint can reserve some parameters for authenticated callers
+func requireWhen(cond func(*http.Request) bool, middlewares ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		guarded := chi.Chain(middlewares...).Handler(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cond(r) {
+				guarded.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// headerTenant scopes public reads to the tenant named in their tenant
+// header, answering 400 if it is missing or invalid. Requests authenticated
+// by requireWhen keep the tenant of their token.
+func (s *Service) headerTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.UserFromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
 			return
 		}
-		// Add user ID to context
-		ctx := context.WithValue(r.Context(), "user_id", userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	}
+
+		tenantID, err := s.tenants.FromHeader(r.Header.Get(auth.TenantHeader))
+		if err != nil {
+			platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "A valid "+auth.TenantHeader+" header is required")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithTenant(r.Context(), tenantID)))
+	})
+}
+
+// includeDeleted reports whether a request asks for soft-deleted benefits
+func includeDeleted(r *http.Request) bool {
+	include, _ := strconv.ParseBool(r.URL.Query().Get("include_deleted"))
+	return include
 }
 
-// ListBenefits returns a paginated list of benefits
+// benefitFilter selects the benefits a list returns
+type benefitFilter struct {
+	Status   string
+	Category string
+	Partner  string
+	// Query matches benefits whose name or description contains every word
+	Query string
+	// IncludeDeleted also returns soft-deleted benefits, for admins
+	IncludeDeleted bool
+}
+
+// ListBenefits returns a paginated list of benefits. Soft-deleted benefits
+// are left out unless an admin passes include_deleted=true. q searches names
+// and descriptions; pages rank the closest name matches first, while cursor
+// pages stay newest first so their cursors remain stable.
 func (s *Service) ListBenefits(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
-	status := r.URL.Query().Get("status")
-	category := r.URL.Query().Get("category")
-	partner := r.URL.Query().Get("partner")
-	
+	filter := benefitFilter{
+		Status:         r.URL.Query().Get("status"),
+		Category:       r.URL.Query().Get("category"),
+		Partner:        r.URL.Query().Get("partner"),
+		Query:          strings.TrimSpace(r.URL.Query().Get("q")),
+		IncludeDeleted: includeDeleted(r),
+	}
+
+	if len(filter.Query) > maxSearchLength {
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest,
+			fmt.Sprintf("Search query must be at most %d characters", maxSearchLength))
+		return
+	}
+	if _, ok := benefitStatusFilters[filter.Status]; filter.Status != "" && !ok {
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Status must be one of active, inactive, live, scheduled, or expired")
+		return
+	}
+
 	pageStr := r.URL.Query().Get("page")
 	if pageStr == "" {
 		pageStr = "1"
@@ -130,7 +245,7 @@ func (s *Service) ListBenefits(w http.ResponseWriter, r *http.Request) {
 	if err != nil || page < 1 {
 		page = 1
 	}
-	
+
 	limitStr := r.URL.Query().Get("limit")
 	if limitStr == "" {
 		limitStr = "50"
@@ -140,42 +255,65 @@ func (s *Service) ListBenefits(w http.ResponseWriter, r *http.Request) {
 		limit = 50
 	}
 
-	// Get benefits from database
-	benefits, total, err := s.getBenefits(status, category, partner, page, limit)
+	// A cursor parameter, even an empty one for the first page, selects
+	// cursor pagination over page numbers
+	cursor, cursorMode, err := platformhttp.CursorFromRequest(r)
+	if err != nil {
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Cursor is invalid")
+		return
+	}
+	if cursorMode {
+		page = 0
+	}
+
+	// Get benefits from cache or database
+	cacheKey := fmt.Sprintf("tenant=%s&status=%s&category=%s&partner=%s&q=%s&deleted=%t&page=%d&limit=%d&cursor=%s",
+		auth.TenantFromContext(r.Context()), filter.Status, filter.Category, filter.Partner, url.QueryEscape(filter.Query),
+		filter.IncludeDeleted, page, limit, r.URL.Query().Get(platformhttp.CursorParam))
+	cached, err := s.cache.GetOrLoad(cacheBenefitLists, cacheKey, func() (interface{}, error) {
+		if cursorMode {
+			benefits, total, next, err := s.getBenefitsAfter(r.Context(), filter, cursor, limit)
+			if err != nil {
+				return nil, err
+			}
+			return &BenefitListResponse{Benefits: benefits, Total: total, Limit: limit, NextCursor: next}, nil
+		}
+		benefits, total, err := s.getBenefits(r.Context(), filter, page, limit)
+		if err != nil {
+			return nil, err
+		}
+		return &BenefitListResponse{Benefits: benefits, Total: total, Page: page, Limit: limit}, nil
+	})
 	if err != nil {
 		s.logger.Errorf("Failed to get benefits: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Failed to retrieve benefits"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve benefits")
 		return
 	}
 
-	response := &BenefitListResponse{
-		Benefits: benefits,
-		Total:    total,
-		Page:     page,
-		Limit:    limit,
+	// A benefit can leave the list without any updated_at moving, so the
+	// list is versioned by its content
+	response := cached.(*BenefitListResponse)
+	validators, err := platformhttp.NewContentValidators(response)
+	if err != nil {
+		s.logger.Errorf("Failed to compute benefit list ETag: %v", err)
+	} else if platformhttp.CheckNotModified(w, r, validators, s.config.Cache.ClientMaxAge) {
+		return
 	}
 
 	render.JSON(w, r, response)
 }
 
-// CreateBenefit creates a new benefit
+// CreateBenefit creates a new benefit. Benefits that have already ended are
+// rejected unless allow_past=true is given, e.g. to backfill history.
 func (s *Service) CreateBenefit(w http.ResponseWriter, r *http.Request) {
 	var req CreateBenefitRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
-		return
-	}
-
-	// Validate request
-	if req.Name == "" || req.Points <= 0 || req.Partner == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Name, points, and partner are required"})
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
 		return
 	}
 
 	// Create benefit
+	actor := authmw.Actor(r.Context())
 	benefit := &Benefit{
 		ID:          uuid.New().String(),
 		Name:        req.Name,
@@ -188,62 +326,92 @@ func (s *Service) CreateBenefit(w http.ResponseWriter, r *http.Request) {
 		EndsAt:      req.EndsAt,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+		CreatedBy:   actor,
+		UpdatedBy:   actor,
+	}
+
+	allowPast, _ := strconv.ParseBool(r.URL.Query().Get("allow_past"))
+
+	normalizeBenefitTimes(benefit)
+	errs, err := s.validateBenefit(r.Context(), benefit, !allowPast, time.Now())
+	if err != nil {
+		s.logger.Errorf("Failed to validate benefit: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to validate benefit")
+		return
+	}
+	if errs != nil {
+		platformhttp.ValidationError(w, r, errs)
+		return
 	}
 
 	// Save to database
-	if err := s.saveBenefit(benefit); err != nil {
+	if err := s.saveBenefit(r.Context(), benefit); err != nil {
 		s.logger.Errorf("Failed to save benefit: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Failed to create benefit"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to create benefit")
 		return
 	}
 
+	s.cache.InvalidateNamespace(cacheBenefitLists)
+	s.logger.Infof("Benefit %s created by %s", benefit.ID, actor)
+
 	render.Status(r, http.StatusCreated)
 	render.JSON(w, r, benefit)
 }
 
-// GetBenefit returns a specific benefit by ID
+// GetBenefit returns a specific benefit by ID. A soft-deleted benefit is not
+// found unless an admin passes include_deleted=true.
 func (s *Service) GetBenefit(w http.ResponseWriter, r *http.Request) {
 	benefitID := chi.URLParam(r, "id")
 	if benefitID == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Benefit ID required"})
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Benefit ID required")
 		return
 	}
 
-	benefit, err := s.getBenefit(benefitID)
+	benefit, err := s.cache.GetOrLoad(cacheBenefits, benefitCacheKey(r.Context(), benefitID), func() (interface{}, error) {
+		return s.getBenefit(r.Context(), benefitID)
+	})
 	if err != nil {
-		s.logger.Errorf("Failed to get benefit %s: %v", benefitID, err)
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{"error": "Benefit not found"})
+		s.writeBenefitLookupError(w, r, benefitID, err)
 		return
 	}
 
-	render.JSON(w, r, benefit)
+	b := benefit.(*Benefit)
+	if b.DeletedAt != nil && !includeDeleted(r) {
+		platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Benefit not found")
+		return
+	}
+
+	// Every update moves updated_at, so it versions the benefit on its own
+	if platformhttp.CheckNotModified(w, r, platformhttp.NewValidators(b.UpdatedAt, b.ID), s.config.Cache.ClientMaxAge) {
+		return
+	}
+
+	render.JSON(w, r, b)
 }
 
-// UpdateBenefit updates an existing benefit
+// UpdateBenefit updates an existing benefit. A soft-deleted benefit must be
+// restored before it can be updated.
 func (s *Service) UpdateBenefit(w http.ResponseWriter, r *http.Request) {
 	benefitID := chi.URLParam(r, "id")
 	if benefitID == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Benefit ID required"})
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Benefit ID required")
 		return
 	}
 
 	var req UpdateBenefitRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+	if err := platformhttp.DecodeJSON(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
 		return
 	}
 
 	// Get existing benefit
-	existing, err := s.getBenefit(benefitID)
+	existing, err := s.getBenefit(r.Context(), benefitID)
 	if err != nil {
-		s.logger.Errorf("Failed to get benefit %s: %v", benefitID, err)
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{"error": "Benefit not found"})
+		s.writeBenefitLookupError(w, r, benefitID, err)
+		return
+	}
+	if existing.DeletedAt != nil {
+		platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "Benefit is deleted; restore it before updating")
 		return
 	}
 
@@ -272,84 +440,185 @@ func (s *Service) UpdateBenefit(w http.ResponseWriter, r *http.Request) {
 	if req.EndsAt != nil {
 		existing.EndsAt = req.EndsAt
 	}
-	
+
 	existing.UpdatedAt = time.Now()
+	existing.UpdatedBy = authmw.Actor(r.Context())
+
+	normalizeBenefitTimes(existing)
+	errs, err := s.validateBenefit(r.Context(), existing, false, time.Now())
+	if err != nil {
+		s.logger.Errorf("Failed to validate benefit: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to validate benefit")
+		return
+	}
+	if errs != nil {
+		platformhttp.ValidationError(w, r, errs)
+		return
+	}
 
 	// Save to database
-	if err := s.updateBenefit(existing); err != nil {
+	if err := s.updateBenefit(r.Context(), existing); err != nil {
+		if errors.Is(err, errBenefitNotFound) {
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Benefit not found")
+			return
+		}
 		s.logger.Errorf("Failed to update benefit %s: %v", benefitID, err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Failed to update benefit"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to update benefit")
 		return
 	}
 
+	s.invalidateBenefit(r.Context(), benefitID)
+	s.logger.Infof("Benefit %s updated by %s", benefitID, existing.UpdatedBy)
+
 	render.JSON(w, r, existing)
 }
 
-// DeleteBenefit deletes a benefit
+// DeleteBenefit soft-deletes a benefit, hiding it from lists and
+// redemption. Benefits are never removed, so redemptions that reference them
+// stay valid and the benefit can be restored.
 func (s *Service) DeleteBenefit(w http.ResponseWriter, r *http.Request) {
 	benefitID := chi.URLParam(r, "id")
 	if benefitID == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Benefit ID required"})
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Benefit ID required")
 		return
 	}
 
-	// Check if benefit exists
-	_, err := s.getBenefit(benefitID)
-	if err != nil {
-		s.logger.Errorf("Failed to get benefit %s: %v", benefitID, err)
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{"error": "Benefit not found"})
+	actor := authmw.Actor(r.Context())
+	if err := s.deleteBenefit(r.Context(), benefitID, actor); err != nil {
+		if errors.Is(err, errBenefitNotFound) {
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Benefit not found")
+			return
+		}
+		s.logger.Errorf("Failed to delete benefit %s: %v", benefitID, err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to delete benefit")
 		return
 	}
 
-	// Delete from database
-	if err := s.deleteBenefit(benefitID); err != nil {
-		s.logger.Errorf("Failed to delete benefit %s: %v", benefitID, err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Failed to delete benefit"})
+	s.invalidateBenefit(r.Context(), benefitID)
+	s.logger.Infof("Benefit %s deleted by %s", benefitID, actor)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreBenefit undoes a soft delete. Restoring a benefit that is not
+// deleted is harmless.
+func (s *Service) RestoreBenefit(w http.ResponseWriter, r *http.Request) {
+	benefitID := chi.URLParam(r, "id")
+	if benefitID == "" {
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Benefit ID required")
 		return
 	}
 
-	render.Status(r, http.StatusNoContent)
+	actor := authmw.Actor(r.Context())
+	benefit, err := s.restoreBenefit(r.Context(), benefitID, actor)
+	if err != nil {
+		if errors.Is(err, errBenefitNotFound) {
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Benefit not found")
+			return
+		}
+		s.logger.Errorf("Failed to restore benefit %s: %v", benefitID, err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to restore benefit")
+		return
+	}
+
+	s.invalidateBenefit(r.Context(), benefitID)
+	s.logger.Infof("Benefit %s restored by %s", benefitID, actor)
+
+	render.JSON(w, r, benefit)
 }
 
-// GetCategories returns all available benefit categories
-func (s *Service) GetCategories(w http.ResponseWriter, r *http.Request) {
-	categories := []string{
-		"Travel",
-		"Retail",
-		"Dining",
-		"Entertainment",
-		"Technology",
-		"Health & Wellness",
-		"Charity",
-		"Cash Back",
+// InvalidateCache clears a cache namespace (or a single entry when id is given) without a restart
+func (s *Service) InvalidateCache(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	id := r.URL.Query().Get("id")
+
+	var invalidated []string
+	switch {
+	case key == "all":
+		s.cache.Flush()
+		invalidated = cacheNamespaces
+	case isCacheNamespace(key) && id != "":
+		s.cache.Delete(key, id)
+		invalidated = []string{key + "/" + id}
+	case isCacheNamespace(key):
+		s.cache.InvalidateNamespace(key)
+		invalidated = []string{key}
+	default:
+		validKeys := append([]string{"all"}, cacheNamespaces...)
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest,
+			"Unknown cache key, expected one of: "+strings.Join(validKeys, ", "))
+		return
 	}
 
+	s.logger.Infof("Cache invalidated by %s: %v", authmw.Actor(r.Context()), invalidated)
+
 	render.JSON(w, r, map[string]interface{}{
-		"categories": categories,
+		"invalidated": invalidated,
 	})
 }
 
-// GetPartners returns all available benefit partners
-func (s *Service) GetPartners(w http.ResponseWriter, r *http.Request) {
-	partners := []string{
-		"GIFTCO",
-		"TRAVELCO",
-		"RETAILCO",
-		"DININGCO",
-		"ENTERTAINMENTCO",
+// writeBenefitLookupError answers 404 for unknown benefits and 500 for lookup failures
+func (s *Service) writeBenefitLookupError(w http.ResponseWriter, r *http.Request, benefitID string, err error) {
+	if errors.Is(err, errBenefitNotFound) {
+		platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Benefit not found")
+		return
 	}
+	s.logger.Errorf("Failed to get benefit %s: %v", benefitID, err)
+	platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve benefit")
+}
 
-	render.JSON(w, r, map[string]interface{}{
-		"partners": partners,
-	})
+// benefitCacheKey keys a cached benefit by its tenant, so a benefit ID never
+// reads another tenant's cached benefit
+func benefitCacheKey(ctx context.Context, id string) string {
+	return auth.TenantFromContext(ctx) + "/" + id
+}
+
+// invalidateBenefit drops a benefit and every cached list that may contain it
+func (s *Service) invalidateBenefit(ctx context.Context, id string) {
+	s.cache.Delete(cacheBenefits, benefitCacheKey(ctx, id))
+	s.cache.InvalidateNamespace(cacheBenefitLists)
+}
+
+func isCacheNamespace(key string) bool {
+	for _, ns := range cacheNamespaces {
+		if ns == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Database operations. Every query is scoped to the tenant in ctx.
+
+// benefitColumns are the columns scanned by scanBenefit, in order
+const benefitColumns = `id, name, COALESCE(description, ''), points, partner, COALESCE(category, ''), active,
+	starts_at, ends_at, COALESCE(created_by, ''), COALESCE(updated_by, ''), created_at, updated_at, deleted_at`
+
+// maxSearchLength caps the q parameter of a benefit list
+const maxSearchLength = 100
+
+// benefitStatusFilters map the status query parameter to SQL predicates. A
+// benefit is live while it is active and inside its availability window.
+var benefitStatusFilters = map[string]string{
+	BenefitStatusActive:    `active`,
+	BenefitStatusInactive:  `NOT active`,
+	BenefitStatusLive:      `active AND (starts_at IS NULL OR starts_at <= NOW()) AND (ends_at IS NULL OR ends_at > NOW())`,
+	BenefitStatusScheduled: `active AND starts_at > NOW()`,
+	BenefitStatusExpired:   `active AND ends_at <= NOW()`,
+}
+
+func scanBenefit(row pgx.Row) (*Benefit, error) {
+	var benefit Benefit
+	err := row.Scan(&benefit.ID, &benefit.Name, &benefit.Description, &benefit.Points, &benefit.Partner, &benefit.Category,
+		&benefit.Active, &benefit.StartsAt, &benefit.EndsAt, &benefit.CreatedBy, &benefit.UpdatedBy, &benefit.CreatedAt, &benefit.UpdatedAt,
+		&benefit.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &benefit, nil
 }
 
-// Database operations (placeholder implementations)
-func (s *Service) getBenefits(status, category, partner string, page, limit int) ([]*Benefit, int, error) {
+func (s *Service) getBenefits(ctx context.Context, filter benefitFilter, page, limit int) ([]*Benefit, int, error) {
 	if s.db == nil {
 		// Return mock data for now
 		benefits := []*Benefit{
@@ -378,12 +647,137 @@ func (s *Service) getBenefits(status, category, partner string, page, limit int)
 		}
 		return benefits, 2, nil
 	}
-	
-	// TODO: Implement actual database query
-	return nil, 0, fmt.Errorf("not implemented")
+
+	where, args := benefitListFilter(ctx, filter)
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM benefits `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	order, args := benefitListOrder(filter, args)
+	args = append(args, limit, (page-1)*limit)
+	query := fmt.Sprintf(`SELECT %s FROM benefits %s ORDER BY %s LIMIT $%d OFFSET $%d`,
+		benefitColumns, where, order, len(args)-1, len(args))
+
+	benefits, err := s.queryBenefits(ctx, query, args...)
+	return benefits, total, err
 }
 
-func (s *Service) getBenefit(id string) (*Benefit, error) {
+// getBenefitsAfter returns the page of benefits following cursor, or the
+// first page when cursor is nil, in the same order as getBenefits, with the
+// cursor of the next page if there is one
+func (s *Service) getBenefitsAfter(ctx context.Context, filter benefitFilter, cursor *platformhttp.Cursor, limit int) ([]*Benefit, int, string, error) {
+	if s.db == nil {
+		benefits, total, err := s.getBenefits(ctx, filter, 1, limit)
+		return benefits, total, "", err
+	}
+
+	where, args := benefitListFilter(ctx, filter)
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM benefits `+where, args...).Scan(&total); err != nil {
+		return nil, 0, "", err
+	}
+
+	if cursor != nil {
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		where += fmt.Sprintf(" AND (created_at < $%d OR (created_at = $%d AND id > $%d))", len(args)-1, len(args)-1, len(args))
+	}
+	// Read one extra row to learn whether another page follows
+	args = append(args, limit+1)
+	query := fmt.Sprintf(`SELECT %s FROM benefits %s ORDER BY created_at DESC, id LIMIT $%d`,
+		benefitColumns, where, len(args))
+
+	benefits, err := s.queryBenefits(ctx, query, args...)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	var next string
+	if len(benefits) > limit {
+		benefits = benefits[:limit]
+		last := benefits[limit-1]
+		next = platformhttp.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return benefits, total, next, nil
+}
+
+// benefitListFilter builds the WHERE clause and arguments shared by the
+// benefit list queries
+func benefitListFilter(ctx context.Context, filter benefitFilter) (string, []interface{}) {
+	where := `WHERE tenant_id = $1`
+	args := []interface{}{auth.TenantFromContext(ctx)}
+	if !filter.IncludeDeleted {
+		where += ` AND deleted_at IS NULL`
+	}
+	if predicate, ok := benefitStatusFilters[filter.Status]; ok {
+		where += ` AND ` + predicate
+	}
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		where += fmt.Sprintf(" AND category = $%d", len(args))
+	}
+	if filter.Partner != "" {
+		args = append(args, filter.Partner)
+		where += fmt.Sprintf(" AND partner = $%d", len(args))
+	}
+	for _, word := range strings.Fields(filter.Query) {
+		args = append(args, "%"+escapeLike(word)+"%")
+		where += fmt.Sprintf(" AND (name ILIKE $%d OR description ILIKE $%d)", len(args), len(args))
+	}
+	return where, args
+}
+
+// benefitListOrder returns the ORDER BY clause for a page of benefits. A
+// search ranks a name equal to the query first, then names starting with it,
+// then names containing it, then description-only matches; the newest come
+// first within each rank.
+func benefitListOrder(filter benefitFilter, args []interface{}) (string, []interface{}) {
+	if filter.Query == "" {
+		return `created_at DESC, id`, args
+	}
+	query := escapeLike(filter.Query)
+	args = append(args, query, query+"%", "%"+query+"%")
+	n := len(args)
+	return fmt.Sprintf(`CASE WHEN name ILIKE $%d THEN 0 WHEN name ILIKE $%d THEN 1 WHEN name ILIKE $%d THEN 2 ELSE 3 END,
+		created_at DESC, id`, n-2, n-1, n), args
+}
+
+// escapeLike escapes the LIKE wildcards in s, so they match literally
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// queryBenefits runs a query selecting benefitColumns
+func (s *Service) queryBenefits(ctx context.Context, query string, args ...interface{}) ([]*Benefit, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	benefits := []*Benefit{}
+	for rows.Next() {
+		benefit, err := scanBenefit(rows)
+		if err != nil {
+			return nil, err
+		}
+		benefits = append(benefits, benefit)
+	}
+
+	return benefits, rows.Err()
+}
+
+// getBenefit returns a benefit by ID, including deactivated and
+// soft-deleted ones, or errBenefitNotFound
+func (s *Service) getBenefit(ctx context.Context, id string) (*Benefit, error) {
+	// Benefit IDs are UUIDs, so any other ID names no benefit
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, errBenefitNotFound
+	}
 	if s.db == nil {
 		// Return mock data for now
 		return &Benefit{
@@ -398,37 +792,106 @@ func (s *Service) getBenefit(id string) (*Benefit, error) {
 			UpdatedAt:   time.Now().Add(-24 * time.Hour),
 		}, nil
 	}
-	
-	// TODO: Implement actual database query
-	return nil, fmt.Errorf("not implemented")
+
+	query := `SELECT ` + benefitColumns + ` FROM benefits WHERE id = $1 AND tenant_id = $2`
+	benefit, err := scanBenefit(s.db.QueryRow(ctx, query, id, auth.TenantFromContext(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errBenefitNotFound
+		}
+		return nil, err
+	}
+	return benefit, nil
 }
 
-func (s *Service) saveBenefit(benefit *Benefit) error {
+func (s *Service) saveBenefit(ctx context.Context, benefit *Benefit) error {
 	if s.db == nil {
 		s.logger.Infof("Would save benefit: %+v", benefit)
 		return nil
 	}
-	
-	// TODO: Implement actual database save
-	return fmt.Errorf("not implemented")
+
+	return s.db.Exec(ctx, insertBenefitQuery, insertBenefitArgs(ctx, benefit)...)
 }
 
-func (s *Service) updateBenefit(benefit *Benefit) error {
+const insertBenefitQuery = `
+	INSERT INTO benefits (id, tenant_id, name, description, points, partner, category, active, starts_at, ends_at,
+		created_by, updated_by, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+
+func insertBenefitArgs(ctx context.Context, benefit *Benefit) []interface{} {
+	return []interface{}{benefit.ID, auth.TenantFromContext(ctx), benefit.Name, benefit.Description, benefit.Points,
+		benefit.Partner, benefit.Category, benefit.Active, benefit.StartsAt, benefit.EndsAt, benefit.CreatedBy,
+		benefit.UpdatedBy, benefit.CreatedAt, benefit.UpdatedAt}
+}
+
+func (s *Service) updateBenefit(ctx context.Context, benefit *Benefit) error {
 	if s.db == nil {
 		s.logger.Infof("Would update benefit: %+v", benefit)
 		return nil
 	}
-	
-	// TODO: Implement actual database update
-	return fmt.Errorf("not implemented")
+
+	var id string
+	err := s.db.QueryRow(ctx, updateBenefitQuery, updateBenefitArgs(ctx, benefit)...).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errBenefitNotFound
+	}
+	return err
+}
+
+const updateBenefitQuery = `
+	UPDATE benefits
+	SET name = $3, description = $4, points = $5, partner = $6, category = $7, active = $8,
+		starts_at = $9, ends_at = $10, updated_by = $11, updated_at = $12
+	WHERE id = $1 AND tenant_id = $2
+	RETURNING id`
+
+func updateBenefitArgs(ctx context.Context, benefit *Benefit) []interface{} {
+	return []interface{}{benefit.ID, auth.TenantFromContext(ctx), benefit.Name, benefit.Description, benefit.Points,
+		benefit.Partner, benefit.Category, benefit.Active, benefit.StartsAt, benefit.EndsAt, benefit.UpdatedBy,
+		benefit.UpdatedAt}
 }
 
-func (s *Service) deleteBenefit(id string) error {
+// deleteBenefit soft-deletes a benefit rather than removing it, so
+// redemptions that reference it stay valid. Deleting it again keeps the
+// original deletion time.
+func (s *Service) deleteBenefit(ctx context.Context, id, actor string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return errBenefitNotFound
+	}
 	if s.db == nil {
 		s.logger.Infof("Would delete benefit: %s", id)
 		return nil
 	}
-	
-	// TODO: Implement actual database delete
-	return fmt.Errorf("not implemented")
+
+	var deletedID string
+	err := s.db.QueryRow(ctx, `
+		UPDATE benefits SET deleted_at = COALESCE(deleted_at, NOW()), updated_by = $3, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING id
+	`, id, auth.TenantFromContext(ctx), actor).Scan(&deletedID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errBenefitNotFound
+	}
+	return err
+}
+
+// restoreBenefit clears a benefit's deletion and returns it
+func (s *Service) restoreBenefit(ctx context.Context, id, actor string) (*Benefit, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, errBenefitNotFound
+	}
+	if s.db == nil {
+		s.logger.Infof("Would restore benefit: %s", id)
+		return s.getBenefit(ctx, id)
+	}
+
+	query := `
+		UPDATE benefits SET deleted_at = NULL, updated_by = $3, updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING ` + benefitColumns
+	benefit, err := scanBenefit(s.db.QueryRow(ctx, query, id, auth.TenantFromContext(ctx), actor))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errBenefitNotFound
+	}
+	return benefit, err
 }
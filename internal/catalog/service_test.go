@@ -0,0 +1,463 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/migrate"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestService creates a catalog service without a database; configure
+// adjusts the loaded configuration first
+func newTestService(t *testing.T, configure ...func(*config.Config)) *Service {
+	t.Helper()
+
+	cfg, err := config.Load("catalog-svc")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Redis.Addr = ""
+	cfg.Security.JWT.Revocation = false
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewService(cfg, logger)
+}
+
+// withTenancy enables multi-tenancy
+func withTenancy(cfg *config.Config) {
+	cfg.Security.Tenancy.Enabled = true
+}
+
+// withTestDB migrates the test database for s. The test is skipped without
+// a database.
+func withTestDB(t *testing.T, s *Service) {
+	t.Helper()
+
+	db := databasetest.Open(t)
+	if err := migrate.Run(context.Background(), db, "catalog", Migrations, migrate.ModeApply, s.logger); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	s.SetDatabase(db)
+}
+
+// createTestBenefit saves a live benefit in ctx's tenant
+func createTestBenefit(t *testing.T, ctx context.Context, s *Service) *Benefit {
+	t.Helper()
+
+	now := time.Now()
+	benefit := &Benefit{
+		ID:        uuid.New().String(),
+		Name:      "Test benefit " + uuid.New().String()[:8],
+		Points:    500,
+		Partner:   "GIFTCO",
+		Category:  "Retail",
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.saveBenefit(ctx, benefit); err != nil {
+		t.Fatalf("failed to save benefit: %v", err)
+	}
+	return benefit
+}
+
+// adminToken issues an admin token bound to tenantID
+func adminToken(t *testing.T, s *Service, tenantID string) string {
+	t.Helper()
+
+	tok, err := s.jwtManager.GenerateTenantToken(uuid.New().String(), "admin@example.com", auth.RoleAdmin, tenantID)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	return tok
+}
+
+// serve sends a request with a JSON body, if any, through s's routes.
+// headers are name, value pairs.
+func serve(s *Service, method, path, tok string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPublicReadsRequireTenantHeader(t *testing.T) {
+	s := newTestService(t, withTenancy)
+
+	paths := []string{"/v1/benefits", "/v1/benefits/" + uuid.New().String(), "/v1/categories", "/v1/partners"}
+	tests := []struct {
+		name    string
+		headers []string
+		want    int
+	}{
+		{"missing tenant", nil, http.StatusBadRequest},
+		{"invalid tenant", []string{auth.TenantHeader, "Not A Tenant!"}, http.StatusBadRequest},
+		{"tenant", []string{auth.TenantHeader, "acme"}, http.StatusOK},
+	}
+	for _, path := range paths {
+		for _, tt := range tests {
+			t.Run(path+"/"+tt.name, func(t *testing.T) {
+				rec := serve(s, http.MethodGet, path, "", nil, tt.headers...)
+				if rec.Code != tt.want {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+				}
+			})
+		}
+	}
+}
+
+func TestMalformedBenefitIDIsNotFound(t *testing.T) {
+	s := newTestService(t)
+	admin := adminToken(t, s, "")
+	name := "Renamed benefit"
+
+	tests := []struct {
+		method, path string
+		tok          string
+		body         interface{}
+	}{
+		{http.MethodGet, "/v1/benefits/abc", "", nil},
+		{http.MethodGet, "/v1/benefits/" + uuid.New().String()[:35], "", nil},
+		{http.MethodPut, "/v1/benefits/abc", admin, UpdateBenefitRequest{Name: &name}},
+		{http.MethodDelete, "/v1/benefits/abc", admin, nil},
+		{http.MethodPost, "/v1/benefits/abc/restore", admin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if rec := serve(s, tt.method, tt.path, tt.tok, tt.body); rec.Code != http.StatusNotFound {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusNotFound, rec.Body)
+			}
+		})
+	}
+}
+
+func TestAdminListKeepsTokenTenant(t *testing.T) {
+	s := newTestService(t, withTenancy)
+
+	// The admin's token names the tenant, so no header is needed
+	rec := serve(s, http.MethodGet, "/v1/benefits?include_deleted=true", adminToken(t, s, "acme"), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+}
+
+func TestGetBenefitCachesPerTenant(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	benefitID := uuid.New().String()
+
+	rec := serve(s, http.MethodGet, "/v1/benefits/"+benefitID, "", nil, auth.TenantHeader, "acme")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if _, ok := s.cache.Get(cacheBenefits, "acme/"+benefitID); !ok {
+		t.Error("benefit not cached under its tenant")
+	}
+	if _, ok := s.cache.Get(cacheBenefits, benefitID); ok {
+		t.Error("benefit cached under its bare ID, where every tenant reads it")
+	}
+}
+
+func TestGetBenefitIsScopedToTenant(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantA, tenantB := databasetest.Tenant(t), databasetest.Tenant(t)
+	benefit := createTestBenefit(t, auth.WithTenant(context.Background(), tenantA), s)
+
+	// Cache the benefit for its own tenant first, so a shared cache entry
+	// would leak it to the other
+	if rec := serve(s, http.MethodGet, "/v1/benefits/"+benefit.ID, "", nil, auth.TenantHeader, tenantA); rec.Code != http.StatusOK {
+		t.Fatalf("own tenant: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := serve(s, http.MethodGet, "/v1/benefits/"+benefit.ID, "", nil, auth.TenantHeader, tenantB); rec.Code != http.StatusNotFound {
+		t.Fatalf("other tenant: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec := serve(s, http.MethodGet, "/v1/benefits", "", nil, auth.TenantHeader, tenantB)
+	var list BenefitListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if list.Total != 0 || len(list.Benefits) != 0 {
+		t.Fatalf("other tenant lists %d benefits, want none", list.Total)
+	}
+}
+
+func TestUpdateBenefitInvalidatesItsCacheEntry(t *testing.T) {
+	s := newTestService(t)
+	benefitID := uuid.New().String()
+
+	if rec := serve(s, http.MethodGet, "/v1/benefits/"+benefitID, "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("get: status = %d: %s", rec.Code, rec.Body)
+	}
+	key := auth.DefaultTenant + "/" + benefitID
+	if _, ok := s.cache.Get(cacheBenefits, key); !ok {
+		t.Fatal("benefit not cached by a read")
+	}
+	s.cache.Set(cacheBenefits, auth.DefaultTenant+"/other", "unrelated")
+	s.cache.Set(cacheBenefitLists, "page", "may list the benefit")
+
+	name := "Renamed benefit"
+	rec := serve(s, http.MethodPut, "/v1/benefits/"+benefitID, adminToken(t, s, ""), UpdateBenefitRequest{Name: &name})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	if _, ok := s.cache.Get(cacheBenefits, key); ok {
+		t.Error("updated benefit still cached")
+	}
+	if _, ok := s.cache.Get(cacheBenefits, auth.DefaultTenant+"/other"); !ok {
+		t.Error("update dropped another benefit's cache entry")
+	}
+	if _, ok := s.cache.Get(cacheBenefitLists, "page"); ok {
+		t.Error("benefit lists still cached after an update")
+	}
+}
+
+func TestAdminInvalidateCache(t *testing.T) {
+	tests := []struct {
+		query     string
+		want      int
+		cleared   []string
+		untouched []string
+	}{
+		{"key=benefits", http.StatusOK, []string{cacheBenefits + "/a", cacheBenefits + "/b"}, []string{cachePartners + "/p"}},
+		{"key=benefits&id=a", http.StatusOK, []string{cacheBenefits + "/a"}, []string{cacheBenefits + "/b", cachePartners + "/p"}},
+		{"key=all", http.StatusOK, []string{cacheBenefits + "/a", cacheBenefits + "/b", cachePartners + "/p"}, nil},
+		{"key=unknown", http.StatusBadRequest, nil, []string{cacheBenefits + "/a", cachePartners + "/p"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			s := newTestService(t)
+			for _, entry := range []string{cacheBenefits + "/a", cacheBenefits + "/b", cachePartners + "/p"} {
+				ns, key, _ := strings.Cut(entry, "/")
+				s.cache.Set(ns, key, entry)
+			}
+
+			rec := serve(s, http.MethodPost, "/v1/admin/cache/invalidate?"+tt.query, adminToken(t, s, ""), nil)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			for _, entry := range tt.cleared {
+				ns, key, _ := strings.Cut(entry, "/")
+				if _, ok := s.cache.Get(ns, key); ok {
+					t.Errorf("%s not invalidated", entry)
+				}
+			}
+			for _, entry := range tt.untouched {
+				ns, key, _ := strings.Cut(entry, "/")
+				if _, ok := s.cache.Get(ns, key); !ok {
+					t.Errorf("%s invalidated", entry)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminInvalidateCacheRequiresAdmin(t *testing.T) {
+	s := newTestService(t)
+	tok, err := s.jwtManager.GenerateToken(uuid.New().String(), "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	if rec := serve(s, http.MethodPost, "/v1/admin/cache/invalidate?key=all", tok, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestCatalogMutationsRequireAdmin(t *testing.T) {
+	s := newTestService(t)
+	user, err := s.jwtManager.GenerateToken(uuid.New().String(), "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	admin := adminToken(t, s, "")
+	create := CreateBenefitRequest{Name: "Guarded benefit", Points: 500, Partner: "GIFTCO", Category: "Retail", Active: true}
+
+	if rec := serve(s, http.MethodPost, "/v1/benefits", user, create); rec.Code != http.StatusForbidden {
+		t.Fatalf("user create: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	rec := serve(s, http.MethodPost, "/v1/benefits", admin, create)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("admin create: status = %d: %s", rec.Code, rec.Body)
+	}
+	var benefit Benefit
+	if err := json.NewDecoder(rec.Body).Decode(&benefit); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	name := "Renamed benefit"
+	mutations := []struct {
+		method string
+		body   interface{}
+		want   int
+	}{
+		{http.MethodPut, UpdateBenefitRequest{Name: &name}, http.StatusOK},
+		{http.MethodDelete, nil, http.StatusNoContent},
+	}
+	for _, m := range mutations {
+		path := "/v1/benefits/" + benefit.ID
+		if rec := serve(s, m.method, path, user, m.body); rec.Code != http.StatusForbidden {
+			t.Fatalf("user %s: status = %d, want %d", m.method, rec.Code, http.StatusForbidden)
+		}
+		if rec := serve(s, m.method, path, admin, m.body); rec.Code != m.want {
+			t.Fatalf("admin %s: status = %d, want %d: %s", m.method, rec.Code, m.want, rec.Body)
+		}
+	}
+
+	// Reads stay public
+	for _, path := range []string{"/v1/benefits", "/v1/categories", "/v1/partners"} {
+		if rec := serve(s, http.MethodGet, path, "", nil); rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want %d", path, rec.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestBenefitChangesRecordActingAdmin(t *testing.T) {
+	s := newTestService(t)
+	creatorID, editorID := uuid.New().String(), uuid.New().String()
+	creator, err := s.jwtManager.GenerateToken(creatorID, "creator@example.com", auth.RoleAdmin)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	editor, err := s.jwtManager.GenerateToken(editorID, "editor@example.com", auth.RoleAdmin)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	rec := serve(s, http.MethodPost, "/v1/benefits", creator, CreateBenefitRequest{
+		Name: "Audited benefit", Points: 500, Partner: "GIFTCO", Category: "Retail", Active: true,
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", rec.Code, rec.Body)
+	}
+	var created Benefit
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.CreatedBy != "user:"+creatorID || created.UpdatedBy != "user:"+creatorID {
+		t.Fatalf("created by %q, updated by %q; want user:%s for both", created.CreatedBy, created.UpdatedBy, creatorID)
+	}
+
+	name := "Renamed benefit"
+	rec = serve(s, http.MethodPut, "/v1/benefits/"+created.ID, editor, UpdateBenefitRequest{Name: &name})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+	}
+	var updated Benefit
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if updated.UpdatedBy != "user:"+editorID {
+		t.Fatalf("updated by %q, want user:%s", updated.UpdatedBy, editorID)
+	}
+}
+
+func TestBenefitUpdateStoresActingAdmin(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantID := databasetest.Tenant(t)
+	benefit := createTestBenefit(t, auth.WithTenant(context.Background(), tenantID), s)
+
+	editorID := uuid.New().String()
+	editor, err := s.jwtManager.GenerateTenantToken(editorID, "editor@example.com", auth.RoleAdmin, tenantID)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	active := false
+	if rec := serve(s, http.MethodPut, "/v1/benefits/"+benefit.ID, editor, UpdateBenefitRequest{Active: &active}); rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	stored, err := s.getBenefit(auth.WithTenant(context.Background(), tenantID), benefit.ID)
+	if err != nil {
+		t.Fatalf("failed to read benefit: %v", err)
+	}
+	if stored.UpdatedBy != "user:"+editorID {
+		t.Fatalf("stored updated_by = %q, want user:%s", stored.UpdatedBy, editorID)
+	}
+}
+
+func TestAdminRoutesRejectInvalidTokens(t *testing.T) {
+	s := newTestService(t)
+	forged, err := auth.NewJWTManager(&auth.JWTConfig{
+		Secret:     "another-secret-at-least-32-bytes-long",
+		Issuer:     s.config.Security.JWT.Issuer,
+		Audience:   s.config.Security.JWT.Audience,
+		Expiration: time.Minute,
+	}).GenerateToken(uuid.New().String(), "admin@example.com", auth.RoleAdmin)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		headers []string
+	}{
+		{"no token", nil},
+		{"user ID header", []string{"X-User-ID", "admin-1"}},
+		{"not a bearer token", []string{"Authorization", "Basic YWRtaW46cGFzcw=="}},
+		{"malformed token", []string{"Authorization", "Bearer not.a.token"}},
+		{"foreign signature", []string{"Authorization", "Bearer " + forged}},
+	}
+	requests := []struct{ method, path string }{
+		{http.MethodPost, "/v1/benefits"},
+		{http.MethodPut, "/v1/benefits/" + uuid.New().String()},
+		{http.MethodPost, "/v1/categories"},
+	}
+	for _, req := range requests {
+		for _, tt := range tests {
+			t.Run(req.method+" "+req.path+"/"+tt.name, func(t *testing.T) {
+				rec := serve(s, req.method, req.path, "", map[string]string{"name": "Dining"}, tt.headers...)
+				if rec.Code != http.StatusUnauthorized {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+				}
+				var body platformhttp.ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Code != platformhttp.ErrCodeUnauthorized {
+					t.Fatalf("body = %+v (err %v), want the %s error shape", body, err, platformhttp.ErrCodeUnauthorized)
+				}
+			})
+		}
+	}
+
+	if rec := serve(s, http.MethodGet, "/v1/benefits?include_deleted=true", adminToken(t, s, ""), nil); rec.Code != http.StatusOK {
+		t.Fatalf("valid token: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+}
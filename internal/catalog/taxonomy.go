@@ -0,0 +1,169 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// Categories and partners served when no database is configured. The same
+// values seed the benefit_categories and benefit_partners tables.
+var (
+	defaultCategories = []string{
+		"Travel",
+		"Retail",
+		"Dining",
+		"Entertainment",
+		"Technology",
+		"Health & Wellness",
+		"Charity",
+		"Cash Back",
+	}
+	defaultPartners = []string{
+		"GIFTCO",
+		"TRAVELCO",
+		"RETAILCO",
+		"DININGCO",
+		"ENTERTAINMENTCO",
+	}
+)
+
+// RegisterNameRequest registers a new benefit category or partner
+type RegisterNameRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// GetCategories returns all available benefit categories
+func (s *Service) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := s.listCategories(r.Context())
+	if err != nil {
+		s.logger.Errorf("Failed to get categories: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve categories")
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"categories": categories,
+	})
+}
+
+// GetPartners returns all available benefit partners
+func (s *Service) GetPartners(w http.ResponseWriter, r *http.Request) {
+	partners, err := s.listPartners(r.Context())
+	if err != nil {
+		s.logger.Errorf("Failed to get partners: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve partners")
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"partners": partners,
+	})
+}
+
+// CreateCategory registers a benefit category
+func (s *Service) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	s.registerName(w, r, "benefit_categories", cacheCategories, "category")
+}
+
+// CreatePartner registers a benefit partner
+func (s *Service) CreatePartner(w http.ResponseWriter, r *http.Request) {
+	s.registerName(w, r, "benefit_partners", cachePartners, "partner")
+}
+
+func (s *Service) registerName(w http.ResponseWriter, r *http.Request, table, namespace, kind string) {
+	var req RegisterNameRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	// required accepts whitespace, which makes no sense as a name
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		platformhttp.ValidationError(w, r, map[string]string{"name": "is required"})
+		return
+	}
+
+	if s.db == nil {
+		platformhttp.Error(w, r, http.StatusServiceUnavailable, platformhttp.ErrCodeUnavailable, "Database not available")
+		return
+	}
+
+	actor := authmw.Actor(r.Context())
+	err := s.db.Exec(r.Context(), `INSERT INTO `+table+` (tenant_id, name, created_by) VALUES ($1, $2, $3)`,
+		auth.TenantFromContext(r.Context()), name, actor)
+	if err != nil {
+		if database.IsUniqueViolation(err) {
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "A "+kind+" with this name already exists")
+			return
+		}
+		s.logger.Errorf("Failed to register %s %q: %v", kind, name, err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to register "+kind)
+		return
+	}
+
+	s.cache.InvalidateNamespace(namespace)
+	s.logger.Infof("Benefit %s %q registered by %s", kind, name, actor)
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]string{"name": name})
+}
+
+// listCategories returns the tenant's active categories through the cache
+func (s *Service) listCategories(ctx context.Context) ([]string, error) {
+	return s.listNames(ctx, cacheCategories, "benefit_categories", defaultCategories)
+}
+
+// listPartners returns the tenant's active partners through the cache
+func (s *Service) listPartners(ctx context.Context) ([]string, error) {
+	return s.listNames(ctx, cachePartners, "benefit_partners", defaultPartners)
+}
+
+func (s *Service) listNames(ctx context.Context, namespace, table string, defaults []string) ([]string, error) {
+	tenantID := auth.TenantFromContext(ctx)
+	names, err := s.cache.GetOrLoad(namespace, tenantID, func() (interface{}, error) {
+		if s.db == nil {
+			return defaults, nil
+		}
+		return s.getNames(ctx, table, tenantID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names.([]string), nil
+}
+
+func (s *Service) getNames(ctx context.Context, table, tenantID string) ([]string, error) {
+	rows, err := s.db.Query(ctx, `SELECT DISTINCT name FROM `+table+` WHERE tenant_id = $1 AND active ORDER BY name`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
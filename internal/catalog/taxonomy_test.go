@@ -0,0 +1,148 @@
+package catalog
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+func TestRegisterDuplicateNameConflicts(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantA, tenantB := databasetest.Tenant(t), databasetest.Tenant(t)
+
+	for _, path := range []string{"/v1/categories", "/v1/partners"} {
+		t.Run(path, func(t *testing.T) {
+			req := RegisterNameRequest{Name: "Wellness"}
+			if rec := serve(s, http.MethodPost, path, adminToken(t, s, tenantA), req); rec.Code != http.StatusCreated {
+				t.Fatalf("first: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+			}
+
+			// The primary key rejects the duplicate, which is a conflict rather than a 500
+			rec := serve(s, http.MethodPost, path, adminToken(t, s, tenantA), req)
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("duplicate: status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+			}
+			var body platformhttp.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.Code != platformhttp.ErrCodeConflict {
+				t.Fatalf("code = %q, want %q", body.Code, platformhttp.ErrCodeConflict)
+			}
+
+			// Names are unique per tenant
+			if rec := serve(s, http.MethodPost, path, adminToken(t, s, tenantB), req); rec.Code != http.StatusCreated {
+				t.Fatalf("other tenant: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+			}
+		})
+	}
+}
+
+// listedNames returns the names GET path serves to tenantID under key
+func listedNames(t *testing.T, s *Service, path, key, tenantID string) []string {
+	t.Helper()
+
+	rec := serve(s, http.MethodGet, path, "", nil, auth.TenantHeader, tenantID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: status = %d: %s", path, rec.Code, rec.Body)
+	}
+	var body map[string][]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return body[key]
+}
+
+func TestRegisterNameRequiresAdmin(t *testing.T) {
+	s := newTestService(t)
+	user, err := s.jwtManager.GenerateToken(uuid.New().String(), "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	for _, path := range []string{"/v1/categories", "/v1/partners"} {
+		if rec := serve(s, http.MethodPost, path, "", RegisterNameRequest{Name: "Wellness"}); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("anonymous POST %s: status = %d, want %d", path, rec.Code, http.StatusUnauthorized)
+		}
+		if rec := serve(s, http.MethodPost, path, user, RegisterNameRequest{Name: "Wellness"}); rec.Code != http.StatusForbidden {
+			t.Fatalf("user POST %s: status = %d, want %d", path, rec.Code, http.StatusForbidden)
+		}
+
+		// A blank name is rejected before the database is needed
+		rec := serve(s, http.MethodPost, path, adminToken(t, s, ""), RegisterNameRequest{Name: "   "})
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("blank POST %s: status = %d, want %d: %s", path, rec.Code, http.StatusUnprocessableEntity, rec.Body)
+		}
+		var body platformhttp.ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if body.Fields["name"] == "" {
+			t.Fatalf("fields = %v, want an error for name", body.Fields)
+		}
+	}
+}
+
+func TestCreateBenefitRequiresRegisteredNames(t *testing.T) {
+	s := newTestService(t)
+	req := CreateBenefitRequest{Name: "Mystery box", Points: 500, Partner: "NOBODY", Category: "Nothing", Active: true}
+
+	rec := serve(s, http.MethodPost, "/v1/benefits", adminToken(t, s, ""), req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+	}
+	var body platformhttp.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Fields["partner"] == "" || body.Fields["category"] == "" {
+		t.Fatalf("fields = %v, want errors for partner and category", body.Fields)
+	}
+
+	// Category is optional; the partner is not
+	req.Partner, req.Category = "GIFTCO", ""
+	if rec := serve(s, http.MethodPost, "/v1/benefits", adminToken(t, s, ""), req); rec.Code != http.StatusCreated {
+		t.Fatalf("without category: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+}
+
+func TestRegisteredNamesAreListedAndAccepted(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantA, tenantB := databasetest.Tenant(t), databasetest.Tenant(t)
+
+	// Load both tenants' lists into the cache before registering
+	for _, tenantID := range []string{tenantA, tenantB} {
+		listedNames(t, s, "/v1/categories", "categories", tenantID)
+		listedNames(t, s, "/v1/partners", "partners", tenantID)
+	}
+
+	for _, path := range []string{"/v1/categories", "/v1/partners"} {
+		if rec := serve(s, http.MethodPost, path, adminToken(t, s, tenantA), RegisterNameRequest{Name: " Acme "}); rec.Code != http.StatusCreated {
+			t.Fatalf("POST %s: status = %d, want %d: %s", path, rec.Code, http.StatusCreated, rec.Body)
+		}
+	}
+
+	for _, list := range []struct{ path, key string }{{"/v1/categories", "categories"}, {"/v1/partners", "partners"}} {
+		if got := listedNames(t, s, list.path, list.key, tenantA); !containsName(got, "Acme") {
+			t.Fatalf("%s = %v, want the registered name listed", list.key, got)
+		}
+		if got := listedNames(t, s, list.path, list.key, tenantB); containsName(got, "Acme") {
+			t.Fatalf("other tenant's %s = %v, want the name kept to its tenant", list.key, got)
+		}
+	}
+
+	create := CreateBenefitRequest{Name: "Acme voucher", Points: 500, Partner: "Acme", Category: "Acme", Active: true}
+	if rec := serve(s, http.MethodPost, "/v1/benefits", adminToken(t, s, tenantA), create); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	if rec := serve(s, http.MethodPost, "/v1/benefits", adminToken(t, s, tenantB), create); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("other tenant create: status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+	}
+}
@@ -0,0 +1,68 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// FieldErrors maps request fields to validation messages
+type FieldErrors map[string]string
+
+// validateBenefit checks a benefit's points, availability window, and that
+// its partner and category are registered. With rejectEnded set, the benefit
+// must also not have already ended. The error is set only if the registered
+// values could not be loaded.
+func (s *Service) validateBenefit(ctx context.Context, benefit *Benefit, rejectEnded bool, now time.Time) (FieldErrors, error) {
+	errs := FieldErrors{}
+
+	partners, err := s.listPartners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !containsName(partners, benefit.Partner) {
+		errs["partner"] = "must be a registered partner"
+	}
+
+	if benefit.Category != "" {
+		categories, err := s.listCategories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !containsName(categories, benefit.Category) {
+			errs["category"] = "must be a registered category"
+		}
+	}
+
+	if benefit.Points <= 0 {
+		errs["points"] = "must be greater than zero"
+	} else if max := s.config.Catalog.MaxPointsCost; max > 0 && benefit.Points > max {
+		errs["points"] = fmt.Sprintf("must not exceed %d", max)
+	}
+
+	// For updates the window combines the request with the stored values
+	if benefit.StartsAt != nil && benefit.EndsAt != nil && !benefit.EndsAt.After(*benefit.StartsAt) {
+		errs["ends_at"] = "must be after starts_at"
+	} else if rejectEnded && benefit.EndsAt != nil && !benefit.EndsAt.After(now) {
+		errs["ends_at"] = "must be in the future (set allow_past=true to create an ended benefit)"
+	}
+
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	return errs, nil
+}
+
+// normalizeBenefitTimes converts the availability window to UTC
+func normalizeBenefitTimes(benefit *Benefit) {
+	benefit.StartsAt = utcPtr(benefit.StartsAt)
+	benefit.EndsAt = utcPtr(benefit.EndsAt)
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
@@ -0,0 +1,188 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+func TestValidateBenefit(t *testing.T) {
+	s := newTestService(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) *time.Time {
+		ts := now.Add(offset)
+		return &ts
+	}
+	maxPoints := s.config.Catalog.MaxPointsCost
+
+	tests := []struct {
+		name        string
+		edit        func(*Benefit)
+		rejectEnded bool
+		want        FieldErrors
+	}{
+		{"valid", func(b *Benefit) {}, true, nil},
+		{"window", func(b *Benefit) { b.StartsAt, b.EndsAt = at(time.Hour), at(2*time.Hour) }, true, nil},
+		{"inverted window", func(b *Benefit) { b.StartsAt, b.EndsAt = at(2*time.Hour), at(time.Hour) }, true,
+			FieldErrors{"ends_at": "must be after starts_at"}},
+		{"empty window", func(b *Benefit) { b.StartsAt, b.EndsAt = at(time.Hour), at(time.Hour) }, true,
+			FieldErrors{"ends_at": "must be after starts_at"}},
+		{"inverted window on update", func(b *Benefit) { b.StartsAt, b.EndsAt = at(-time.Hour), at(-2*time.Hour) }, false,
+			FieldErrors{"ends_at": "must be after starts_at"}},
+		{"past end date", func(b *Benefit) { b.EndsAt = at(-time.Hour) }, true,
+			FieldErrors{"ends_at": "must be in the future (set allow_past=true to create an ended benefit)"}},
+		{"past end date allowed", func(b *Benefit) { b.EndsAt = at(-time.Hour) }, false, nil},
+		{"zero points", func(b *Benefit) { b.Points = 0 }, true, FieldErrors{"points": "must be greater than zero"}},
+		{"maximum points", func(b *Benefit) { b.Points = maxPoints }, true, nil},
+		{"excessive points", func(b *Benefit) { b.Points = maxPoints + 1 }, true,
+			FieldErrors{"points": fmt.Sprintf("must not exceed %d", maxPoints)}},
+		{"unknown partner and category", func(b *Benefit) { b.Partner, b.Category = "NOBODY", "Nothing" }, true,
+			FieldErrors{"partner": "must be a registered partner", "category": "must be a registered category"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			benefit := &Benefit{Name: "Test benefit", Points: 500, Partner: "GIFTCO", Category: "Retail"}
+			tt.edit(benefit)
+
+			errs, err := s.validateBenefit(context.Background(), benefit, tt.rejectEnded, now)
+			if err != nil {
+				t.Fatalf("validateBenefit: %v", err)
+			}
+			if len(errs) != len(tt.want) {
+				t.Fatalf("errors = %v, want %v", errs, tt.want)
+			}
+			for field, message := range tt.want {
+				if errs[field] != message {
+					t.Errorf("%s = %q, want %q", field, errs[field], message)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateBenefitValidation(t *testing.T) {
+	s := newTestService(t)
+	past := time.Now().Add(-time.Hour)
+	inverted := time.Now().Add(24 * time.Hour)
+	later := inverted.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		query      string
+		req        CreateBenefitRequest
+		want       int
+		wantFields []string
+	}{
+		{"inverted window", "", CreateBenefitRequest{Name: "Trip", Points: 500, Partner: "TRAVELCO", StartsAt: &later, EndsAt: &inverted},
+			http.StatusUnprocessableEntity, []string{"ends_at"}},
+		{"past end date", "", CreateBenefitRequest{Name: "Trip", Points: 500, Partner: "TRAVELCO", EndsAt: &past},
+			http.StatusUnprocessableEntity, []string{"ends_at"}},
+		{"past end date allowed", "?allow_past=true", CreateBenefitRequest{Name: "Trip", Points: 500, Partner: "TRAVELCO", EndsAt: &past},
+			http.StatusCreated, nil},
+		{"excessive points", "", CreateBenefitRequest{Name: "Trip", Points: 1_000_000, Partner: "TRAVELCO"},
+			http.StatusUnprocessableEntity, []string{"points"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, http.MethodPost, "/v1/benefits"+tt.query, adminToken(t, s, ""), tt.req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusUnprocessableEntity {
+				return
+			}
+
+			var body platformhttp.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.Code != platformhttp.ErrCodeValidationFailed || len(body.Fields) != len(tt.wantFields) {
+				t.Fatalf("error = %+v, want %s for %v", body, platformhttp.ErrCodeValidationFailed, tt.wantFields)
+			}
+			for _, field := range tt.wantFields {
+				if body.Fields[field] == "" {
+					t.Errorf("no error for %s in %v", field, body.Fields)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateBenefitNormalizesTimesToUTC(t *testing.T) {
+	s := newTestService(t)
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	startsAt := time.Now().Add(time.Hour).In(zone).Truncate(time.Second)
+	endsAt := startsAt.Add(24 * time.Hour)
+
+	rec := serve(s, http.MethodPost, "/v1/benefits", adminToken(t, s, ""),
+		CreateBenefitRequest{Name: "Trip", Points: 500, Partner: "TRAVELCO", StartsAt: &startsAt, EndsAt: &endsAt})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+
+	var benefit Benefit
+	if err := json.NewDecoder(rec.Body).Decode(&benefit); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if benefit.StartsAt == nil || benefit.StartsAt.Location() != time.UTC || !benefit.StartsAt.Equal(startsAt) {
+		t.Errorf("starts_at = %v, want %v in UTC", benefit.StartsAt, startsAt.UTC())
+	}
+	if benefit.EndsAt == nil || benefit.EndsAt.Location() != time.UTC || !benefit.EndsAt.Equal(endsAt) {
+		t.Errorf("ends_at = %v, want %v in UTC", benefit.EndsAt, endsAt.UTC())
+	}
+}
+
+func TestUpdateBenefitValidatesAgainstStoredWindow(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	withTestDB(t, s)
+	tenantID := databasetest.Tenant(t)
+	admin := adminToken(t, s, tenantID)
+
+	benefit := createTestBenefit(t, auth.WithTenant(context.Background(), tenantID), s)
+	startsAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	endsAt := startsAt.Add(48 * time.Hour)
+	if rec := serve(s, http.MethodPut, "/v1/benefits/"+benefit.ID, admin, UpdateBenefitRequest{StartsAt: &startsAt, EndsAt: &endsAt}); rec.Code != http.StatusOK {
+		t.Fatalf("set window: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	// Each update below sets one end of the window, so it is checked
+	// against the other end as stored
+	beforeStart := startsAt.Add(-time.Hour)
+	afterEnd := endsAt.Add(time.Hour)
+	withinWindow := startsAt.Add(time.Hour)
+	tests := []struct {
+		name string
+		req  UpdateBenefitRequest
+		want int
+	}{
+		{"end before stored start", UpdateBenefitRequest{EndsAt: &beforeStart}, http.StatusUnprocessableEntity},
+		{"end at stored start", UpdateBenefitRequest{EndsAt: &startsAt}, http.StatusUnprocessableEntity},
+		{"start after stored end", UpdateBenefitRequest{StartsAt: &afterEnd}, http.StatusUnprocessableEntity},
+		{"start within stored window", UpdateBenefitRequest{StartsAt: &withinWindow}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, http.MethodPut, "/v1/benefits/"+benefit.ID, admin, tt.req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusUnprocessableEntity {
+				return
+			}
+			var body platformhttp.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.Fields["ends_at"] != "must be after starts_at" {
+				t.Fatalf("fields = %v, want ends_at must be after starts_at", body.Fields)
+			}
+		})
+	}
+}
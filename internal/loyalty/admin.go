@@ -0,0 +1,176 @@
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// Audit log entries written for admin point adjustments
+const (
+	auditActionAdjustPoints = "points.adjust"
+	auditTargetLoyaltyUser  = "loyalty_user"
+)
+
+var errBelowMinimumBalance = errors.New("adjustment would take the balance below the allowed minimum")
+
+// AdminAdjustmentRequest is a manual credit (positive amount) or debit
+// (negative amount) made by a support agent
+type AdminAdjustmentRequest struct {
+	UserID string `json:"user_id" validate:"required"`
+	Amount int    `json:"amount" validate:"required"`
+	Reason string `json:"reason" validate:"required"`
+}
+
+// AdminAdjustPoints credits or debits a user's points on an admin's behalf,
+// recording an adjustment transaction and an audit_log entry naming the
+// admin in one database transaction. Debits may take the available balance
+// down to loyalty.adjustment_min_balance, which is negative to allow
+// clawing back points that were already spent. An Idempotency-Key header
+// makes the request safe to retry.
+func (s *Service) AdminAdjustPoints(w http.ResponseWriter, r *http.Request) {
+	var req AdminAdjustmentRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	adminID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
+		return
+	}
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+
+	result, err := s.applyAdminAdjustment(r.Context(), adminID, &req, idempotencyKey)
+	if database.IsUniqueViolation(err) {
+		// A concurrent retry with the same key committed first; replay it
+		result, err = s.applyAdminAdjustment(r.Context(), adminID, &req, idempotencyKey)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errUserNotFound):
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "User not found")
+		case errors.Is(err, errBelowMinimumBalance):
+			platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInsufficientPoints,
+				fmt.Sprintf("Adjustment would take the available balance below %d", s.config.Loyalty.AdjustmentMinBalance))
+		case errors.Is(err, errIdempotencyConflict):
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "Idempotency-Key already used with a different request")
+		default:
+			s.logger.Errorf("Failed to adjust points for user %s: %v", req.UserID, err)
+			platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to adjust points")
+		}
+		return
+	}
+
+	render.JSON(w, r, LoyaltyResponse{Success: true, Message: "Points adjusted successfully", Data: result})
+}
+
+// applyAdminAdjustment records the adjustment and its audit entry and updates
+// the balance atomically. Adjustments do not count toward lifetime points, so
+// they never change the user's tier.
+func (s *Service) applyAdminAdjustment(ctx context.Context, adminID string, req *AdminAdjustmentRequest, idempotencyKey string) (*AdjustmentResult, error) {
+	tenantID := auth.TenantFromContext(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var points, held int
+	err = tx.QueryRow(ctx, `
+		SELECT u.points, COALESCE((
+			SELECT SUM(h.amount) FROM loyalty_point_holds h
+			WHERE h.user_id = u.id AND h.status = 'held' AND h.expires_at > NOW()
+		), 0)
+		FROM loyalty_users u WHERE u.id = $1 AND u.tenant_id = $2 FOR UPDATE
+	`, req.UserID, tenantID).Scan(&points, &held)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, err
+	}
+
+	if idempotencyKey != "" {
+		// The user row lock serializes retries, so the lookup cannot race the insert
+		existing, balanceAfter, err := s.getTransactionByKey(ctx, tx, req.UserID, "adjustment", idempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if existing.Amount != req.Amount || existing.Description != req.Reason {
+				return nil, errIdempotencyConflict
+			}
+			return &AdjustmentResult{Transaction: existing, Balance: balanceAfter, Replayed: true}, nil
+		}
+	}
+
+	// Points reserved by active holds cannot be clawed back
+	if req.Amount < 0 && points-held+req.Amount < s.config.Loyalty.AdjustmentMinBalance {
+		return nil, errBelowMinimumBalance
+	}
+
+	now := time.Now()
+	balance := points + req.Amount
+	transaction := &Transaction{
+		ID:          uuid.New().String(),
+		UserID:      req.UserID,
+		Type:        "adjustment",
+		Amount:      req.Amount,
+		Description: req.Reason,
+		CreatedAt:   now,
+		CreatedBy:   authmw.Actor(ctx),
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO loyalty_transactions (id, tenant_id, user_id, type, amount, description, idempotency_key, balance_after, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10)
+	`, transaction.ID, tenantID, transaction.UserID, transaction.Type, transaction.Amount, transaction.Description, idempotencyKey, balance, transaction.CreatedBy, now)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE loyalty_users SET points = $1, updated_at = $2 WHERE id = $3 AND tenant_id = $4`,
+		balance, now, req.UserID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := json.Marshal(map[string]interface{}{
+		"transaction_id": transaction.ID,
+		"amount":         req.Amount,
+		"balance_before": points,
+		"balance_after":  balance,
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO audit_log (id, tenant_id, actor_id, action, target_type, target_id, reason, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, uuid.New().String(), tenantID, adminID, auditActionAdjustPoints, auditTargetLoyaltyUser, req.UserID, req.Reason, details, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.invalidateBalance(ctx, req.UserID)
+
+	s.logger.Infof("Admin %s adjusted points for user %s by %d: %s", adminID, req.UserID, req.Amount, req.Reason)
+	return &AdjustmentResult{Transaction: transaction, Balance: balance}, nil
+}
@@ -0,0 +1,184 @@
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// adminAdjust posts req to the admin adjust endpoint as adminID in ctx's
+// tenant, returning the status and the decoded result
+func adminAdjust(t *testing.T, ctx context.Context, s *Service, adminID string, req AdminAdjustmentRequest, headers ...string) (int, *AdjustmentResult) {
+	t.Helper()
+
+	tok, err := s.jwtManager.GenerateTenantToken(adminID, adminID+"@example.com", auth.RoleAdmin, auth.TenantFromContext(ctx))
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	rec := serve(s, http.MethodPost, "/v1/loyalty/admin/adjust", tok, req, headers...)
+	var body struct {
+		Data *AdjustmentResult `json:"data"`
+	}
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, body.Data
+}
+
+// auditEntry is an audit_log row
+type auditEntry struct {
+	ActorID, Action, TargetType, TargetID, Reason string
+	Details                                       map[string]interface{}
+}
+
+// auditEntries reads the audit log entries about a loyalty user
+func auditEntries(t *testing.T, ctx context.Context, s *Service, userID string) []auditEntry {
+	t.Helper()
+
+	rows, err := s.db.Query(ctx, `
+		SELECT actor_id, action, target_type, target_id, reason, details
+		FROM audit_log WHERE tenant_id = $1 AND target_id = $2 ORDER BY created_at
+	`, auth.TenantFromContext(ctx), userID)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []auditEntry
+	for rows.Next() {
+		var entry auditEntry
+		var details []byte
+		if err := rows.Scan(&entry.ActorID, &entry.Action, &entry.TargetType, &entry.TargetID, &entry.Reason, &details); err != nil {
+			t.Fatalf("failed to scan audit log: %v", err)
+		}
+		if err := json.Unmarshal(details, &entry.Details); err != nil {
+			t.Fatalf("audit details are not JSON: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	return entries
+}
+
+func TestAdminAdjustRequestValidation(t *testing.T) {
+	s := newTestService(t)
+	valid := AdminAdjustmentRequest{UserID: "user-1", Amount: 100, Reason: "goodwill"}
+
+	tests := []struct {
+		name string
+		tok  string
+		req  AdminAdjustmentRequest
+		want int
+	}{
+		{"users may not adjust", token(t, s, "user-1", "user"), valid, http.StatusForbidden},
+		{"services may not adjust", token(t, s, "partner-gateway", auth.RoleService), valid, http.StatusForbidden},
+		{"no reason", token(t, s, "admin-1", auth.RoleAdmin), AdminAdjustmentRequest{UserID: "user-1", Amount: 100}, http.StatusUnprocessableEntity},
+		{"zero amount", token(t, s, "admin-1", auth.RoleAdmin), AdminAdjustmentRequest{UserID: "user-1", Reason: "goodwill"}, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, http.MethodPost, "/v1/loyalty/admin/adjust", tt.tok, tt.req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestAdminAdjustPoints(t *testing.T) {
+	s := newTestService(t, withTenancy, func(cfg *config.Config) { cfg.Loyalty.AdjustmentMinBalance = -500 })
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 1000)
+
+	// A credit
+	status, result := adminAdjust(t, ctx, s, "admin-1", AdminAdjustmentRequest{UserID: userID, Amount: 250, Reason: "goodwill for a late delivery"})
+	if status != http.StatusOK || result.Balance != 1250 {
+		t.Fatalf("credit: status %d, result %+v; want balance 1250", status, result)
+	}
+	credit := result.Transaction
+	if credit.Type != "adjustment" || credit.Amount != 250 || credit.CreatedBy != auth.ActorUserPrefix+"admin-1" {
+		t.Fatalf("credit transaction = %+v, want a 250 point adjustment by admin-1", credit)
+	}
+
+	// A debit may take the balance negative, down to the configured minimum
+	status, result = adminAdjust(t, ctx, s, "admin-2", AdminAdjustmentRequest{UserID: userID, Amount: -1500, Reason: "fraud clawback"})
+	if status != http.StatusOK || result.Balance != -250 {
+		t.Fatalf("debit: status %d, result %+v; want balance -250", status, result)
+	}
+	if status, _ := adminAdjust(t, ctx, s, "admin-2", AdminAdjustmentRequest{UserID: userID, Amount: -251, Reason: "fraud clawback"}); status != http.StatusBadRequest {
+		t.Fatalf("debit below the minimum: status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if points := userPoints(t, ctx, s, userID); points != -250 {
+		t.Fatalf("points = %d, want -250", points)
+	}
+
+	// Adjustments never count toward the tier
+	var lifetime int
+	if err := s.db.QueryRow(ctx, `SELECT lifetime_points FROM loyalty_users WHERE id = $1`, userID).Scan(&lifetime); err != nil {
+		t.Fatalf("failed to read lifetime points: %v", err)
+	}
+	if lifetime != 1000 {
+		t.Fatalf("lifetime points = %d, want 1000", lifetime)
+	}
+
+	// One audit entry per applied adjustment, naming the admin from the token
+	entries := auditEntries(t, ctx, s, userID)
+	if len(entries) != 2 {
+		t.Fatalf("audit log has %d entries, want 2", len(entries))
+	}
+	want := []struct {
+		actor, reason         string
+		amount, before, after float64
+	}{
+		{"admin-1", "goodwill for a late delivery", 250, 1000, 1250},
+		{"admin-2", "fraud clawback", -1500, 1250, -250},
+	}
+	for i, entry := range entries {
+		w := want[i]
+		if entry.ActorID != w.actor || entry.Action != auditActionAdjustPoints || entry.TargetType != auditTargetLoyaltyUser || entry.TargetID != userID || entry.Reason != w.reason {
+			t.Errorf("audit entry %d = %+v, want %s adjusting %s: %s", i, entry, w.actor, userID, w.reason)
+		}
+		d := entry.Details
+		if d["amount"] != w.amount || d["balance_before"] != w.before || d["balance_after"] != w.after {
+			t.Errorf("audit entry %d details = %v, want amount %v from %v to %v", i, d, w.amount, w.before, w.after)
+		}
+	}
+	if entries[0].Details["transaction_id"] != credit.ID {
+		t.Errorf("audit transaction_id = %v, want %s", entries[0].Details["transaction_id"], credit.ID)
+	}
+}
+
+func TestAdminAdjustIdempotency(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 1000)
+	key := uuid.New().String()
+	req := AdminAdjustmentRequest{UserID: userID, Amount: 100, Reason: "goodwill"}
+
+	for i, replayed := range []bool{false, true} {
+		status, result := adminAdjust(t, ctx, s, "admin-1", req, "Idempotency-Key", key)
+		if status != http.StatusOK || result.Replayed != replayed || result.Balance != 1100 {
+			t.Fatalf("attempt %d: status %d, result %+v; want balance 1100, replayed %v", i, status, result, replayed)
+		}
+	}
+	if entries := auditEntries(t, ctx, s, userID); len(entries) != 1 {
+		t.Fatalf("audit log has %d entries after a retry, want 1", len(entries))
+	}
+
+	req.Amount = 200
+	if status, _ := adminAdjust(t, ctx, s, "admin-1", req, "Idempotency-Key", key); status != http.StatusConflict {
+		t.Fatalf("reused key: status = %d, want %d", status, http.StatusConflict)
+	}
+	if status, _ := adminAdjust(t, ctx, s, "admin-1", AdminAdjustmentRequest{UserID: uuid.New().String(), Amount: 100, Reason: "goodwill"}); status != http.StatusNotFound {
+		t.Fatalf("unknown user: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
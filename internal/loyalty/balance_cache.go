@@ -0,0 +1,135 @@
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// balanceCacheRetryInterval is how long the cache is bypassed after Redis fails
+	balanceCacheRetryInterval = 5 * time.Second
+	// balanceInvalidateTimeout bounds an invalidation, which outlives the
+	// request that made the change
+	balanceInvalidateTimeout = time.Second
+)
+
+// balanceCache keeps users' balances in Redis so balance reads, the hottest
+// path during promotions, do not each query Postgres. Every operation that
+// changes a user's points or holds invalidates the entry after its database
+// transaction commits; a read that races a write can still cache the old
+// balance, so the TTL bounds how long it is served. While Redis is
+// unreachable, reads go straight to the database.
+type balanceCache struct {
+	redis  *database.RedisDB
+	ttl    time.Duration
+	logger *logrus.Logger
+
+	mu sync.Mutex
+	// bypassUntil skips Redis after a failure instead of timing out every read
+	bypassUntil time.Time
+}
+
+// newBalanceCache returns the balance cache, or nil when it is disabled
+func newBalanceCache(redis *database.RedisDB, ttl time.Duration, logger *logrus.Logger) *balanceCache {
+	if redis == nil || ttl <= 0 {
+		return nil
+	}
+	return &balanceCache{redis: redis, ttl: ttl, logger: logger}
+}
+
+// balanceCacheKey scopes entries to a tenant so reads never cross tenants
+func balanceCacheKey(tenantID, userID string) string {
+	return "loyalty:balance:" + tenantID + ":" + userID
+}
+
+// get returns a cached balance. Failures are logged and reported as a miss.
+func (c *balanceCache) get(ctx context.Context, tenantID, userID string) (*User, bool) {
+	if c == nil || c.bypassed() {
+		return nil, false
+	}
+
+	reply, err := c.redis.Do(ctx, "GET", balanceCacheKey(tenantID, userID))
+	if err != nil {
+		c.fail("read", err)
+		return nil, false
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return nil, false
+	}
+
+	var user User
+	if err := json.Unmarshal([]byte(value), &user); err != nil {
+		c.logger.Warnf("Discarding unreadable cached balance for user %s: %v", userID, err)
+		return nil, false
+	}
+	return &user, true
+}
+
+// set caches a balance read from the database
+func (c *balanceCache) set(ctx context.Context, tenantID string, user *User) {
+	if c == nil || c.bypassed() {
+		return
+	}
+
+	value, err := json.Marshal(user)
+	if err != nil {
+		c.logger.Warnf("Failed to encode balance for user %s: %v", user.ID, err)
+		return
+	}
+	if err := c.redis.SetEX(ctx, balanceCacheKey(tenantID, user.ID), string(value), c.ttl); err != nil {
+		c.fail("write", err)
+	}
+}
+
+// invalidate drops a user's cached balance. Call it only after the change
+// has committed, or a concurrent read could cache the balance being replaced.
+func (c *balanceCache) invalidate(ctx context.Context, tenantID, userID string) {
+	if c == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), balanceInvalidateTimeout)
+	defer cancel()
+	if _, err := c.redis.Do(ctx, "DEL", balanceCacheKey(tenantID, userID)); err != nil {
+		c.fail("invalidate", err)
+	}
+}
+
+func (c *balanceCache) bypassed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Before(c.bypassUntil)
+}
+
+func (c *balanceCache) fail(op string, err error) {
+	c.mu.Lock()
+	c.bypassUntil = time.Now().Add(balanceCacheRetryInterval)
+	c.mu.Unlock()
+	c.logger.Warnf("Balance cache %s failed, using the database for %s: %v", op, balanceCacheRetryInterval, err)
+}
+
+// getBalance returns a user's balance through the cache
+func (s *Service) getBalance(ctx context.Context, userID string) (*User, error) {
+	tenantID := auth.TenantFromContext(ctx)
+	if user, ok := s.balances.get(ctx, tenantID, userID); ok {
+		return user, nil
+	}
+
+	user, err := s.getUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.balances.set(ctx, tenantID, user)
+	return user, nil
+}
+
+// invalidateBalance drops a user's cached balance in the tenant of ctx
+func (s *Service) invalidateBalance(ctx context.Context, userID string) {
+	s.balances.invalidate(ctx, auth.TenantFromContext(ctx), userID)
+}
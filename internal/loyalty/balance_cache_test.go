@@ -0,0 +1,216 @@
+package loyalty
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+)
+
+// fakeRedis answers the GET, SET, and DEL commands the balance cache sends,
+// ignoring expiry
+type fakeRedis struct {
+	listener net.Listener
+
+	mu   sync.Mutex
+	data map[string]string
+}
+
+// newFakeRedis starts a fake Redis server, stopped when the test ends
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	r := &fakeRedis{listener: listener, data: make(map[string]string)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		r.mu.Lock()
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			if value, ok := r.data[args[1]]; ok {
+				reply = "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+			} else {
+				reply = "$-1\r\n"
+			}
+		case "SET":
+			r.data[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case "DEL":
+			_, ok := r.data[args[1]]
+			delete(r.data, args[1])
+			reply = ":0\r\n"
+			if ok {
+				reply = ":1\r\n"
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		r.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as a RESP array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, n)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func (r *fakeRedis) cached(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.data[key]
+	return value, ok
+}
+
+// withBalanceCache caches s's balances in a fake Redis, which it returns
+func withBalanceCache(t *testing.T, s *Service) *fakeRedis {
+	t.Helper()
+
+	redis := newFakeRedis(t)
+	s.balances = newBalanceCache(database.OpenRedisDB(&database.RedisConfig{Addr: redis.listener.Addr().String()}), time.Minute, s.logger)
+	return redis
+}
+
+func TestBalanceCacheHitAndMiss(t *testing.T) {
+	s := newTestService(t)
+	redis := withBalanceCache(t, s)
+	ctx := context.Background()
+
+	if _, ok := s.balances.get(ctx, "tenant-a", "user-1"); ok {
+		t.Fatal("hit on an empty cache")
+	}
+
+	s.balances.set(ctx, "tenant-a", &User{ID: "user-1", Points: 1500, Tier: "Silver"})
+	if _, ok := redis.cached("loyalty:balance:tenant-a:user-1"); !ok {
+		t.Fatal("balance not stored under its tenant's key")
+	}
+	user, ok := s.balances.get(ctx, "tenant-a", "user-1")
+	if !ok || user.Points != 1500 || user.Tier != "Silver" {
+		t.Fatalf("get = %+v, %v; want the cached balance", user, ok)
+	}
+
+	// Entries never cross tenants
+	if _, ok := s.balances.get(ctx, "tenant-b", "user-1"); ok {
+		t.Fatal("hit for another tenant's user")
+	}
+
+	s.balances.invalidate(ctx, "tenant-a", "user-1")
+	if _, ok := s.balances.get(ctx, "tenant-a", "user-1"); ok {
+		t.Fatal("hit after invalidation")
+	}
+}
+
+func TestBalanceCacheDisabled(t *testing.T) {
+	redis := database.OpenRedisDB(&database.RedisConfig{Addr: "127.0.0.1:0"})
+	if newBalanceCache(nil, time.Minute, nil) != nil || newBalanceCache(redis, 0, nil) != nil {
+		t.Fatal("cache enabled without Redis or a TTL")
+	}
+
+	// A disabled cache always misses
+	var cache *balanceCache
+	cache.set(context.Background(), "tenant-a", &User{ID: "user-1"})
+	if _, ok := cache.get(context.Background(), "tenant-a", "user-1"); ok {
+		t.Fatal("disabled cache hit")
+	}
+	cache.invalidate(context.Background(), "tenant-a", "user-1")
+}
+
+func TestBalanceCacheBypassesUnavailableRedis(t *testing.T) {
+	s := newTestService(t)
+	redis := withBalanceCache(t, s)
+	redis.listener.Close()
+
+	if _, ok := s.balances.get(context.Background(), "tenant-a", "user-1"); ok {
+		t.Fatal("hit with Redis down")
+	}
+	if !s.balances.bypassed() {
+		t.Fatal("cache still used after Redis failed")
+	}
+}
+
+func TestBalanceCacheInvalidatedByPointsChanges(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	redis := withBalanceCache(t, s)
+	userID := createTestUser(t, ctx, s, 1000)
+	key := balanceCacheKey(auth.TenantFromContext(ctx), userID)
+
+	// A miss reads the database and fills the cache
+	user, err := s.getBalance(ctx, userID)
+	if err != nil || user.Points != 1000 {
+		t.Fatalf("getBalance = %+v, %v; want 1000 points", user, err)
+	}
+	if _, ok := redis.cached(key); !ok {
+		t.Fatal("balance not cached after a miss")
+	}
+
+	for _, change := range []struct {
+		kind   string
+		amount int
+		want   int
+	}{{"earn", 250, 1250}, {"spend", 1000, 250}} {
+		transaction := &Transaction{ID: uuid.New().String(), UserID: userID, Type: change.kind, Amount: change.amount, Description: change.kind, CreatedAt: time.Now()}
+		if _, err := s.applyPointsChange(ctx, transaction, ""); err != nil {
+			t.Fatalf("%s: %v", change.kind, err)
+		}
+		if _, ok := redis.cached(key); ok {
+			t.Fatalf("balance still cached after %s", change.kind)
+		}
+		if user, err := s.getBalance(ctx, userID); err != nil || user.Points != change.want {
+			t.Fatalf("balance after %s = %+v, %v; want %d points", change.kind, user, err, change.want)
+		}
+	}
+}
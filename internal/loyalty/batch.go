@@ -0,0 +1,208 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// Batch item statuses
+const (
+	BatchItemApplied    = "applied"
+	BatchItemReplayed   = "replayed"
+	BatchItemFailed     = "failed"
+	BatchItemRolledBack = "rolled_back"
+)
+
+// BatchEarnItem is one earn in a batch. IdempotencyKey makes the item safe
+// to resend, as the Idempotency-Key header does for a single earn.
+type BatchEarnItem struct {
+	UserID         string `json:"user_id" validate:"required"`
+	Amount         int    `json:"amount" validate:"required,min=1"`
+	Description    string `json:"description" validate:"required"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// BatchEarnRequest earns points for many users at once
+type BatchEarnRequest struct {
+	Items []BatchEarnItem `json:"items" validate:"required,min=1"`
+}
+
+// BatchEarnItemResult is the outcome of one batch item
+type BatchEarnItemResult struct {
+	Index       int          `json:"index"`
+	UserID      string       `json:"user_id"`
+	Status      string       `json:"status"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	// Balance is the user's points after the item was applied
+	Balance *int                        `json:"balance,omitempty"`
+	Error   *platformhttp.ErrorResponse `json:"error,omitempty"`
+}
+
+// BatchEarnResult summarizes a batch earn
+type BatchEarnResult struct {
+	Atomic   bool                   `json:"atomic"`
+	Applied  int                    `json:"applied"`
+	Replayed int                    `json:"replayed"`
+	Failed   int                    `json:"failed"`
+	Items    []*BatchEarnItemResult `json:"items"`
+}
+
+// EarnPointsBatch earns points for a batch of transactions in one database
+// transaction. Each item succeeds or fails on its own, unless ?atomic=true
+// is set, in which case any failure rolls back the whole batch.
+func (s *Service) EarnPointsBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchEarnRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	if limit := s.config.Loyalty.MaxEarnBatch; limit > 0 && len(req.Items) > limit {
+		platformhttp.ValidationError(w, r, map[string]string{"items": fmt.Sprintf("must contain at most %d items", limit)})
+		return
+	}
+
+	atomic, _ := strconv.ParseBool(r.URL.Query().Get("atomic"))
+
+	result, err := s.applyEarnBatch(r.Context(), req.Items, atomic)
+	if err != nil {
+		s.logger.Errorf("Failed to apply earn batch of %d items: %v", len(req.Items), err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to process points earning")
+		return
+	}
+
+	if atomic && result.Failed > 0 {
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Batch rolled back because items failed", Data: result})
+		return
+	}
+
+	render.JSON(w, r, LoyaltyResponse{Success: true, Message: "Batch processed", Data: result})
+}
+
+// applyEarnBatch applies each item in a savepoint, so a failed item is
+// undone without losing the items before it. An error is returned only when
+// the batch itself could not be processed.
+func (s *Service) applyEarnBatch(ctx context.Context, items []BatchEarnItem, atomic bool) (*BatchEarnResult, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	result := &BatchEarnResult{Atomic: atomic, Items: make([]*BatchEarnItemResult, len(items))}
+	var tierChanges []string
+	for i := range items {
+		item := &items[i]
+		itemResult := &BatchEarnItemResult{Index: i, UserID: item.UserID}
+		result.Items[i] = itemResult
+
+		if fields := platformhttp.Validate(item); fields != nil {
+			itemResult.fail(platformhttp.ErrCodeValidationFailed, "Validation failed", fields)
+			continue
+		}
+
+		change, err := s.earnBatchItem(ctx, tx, item)
+		switch {
+		case errors.Is(err, errUserNotFound):
+			itemResult.fail(platformhttp.ErrCodeNotFound, "User not found", nil)
+		case errors.Is(err, errIdempotencyConflict):
+			itemResult.fail(platformhttp.ErrCodeConflict, "Idempotency key already used with a different request", nil)
+		case err != nil:
+			return nil, err
+		default:
+			itemResult.Status = BatchItemApplied
+			if change.Replayed {
+				itemResult.Status = BatchItemReplayed
+			}
+			itemResult.Transaction = change.Transaction
+			itemResult.Balance = &change.Balance
+			if change.TierChange != nil {
+				tierChanges = append(tierChanges, fmt.Sprintf("User %s moved from tier %s to %s", item.UserID, change.TierChange.From, change.TierChange.To))
+			}
+		}
+	}
+
+	for _, item := range result.Items {
+		if item.Status == BatchItemFailed {
+			result.Failed++
+		}
+	}
+
+	if atomic && result.Failed > 0 {
+		for _, item := range result.Items {
+			if item.Status != BatchItemFailed {
+				item.Status = BatchItemRolledBack
+				item.Transaction = nil
+				item.Balance = nil
+			}
+		}
+		return result, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	tenantID := auth.TenantFromContext(ctx)
+	for _, item := range result.Items {
+		switch item.Status {
+		case BatchItemApplied:
+			result.Applied++
+			s.balances.invalidate(ctx, tenantID, item.UserID)
+			recordPointsMetrics(item.Transaction)
+		case BatchItemReplayed:
+			result.Replayed++
+		}
+	}
+	for _, change := range tierChanges {
+		s.logger.Info(change)
+	}
+	s.logger.Infof("Applied earn batch by %s: %d applied, %d replayed, %d failed", authmw.Actor(ctx), result.Applied, result.Replayed, result.Failed)
+	return result, nil
+}
+
+// earnBatchItem records one item's earn inside a savepoint of tx
+func (s *Service) earnBatchItem(ctx context.Context, tx pgx.Tx, item *BatchEarnItem) (*pointsChange, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer savepoint.Rollback(ctx)
+
+	now := time.Now()
+	change, err := s.recordPointsChange(ctx, savepoint, &Transaction{
+		ID:          uuid.New().String(),
+		UserID:      item.UserID,
+		Type:        "earn",
+		Amount:      item.Amount,
+		Description: item.Description,
+		CreatedAt:   now,
+		ExpiresAt:   s.earnExpiry(now),
+		CreatedBy:   authmw.Actor(ctx),
+	}, item.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := savepoint.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (r *BatchEarnItemResult) fail(code platformhttp.ErrorCode, message string, fields map[string]string) {
+	r.Status = BatchItemFailed
+	r.Error = &platformhttp.ErrorResponse{Code: code, Message: message, Fields: fields}
+}
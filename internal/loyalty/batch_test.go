@@ -0,0 +1,126 @@
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// earnBatch posts items to the batch earn endpoint as a service in ctx's
+// tenant, returning the status and the decoded result
+func earnBatch(t *testing.T, ctx context.Context, s *Service, path string, items []BatchEarnItem) (int, *BatchEarnResult) {
+	t.Helper()
+
+	rec := serve(s, http.MethodPost, path, token(t, s, "partner-gateway", auth.RoleService),
+		BatchEarnRequest{Items: items}, auth.TenantHeader, auth.TenantFromContext(ctx))
+	var body struct {
+		Data *BatchEarnResult `json:"data"`
+	}
+	if rec.Code == http.StatusOK || rec.Code == http.StatusUnprocessableEntity {
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, body.Data
+}
+
+func TestEarnBatchRequestValidation(t *testing.T) {
+	s := newTestService(t, func(cfg *config.Config) { cfg.Loyalty.MaxEarnBatch = 2 })
+	item := BatchEarnItem{UserID: "user-1", Amount: 10, Description: "purchase"}
+
+	tests := []struct {
+		name  string
+		tok   string
+		items []BatchEarnItem
+		want  int
+	}{
+		{"users may not batch", token(t, s, "user-1", "user"), []BatchEarnItem{item}, http.StatusForbidden},
+		{"no items", token(t, s, "partner-gateway", auth.RoleService), []BatchEarnItem{}, http.StatusUnprocessableEntity},
+		{"over the size limit", token(t, s, "partner-gateway", auth.RoleService), []BatchEarnItem{item, item, item}, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, http.MethodPost, "/v1/loyalty/earn/batch", tt.tok, BatchEarnRequest{Items: tt.items})
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestEarnBatchMixedItems(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 1000)
+	key := uuid.New().String()
+
+	status, result := earnBatch(t, ctx, s, "/v1/loyalty/earn/batch", []BatchEarnItem{
+		{UserID: userID, Amount: 100, Description: "purchase"},
+		{UserID: userID, Amount: 0, Description: "purchase"},
+		{UserID: uuid.New().String(), Amount: 100, Description: "purchase"},
+		{UserID: userID, Amount: 50, Description: "purchase", IdempotencyKey: key},
+		{UserID: userID, Amount: 50, Description: "purchase", IdempotencyKey: key},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+	if result.Applied != 2 || result.Replayed != 1 || result.Failed != 2 {
+		t.Fatalf("applied %d, replayed %d, failed %d; want 2, 1, 2", result.Applied, result.Replayed, result.Failed)
+	}
+
+	wantStatus := []string{BatchItemApplied, BatchItemFailed, BatchItemFailed, BatchItemApplied, BatchItemReplayed}
+	wantCode := []platformhttp.ErrorCode{"", platformhttp.ErrCodeValidationFailed, platformhttp.ErrCodeNotFound, "", ""}
+	for i, item := range result.Items {
+		if item.Index != i || item.Status != wantStatus[i] {
+			t.Errorf("item %d = %d %s, want %s", i, item.Index, item.Status, wantStatus[i])
+		}
+		if (item.Error == nil) != (wantCode[i] == "") || (item.Error != nil && item.Error.Code != wantCode[i]) {
+			t.Errorf("item %d error = %+v, want %q", i, item.Error, wantCode[i])
+		}
+	}
+	if balance := result.Items[3].Balance; balance == nil || *balance != 1150 {
+		t.Errorf("balance after item 3 = %v, want 1150", balance)
+	}
+
+	// Failed items do not roll back the others, and the replay earns nothing
+	if points := userPoints(t, ctx, s, userID); points != 1150 {
+		t.Fatalf("points = %d, want 1150", points)
+	}
+}
+
+func TestEarnBatchAtomic(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 1000)
+
+	status, result := earnBatch(t, ctx, s, "/v1/loyalty/earn/batch?atomic=true", []BatchEarnItem{
+		{UserID: userID, Amount: 100, Description: "purchase"},
+		{UserID: uuid.New().String(), Amount: 100, Description: "purchase"},
+	})
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", status, http.StatusUnprocessableEntity)
+	}
+	if !result.Atomic || result.Applied != 0 || result.Items[0].Status != BatchItemRolledBack || result.Items[0].Balance != nil {
+		t.Fatalf("result = %+v, first item %+v; want it rolled back", result, result.Items[0])
+	}
+	if points := userPoints(t, ctx, s, userID); points != 1000 {
+		t.Fatalf("points = %d after a rolled back batch, want 1000", points)
+	}
+
+	status, result = earnBatch(t, ctx, s, "/v1/loyalty/earn/batch?atomic=true", []BatchEarnItem{
+		{UserID: userID, Amount: 100, Description: "purchase"},
+		{UserID: userID, Amount: 200, Description: "purchase"},
+	})
+	if status != http.StatusOK || result.Applied != 2 {
+		t.Fatalf("status = %d, applied = %d; want the whole batch applied", status, result.Applied)
+	}
+	if points := userPoints(t, ctx, s, userID); points != 1300 {
+		t.Fatalf("points = %d, want 1300", points)
+	}
+}
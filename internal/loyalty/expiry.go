@@ -0,0 +1,165 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+)
+
+// expiryActor records the sweeper as the creator of expire transactions
+const expiryActor = auth.ActorServicePrefix + "loyalty-svc"
+
+// earnExpiry returns when points earned at earnedAt expire, or nil when
+// points expiry is disabled
+func (s *Service) earnExpiry(earnedAt time.Time) *time.Time {
+	if s.config.Loyalty.PointsExpiry <= 0 {
+		return nil
+	}
+	expiresAt := earnedAt.Add(s.config.Loyalty.PointsExpiry)
+	return &expiresAt
+}
+
+// RunExpirySweeper periodically expires unspent points past their expiry
+// until ctx is cancelled
+func (s *Service) RunExpirySweeper(ctx context.Context) {
+	interval := s.config.Loyalty.ExpirySweepInterval
+	if interval <= 0 || s.config.Loyalty.PointsExpiry <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.expirePoints(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.logger.Errorf("Failed to expire points: %v", err)
+			}
+			if expired > 0 {
+				s.logger.Infof("Expired %d unspent points", expired)
+			}
+		}
+	}
+}
+
+// expirePoints expires points for every user with expired earn transactions
+// and returns the total number of points expired
+func (s *Service) expirePoints(ctx context.Context) (int, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT DISTINCT u.id, u.tenant_id
+		FROM loyalty_users u
+		JOIN loyalty_transactions t ON t.user_id = u.id
+		WHERE t.type = 'earn' AND t.expires_at <= NOW() AND u.points > 0
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	type candidate struct{ userID, tenantID string }
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.userID, &c.tenantID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, c := range candidates {
+		expired, err := s.expireUserPoints(ctx, c.userID, c.tenantID)
+		if err != nil {
+			return total, err
+		}
+		total += expired
+	}
+	return total, nil
+}
+
+// expireUserPoints expires one user's unspent expired points. Spends and
+// debit adjustments are treated as consuming the oldest points first, so the points still to expire
+// are the expired earnings less everything already spent or expired. That
+// makes the sweep idempotent: once an expire entry is recorded, running it
+// again finds nothing left to expire.
+func (s *Service) expireUserPoints(ctx context.Context, userID, tenantID string) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the user row so expiry serializes with concurrent earns and spends
+	var points, held int
+	err = tx.QueryRow(ctx, `
+		SELECT u.points, COALESCE((
+			SELECT SUM(h.amount) FROM loyalty_point_holds h
+			WHERE h.user_id = u.id AND h.status = 'held' AND h.expires_at > NOW()
+		), 0)
+		FROM loyalty_users u WHERE u.id = $1 AND u.tenant_id = $2 FOR UPDATE
+	`, userID, tenantID).Scan(&points, &held)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var expiredEarned, debited, reversed int
+	err = tx.QueryRow(ctx, `
+		SELECT
+			COALESCE(SUM(amount) FILTER (WHERE type = 'earn' AND expires_at <= NOW()), 0),
+			COALESCE(SUM(amount) FILTER (WHERE type IN ('spend', 'expire')), 0)
+				- COALESCE(SUM(amount) FILTER (WHERE type = 'adjustment' AND amount < 0), 0),
+			COALESCE(SUM(amount) FILTER (WHERE type = 'reversal'), 0)
+		FROM loyalty_transactions WHERE user_id = $1 AND tenant_id = $2
+	`, userID, tenantID).Scan(&expiredEarned, &debited, &reversed)
+	if err != nil {
+		return 0, err
+	}
+
+	// Points reserved by active holds are left for the hold to capture; if
+	// it is released instead, a later sweep expires them
+	amount := expiredEarned - (debited - reversed)
+	if available := points - held; amount > available {
+		amount = available
+	}
+	if amount <= 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	balance := points - amount
+	_, err = tx.Exec(ctx, `
+		INSERT INTO loyalty_transactions (id, tenant_id, user_id, type, amount, description, balance_after, created_by, created_at)
+		VALUES ($1, $2, $3, 'expire', $4, $5, $6, $7, $8)
+	`, uuid.New().String(), tenantID, userID, amount, "Points expired", balance, expiryActor, now)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE loyalty_users SET points = $1, updated_at = $2 WHERE id = $3 AND tenant_id = $4`, balance, now, userID, tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	s.balances.invalidate(ctx, tenantID, userID)
+
+	s.logger.Infof("Expired %d points for user %s", amount, userID)
+	return amount, nil
+}
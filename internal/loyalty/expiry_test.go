@@ -0,0 +1,163 @@
+package loyalty
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// change records a transaction of txType for userID, updating their points
+func change(t *testing.T, ctx context.Context, s *Service, userID, txType string, amount int, expiresAt *time.Time) {
+	t.Helper()
+
+	transaction := &Transaction{ID: uuid.New().String(), UserID: userID, Type: txType, Amount: amount, Description: "test", CreatedAt: time.Now(), ExpiresAt: expiresAt}
+	if _, err := s.applyPointsChange(ctx, transaction, ""); err != nil {
+		t.Fatalf("%s %d: %v", txType, amount, err)
+	}
+}
+
+// expiredAmounts returns the amounts of a user's expire transactions
+func expiredAmounts(t *testing.T, ctx context.Context, s *Service, userID string) []int {
+	t.Helper()
+
+	rows, err := s.db.Query(ctx, `SELECT amount FROM loyalty_transactions WHERE user_id = $1 AND type = 'expire' ORDER BY created_at`, userID)
+	if err != nil {
+		t.Fatalf("failed to query expire transactions: %v", err)
+	}
+	defer rows.Close()
+
+	var amounts []int
+	for rows.Next() {
+		var amount int
+		if err := rows.Scan(&amount); err != nil {
+			t.Fatalf("failed to scan expire transaction: %v", err)
+		}
+		amounts = append(amounts, amount)
+	}
+	return amounts
+}
+
+// expire sweeps userID's expired points, failing unless want are expired
+func expire(t *testing.T, ctx context.Context, s *Service, userID string, want int) {
+	t.Helper()
+
+	expired, err := s.expireUserPoints(ctx, userID, auth.TenantFromContext(ctx))
+	if err != nil {
+		t.Fatalf("expireUserPoints: %v", err)
+	}
+	if expired != want {
+		t.Fatalf("expired %d points, want %d", expired, want)
+	}
+}
+
+func TestEarnSetsExpiry(t *testing.T) {
+	for _, expiry := range []time.Duration{0, 24 * time.Hour} {
+		t.Run(expiry.String(), func(t *testing.T) {
+			s := newTestService(t, withTenancy, func(cfg *config.Config) {
+				cfg.Loyalty.PointsExpiry = expiry
+			})
+			ctx := withTestDB(t, s)
+			userID := createTestUser(t, ctx, s, 0)
+
+			rec := serve(s, http.MethodPost, "/v1/loyalty/earn", tenantToken(t, s, userID, auth.TenantFromContext(ctx)),
+				EarnRequest{UserID: userID, Amount: 100, Description: "Purchase"})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("earn: status = %d: %s", rec.Code, rec.Body)
+			}
+
+			var createdAt time.Time
+			var expiresAt *time.Time
+			err := s.db.QueryRow(ctx, `SELECT created_at, expires_at FROM loyalty_transactions WHERE user_id = $1 AND type = 'earn'`, userID).Scan(&createdAt, &expiresAt)
+			if err != nil {
+				t.Fatalf("failed to read earn transaction: %v", err)
+			}
+			switch {
+			case expiry == 0 && expiresAt != nil:
+				t.Fatalf("expires_at = %v with expiry disabled, want none", expiresAt)
+			case expiry > 0 && (expiresAt == nil || !expiresAt.Equal(createdAt.Add(expiry))):
+				t.Fatalf("expires_at = %v, want %v", expiresAt, createdAt.Add(expiry))
+			}
+		})
+	}
+}
+
+func TestExpireUserPointsOnce(t *testing.T) {
+	s := newTestService(t)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 0)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	change(t, ctx, s, userID, "earn", 100, &past)
+	change(t, ctx, s, userID, "earn", 50, &future)
+
+	expire(t, ctx, s, userID, 100)
+	if got := userPoints(t, ctx, s, userID); got != 50 {
+		t.Fatalf("points = %d, want the 50 not yet expired", got)
+	}
+
+	// A second sweep finds the expired points already offset
+	expire(t, ctx, s, userID, 0)
+	if got := expiredAmounts(t, ctx, s, userID); len(got) != 1 {
+		t.Fatalf("expire transactions = %v, want one", got)
+	}
+	if got := userPoints(t, ctx, s, userID); got != 50 {
+		t.Fatalf("points after a second sweep = %d, want 50", got)
+	}
+}
+
+func TestExpireUserPointsNetsSpendsAndReversals(t *testing.T) {
+	s := newTestService(t)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 0)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	change(t, ctx, s, userID, "earn", 100, &past)
+	change(t, ctx, s, userID, "earn", 50, &future)
+
+	// Spends consume the oldest points first; a reversed spend gives them back
+	change(t, ctx, s, userID, "spend", 30, nil)
+	change(t, ctx, s, userID, "reversal", 10, nil)
+
+	expire(t, ctx, s, userID, 80)
+	if got := userPoints(t, ctx, s, userID); got != 50 {
+		t.Fatalf("points = %d, want 50", got)
+	}
+
+	// Spending the rest leaves nothing for a later sweep
+	change(t, ctx, s, userID, "spend", 50, nil)
+	expire(t, ctx, s, userID, 0)
+}
+
+func TestExpireUserPointsLeavesHeldPoints(t *testing.T) {
+	s := newTestService(t)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 0)
+	past := time.Now().Add(-time.Hour)
+	change(t, ctx, s, userID, "earn", 100, &past)
+
+	hold, err := s.placeHold(ctx, userID, 60, uuid.New().String())
+	if err != nil {
+		t.Fatalf("placeHold: %v", err)
+	}
+
+	// Held points are left for the hold to capture
+	expire(t, ctx, s, userID, 40)
+	if got := userPoints(t, ctx, s, userID); got != 60 {
+		t.Fatalf("points = %d, want the 60 held", got)
+	}
+
+	// Once released, a later sweep expires them
+	if _, _, err := s.completeHold(ctx, hold.ID, userID, HoldStatusReleased); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	expire(t, ctx, s, userID, 60)
+	if got := userPoints(t, ctx, s, userID); got != 0 {
+		t.Fatalf("points = %d, want 0", got)
+	}
+	if got := expiredAmounts(t, ctx, s, userID); len(got) != 2 || got[0] != 40 || got[1] != 60 {
+		t.Fatalf("expire transactions = %v, want [40 60]", got)
+	}
+}
@@ -0,0 +1,107 @@
+package loyalty
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// History page sizes
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 100
+)
+
+// historyTypes are the transaction types history can be filtered by
+var historyTypes = map[string]bool{
+	"earn":       true,
+	"spend":      true,
+	"reversal":   true,
+	"expire":     true,
+	"adjustment": true,
+}
+
+// HistoryResponse is a page of a user's transaction history
+type HistoryResponse struct {
+	Transactions []*Transaction `json:"transactions"`
+	// Total counts every transaction matching the filters, across all pages
+	Total int `json:"total"`
+	// Page is set in offset mode
+	Page  int `json:"page,omitempty"`
+	Limit int `json:"limit"`
+	// NextCursor continues a cursor-paged listing; it is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// historyFilter selects a page of a user's transaction history
+type historyFilter struct {
+	Type  string
+	From  *time.Time // inclusive
+	To    *time.Time // exclusive
+	Page  int
+	Limit int
+	// CursorMode pages from Cursor, or from the start when it is nil,
+	// instead of by Page
+	CursorMode bool
+	Cursor     *platformhttp.Cursor
+}
+
+// parseHistoryFilter reads the page or cursor, limit, type, from, and to
+// query parameters. Invalid paging falls back to the defaults, as the catalog
+// does; invalid filters and cursors are rejected.
+func parseHistoryFilter(r *http.Request) (*historyFilter, error) {
+	query := r.URL.Query()
+	filter := &historyFilter{Page: 1, Limit: defaultHistoryLimit}
+
+	if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 0 {
+		filter.Page = page
+	}
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+		filter.Limit = limit
+		if limit > maxHistoryLimit {
+			filter.Limit = maxHistoryLimit
+		}
+	}
+
+	var err error
+	if filter.Cursor, filter.CursorMode, err = platformhttp.CursorFromRequest(r); err != nil {
+		return nil, errors.New("Cursor is invalid")
+	}
+	if filter.CursorMode {
+		filter.Page = 0
+	}
+
+	if txType := query.Get("type"); txType != "" {
+		if !historyTypes[txType] {
+			return nil, errors.New("Type must be one of earn, spend, reversal, expire, or adjustment")
+		}
+		filter.Type = txType
+	}
+
+	if filter.From, err = parseTimeParam(query.Get("from")); err != nil {
+		return nil, errors.New("From must be an RFC3339 timestamp")
+	}
+	if filter.To, err = parseTimeParam(query.Get("to")); err != nil {
+		return nil, errors.New("To must be an RFC3339 timestamp")
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, errors.New("From must be earlier than to")
+	}
+
+	return filter, nil
+}
+
+// parseTimeParam parses an optional RFC3339 query parameter
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
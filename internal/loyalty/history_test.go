@@ -0,0 +1,188 @@
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+)
+
+func TestParseHistoryFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantPage  int
+		wantLimit int
+		wantErr   bool
+	}{
+		{"defaults", "", 1, defaultHistoryLimit, false},
+		{"page and limit", "page=3&limit=10", 3, 10, false},
+		{"limit is capped", "limit=1000", 1, maxHistoryLimit, false},
+		{"invalid paging falls back", "page=0&limit=-5", 1, defaultHistoryLimit, false},
+		{"non-numeric paging falls back", "page=two&limit=ten", 1, defaultHistoryLimit, false},
+		{"type", "type=spend", 1, defaultHistoryLimit, false},
+		{"unknown type", "type=refund", 0, 0, true},
+		{"date range", "from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z", 1, defaultHistoryLimit, false},
+		{"invalid from", "from=yesterday", 0, 0, true},
+		{"invalid to", "to=2026-02-01", 0, 0, true},
+		{"empty range", "from=2026-02-01T00:00:00Z&to=2026-02-01T00:00:00Z", 0, 0, true},
+		{"first cursor page", "cursor=&page=3&limit=10", 0, 10, false},
+		{"invalid cursor", "cursor=not-a-cursor", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := parseHistoryFilter(httptest.NewRequest(http.MethodGet, "/v1/loyalty/history?"+tt.query, nil))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseHistoryFilter(%q) = %+v, want an error", tt.query, filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseHistoryFilter(%q): %v", tt.query, err)
+			}
+			if filter.Page != tt.wantPage || filter.Limit != tt.wantLimit {
+				t.Fatalf("page %d limit %d, want page %d limit %d", filter.Page, filter.Limit, tt.wantPage, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestHistoryRejectsInvalidFilters(t *testing.T) {
+	s := newTestService(t)
+
+	rec := serve(s, http.MethodGet, "/v1/loyalty/history?type=refund", token(t, s, uuid.New().String(), "user"), nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHistoryPages(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 1000)
+	tok := tenantToken(t, s, userID, auth.TenantFromContext(ctx))
+
+	history := func(query string) HistoryResponse {
+		t.Helper()
+
+		rec := serve(s, http.MethodGet, "/v1/loyalty/history?"+query, tok, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("history?%s: status = %d: %s", query, rec.Code, rec.Body)
+		}
+		var response struct {
+			Data HistoryResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("decode history: %v", err)
+		}
+		return response.Data
+	}
+
+	// A user without transactions gets an empty first page
+	empty := history("")
+	if empty.Total != 0 || empty.Transactions == nil || len(empty.Transactions) != 0 || empty.Page != 1 {
+		t.Fatalf("empty history = %+v, want an empty first page", empty)
+	}
+
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		record(t, ctx, s, userID, "earn", start.Add(time.Duration(i)*time.Minute))
+	}
+	record(t, ctx, s, userID, "spend", start.Add(10*time.Minute))
+
+	page := history("page=2&limit=2")
+	if page.Total != 6 || len(page.Transactions) != 2 || page.Page != 2 || page.Limit != 2 {
+		t.Fatalf("page 2 = %+v, want 2 of 6 transactions", page)
+	}
+	last := history("page=3&limit=4")
+	if last.Total != 6 || len(last.Transactions) != 0 {
+		t.Fatalf("page past the end = %+v, want no transactions and the full total", last)
+	}
+	far := history("page=1000&limit=100")
+	if far.Total != 6 || len(far.Transactions) != 0 || far.Page != 1000 {
+		t.Fatalf("out-of-range page = %+v, want an empty page 1000", far)
+	}
+
+	// Filters apply to the total as well as the page
+	if spends := history("type=spend"); spends.Total != 1 || spends.Transactions[0].Type != "spend" {
+		t.Fatalf("spend history = %+v, want the one spend", spends)
+	}
+	from := start.Add(time.Minute).UTC().Format(time.RFC3339)
+	to := start.Add(3 * time.Minute).UTC().Format(time.RFC3339)
+	window := history("from=" + from + "&to=" + to + "&limit=1")
+	if window.Total != 2 || len(window.Transactions) != 1 {
+		t.Fatalf("windowed history = %+v, want 1 of 2 transactions", window)
+	}
+}
+
+func TestHistoryCursorPages(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 1000)
+	tok := tenantToken(t, s, userID, auth.TenantFromContext(ctx))
+
+	// Seven transactions, three sharing a creation time so the ID breaks the tie
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, offset := range []time.Duration{0, time.Minute, time.Minute, time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute} {
+		record(t, ctx, s, userID, "earn", start.Add(offset))
+	}
+
+	seen := make(map[string]int)
+	cursor := ""
+	for page := 0; ; page++ {
+		if page > 7 {
+			t.Fatal("paging did not end")
+		}
+		rec := serve(s, http.MethodGet, "/v1/loyalty/history?limit=3&cursor="+url.QueryEscape(cursor), tok, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("page %d: status = %d: %s", page, rec.Code, rec.Body)
+		}
+		var response struct {
+			Data HistoryResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("decode history: %v", err)
+		}
+		for _, tx := range response.Data.Transactions {
+			seen[tx.ID]++
+		}
+
+		// Transactions recorded while paging are newer than the cursor, so
+		// they shift no rows between pages and are not returned
+		record(t, ctx, s, userID, "earn", time.Now())
+
+		if response.Data.NextCursor == "" {
+			break
+		}
+		cursor = response.Data.NextCursor
+	}
+
+	if len(seen) != 7 {
+		t.Fatalf("listed %d transactions, want the 7 recorded before paging", len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("transaction %s listed %d times, want once", id, n)
+		}
+	}
+}
+
+// record inserts a transaction of txType for userID created at createdAt
+func record(t *testing.T, ctx context.Context, s *Service, userID, txType string, createdAt time.Time) {
+	t.Helper()
+
+	err := s.db.Exec(ctx, `
+		INSERT INTO loyalty_transactions (id, tenant_id, user_id, type, amount, description, balance_after, created_at)
+		VALUES ($1, $2, $3, $4, 10, 'test', 0, $5)
+	`, uuid.New().String(), auth.TenantFromContext(ctx), userID, txType, createdAt)
+	if err != nil {
+		t.Fatalf("failed to record transaction: %v", err)
+	}
+}
@@ -0,0 +1,346 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// Hold statuses
+const (
+	HoldStatusHeld     = "held"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+var (
+	errInsufficientPoints = errors.New("insufficient points")
+	errHoldNotFound       = errors.New("hold not found")
+	errHoldNotActive      = errors.New("hold is no longer active")
+)
+
+// Hold represents points authorized for a pending operation but not yet spent
+type Hold struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Amount    int       `json:"amount"`
+	Reference string    `json:"reference"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// HoldRequest represents a request to place a points hold
+type HoldRequest struct {
+	UserID    string `json:"user_id" validate:"required"`
+	Amount    int    `json:"amount" validate:"required,min=1"`
+	Reference string `json:"reference" validate:"required"`
+}
+
+// HoldActionRequest identifies the user whose hold a service captures or releases
+type HoldActionRequest struct {
+	UserID string `json:"user_id" validate:"required"`
+}
+
+// PlaceHold places a hold on a user's available points
+func (s *Service) PlaceHold(w http.ResponseWriter, r *http.Request) {
+	var req HoldRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	userID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
+		return
+	}
+	if userID != req.UserID {
+		platformhttp.Error(w, r, http.StatusForbidden, platformhttp.ErrCodeForbidden, "Can only hold points on your own account")
+		return
+	}
+
+	// Ensure user exists in loyalty_users (auto-create if needed)
+	if _, err := s.getUserByID(r.Context(), userID); err != nil {
+		s.logger.Errorf("Failed to get/create user: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get user info")
+		return
+	}
+
+	hold, err := s.placeHold(r.Context(), userID, req.Amount, req.Reference)
+	if err != nil {
+		if errors.Is(err, errInsufficientPoints) {
+			platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInsufficientPoints, "Insufficient points")
+			return
+		}
+		if database.IsUniqueViolation(err) {
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "A hold already exists for this reference")
+			return
+		}
+		s.logger.Errorf("Failed to place hold: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to place hold")
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, LoyaltyResponse{Success: true, Message: "Points held successfully", Data: hold})
+}
+
+// CaptureHold spends the points reserved by a hold. Only services may
+// capture, once whatever the points pay for has been delivered.
+func (s *Service) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	s.finishHold(w, r, HoldStatusCaptured)
+}
+
+// ReleaseHold returns the points reserved by a hold to the available balance.
+// Only services may release; an abandoned hold expires on its own.
+func (s *Service) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	s.finishHold(w, r, HoldStatusReleased)
+}
+
+func (s *Service) finishHold(w http.ResponseWriter, r *http.Request, status string) {
+	holdID := chi.URLParam(r, "id")
+	var req HoldActionRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	hold, transaction, err := s.completeHold(r.Context(), holdID, req.UserID, status)
+	if err != nil {
+		switch {
+		case errors.Is(err, errHoldNotFound):
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Hold not found")
+		case errors.Is(err, errHoldNotActive):
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "Hold is no longer active")
+		default:
+			s.logger.Errorf("Failed to %s hold %s: %v", status, holdID, err)
+			platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to update hold")
+		}
+		return
+	}
+
+	data := map[string]interface{}{"hold": hold}
+	if transaction != nil {
+		data["transaction"] = transaction
+	}
+
+	render.JSON(w, r, LoyaltyResponse{Success: true, Message: "Hold " + status, Data: data})
+}
+
+// RunHoldSweeper periodically marks abandoned holds as expired until ctx is cancelled
+func (s *Service) RunHoldSweeper(ctx context.Context) {
+	interval := s.config.Loyalty.HoldSweepInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.expireHolds(ctx)
+			if err != nil {
+				s.logger.Errorf("Failed to expire holds: %v", err)
+				continue
+			}
+			if expired > 0 {
+				s.logger.Infof("Expired %d abandoned point holds", expired)
+			}
+		}
+	}
+}
+
+// Database helper methods
+func (s *Service) placeHold(ctx context.Context, userID string, amount int, reference string) (*Hold, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the user row so concurrent holds and spends see a consistent balance
+	var available int
+	err = tx.QueryRow(ctx, `
+		SELECT u.points - COALESCE((
+			SELECT SUM(h.amount) FROM loyalty_point_holds h
+			WHERE h.user_id = u.id AND h.status = 'held' AND h.expires_at > NOW()
+		), 0)
+		FROM loyalty_users u WHERE u.id = $1 AND u.tenant_id = $2 FOR UPDATE
+	`, userID, auth.TenantFromContext(ctx)).Scan(&available)
+	if err != nil {
+		return nil, err
+	}
+
+	if available < amount {
+		return nil, errInsufficientPoints
+	}
+
+	now := time.Now()
+	hold := &Hold{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Amount:    amount,
+		Reference: reference,
+		Status:    HoldStatusHeld,
+		ExpiresAt: now.Add(s.config.Loyalty.HoldTTL),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO loyalty_point_holds (id, tenant_id, user_id, amount, reference, status, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, hold.ID, auth.TenantFromContext(ctx), hold.UserID, hold.Amount, hold.Reference, hold.Status, hold.ExpiresAt, hold.CreatedAt, hold.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// The available balance changed, so conditional balance reads must miss
+	if err := touchUser(ctx, tx, userID, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.invalidateBalance(ctx, userID)
+
+	return hold, nil
+}
+
+// completeHold captures or releases an active hold. Capturing records a spend
+// transaction and deducts the points in the same database transaction.
+func (s *Service) completeHold(ctx context.Context, holdID, userID, status string) (*Hold, *Transaction, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var hold Hold
+	err = tx.QueryRow(ctx, `
+		SELECT id, user_id, amount, reference, status, expires_at, created_at, updated_at
+		FROM loyalty_point_holds WHERE id = $1 AND user_id = $2 AND tenant_id = $3 FOR UPDATE
+	`, holdID, userID, auth.TenantFromContext(ctx)).Scan(&hold.ID, &hold.UserID, &hold.Amount, &hold.Reference, &hold.Status, &hold.ExpiresAt, &hold.CreatedAt, &hold.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, errHoldNotFound
+		}
+		return nil, nil, err
+	}
+
+	// A hold already finished this way is returned as is, so a service
+	// retrying a capture whose response was lost does not see a conflict
+	if hold.Status == status {
+		return &hold, nil, nil
+	}
+
+	now := time.Now()
+	if hold.Status != HoldStatusHeld || !hold.ExpiresAt.After(now) {
+		return nil, nil, errHoldNotActive
+	}
+
+	var transaction *Transaction
+	if status == HoldStatusCaptured {
+		transaction = &Transaction{
+			ID:          uuid.New().String(),
+			UserID:      hold.UserID,
+			Type:        "spend",
+			Amount:      hold.Amount,
+			Description: fmt.Sprintf("Captured hold %s (%s)", hold.ID, hold.Reference),
+			CreatedAt:   now,
+			CreatedBy:   authmw.Actor(ctx),
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO loyalty_transactions (id, tenant_id, user_id, type, amount, description, created_by, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, transaction.ID, auth.TenantFromContext(ctx), transaction.UserID, transaction.Type, transaction.Amount, transaction.Description, transaction.CreatedBy, transaction.CreatedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE loyalty_users SET points = points - $1, updated_at = $2 WHERE id = $3 AND tenant_id = $4`,
+			hold.Amount, now, hold.UserID, auth.TenantFromContext(ctx))
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if err := touchUser(ctx, tx, hold.UserID, now); err != nil {
+		return nil, nil, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE loyalty_point_holds SET status = $1, updated_at = $2 WHERE id = $3 AND tenant_id = $4`,
+		status, now, hold.ID, auth.TenantFromContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	s.invalidateBalance(ctx, hold.UserID)
+	if transaction != nil {
+		recordPointsMetrics(transaction)
+	}
+
+	hold.Status = status
+	hold.UpdatedAt = now
+	return &hold, transaction, nil
+}
+
+// expireHolds marks lapsed holds as expired and bumps their owners' updated_at
+// so clients revalidating by Last-Modified, or reading a cached balance, see
+// the restored balance
+func (s *Service) expireHolds(ctx context.Context) (int64, error) {
+	rows, err := s.db.Query(ctx, `
+		WITH expired AS (
+			UPDATE loyalty_point_holds SET status = 'expired', updated_at = NOW()
+			WHERE status = 'held' AND expires_at <= NOW()
+			RETURNING user_id, tenant_id
+		), touched AS (
+			UPDATE loyalty_users SET updated_at = NOW()
+			WHERE (id, tenant_id) IN (SELECT user_id, tenant_id FROM expired)
+		)
+		SELECT user_id, tenant_id FROM expired
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var expired int64
+	for rows.Next() {
+		var userID, tenantID string
+		if err := rows.Scan(&userID, &tenantID); err != nil {
+			return expired, err
+		}
+		s.balances.invalidate(ctx, tenantID, userID)
+		expired++
+	}
+	return expired, rows.Err()
+}
+
+// touchUser bumps a user's updated_at so conditional balance reads see the change
+func touchUser(ctx context.Context, tx pgx.Tx, userID string, now time.Time) error {
+	_, err := tx.Exec(ctx, `UPDATE loyalty_users SET updated_at = $1 WHERE id = $2 AND tenant_id = $3`,
+		now, userID, auth.TenantFromContext(ctx))
+	return err
+}
@@ -0,0 +1,181 @@
+package loyalty
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+)
+
+func TestFinishHoldRequiresServiceRole(t *testing.T) {
+	s := newTestService(t)
+	userID := uuid.New().String()
+	holdID := uuid.New().String()
+
+	tests := []struct {
+		name string
+		role string
+		body interface{}
+		want int
+	}{
+		{"user cannot capture", "user", map[string]string{"user_id": userID}, http.StatusForbidden},
+		{"admin cannot capture", auth.RoleAdmin, map[string]string{"user_id": userID}, http.StatusForbidden},
+		{"service must name the user", auth.RoleService, map[string]string{}, http.StatusUnprocessableEntity},
+	}
+	for _, action := range []string{"capture", "release"} {
+		for _, tt := range tests {
+			t.Run(action+"/"+tt.name, func(t *testing.T) {
+				rec := serve(s, http.MethodPost, "/v1/loyalty/holds/"+holdID+"/"+action, token(t, s, userID, tt.role), tt.body)
+				if rec.Code != tt.want {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+				}
+			})
+		}
+	}
+}
+
+func TestCompleteHoldCapturesOnce(t *testing.T) {
+	s := newTestService(t)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 500)
+
+	hold, err := s.placeHold(ctx, userID, 200, uuid.New().String())
+	if err != nil {
+		t.Fatalf("placeHold: %v", err)
+	}
+
+	captured, transaction, err := s.completeHold(ctx, hold.ID, userID, HoldStatusCaptured)
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if captured.Status != HoldStatusCaptured || transaction == nil {
+		t.Fatalf("capture = %+v, %+v; want a captured hold and its spend", captured, transaction)
+	}
+
+	// A retried capture succeeds without spending the points again
+	replayed, transaction, err := s.completeHold(ctx, hold.ID, userID, HoldStatusCaptured)
+	if err != nil {
+		t.Fatalf("retried capture: %v", err)
+	}
+	if replayed.Status != HoldStatusCaptured || transaction != nil {
+		t.Fatalf("retried capture = %+v, %+v; want the captured hold and no spend", replayed, transaction)
+	}
+	if got := userPoints(t, ctx, s, userID); got != 300 {
+		t.Fatalf("points = %d, want 300", got)
+	}
+
+	// A captured hold cannot be released
+	if _, _, err := s.completeHold(ctx, hold.ID, userID, HoldStatusReleased); err != errHoldNotActive {
+		t.Fatalf("release after capture: err = %v, want %v", err, errHoldNotActive)
+	}
+}
+
+func TestCompleteHoldIsScopedToUserAndTenant(t *testing.T) {
+	s := newTestService(t)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 500)
+
+	hold, err := s.placeHold(ctx, userID, 100, uuid.New().String())
+	if err != nil {
+		t.Fatalf("placeHold: %v", err)
+	}
+
+	if _, _, err := s.completeHold(ctx, hold.ID, uuid.New().String(), HoldStatusCaptured); err != errHoldNotFound {
+		t.Fatalf("other user's capture: err = %v, want %v", err, errHoldNotFound)
+	}
+	otherTenant := auth.WithTenant(ctx, "other-tenant")
+	if _, _, err := s.completeHold(otherTenant, hold.ID, userID, HoldStatusCaptured); err != errHoldNotFound {
+		t.Fatalf("other tenant's capture: err = %v, want %v", err, errHoldNotFound)
+	}
+}
+
+func TestHoldsReduceAvailablePoints(t *testing.T) {
+	s := newTestService(t)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 500)
+
+	hold, err := s.placeHold(ctx, userID, 400, uuid.New().String())
+	if err != nil {
+		t.Fatalf("placeHold: %v", err)
+	}
+	user, err := s.getUserByID(ctx, userID)
+	if err != nil {
+		t.Fatalf("getUserByID: %v", err)
+	}
+	if user.Points != 500 || user.HeldPoints != 400 || user.AvailablePoints != 100 {
+		t.Fatalf("balance = %d total, %d held, %d available; want 500, 400, 100", user.Points, user.HeldPoints, user.AvailablePoints)
+	}
+
+	// Held points cannot be held or spent again
+	if _, err := s.placeHold(ctx, userID, 200, uuid.New().String()); err != errInsufficientPoints {
+		t.Fatalf("hold beyond available points: err = %v, want %v", err, errInsufficientPoints)
+	}
+
+	// Releasing returns the points without spending them
+	if _, transaction, err := s.completeHold(ctx, hold.ID, userID, HoldStatusReleased); err != nil || transaction != nil {
+		t.Fatalf("release = %+v, %v; want no spend", transaction, err)
+	}
+	if _, err := s.placeHold(ctx, userID, 500, uuid.New().String()); err != nil {
+		t.Fatalf("hold of the released points: %v", err)
+	}
+	if got := userPoints(t, ctx, s, userID); got != 500 {
+		t.Fatalf("points = %d, want 500", got)
+	}
+}
+
+func TestExpireHoldsReleasesAbandonedHolds(t *testing.T) {
+	s := newTestService(t)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 500)
+
+	abandoned, err := s.placeHold(ctx, userID, 300, uuid.New().String())
+	if err != nil {
+		t.Fatalf("placeHold: %v", err)
+	}
+	active, err := s.placeHold(ctx, userID, 100, uuid.New().String())
+	if err != nil {
+		t.Fatalf("placeHold: %v", err)
+	}
+	// Abandon the first hold by letting it lapse
+	if err := s.db.Exec(ctx, `UPDATE loyalty_point_holds SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, abandoned.ID); err != nil {
+		t.Fatalf("failed to backdate hold: %v", err)
+	}
+
+	// A lapsed hold stops counting against the balance even before the sweep
+	user, err := s.getUserByID(ctx, userID)
+	if err != nil {
+		t.Fatalf("getUserByID: %v", err)
+	}
+	if user.AvailablePoints != 400 {
+		t.Fatalf("available points = %d, want 400", user.AvailablePoints)
+	}
+
+	if _, err := s.expireHolds(ctx); err != nil {
+		t.Fatalf("expireHolds: %v", err)
+	}
+	tests := []struct {
+		hold *Hold
+		want string
+	}{
+		{abandoned, HoldStatusExpired},
+		{active, HoldStatusHeld},
+	}
+	for _, tt := range tests {
+		var status string
+		if err := s.db.QueryRow(ctx, `SELECT status FROM loyalty_point_holds WHERE id = $1`, tt.hold.ID).Scan(&status); err != nil {
+			t.Fatalf("failed to read hold: %v", err)
+		}
+		if status != tt.want {
+			t.Errorf("hold of %d points: status = %q, want %q", tt.hold.Amount, status, tt.want)
+		}
+	}
+
+	// An expired hold can no longer be captured
+	if _, _, err := s.completeHold(ctx, abandoned.ID, userID, HoldStatusCaptured); err != errHoldNotActive {
+		t.Fatalf("capture of an expired hold: err = %v, want %v", err, errHoldNotActive)
+	}
+	if got := userPoints(t, ctx, s, userID); got != 500 {
+		t.Fatalf("points = %d, want 500", got)
+	}
+}
@@ -0,0 +1,241 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+var (
+	errUserNotFound        = errors.New("user not found")
+	errIdempotencyConflict = errors.New("idempotency key reused with different parameters")
+)
+
+// AdjustmentRequest represents an internal deduct or credit request
+type AdjustmentRequest struct {
+	UserID         string `json:"user_id" validate:"required"`
+	Amount         int    `json:"amount" validate:"required,min=1"`
+	Reason         string `json:"reason" validate:"required"`
+	IdempotencyKey string `json:"idempotency_key" validate:"required"`
+	// RedemptionID links a deduction to the redemption it paid for, so it can be reversed
+	RedemptionID string `json:"redemption_id,omitempty"`
+}
+
+// AdjustmentResult is the outcome of an internal deduct or credit
+type AdjustmentResult struct {
+	Transaction *Transaction `json:"transaction"`
+	Balance     int          `json:"balance"`
+	Replayed    bool         `json:"replayed"`
+}
+
+// BalancesRequest represents a bulk balance lookup
+type BalancesRequest struct {
+	UserIDs []string `json:"user_ids" validate:"required,min=1"`
+}
+
+// GetBalances returns the balances of many users in one query, so batch
+// callers avoid a request per user. Unknown users are omitted.
+func (s *Service) GetBalances(w http.ResponseWriter, r *http.Request) {
+	var req BalancesRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	if limit := s.config.Loyalty.MaxBalanceLookup; limit > 0 && len(req.UserIDs) > limit {
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, fmt.Sprintf("At most %d user IDs may be requested at once", limit))
+		return
+	}
+
+	users, err := s.getUsersByIDs(r.Context(), req.UserIDs)
+	if err != nil {
+		s.logger.Errorf("Failed to get balances for %d users: %v", len(req.UserIDs), err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get balances")
+		return
+	}
+
+	render.JSON(w, r, LoyaltyResponse{Success: true, Message: "Balances retrieved successfully", Data: users})
+}
+
+// InternalDeduct deducts points on behalf of another service
+func (s *Service) InternalDeduct(w http.ResponseWriter, r *http.Request) {
+	s.adjustPoints(w, r, "spend")
+}
+
+// InternalCredit credits points on behalf of another service
+func (s *Service) InternalCredit(w http.ResponseWriter, r *http.Request) {
+	s.adjustPoints(w, r, "earn")
+}
+
+func (s *Service) adjustPoints(w http.ResponseWriter, r *http.Request, txType string) {
+	var req AdjustmentRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	result, err := s.applyAdjustment(r.Context(), txType, &req)
+	if database.IsUniqueViolation(err) {
+		// A concurrent request with the same idempotency key committed first; replay its result
+		result, err = s.applyAdjustment(r.Context(), txType, &req)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errUserNotFound):
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "User not found")
+		case errors.Is(err, errInsufficientPoints):
+			platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInsufficientPoints, "Insufficient points")
+		case errors.Is(err, errIdempotencyConflict):
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "Idempotency key already used for a different request")
+		default:
+			s.logger.Errorf("Failed to apply %s adjustment for %s: %v", txType, req.IdempotencyKey, err)
+			platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to adjust points")
+		}
+		return
+	}
+
+	render.JSON(w, r, LoyaltyResponse{Success: true, Message: "Points adjusted successfully", Data: result})
+}
+
+// applyAdjustment records the transaction and updates the balance atomically.
+// A repeated idempotency key returns the originally recorded result.
+func (s *Service) applyAdjustment(ctx context.Context, txType string, req *AdjustmentRequest) (*AdjustmentResult, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var points, held, lifetime int
+	err = tx.QueryRow(ctx, `
+		SELECT u.points, COALESCE((
+			SELECT SUM(h.amount) FROM loyalty_point_holds h
+			WHERE h.user_id = u.id AND h.status = 'held' AND h.expires_at > NOW()
+		), 0), u.lifetime_points
+		FROM loyalty_users u WHERE u.id = $1 AND u.tenant_id = $2 FOR UPDATE
+	`, req.UserID, auth.TenantFromContext(ctx)).Scan(&points, &held, &lifetime)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, err
+	}
+
+	// The user row lock serializes replays, so the lookup cannot race the insert
+	existing, balanceAfter, err := s.getTransactionByKey(ctx, tx, req.UserID, txType, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.Amount != req.Amount {
+			return nil, errIdempotencyConflict
+		}
+		return &AdjustmentResult{Transaction: existing, Balance: balanceAfter, Replayed: true}, nil
+	}
+
+	change := req.Amount
+	if txType == "spend" {
+		if points-held < req.Amount {
+			return nil, errInsufficientPoints
+		}
+		change = -req.Amount
+	} else {
+		lifetime += req.Amount
+	}
+
+	now := time.Now()
+	transaction := &Transaction{
+		ID:          uuid.New().String(),
+		UserID:      req.UserID,
+		Type:        txType,
+		Amount:      req.Amount,
+		Description: req.Reason,
+		CreatedAt:   now,
+		CreatedBy:   authmw.Actor(ctx),
+	}
+	if txType == "earn" {
+		transaction.ExpiresAt = s.earnExpiry(now)
+	}
+	balance := points + change
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO loyalty_transactions (id, tenant_id, user_id, type, amount, description, idempotency_key, redemption_id, balance_after, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11, $12)
+	`, transaction.ID, auth.TenantFromContext(ctx), transaction.UserID, transaction.Type, transaction.Amount, transaction.Description, req.IdempotencyKey, req.RedemptionID, balance, transaction.CreatedBy, transaction.CreatedAt, transaction.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE loyalty_users SET points = $1, lifetime_points = $2, tier = $3, updated_at = $4
+		WHERE id = $5 AND tenant_id = $6
+	`, balance, lifetime, s.recalculateTier(lifetime), now, req.UserID, auth.TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.invalidateBalance(ctx, req.UserID)
+	recordPointsMetrics(transaction)
+
+	s.logger.Infof("Applied %s adjustment of %d points for user %s by %s", txType, req.Amount, req.UserID, transaction.CreatedBy)
+	return &AdjustmentResult{Transaction: transaction, Balance: balance}, nil
+}
+
+func (s *Service) getUsersByIDs(ctx context.Context, userIDs []string) ([]*User, error) {
+	query := `
+		SELECT u.id, u.email, u.points, COALESCE((
+			SELECT SUM(h.amount) FROM loyalty_point_holds h
+			WHERE h.user_id = u.id AND h.status = 'held' AND h.expires_at > NOW()
+		), 0), u.tier, u.created_at, u.updated_at
+		FROM loyalty_users u WHERE u.id = ANY($1) AND u.tenant_id = $2
+		ORDER BY u.id
+	`
+
+	rows, err := s.db.Query(ctx, query, userIDs, auth.TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.ID, &user.Email, &user.Points, &user.HeldPoints, &user.Tier, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		user.AvailablePoints = user.Points - user.HeldPoints
+		users = append(users, &user)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *Service) getTransactionByKey(ctx context.Context, tx pgx.Tx, userID, txType, key string) (*Transaction, int, error) {
+	var t Transaction
+	var balanceAfter int
+	err := tx.QueryRow(ctx, `
+		SELECT id, user_id, type, amount, description, balance_after, COALESCE(created_by, ''), created_at, expires_at
+		FROM loyalty_transactions WHERE user_id = $1 AND type = $2 AND idempotency_key = $3 AND tenant_id = $4
+	`, userID, txType, key, auth.TenantFromContext(ctx)).Scan(&t.ID, &t.UserID, &t.Type, &t.Amount, &t.Description, &balanceAfter, &t.CreatedBy, &t.CreatedAt, &t.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	return &t, balanceAfter, nil
+}
@@ -0,0 +1,295 @@
+package loyalty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/jsonutil"
+)
+
+func TestApplyAdjustmentIsScopedToTenant(t *testing.T) {
+	s := newTestService(t)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 100)
+
+	otherTenant := auth.WithTenant(ctx, "other-tenant")
+	_, err := s.applyAdjustment(otherTenant, "earn", &AdjustmentRequest{
+		UserID: userID, Amount: 50, Reason: "test", IdempotencyKey: uuid.New().String(),
+	})
+	if err != errUserNotFound {
+		t.Fatalf("adjustment in another tenant: err = %v, want %v", err, errUserNotFound)
+	}
+	if got := userPoints(t, ctx, s, userID); got != 100 {
+		t.Fatalf("points = %d, want 100", got)
+	}
+}
+
+func TestApplyAdjustmentReplaysIdempotencyKey(t *testing.T) {
+	s := newTestService(t)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 100)
+
+	req := &AdjustmentRequest{UserID: userID, Amount: 40, Reason: "test", IdempotencyKey: uuid.New().String()}
+	first, err := s.applyAdjustment(ctx, "spend", req)
+	if err != nil {
+		t.Fatalf("first deduction: %v", err)
+	}
+	replay, err := s.applyAdjustment(ctx, "spend", req)
+	if err != nil {
+		t.Fatalf("replayed deduction: %v", err)
+	}
+	if !replay.Replayed || replay.Transaction.ID != first.Transaction.ID || replay.Balance != 60 {
+		t.Fatalf("replay = %+v, want the first deduction replayed with balance 60", replay)
+	}
+
+	conflicting := *req
+	conflicting.Amount = 10
+	if _, err := s.applyAdjustment(ctx, "spend", &conflicting); err != errIdempotencyConflict {
+		t.Fatalf("reused key with another amount: err = %v, want %v", err, errIdempotencyConflict)
+	}
+	if got := userPoints(t, ctx, s, userID); got != 60 {
+		t.Fatalf("points = %d, want 60", got)
+	}
+}
+
+func TestInternalAdjustmentsRequireServiceToken(t *testing.T) {
+	s := newTestService(t)
+	userID := uuid.New().String()
+	req := AdjustmentRequest{UserID: userID, Amount: 10, Reason: "test", IdempotencyKey: uuid.New().String()}
+
+	tests := []struct {
+		name string
+		tok  string
+		want int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"user", token(t, s, userID, "user"), http.StatusForbidden},
+		{"admin", token(t, s, userID, auth.RoleAdmin), http.StatusForbidden},
+	}
+	for _, path := range []string{"/v1/loyalty/internal/deduct", "/v1/loyalty/internal/credit"} {
+		for _, tt := range tests {
+			t.Run(path+"/"+tt.name, func(t *testing.T) {
+				if rec := serve(s, http.MethodPost, path, tt.tok, req); rec.Code != tt.want {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+				}
+			})
+		}
+	}
+}
+
+func TestInternalDeduct(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	tenantID := auth.TenantFromContext(ctx)
+	userID := createTestUser(t, ctx, s, 100)
+
+	serviceToken, err := s.jwtManager.GenerateServiceToken("redemption-svc")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	deduct := func(req AdjustmentRequest) (*AdjustmentResult, *platformhttp.ErrorResponse) {
+		t.Helper()
+
+		rec := serve(s, http.MethodPost, "/v1/loyalty/internal/deduct", serviceToken, req, auth.TenantHeader, tenantID)
+		if rec.Code != http.StatusOK {
+			var body platformhttp.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			return nil, &body
+		}
+		var body struct {
+			Data AdjustmentResult `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return &body.Data, nil
+	}
+
+	// The redemption's ID is its idempotency key, so a retried call replays
+	req := AdjustmentRequest{UserID: userID, Amount: 60, Reason: "redemption", IdempotencyKey: uuid.New().String()}
+	first, errBody := deduct(req)
+	if errBody != nil {
+		t.Fatalf("deduct: %+v", errBody)
+	}
+	if first.Balance != 40 || first.Replayed {
+		t.Fatalf("deduct = %+v, want balance 40", first)
+	}
+	replay, errBody := deduct(req)
+	if errBody != nil {
+		t.Fatalf("replayed deduct: %+v", errBody)
+	}
+	if !replay.Replayed || replay.Balance != 40 || replay.Transaction.ID != first.Transaction.ID {
+		t.Fatalf("replayed deduct = %+v, want the first deduction replayed", replay)
+	}
+
+	_, errBody = deduct(AdjustmentRequest{UserID: userID, Amount: 50, Reason: "redemption", IdempotencyKey: uuid.New().String()})
+	if errBody == nil || errBody.Code != platformhttp.ErrCodeInsufficientPoints {
+		t.Fatalf("deduct beyond the balance: error = %+v, want %s", errBody, platformhttp.ErrCodeInsufficientPoints)
+	}
+	if got := userPoints(t, ctx, s, userID); got != 40 {
+		t.Fatalf("points = %d, want 40", got)
+	}
+}
+
+func TestAdjustmentsKeepLargePointValues(t *testing.T) {
+	const points = 1<<53 + 1 // the smallest integer a float64 cannot hold
+
+	body := []byte(`{"user_id": "user-1", "amount": 9007199254740993, "reason": "test", "idempotency_key": "key-1"}`)
+	r := httptest.NewRequest(http.MethodPost, "/v1/loyalty/internal/credit", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	var req AdjustmentRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+	if req.Amount != points {
+		t.Fatalf("amount = %d, want %d", req.Amount, points)
+	}
+
+	// The response passes through LoyaltyResponse's interface{} data
+	response, err := json.Marshal(LoyaltyResponse{Success: true, Data: &AdjustmentResult{
+		Transaction: &Transaction{ID: "tx-1", UserID: req.UserID, Type: "earn", Amount: req.Amount},
+		Balance:     points,
+	}})
+	if err != nil {
+		t.Fatalf("marshal response: %v", err)
+	}
+	var decoded struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := jsonutil.Unmarshal(response, &decoded); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if balance, err := jsonutil.MapInt64(decoded.Data, "balance"); err != nil || balance != points {
+		t.Errorf("balance = %d, %v; want %d", balance, err, int64(points))
+	}
+	transaction, _ := decoded.Data["transaction"].(map[string]interface{})
+	if amount, err := jsonutil.MapInt64(transaction, "amount"); err != nil || amount != points {
+		t.Errorf("transaction amount = %d, %v; want %d", amount, err, int64(points))
+	}
+}
+
+func TestConcurrentDeductsWithOneIdempotencyKey(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	tenantID := auth.TenantFromContext(ctx)
+	userID := createTestUser(t, ctx, s, 100)
+
+	serviceToken, err := s.jwtManager.GenerateServiceToken("redemption-svc")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	req := AdjustmentRequest{UserID: userID, Amount: 30, Reason: "redemption", IdempotencyKey: uuid.New().String()}
+
+	// Retries racing the original must all get its result, whether they
+	// find it once the user row is unlocked or collide on the unique key
+	const attempts = 8
+	start := make(chan struct{})
+	results := make([]*httptest.ResponseRecorder, attempts)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			results[i] = serve(s, http.MethodPost, "/v1/loyalty/internal/deduct", serviceToken, req, auth.TenantHeader, tenantID)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	transactions := map[string]bool{}
+	for i, rec := range results {
+		if rec.Code != http.StatusOK {
+			t.Fatalf("attempt %d: status = %d, want %d: %s", i, rec.Code, http.StatusOK, rec.Body)
+		}
+		var body struct {
+			Data AdjustmentResult `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("attempt %d: decode: %v", i, err)
+		}
+		if body.Data.Balance != 70 {
+			t.Errorf("attempt %d: balance = %d, want 70", i, body.Data.Balance)
+		}
+		transactions[body.Data.Transaction.ID] = true
+	}
+	if len(transactions) != 1 {
+		t.Errorf("recorded %d transactions, want 1", len(transactions))
+	}
+	if got := userPoints(t, ctx, s, userID); got != 70 {
+		t.Fatalf("points = %d, want 70", got)
+	}
+}
+
+func TestGetBalancesValidation(t *testing.T) {
+	s := newTestService(t, func(cfg *config.Config) {
+		cfg.Loyalty.MaxBalanceLookup = 2
+	})
+	serviceToken := token(t, s, "redemption-svc", auth.RoleService)
+
+	tests := []struct {
+		name    string
+		tok     string
+		userIDs []string
+		want    int
+	}{
+		{"user token", token(t, s, uuid.New().String(), "user"), []string{uuid.New().String()}, http.StatusForbidden},
+		{"admin token", token(t, s, uuid.New().String(), auth.RoleAdmin), []string{uuid.New().String()}, http.StatusForbidden},
+		{"no user IDs", serviceToken, []string{}, http.StatusUnprocessableEntity},
+		{"over the limit", serviceToken, []string{uuid.New().String(), uuid.New().String(), uuid.New().String()}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, http.MethodPost, "/v1/loyalty/balances", tt.tok, BalancesRequest{UserIDs: tt.userIDs})
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestGetBalances(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	tenantID := auth.TenantFromContext(ctx)
+	rich := createTestUser(t, ctx, s, 5000)
+	poor := createTestUser(t, ctx, s, 10)
+	otherTenant := createTestUser(t, auth.WithTenant(context.Background(), databasetest.Tenant(t)), s, 700)
+
+	serviceToken, err := s.jwtManager.GenerateServiceToken("redemption-svc")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	req := BalancesRequest{UserIDs: []string{rich, uuid.New().String(), poor, otherTenant}}
+	rec := serve(s, http.MethodPost, "/v1/loyalty/balances", serviceToken, req, auth.TenantHeader, tenantID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Data []User `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	// Unknown users and other tenants' users are omitted
+	points := make(map[string]int)
+	for _, user := range body.Data {
+		points[user.ID] = user.Points
+	}
+	if len(points) != 2 || points[rich] != 5000 || points[poor] != 10 {
+		t.Fatalf("balances = %v, want %s: 5000 and %s: 10", points, rich, poor)
+	}
+}
@@ -0,0 +1,13 @@
+package loyalty
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations holds the service's schema migrations, which its main applies
+// on startup with the migrate package
+var Migrations, _ = fs.Sub(migrationFiles, "migrations")
@@ -0,0 +1,99 @@
+-- Loyalty members, their point transactions and holds, and the rewards catalog
+
+-- Create loyalty_users table
+CREATE TABLE IF NOT EXISTS loyalty_users (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) DEFAULT 'default' NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    points INTEGER DEFAULT 0 NOT NULL,
+    lifetime_points INTEGER DEFAULT 0 NOT NULL,
+    tier VARCHAR(50) DEFAULT 'Bronze' NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (tenant_id, email)
+);
+
+-- Create loyalty_transactions table
+CREATE TABLE IF NOT EXISTS loyalty_transactions (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) DEFAULT 'default' NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('earn', 'spend', 'reversal', 'expire')),
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    description TEXT NOT NULL,
+    idempotency_key VARCHAR(255),
+    redemption_id VARCHAR(36),
+    reverses_id VARCHAR(36) REFERENCES loyalty_transactions(id),
+    settled_at TIMESTAMP WITH TIME ZONE,
+    balance_after INTEGER,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (user_id) REFERENCES loyalty_users(id) ON DELETE CASCADE
+);
+
+-- Create loyalty_rewards table
+CREATE TABLE IF NOT EXISTS loyalty_rewards (
+    id VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    points_cost INTEGER NOT NULL CHECK (points_cost > 0),
+    category VARCHAR(100) NOT NULL,
+    is_active BOOLEAN DEFAULT true NOT NULL,
+    created_by VARCHAR(255),
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- Create loyalty_point_holds table (points authorized but not yet spent)
+CREATE TABLE IF NOT EXISTS loyalty_point_holds (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) DEFAULT 'default' NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    reference VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'held' CHECK (status IN ('held', 'captured', 'released', 'expired')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES loyalty_users(id) ON DELETE CASCADE
+);
+
+-- Create indexes for better performance
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_point_holds_reference ON loyalty_point_holds(tenant_id, reference);
+CREATE INDEX IF NOT EXISTS idx_loyalty_point_holds_user_status ON loyalty_point_holds(user_id, status);
+CREATE INDEX IF NOT EXISTS idx_loyalty_users_tenant ON loyalty_users(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_users_tier ON loyalty_users(tier);
+CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_user_id ON loyalty_transactions(tenant_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_created_at ON loyalty_transactions(created_at);
+CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_user_history ON loyalty_transactions(user_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_transactions_idempotency ON loyalty_transactions(user_id, type, idempotency_key) WHERE idempotency_key IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_transactions_reverses ON loyalty_transactions(reverses_id) WHERE reverses_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_expires_at ON loyalty_transactions(expires_at) WHERE type = 'earn' AND expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_redemption ON loyalty_transactions(redemption_id) WHERE redemption_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_loyalty_rewards_category ON loyalty_rewards(category);
+CREATE INDEX IF NOT EXISTS idx_loyalty_rewards_points_cost ON loyalty_rewards(points_cost);
+CREATE INDEX IF NOT EXISTS idx_loyalty_rewards_active ON loyalty_rewards(is_active);
+
+-- Create function to automatically update updated_at timestamp
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- Create triggers to automatically update updated_at
+CREATE OR REPLACE TRIGGER update_loyalty_users_updated_at
+    BEFORE UPDATE ON loyalty_users
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE OR REPLACE TRIGGER update_loyalty_rewards_updated_at
+    BEFORE UPDATE ON loyalty_rewards
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE OR REPLACE TRIGGER update_loyalty_point_holds_updated_at
+    BEFORE UPDATE ON loyalty_point_holds
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
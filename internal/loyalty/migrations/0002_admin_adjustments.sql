@@ -0,0 +1,25 @@
+-- Manual point adjustments by support agents, and the audit log recording who made them
+
+-- Adjustments are signed: credits are positive and debits negative
+ALTER TABLE loyalty_transactions DROP CONSTRAINT IF EXISTS loyalty_transactions_type_check;
+ALTER TABLE loyalty_transactions ADD CONSTRAINT loyalty_transactions_type_check
+    CHECK (type IN ('earn', 'spend', 'reversal', 'expire', 'adjustment'));
+ALTER TABLE loyalty_transactions DROP CONSTRAINT IF EXISTS loyalty_transactions_amount_check;
+ALTER TABLE loyalty_transactions ADD CONSTRAINT loyalty_transactions_amount_check
+    CHECK (amount > 0 OR (type = 'adjustment' AND amount <> 0));
+
+-- Create audit_log table
+CREATE TABLE IF NOT EXISTS audit_log (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) DEFAULT 'default' NOT NULL,
+    actor_id VARCHAR(255) NOT NULL,
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id VARCHAR(36) NOT NULL,
+    reason TEXT NOT NULL,
+    details JSONB DEFAULT '{}' NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(tenant_id, target_type, target_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at DESC);
@@ -0,0 +1,16 @@
+-- Rewards redeemed directly from the loyalty program, each paid for by a spend transaction
+
+CREATE TABLE IF NOT EXISTS reward_redemptions (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) DEFAULT 'default' NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    reward_id VARCHAR(36) NOT NULL,
+    points INTEGER NOT NULL CHECK (points > 0),
+    transaction_id VARCHAR(36) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES loyalty_users(id) ON DELETE CASCADE,
+    FOREIGN KEY (reward_id) REFERENCES loyalty_rewards(id),
+    FOREIGN KEY (transaction_id) REFERENCES loyalty_transactions(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reward_redemptions_user ON reward_redemptions(tenant_id, user_id, created_at DESC);
@@ -0,0 +1,286 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+var (
+	errDeductionNotFound = errors.New("no deduction recorded for redemption")
+	errDeductionReversed = errors.New("deduction has already been reversed")
+	errDeductionSettled  = errors.New("deduction has already been settled")
+)
+
+// ReversalRequest represents a request to refund a redemption's deduction
+type ReversalRequest struct {
+	UserID       string `json:"user_id" validate:"required"`
+	RedemptionID string `json:"redemption_id" validate:"required"`
+	Reason       string `json:"reason" validate:"required"`
+}
+
+// SettlementRequest marks a redemption's deduction as fulfilled
+type SettlementRequest struct {
+	UserID       string `json:"user_id" validate:"required"`
+	RedemptionID string `json:"redemption_id" validate:"required"`
+}
+
+// ReversalResult is the outcome of a reversal
+type ReversalResult struct {
+	Reversal  *Transaction `json:"reversal"`
+	Deduction *Transaction `json:"deduction"`
+	Balance   int          `json:"balance"`
+	Replayed  bool         `json:"replayed"`
+}
+
+// OrphanedDeduction is a redemption deduction that has not been reversed
+type OrphanedDeduction struct {
+	Transaction
+	RedemptionID string `json:"redemption_id"`
+}
+
+// ReverseDeduction refunds the points deducted for a redemption. The reversal
+// is recorded as its own ledger transaction linked to the deduction, and a
+// deduction can only ever be reversed once, so retries are safe.
+func (s *Service) ReverseDeduction(w http.ResponseWriter, r *http.Request) {
+	var req ReversalRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	result, err := s.reverseDeduction(r.Context(), &req)
+	if database.IsUniqueViolation(err) {
+		// A concurrent retry recorded the reversal first; replay it
+		result, err = s.reverseDeduction(r.Context(), &req)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errUserNotFound):
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "User not found")
+		case errors.Is(err, errDeductionNotFound):
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "No deduction found for this redemption")
+		case errors.Is(err, errDeductionSettled):
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "Deduction was settled by a fulfilled redemption")
+		default:
+			s.logger.Errorf("Failed to reverse deduction for redemption %s: %v", req.RedemptionID, err)
+			platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to reverse deduction")
+		}
+		return
+	}
+
+	render.JSON(w, r, LoyaltyResponse{Success: true, Message: "Deduction reversed successfully", Data: result})
+}
+
+// SettleDeduction records that a redemption's deduction paid for a fulfilled
+// benefit, so it is no longer reported as orphaned. Settling is idempotent.
+func (s *Service) SettleDeduction(w http.ResponseWriter, r *http.Request) {
+	var req SettlementRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	if err := s.settleDeduction(r.Context(), &req); err != nil {
+		switch {
+		case errors.Is(err, errDeductionNotFound):
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "No deduction found for this redemption")
+		case errors.Is(err, errDeductionReversed):
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "Deduction has already been reversed")
+		default:
+			s.logger.Errorf("Failed to settle deduction for redemption %s: %v", req.RedemptionID, err)
+			platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to settle deduction")
+		}
+		return
+	}
+
+	render.JSON(w, r, LoyaltyResponse{Success: true, Message: "Deduction settled successfully"})
+}
+
+// GetOrphanedDeductions reports redemption deductions older than the orphan
+// grace period that were neither settled by a fulfillment nor reversed, i.e.
+// points taken for a benefit the user never received.
+func (s *Service) GetOrphanedDeductions(w http.ResponseWriter, r *http.Request) {
+	before := time.Now().Add(-s.config.Loyalty.OrphanGrace)
+
+	deductions, err := s.getOrphanedDeductions(r.Context(), before)
+	if err != nil {
+		s.logger.Errorf("Failed to get orphaned deductions: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get orphaned deductions")
+		return
+	}
+
+	render.JSON(w, r, LoyaltyResponse{
+		Success: true,
+		Message: "Orphaned deductions retrieved successfully",
+		Data: map[string]interface{}{
+			"deductions": deductions,
+			"total":      len(deductions),
+			"before":     before,
+		},
+	})
+}
+
+func (s *Service) reverseDeduction(ctx context.Context, req *ReversalRequest) (*ReversalResult, error) {
+	tenantID := auth.TenantFromContext(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var points int
+	err = tx.QueryRow(ctx, `SELECT points FROM loyalty_users WHERE id = $1 AND tenant_id = $2 FOR UPDATE`, req.UserID, tenantID).Scan(&points)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, err
+	}
+
+	deduction, settled, err := s.getRedemptionDeduction(ctx, tx, req.UserID, req.RedemptionID)
+	if err != nil {
+		return nil, err
+	}
+	if settled {
+		return nil, errDeductionSettled
+	}
+
+	// The user row lock serializes retries, so the lookup cannot race the insert
+	var existing Transaction
+	var balanceAfter int
+	err = tx.QueryRow(ctx, `
+		SELECT id, user_id, type, amount, description, COALESCE(created_by, ''), balance_after, created_at
+		FROM loyalty_transactions WHERE reverses_id = $1
+	`, deduction.ID).Scan(&existing.ID, &existing.UserID, &existing.Type, &existing.Amount, &existing.Description, &existing.CreatedBy, &balanceAfter, &existing.CreatedAt)
+	if err == nil {
+		return &ReversalResult{Reversal: &existing, Deduction: deduction, Balance: balanceAfter, Replayed: true}, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	now := time.Now()
+	reversal := &Transaction{
+		ID:          uuid.New().String(),
+		UserID:      req.UserID,
+		Type:        "reversal",
+		Amount:      deduction.Amount,
+		Description: req.Reason,
+		CreatedAt:   now,
+		CreatedBy:   authmw.Actor(ctx),
+	}
+	balance := points + deduction.Amount
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO loyalty_transactions (id, tenant_id, user_id, type, amount, description, redemption_id, reverses_id, balance_after, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, reversal.ID, tenantID, reversal.UserID, reversal.Type, reversal.Amount, reversal.Description, req.RedemptionID, deduction.ID, balance, reversal.CreatedBy, reversal.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE loyalty_users SET points = $1, updated_at = $2 WHERE id = $3 AND tenant_id = $4`, balance, now, req.UserID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.invalidateBalance(ctx, req.UserID)
+
+	s.logger.Infof("Reversed %d points deducted for redemption %s by %s", reversal.Amount, req.RedemptionID, reversal.CreatedBy)
+	return &ReversalResult{Reversal: reversal, Deduction: deduction, Balance: balance}, nil
+}
+
+func (s *Service) settleDeduction(ctx context.Context, req *SettlementRequest) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	deduction, settled, err := s.getRedemptionDeduction(ctx, tx, req.UserID, req.RedemptionID)
+	if err != nil {
+		return err
+	}
+	if settled {
+		return nil
+	}
+
+	var reversed bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM loyalty_transactions WHERE reverses_id = $1)`, deduction.ID).Scan(&reversed)
+	if err != nil {
+		return err
+	}
+	if reversed {
+		return errDeductionReversed
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE loyalty_transactions SET settled_at = $1 WHERE id = $2`, time.Now(), deduction.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// getRedemptionDeduction locks and returns the deduction recorded for a
+// redemption, and whether it has been settled
+func (s *Service) getRedemptionDeduction(ctx context.Context, tx pgx.Tx, userID, redemptionID string) (*Transaction, bool, error) {
+	var deduction Transaction
+	var settledAt *time.Time
+	err := tx.QueryRow(ctx, `
+		SELECT id, user_id, type, amount, description, COALESCE(created_by, ''), created_at, settled_at
+		FROM loyalty_transactions
+		WHERE user_id = $1 AND tenant_id = $2 AND redemption_id = $3 AND type = 'spend'
+		FOR UPDATE
+	`, userID, auth.TenantFromContext(ctx), redemptionID).Scan(&deduction.ID, &deduction.UserID, &deduction.Type, &deduction.Amount, &deduction.Description, &deduction.CreatedBy, &deduction.CreatedAt, &settledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, errDeductionNotFound
+		}
+		return nil, false, err
+	}
+	return &deduction, settledAt != nil, nil
+}
+
+func (s *Service) getOrphanedDeductions(ctx context.Context, before time.Time) ([]*OrphanedDeduction, error) {
+	query := `
+		SELECT d.id, d.user_id, d.type, d.amount, d.description, COALESCE(d.created_by, ''), d.created_at, d.redemption_id
+		FROM loyalty_transactions d
+		WHERE d.tenant_id = $1 AND d.type = 'spend' AND d.redemption_id IS NOT NULL AND d.created_at < $2
+		AND d.settled_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM loyalty_transactions r WHERE r.reverses_id = d.id)
+		ORDER BY d.created_at ASC
+	`
+
+	rows, err := s.db.Query(ctx, query, auth.TenantFromContext(ctx), before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deductions := []*OrphanedDeduction{}
+	for rows.Next() {
+		var d OrphanedDeduction
+		err := rows.Scan(&d.ID, &d.UserID, &d.Type, &d.Amount, &d.Description, &d.CreatedBy, &d.CreatedAt, &d.RedemptionID)
+		if err != nil {
+			return nil, err
+		}
+		deductions = append(deductions, &d)
+	}
+
+	return deductions, rows.Err()
+}
@@ -0,0 +1,212 @@
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// redemptionLedger drives the saga's internal endpoints for one tenant
+type redemptionLedger struct {
+	t        *testing.T
+	s        *Service
+	tenantID string
+	tok      string
+}
+
+func newRedemptionLedger(t *testing.T, s *Service, ctx context.Context) *redemptionLedger {
+	t.Helper()
+
+	tok, err := s.jwtManager.GenerateServiceToken("redemption-svc")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	return &redemptionLedger{t: t, s: s, tenantID: auth.TenantFromContext(ctx), tok: tok}
+}
+
+// call posts body to an internal endpoint, decoding the response data into
+// out on success, and returns the status
+func (l *redemptionLedger) call(path string, body, out interface{}) int {
+	l.t.Helper()
+
+	rec := serve(l.s, http.MethodPost, "/v1/loyalty/internal/"+path, l.tok, body, auth.TenantHeader, l.tenantID)
+	if rec.Code == http.StatusOK && out != nil {
+		resp := struct {
+			Data interface{} `json:"data"`
+		}{Data: out}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			l.t.Fatalf("decode %s: %v", path, err)
+		}
+	}
+	return rec.Code
+}
+
+// deduct spends points for a new redemption and returns its ID
+func (l *redemptionLedger) deduct(userID string, amount int) string {
+	l.t.Helper()
+
+	redemptionID := uuid.New().String()
+	req := AdjustmentRequest{UserID: userID, Amount: amount, Reason: "redemption", IdempotencyKey: redemptionID, RedemptionID: redemptionID}
+	if status := l.call("deduct", req, nil); status != http.StatusOK {
+		l.t.Fatalf("deduct: status = %d", status)
+	}
+	return redemptionID
+}
+
+// reversalCount counts the reversals recorded for a redemption
+func reversalCount(t *testing.T, ctx context.Context, s *Service, redemptionID string) int {
+	t.Helper()
+
+	var n int
+	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM loyalty_transactions WHERE redemption_id = $1 AND type = 'reversal'`, redemptionID).Scan(&n)
+	if err != nil {
+		t.Fatalf("failed to count reversals: %v", err)
+	}
+	return n
+}
+
+func TestReverseDeductionIsRecordedOnce(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	ledger := newRedemptionLedger(t, s, ctx)
+	userID := createTestUser(t, ctx, s, 100)
+	redemptionID := ledger.deduct(userID, 60)
+
+	req := ReversalRequest{UserID: userID, RedemptionID: redemptionID, Reason: "partner rejected"}
+	var first ReversalResult
+	if status := ledger.call("reverse", req, &first); status != http.StatusOK {
+		t.Fatalf("reverse: status = %d", status)
+	}
+	if first.Replayed || first.Balance != 100 || first.Reversal.Amount != 60 || first.Deduction == nil {
+		t.Fatalf("reverse = %+v, want 60 points refunded to 100", first)
+	}
+
+	// Retries replay the recorded reversal, including concurrent ones
+	var wg sync.WaitGroup
+	results := make([]ReversalResult, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if status := ledger.call("reverse", req, &results[i]); status != http.StatusOK {
+				t.Errorf("retried reverse: status = %d", status)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, retry := range results {
+		if !retry.Replayed || retry.Reversal == nil || retry.Reversal.ID != first.Reversal.ID || retry.Balance != 100 {
+			t.Fatalf("retried reverse = %+v, want the first reversal replayed", retry)
+		}
+	}
+
+	if got := reversalCount(t, ctx, s, redemptionID); got != 1 {
+		t.Fatalf("recorded %d reversals, want 1", got)
+	}
+	if got := userPoints(t, ctx, s, userID); got != 100 {
+		t.Fatalf("points = %d, want 100", got)
+	}
+
+	// A reversed deduction can no longer be settled
+	if status := ledger.call("settle", SettlementRequest{UserID: userID, RedemptionID: redemptionID}, nil); status != http.StatusConflict {
+		t.Fatalf("settle after reversal: status = %d, want %d", status, http.StatusConflict)
+	}
+}
+
+func TestReverseDeductionRejectsSettledAndUnknown(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	ledger := newRedemptionLedger(t, s, ctx)
+	userID := createTestUser(t, ctx, s, 100)
+
+	unknown := ReversalRequest{UserID: userID, RedemptionID: uuid.New().String(), Reason: "partner rejected"}
+	if status := ledger.call("reverse", unknown, nil); status != http.StatusNotFound {
+		t.Fatalf("reverse unknown redemption: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	redemptionID := ledger.deduct(userID, 60)
+	if status := ledger.call("settle", SettlementRequest{UserID: userID, RedemptionID: redemptionID}, nil); status != http.StatusOK {
+		t.Fatalf("settle: status = %d", status)
+	}
+	settled := ReversalRequest{UserID: userID, RedemptionID: redemptionID, Reason: "partner rejected"}
+	if status := ledger.call("reverse", settled, nil); status != http.StatusConflict {
+		t.Fatalf("reverse settled redemption: status = %d, want %d", status, http.StatusConflict)
+	}
+	if got := reversalCount(t, ctx, s, redemptionID); got != 0 {
+		t.Fatalf("recorded %d reversals of a settled deduction", got)
+	}
+}
+
+func TestOrphanedDeductionsReport(t *testing.T) {
+	s := newTestService(t, withTenancy, func(cfg *config.Config) {
+		cfg.Loyalty.OrphanGrace = 0
+	})
+	ctx := withTestDB(t, s)
+	ledger := newRedemptionLedger(t, s, ctx)
+	userID := createTestUser(t, ctx, s, 1000)
+
+	// A failed redemption whose reversal never happened, beside one that was
+	// fulfilled and one that was refunded
+	failed := ledger.deduct(userID, 100)
+	fulfilled := ledger.deduct(userID, 200)
+	if status := ledger.call("settle", SettlementRequest{UserID: userID, RedemptionID: fulfilled}, nil); status != http.StatusOK {
+		t.Fatalf("settle: status = %d", status)
+	}
+	reversed := ledger.deduct(userID, 300)
+	if status := ledger.call("reverse", ReversalRequest{UserID: userID, RedemptionID: reversed, Reason: "partner rejected"}, nil); status != http.StatusOK {
+		t.Fatalf("reverse: status = %d", status)
+	}
+
+	admin, err := s.jwtManager.GenerateTenantToken(uuid.New().String(), "admin@example.com", auth.RoleAdmin, ledger.tenantID)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	rec := serve(s, http.MethodGet, "/v1/loyalty/admin/orphaned-deductions", admin, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Data struct {
+			Deductions []OrphanedDeduction `json:"deductions"`
+			Total      int                 `json:"total"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Data.Total != 1 || len(body.Data.Deductions) != 1 {
+		t.Fatalf("report = %+v, want only the failed redemption", body.Data)
+	}
+	if orphan := body.Data.Deductions[0]; orphan.RedemptionID != failed || orphan.Amount != 100 || orphan.UserID != userID {
+		t.Fatalf("orphan = %+v, want redemption %s's 100 points", orphan, failed)
+	}
+
+	// Once reversed, the deduction is no longer an orphan
+	if status := ledger.call("reverse", ReversalRequest{UserID: userID, RedemptionID: failed, Reason: "reconciled"}, nil); status != http.StatusOK {
+		t.Fatalf("reverse: status = %d", status)
+	}
+	rec = serve(s, http.MethodGet, "/v1/loyalty/admin/orphaned-deductions", admin, nil)
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Data.Total != 0 {
+		t.Fatalf("report = %+v after the reversal, want none", body.Data)
+	}
+}
+
+func TestOrphanedDeductionsRequireAdmin(t *testing.T) {
+	s := newTestService(t)
+
+	for _, role := range []string{"user", auth.RoleService} {
+		rec := serve(s, http.MethodGet, "/v1/loyalty/admin/orphaned-deductions", token(t, s, uuid.New().String(), role), nil)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s: status = %d, want %d", role, rec.Code, http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package loyalty
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+var (
+	errRewardNotFound = errors.New("reward not found")
+	errRewardInactive = errors.New("reward is not active")
+)
+
+// RewardRedemption records a reward redeemed with the points of a spend
+// transaction
+type RewardRedemption struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"user_id"`
+	RewardID      string    `json:"reward_id"`
+	Points        int       `json:"points"`
+	TransactionID string    `json:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// RewardRedemptionResult is the response to a reward redemption
+type RewardRedemptionResult struct {
+	Redemption  *RewardRedemption `json:"redemption"`
+	Transaction *Transaction      `json:"transaction"`
+	// Balance is the user's points after the redemption
+	Balance  int  `json:"balance"`
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+// RedeemReward spends the reward's points cost from the caller's balance and
+// records the redemption. Unlike benefit redemptions it involves no partner,
+// so it completes in one database transaction. An Idempotency-Key header
+// makes it safe to retry.
+func (s *Service) RedeemReward(w http.ResponseWriter, r *http.Request) {
+	rewardID := chi.URLParam(r, "id")
+	userID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
+		return
+	}
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+
+	// Ensure user exists in loyalty_users (auto-create if needed)
+	if _, err := s.getUserByID(r.Context(), userID); err != nil {
+		s.logger.Errorf("Failed to get user: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get user info")
+		return
+	}
+
+	result, err := s.redeemReward(r.Context(), userID, rewardID, idempotencyKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, errRewardNotFound):
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Reward not found")
+		case errors.Is(err, errRewardInactive):
+			platformhttp.Error(w, r, http.StatusUnprocessableEntity, platformhttp.ErrCodeBenefitUnavailable, "Reward is no longer offered")
+		case errors.Is(err, errInsufficientPoints):
+			platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInsufficientPoints, "Insufficient points")
+		case errors.Is(err, errIdempotencyConflict):
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "Idempotency-Key already used with a different request")
+		default:
+			s.logger.Errorf("Failed to redeem reward %s for user %s: %v", rewardID, userID, err)
+			platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to redeem reward")
+		}
+		return
+	}
+
+	message := "Reward redeemed successfully"
+	if result.Replayed {
+		message = "Reward already redeemed"
+	}
+	render.JSON(w, r, LoyaltyResponse{Success: true, Message: message, Data: result})
+}
+
+// redeemReward records the spend and the redemption together. The spend goes
+// through recordPointsChange, so the balance is checked under the same user
+// row lock as any other spend.
+func (s *Service) redeemReward(ctx context.Context, userID, rewardID, idempotencyKey string) (*RewardRedemptionResult, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var name string
+	var cost int
+	var active bool
+	err = tx.QueryRow(ctx, `
+		SELECT name, points_cost, is_active FROM loyalty_rewards WHERE id = $1 FOR SHARE
+	`, rewardID).Scan(&name, &cost, &active)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errRewardNotFound
+		}
+		return nil, err
+	}
+	if !active {
+		return nil, errRewardInactive
+	}
+
+	now := time.Now()
+	transaction := &Transaction{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Type:        "spend",
+		Amount:      cost,
+		Description: "Redeemed reward " + name,
+		CreatedAt:   now,
+		CreatedBy:   authmw.Actor(ctx),
+	}
+	change, err := s.recordPointsChange(ctx, tx, transaction, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RewardRedemptionResult{Transaction: change.Transaction, Balance: change.Balance, Replayed: change.Replayed}
+	if change.Replayed {
+		result.Redemption, err = getRewardRedemption(ctx, tx, change.Transaction.ID)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	redemption := &RewardRedemption{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		RewardID:      rewardID,
+		Points:        cost,
+		TransactionID: transaction.ID,
+		CreatedAt:     now,
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO reward_redemptions (id, tenant_id, user_id, reward_id, points, transaction_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, redemption.ID, auth.TenantFromContext(ctx), redemption.UserID, redemption.RewardID, redemption.Points,
+		redemption.TransactionID, redemption.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.pointsChanged(ctx, transaction, change)
+	s.logger.Infof("User %s redeemed reward %s for %d points", userID, rewardID, cost)
+
+	result.Redemption = redemption
+	return result, nil
+}
+
+// getRewardRedemption returns the redemption paid for by a transaction, or
+// errIdempotencyConflict when the transaction was a plain spend
+func getRewardRedemption(ctx context.Context, tx pgx.Tx, transactionID string) (*RewardRedemption, error) {
+	var redemption RewardRedemption
+	err := tx.QueryRow(ctx, `
+		SELECT id, user_id, reward_id, points, transaction_id, created_at
+		FROM reward_redemptions WHERE transaction_id = $1 AND tenant_id = $2
+	`, transactionID, auth.TenantFromContext(ctx)).Scan(&redemption.ID, &redemption.UserID, &redemption.RewardID,
+		&redemption.Points, &redemption.TransactionID, &redemption.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errIdempotencyConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
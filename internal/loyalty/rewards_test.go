@@ -0,0 +1,144 @@
+package loyalty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+)
+
+// createTestReward inserts a reward costing cost points
+func createTestReward(t *testing.T, ctx context.Context, s *Service, cost int, active bool) string {
+	t.Helper()
+
+	rewardID := uuid.New().String()
+	err := s.db.Exec(ctx, `
+		INSERT INTO loyalty_rewards (id, name, points_cost, category, is_active)
+		VALUES ($1, 'Coffee voucher', $2, 'dining', $3)
+	`, rewardID, cost, active)
+	if err != nil {
+		t.Fatalf("failed to create reward: %v", err)
+	}
+	return rewardID
+}
+
+// redeem posts a redemption of rewardID as userID, returning the status and
+// the decoded result
+func redeem(t *testing.T, ctx context.Context, s *Service, userID, rewardID string, headers ...string) (int, *RewardRedemptionResult) {
+	t.Helper()
+
+	tok := tenantToken(t, s, userID, auth.TenantFromContext(ctx))
+	rec := serve(s, http.MethodPost, "/v1/loyalty/rewards/"+rewardID+"/redeem", tok, nil, headers...)
+	var body struct {
+		Data *RewardRedemptionResult `json:"data"`
+	}
+	if rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return rec.Code, body.Data
+}
+
+// rewardRedemptions counts a user's reward redemptions
+func rewardRedemptions(t *testing.T, ctx context.Context, s *Service, userID string) int {
+	t.Helper()
+
+	var n int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM reward_redemptions WHERE user_id = $1`, userID).Scan(&n); err != nil {
+		t.Fatalf("failed to count reward redemptions: %v", err)
+	}
+	return n
+}
+
+func TestRedeemRewardRequiresAuthentication(t *testing.T) {
+	s := newTestService(t)
+
+	if rec := serve(s, http.MethodPost, "/v1/loyalty/rewards/"+uuid.New().String()+"/redeem", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRedeemReward(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 1000)
+	rewardID := createTestReward(t, ctx, s, 400, true)
+
+	status, result := redeem(t, ctx, s, userID, rewardID)
+	if status != http.StatusOK || result.Balance != 600 {
+		t.Fatalf("status %d, result %+v; want balance 600", status, result)
+	}
+	tx, redemption := result.Transaction, result.Redemption
+	if tx.Type != "spend" || tx.Amount != 400 {
+		t.Fatalf("transaction = %+v, want a 400 point spend", tx)
+	}
+	if redemption.RewardID != rewardID || redemption.UserID != userID || redemption.Points != 400 || redemption.TransactionID != tx.ID {
+		t.Fatalf("redemption = %+v, want reward %s for 400 points paid by %s", redemption, rewardID, tx.ID)
+	}
+	if points := userPoints(t, ctx, s, userID); points != 600 {
+		t.Fatalf("points = %d, want 600", points)
+	}
+	if n := rewardRedemptions(t, ctx, s, userID); n != 1 {
+		t.Fatalf("%d reward redemptions, want 1", n)
+	}
+}
+
+func TestRedeemRewardRefusals(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+
+	tests := []struct {
+		name     string
+		cost     int
+		active   bool
+		rewardID string
+		want     int
+	}{
+		{"insufficient points", 1001, true, "", http.StatusBadRequest},
+		{"inactive reward", 100, false, "", http.StatusUnprocessableEntity},
+		{"unknown reward", 0, false, uuid.New().String(), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := createTestUser(t, ctx, s, 1000)
+			rewardID := tt.rewardID
+			if rewardID == "" {
+				rewardID = createTestReward(t, ctx, s, tt.cost, tt.active)
+			}
+
+			if status, _ := redeem(t, ctx, s, userID, rewardID); status != tt.want {
+				t.Fatalf("status = %d, want %d", status, tt.want)
+			}
+			if points := userPoints(t, ctx, s, userID); points != 1000 {
+				t.Fatalf("points = %d after a refused redemption, want 1000", points)
+			}
+			if n := rewardRedemptions(t, ctx, s, userID); n != 0 {
+				t.Fatalf("%d reward redemptions recorded for a refused redemption", n)
+			}
+		})
+	}
+}
+
+func TestRedeemRewardIdempotency(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 1000)
+	rewardID := createTestReward(t, ctx, s, 400, true)
+	key := uuid.New().String()
+
+	_, first := redeem(t, ctx, s, userID, rewardID, "Idempotency-Key", key)
+	status, retry := redeem(t, ctx, s, userID, rewardID, "Idempotency-Key", key)
+	if status != http.StatusOK || !retry.Replayed || retry.Redemption.ID != first.Redemption.ID || retry.Balance != 600 {
+		t.Fatalf("retry: status %d, result %+v; want the first redemption replayed", status, retry)
+	}
+	if points := userPoints(t, ctx, s, userID); points != 600 {
+		t.Fatalf("points = %d after a retry, want 600", points)
+	}
+	if n := rewardRedemptions(t, ctx, s, userID); n != 1 {
+		t.Fatalf("%d reward redemptions after a retry, want 1", n)
+	}
+}
@@ -2,16 +2,22 @@ package loyalty
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/lifecycle"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,26 +27,45 @@ type Service struct {
 	logger     *logrus.Logger
 	db         *database.PostgresDB
 	jwtManager *auth.JWTManager
+	tenants    auth.TenantScope
+	// tiers are the configured loyalty tiers, lowest threshold first
+	tiers []config.TierConfig
+	// background runs the hold and expiry sweepers
+	background *lifecycle.Group
+	// earnLimiter throttles earning per user; nil when rate limiting is disabled
+	earnLimiter *platformhttp.RateLimiter
+	// redis backs the balance cache and shared rate limits; nil when no
+	// Redis address is configured
+	redis *database.RedisDB
+	// balances caches GetBalance reads; nil when disabled
+	balances *balanceCache
 }
 
 // User represents a user's loyalty profile
 type User struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Points    int       `json:"points"`
-	Tier      string    `json:"tier"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID     string `json:"id"`
+	Email  string `json:"email"`
+	Points int    `json:"points"`
+	// HeldPoints are reserved by active holds and cannot be spent
+	HeldPoints      int       `json:"held_points"`
+	AvailablePoints int       `json:"available_points"`
+	Tier            string    `json:"tier"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 // Transaction represents a loyalty transaction
 type Transaction struct {
 	ID          string    `json:"id"`
 	UserID      string    `json:"user_id"`
-	Type        string    `json:"type"` // "earn" or "spend"
+	Type        string    `json:"type"` // "earn", "spend", "reversal", "expire", or "adjustment"
 	Amount      int       `json:"amount"`
 	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"created_at"`
+	// ExpiresAt is when earned points expire, if points expiry is enabled
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// CreatedBy identifies the acting user or service
+	CreatedBy string `json:"created_by,omitempty"`
 }
 
 // Reward represents an available reward
@@ -67,6 +92,16 @@ type SpendRequest struct {
 	Description string `json:"description" validate:"required"`
 }
 
+// pointsChange is the outcome of applyPointsChange
+type pointsChange struct {
+	// Transaction is the recorded transaction, or the original one on a replay
+	Transaction *Transaction
+	TierChange  *TierChange
+	Replayed    bool
+	// Balance is the user's points after the change
+	Balance int
+}
+
 // LoyaltyResponse represents a loyalty service response
 type LoyaltyResponse struct {
 	Success bool        `json:"success"`
@@ -77,19 +112,63 @@ type LoyaltyResponse struct {
 // NewService creates a new loyalty service
 func NewService(cfg *config.Config, logger *logrus.Logger) *Service {
 	// Initialize JWT manager
-	jwtConfig := &auth.JWTConfig{
-		Secret:     cfg.Security.JWT.Secret,
-		Issuer:     cfg.Security.JWT.Issuer,
-		Audience:   cfg.Security.JWT.Audience,
-		Expiration: cfg.Security.JWT.Expiration,
-	}
-	jwtManager := auth.NewJWTManager(jwtConfig)
+	jwtManager := auth.NewJWTManagerFromConfig(cfg)
 
-	return &Service{
+	service := &Service{
 		config:     cfg,
 		logger:     logger,
 		jwtManager: jwtManager,
+		tenants:    auth.NewTenantScope(cfg.Security.Tenancy.Enabled, cfg.Security.Tenancy.DefaultTenant),
+		tiers:      sortTiers(cfg.Loyalty.Tiers),
+		background: lifecycle.NewGroup(),
+	}
+
+	if cfg.Redis.Addr != "" {
+		service.redis = database.OpenRedisDB(&database.RedisConfig{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+			PoolSize: cfg.Redis.PoolSize,
+		})
+	}
+	service.balances = newBalanceCache(service.redis, cfg.Loyalty.BalanceCacheTTL, logger)
+
+	// Throttle earning per user so a leaked token cannot mint points in bulk
+	if rl := cfg.Security.RateLimit; rl.Enabled {
+		var store platformhttp.RateLimitStore
+		if rl.Redis && service.redis != nil {
+			store = platformhttp.NewRedisRateLimitStore(service.redis)
+		}
+
+		limits := rl.ForRoute("loyalty_earn")
+		service.earnLimiter = platformhttp.NewRateLimiter(platformhttp.RateLimitConfig{
+			Name:              "loyalty_earn",
+			RequestsPerMinute: limits.RequestsPerMinute,
+			Burst:             limits.Burst,
+			Headers:           rl.Headers,
+			KeyFunc:           authmw.RateLimitKey,
+			Store:             store,
+			Logger:            logger,
+		})
 	}
+
+	return service
+}
+
+// Start runs the hold and expiry sweepers in the background until Shutdown
+func (s *Service) Start() {
+	s.background.Go(s.RunHoldSweeper)
+	s.background.Go(s.RunExpirySweeper)
+}
+
+// Shutdown stops the sweepers and waits, until ctx is done, for a sweep in
+// progress to finish
+func (s *Service) Shutdown(ctx context.Context) error {
+	err := s.background.Shutdown(ctx)
+	if s.redis != nil {
+		s.redis.Close()
+	}
+	return err
 }
 
 // SetDatabase sets the database connection
@@ -98,46 +177,76 @@ func (s *Service) SetDatabase(db *database.PostgresDB) {
 }
 
 // Routes returns the loyalty service routes
-func (s *Service) Routes(r *chi.Mux) {
+func (s *Service) Routes(r chi.Router) {
+	authOpts := []authmw.Option{authmw.WithTenants(s.tenants), authmw.WithLogger(s.logger)}
+
 	r.Route("/v1/loyalty", func(r chi.Router) {
-		r.Post("/earn", s.AuthMiddleware(s.EarnPoints))
-		r.Post("/spend", s.AuthMiddleware(s.SpendPoints))
-		r.Get("/balance", s.AuthMiddleware(s.GetBalance))
-		r.Get("/history", s.AuthMiddleware(s.GetHistory))
 		r.Get("/rewards", s.GetRewards)
+
+		r.Group(func(r chi.Router) {
+			r.Use(authmw.RequireJWT(s.jwtManager, authOpts...))
+
+			if s.earnLimiter != nil {
+				r.With(s.earnLimiter.Middleware).Post("/earn", s.EarnPoints)
+			} else {
+				r.Post("/earn", s.EarnPoints)
+			}
+			r.Post("/spend", s.SpendPoints)
+			r.Post("/rewards/{id}/redeem", s.RedeemReward)
+			r.Get("/balance", s.GetBalance)
+			r.Get("/history", s.GetHistory)
+			r.Post("/holds", s.PlaceHold)
+
+			// Service-to-service endpoints used by the redemption saga
+			r.Group(func(r chi.Router) {
+				r.Use(authmw.RequireRole(auth.RoleService, authOpts...))
+
+				r.Post("/internal/deduct", s.InternalDeduct)
+				r.Post("/internal/credit", s.InternalCredit)
+				r.Post("/internal/reverse", s.ReverseDeduction)
+				r.Post("/internal/settle", s.SettleDeduction)
+				r.Post("/holds/{id}/capture", s.CaptureHold)
+				r.Post("/holds/{id}/release", s.ReleaseHold)
+				r.Post("/balances", s.GetBalances)
+				// Nightly partner imports earn for many users at once
+				r.Post("/earn/batch", s.EarnPointsBatch)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(authmw.RequireRole(auth.RoleAdmin, authOpts...))
+
+				r.Get("/admin/orphaned-deductions", s.GetOrphanedDeductions)
+				r.Post("/admin/adjust", s.AdminAdjustPoints)
+			})
+		})
 	})
 }
 
 // EarnPoints handles points earning
 func (s *Service) EarnPoints(w http.ResponseWriter, r *http.Request) {
 	var req EarnRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Invalid request body"})
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
 		return
 	}
 
-	// Validate request
-	if req.UserID == "" || req.Amount <= 0 || req.Description == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "User ID, amount, and description are required"})
+	// Get user from context (set by auth middleware)
+	userID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
 		return
 	}
-
-	// Get user from context (set by auth middleware)
-	userID := r.Context().Value("user_id").(string)
 	if userID != req.UserID {
-		render.Status(r, http.StatusForbidden)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Can only earn points for your own account"})
+		platformhttp.Error(w, r, http.StatusForbidden, platformhttp.ErrCodeForbidden, "Can only earn points for your own account")
 		return
 	}
+	idempotencyKey := r.Header.Get("Idempotency-Key")
 
 	// Ensure user exists in loyalty_users (auto-create if needed)
 	_, err := s.getUserByID(r.Context(), userID)
 	if err != nil {
 		s.logger.Errorf("Failed to get/create user: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to get user info"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get user info")
 		return
 	}
 
@@ -151,20 +260,19 @@ func (s *Service) EarnPoints(w http.ResponseWriter, r *http.Request) {
 		Amount:      req.Amount,
 		Description: req.Description,
 		CreatedAt:   now,
+		ExpiresAt:   s.earnExpiry(now),
+		CreatedBy:   authmw.Actor(r.Context()),
 	}
 
-	if err := s.createTransaction(r.Context(), transaction); err != nil {
-		s.logger.Errorf("Failed to create transaction: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to process points earning"})
-		return
-	}
-
-	// Update user points
-	if err := s.updateUserPoints(r.Context(), userID, req.Amount); err != nil {
-		s.logger.Errorf("Failed to update user points: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to update user points"})
+	// Record the transaction and update user points together
+	result, err := s.applyPointsChange(r.Context(), transaction, idempotencyKey)
+	if err != nil {
+		if errors.Is(err, errIdempotencyConflict) {
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "Idempotency-Key already used with a different request")
+			return
+		}
+		s.logger.Errorf("Failed to earn points: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to process points earning")
 		return
 	}
 
@@ -172,18 +280,30 @@ func (s *Service) EarnPoints(w http.ResponseWriter, r *http.Request) {
 	updatedUser, err := s.getUserByID(r.Context(), userID)
 	if err != nil {
 		s.logger.Errorf("Failed to get updated user: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to get updated user info"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get updated user info")
 		return
 	}
 
+	data := map[string]interface{}{
+		"transaction": result.Transaction,
+		"user":        updatedUser,
+	}
+
+	// A replayed request returns the original transaction and the current balance
+	if result.Replayed {
+		data["replayed"] = true
+		render.JSON(w, r, LoyaltyResponse{Success: true, Message: "Points already earned", Data: data})
+		return
+	}
+
+	if result.TierChange != nil {
+		data["tier_change"] = result.TierChange
+	}
+
 	response := LoyaltyResponse{
 		Success: true,
 		Message: "Points earned successfully",
-		Data: map[string]interface{}{
-			"transaction": transaction,
-			"user":        updatedUser,
-		},
+		Data:    data,
 	}
 
 	render.Status(r, http.StatusCreated)
@@ -193,39 +313,27 @@ func (s *Service) EarnPoints(w http.ResponseWriter, r *http.Request) {
 // SpendPoints handles points spending
 func (s *Service) SpendPoints(w http.ResponseWriter, r *http.Request) {
 	var req SpendRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Invalid request body"})
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
 		return
 	}
 
-	// Validate request
-	if req.UserID == "" || req.Amount <= 0 || req.Description == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "User ID, amount, and description are required"})
+	// Get user from context (set by auth middleware)
+	userID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
 		return
 	}
-
-	// Get user from context (set by auth middleware)
-	userID := r.Context().Value("user_id").(string)
 	if userID != req.UserID {
-		render.Status(r, http.StatusForbidden)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Can only spend points from your own account"})
+		platformhttp.Error(w, r, http.StatusForbidden, platformhttp.ErrCodeForbidden, "Can only spend points from your own account")
 		return
 	}
+	idempotencyKey := r.Header.Get("Idempotency-Key")
 
-	// Check if user has enough points
-	user, err := s.getUserByID(r.Context(), userID)
-	if err != nil {
+	// Ensure user exists in loyalty_users (auto-create if needed)
+	if _, err := s.getUserByID(r.Context(), userID); err != nil {
 		s.logger.Errorf("Failed to get user: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to get user info"})
-		return
-	}
-
-	if user.Points < req.Amount {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Insufficient points"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get user info")
 		return
 	}
 
@@ -239,20 +347,23 @@ func (s *Service) SpendPoints(w http.ResponseWriter, r *http.Request) {
 		Amount:      req.Amount,
 		Description: req.Description,
 		CreatedAt:   now,
+		CreatedBy:   authmw.Actor(r.Context()),
 	}
 
-	if err := s.createTransaction(r.Context(), transaction); err != nil {
-		s.logger.Errorf("Failed to create transaction: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to process points spending"})
-		return
-	}
-
-	// Update user points (subtract)
-	if err := s.updateUserPoints(r.Context(), userID, -req.Amount); err != nil {
-		s.logger.Errorf("Failed to update user points: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to update user points"})
+	// Record the transaction and subtract user points together; the balance
+	// is checked under a row lock so concurrent spends cannot overdraw it
+	result, err := s.applyPointsChange(r.Context(), transaction, idempotencyKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, errInsufficientPoints):
+			platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInsufficientPoints, "Insufficient points")
+			return
+		case errors.Is(err, errIdempotencyConflict):
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "Idempotency-Key already used with a different request")
+			return
+		}
+		s.logger.Errorf("Failed to spend points: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to process points spending")
 		return
 	}
 
@@ -260,18 +371,24 @@ func (s *Service) SpendPoints(w http.ResponseWriter, r *http.Request) {
 	updatedUser, err := s.getUserByID(r.Context(), userID)
 	if err != nil {
 		s.logger.Errorf("Failed to get updated user: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to get updated user info"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get updated user info")
 		return
 	}
 
+	message := "Points spent successfully"
+	data := map[string]interface{}{
+		"transaction": result.Transaction,
+		"user":        updatedUser,
+	}
+	if result.Replayed {
+		message = "Points already spent"
+		data["replayed"] = true
+	}
+
 	response := LoyaltyResponse{
 		Success: true,
-		Message: "Points spent successfully",
-		Data: map[string]interface{}{
-			"transaction": transaction,
-			"user":        updatedUser,
-		},
+		Message: message,
+		Data:    data,
 	}
 
 	render.JSON(w, r, response)
@@ -279,13 +396,22 @@ func (s *Service) SpendPoints(w http.ResponseWriter, r *http.Request) {
 
 // GetBalance returns the current user's loyalty balance
 func (s *Service) GetBalance(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
+	userID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
+		return
+	}
 
-	user, err := s.getUserByID(r.Context(), userID)
+	user, err := s.getBalance(r.Context(), userID)
 	if err != nil {
 		s.logger.Errorf("Failed to get user balance: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to get user balance"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get user balance")
+		return
+	}
+
+	// Holds change the balance without changing points, so they are part of the ETag
+	validators := platformhttp.NewValidators(user.UpdatedAt, user.Points, user.HeldPoints)
+	if platformhttp.CheckNotModified(w, r, validators, s.config.Cache.ClientMaxAge) {
 		return
 	}
 
@@ -300,20 +426,35 @@ func (s *Service) GetBalance(w http.ResponseWriter, r *http.Request) {
 
 // GetHistory returns the user's transaction history
 func (s *Service) GetHistory(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
+	userID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
+		return
+	}
 
-	transactions, err := s.getUserTransactions(r.Context(), userID)
+	filter, err := parseHistoryFilter(r)
+	if err != nil {
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	transactions, total, nextCursor, err := s.getUserTransactions(r.Context(), userID, filter)
 	if err != nil {
 		s.logger.Errorf("Failed to get user history: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to get transaction history"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get transaction history")
 		return
 	}
 
 	response := LoyaltyResponse{
 		Success: true,
 		Message: "History retrieved successfully",
-		Data:    transactions,
+		Data: &HistoryResponse{
+			Transactions: transactions,
+			Total:        total,
+			Page:         filter.Page,
+			Limit:        filter.Limit,
+			NextCursor:   nextCursor,
+		},
 	}
 
 	render.JSON(w, r, response)
@@ -324,8 +465,7 @@ func (s *Service) GetRewards(w http.ResponseWriter, r *http.Request) {
 	rewards, err := s.getActiveRewards(r.Context())
 	if err != nil {
 		s.logger.Errorf("Failed to get rewards: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Failed to get rewards"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to get rewards")
 		return
 	}
 
@@ -338,99 +478,169 @@ func (s *Service) GetRewards(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, response)
 }
 
-// AuthMiddleware validates JWT tokens
-func (s *Service) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Authorization header required"})
-			return
-		}
+// Database helper methods. Every query is scoped to the tenant in ctx.
 
-		// Extract token from "Bearer <token>"
-		if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Invalid authorization header format"})
-			return
+// applyPointsChange records an earn or spend transaction and updates the
+// user's points and tier in one database transaction. Spends fail with
+// errInsufficientPoints unless the points not held by active holds cover them.
+// A repeated idempotency key returns the originally recorded transaction, or
+// errIdempotencyConflict if the request differs from the original.
+func (s *Service) applyPointsChange(ctx context.Context, transaction *Transaction, idempotencyKey string) (*pointsChange, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := s.recordPointsChange(ctx, tx, transaction, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	s.pointsChanged(ctx, transaction, result)
+	return result, nil
+}
+
+// pointsChanged does the work that follows a committed points change
+func (s *Service) pointsChanged(ctx context.Context, transaction *Transaction, result *pointsChange) {
+	s.invalidateBalance(ctx, transaction.UserID)
+	if !result.Replayed {
+		recordPointsMetrics(transaction)
+	}
+
+	if result.TierChange != nil {
+		s.logger.Infof("User %s moved from tier %s to %s", transaction.UserID, result.TierChange.From, result.TierChange.To)
+	}
+}
+
+// recordPointsChange is applyPointsChange within the caller's database
+// transaction, which the caller commits
+func (s *Service) recordPointsChange(ctx context.Context, tx pgx.Tx, transaction *Transaction, idempotencyKey string) (*pointsChange, error) {
+	tenantID := auth.TenantFromContext(ctx)
+
+	// Lock the user row so concurrent spends see each other's deductions
+	var points, held, lifetime int
+	var tier string
+	err := tx.QueryRow(ctx, `
+		SELECT u.points, COALESCE((
+			SELECT SUM(h.amount) FROM loyalty_point_holds h
+			WHERE h.user_id = u.id AND h.status = 'held' AND h.expires_at > NOW()
+		), 0), u.lifetime_points, u.tier
+		FROM loyalty_users u WHERE u.id = $1 AND u.tenant_id = $2 FOR UPDATE
+	`, transaction.UserID, tenantID).Scan(&points, &held, &lifetime, &tier)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errUserNotFound
 		}
+		return nil, err
+	}
 
-		token := authHeader[7:]
-		claims, err := s.jwtManager.ValidateToken(token)
+	if idempotencyKey != "" {
+		// The user row lock serializes retries, so the lookup cannot race the insert
+		existing, _, err := s.getTransactionByKey(ctx, tx, transaction.UserID, transaction.Type, idempotencyKey)
 		if err != nil {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, LoyaltyResponse{Success: false, Message: "Invalid token"})
-			return
+			return nil, err
 		}
+		if existing != nil {
+			if existing.Amount != transaction.Amount || existing.Description != transaction.Description {
+				return nil, errIdempotencyConflict
+			}
+			return &pointsChange{Transaction: existing, Replayed: true, Balance: points}, nil
+		}
+	}
 
-		// Add user info to context
-		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
-		ctx = context.WithValue(ctx, "user_email", claims.Email)
-		ctx = context.WithValue(ctx, "user_role", claims.Role)
+	change := transaction.Amount
+	if transaction.Type == "spend" {
+		if points-held < transaction.Amount {
+			return nil, errInsufficientPoints
+		}
+		change = -transaction.Amount
+	} else {
+		lifetime += transaction.Amount
+	}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+	var tierChange *TierChange
+	if newTier := s.recalculateTier(lifetime); newTier != tier {
+		tierChange = &TierChange{From: tier, To: newTier}
+		tier = newTier
 	}
-}
 
-// Database helper methods
-func (s *Service) createTransaction(ctx context.Context, tx *Transaction) error {
-	query := `
-		INSERT INTO loyalty_transactions (id, user_id, type, amount, description, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`
+	_, err = tx.Exec(ctx, `
+		INSERT INTO loyalty_transactions (id, tenant_id, user_id, type, amount, description, idempotency_key, balance_after, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11)
+	`, transaction.ID, tenantID, transaction.UserID, transaction.Type, transaction.Amount, transaction.Description, idempotencyKey, points+change, transaction.CreatedBy, transaction.CreatedAt, transaction.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
 
-	err := s.db.Exec(ctx, query, tx.ID, tx.UserID, tx.Type, tx.Amount, tx.Description, tx.CreatedAt)
-	return err
-}
+	_, err = tx.Exec(ctx, `
+		UPDATE loyalty_users SET points = points + $1, lifetime_points = $2, tier = $3, updated_at = $4
+		WHERE id = $5 AND tenant_id = $6
+	`, change, lifetime, tier, time.Now(), transaction.UserID, tenantID)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *Service) updateUserPoints(ctx context.Context, userID string, pointsChange int) error {
-	query := `
-		UPDATE loyalty_users 
-		SET points = points + $1, updated_at = $2
-		WHERE id = $3
-	`
+	return &pointsChange{Transaction: transaction, TierChange: tierChange, Balance: points + change}, nil
+}
 
-	err := s.db.Exec(ctx, query, pointsChange, time.Now(), userID)
-	return err
+// recordPointsMetrics counts the points moved by a committed transaction
+func recordPointsMetrics(transaction *Transaction) {
+	switch transaction.Type {
+	case "earn":
+		metrics.PointsEarned.Add(float64(transaction.Amount))
+	case "spend":
+		metrics.PointsSpent.Add(float64(transaction.Amount))
+	}
 }
 
 // createLoyaltyUser creates a new loyalty user record
 func (s *Service) createLoyaltyUser(ctx context.Context, userID string, email string) error {
 	query := `
-		INSERT INTO loyalty_users (id, email, points, tier, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO loyalty_users (id, tenant_id, email, points, tier, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
 	now := time.Now()
-	err := s.db.Exec(ctx, query, userID, email, 0, "Bronze", now, now)
+	err := s.db.Exec(ctx, query, userID, auth.TenantFromContext(ctx), email, 0, s.recalculateTier(0), now, now)
 	return err
 }
 
 // getUserByID gets a user from loyalty_users, auto-creating if they don't exist
 func (s *Service) getUserByID(ctx context.Context, userID string) (*User, error) {
-	query := `SELECT id, email, points, tier, created_at, updated_at FROM loyalty_users WHERE id = $1`
+	query := `
+		SELECT u.id, u.email, u.points, COALESCE((
+			SELECT SUM(h.amount) FROM loyalty_point_holds h
+			WHERE h.user_id = u.id AND h.status = 'held' AND h.expires_at > NOW()
+		), 0), u.tier, u.created_at, u.updated_at
+		FROM loyalty_users u WHERE u.id = $1 AND u.tenant_id = $2
+	`
+	tenantID := auth.TenantFromContext(ctx)
 
 	var user User
-	err := s.db.QueryRow(ctx, query, userID).Scan(
-		&user.ID, &user.Email, &user.Points, &user.Tier, &user.CreatedAt, &user.UpdatedAt,
+	err := s.db.QueryRow(ctx, query, userID, tenantID).Scan(
+		&user.ID, &user.Email, &user.Points, &user.HeldPoints, &user.Tier, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
 		// User doesn't exist in loyalty_users, try to get their email from auth context
-		userEmail, ok := ctx.Value("user_email").(string)
+		userEmail, ok := authmw.Email(ctx)
 		if !ok {
 			return nil, err
 		}
 
-		// Auto-create the loyalty user
-		if err := s.createLoyaltyUser(ctx, userID, userEmail); err != nil {
+		// Auto-create the loyalty user; a unique violation means a concurrent request already did
+		if err := s.createLoyaltyUser(ctx, userID, userEmail); err != nil && !database.IsUniqueViolation(err) {
 			s.logger.Errorf("Failed to auto-create loyalty user: %v", err)
 			return nil, err
 		}
 
 		// Now get the newly created user
-		err = s.db.QueryRow(ctx, query, userID).Scan(
-			&user.ID, &user.Email, &user.Points, &user.Tier, &user.CreatedAt, &user.UpdatedAt,
+		err = s.db.QueryRow(ctx, query, userID, tenantID).Scan(
+			&user.ID, &user.Email, &user.Points, &user.HeldPoints, &user.Tier, &user.CreatedAt, &user.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -439,29 +649,77 @@ func (s *Service) getUserByID(ctx context.Context, userID string) (*User, error)
 		s.logger.Infof("Auto-created loyalty user: %s (%s)", userID, userEmail)
 	}
 
+	user.AvailablePoints = user.Points - user.HeldPoints
 	return &user, nil
 }
 
-func (s *Service) getUserTransactions(ctx context.Context, userID string) ([]*Transaction, error) {
-	query := `SELECT id, user_id, type, amount, description, created_at FROM loyalty_transactions WHERE user_id = $1 ORDER BY created_at DESC`
+// getUserTransactions returns a page of a user's transactions, the total
+// matching the filter, and in cursor mode the cursor of the next page
+func (s *Service) getUserTransactions(ctx context.Context, userID string, filter *historyFilter) ([]*Transaction, int, string, error) {
+	where := `WHERE user_id = $1 AND tenant_id = $2`
+	args := []interface{}{userID, auth.TenantFromContext(ctx)}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		where += fmt.Sprintf(" AND type = $%d", len(args))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		where += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		where += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM loyalty_transactions `+where, args...).Scan(&total); err != nil {
+		return nil, 0, "", err
+	}
 
-	rows, err := s.db.Query(ctx, query, userID)
+	// Cursor mode reads one extra row to learn whether another page follows
+	var paging string
+	if filter.CursorMode {
+		if c := filter.Cursor; c != nil {
+			args = append(args, c.CreatedAt, c.ID)
+			where += fmt.Sprintf(" AND (created_at < $%d OR (created_at = $%d AND id > $%d))", len(args)-1, len(args)-1, len(args))
+		}
+		args = append(args, filter.Limit+1)
+		paging = fmt.Sprintf("LIMIT $%d", len(args))
+	} else {
+		args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+		paging = fmt.Sprintf("LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+	query := fmt.Sprintf(`
+		SELECT id, user_id, type, amount, description, created_at, expires_at FROM loyalty_transactions %s
+		ORDER BY created_at DESC, id %s
+	`, where, paging)
+
+	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, "", err
 	}
 	defer rows.Close()
 
-	var transactions []*Transaction
+	transactions := []*Transaction{}
 	for rows.Next() {
 		var tx Transaction
-		err := rows.Scan(&tx.ID, &tx.UserID, &tx.Type, &tx.Amount, &tx.Description, &tx.CreatedAt)
+		err := rows.Scan(&tx.ID, &tx.UserID, &tx.Type, &tx.Amount, &tx.Description, &tx.CreatedAt, &tx.ExpiresAt)
 		if err != nil {
-			return nil, err
+			return nil, 0, "", err
 		}
 		transactions = append(transactions, &tx)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, "", err
+	}
 
-	return transactions, nil
+	var nextCursor string
+	if filter.CursorMode && len(transactions) > filter.Limit {
+		transactions = transactions[:filter.Limit]
+		last := transactions[len(transactions)-1]
+		nextCursor = platformhttp.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return transactions, total, nextCursor, nil
 }
 
 func (s *Service) getActiveRewards(ctx context.Context) ([]*Reward, error) {
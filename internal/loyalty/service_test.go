@@ -0,0 +1,449 @@
+package loyalty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/migrate"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestService creates a loyalty service without Redis or a database;
+// configure adjusts the loaded configuration first
+func newTestService(t *testing.T, configure ...func(*config.Config)) *Service {
+	t.Helper()
+
+	cfg, err := config.Load("loyalty-svc")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Redis.Addr = ""
+	cfg.Security.JWT.Revocation = false
+	cfg.Security.RateLimit.Enabled = false
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewService(cfg, logger)
+}
+
+// withTestDB migrates the test database for s and returns a context scoped
+// to a tenant of its own. The test is skipped without a database.
+func withTestDB(t *testing.T, s *Service) context.Context {
+	t.Helper()
+
+	db := databasetest.Open(t)
+	if err := migrate.Run(context.Background(), db, "loyalty", Migrations, migrate.ModeApply, s.logger); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	s.SetDatabase(db)
+	return auth.WithTenant(context.Background(), databasetest.Tenant(t))
+}
+
+// createTestUser inserts a loyalty user with the given points in ctx's tenant
+func createTestUser(t *testing.T, ctx context.Context, s *Service, points int) string {
+	t.Helper()
+
+	userID := uuid.New().String()
+	err := s.db.Exec(ctx, `
+		INSERT INTO loyalty_users (id, tenant_id, email, points, lifetime_points)
+		VALUES ($1, $2, $3, $4, $4)
+	`, userID, auth.TenantFromContext(ctx), userID+"@example.com", points)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return userID
+}
+
+// userPoints reads a user's points balance
+func userPoints(t *testing.T, ctx context.Context, s *Service, userID string) int {
+	t.Helper()
+
+	var points int
+	if err := s.db.QueryRow(ctx, `SELECT points FROM loyalty_users WHERE id = $1`, userID).Scan(&points); err != nil {
+		t.Fatalf("failed to read points: %v", err)
+	}
+	return points
+}
+
+// token issues a token for userID with role
+func token(t *testing.T, s *Service, userID, role string) string {
+	t.Helper()
+
+	tok, err := s.jwtManager.GenerateToken(userID, userID+"@example.com", role)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	return tok
+}
+
+// tenantToken issues a user token bound to tenantID
+func tenantToken(t *testing.T, s *Service, userID, tenantID string) string {
+	t.Helper()
+
+	tok, err := s.jwtManager.GenerateTenantToken(userID, userID+"@example.com", "user", tenantID)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	return tok
+}
+
+// withTenancy enables multi-tenancy
+func withTenancy(cfg *config.Config) {
+	cfg.Security.Tenancy.Enabled = true
+}
+
+// serve sends a request with a JSON body, if any, through s's routes.
+// headers are name, value pairs.
+func serve(s *Service, method, path, tok string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRoutesRequireAuthentication(t *testing.T) {
+	s := newTestService(t)
+
+	rec := serve(s, http.MethodGet, "/v1/loyalty/balance", "", nil)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestHandlersWithoutMiddlewareReturnUnauthorized(t *testing.T) {
+	s := newTestService(t)
+
+	// Mounted without the auth middleware, the handlers have no caller to
+	// read and must reject the request rather than panic
+	handlers := map[string]http.HandlerFunc{
+		"GetBalance": s.GetBalance,
+		"GetHistory": s.GetHistory,
+	}
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			var body platformhttp.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.Code != platformhttp.ErrCodeUnauthorized {
+				t.Fatalf("code = %q, want %q", body.Code, platformhttp.ErrCodeUnauthorized)
+			}
+		})
+	}
+}
+
+func TestConcurrentSpendsOfFullBalance(t *testing.T) {
+	s := newTestService(t)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 500)
+
+	// Without the user row lock every spend would see the full balance
+	const spends = 8
+	start := make(chan struct{})
+	errs := make(chan error, spends)
+	var wg sync.WaitGroup
+	for i := 0; i < spends; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := s.applyPointsChange(ctx, &Transaction{
+				ID:          uuid.New().String(),
+				UserID:      userID,
+				Type:        "spend",
+				Amount:      500,
+				Description: "concurrent spend",
+				CreatedAt:   time.Now(),
+			}, "")
+			errs <- err
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch err {
+		case nil:
+			succeeded++
+		case errInsufficientPoints:
+		default:
+			t.Errorf("spend: err = %v, want nil or %v", err, errInsufficientPoints)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d spends succeeded, want 1", succeeded)
+	}
+	if got := userPoints(t, ctx, s, userID); got != 0 {
+		t.Fatalf("points = %d, want 0", got)
+	}
+
+	// The ledger agrees with the balance
+	var recorded int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM loyalty_transactions WHERE user_id = $1 AND type = 'spend'`, userID).Scan(&recorded); err != nil {
+		t.Fatalf("failed to count spends: %v", err)
+	}
+	if recorded != 1 {
+		t.Fatalf("ledger has %d spends, want 1", recorded)
+	}
+}
+
+func TestReadsAreScopedToTenant(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	tenantA, tenantB := auth.TenantFromContext(ctx), databasetest.Tenant(t)
+	userID := createTestUser(t, ctx, s, 500)
+	earn := &Transaction{ID: uuid.New().String(), UserID: userID, Type: "earn", Amount: 100, Description: "test", CreatedAt: time.Now()}
+	if _, err := s.applyPointsChange(ctx, earn, ""); err != nil {
+		t.Fatalf("earn: %v", err)
+	}
+
+	history := func(tenantID string) int {
+		rec := serve(s, http.MethodGet, "/v1/loyalty/history", tenantToken(t, s, userID, tenantID), nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("history in %s: status = %d: %s", tenantID, rec.Code, rec.Body)
+		}
+		var response struct {
+			Data HistoryResponse `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("decode history: %v", err)
+		}
+		return len(response.Data.Transactions)
+	}
+	if got := history(tenantA); got != 1 {
+		t.Fatalf("own tenant sees %d transactions, want 1", got)
+	}
+	if got := history(tenantB); got != 0 {
+		t.Fatalf("other tenant sees %d transactions, want none", got)
+	}
+
+	serviceToken, err := s.jwtManager.GenerateServiceToken("redemption-svc")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	balances := func(tenantID string) int {
+		rec := serve(s, http.MethodPost, "/v1/loyalty/balances", serviceToken, BalancesRequest{UserIDs: []string{userID}}, auth.TenantHeader, tenantID)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("balances in %s: status = %d: %s", tenantID, rec.Code, rec.Body)
+		}
+		var response struct {
+			Data []*User `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("decode balances: %v", err)
+		}
+		return len(response.Data)
+	}
+	if got := balances(tenantA); got != 1 {
+		t.Fatalf("own tenant sees %d balances, want 1", got)
+	}
+	if got := balances(tenantB); got != 0 {
+		t.Fatalf("other tenant sees %d balances, want none", got)
+	}
+
+	spend := &Transaction{ID: uuid.New().String(), UserID: userID, Type: "spend", Amount: 100, Description: "test", CreatedAt: time.Now()}
+	if _, err := s.applyPointsChange(auth.WithTenant(ctx, tenantB), spend, ""); err != errUserNotFound {
+		t.Fatalf("spend in other tenant: err = %v, want %v", err, errUserNotFound)
+	}
+	if got := userPoints(t, ctx, s, userID); got != 600 {
+		t.Fatalf("points = %d, want 600", got)
+	}
+}
+
+func TestBalanceConditionalRequests(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	tenantID := auth.TenantFromContext(ctx)
+	userID := createTestUser(t, ctx, s, 100)
+	tok := tenantToken(t, s, userID, tenantID)
+
+	first := serve(s, http.MethodGet, "/v1/loyalty/balance", tok, nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("balance: status = %d: %s", first.Code, first.Body)
+	}
+	etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("ETag = %q, Last-Modified = %q; want both set", etag, lastModified)
+	}
+	if got := first.Header().Get("Cache-Control"); !strings.HasPrefix(got, "private") {
+		t.Errorf("Cache-Control = %q, want private", got)
+	}
+
+	// An unchanged balance is not sent again
+	for _, header := range []string{"If-None-Match", "If-Modified-Since"} {
+		value := etag
+		if header == "If-Modified-Since" {
+			value = lastModified
+		}
+		rec := serve(s, http.MethodGet, "/v1/loyalty/balance", tok, nil, header, value)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Fatalf("%s: status = %d with %d bytes, want an empty %d", header, rec.Code, rec.Body.Len(), http.StatusNotModified)
+		}
+	}
+
+	// Earning points changes the ETag, so the old one gets a fresh balance
+	serviceToken, err := s.jwtManager.GenerateServiceToken("redemption-svc")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	credit := AdjustmentRequest{UserID: userID, Amount: 50, Reason: "refund", IdempotencyKey: uuid.New().String()}
+	if rec := serve(s, http.MethodPost, "/v1/loyalty/internal/credit", serviceToken, credit, auth.TenantHeader, tenantID); rec.Code != http.StatusOK {
+		t.Fatalf("credit: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(s, http.MethodGet, "/v1/loyalty/balance", tok, nil, "If-None-Match", etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("after a points change: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Error("ETag unchanged after a points change")
+	}
+	var body struct {
+		Data User `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Data.Points != 150 {
+		t.Fatalf("points = %d, want 150", body.Data.Points)
+	}
+}
+
+// pointsResult is the data of an earn or spend response
+type pointsResult struct {
+	Transaction Transaction `json:"transaction"`
+	User        User        `json:"user"`
+	Replayed    bool        `json:"replayed"`
+}
+
+// changePoints posts an earn or spend of amount for userID with an
+// Idempotency-Key, returning the status and, on success, the result
+func changePoints(t *testing.T, ctx context.Context, s *Service, action, userID string, amount int, description, key string) (int, *pointsResult) {
+	t.Helper()
+
+	rec := serve(s, http.MethodPost, "/v1/loyalty/"+action, tenantToken(t, s, userID, auth.TenantFromContext(ctx)),
+		EarnRequest{UserID: userID, Amount: amount, Description: description}, "Idempotency-Key", key)
+	if rec.Code >= 300 {
+		return rec.Code, nil
+	}
+	var response struct {
+		Data pointsResult `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("decode %s: %v", action, err)
+	}
+	return rec.Code, &response.Data
+}
+
+// transactionCount counts a user's transactions of txType
+func transactionCount(t *testing.T, ctx context.Context, s *Service, userID, txType string) int {
+	t.Helper()
+
+	var n int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM loyalty_transactions WHERE user_id = $1 AND type = $2`, userID, txType).Scan(&n); err != nil {
+		t.Fatalf("failed to count transactions: %v", err)
+	}
+	return n
+}
+
+func TestEarnAndSpendReplayIdempotencyKey(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+
+	for _, tt := range []struct {
+		action     string
+		firstCode  int
+		wantPoints int
+	}{
+		{"earn", http.StatusCreated, 600},
+		{"spend", http.StatusOK, 400},
+	} {
+		t.Run(tt.action, func(t *testing.T) {
+			userID := createTestUser(t, ctx, s, 500)
+			key := uuid.New().String()
+
+			status, first := changePoints(t, ctx, s, tt.action, userID, 100, "Purchase", key)
+			if status != tt.firstCode || first.Replayed {
+				t.Fatalf("first %s: status = %d, replayed = %v; want %d", tt.action, status, first != nil && first.Replayed, tt.firstCode)
+			}
+
+			// A retry returns the original transaction without applying it again
+			status, retry := changePoints(t, ctx, s, tt.action, userID, 100, "Purchase", key)
+			if status != http.StatusOK || !retry.Replayed || retry.Transaction.ID != first.Transaction.ID {
+				t.Fatalf("retried %s: status = %d, result = %+v; want the original transaction replayed", tt.action, status, retry)
+			}
+			if retry.User.Points != tt.wantPoints || userPoints(t, ctx, s, userID) != tt.wantPoints {
+				t.Fatalf("points = %d, want %d after one %s", userPoints(t, ctx, s, userID), tt.wantPoints, tt.action)
+			}
+			if n := transactionCount(t, ctx, s, userID, tt.action); n != 1 {
+				t.Fatalf("%d %s transactions, want 1", n, tt.action)
+			}
+
+			// Reusing the key for a different request is a conflict
+			if status, _ := changePoints(t, ctx, s, tt.action, userID, 200, "Purchase", key); status != http.StatusConflict {
+				t.Fatalf("%s with a reused key: status = %d, want %d", tt.action, status, http.StatusConflict)
+			}
+			if status, _ := changePoints(t, ctx, s, tt.action, userID, 100, "Other purchase", key); status != http.StatusConflict {
+				t.Fatalf("%s with a reused key and description: status = %d, want %d", tt.action, status, http.StatusConflict)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeysAreScopedToUser(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	key := uuid.New().String()
+
+	for _, userID := range []string{createTestUser(t, ctx, s, 0), createTestUser(t, ctx, s, 0)} {
+		status, result := changePoints(t, ctx, s, "earn", userID, 100, "Purchase", key)
+		if status != http.StatusCreated || result.Replayed {
+			t.Fatalf("earn for %s: status = %d, want %d without a replay", userID, status, http.StatusCreated)
+		}
+		if got := userPoints(t, ctx, s, userID); got != 100 {
+			t.Fatalf("points for %s = %d, want 100", userID, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package loyalty
+
+import (
+	"sort"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// defaultTier is used when no tiers are configured
+const defaultTier = "Bronze"
+
+// TierChange reports that a points change moved a user to another tier
+type TierChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// sortTiers returns the configured tiers ordered by threshold, lowest first
+func sortTiers(tiers []config.TierConfig) []config.TierConfig {
+	sorted := append([]config.TierConfig(nil), tiers...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].MinPoints < sorted[j].MinPoints })
+	return sorted
+}
+
+// recalculateTier returns the highest tier whose threshold the lifetime
+// earned points meet. Reaching a threshold exactly qualifies for the tier.
+func (s *Service) recalculateTier(lifetimePoints int) string {
+	tier := defaultTier
+	if len(s.tiers) > 0 {
+		tier = s.tiers[0].Name
+	}
+	for _, t := range s.tiers {
+		if lifetimePoints < t.MinPoints {
+			break
+		}
+		tier = t.Name
+	}
+	return tier
+}
@@ -0,0 +1,107 @@
+package loyalty
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+func TestRecalculateTierBoundaries(t *testing.T) {
+	s := newTestService(t, func(cfg *config.Config) {
+		// Configured out of order to check the thresholds are sorted
+		cfg.Loyalty.Tiers = []config.TierConfig{
+			{Name: "Gold", MinPoints: 20000},
+			{Name: "Bronze", MinPoints: 0},
+			{Name: "Platinum", MinPoints: 50000},
+			{Name: "Silver", MinPoints: 5000},
+		}
+	})
+
+	tests := []struct {
+		lifetime int
+		want     string
+	}{
+		{0, "Bronze"},
+		{4999, "Bronze"},
+		{5000, "Silver"},
+		{19999, "Silver"},
+		{20000, "Gold"},
+		{49999, "Gold"},
+		{50000, "Platinum"},
+		{1000000, "Platinum"},
+	}
+	for _, tt := range tests {
+		if got := s.recalculateTier(tt.lifetime); got != tt.want {
+			t.Errorf("recalculateTier(%d) = %s, want %s", tt.lifetime, got, tt.want)
+		}
+	}
+}
+
+func TestRecalculateTierWithoutEntryTier(t *testing.T) {
+	// Users below the lowest threshold still get the lowest tier
+	s := newTestService(t, func(cfg *config.Config) {
+		cfg.Loyalty.Tiers = []config.TierConfig{{Name: "Member", MinPoints: 100}, {Name: "VIP", MinPoints: 1000}}
+	})
+	if got := s.recalculateTier(0); got != "Member" {
+		t.Errorf("recalculateTier(0) = %s, want Member", got)
+	}
+
+	s = newTestService(t, func(cfg *config.Config) { cfg.Loyalty.Tiers = nil })
+	if got := s.recalculateTier(50000); got != defaultTier {
+		t.Errorf("recalculateTier without tiers = %s, want %s", got, defaultTier)
+	}
+}
+
+func TestEarnReportsTierChange(t *testing.T) {
+	s := newTestService(t, withTenancy)
+	ctx := withTestDB(t, s)
+	userID := createTestUser(t, ctx, s, 4999)
+	tenantID := auth.TenantFromContext(ctx)
+
+	earn := func(amount int) map[string]json.RawMessage {
+		t.Helper()
+
+		req := EarnRequest{UserID: userID, Amount: amount, Description: "purchase"}
+		rec := serve(s, http.MethodPost, "/v1/loyalty/earn", tenantToken(t, s, userID, tenantID), req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("earn: status = %d: %s", rec.Code, rec.Body)
+		}
+		var body struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return body.Data
+	}
+
+	// Reaching the threshold exactly crosses the boundary
+	data := earn(1)
+	var change TierChange
+	if err := json.Unmarshal(data["tier_change"], &change); err != nil {
+		t.Fatalf("decode tier change %s: %v", data["tier_change"], err)
+	}
+	if change != (TierChange{From: "Bronze", To: "Silver"}) {
+		t.Fatalf("tier change = %+v, want Bronze to Silver", change)
+	}
+
+	// Earning within a tier reports no change
+	if data := earn(1); data["tier_change"] != nil {
+		t.Fatalf("tier change = %s within a tier", data["tier_change"])
+	}
+
+	// Spending never lowers the tier, which follows lifetime points
+	spend := &Transaction{ID: uuid.New().String(), UserID: userID, Type: "spend", Amount: 5001, Description: "redeem", CreatedAt: time.Now()}
+	result, err := s.applyPointsChange(ctx, spend, "")
+	if err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+	if result.TierChange != nil {
+		t.Fatalf("spend changed tier: %+v", result.TierChange)
+	}
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SMS encodings
+const (
+	smsEncodingGSM7 = "gsm7"
+	smsEncodingUCS2 = "ucs2"
+)
+
+// SMS overflow handling modes
+const (
+	smsOverflowReject   = "reject"
+	smsOverflowTruncate = "truncate"
+)
+
+// gsm7Basic is the GSM 03.38 basic character set; each character is one septet
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extension characters need an escape septet, so each counts twice
+const gsm7Extension = "^{}\\[~]|€\f"
+
+// ContentError reports notification content that breaks a channel limit
+type ContentError struct {
+	Field   string
+	Message string
+}
+
+func (e *ContentError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// prepareContent validates a notification's content against its channel's
+// limits, truncating over-length SMS when configured to, and records the SMS
+// segment count
+func (s *Service) prepareContent(notification *Notification) error {
+	limits := s.config.Notify.Content
+
+	switch notification.Channel {
+	case "email":
+		if limits.SubjectMaxLength > 0 && utf8.RuneCountInString(notification.Subject) > limits.SubjectMaxLength {
+			return &ContentError{Field: "subject", Message: fmt.Sprintf("must be at most %d characters", limits.SubjectMaxLength)}
+		}
+	case "sms":
+		segments, _ := smsSegments(notification.Message)
+		if limits.SMSMaxSegments > 0 && segments > limits.SMSMaxSegments {
+			if limits.SMSOverflow != smsOverflowTruncate {
+				return &ContentError{Field: "message", Message: fmt.Sprintf("needs %d SMS segments, at most %d are allowed", segments, limits.SMSMaxSegments)}
+			}
+			notification.Message = truncateSMS(notification.Message, limits.SMSMaxSegments)
+			notification.Truncated = true
+			segments, _ = smsSegments(notification.Message)
+		}
+		notification.Segments = segments
+	}
+
+	return nil
+}
+
+// smsSegments returns how many segments an SMS is sent as, and its encoding.
+// Messages that fit the GSM 7-bit alphabet get 160 septets in a single segment
+// or 153 per segment when split; anything else is sent as UCS-2 with 70 code
+// units, or 67 per segment when split.
+func smsSegments(message string) (int, string) {
+	encoding := smsEncoding(message)
+
+	units := 0
+	for _, r := range message {
+		units += smsUnits(r, encoding)
+	}
+
+	single, multi := smsSegmentSizes(encoding)
+	if units <= single {
+		return 1, encoding
+	}
+	return (units + multi - 1) / multi, encoding
+}
+
+// truncateSMS cuts a message down to fit in maxSegments, never splitting a character
+func truncateSMS(message string, maxSegments int) string {
+	encoding := smsEncoding(message)
+
+	single, multi := smsSegmentSizes(encoding)
+	limit := single
+	if maxSegments > 1 {
+		limit = multi * maxSegments
+	}
+
+	units := 0
+	for i, r := range message {
+		units += smsUnits(r, encoding)
+		if units > limit {
+			return message[:i]
+		}
+	}
+	return message
+}
+
+func smsEncoding(message string) string {
+	for _, r := range message {
+		if !strings.ContainsRune(gsm7Basic, r) && !strings.ContainsRune(gsm7Extension, r) {
+			return smsEncodingUCS2
+		}
+	}
+	return smsEncodingGSM7
+}
+
+// smsUnits returns how many septets (GSM-7) or UTF-16 code units (UCS-2) a character takes
+func smsUnits(r rune, encoding string) int {
+	if encoding == smsEncodingGSM7 {
+		if strings.ContainsRune(gsm7Extension, r) {
+			return 2
+		}
+		return 1
+	}
+	if r > 0xFFFF {
+		return 2
+	}
+	return 1
+}
+
+// smsSegmentSizes returns the capacity of a single-segment SMS and of each
+// segment of a multipart SMS, which loses room to the concatenation header
+func smsSegmentSizes(encoding string) (single, multi int) {
+	if encoding == smsEncodingGSM7 {
+		return 160, 153
+	}
+	return 70, 67
+}
@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+func TestSMSSegments(t *testing.T) {
+	tests := []struct {
+		name         string
+		message      string
+		wantSegments int
+		wantEncoding string
+	}{
+		{"empty", "", 1, smsEncodingGSM7},
+		{"single gsm7", strings.Repeat("a", 160), 1, smsEncodingGSM7},
+		{"split gsm7", strings.Repeat("a", 161), 2, smsEncodingGSM7},
+		{"two full gsm7 parts", strings.Repeat("a", 306), 2, smsEncodingGSM7},
+		{"gsm7 extension counts twice", strings.Repeat("€", 80), 1, smsEncodingGSM7},
+		{"gsm7 extension over one segment", strings.Repeat("€", 81), 2, smsEncodingGSM7},
+		{"gsm7 accents", strings.Repeat("é", 160), 1, smsEncodingGSM7},
+		{"single ucs2", strings.Repeat("ç", 70), 1, smsEncodingUCS2},
+		{"split ucs2", strings.Repeat("ç", 71), 2, smsEncodingUCS2},
+		{"one character forces ucs2", strings.Repeat("a", 69) + "漢", 1, smsEncodingUCS2},
+		{"ucs2 over one segment", strings.Repeat("a", 70) + "漢", 2, smsEncodingUCS2},
+		{"emoji takes two units", strings.Repeat("🎁", 35), 1, smsEncodingUCS2},
+		{"emoji over one segment", strings.Repeat("🎁", 36), 2, smsEncodingUCS2},
+		{"three ucs2 parts", strings.Repeat("漢", 67*3), 3, smsEncodingUCS2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, encoding := smsSegments(tt.message)
+			if segments != tt.wantSegments || encoding != tt.wantEncoding {
+				t.Fatalf("smsSegments = %d, %s; want %d, %s", segments, encoding, tt.wantSegments, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestTruncateSMS(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     string
+		maxSegments int
+		wantRunes   int
+	}{
+		{"fits", "Your reward is ready", 1, 20},
+		{"gsm7 single", strings.Repeat("a", 200), 1, 160},
+		{"gsm7 multipart", strings.Repeat("a", 500), 2, 306},
+		{"extension character not split", strings.Repeat("a", 159) + "€", 1, 159},
+		{"ucs2 single", strings.Repeat("漢", 100), 1, 70},
+		{"ucs2 multipart", strings.Repeat("漢", 300), 3, 201},
+		{"surrogate pair not split", strings.Repeat("a", 69) + "🎁", 1, 69},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateSMS(tt.message, tt.maxSegments)
+			if !utf8.ValidString(got) || !strings.HasPrefix(tt.message, got) {
+				t.Fatalf("truncateSMS = %q, want a whole-character prefix", got)
+			}
+			if n := utf8.RuneCountInString(got); n != tt.wantRunes {
+				t.Fatalf("truncated to %d characters, want %d", n, tt.wantRunes)
+			}
+			if segments, _ := smsSegments(got); segments > tt.maxSegments {
+				t.Fatalf("truncated message needs %d segments, want at most %d", segments, tt.maxSegments)
+			}
+		})
+	}
+}
+
+// withContentLimits sets the notification content limits
+func withContentLimits(limits config.ContentLimitConfig) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Notify.Content = limits
+	}
+}
+
+func TestPrepareContent(t *testing.T) {
+	long := strings.Repeat("漢", 150) // three UCS-2 segments
+
+	tests := []struct {
+		name          string
+		limits        config.ContentLimitConfig
+		notification  Notification
+		wantField     string
+		wantSegments  int
+		wantTruncated bool
+	}{
+		{"sms within limit", config.ContentLimitConfig{SMSMaxSegments: 3, SMSOverflow: smsOverflowReject}, Notification{Channel: "sms", Message: long}, "", 3, false},
+		{"sms rejected", config.ContentLimitConfig{SMSMaxSegments: 2, SMSOverflow: smsOverflowReject}, Notification{Channel: "sms", Message: long}, "message", 0, false},
+		{"sms truncated", config.ContentLimitConfig{SMSMaxSegments: 2, SMSOverflow: smsOverflowTruncate}, Notification{Channel: "sms", Message: long}, "", 2, true},
+		{"sms unlimited", config.ContentLimitConfig{SMSOverflow: smsOverflowReject}, Notification{Channel: "sms", Message: long}, "", 3, false},
+		{"subject within limit", config.ContentLimitConfig{SubjectMaxLength: 5}, Notification{Channel: "email", Subject: "Hello"}, "", 0, false},
+		{"subject counted in characters", config.ContentLimitConfig{SubjectMaxLength: 5}, Notification{Channel: "email", Subject: "Ünïcö"}, "", 0, false},
+		{"subject too long", config.ContentLimitConfig{SubjectMaxLength: 5}, Notification{Channel: "email", Subject: "Hello!"}, "subject", 0, false},
+		{"push unchecked", config.ContentLimitConfig{SubjectMaxLength: 5, SMSMaxSegments: 1}, Notification{Channel: "push", Subject: "Hello!", Message: long}, "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t, withContentLimits(tt.limits))
+			notification := tt.notification
+
+			err := s.prepareContent(&notification)
+			if tt.wantField != "" {
+				var contentErr *ContentError
+				if !errors.As(err, &contentErr) || contentErr.Field != tt.wantField {
+					t.Fatalf("prepareContent = %v, want a %s content error", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("prepareContent: %v", err)
+			}
+			if notification.Segments != tt.wantSegments || notification.Truncated != tt.wantTruncated {
+				t.Fatalf("segments = %d, truncated = %v; want %d, %v", notification.Segments, notification.Truncated, tt.wantSegments, tt.wantTruncated)
+			}
+			if tt.wantTruncated {
+				if segments, _ := smsSegments(notification.Message); segments != tt.wantSegments {
+					t.Fatalf("truncated message needs %d segments", segments)
+				}
+			}
+		})
+	}
+}
+
+// postNotification sends a notification request through s's routes as a user
+func postNotification(t *testing.T, s *Service, req NotificationRequest) *httptest.ResponseRecorder {
+	t.Helper()
+
+	tok, err := s.jwtManager.GenerateToken(req.UserID, "member@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/v1/notifications", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+tok)
+
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httpReq)
+	return rec
+}
+
+func TestSendOverLengthSMS(t *testing.T) {
+	message := strings.Repeat("Your reward is ready! ", 30) // 660 GSM-7 characters, five segments
+	req := NotificationRequest{UserID: uuid.New().String(), Type: "sms", Channel: "sms", Message: message}
+
+	t.Run("reject", func(t *testing.T) {
+		s, _ := newTestService(t, withContentLimits(config.ContentLimitConfig{SMSMaxSegments: 3, SMSOverflow: smsOverflowReject}))
+
+		rec := postNotification(t, s, req)
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+		}
+		var body platformhttp.ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if body.Code != platformhttp.ErrCodeValidationFailed || !strings.Contains(body.Fields["message"], "5 SMS segments") {
+			t.Fatalf("error = %+v, want the message field to report 5 segments", body)
+		}
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		s, sender := newTestService(t, withContentLimits(config.ContentLimitConfig{SMSMaxSegments: 3, SMSOverflow: smsOverflowTruncate}))
+		startTestService(t, s)
+
+		rec := postNotification(t, s, req)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+		}
+		var resp NotificationResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Segments != 3 || !resp.Truncated {
+			t.Fatalf("response = %+v, want 3 segments, truncated", resp)
+		}
+
+		// The provider is given the truncated message
+		sent := sender.next(t)
+		if sent.Message != message[:3*153] {
+			t.Fatalf("sent %d characters, want the first %d", len(sent.Message), 3*153)
+		}
+	})
+}
@@ -0,0 +1,266 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/lifecycle"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// errSendTimeout marks a notification whose provider did not answer in time
+var errSendTimeout = errors.New("timeout")
+
+var (
+	queueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "notification_queue_depth",
+		Help: "Notifications waiting to be sent, by channel.",
+	}, []string{"channel"})
+
+	inFlightSends = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "notification_sends_in_flight",
+		Help: "Notifications currently being sent, by channel.",
+	}, []string{"channel"})
+)
+
+// dispatcher queues notifications per channel and sends them within each
+// channel's rate and concurrency limits
+type dispatcher struct {
+	// ctx is cancelled on shutdown, stopping the workers and any sends in
+	// flight; group tracks them so shutdown can wait for them
+	ctx      context.Context
+	group    *lifecycle.Group
+	sender   Sender
+	logger   *logrus.Logger
+	onResult func(*Notification, error)
+
+	mu       sync.Mutex
+	channels map[string]*channelQueue
+	limits   func(channel string) config.ChannelLimitConfig
+}
+
+// channelQueue is the queue and limiter state for a single channel
+type channelQueue struct {
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+	slots    chan struct{}
+	wake     chan struct{}
+
+	mu          sync.Mutex
+	queue       []*Notification
+	pausedUntil time.Time
+}
+
+func newDispatcher(group *lifecycle.Group, cfg config.NotifyConfig, sender Sender, logger *logrus.Logger, onResult func(*Notification, error)) *dispatcher {
+	return &dispatcher{
+		ctx:      group.Context(),
+		group:    group,
+		sender:   sender,
+		logger:   logger,
+		onResult: onResult,
+		channels: make(map[string]*channelQueue),
+		limits:   cfg.ChannelLimits,
+	}
+}
+
+// enqueue queues a notification for sending; overflow is queued, never dropped
+func (d *dispatcher) enqueue(notification *Notification) {
+	q := d.channel(notification.Channel)
+
+	q.mu.Lock()
+	q.queue = append(q.queue, notification)
+	queueDepth.WithLabelValues(q.name).Set(float64(len(q.queue)))
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// channel returns the queue for a channel, starting its worker on first use
+func (d *dispatcher) channel(name string) *channelQueue {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if q, ok := d.channels[name]; ok {
+		return q
+	}
+
+	limits := d.limits(name)
+	q := &channelQueue{
+		name:    name,
+		timeout: limits.SendTimeout,
+		slots:   make(chan struct{}, limits.MaxInFlight),
+		wake:    make(chan struct{}, 1),
+	}
+	if limits.MaxPerSecond > 0 {
+		q.interval = time.Duration(float64(time.Second) / limits.MaxPerSecond)
+	}
+	d.channels[name] = q
+
+	d.group.Go(func(context.Context) { d.run(q) })
+	return q
+}
+
+// run sends queued notifications, spacing sends by the channel's rate,
+// capping in-flight sends, and pausing while the provider asks us to back off.
+// It returns once the dispatcher's context is cancelled.
+func (d *dispatcher) run(q *channelQueue) {
+	var lastSend time.Time
+
+	for {
+		if q.empty() {
+			select {
+			case <-q.wake:
+				continue
+			case <-d.ctx.Done():
+				return
+			}
+		}
+
+		if !d.sleep(q.pauseRemaining()) || !d.sleep(q.interval-time.Since(lastSend)) {
+			return
+		}
+
+		select {
+		case q.slots <- struct{}{}:
+		case <-d.ctx.Done():
+			return
+		}
+
+		// A send that finished while we waited may have asked us to back off
+		if q.pauseRemaining() > 0 {
+			<-q.slots
+			continue
+		}
+
+		notification := q.pop()
+		lastSend = time.Now()
+		inFlightSends.WithLabelValues(q.name).Inc()
+
+		started := d.group.Go(func(context.Context) {
+			defer func() {
+				inFlightSends.WithLabelValues(q.name).Dec()
+				<-q.slots
+			}()
+
+			err := d.send(q, notification)
+
+			var retryErr *RetryAfterError
+			if errors.As(err, &retryErr) {
+				d.logger.Warnf("Provider throttled %s channel, backing off for %s", q.name, retryErr.RetryAfter)
+				q.pause(retryErr.RetryAfter)
+				q.requeue(notification)
+				return
+			}
+
+			d.onResult(notification, err)
+		})
+		if !started {
+			// Shutting down; leave the notification pending
+			inFlightSends.WithLabelValues(q.name).Dec()
+			<-q.slots
+			return
+		}
+	}
+}
+
+// send delivers a notification within the channel's send timeout. A sender
+// that ignores its context is abandoned at the deadline so its slot is freed.
+func (d *dispatcher) send(q *channelQueue, notification *Notification) error {
+	ctx := d.ctx
+	if q.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.timeout)
+		defer cancel()
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- d.sender.Send(ctx, notification)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errSendTimeout
+	}
+	return err
+}
+
+// sleep waits for d, returning false if the dispatcher shut down first
+func (d *dispatcher) sleep(wait time.Duration) bool {
+	if wait <= 0 {
+		return d.ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-d.ctx.Done():
+		return false
+	}
+}
+
+func (q *channelQueue) empty() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.queue) == 0
+}
+
+// pop removes and returns the head of the queue. Only the channel's worker
+// removes items, so the queue is never empty here.
+func (q *channelQueue) pop() *Notification {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	notification := q.queue[0]
+	q.queue = q.queue[1:]
+	queueDepth.WithLabelValues(q.name).Set(float64(len(q.queue)))
+	return notification
+}
+
+// requeue puts a throttled notification back at the front of the queue
+func (q *channelQueue) requeue(notification *Notification) {
+	q.mu.Lock()
+	q.queue = append([]*Notification{notification}, q.queue...)
+	queueDepth.WithLabelValues(q.name).Set(float64(len(q.queue)))
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *channelQueue) pause(d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(q.pausedUntil) {
+		q.pausedUntil = until
+	}
+}
+
+func (q *channelQueue) pauseRemaining() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return time.Until(q.pausedUntil)
+}
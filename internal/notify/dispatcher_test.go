@@ -0,0 +1,327 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/lifecycle"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestDispatcher creates a dispatcher sending through sender whose results
+// are delivered on the returned channel; it is shut down when the test ends
+func newTestDispatcher(t *testing.T, cfg config.NotifyConfig, sender Sender) (*dispatcher, chan error) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	results := make(chan error, 64)
+	group := lifecycle.NewGroup()
+	d := newDispatcher(group, cfg, sender, logger, func(_ *Notification, err error) {
+		results <- err
+	})
+	t.Cleanup(func() {
+		group.Cancel()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := group.Wait(ctx); err != nil {
+			t.Errorf("dispatcher did not stop: %v", err)
+		}
+	})
+	return d, results
+}
+
+// testNotification returns a notification on a channel unique to the test, so
+// its queue and gauges are its own
+func testNotification(channel string) *Notification {
+	return &Notification{ID: uuid.New().String(), UserID: uuid.New().String(), Channel: channel}
+}
+
+// gaugeValue reads a channel's gauge from the default registry
+func gaugeValue(t *testing.T, name, channel string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "channel" && label.GetValue() == channel {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// blockingSender holds every send until released, tracking how many are in
+// flight at once
+type blockingSender struct {
+	release  chan struct{}
+	current  int32
+	max      int32
+	started  chan struct{}
+	finished int32
+}
+
+func newBlockingSender() *blockingSender {
+	return &blockingSender{release: make(chan struct{}), started: make(chan struct{}, 64)}
+}
+
+func (s *blockingSender) Send(ctx context.Context, notification *Notification) error {
+	current := atomic.AddInt32(&s.current, 1)
+	for {
+		max := atomic.LoadInt32(&s.max)
+		if current <= max || atomic.CompareAndSwapInt32(&s.max, max, current) {
+			break
+		}
+	}
+	s.started <- struct{}{}
+
+	defer atomic.AddInt32(&s.current, -1)
+	select {
+	case <-s.release:
+		atomic.AddInt32(&s.finished, 1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitStarted waits for n sends to start
+func (s *blockingSender) waitStarted(t *testing.T, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d sends started", i, n)
+		}
+	}
+}
+
+// waitResults waits for n results, failing on any error
+func waitResults(t *testing.T, results chan error, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				t.Fatalf("send %d failed: %v", i, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d notifications sent", i, n)
+		}
+	}
+}
+
+func TestDispatcherCapsConcurrentSends(t *testing.T) {
+	channel := "test-" + uuid.New().String()
+	sender := newBlockingSender()
+	d, results := newTestDispatcher(t, config.NotifyConfig{
+		Email: config.ChannelLimitConfig{MaxInFlight: 2},
+	}, sender)
+
+	const total = 6
+	for i := 0; i < total; i++ {
+		d.enqueue(testNotification(channel))
+	}
+
+	// Two sends start; the rest wait in the queue rather than being dropped
+	sender.waitStarted(t, 2)
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&sender.current); got != 2 {
+		t.Fatalf("in flight = %d, want 2", got)
+	}
+	if got := gaugeValue(t, "notification_sends_in_flight", channel); got != 2 {
+		t.Errorf("in-flight gauge = %v, want 2", got)
+	}
+	if got := gaugeValue(t, "notification_queue_depth", channel); got != total-2 {
+		t.Errorf("queue depth gauge = %v, want %d", got, total-2)
+	}
+
+	// Releasing the sends drains the queue, never exceeding the cap
+	close(sender.release)
+	waitResults(t, results, total)
+	if got := atomic.LoadInt32(&sender.max); got != 2 {
+		t.Errorf("max in flight = %d, want 2", got)
+	}
+	if got := atomic.LoadInt32(&sender.finished); got != total {
+		t.Errorf("sent %d notifications, want %d", got, total)
+	}
+	if got := gaugeValue(t, "notification_queue_depth", channel); got != 0 {
+		t.Errorf("queue depth gauge = %v after draining, want 0", got)
+	}
+}
+
+// throttlingSender asks the first send to retry later and accepts the rest
+type throttlingSender struct {
+	retryAfter time.Duration
+
+	mu    sync.Mutex
+	sends []time.Time
+}
+
+func (s *throttlingSender) Send(ctx context.Context, notification *Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sends = append(s.sends, time.Now())
+	if len(s.sends) == 1 {
+		return &RetryAfterError{RetryAfter: s.retryAfter}
+	}
+	return nil
+}
+
+func TestDispatcherRequeuesThrottledSends(t *testing.T) {
+	channel := "test-" + uuid.New().String()
+	sender := &throttlingSender{retryAfter: 100 * time.Millisecond}
+	d, results := newTestDispatcher(t, config.NotifyConfig{
+		Email: config.ChannelLimitConfig{MaxInFlight: 1},
+	}, sender)
+
+	d.enqueue(testNotification(channel))
+	d.enqueue(testNotification(channel))
+
+	// The throttled notification is retried rather than reported as failed
+	waitResults(t, results, 2)
+	select {
+	case err := <-results:
+		t.Fatalf("unexpected extra result: %v", err)
+	default:
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	if len(sender.sends) != 3 {
+		t.Fatalf("sends = %d, want 3", len(sender.sends))
+	}
+	if wait := sender.sends[1].Sub(sender.sends[0]); wait < sender.retryAfter {
+		t.Errorf("channel resumed after %s, want at least %s", wait, sender.retryAfter)
+	}
+}
+
+func TestDispatcherSpacesSendsByRate(t *testing.T) {
+	channel := "test-" + uuid.New().String()
+	d, results := newTestDispatcher(t, config.NotifyConfig{
+		Email: config.ChannelLimitConfig{MaxPerSecond: 50, MaxInFlight: 4},
+	}, newRecordingSender())
+
+	const total = 5
+	started := time.Now()
+	for i := 0; i < total; i++ {
+		d.enqueue(testNotification(channel))
+	}
+	waitResults(t, results, total)
+
+	// 50 per second spaces sends 20ms apart
+	if elapsed := time.Since(started); elapsed < (total-1)*20*time.Millisecond {
+		t.Errorf("sent %d notifications in %s, faster than 50 per second", total, elapsed)
+	}
+}
+
+// slowSender takes delay to answer, ignoring its context unless honorContext
+// is set
+type slowSender struct {
+	delay        time.Duration
+	honorContext bool
+	calls        int32
+}
+
+func (s *slowSender) Send(ctx context.Context, notification *Notification) error {
+	if atomic.AddInt32(&s.calls, 1) > 1 {
+		return nil
+	}
+	if !s.honorContext {
+		time.Sleep(s.delay)
+		return nil
+	}
+	select {
+	case <-time.After(s.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestDispatcherTimesOutSlowSends(t *testing.T) {
+	for _, honorContext := range []bool{true, false} {
+		name := "sender ignores context"
+		if honorContext {
+			name = "sender honors context"
+		}
+		t.Run(name, func(t *testing.T) {
+			channel := "test-" + uuid.New().String()
+			sender := &slowSender{delay: time.Second, honorContext: honorContext}
+			d, results := newTestDispatcher(t, config.NotifyConfig{
+				SendTimeout: time.Second,
+				Email:       config.ChannelLimitConfig{MaxInFlight: 1, SendTimeout: 50 * time.Millisecond},
+			}, sender)
+
+			d.enqueue(testNotification(channel))
+			d.enqueue(testNotification(channel))
+
+			// The channel's own timeout applies, and the slow send's slot is
+			// freed for the next notification
+			select {
+			case err := <-results:
+				if !errors.Is(err, errSendTimeout) {
+					t.Fatalf("slow send = %v, want %v", err, errSendTimeout)
+				}
+			case <-time.After(500 * time.Millisecond):
+				t.Fatal("slow send was not timed out")
+			}
+			waitResults(t, results, 1)
+		})
+	}
+}
+
+func TestCompleteNotificationRecordsTimeout(t *testing.T) {
+	s, _ := newTestService(t)
+	notification := &Notification{ID: uuid.New().String(), Status: "pending", Channel: "email", CreatedAt: time.Now()}
+
+	s.completeNotification(notification, errSendTimeout)
+
+	if notification.Status != "failed" || notification.Error != "timeout" || notification.SentAt != nil {
+		t.Fatalf("notification = %+v, want failed with a timeout error", notification)
+	}
+}
+
+func TestDispatcherShutdownCancelsSends(t *testing.T) {
+	channel := "test-" + uuid.New().String()
+	sender := newBlockingSender()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	group := lifecycle.NewGroup()
+	d := newDispatcher(group, config.NotifyConfig{}, sender, logger, func(*Notification, error) {})
+
+	d.enqueue(testNotification(channel))
+	sender.waitStarted(t, 1)
+
+	// Shutting down cancels the send in flight, so nothing is left running
+	group.Cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := group.Wait(ctx); err != nil {
+		t.Fatalf("dispatcher did not stop: %v", err)
+	}
+	if got := atomic.LoadInt32(&sender.current); got != 0 {
+		t.Fatalf("%d sends still running after shutdown", got)
+	}
+}
@@ -0,0 +1,23 @@
+-- Notifications sent to users. Users belong to the auth service, so user_id
+-- is not a foreign key here.
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id UUID NOT NULL,
+    type VARCHAR(20) NOT NULL, -- email, sms, push
+    subject VARCHAR(255),
+    message TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    channel VARCHAR(20) NOT NULL, -- email, sms, push
+    segments INTEGER,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    metadata JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(tenant_id, user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_status ON notifications(status);
+CREATE INDEX IF NOT EXISTS idx_notifications_created_at ON notifications(created_at);
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/sirupsen/logrus"
+)
+
+// Consumed event outcomes
+const (
+	OutcomeQueued  = "queued"
+	OutcomeDeduped = "deduped"
+	OutcomeSkipped = "skipped"
+	OutcomeFailed  = "failed"
+)
+
+// Reasons recorded with non-queued outcomes
+const (
+	reasonDuplicateEvent  = "duplicate_event"
+	reasonMissingUser     = "missing_user"
+	reasonDecodeError     = "decode_error"
+	reasonContentRejected = "content_rejected"
+	reasonNoChannels      = "no_channels"
+	reasonSaveFailed      = "save_failed"
+	reasonExpired         = "expired"
+)
+
+// ConsumptionOutcome records what the consumer did with one event
+type ConsumptionOutcome struct {
+	EventID        string    `json:"event_id"`
+	Topic          string    `json:"topic"`
+	Outcome        string    `json:"outcome"`
+	Reason         string    `json:"reason,omitempty"`
+	NotificationID string    `json:"notification_id,omitempty"`
+	ConsumedAt     time.Time `json:"consumed_at"`
+}
+
+// outcomeLog keeps the most recent consumption outcomes in memory and writes
+// each one to the structured log
+type outcomeLog struct {
+	logger *logrus.Logger
+	retain int
+
+	mu      sync.Mutex
+	entries []*ConsumptionOutcome
+	queued  map[string]bool // event IDs that produced a notification
+}
+
+func newOutcomeLog(logger *logrus.Logger, retain int) *outcomeLog {
+	return &outcomeLog{
+		logger: logger,
+		retain: retain,
+		queued: make(map[string]bool),
+	}
+}
+
+// record stores an outcome, evicting the oldest once the log is full
+func (l *outcomeLog) record(outcome *ConsumptionOutcome) {
+	outcome.ConsumedAt = time.Now()
+
+	l.logger.WithFields(logrus.Fields{
+		"event_id":        outcome.EventID,
+		"topic":           outcome.Topic,
+		"outcome":         outcome.Outcome,
+		"reason":          outcome.Reason,
+		"notification_id": outcome.NotificationID,
+	}).Info("Consumed event")
+
+	if l.retain <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) >= l.retain {
+		evicted := l.entries[0]
+		l.entries = l.entries[1:]
+		if evicted.Outcome == OutcomeQueued {
+			delete(l.queued, evicted.EventID)
+		}
+	}
+	l.entries = append(l.entries, outcome)
+	if outcome.Outcome == OutcomeQueued && outcome.EventID != "" {
+		l.queued[outcome.EventID] = true
+	}
+}
+
+// alreadyQueued reports whether an event already produced a notification.
+// Deduplication only covers events still retained in the log.
+func (l *outcomeLog) alreadyQueued(eventID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.queued[eventID]
+}
+
+// list returns retained outcomes, newest first, optionally for a single event
+func (l *outcomeLog) list(eventID string) []*ConsumptionOutcome {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	outcomes := []*ConsumptionOutcome{}
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if eventID == "" || l.entries[i].EventID == eventID {
+			outcome := *l.entries[i]
+			outcomes = append(outcomes, &outcome)
+		}
+	}
+	return outcomes
+}
+
+// ListConsumptionOutcomes returns recent consumed event outcomes, filtered by
+// the event_id query parameter when present
+func (s *Service) ListConsumptionOutcomes(w http.ResponseWriter, r *http.Request) {
+	outcomes := s.outcomes.list(r.URL.Query().Get("event_id"))
+
+	render.JSON(w, r, map[string]interface{}{
+		"outcomes": outcomes,
+		"total":    len(outcomes),
+	})
+}
@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// consumeEvent handles a redemption completed event and returns the outcome
+// recorded for it
+func consumeEvent(t *testing.T, s *Service, event redemptionCompletedEvent) *ConsumptionOutcome {
+	t.Helper()
+
+	if err := s.handleRedemptionCompleted(context.Background(), event); err != nil {
+		t.Fatalf("handleRedemptionCompleted: %v", err)
+	}
+	outcomes := s.outcomes.list("")
+	if len(outcomes) == 0 {
+		t.Fatal("no outcome recorded")
+	}
+	return outcomes[0]
+}
+
+// completedEvent returns a redemption completed event for a new user
+func completedEvent() redemptionCompletedEvent {
+	return redemptionCompletedEvent{
+		EventID:     uuid.New().String(),
+		UserID:      uuid.New().String(),
+		BenefitName: "$25 Gift Card",
+		Points:      2000,
+		PartnerRef:  "GIFTCO-123",
+	}
+}
+
+func TestConsumptionOutcomes(t *testing.T) {
+	tests := []struct {
+		name        string
+		configure   func(*config.Config)
+		edit        func(*redemptionCompletedEvent)
+		wantOutcome string
+		wantReason  string
+	}{
+		{"queued", nil, nil, OutcomeQueued, ""},
+		{"no channels", func(cfg *config.Config) { cfg.Notify.RedemptionChannels = nil }, nil, OutcomeSkipped, reasonNoChannels},
+		{"missing user", nil, func(e *redemptionCompletedEvent) { e.UserID = "" }, OutcomeSkipped, reasonMissingUser},
+		{"content rejected", func(cfg *config.Config) {
+			cfg.Notify.RedemptionChannels = []string{"email"}
+			cfg.Notify.Content.SubjectMaxLength = 1
+		}, nil, OutcomeFailed, reasonContentRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configure := func(cfg *config.Config) {
+				cfg.Notify.RedemptionChannels = []string{"email"}
+				if tt.configure != nil {
+					tt.configure(cfg)
+				}
+			}
+			s, _ := newTestService(t, configure)
+			event := completedEvent()
+			if tt.edit != nil {
+				tt.edit(&event)
+			}
+
+			outcome := consumeEvent(t, s, event)
+			if outcome.EventID != event.EventID || outcome.Outcome != tt.wantOutcome || outcome.Reason != tt.wantReason {
+				t.Fatalf("outcome = %+v, want %s (%q)", outcome, tt.wantOutcome, tt.wantReason)
+			}
+			if outcome.Topic != s.config.Kafka.Topics.RedemptionComplete || outcome.ConsumedAt.IsZero() {
+				t.Fatalf("outcome = %+v, want the topic and consumption time", outcome)
+			}
+			if (outcome.NotificationID != "") != (tt.wantOutcome == OutcomeQueued) {
+				t.Fatalf("notification ID = %q for outcome %s", outcome.NotificationID, outcome.Outcome)
+			}
+		})
+	}
+}
+
+func TestDuplicateEventIsDeduped(t *testing.T) {
+	s, sender := newTestService(t, func(cfg *config.Config) {
+		cfg.Notify.RedemptionChannels = []string{"email"}
+	})
+	event := completedEvent()
+
+	first := consumeEvent(t, s, event)
+	if first.Outcome != OutcomeQueued {
+		t.Fatalf("first delivery = %+v, want queued", first)
+	}
+	sender.next(t)
+
+	// A redelivery is recorded but notifies nobody
+	second := consumeEvent(t, s, event)
+	if second.Outcome != OutcomeDeduped || second.Reason != reasonDuplicateEvent || second.NotificationID != "" {
+		t.Fatalf("redelivery = %+v, want deduped as a duplicate event", second)
+	}
+	select {
+	case notification := <-sender.sent:
+		t.Fatalf("redelivered event sent %+v", notification)
+	default:
+	}
+
+	// Both outcomes can be looked up by event ID
+	admin, err := s.jwtManager.GenerateToken(uuid.New().String(), "admin@example.com", auth.RoleAdmin)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/consumption-outcomes?event_id="+event.EventID, nil)
+	req.Header.Set("Authorization", "Bearer "+admin)
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list outcomes: status = %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Outcomes []ConsumptionOutcome `json:"outcomes"`
+		Total    int                  `json:"total"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Total != 2 || body.Outcomes[0].Outcome != OutcomeDeduped || body.Outcomes[1].Outcome != OutcomeQueued {
+		t.Fatalf("outcomes = %+v, want deduped then queued", body.Outcomes)
+	}
+}
+
+func TestOutcomeLogEvictsOldest(t *testing.T) {
+	s, _ := newTestService(t)
+	log := newOutcomeLog(s.logger, 2)
+
+	first := &ConsumptionOutcome{EventID: "event-1", Outcome: OutcomeQueued}
+	log.record(first)
+	log.record(&ConsumptionOutcome{EventID: "event-2", Outcome: OutcomeSkipped, Reason: reasonMissingUser})
+	log.record(&ConsumptionOutcome{EventID: "event-3", Outcome: OutcomeQueued})
+
+	outcomes := log.list("")
+	if len(outcomes) != 2 || outcomes[0].EventID != "event-3" || outcomes[1].EventID != "event-2" {
+		t.Fatalf("outcomes = %+v, want event-3 and event-2", outcomes)
+	}
+	// Deduplication only covers retained events
+	if log.alreadyQueued("event-1") || !log.alreadyQueued("event-3") {
+		t.Fatal("dedup state does not match the retained outcomes")
+	}
+}
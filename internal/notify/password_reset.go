@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/jsonutil"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+)
+
+// passwordResetTemplate is the email template reset tokens are sent with
+const passwordResetTemplate = "password-reset"
+
+// passwordResetRequestedEvent is the auth service's password reset event
+type passwordResetRequestedEvent struct {
+	EventID   string    `json:"event_id"`
+	TenantID  string    `json:"tenant_id"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// consumePasswordResetEvents consumes password reset events until ctx is done
+func (s *Service) consumePasswordResetEvents(ctx context.Context) {
+	if s.resets == nil {
+		s.logger.Warn("Kafka consumer not initialized, skipping password reset consumption")
+		return
+	}
+
+	s.logger.Infof("Starting to consume %s events...", s.config.Kafka.Topics.PasswordResetRequested)
+
+	if err := s.resets.ConsumeMessages(ctx, s.handlePasswordResetRequested); err != nil && ctx.Err() == nil {
+		s.logger.Errorf("Stopped consuming password reset events: %v", err)
+	}
+}
+
+// handlePasswordResetRequested emails a reset token to the address in the
+// event. Tokens that expired before they could be sent are skipped.
+func (s *Service) handlePasswordResetRequested(msg *messaging.Message) error {
+	outcome := &ConsumptionOutcome{Topic: msg.Topic}
+	defer s.outcomes.record(outcome)
+
+	var event passwordResetRequestedEvent
+	if err := jsonutil.Unmarshal(msg.Value, &event); err != nil {
+		outcome.EventID = string(msg.Key)
+		outcome.Outcome, outcome.Reason = OutcomeFailed, reasonDecodeError
+		s.logger.Warnf("Skipping malformed password reset event at offset %d: %v", msg.Offset, err)
+		return nil
+	}
+	outcome.EventID = event.EventID
+
+	if event.EventID != "" && s.outcomes.alreadyQueued(event.EventID) {
+		outcome.Outcome, outcome.Reason = OutcomeDeduped, reasonDuplicateEvent
+		return nil
+	}
+
+	if event.UserID == "" || event.Email == "" || event.Token == "" {
+		outcome.Outcome, outcome.Reason = OutcomeSkipped, reasonMissingUser
+		return nil
+	}
+	if !time.Now().Before(event.ExpiresAt) {
+		outcome.Outcome, outcome.Reason = OutcomeSkipped, reasonExpired
+		return nil
+	}
+
+	notification, err := newTemplatedNotification(passwordResetTemplate, "email", event.UserID, map[string]string{
+		"reset_token": event.Token,
+		"expires_at":  event.ExpiresAt.UTC().Format(time.RFC1123),
+	})
+	if err != nil {
+		outcome.Outcome, outcome.Reason = OutcomeFailed, reasonContentRejected
+		s.logger.Warnf("Skipping password reset notification for event %s: %v", event.EventID, err)
+		return nil
+	}
+	notification.TenantID = event.TenantID
+	notification.Recipient = event.Email
+
+	if err := s.sendNotification(s.ctx, notification); err != nil {
+		// Leave the event uncommitted so it is redelivered
+		outcome.Outcome, outcome.Reason = OutcomeFailed, reasonSaveFailed
+		return fmt.Errorf("failed to save password reset notification for event %s: %w", event.EventID, err)
+	}
+	outcome.Outcome, outcome.NotificationID = OutcomeQueued, notification.ID
+	return nil
+}
@@ -0,0 +1,265 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Notification providers selectable per channel
+const (
+	ProviderLog     = "log"
+	ProviderSMTP    = "smtp"
+	ProviderWebhook = "webhook"
+)
+
+// errNoRecipient is returned when a provider has no address to deliver to
+var errNoRecipient = errors.New("no recipient address for user")
+
+// Sender delivers a notification through a provider
+type Sender interface {
+	Send(ctx context.Context, notification *Notification) error
+}
+
+// RetryAfterError is returned by a Sender when the provider throttles
+// requests and asks the caller to wait before sending again
+type RetryAfterError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("provider asked to retry after %s: %v", e.RetryAfter, e.Err)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// channelSenders routes each notification to the sender for its channel
+type channelSenders map[string]Sender
+
+// Send delivers the notification with its channel's sender
+func (c channelSenders) Send(ctx context.Context, notification *Notification) error {
+	sender, ok := c[notification.Channel]
+	if !ok {
+		return fmt.Errorf("no sender for %s channel", notification.Channel)
+	}
+	return sender.Send(ctx, notification)
+}
+
+// newSenders builds the configured sender for each channel. Push
+// notifications are always logged.
+func newSenders(cfg config.NotifyProviderConfig, httpClient *http.Client, logger *logrus.Logger) (channelSenders, error) {
+	logSender := NewLogSender(logger)
+	senders := channelSenders{"email": logSender, "sms": logSender, "push": logSender}
+
+	switch cfg.Email {
+	case "", ProviderLog:
+	case ProviderSMTP:
+		senders["email"] = NewSMTPSender(cfg.SMTP)
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", cfg.Email)
+	}
+
+	switch cfg.SMS {
+	case "", ProviderLog:
+	case ProviderWebhook:
+		senders["sms"] = NewWebhookSMSSender(cfg.SMSWebhook, httpClient)
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q", cfg.SMS)
+	}
+
+	return senders, nil
+}
+
+// LogSender simulates a provider by logging notifications
+type LogSender struct {
+	logger *logrus.Logger
+}
+
+// NewLogSender creates a sender that only logs notifications
+func NewLogSender(logger *logrus.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+// Send logs the notification after a simulated provider delay
+func (l *LogSender) Send(ctx context.Context, notification *Notification) error {
+	l.logger.Infof("Sending notification %s to user %s via %s", notification.ID, notification.UserID, notification.Channel)
+
+	select {
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NoopSender accepts every notification without delivering it
+type NoopSender struct{}
+
+// Send returns immediately
+func (NoopSender) Send(ctx context.Context, notification *Notification) error {
+	return nil
+}
+
+// SMTPSender delivers email notifications through an SMTP relay
+type SMTPSender struct {
+	config config.SMTPConfig
+}
+
+// NewSMTPSender creates a sender for the SMTP relay
+func NewSMTPSender(cfg config.SMTPConfig) *SMTPSender {
+	return &SMTPSender{config: cfg}
+}
+
+// Send emails the notification as plain text. STARTTLS is used when the relay
+// offers it, and is required before authenticating.
+func (s *SMTPSender) Send(ctx context.Context, notification *Notification) error {
+	if notification.Recipient == "" {
+		return errNoRecipient
+	}
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if s.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.config.From); err != nil {
+		return fmt.Errorf("SMTP server rejected sender: %w", err)
+	}
+	if err := client.Rcpt(notification.Recipient); err != nil {
+		return fmt.Errorf("SMTP server rejected recipient: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP server refused message: %w", err)
+	}
+	if _, err := w.Write(s.message(notification)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// message formats the notification as a plain text email
+func (s *SMTPSender) message(notification *Notification) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + s.config.From + "\r\n")
+	b.WriteString("To: " + notification.Recipient + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", notification.Subject) + "\r\n")
+	b.WriteString("Message-ID: <" + notification.ID + "@" + s.config.Host + ">\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(notification.Message, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// WebhookSMSSender delivers SMS notifications by posting them to an SMS
+// provider's webhook
+type WebhookSMSSender struct {
+	config     config.WebhookConfig
+	httpClient *http.Client
+}
+
+// NewWebhookSMSSender creates a sender posting to the configured webhook
+func NewWebhookSMSSender(cfg config.WebhookConfig, httpClient *http.Client) *WebhookSMSSender {
+	return &WebhookSMSSender{config: cfg, httpClient: httpClient}
+}
+
+// smsWebhookRequest is the body posted to the SMS webhook. Reference is the
+// notification ID, so the provider can deduplicate retries.
+type smsWebhookRequest struct {
+	To        string `json:"to"`
+	Message   string `json:"message"`
+	Reference string `json:"reference"`
+}
+
+// Send posts the SMS to the webhook. A 429 response is reported as a
+// RetryAfterError so the channel backs off.
+func (w *WebhookSMSSender) Send(ctx context.Context, notification *Notification) error {
+	if notification.Recipient == "" {
+		return errNoRecipient
+	}
+
+	body, err := json.Marshal(&smsWebhookRequest{
+		To:        notification.Recipient,
+		Message:   notification.Message,
+		Reference: notification.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal SMS request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.config.Token)
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call SMS provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	providerErr := fmt.Errorf("SMS provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := time.Second
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return &RetryAfterError{RetryAfter: retryAfter, Err: providerErr}
+	}
+	return providerErr
+}
@@ -0,0 +1,243 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewSenders(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	senders, err := newSenders(config.NotifyProviderConfig{}, http.DefaultClient, logger)
+	if err != nil {
+		t.Fatalf("newSenders: %v", err)
+	}
+	for _, channel := range []string{"email", "sms", "push"} {
+		if _, ok := senders[channel].(*LogSender); !ok {
+			t.Errorf("%s sender = %T, want *LogSender by default", channel, senders[channel])
+		}
+	}
+
+	senders, err = newSenders(config.NotifyProviderConfig{Email: ProviderSMTP, SMS: ProviderWebhook}, http.DefaultClient, logger)
+	if err != nil {
+		t.Fatalf("newSenders: %v", err)
+	}
+	if _, ok := senders["email"].(*SMTPSender); !ok {
+		t.Errorf("email sender = %T, want *SMTPSender", senders["email"])
+	}
+	if _, ok := senders["sms"].(*WebhookSMSSender); !ok {
+		t.Errorf("sms sender = %T, want *WebhookSMSSender", senders["sms"])
+	}
+	if _, ok := senders["push"].(*LogSender); !ok {
+		t.Errorf("push sender = %T, want *LogSender", senders["push"])
+	}
+
+	for _, cfg := range []config.NotifyProviderConfig{{Email: "carrier-pigeon"}, {SMS: "smtp"}} {
+		if _, err := newSenders(cfg, http.DefaultClient, logger); err == nil {
+			t.Errorf("newSenders(%+v) accepted an unknown provider", cfg)
+		}
+	}
+}
+
+func TestChannelSendersRouteByChannel(t *testing.T) {
+	recording := newRecordingSender()
+	senders := channelSenders{"email": recording, "sms": NoopSender{}}
+
+	if err := senders.Send(context.Background(), &Notification{ID: "n-1", Channel: "email"}); err != nil {
+		t.Fatalf("send email: %v", err)
+	}
+	if sent := recording.next(t); sent.ID != "n-1" {
+		t.Fatalf("email sender got %+v", sent)
+	}
+	if err := senders.Send(context.Background(), &Notification{ID: "n-2", Channel: "sms"}); err != nil {
+		t.Fatalf("send sms: %v", err)
+	}
+	select {
+	case sent := <-recording.sent:
+		t.Fatalf("email sender got the SMS %+v", sent)
+	default:
+	}
+	if err := senders.Send(context.Background(), &Notification{ID: "n-3", Channel: "fax"}); err == nil {
+		t.Fatal("send on a channel without a sender succeeded")
+	}
+}
+
+func TestWebhookSMSSender(t *testing.T) {
+	notification := &Notification{ID: "n-1", Channel: "sms", Recipient: "+15555550100", Message: "Your reward is ready"}
+
+	t.Run("delivered", func(t *testing.T) {
+		var got smsWebhookRequest
+		var authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Errorf("decode: %v", err)
+			}
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		sender := NewWebhookSMSSender(config.WebhookConfig{URL: server.URL, Token: "secret"}, server.Client())
+		if err := sender.Send(context.Background(), notification); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		if got != (smsWebhookRequest{To: "+15555550100", Message: "Your reward is ready", Reference: "n-1"}) {
+			t.Errorf("posted %+v", got)
+		}
+		if authorization != "Bearer secret" {
+			t.Errorf("Authorization = %q, want the configured token", authorization)
+		}
+	})
+
+	t.Run("throttled", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		err := NewWebhookSMSSender(config.WebhookConfig{URL: server.URL}, server.Client()).Send(context.Background(), notification)
+		var retryErr *RetryAfterError
+		if !errors.As(err, &retryErr) || retryErr.RetryAfter != 3*time.Second {
+			t.Fatalf("Send = %v, want a RetryAfterError of 3s", err)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid number\n"))
+		}))
+		defer server.Close()
+
+		err := NewWebhookSMSSender(config.WebhookConfig{URL: server.URL}, server.Client()).Send(context.Background(), notification)
+		if err == nil || err.Error() != "SMS provider returned 400: invalid number" {
+			t.Fatalf("Send = %v, want the provider's status and detail", err)
+		}
+	})
+
+	t.Run("no recipient", func(t *testing.T) {
+		err := NewWebhookSMSSender(config.WebhookConfig{URL: "http://127.0.0.1:0"}, http.DefaultClient).Send(context.Background(), &Notification{ID: "n-2"})
+		if !errors.Is(err, errNoRecipient) {
+			t.Fatalf("Send = %v, want %v", err, errNoRecipient)
+		}
+	})
+}
+
+// fakeSMTPServer accepts one SMTP session, rejecting recipients in reject,
+// and sends the received message data on its channel
+func fakeSMTPServer(t *testing.T, reject string) (config.SMTPConfig, chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "RCPT") && reject != "" && strings.Contains(command, strings.ToUpper(reject)):
+				reply("550 mailbox unavailable")
+			case command == "DATA":
+				reply("354 end with .")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- data.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return config.SMTPConfig{Host: host, Port: portNumber, From: "rewards@example.com"}, received
+}
+
+func TestSMTPSender(t *testing.T) {
+	notification := &Notification{
+		ID: "n-1", Channel: "email", Recipient: "member@example.com",
+		Subject: "Your reward is ready", Message: "Dear Member,\nyour gift card is ready.",
+	}
+
+	t.Run("delivered", func(t *testing.T) {
+		cfg, received := fakeSMTPServer(t, "")
+		if err := NewSMTPSender(cfg).Send(context.Background(), notification); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		message := <-received
+		for _, want := range []string{
+			"From: rewards@example.com\r\n",
+			"To: member@example.com\r\n",
+			"Subject: Your reward is ready\r\n",
+			"Message-ID: <n-1@127.0.0.1>\r\n",
+			"\r\n\r\nDear Member,\r\nyour gift card is ready.",
+		} {
+			if !strings.Contains(message, want) {
+				t.Errorf("message %q does not contain %q", message, want)
+			}
+		}
+	})
+
+	t.Run("recipient rejected", func(t *testing.T) {
+		cfg, _ := fakeSMTPServer(t, "member@example.com")
+		err := NewSMTPSender(cfg).Send(context.Background(), notification)
+		if err == nil || !strings.Contains(err.Error(), "rejected recipient") || !strings.Contains(err.Error(), "550") || !strings.Contains(err.Error(), "mailbox unavailable") {
+			t.Fatalf("Send = %v, want the relay's rejection", err)
+		}
+	})
+
+	t.Run("no recipient", func(t *testing.T) {
+		if err := NewSMTPSender(config.SMTPConfig{}).Send(context.Background(), &Notification{ID: "n-2"}); !errors.Is(err, errNoRecipient) {
+			t.Fatalf("Send = %v, want %v", err, errNoRecipient)
+		}
+	})
+}
+
+func TestNoopSender(t *testing.T) {
+	if err := (NoopSender{}).Send(context.Background(), &Notification{ID: "n-1", Channel: "email"}); err != nil {
+		t.Fatalf("Send = %v, want nil", err)
+	}
+}
@@ -2,47 +2,98 @@ package notify
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/lifecycle"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
 	"github.com/sirupsen/logrus"
 )
 
+// statusUpdateTimeout bounds recording a delivery outcome
+const statusUpdateTimeout = 5 * time.Second
+
+// errNotificationNotFound is returned when a notification does not exist in the caller's tenant
+var errNotificationNotFound = errors.New("notification not found")
+
 // Service represents the notification service
 type Service struct {
-	config *config.Config
-	logger *logrus.Logger
-	kafka  *messaging.KafkaConsumer
+	config     *config.Config
+	logger     *logrus.Logger
+	jwtManager *auth.JWTManager
+	tenants    auth.TenantScope
+	db         *database.PostgresDB
+	bus        messaging.EventBus
+	kafka      messaging.Consumer
+	resets     messaging.Consumer
+	senders    channelSenders
+	dispatcher *dispatcher
+	outcomes   *outcomeLog
+
+	// webhookCompleted and webhookFailed consume redemption events for
+	// webhook deliveries, which webhookClient posts
+	webhookCompleted messaging.Consumer
+	webhookFailed    messaging.Consumer
+	webhookClient    *http.Client
+
+	// background runs the consumers and dispatcher; ctx is its context,
+	// which Shutdown cancels
+	background *lifecycle.Group
+	ctx        context.Context
 }
 
 // Notification represents a notification
 type Notification struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	Type      string    `json:"type"`      // email, sms, push
-	Subject   string    `json:"subject"`
-	Message   string    `json:"message"`
-	Status    string    `json:"status"`    // pending, sent, failed
-	Channel   string    `json:"channel"`   // email, sms, push
-	CreatedAt time.Time `json:"created_at"`
-	SentAt    *time.Time `json:"sent_at,omitempty"`
-	Error     string    `json:"error,omitempty"`
+	ID       string `json:"id"`
+	TenantID string `json:"-"`
+	UserID   string `json:"user_id"`
+	// Recipient is the email address or phone number delivered to
+	Recipient string     `json:"-"`
+	Type      string     `json:"type"` // email, sms, push
+	Subject   string     `json:"subject"`
+	Message   string     `json:"message"`
+	Status    string     `json:"status"`  // pending, sent, failed
+	Channel   string     `json:"channel"` // email, sms, push
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at"` // null until the notification is sent
+	Error     string     `json:"error,omitempty"`
+	// Segments is the number of SMS segments the message is sent as
+	Segments  int  `json:"segments,omitempty"`
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // NotificationRequest represents a request to send a notification
 type NotificationRequest struct {
-	UserID  string            `json:"user_id" validate:"required"`
-	Type    string            `json:"type" validate:"required,oneof=email sms push"`
-	Subject string            `json:"subject"`
-	Message string            `json:"message" validate:"required"`
-	Channel string            `json:"channel" validate:"required,oneof=email sms push"`
-	Data    map[string]string `json:"data,omitempty"`
+	UserID  string `json:"user_id" validate:"required"`
+	Type    string `json:"type" validate:"required,oneof=email sms push"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+	Channel string `json:"channel" validate:"required,oneof=email sms push"`
+	// TemplateID renders the subject and message from a template on the
+	// channel, filling its variables from Data, instead of sending Message
+	TemplateID string            `json:"template_id,omitempty"`
+	Data       map[string]string `json:"data,omitempty"`
+}
+
+// NotificationListResponse is a page of a user's notifications
+type NotificationListResponse struct {
+	Notifications []*Notification `json:"notifications"`
+	Total         int             `json:"total"`
+	Page          int             `json:"page"`
+	Limit         int             `json:"limit"`
 }
 
 // NotificationResponse represents a notification response
@@ -50,98 +101,138 @@ type NotificationResponse struct {
 	NotificationID string `json:"notification_id"`
 	Status         string `json:"status"`
 	Message        string `json:"message"`
+	Segments       int    `json:"segments,omitempty"`
+	Truncated      bool   `json:"truncated,omitempty"`
 }
 
 // EmailTemplate represents an email template
 type EmailTemplate struct {
-	ID      string            `json:"id"`
-	Name    string            `json:"name"`
-	Subject string            `json:"subject"`
-	Body    string            `json:"body"`
-	Variables []string        `json:"variables"`
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Subject   string   `json:"subject"`
+	Body      string   `json:"body"`
+	Variables []string `json:"variables"`
 }
 
 // SMSTemplate represents an SMS template
 type SMSTemplate struct {
-	ID      string   `json:"id"`
-	Name    string   `json:"name"`
-	Message string   `json:"message"`
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Message   string   `json:"message"`
 	Variables []string `json:"variables"`
 }
 
 // NewService creates a new notification service
 func NewService(cfg *config.Config, logger *logrus.Logger) *Service {
-	// Initialize Kafka consumer for redemption events
-	kafkaConfig := &messaging.KafkaConfig{
-		Brokers:  cfg.Kafka.Brokers,
-		ClientID: cfg.Kafka.ClientID,
-		GroupID:  cfg.Kafka.GroupID,
-	}
-	kafkaConsumer := messaging.NewKafkaConsumer(kafkaConfig, "redemption.completed.v1", logger)
+	// Initialize JWT manager
+	jwtManager := auth.NewJWTManagerFromConfig(cfg)
 
+	background := lifecycle.NewGroup()
 	service := &Service{
-		config: cfg,
-		logger: logger,
-		kafka:  kafkaConsumer,
+		config:     cfg,
+		logger:     logger,
+		jwtManager: jwtManager,
+		tenants:    auth.NewTenantScope(cfg.Security.Tenancy.Enabled, cfg.Security.Tenancy.DefaultTenant),
+		background: background,
+		ctx:        background.Context(),
+		outcomes:   newOutcomeLog(logger, cfg.Notify.OutcomeRetention),
+	}
+	service.webhookClient = newWebhookClient(&tracing.Transport{})
+
+	senders, err := newSenders(cfg.Notify.Providers, &http.Client{Transport: &tracing.Transport{}}, logger)
+	if err != nil {
+		logger.Errorf("Failed to configure notification providers, logging notifications instead: %v", err)
+		logSender := NewLogSender(logger)
+		senders = channelSenders{"email": logSender, "sms": logSender, "push": logSender}
 	}
+	service.senders = senders
+	service.dispatcher = newDispatcher(background, cfg.Notify, senders, logger, service.completeNotification)
 
-	// Start consuming Kafka events
-	go service.consumeRedemptionEvents()
+	// Initialize consumers for redemption and password reset events
+	kafkaConfig := &messaging.KafkaConfig{
+		Driver:         cfg.Kafka.Driver,
+		Brokers:        cfg.Kafka.Brokers,
+		ClientID:       cfg.Kafka.ClientID,
+		GroupID:        cfg.Kafka.GroupID,
+		MaxAttempts:    cfg.Kafka.ConsumerRetry.MaxAttempts,
+		RetryBaseDelay: cfg.Kafka.ConsumerRetry.BaseDelay,
+		RetryMaxDelay:  cfg.Kafka.ConsumerRetry.MaxDelay,
+		DeadLetter:     cfg.Kafka.DeadLetter,
+	}
+	bus, err := messaging.NewEventBus(kafkaConfig, logger)
+	if err != nil {
+		logger.Errorf("Failed to initialize event bus: %v", err)
+	} else {
+		service.bus = bus
+		service.kafka = bus.Consumer(cfg.Kafka.Topics.RedemptionComplete)
+		service.resets = bus.Consumer(cfg.Kafka.Topics.PasswordResetRequested)
+	}
+
+	// Webhooks read redemption events in their own consumer group, so every
+	// event reaches both them and the notification consumer
+	webhookConfig := *kafkaConfig
+	webhookConfig.GroupID = cfg.Notify.Webhooks.GroupID
+	if webhookConfig.GroupID == "" {
+		webhookConfig.GroupID = cfg.Kafka.GroupID + "-webhooks"
+	}
+	webhookBus, err := messaging.NewEventBus(&webhookConfig, logger)
+	if err != nil {
+		logger.Errorf("Failed to initialize webhook event bus: %v", err)
+	} else {
+		service.webhookCompleted = webhookBus.Consumer(cfg.Kafka.Topics.RedemptionComplete)
+		service.webhookFailed = webhookBus.Consumer(cfg.Kafka.Topics.RedemptionFailed)
+	}
 
 	return service
 }
 
+// SetDatabase sets the database connection
+func (s *Service) SetDatabase(db *database.PostgresDB) {
+	s.db = db
+}
+
+// SetSender replaces the sender for a channel, for example with a NoopSender
+// in tests. It must be called before any notification is sent.
+func (s *Service) SetSender(channel string, sender Sender) {
+	s.senders[channel] = sender
+}
+
 // Routes returns the notification service routes
 func (s *Service) Routes(r chi.Router) {
+	requireJWT := authmw.RequireJWT(s.jwtManager, authmw.WithTenants(s.tenants), authmw.WithLogger(s.logger))
+
 	r.Route("/v1", func(r chi.Router) {
 		r.Route("/notifications", func(r chi.Router) {
-			r.Post("/", s.AuthMiddleware(s.SendNotification))
-			r.Get("/{id}", s.AuthMiddleware(s.GetNotification))
-			r.Get("/", s.AuthMiddleware(s.ListNotifications))
+			r.Use(requireJWT)
+			r.Post("/", s.SendNotification)
+			r.Get("/{id}", s.GetNotification)
+			r.Get("/", s.ListNotifications)
 		})
 		r.Route("/templates", func(r chi.Router) {
 			r.Get("/email", s.GetEmailTemplates)
 			r.Get("/sms", s.GetSMSTemplates)
 		})
+		r.With(requireJWT).Get("/admin/consumption-outcomes", s.ListConsumptionOutcomes)
+		r.With(requireJWT, authmw.RequireRole(auth.RoleAdmin, authmw.WithLogger(s.logger))).Route("/webhooks", s.webhookRoutes)
 	})
 }
 
-// AuthMiddleware is a placeholder for JWT authentication
-func (s *Service) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Implement JWT validation
-		// For now, just check if user ID header is present
-		userID := r.Header.Get("X-User-ID")
-		if userID == "" {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "User ID required"})
-			return
-		}
-		// Add user ID to context
-		ctx := context.WithValue(r.Context(), "user_id", userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	}
-}
-
 // SendNotification handles sending a notification
 func (s *Service) SendNotification(w http.ResponseWriter, r *http.Request) {
 	var req NotificationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
 		return
 	}
-
-	// Validate request
-	if req.UserID == "" || req.Type == "" || req.Message == "" || req.Channel == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "User ID, type, message, and channel are required"})
+	if req.Message == "" && req.TemplateID == "" {
+		platformhttp.ValidationError(w, r, map[string]string{"message": "is required without template_id"})
 		return
 	}
 
 	// Create notification
 	notification := &Notification{
 		ID:        uuid.New().String(),
+		TenantID:  auth.TenantFromContext(r.Context()),
 		UserID:    req.UserID,
 		Type:      req.Type,
 		Subject:   req.Subject,
@@ -151,14 +242,38 @@ func (s *Service) SendNotification(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: time.Now(),
 	}
 
-	// Send notification asynchronously
-	go s.sendNotification(notification)
+	var err error
+	if req.TemplateID != "" {
+		err = applyTemplate(notification, req.TemplateID, req.Data)
+	}
+	if err == nil {
+		err = s.prepareContent(notification)
+	}
+	if err != nil {
+		var contentErr *ContentError
+		if errors.As(err, &contentErr) {
+			platformhttp.ValidationError(w, r, map[string]string{contentErr.Field: contentErr.Message})
+			return
+		}
+		s.logger.Errorf("Failed to prepare notification content: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to queue notification")
+		return
+	}
+
+	// Record and queue notification for asynchronous delivery
+	if err := s.sendNotification(r.Context(), notification); err != nil {
+		s.logger.Errorf("Failed to save notification: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to queue notification")
+		return
+	}
 
 	// Return immediate response
 	response := &NotificationResponse{
 		NotificationID: notification.ID,
 		Status:         "pending",
 		Message:        "Notification queued for delivery",
+		Segments:       notification.Segments,
+		Truncated:      notification.Truncated,
 	}
 
 	render.Status(r, http.StatusAccepted)
@@ -169,164 +284,419 @@ func (s *Service) SendNotification(w http.ResponseWriter, r *http.Request) {
 func (s *Service) GetNotification(w http.ResponseWriter, r *http.Request) {
 	notificationID := chi.URLParam(r, "id")
 	if notificationID == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Notification ID required"})
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Notification ID required")
 		return
 	}
 
-	notification, err := s.getNotification(notificationID)
+	notification, err := s.getNotification(r.Context(), notificationID)
 	if err != nil {
+		if errors.Is(err, errNotificationNotFound) {
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Notification not found")
+			return
+		}
 		s.logger.Errorf("Failed to get notification %s: %v", notificationID, err)
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{"error": "Notification not found"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve notification")
 		return
 	}
 
 	render.JSON(w, r, notification)
 }
 
-// ListNotifications returns the user's notification history
+// ListNotifications returns a page of the authenticated user's notification
+// history, newest first
 func (s *Service) ListNotifications(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
-	
-	notifications, err := s.getNotificationsByUser(userID)
+	userID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	notifications, total, err := s.getNotificationsByUser(r.Context(), userID, page, limit)
 	if err != nil {
 		s.logger.Errorf("Failed to get notifications: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Failed to retrieve notifications"})
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve notifications")
 		return
 	}
 
-	render.JSON(w, r, notifications)
+	render.JSON(w, r, &NotificationListResponse{
+		Notifications: notifications,
+		Total:         total,
+		Page:          page,
+		Limit:         limit,
+	})
 }
 
 // GetEmailTemplates returns available email templates
 func (s *Service) GetEmailTemplates(w http.ResponseWriter, r *http.Request) {
-	templates := []*EmailTemplate{
-		{
-			ID:        "redemption-completed",
-			Name:      "Redemption Completed",
-			Subject:   "Your reward has been fulfilled!",
-			Body:      "Dear {{user_name}}, your {{benefit_name}} has been successfully fulfilled. Reference: {{partner_ref}}",
-			Variables: []string{"user_name", "benefit_name", "partner_ref"},
-		},
-		{
-			ID:        "points-earned",
-			Name:      "Points Earned",
-			Subject:   "You've earned {{points}} points!",
-			Body:      "Congratulations! You've earned {{points}} points from your recent transaction at {{merchant}}.",
-			Variables: []string{"points", "merchant"},
-		},
-		{
-			ID:        "welcome",
-			Name:      "Welcome",
-			Subject:   "Welcome to our loyalty program!",
-			Body:      "Welcome {{user_name}}! Start earning points with every purchase.",
-			Variables: []string{"user_name"},
-		},
-	}
-
 	render.JSON(w, r, map[string]interface{}{
-		"templates": templates,
-		"total":     len(templates),
+		"templates": emailTemplates,
+		"total":     len(emailTemplates),
 	})
 }
 
 // GetSMSTemplates returns available SMS templates
 func (s *Service) GetSMSTemplates(w http.ResponseWriter, r *http.Request) {
-	templates := []*SMSTemplate{
-		{
-			ID:        "redemption-completed-sms",
-			Name:      "Redemption Completed SMS",
-			Message:   "Your {{benefit_name}} has been fulfilled! Ref: {{partner_ref}}",
-			Variables: []string{"benefit_name", "partner_ref"},
-		},
-		{
-			ID:        "points-earned-sms",
-			Name:      "Points Earned SMS",
-			Message:   "You earned {{points}} points! Keep shopping to earn more.",
-			Variables: []string{"points"},
-		},
+	render.JSON(w, r, map[string]interface{}{
+		"templates": smsTemplates,
+		"total":     len(smsTemplates),
+	})
+}
+
+// PingEventBus reports whether the event bus is reachable. The consumer
+// reconnects on its own, so notifications keep being served while it is down.
+func (s *Service) PingEventBus(ctx context.Context) error {
+	if s.bus == nil {
+		return errors.New("event bus not initialized")
 	}
+	return s.bus.Ping(ctx)
+}
 
-	render.JSON(w, r, map[string]interface{}{
-		"templates": templates,
-		"total":     len(templates),
+// Start consumes redemption and password reset events and delivers webhooks
+// in the background until Shutdown, reporting each consumer's lag every
+// kafka.lag_interval
+func (s *Service) Start() {
+	s.background.Go(s.consumeRedemptionEvents)
+	s.background.Go(s.consumePasswordResetEvents)
+	s.background.Go(func(ctx context.Context) {
+		s.consumeWebhookEvents(ctx, s.webhookCompleted, WebhookEventRedemptionCompleted)
+	})
+	s.background.Go(func(ctx context.Context) {
+		s.consumeWebhookEvents(ctx, s.webhookFailed, WebhookEventRedemptionFailed)
 	})
+	s.background.Go(s.runWebhookDeliveries)
+
+	if interval := s.config.Kafka.LagInterval; interval > 0 {
+		for _, consumer := range s.consumers() {
+			if reporter, ok := consumer.(messaging.LagReporter); ok {
+				s.background.Go(func(ctx context.Context) {
+					reporter.ReportLag(ctx, interval)
+				})
+			}
+		}
+	}
 }
 
-// consumeRedemptionEvents consumes redemption events from Kafka
-func (s *Service) consumeRedemptionEvents() {
+// Shutdown stops consuming events, cancels queued and in-flight sends, and
+// waits until ctx is done for the consumers and senders to return. An event
+// being handled is left uncommitted and redelivered after a restart.
+func (s *Service) Shutdown(ctx context.Context) error {
+	s.background.Cancel()
+	errs := []error{s.background.Wait(ctx)}
+	for _, consumer := range s.consumers() {
+		if consumer != nil {
+			errs = append(errs, consumer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// consumers returns the service's event consumers, which are nil if the
+// event bus could not be initialized
+func (s *Service) consumers() []messaging.Consumer {
+	return []messaging.Consumer{s.kafka, s.resets, s.webhookCompleted, s.webhookFailed}
+}
+
+// consumeRedemptionEvents consumes redemption events from Kafka until ctx is done
+func (s *Service) consumeRedemptionEvents(ctx context.Context) {
 	if s.kafka == nil {
 		s.logger.Warn("Kafka consumer not initialized, skipping event consumption")
 		return
 	}
 
-	s.logger.Info("Starting to consume redemption events...")
-	
-	// TODO: Implement actual Kafka event consumption
-	// For now, just log that we would consume events
-	s.logger.Info("Would consume redemption.completed.v1 events from Kafka")
+	s.logger.Infof("Starting to consume %s events...", s.config.Kafka.Topics.RedemptionComplete)
+
+	if err := messaging.ConsumeTyped(ctx, s.kafka, s.handleRedemptionCompleted); err != nil && ctx.Err() == nil {
+		s.logger.Errorf("Stopped consuming redemption events: %v", err)
+	}
 }
 
-// sendNotification sends a notification through the appropriate channel
-func (s *Service) sendNotification(notification *Notification) {
-	s.logger.Infof("Sending notification %s to user %s via %s", notification.ID, notification.UserID, notification.Channel)
+// redemptionCompletedEvent is the subset of the redemption completed event used for notifications
+type redemptionCompletedEvent struct {
+	EventID     string `json:"event_id"`
+	UserID      string `json:"user_id"`
+	BenefitID   string `json:"benefit_id"`
+	BenefitName string `json:"benefit_name"`
+	Points      int    `json:"points"`
+	PartnerRef  string `json:"partner_ref"`
+}
 
-	// Simulate sending delay
-	time.Sleep(100 * time.Millisecond)
+// templateVars returns the values the redemption completed templates are
+// rendered with. The event does not carry the user's name.
+func (e *redemptionCompletedEvent) templateVars() map[string]string {
+	benefitName := e.BenefitName
+	if benefitName == "" {
+		benefitName = "reward"
+	}
+	return map[string]string{
+		"user_name":    "Member",
+		"benefit_name": benefitName,
+		"partner_ref":  e.PartnerRef,
+		"points":       strconv.Itoa(e.Points),
+	}
+}
+
+// handleRedemptionCompleted notifies the user on each configured channel that
+// their redemption was fulfilled. Every decoded event gets a recorded
+// outcome, including skips; malformed events are dead-lettered by
+// ConsumeTyped. Events that can never be notified are logged and skipped
+// rather than returned as errors, so they do not hold up the consumer.
+func (s *Service) handleRedemptionCompleted(ctx context.Context, event redemptionCompletedEvent) error {
+	outcome := &ConsumptionOutcome{Topic: s.config.Kafka.Topics.RedemptionComplete, EventID: event.EventID}
+	defer s.outcomes.record(outcome)
+
+	if event.EventID != "" && s.outcomes.alreadyQueued(event.EventID) {
+		outcome.Outcome, outcome.Reason = OutcomeDeduped, reasonDuplicateEvent
+		return nil
+	}
+
+	if event.UserID == "" {
+		outcome.Outcome, outcome.Reason = OutcomeSkipped, reasonMissingUser
+		return nil
+	}
+
+	vars := event.templateVars()
+	var notifications []*Notification
+	for _, channel := range s.config.Notify.RedemptionChannels {
+		notification, err := newTemplatedNotification(redemptionCompletedTemplate, channel, event.UserID, vars)
+		if err == nil {
+			// Redemption events do not carry a tenant
+			notification.TenantID = auth.TenantFromContext(ctx)
+		}
+		if err == nil {
+			err = s.prepareContent(notification)
+		}
+		if err != nil {
+			outcome.Outcome, outcome.Reason = OutcomeFailed, reasonContentRejected
+			s.logger.Warnf("Skipping redemption notification for event %s: %v", event.EventID, err)
+			return nil
+		}
+		notifications = append(notifications, notification)
+	}
 
-	// Simulate success (in real implementation, this would call actual email/SMS services)
-	notification.Status = "sent"
-	sentAt := time.Now()
-	notification.SentAt = &sentAt
+	if len(notifications) == 0 {
+		outcome.Outcome, outcome.Reason = OutcomeSkipped, reasonNoChannels
+		return nil
+	}
+
+	for _, notification := range notifications {
+		if err := s.sendNotification(ctx, notification); err != nil {
+			// Return the error so the consumer retries the event
+			outcome.Outcome, outcome.Reason = OutcomeFailed, reasonSaveFailed
+			return fmt.Errorf("failed to save redemption notification for event %s: %w", event.EventID, err)
+		}
+	}
+	outcome.Outcome, outcome.NotificationID = OutcomeQueued, notifications[0].ID
+	return nil
+}
+
+// sendNotification records a pending notification and queues it for
+// delivery on its channel
+func (s *Service) sendNotification(ctx context.Context, notification *Notification) error {
+	if err := s.resolveRecipient(ctx, notification); err != nil {
+		return err
+	}
+	if err := s.saveNotification(ctx, notification); err != nil {
+		return err
+	}
+	s.dispatcher.enqueue(notification)
+	return nil
+}
+
+// completeNotification records the outcome of a delivery attempt. The update
+// is made even during shutdown, so a send that finished is not left pending.
+func (s *Service) completeNotification(notification *Notification, err error) {
+	if err != nil {
+		notification.Status = "failed"
+		notification.Error = err.Error()
+		s.logger.Errorf("Failed to send notification %s: %v", notification.ID, err)
+	} else {
+		notification.Status = "sent"
+		notification.SentAt = timePtr(time.Now())
+		s.logger.Infof("Notification %s sent successfully", notification.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(s.ctx), statusUpdateTimeout)
+	defer cancel()
+	if err := s.updateNotificationStatus(ctx, notification); err != nil {
+		s.logger.Errorf("Failed to record status of notification %s: %v", notification.ID, err)
+	}
 
-	s.logger.Infof("Notification %s sent successfully", notification.ID)
-	
-	// TODO: Save notification status to database
 	// TODO: Emit notification sent event
 }
 
-// Database operations (placeholder implementations)
-func (s *Service) getNotification(id string) (*Notification, error) {
-	// Return mock data for now
-	return &Notification{
-		ID:        id,
-		UserID:    "user-123",
-		Type:      "email",
-		Subject:   "Your reward has been fulfilled!",
-		Message:   "Dear User, your $25 Gift Card has been successfully fulfilled. Reference: VENDOR-12345",
-		Status:    "sent",
-		Channel:   "email",
-		CreatedAt: time.Now().Add(-1 * time.Hour),
-		SentAt:    &time.Time{},
-	}, nil
-}
-
-func (s *Service) getNotificationsByUser(userID string) ([]*Notification, error) {
-	// Return mock data for now
-	return []*Notification{
-		{
-			ID:        "notif-1",
-			UserID:    userID,
+// notificationColumns lists the columns scanNotification reads, in order
+const notificationColumns = `id, tenant_id, user_id, type, COALESCE(subject, ''), message, status, channel,
+	COALESCE(segments, 0), truncated, created_at, sent_at, COALESCE(error, '')`
+
+// saveNotification records a new pending notification
+func (s *Service) saveNotification(ctx context.Context, notification *Notification) error {
+	if s.db == nil {
+		s.logger.Infof("Would save notification: %+v", notification)
+		return nil
+	}
+
+	return s.db.Exec(ctx, `
+		INSERT INTO notifications (id, tenant_id, user_id, type, subject, message, status, channel,
+			segments, truncated, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, NULLIF($9, 0), $10, $11)`,
+		notification.ID, notification.TenantID, notification.UserID, notification.Type, notification.Subject,
+		notification.Message, notification.Status, notification.Channel, notification.Segments,
+		notification.Truncated, notification.CreatedAt)
+}
+
+// resolveRecipient looks up the user's email address or phone number for the
+// notification's channel. A user without one is left for the provider to fail.
+func (s *Service) resolveRecipient(ctx context.Context, notification *Notification) error {
+	if s.db == nil || notification.Recipient != "" {
+		return nil
+	}
+
+	var column string
+	switch notification.Channel {
+	case "email":
+		column = "email"
+	case "sms":
+		column = "phone"
+	default:
+		return nil
+	}
+
+	err := s.db.QueryRow(ctx, `SELECT COALESCE(`+column+`, '') FROM users WHERE id = $1 AND tenant_id = $2`,
+		notification.UserID, notification.TenantID).Scan(&notification.Recipient)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// updateNotificationStatus records the outcome of a delivery attempt
+func (s *Service) updateNotificationStatus(ctx context.Context, notification *Notification) error {
+	if s.db == nil {
+		return nil
+	}
+
+	return s.db.Exec(ctx, `
+		UPDATE notifications SET status = $3, sent_at = $4, error = NULLIF($5, '')
+		WHERE id = $1 AND tenant_id = $2`,
+		notification.ID, notification.TenantID, notification.Status, notification.SentAt, notification.Error)
+}
+
+func (s *Service) getNotification(ctx context.Context, id string) (*Notification, error) {
+	if s.db == nil {
+		// Return mock data for now
+		sentAt := time.Now().Add(-1 * time.Hour)
+		return &Notification{
+			ID:        id,
+			UserID:    "user-123",
 			Type:      "email",
 			Subject:   "Your reward has been fulfilled!",
 			Message:   "Dear User, your $25 Gift Card has been successfully fulfilled. Reference: VENDOR-12345",
 			Status:    "sent",
 			Channel:   "email",
-			CreatedAt: time.Now().Add(-24 * time.Hour),
-			SentAt:    &time.Time{},
-		},
-		{
-			ID:        "notif-2",
-			UserID:    userID,
-			Type:      "sms",
-			Subject:   "",
-			Message:   "You earned 300 points! Keep shopping to earn more.",
-			Status:    "sent",
-			Channel:   "sms",
-			CreatedAt: time.Now().Add(-48 * time.Hour),
-			SentAt:    &time.Time{},
-		},
-	}, nil
+			CreatedAt: sentAt,
+			SentAt:    timePtr(sentAt),
+		}, nil
+	}
+
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE id = $1 AND tenant_id = $2`
+
+	notification, err := scanNotification(s.db.QueryRow(ctx, query, id, auth.TenantFromContext(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errNotificationNotFound
+		}
+		return nil, err
+	}
+	return notification, nil
+}
+
+// getNotificationsByUser returns a page of the user's notifications, newest
+// first, and the user's total number of notifications
+func (s *Service) getNotificationsByUser(ctx context.Context, userID string, page, limit int) ([]*Notification, int, error) {
+	if s.db == nil {
+		// Return mock data for now
+		day, twoDays := time.Now().Add(-24*time.Hour), time.Now().Add(-48*time.Hour)
+		return []*Notification{
+			{
+				ID:        "notif-1",
+				UserID:    userID,
+				Type:      "email",
+				Subject:   "Your reward has been fulfilled!",
+				Message:   "Dear User, your $25 Gift Card has been successfully fulfilled. Reference: VENDOR-12345",
+				Status:    "sent",
+				Channel:   "email",
+				CreatedAt: day,
+				SentAt:    timePtr(day),
+			},
+			{
+				ID:        "notif-2",
+				UserID:    userID,
+				Type:      "sms",
+				Subject:   "",
+				Message:   "You earned 300 points! Keep shopping to earn more.",
+				Status:    "sent",
+				Channel:   "sms",
+				CreatedAt: twoDays,
+				SentAt:    timePtr(twoDays),
+			},
+		}, 2, nil
+	}
+
+	tenantID := auth.TenantFromContext(ctx)
+
+	var total int
+	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND tenant_id = $2`,
+		userID, tenantID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + notificationColumns + ` FROM notifications
+		WHERE user_id = $1 AND tenant_id = $2
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4`
+
+	rows, err := s.db.Query(ctx, query, userID, tenantID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, total, rows.Err()
+}
+
+// scanNotification reads a row selected with notificationColumns
+func scanNotification(row pgx.Row) (*Notification, error) {
+	var notification Notification
+	err := row.Scan(&notification.ID, &notification.TenantID, &notification.UserID, &notification.Type,
+		&notification.Subject, &notification.Message, &notification.Status, &notification.Channel,
+		&notification.Segments, &notification.Truncated, &notification.CreatedAt, &notification.SentAt,
+		&notification.Error)
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// timePtr returns a pointer to t, for optional timestamps that must never be the zero time
+func timePtr(t time.Time) *time.Time {
+	return &t
 }
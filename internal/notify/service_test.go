@@ -0,0 +1,425 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/auth"
+	platformauth "github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/migrate"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/redemption"
+	"github.com/sirupsen/logrus"
+)
+
+// loadTestConfig loads service's configuration for the in-memory event bus,
+// without Redis; configure adjusts it first
+func loadTestConfig(t *testing.T, service string, configure ...func(*config.Config)) *config.Config {
+	t.Helper()
+
+	cfg, err := config.Load(service)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Kafka.Driver = messaging.DriverMemory
+	cfg.Redis.Addr = ""
+	cfg.Security.JWT.Revocation = false
+	cfg.Security.RateLimit.Enabled = false
+	for _, fn := range configure {
+		fn(cfg)
+	}
+	return cfg
+}
+
+// newTestService creates a notification service without a database whose
+// channels deliver to the returned sender; configure adjusts the loaded
+// configuration first
+func newTestService(t *testing.T, configure ...func(*config.Config)) (*Service, *recordingSender) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	s := NewService(loadTestConfig(t, "notify-svc", configure...), logger)
+
+	sender := newRecordingSender()
+	for _, channel := range []string{"email", "sms", "push"} {
+		s.SetSender(channel, sender)
+	}
+	return s, sender
+}
+
+// withTestDB migrates the test database for s, including the auth schema
+// recipients are looked up in, and returns a tenant of the test's own. The
+// test is skipped without a database.
+func withTestDB(t *testing.T, s *Service) string {
+	t.Helper()
+
+	db := databasetest.Open(t)
+	for service, migrations := range map[string]fs.FS{"auth": auth.Migrations, "notify": Migrations} {
+		if err := migrate.Run(context.Background(), db, service, migrations, migrate.ModeApply, s.logger); err != nil {
+			t.Fatalf("failed to migrate %s: %v", service, err)
+		}
+	}
+	s.SetDatabase(db)
+	return databasetest.Tenant(t)
+}
+
+// startTestService starts s's consumers and senders, shutting them down when
+// the test ends
+func startTestService(t *testing.T, s *Service) {
+	t.Helper()
+
+	s.Start()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+	})
+}
+
+// recordingSender records the notifications it is given
+type recordingSender struct {
+	sent chan *Notification
+}
+
+func newRecordingSender() *recordingSender {
+	return &recordingSender{sent: make(chan *Notification, 16)}
+}
+
+func (s *recordingSender) Send(ctx context.Context, notification *Notification) error {
+	sent := *notification
+	s.sent <- &sent
+	return nil
+}
+
+// next waits for the next notification sent
+func (s *recordingSender) next(t *testing.T) *Notification {
+	t.Helper()
+
+	select {
+	case notification := <-s.sent:
+		return notification
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification sent")
+		return nil
+	}
+}
+
+func TestRedemptionNotificationOverMemoryBus(t *testing.T) {
+	s, sender := newTestService(t)
+	startTestService(t, s)
+
+	// A redemption service in the same process publishes to the same bus
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	redemptions := redemption.NewService(loadTestConfig(t, "redemption-svc", func(cfg *config.Config) {
+		cfg.Services.CatalogURL = ""
+		cfg.Services.LoyaltyURL = ""
+		cfg.Services.PartnerGatewayURL = ""
+	}), logger)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		redemptions.Shutdown(ctx)
+	})
+	router := chi.NewRouter()
+	redemptions.Routes(router)
+
+	userID := uuid.New().String()
+	tok, err := s.jwtManager.GenerateToken(userID, "member@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	body, _ := json.Marshal(redemption.RedemptionRequest{
+		BenefitID: uuid.New().String(),
+		Points:    2000,
+		Details:   &redemption.FulfillmentDetails{Amount: 25, Currency: "USD"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/redeem", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+tok)
+	req.Header.Set("Idempotency-Key", uuid.New().String())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("redeem: status = %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+
+	// The completed saga's event is consumed and the member notified
+	notification := sender.next(t)
+	if notification.UserID != userID || notification.Channel != "email" {
+		t.Fatalf("notification = %+v, want an email to %s", notification, userID)
+	}
+	if notification.Subject == "" || notification.Message == "" {
+		t.Fatalf("notification = %+v, want the redemption template rendered", notification)
+	}
+}
+
+func TestConsumeRedemptionEventsSkipsMalformedMessages(t *testing.T) {
+	topic := "test-redemption-completed-" + uuid.New().String()
+	s, sender := newTestService(t, func(cfg *config.Config) {
+		cfg.Kafka.Topics.RedemptionComplete = topic
+		cfg.Notify.RedemptionChannels = []string{"email"}
+	})
+	startTestService(t, s)
+
+	// Malformed messages are skipped without holding up the valid one behind them
+	producer := s.bus.Producer()
+	ctx := context.Background()
+	for _, value := range []string{`{not json`, `{"user_id": 42}`} {
+		if err := producer.SendMessage(ctx, topic, nil, []byte(value)); err != nil {
+			t.Fatalf("send %s: %v", value, err)
+		}
+	}
+	event := completedEvent()
+	if err := producer.SendJSONMessage(ctx, topic, []byte(event.UserID), event); err != nil {
+		t.Fatalf("send event: %v", err)
+	}
+
+	notification := sender.next(t)
+	if notification.UserID != event.UserID || notification.Channel != "email" {
+		t.Fatalf("notification = %+v, want an email to %s", notification, event.UserID)
+	}
+	if !strings.Contains(notification.Message, event.BenefitName) || !strings.Contains(notification.Message, event.PartnerRef) {
+		t.Fatalf("message = %q, want the benefit and partner reference", notification.Message)
+	}
+	select {
+	case extra := <-sender.sent:
+		t.Fatalf("malformed message sent %+v", extra)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	outcomes := s.outcomes.list("")
+	if len(outcomes) != 1 || outcomes[0].EventID != event.EventID || outcomes[0].Outcome != OutcomeQueued {
+		t.Fatalf("outcomes = %+v, want only the valid event queued", outcomes)
+	}
+}
+
+// failingSender fails every send with err
+type failingSender struct {
+	err error
+}
+
+func (f failingSender) Send(ctx context.Context, notification *Notification) error {
+	return f.err
+}
+
+func TestDeliveryOutcomesArePersisted(t *testing.T) {
+	s, sender := newTestService(t, func(cfg *config.Config) {
+		cfg.Security.Tenancy.Enabled = true
+	})
+	tenantID := withTestDB(t, s)
+	s.SetSender("email", failingSender{err: errors.New("smtp: 550 mailbox unavailable")})
+	startTestService(t, s)
+
+	userID := uuid.New().String()
+	tok, err := s.jwtManager.GenerateTenantToken(userID, "member@example.com", "user", tenantID)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	router := chi.NewRouter()
+	s.Routes(router)
+	call := func(method, path string, body interface{}, out interface{}) {
+		t.Helper()
+
+		var reader io.Reader
+		if body != nil {
+			payload, _ := json.Marshal(body)
+			reader = bytes.NewReader(payload)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tok)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK && rec.Code != http.StatusAccepted {
+			t.Fatalf("%s %s: status = %d: %s", method, path, rec.Code, rec.Body)
+		}
+		if err := json.NewDecoder(rec.Body).Decode(out); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+	}
+	// send queues a notification and waits for its delivery outcome
+	send := func(channel string) *Notification {
+		t.Helper()
+
+		var queued NotificationResponse
+		call(http.MethodPost, "/v1/notifications", NotificationRequest{UserID: userID, Type: channel, Channel: channel, Message: "Your reward is ready"}, &queued)
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			var notification Notification
+			call(http.MethodGet, "/v1/notifications/"+queued.NotificationID, nil, &notification)
+			if notification.Status != "pending" {
+				return &notification
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("notification %s still pending", queued.NotificationID)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	failed := send("email")
+	if failed.Status != "failed" || failed.Error != "smtp: 550 mailbox unavailable" || failed.SentAt != nil {
+		t.Fatalf("failed send = %+v, want status failed with the provider error", failed)
+	}
+	sent := send("sms")
+	sender.next(t)
+	if sent.Status != "sent" || sent.Error != "" || sent.SentAt == nil {
+		t.Fatalf("successful send = %+v, want status sent with sent_at", sent)
+	}
+
+	// The history is paged, newest first
+	var page NotificationListResponse
+	call(http.MethodGet, "/v1/notifications?limit=1", nil, &page)
+	if page.Total != 2 || len(page.Notifications) != 1 || page.Notifications[0].ID != sent.ID {
+		t.Fatalf("first page = %+v, want the newest of 2 notifications", page)
+	}
+}
+
+// sentAtJSON returns the sent_at field of notification as serialized
+func sentAtJSON(t *testing.T, notification *Notification) interface{} {
+	t.Helper()
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	value, ok := decoded["sent_at"]
+	if !ok {
+		t.Fatalf("sent_at missing from %s", body)
+	}
+	return value
+}
+
+func TestUnsentNotificationJSONHasNullSentAt(t *testing.T) {
+	s, _ := newTestService(t)
+	pending := &Notification{ID: uuid.New().String(), Status: "pending", Channel: "email", CreatedAt: time.Now()}
+	if got := sentAtJSON(t, pending); got != nil {
+		t.Fatalf("pending sent_at = %v, want null", got)
+	}
+
+	failed := &Notification{ID: uuid.New().String(), Status: "pending", Channel: "email", CreatedAt: time.Now()}
+	s.completeNotification(failed, errors.New("provider down"))
+	if got := sentAtJSON(t, failed); got != nil {
+		t.Fatalf("failed sent_at = %v, want null", got)
+	}
+
+	sent := &Notification{ID: uuid.New().String(), Status: "pending", Channel: "email", CreatedAt: time.Now()}
+	s.completeNotification(sent, nil)
+	sentAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(sentAtJSON(t, sent)))
+	if err != nil || sentAt.IsZero() {
+		t.Fatalf("sent sent_at = %v (%v), want the send time", sentAt, err)
+	}
+}
+
+func TestMockNotificationsHaveSendTime(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	notification, err := s.getNotification(ctx, "notif-1")
+	if err != nil {
+		t.Fatalf("getNotification: %v", err)
+	}
+	notifications, _, err := s.getNotificationsByUser(ctx, "user-123", 1, 10)
+	if err != nil {
+		t.Fatalf("getNotificationsByUser: %v", err)
+	}
+	for _, n := range append(notifications, notification) {
+		sentAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(sentAtJSON(t, n)))
+		if err != nil || sentAt.IsZero() || sentAt.Before(n.CreatedAt) {
+			t.Fatalf("%s sent_at = %v (%v), want a send time no earlier than %v", n.ID, sentAt, err, n.CreatedAt)
+		}
+	}
+}
+
+func TestPingEventBus(t *testing.T) {
+	s, _ := newTestService(t)
+	if err := s.PingEventBus(context.Background()); err != nil {
+		t.Fatalf("PingEventBus = %v, want the memory bus reachable", err)
+	}
+
+	// Without a bus the service still starts, reporting the bus as down
+	s.bus = nil
+	if err := s.PingEventBus(context.Background()); err == nil {
+		t.Fatal("PingEventBus succeeded without an event bus")
+	}
+}
+
+func TestRoutesRejectInvalidTokens(t *testing.T) {
+	s, _ := newTestService(t)
+	forged, err := platformauth.NewJWTManager(&platformauth.JWTConfig{
+		Secret:     "another-secret-at-least-32-bytes-long",
+		Issuer:     s.config.Security.JWT.Issuer,
+		Audience:   s.config.Security.JWT.Audience,
+		Expiration: time.Minute,
+	}).GenerateToken("user-123", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	valid, err := s.jwtManager.GenerateToken("user-123", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	get := func(path, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		router := chi.NewRouter()
+		s.Routes(router)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name, header, value string
+	}{
+		{"no token", "", ""},
+		{"user ID header", "X-User-ID", "user-123"},
+		{"not a bearer token", "Authorization", "Basic dXNlcjpwYXNz"},
+		{"malformed token", "Authorization", "Bearer not.a.token"},
+		{"foreign signature", "Authorization", "Bearer " + forged},
+	}
+	for _, path := range []string{"/v1/notifications", "/v1/notifications/" + uuid.New().String()} {
+		for _, tt := range tests {
+			t.Run(path+"/"+tt.name, func(t *testing.T) {
+				rec := get(path, tt.header, tt.value)
+				if rec.Code != http.StatusUnauthorized {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+				}
+				var body platformhttp.ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Code != platformhttp.ErrCodeUnauthorized {
+					t.Fatalf("body = %+v (err %v), want the %s error shape", body, err, platformhttp.ErrCodeUnauthorized)
+				}
+			})
+		}
+	}
+
+	if rec := get("/v1/notifications", "Authorization", "Bearer "+valid); rec.Code != http.StatusOK {
+		t.Fatalf("valid token: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+}
@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// redemptionCompletedTemplate is the template family notified when a
+// redemption is fulfilled
+const redemptionCompletedTemplate = "redemption-completed"
+
+var emailTemplates = []*EmailTemplate{
+	{
+		ID:        "redemption-completed",
+		Name:      "Redemption Completed",
+		Subject:   "Your reward has been fulfilled!",
+		Body:      "Dear {{user_name}}, your {{benefit_name}} has been successfully fulfilled. Reference: {{partner_ref}}",
+		Variables: []string{"user_name", "benefit_name", "partner_ref"},
+	},
+	{
+		ID:        "points-earned",
+		Name:      "Points Earned",
+		Subject:   "You've earned {{points}} points!",
+		Body:      "Congratulations! You've earned {{points}} points from your recent transaction at {{merchant}}.",
+		Variables: []string{"points", "merchant"},
+	},
+	{
+		ID:        "password-reset",
+		Name:      "Password Reset",
+		Subject:   "Reset your password",
+		Body:      "Use this code to reset your password: {{reset_token}}. It expires at {{expires_at}}. If you did not ask to reset your password, you can ignore this email.",
+		Variables: []string{"reset_token", "expires_at"},
+	},
+	{
+		ID:        "welcome",
+		Name:      "Welcome",
+		Subject:   "Welcome to our loyalty program!",
+		Body:      "Welcome {{user_name}}! Start earning points with every purchase.",
+		Variables: []string{"user_name"},
+	},
+}
+
+var smsTemplates = []*SMSTemplate{
+	{
+		ID:        "redemption-completed-sms",
+		Name:      "Redemption Completed SMS",
+		Message:   "Your {{benefit_name}} has been fulfilled! Ref: {{partner_ref}}",
+		Variables: []string{"benefit_name", "partner_ref"},
+	},
+	{
+		ID:        "points-earned-sms",
+		Name:      "Points Earned SMS",
+		Message:   "You earned {{points}} points! Keep shopping to earn more.",
+		Variables: []string{"points"},
+	},
+}
+
+// placeholderPattern matches {{name}} placeholders, allowing inner spaces
+var placeholderPattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// MissingVariableError is returned when a template placeholder has no value
+type MissingVariableError struct {
+	Name string
+}
+
+func (e *MissingVariableError) Error() string {
+	return fmt.Sprintf("missing template variable %q", e.Name)
+}
+
+// RenderTemplate replaces each {{name}} placeholder in tmpl with its value in
+// vars. Values are inserted as they are, without escaping, and are not
+// themselves expanded. Variables without a placeholder are ignored; a
+// placeholder without a value is a MissingVariableError.
+func RenderTemplate(tmpl string, vars map[string]string) (string, error) {
+	var missing error
+	rendered := placeholderPattern.ReplaceAllStringFunc(tmpl, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		value, ok := vars[name]
+		if !ok && missing == nil {
+			missing = &MissingVariableError{Name: name}
+		}
+		return value
+	})
+	if missing != nil {
+		return "", missing
+	}
+	return rendered, nil
+}
+
+// newTemplatedNotification renders a template family for a channel into a
+// pending notification. SMS templates are the family ID with an "-sms" suffix.
+func newTemplatedNotification(family, channel, userID string, vars map[string]string) (*Notification, error) {
+	notification := &Notification{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Type:      channel,
+		Status:    "pending",
+		Channel:   channel,
+		CreatedAt: time.Now(),
+	}
+
+	templateID := family
+	if channel == "sms" {
+		templateID = family + "-sms"
+	}
+	if err := applyTemplate(notification, templateID, vars); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// applyTemplate renders the notification's subject and message from the
+// template with the given ID on its channel
+func applyTemplate(notification *Notification, templateID string, vars map[string]string) error {
+	var subject, message string
+	switch notification.Channel {
+	case "email":
+		template := findEmailTemplate(templateID)
+		if template == nil {
+			return &ContentError{Field: "template_id", Message: "is not an email template"}
+		}
+		subject, message = template.Subject, template.Body
+	case "sms":
+		template := findSMSTemplate(templateID)
+		if template == nil {
+			return &ContentError{Field: "template_id", Message: "is not an SMS template"}
+		}
+		message = template.Message
+	default:
+		return &ContentError{Field: "template_id", Message: "is not supported on the " + notification.Channel + " channel"}
+	}
+
+	var err error
+	if notification.Subject, err = RenderTemplate(subject, vars); err != nil {
+		return templateVariableError(err)
+	}
+	if notification.Message, err = RenderTemplate(message, vars); err != nil {
+		return templateVariableError(err)
+	}
+	return nil
+}
+
+// templateVariableError reports a missing template variable against the
+// request's data field
+func templateVariableError(err error) error {
+	var missingErr *MissingVariableError
+	if errors.As(err, &missingErr) {
+		return &ContentError{Field: "data", Message: "is missing template variable " + missingErr.Name}
+	}
+	return err
+}
+
+func findEmailTemplate(id string) *EmailTemplate {
+	for _, template := range emailTemplates {
+		if template.ID == id {
+			return template
+		}
+	}
+	return nil
+}
+
+func findSMSTemplate(id string) *SMSTemplate {
+	for _, template := range smsTemplates {
+		if template.ID == id {
+			return template
+		}
+	}
+	return nil
+}
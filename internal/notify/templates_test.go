@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		tmpl        string
+		vars        map[string]string
+		want        string
+		wantMissing string
+	}{
+		{"all variables", "Hi {{user_name}}, enjoy your {{benefit_name}}",
+			map[string]string{"user_name": "Ada", "benefit_name": "gift card"}, "Hi Ada, enjoy your gift card", ""},
+		{"inner spaces", "Hi {{ user_name }}", map[string]string{"user_name": "Ada"}, "Hi Ada", ""},
+		{"repeated placeholder", "{{points}} + {{points}}", map[string]string{"points": "5"}, "5 + 5", ""},
+		{"extra variables are ignored", "Hi {{user_name}}", map[string]string{"user_name": "Ada", "points": "5"}, "Hi Ada", ""},
+		{"empty value", "Ref: {{partner_ref}}", map[string]string{"partner_ref": ""}, "Ref: ", ""},
+		{"missing variable", "Hi {{user_name}}, ref {{partner_ref}}", map[string]string{"user_name": "Ada"}, "", "partner_ref"},
+		{"first missing variable reported", "{{a}} {{b}}", nil, "", "a"},
+		{"values are not escaped", "Hi {{user_name}}", map[string]string{"user_name": `<b>"Ada" & co</b>`}, `Hi <b>"Ada" & co</b>`, ""},
+		{"values are not expanded", "Hi {{user_name}}", map[string]string{"user_name": "{{points}}", "points": "5"}, "Hi {{points}}", ""},
+		{"single braces are literal", "{user_name} {{user_name}}", map[string]string{"user_name": "Ada"}, "{user_name} Ada", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.tmpl, tt.vars)
+			if tt.wantMissing != "" {
+				var missingErr *MissingVariableError
+				if !errors.As(err, &missingErr) || missingErr.Name != tt.wantMissing {
+					t.Fatalf("RenderTemplate = %q, %v; want %s missing", got, err, tt.wantMissing)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("RenderTemplate = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendTemplatedNotification(t *testing.T) {
+	s, sender := newTestService(t)
+	startTestService(t, s)
+
+	rec := postNotification(t, s, NotificationRequest{
+		UserID: uuid.New().String(), Type: "email", Channel: "email", TemplateID: redemptionCompletedTemplate,
+		Data: map[string]string{"user_name": "Ada", "benefit_name": "$25 Gift Card", "partner_ref": "GIFTCO-123"},
+	})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	sent := sender.next(t)
+	if want := "Dear Ada, your $25 Gift Card has been successfully fulfilled. Reference: GIFTCO-123"; sent.Message != want {
+		t.Fatalf("message = %q, want %q", sent.Message, want)
+	}
+	if sent.Subject == "" {
+		t.Fatal("subject not rendered")
+	}
+}
+
+func TestSendTemplatedNotificationRejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       NotificationRequest
+		wantField string
+	}{
+		{"missing variable", NotificationRequest{Type: "email", Channel: "email", TemplateID: redemptionCompletedTemplate,
+			Data: map[string]string{"user_name": "Ada"}}, "data"},
+		{"unknown template", NotificationRequest{Type: "email", Channel: "email", TemplateID: "no-such-template"}, "template_id"},
+		{"template on another channel", NotificationRequest{Type: "sms", Channel: "sms", TemplateID: redemptionCompletedTemplate,
+			Data: map[string]string{"benefit_name": "$25 Gift Card", "partner_ref": "GIFTCO-123"}}, "template_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t)
+			tt.req.UserID = uuid.New().String()
+
+			rec := postNotification(t, s, tt.req)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+			}
+			var body platformhttp.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.Fields[tt.wantField] == "" {
+				t.Fatalf("fields = %v, want an error for %s", body.Fields, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestSendNotificationValidatesRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       NotificationRequest
+		wantField string
+	}{
+		{"unknown type", NotificationRequest{UserID: uuid.New().String(), Type: "carrier-pigeon", Channel: "email", Message: "Hi"}, "type"},
+		{"unknown channel", NotificationRequest{UserID: uuid.New().String(), Type: "email", Channel: "fax", Message: "Hi"}, "channel"},
+		{"missing type", NotificationRequest{UserID: uuid.New().String(), Channel: "email", Message: "Hi"}, "type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t)
+
+			rec := postNotification(t, s, tt.req)
+			if rec.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+			}
+			var body platformhttp.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.Code != platformhttp.ErrCodeValidationFailed || body.Fields[tt.wantField] == "" {
+				t.Fatalf("error = %+v, want a field error for %s", body, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/jsonutil"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+)
+
+// Headers sent with each webhook delivery. The signature is "sha256=" and
+// the hex HMAC-SHA256, keyed with the webhook's secret, of the timestamp, a
+// ".", and the body, so receivers can reject replayed deliveries.
+const (
+	WebhookHeaderSignature = "X-Webhook-Signature"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// Webhook delivery statuses
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryFailed    = "failed"
+)
+
+// webhookErrorDetailLimit caps how much of an endpoint's error response is
+// kept in the attempt log
+const webhookErrorDetailLimit = 512
+
+// webhookPayload is the body posted to webhooks. Data is the redemption
+// event as published.
+type webhookPayload struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// pendingDelivery is a claimed delivery with what is needed to send it
+type pendingDelivery struct {
+	ID        string
+	WebhookID string
+	URL       string
+	Secret    string
+	EventType string
+	EventID   string
+	Payload   []byte
+	// Attempt is the number of this attempt, counting the first as 1
+	Attempt int
+}
+
+// consumeWebhookEvents queues deliveries of eventType events from consumer
+// until ctx is done. It reads in its own consumer group, so it neither
+// competes with the notification consumers nor depends on the saga.
+func (s *Service) consumeWebhookEvents(ctx context.Context, consumer messaging.Consumer, eventType string) {
+	if consumer == nil {
+		s.logger.Warnf("Kafka consumer not initialized, skipping %s webhook deliveries", eventType)
+		return
+	}
+
+	s.logger.Infof("Starting to consume %s events for webhooks...", eventType)
+
+	err := consumer.ConsumeMessages(ctx, func(msg *messaging.Message) error {
+		return s.queueWebhookDeliveries(msg.Context(), eventType, msg.Value)
+	})
+	if err != nil && ctx.Err() == nil {
+		s.logger.Errorf("Stopped consuming %s events for webhooks: %v", eventType, err)
+	}
+}
+
+// queueWebhookDeliveries queues a delivery of the event for each webhook of
+// its tenant subscribed to eventType. An event consumed again is not queued
+// twice for the same webhook.
+func (s *Service) queueWebhookDeliveries(ctx context.Context, eventType string, value []byte) error {
+	var event struct {
+		EventID string `json:"event_id"`
+	}
+	if err := jsonutil.Unmarshal(value, &event); err != nil {
+		return messaging.Permanent(fmt.Errorf("failed to decode %s event: %w", eventType, err))
+	}
+	if event.EventID == "" {
+		s.logger.WithContext(ctx).Warnf("Skipping %s event without an event ID for webhooks", eventType)
+		return nil
+	}
+
+	payload, err := json.Marshal(&webhookPayload{
+		ID:        event.EventID,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      value,
+	})
+	if err != nil {
+		return messaging.Permanent(fmt.Errorf("failed to encode %s webhook payload: %w", eventType, err))
+	}
+
+	if s.db == nil {
+		s.logger.Infof("Would queue webhook deliveries of %s event %s", eventType, event.EventID)
+		return nil
+	}
+
+	// Redemption events do not carry a tenant
+	err = s.db.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_type, event_id, payload)
+		SELECT id, $2, $3, $4::jsonb FROM webhooks
+		WHERE tenant_id = $1 AND $2 = ANY(events)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING`,
+		auth.TenantFromContext(ctx), eventType, event.EventID, payload)
+	if err != nil {
+		// Return the error so the consumer retries the event
+		return fmt.Errorf("failed to queue webhook deliveries of %s event %s: %w", eventType, event.EventID, err)
+	}
+	return nil
+}
+
+// runWebhookDeliveries sends due webhook deliveries every
+// notify.webhooks.poll_interval until ctx is done
+func (s *Service) runWebhookDeliveries(ctx context.Context) {
+	interval := s.config.Notify.Webhooks.PollInterval
+	if interval <= 0 {
+		return
+	}
+	if s.db == nil {
+		s.logger.Warn("Database not initialized, webhook deliveries not started")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := s.deliverWebhooks(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.logger.Errorf("Failed to deliver webhooks: %v", err)
+			}
+			if sent > 0 {
+				s.logger.Debugf("Attempted %d webhook deliveries", sent)
+			}
+		}
+	}
+}
+
+// deliverWebhooks claims a batch of due deliveries and sends them side by
+// side, returning how many were attempted
+func (s *Service) deliverWebhooks(ctx context.Context) (int, error) {
+	deliveries, err := s.claimWebhookDeliveries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *pendingDelivery) {
+			defer wg.Done()
+			s.deliverWebhook(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// claimWebhookDeliveries claims the pending deliveries that are due, including
+// ones whose previous claim timed out, counting the attempt about to be made.
+// SKIP LOCKED lets several instances deliver side by side.
+func (s *Service) claimWebhookDeliveries(ctx context.Context) ([]*pendingDelivery, error) {
+	cfg := s.config.Notify.Webhooks
+
+	rows, err := s.db.Query(ctx, `
+		WITH claimed AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+				AND (started_at IS NULL OR started_at < NOW() - $2 * INTERVAL '1 second')
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET started_at = NOW(), attempts = d.attempts + 1
+		FROM claimed, webhooks w
+		WHERE d.id = claimed.id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, w.url, w.secret, d.event_type, d.event_id, d.payload::text, d.attempts
+	`, cfg.BatchSize, cfg.ClaimTimeout.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*pendingDelivery{}
+	for rows.Next() {
+		var delivery pendingDelivery
+		var payload string
+		err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.URL, &delivery.Secret, &delivery.EventType,
+			&delivery.EventID, &payload, &delivery.Attempt)
+		if err != nil {
+			return nil, err
+		}
+		delivery.Payload = []byte(payload)
+		deliveries = append(deliveries, &delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// deliverWebhook makes one attempt at a delivery and records it. A failed
+// attempt is retried with backoff until notify.webhooks.retry.max_attempts,
+// after which the delivery fails. An attempt cut short by shutdown is not
+// counted, and is made again once the service is back.
+func (s *Service) deliverWebhook(ctx context.Context, delivery *pendingDelivery) {
+	started := time.Now()
+	statusCode, err := s.postWebhook(ctx, delivery, started)
+	duration := time.Since(started)
+
+	// Record the attempt even during shutdown, so it is not made again
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), statusUpdateTimeout)
+	defer cancel()
+
+	if err != nil && ctx.Err() != nil {
+		if relErr := s.db.Exec(recordCtx, `
+			UPDATE webhook_deliveries SET started_at = NULL, attempts = attempts - 1
+			WHERE id = $1 AND status = 'pending'`, delivery.ID); relErr != nil {
+			s.logger.Errorf("Failed to release webhook delivery %s: %v", delivery.ID, relErr)
+		}
+		return
+	}
+
+	status := deliveryDelivered
+	var nextAttempt time.Time
+	var errMessage string
+	if err != nil {
+		errMessage = err.Error()
+		retry := s.config.Notify.Webhooks.Retry
+		if delivery.Attempt >= retry.MaxAttempts {
+			status = deliveryFailed
+			s.logger.Errorf("Giving up on webhook delivery %s of %s event %s after %d attempts: %v",
+				delivery.ID, delivery.EventType, delivery.EventID, delivery.Attempt, err)
+		} else {
+			status = deliveryPending
+			nextAttempt = time.Now().Add(webhookRetryDelay(delivery.Attempt, retry))
+			s.logger.Warnf("Webhook delivery %s of %s event %s failed (attempt %d), retrying at %s: %v",
+				delivery.ID, delivery.EventType, delivery.EventID, delivery.Attempt, nextAttempt.Format(time.RFC3339), err)
+		}
+	}
+
+	if err := s.recordWebhookAttempt(recordCtx, delivery, status, nextAttempt, statusCode, errMessage, duration); err != nil {
+		// The claim times out and the delivery is attempted again
+		s.logger.Errorf("Failed to record attempt %d of webhook delivery %s: %v", delivery.Attempt, delivery.ID, err)
+	}
+}
+
+// recordWebhookAttempt logs an attempt and moves its delivery to status,
+// scheduling the next attempt of a pending one at nextAttempt
+func (s *Service) recordWebhookAttempt(ctx context.Context, delivery *pendingDelivery, status string, nextAttempt time.Time,
+	statusCode int, errMessage string, duration time.Duration) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms)
+		VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, ''), $5)`,
+		delivery.ID, delivery.Attempt, statusCode, errMessage, duration.Milliseconds())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, started_at = NULL,
+			next_attempt_at = CASE WHEN $2 = 'pending' THEN $3 ELSE next_attempt_at END,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() END
+		WHERE id = $1`, delivery.ID, status, nextAttempt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// postWebhook posts a delivery's payload, signed at timestamp, to its
+// webhook. Any response other than 2xx fails the attempt; redirects are not
+// followed. It returns the response status, or 0 if there was none.
+func (s *Service) postWebhook(ctx context.Context, delivery *pendingDelivery, timestamp time.Time) (int, error) {
+	if timeout := s.config.Notify.Webhooks.Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderDelivery, delivery.ID)
+	req.Header.Set(WebhookHeaderTimestamp, unix)
+	req.Header.Set(WebhookHeaderSignature, signWebhook(delivery.Secret, unix, delivery.Payload))
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, webhookErrorDetailLimit))
+	return resp.StatusCode, fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+}
+
+// signWebhook returns the signature header value of body sent at timestamp,
+// in Unix seconds
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookClient returns the client deliveries are posted with, which
+// reports redirects as the response instead of following them
+func newWebhookClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// webhookRetryDelay returns the backoff after the given failed attempt (1
+// for the first), doubling from the base delay up to the max, with up to half
+// of it randomized so retries to a recovering endpoint spread out
+func webhookRetryDelay(attempt int, retry config.RetryConfig) time.Duration {
+	delay := retry.BaseDelay
+	for i := 1; i < attempt && (retry.MaxDelay <= 0 || delay < retry.MaxDelay); i++ {
+		delay *= 2
+	}
+	if retry.MaxDelay > 0 && delay > retry.MaxDelay {
+		delay = retry.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
@@ -0,0 +1,365 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// Events webhooks can subscribe to
+const (
+	WebhookEventRedemptionCompleted = "redemption.completed"
+	WebhookEventRedemptionFailed    = "redemption.failed"
+)
+
+// webhookEvents lists the events a webhook may subscribe to
+var webhookEvents = []string{WebhookEventRedemptionCompleted, WebhookEventRedemptionFailed}
+
+// webhookSecretPrefix marks webhook signing secrets, so a leaked one is
+// recognizable
+const webhookSecretPrefix = "whsec_"
+
+// errWebhookNotFound is returned when a webhook does not exist in the caller's tenant
+var errWebhookNotFound = errors.New("webhook not found")
+
+// Webhook is an HTTP endpoint that redemption events are posted to
+type Webhook struct {
+	ID       string   `json:"id"`
+	TenantID string   `json:"-"`
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	// Secret signs the webhook's deliveries. It is only returned when the
+	// webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookRequest registers a webhook. Without Events it receives every event.
+type WebhookRequest struct {
+	URL    string   `json:"url" validate:"required,max=2048"`
+	Events []string `json:"events"`
+}
+
+// WebhookDelivery is an event queued for a webhook, with its attempts so far
+type WebhookDelivery struct {
+	ID            string                    `json:"id"`
+	WebhookID     string                    `json:"webhook_id"`
+	EventType     string                    `json:"event_type"`
+	EventID       string                    `json:"event_id"`
+	Status        string                    `json:"status"` // pending, delivered, failed
+	Attempts      int                       `json:"attempts"`
+	NextAttemptAt *time.Time                `json:"next_attempt_at"` // null once delivered or failed
+	CreatedAt     time.Time                 `json:"created_at"`
+	DeliveredAt   *time.Time                `json:"delivered_at"`
+	AttemptLog    []*WebhookDeliveryAttempt `json:"attempt_log"`
+}
+
+// WebhookDeliveryAttempt records one attempt to deliver an event
+type WebhookDeliveryAttempt struct {
+	Attempt int `json:"attempt"`
+	// StatusCode is the endpoint's response status, absent when it could
+	// not be reached
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMS  int64     `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// webhookRoutes mounts the webhook admin API
+func (s *Service) webhookRoutes(r chi.Router) {
+	r.Post("/", s.CreateWebhook)
+	r.Get("/", s.ListWebhooks)
+	r.Delete("/{id}", s.DeleteWebhook)
+	r.Get("/{id}/deliveries", s.ListWebhookDeliveries)
+}
+
+// CreateWebhook registers a webhook for the caller's tenant. The response
+// carries the secret its deliveries are signed with, which is not shown again.
+func (s *Service) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req WebhookRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+	if errs := validateWebhookRequest(&req); errs != nil {
+		platformhttp.ValidationError(w, r, errs)
+		return
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		s.logger.Errorf("Failed to generate webhook secret: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to create webhook")
+		return
+	}
+
+	webhook := &Webhook{
+		ID:        uuid.New().String(),
+		TenantID:  auth.TenantFromContext(r.Context()),
+		URL:       req.URL,
+		Events:    req.Events,
+		Secret:    secret,
+		CreatedBy: authmw.Actor(r.Context()),
+		CreatedAt: time.Now(),
+	}
+	if len(webhook.Events) == 0 {
+		webhook.Events = webhookEvents
+	}
+
+	if err := s.saveWebhook(r.Context(), webhook); err != nil {
+		s.logger.Errorf("Failed to save webhook: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to create webhook")
+		return
+	}
+	s.logger.Infof("Webhook %s for %v registered by %s", webhook.ID, webhook.Events, webhook.CreatedBy)
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, webhook)
+}
+
+// ListWebhooks returns the caller's tenant's webhooks, without their secrets
+func (s *Service) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.listWebhooks(r.Context())
+	if err != nil {
+		s.logger.Errorf("Failed to list webhooks: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve webhooks")
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"webhooks": webhooks,
+		"total":    len(webhooks),
+	})
+}
+
+// DeleteWebhook removes a webhook along with its deliveries, including any
+// not yet sent
+func (s *Service) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookID := chi.URLParam(r, "id")
+	if err := s.deleteWebhook(r.Context(), webhookID); err != nil {
+		if errors.Is(err, errWebhookNotFound) {
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Webhook not found")
+			return
+		}
+		s.logger.Errorf("Failed to delete webhook %s: %v", webhookID, err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to delete webhook")
+		return
+	}
+	s.logger.Infof("Webhook %s deleted by %s", webhookID, authmw.Actor(r.Context()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries returns a webhook's most recent deliveries, newest
+// first, each with the log of its attempts
+func (s *Service) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookID := chi.URLParam(r, "id")
+	deliveries, err := s.listWebhookDeliveries(r.Context(), webhookID)
+	if err != nil {
+		if errors.Is(err, errWebhookNotFound) {
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Webhook not found")
+			return
+		}
+		s.logger.Errorf("Failed to list deliveries of webhook %s: %v", webhookID, err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve webhook deliveries")
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"deliveries": deliveries,
+		"total":      len(deliveries),
+	})
+}
+
+// validateWebhookRequest checks what the validate tags cannot: that the URL
+// is absolute HTTP(S) and the events are known
+func validateWebhookRequest(req *WebhookRequest) platformhttp.FieldErrors {
+	errs := platformhttp.FieldErrors{}
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs["url"] = "must be an absolute http or https URL"
+	}
+	seen := make(map[string]bool, len(req.Events))
+	for _, event := range req.Events {
+		if !containsEvent(event) {
+			errs["events"] = "must contain only " + WebhookEventRedemptionCompleted + " or " + WebhookEventRedemptionFailed
+			break
+		}
+		if seen[event] {
+			errs["events"] = "must not repeat an event"
+			break
+		}
+		seen[event] = true
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func containsEvent(event string) bool {
+	for _, known := range webhookEvents {
+		if known == event {
+			return true
+		}
+	}
+	return false
+}
+
+// newWebhookSecret returns a random secret to sign a webhook's deliveries with
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(buf), nil
+}
+
+func (s *Service) saveWebhook(ctx context.Context, webhook *Webhook) error {
+	if s.db == nil {
+		s.logger.Infof("Would save webhook %s for %s", webhook.ID, webhook.URL)
+		return nil
+	}
+
+	return s.db.Exec(ctx, `
+		INSERT INTO webhooks (id, tenant_id, url, secret, events, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)`,
+		webhook.ID, webhook.TenantID, webhook.URL, webhook.Secret, webhook.Events, webhook.CreatedBy, webhook.CreatedAt)
+}
+
+func (s *Service) listWebhooks(ctx context.Context) ([]*Webhook, error) {
+	webhooks := []*Webhook{}
+	if s.db == nil {
+		return webhooks, nil
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT id, tenant_id, url, events, COALESCE(created_by, ''), created_at
+		FROM webhooks
+		WHERE tenant_id = $1
+		ORDER BY created_at, id`, auth.TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var webhook Webhook
+		err := rows.Scan(&webhook.ID, &webhook.TenantID, &webhook.URL, &webhook.Events, &webhook.CreatedBy, &webhook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, &webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *Service) deleteWebhook(ctx context.Context, id string) error {
+	if s.db == nil {
+		s.logger.Infof("Would delete webhook %s", id)
+		return nil
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return errWebhookNotFound
+	}
+
+	var deletedID string
+	err := s.db.QueryRow(ctx, `DELETE FROM webhooks WHERE id = $1 AND tenant_id = $2 RETURNING id`,
+		id, auth.TenantFromContext(ctx)).Scan(&deletedID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errWebhookNotFound
+	}
+	return err
+}
+
+// webhookDeliveryLimit caps the deliveries listed for a webhook
+const webhookDeliveryLimit = 50
+
+// listWebhookDeliveries returns the webhook's most recent deliveries with
+// their attempts
+func (s *Service) listWebhookDeliveries(ctx context.Context, webhookID string) ([]*WebhookDelivery, error) {
+	deliveries := []*WebhookDelivery{}
+	if s.db == nil {
+		return deliveries, nil
+	}
+	if _, err := uuid.Parse(webhookID); err != nil {
+		return nil, errWebhookNotFound
+	}
+
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1 AND tenant_id = $2)`,
+		webhookID, auth.TenantFromContext(ctx)).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errWebhookNotFound
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT id, webhook_id, event_type, event_id, status, attempts,
+			CASE WHEN status = 'pending' THEN next_attempt_at END, created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2`, webhookID, webhookDeliveryLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*WebhookDelivery)
+	ids := []string{}
+	for rows.Next() {
+		delivery := &WebhookDelivery{AttemptLog: []*WebhookDeliveryAttempt{}}
+		err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventType, &delivery.EventID, &delivery.Status,
+			&delivery.Attempts, &delivery.NextAttemptAt, &delivery.CreatedAt, &delivery.DeliveredAt)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+		byID[delivery.ID] = delivery
+		ids = append(ids, delivery.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return deliveries, nil
+	}
+
+	attempts, err := s.db.Query(ctx, `
+		SELECT delivery_id, attempt, COALESCE(status_code, 0), COALESCE(error, ''), duration_ms, attempted_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = ANY($1)
+		ORDER BY delivery_id, attempt`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer attempts.Close()
+
+	for attempts.Next() {
+		var deliveryID string
+		var attempt WebhookDeliveryAttempt
+		err := attempts.Scan(&deliveryID, &attempt.Attempt, &attempt.StatusCode, &attempt.Error, &attempt.DurationMS, &attempt.AttemptedAt)
+		if err != nil {
+			return nil, err
+		}
+		if delivery := byID[deliveryID]; delivery != nil {
+			delivery.AttemptLog = append(delivery.AttemptLog, &attempt)
+		}
+	}
+	return deliveries, attempts.Err()
+}
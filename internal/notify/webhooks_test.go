@@ -0,0 +1,387 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// webhookEndpoint is a webhook receiver that answers with the queued
+// statuses in turn, then 204, recording each request it gets
+type webhookEndpoint struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (e *webhookEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests = append(e.requests, r)
+	e.bodies = append(e.bodies, body)
+	if len(e.statuses) > 0 {
+		status := e.statuses[0]
+		e.statuses = e.statuses[1:]
+		http.Error(w, "endpoint unavailable", status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (e *webhookEndpoint) calls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.requests)
+}
+
+// verifySignature checks a delivery's signature as a receiver would
+func verifySignature(secret string, r *http.Request, body []byte) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(r.Header.Get(WebhookHeaderTimestamp) + "."))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(r.Header.Get(WebhookHeaderSignature)), []byte(want))
+}
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"id":"evt-1"}`)
+	const want = "sha256=5056f09710e0bebdbcd623bb1a7714db4eac94f18745b31b96dd55a69f444e14"
+	if got := signWebhook("whsec_test", "1700000000", body); got != want {
+		t.Fatalf("signature = %s, want %s", got, want)
+	}
+
+	// Any change to the secret, timestamp, or body changes the signature
+	for name, got := range map[string]string{
+		"secret":    signWebhook("whsec_other", "1700000000", body),
+		"timestamp": signWebhook("whsec_test", "1700000001", body),
+		"body":      signWebhook("whsec_test", "1700000000", []byte(`{"id":"evt-2"}`)),
+	} {
+		if got == want {
+			t.Errorf("signature unchanged by a different %s", name)
+		}
+	}
+}
+
+func TestPostWebhookSignsDelivery(t *testing.T) {
+	s, _ := newTestService(t)
+	endpoint := &webhookEndpoint{}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	delivery := &pendingDelivery{
+		ID:        uuid.New().String(),
+		URL:       server.URL,
+		Secret:    "whsec_test",
+		EventType: WebhookEventRedemptionCompleted,
+		EventID:   "evt-1",
+		Payload:   []byte(`{"id":"evt-1","type":"redemption.completed"}`),
+	}
+	sent := time.Unix(1700000000, 0)
+	status, err := s.postWebhook(context.Background(), delivery, sent)
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("postWebhook = %d, %v; want %d", status, err, http.StatusNoContent)
+	}
+
+	r, body := endpoint.requests[0], endpoint.bodies[0]
+	if !bytes.Equal(body, delivery.Payload) || r.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("posted %q as %s, want the payload as JSON", body, r.Header.Get("Content-Type"))
+	}
+	if r.Header.Get(WebhookHeaderEvent) != delivery.EventType || r.Header.Get(WebhookHeaderDelivery) != delivery.ID ||
+		r.Header.Get(WebhookHeaderTimestamp) != strconv.FormatInt(sent.Unix(), 10) {
+		t.Fatalf("headers = %v, want the event, delivery, and timestamp", r.Header)
+	}
+	if !verifySignature(delivery.Secret, r, body) {
+		t.Fatalf("signature %s does not verify", r.Header.Get(WebhookHeaderSignature))
+	}
+	if verifySignature("whsec_other", r, body) {
+		t.Fatal("signature verifies with another secret")
+	}
+}
+
+func TestPostWebhookFailures(t *testing.T) {
+	s, _ := newTestService(t)
+	endpoint := &webhookEndpoint{statuses: []int{http.StatusServiceUnavailable}}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(server.URL, http.StatusFound))
+	defer redirect.Close()
+
+	delivery := &pendingDelivery{ID: uuid.New().String(), URL: server.URL, Secret: "whsec_test", Payload: []byte(`{}`)}
+	status, err := s.postWebhook(context.Background(), delivery, time.Now())
+	if status != http.StatusServiceUnavailable || err == nil || !strings.Contains(err.Error(), "endpoint unavailable") {
+		t.Fatalf("failing endpoint: postWebhook = %d, %v; want 503 with the response body", status, err)
+	}
+
+	// Redirects are reported rather than followed
+	delivery.URL = redirect.URL
+	if status, err := s.postWebhook(context.Background(), delivery, time.Now()); status != http.StatusFound || err == nil {
+		t.Fatalf("redirect: postWebhook = %d, %v; want a failed 302", status, err)
+	}
+	if endpoint.calls() != 1 {
+		t.Fatalf("endpoint called %d times, want the redirect not followed", endpoint.calls())
+	}
+
+	server.Close()
+	delivery.URL = server.URL
+	if status, err := s.postWebhook(context.Background(), delivery, time.Now()); status != 0 || err == nil {
+		t.Fatalf("unreachable endpoint: postWebhook = %d, %v; want no status and an error", status, err)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	retry := config.RetryConfig{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := webhookRetryDelay(tt.attempt, retry); got < tt.want/2 || got > tt.want {
+				t.Fatalf("attempt %d: delay = %s, want between %s and %s", tt.attempt, got, tt.want/2, tt.want)
+			}
+		}
+	}
+	if got := webhookRetryDelay(3, config.RetryConfig{}); got != 0 {
+		t.Fatalf("delay without a base = %s, want 0", got)
+	}
+}
+
+// serveWebhooks sends a request with a JSON body through s's routes as role
+func serveWebhooks(t *testing.T, s *Service, method, path, role string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	tok, err := s.jwtManager.GenerateToken(uuid.New().String(), "admin@example.com", role)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+tok)
+
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateWebhook(t *testing.T) {
+	s, _ := newTestService(t)
+
+	rec := serveWebhooks(t, s, http.MethodPost, "/v1/webhooks", auth.RoleAdmin, WebhookRequest{URL: "https://partner.example.com/hooks"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	var webhook Webhook
+	if err := json.NewDecoder(rec.Body).Decode(&webhook); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !strings.HasPrefix(webhook.Secret, webhookSecretPrefix) || len(webhook.Secret) != len(webhookSecretPrefix)+64 {
+		t.Fatalf("secret = %q, want a random %s secret", webhook.Secret, webhookSecretPrefix)
+	}
+	if len(webhook.Events) != len(webhookEvents) {
+		t.Fatalf("events = %v, want every event by default", webhook.Events)
+	}
+
+	tests := []struct {
+		name string
+		role string
+		req  WebhookRequest
+		want int
+	}{
+		{"users may not register", "user", WebhookRequest{URL: "https://partner.example.com/hooks"}, http.StatusForbidden},
+		{"relative url", auth.RoleAdmin, WebhookRequest{URL: "/hooks"}, http.StatusUnprocessableEntity},
+		{"other scheme", auth.RoleAdmin, WebhookRequest{URL: "ftp://partner.example.com/hooks"}, http.StatusUnprocessableEntity},
+		{"unknown event", auth.RoleAdmin, WebhookRequest{URL: "https://partner.example.com/hooks", Events: []string{"points.earned"}}, http.StatusUnprocessableEntity},
+		{"repeated event", auth.RoleAdmin, WebhookRequest{URL: "https://partner.example.com/hooks", Events: []string{WebhookEventRedemptionFailed, WebhookEventRedemptionFailed}}, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serveWebhooks(t, s, http.MethodPost, "/v1/webhooks", tt.role, tt.req); rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+// registerWebhook saves a webhook at url for eventTypes in ctx's tenant
+func registerWebhook(t *testing.T, ctx context.Context, s *Service, url string, eventTypes ...string) *Webhook {
+	t.Helper()
+
+	webhook := &Webhook{
+		ID:        uuid.New().String(),
+		TenantID:  auth.TenantFromContext(ctx),
+		URL:       url,
+		Events:    eventTypes,
+		Secret:    webhookSecretPrefix + "test",
+		CreatedAt: time.Now(),
+	}
+	if err := s.saveWebhook(ctx, webhook); err != nil {
+		t.Fatalf("failed to save webhook: %v", err)
+	}
+	return webhook
+}
+
+// webhookDelivery returns the one delivery queued for webhook
+func webhookDelivery(t *testing.T, ctx context.Context, s *Service, webhook *Webhook) *WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := s.listWebhookDeliveries(ctx, webhook.ID)
+	if err != nil {
+		t.Fatalf("failed to list deliveries: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("webhook has %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+// withWebhookRetry makes failed deliveries due again at once, up to maxAttempts
+func withWebhookRetry(maxAttempts int) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Notify.Webhooks.Retry = config.RetryConfig{MaxAttempts: maxAttempts}
+	}
+}
+
+func TestWebhookDeliveryRetriesFailingEndpoint(t *testing.T) {
+	s, _ := newTestService(t, withWebhookRetry(5))
+	ctx := auth.WithTenant(context.Background(), withTestDB(t, s))
+	endpoint := &webhookEndpoint{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	webhook := registerWebhook(t, ctx, s, server.URL, WebhookEventRedemptionCompleted)
+	other := registerWebhook(t, ctx, s, server.URL, WebhookEventRedemptionFailed)
+	event := []byte(`{"event_id":"` + uuid.New().String() + `","redemption_id":"r-1"}`)
+
+	// A redelivered event is queued once, and only for subscribed webhooks
+	for i := 0; i < 2; i++ {
+		if err := s.queueWebhookDeliveries(ctx, WebhookEventRedemptionCompleted, event); err != nil {
+			t.Fatalf("failed to queue deliveries: %v", err)
+		}
+	}
+	if deliveries, err := s.listWebhookDeliveries(ctx, other.ID); err != nil || len(deliveries) != 0 {
+		t.Fatalf("unsubscribed webhook has %d deliveries (%v), want none", len(deliveries), err)
+	}
+
+	// Two failures, then success on the third attempt
+	for i := 0; i < 3; i++ {
+		if _, err := s.deliverWebhooks(ctx); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+	if endpoint.calls() != 3 {
+		t.Fatalf("endpoint called %d times, want 3", endpoint.calls())
+	}
+	for i, r := range endpoint.requests {
+		if !verifySignature(webhook.Secret, r, endpoint.bodies[i]) {
+			t.Fatalf("attempt %d signature does not verify", i+1)
+		}
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal(endpoint.bodies[0], &payload); err != nil || payload.Type != WebhookEventRedemptionCompleted || !bytes.Equal(payload.Data, event) {
+		t.Fatalf("payload = %s, want the completed event", endpoint.bodies[0])
+	}
+
+	delivery := webhookDelivery(t, ctx, s, webhook)
+	if delivery.Status != deliveryDelivered || delivery.Attempts != 3 || delivery.DeliveredAt == nil || delivery.NextAttemptAt != nil {
+		t.Fatalf("delivery = %+v, want delivered on attempt 3", delivery)
+	}
+	wantCodes := []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusNoContent}
+	if len(delivery.AttemptLog) != len(wantCodes) {
+		t.Fatalf("attempt log has %d entries, want %d", len(delivery.AttemptLog), len(wantCodes))
+	}
+	for i, attempt := range delivery.AttemptLog {
+		failed := i < 2
+		if attempt.Attempt != i+1 || attempt.StatusCode != wantCodes[i] || (attempt.Error != "") != failed {
+			t.Errorf("attempt log %d = %+v, want status %d", i, attempt, wantCodes[i])
+		}
+	}
+
+	// Nothing is left to send
+	if sent, err := s.deliverWebhooks(ctx); err != nil || sent != 0 {
+		t.Fatalf("deliverWebhooks after delivery = %d, %v; want nothing sent", sent, err)
+	}
+}
+
+func TestWebhookDeliveryGivesUp(t *testing.T) {
+	s, _ := newTestService(t, withWebhookRetry(2))
+	ctx := auth.WithTenant(context.Background(), withTestDB(t, s))
+	endpoint := &webhookEndpoint{statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	webhook := registerWebhook(t, ctx, s, server.URL, WebhookEventRedemptionFailed)
+	if err := s.queueWebhookDeliveries(ctx, WebhookEventRedemptionFailed, []byte(`{"event_id":"`+uuid.New().String()+`"}`)); err != nil {
+		t.Fatalf("failed to queue deliveries: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.deliverWebhooks(ctx); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+
+	if endpoint.calls() != 2 {
+		t.Fatalf("endpoint called %d times, want max_attempts 2", endpoint.calls())
+	}
+	delivery := webhookDelivery(t, ctx, s, webhook)
+	if delivery.Status != deliveryFailed || delivery.Attempts != 2 || delivery.DeliveredAt != nil || len(delivery.AttemptLog) != 2 {
+		t.Fatalf("delivery = %+v, want failed after 2 logged attempts", delivery)
+	}
+}
+
+func TestWebhookDeliveryBacksOff(t *testing.T) {
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.Notify.Webhooks.Retry = config.RetryConfig{MaxAttempts: 5, BaseDelay: time.Hour}
+	})
+	ctx := auth.WithTenant(context.Background(), withTestDB(t, s))
+	endpoint := &webhookEndpoint{statuses: []int{http.StatusInternalServerError}}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	webhook := registerWebhook(t, ctx, s, server.URL, WebhookEventRedemptionCompleted)
+	if err := s.queueWebhookDeliveries(ctx, WebhookEventRedemptionCompleted, []byte(`{"event_id":"`+uuid.New().String()+`"}`)); err != nil {
+		t.Fatalf("failed to queue deliveries: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.deliverWebhooks(ctx); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+
+	// The failed delivery waits out its backoff instead of being sent again
+	if endpoint.calls() != 1 {
+		t.Fatalf("endpoint called %d times, want 1 before the backoff ends", endpoint.calls())
+	}
+	delivery := webhookDelivery(t, ctx, s, webhook)
+	if delivery.Status != deliveryPending || delivery.NextAttemptAt == nil || time.Until(*delivery.NextAttemptAt) < 29*time.Minute {
+		t.Fatalf("delivery = %+v, want pending for at least half the base delay", delivery)
+	}
+}
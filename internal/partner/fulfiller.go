@@ -0,0 +1,24 @@
+package partner
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Fulfiller fulfills a redeemed benefit with its partner and returns the
+// partner's reference for it. An error means nothing was fulfilled, so the
+// request may be retried.
+type Fulfiller interface {
+	Fulfill(ctx context.Context, req *FulfillmentRequest) (partnerRef string, err error)
+}
+
+// SimulatedFulfiller fulfills every request without contacting a partner.
+// It is the default until partner APIs are integrated.
+type SimulatedFulfiller struct{}
+
+// Fulfill returns a new reference prefixed with the partner's code
+func (SimulatedFulfiller) Fulfill(ctx context.Context, req *FulfillmentRequest) (string, error) {
+	return strings.ToUpper(req.Partner) + "-" + uuid.New().String()[:8], nil
+}
@@ -1,319 +1,217 @@
-package auth
+package partner
 
 import (
-	"context"
-	"database/sql"
-	"encoding/json"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
-	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
-// Service represents the authentication service
+// StatusFulfilled is the status of a completed fulfillment
+const StatusFulfilled = "fulfilled"
+
+// Service represents the partner gateway service
 type Service struct {
 	config     *config.Config
 	logger     *logrus.Logger
-	db         *database.PostgresDB
 	jwtManager *auth.JWTManager
+	fulfiller  Fulfiller
+
+	mu           sync.RWMutex
+	fulfillments map[string]*Fulfillment // keyed by redemption ID
+	byRef        map[string]*Fulfillment // keyed by partner reference
 }
 
-// User represents a user in the system
-type User struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	Role         string    `json:"role"`
-	FirstName    *string   `json:"first_name,omitempty"`
-	LastName     *string   `json:"last_name,omitempty"`
-	Phone        *string   `json:"phone,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+// FulfillmentRequest represents a request to fulfill a redeemed benefit with a partner
+type FulfillmentRequest struct {
+	RedemptionID string           `json:"redemption_id" validate:"required"`
+	Partner      string           `json:"partner" validate:"required"`
+	BenefitID    string           `json:"benefit_id" validate:"required"`
+	BenefitType  string           `json:"benefit_type" validate:"required"`
+	GiftCard     *GiftCardPayload `json:"gift_card,omitempty"`
+	Travel       *TravelPayload   `json:"travel,omitempty"`
+	Experience   *EventPayload    `json:"experience,omitempty"`
+	CashBack     *CashBackPayload `json:"cash_back,omitempty"`
+}
+
+// GiftCardPayload holds gift card fulfillment data
+type GiftCardPayload struct {
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Recipient string `json:"recipient,omitempty"`
+}
+
+// TravelPayload holds travel booking data
+type TravelPayload struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Travelers int       `json:"travelers"`
 }
 
-// RegisterRequest represents a user registration request
-type RegisterRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8"`
+// EventPayload holds experience booking data
+type EventPayload struct {
+	EventDate time.Time `json:"event_date"`
 }
 
-// LoginRequest represents a user login request
-type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+// CashBackPayload holds cash back payout data
+type CashBackPayload struct {
+	Amount     int64  `json:"amount"`
+	Currency   string `json:"currency"`
+	AccountRef string `json:"account_ref"`
 }
 
-// AuthResponse represents an authentication response
-type AuthResponse struct {
-	AccessToken string `json:"access_token"`
-	User        *User  `json:"user"`
+// Fulfillment represents the outcome of a partner fulfillment
+type Fulfillment struct {
+	ID           string    `json:"id"`
+	RedemptionID string    `json:"redemption_id"`
+	Partner      string    `json:"partner"`
+	BenefitID    string    `json:"benefit_id"`
+	BenefitType  string    `json:"benefit_type"`
+	PartnerRef   string    `json:"partner_ref"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
-// NewService creates a new authentication service
+// PartnerResponse represents a partner gateway response
+type PartnerResponse struct {
+	Success bool        `json:"success"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// NewService creates a new partner gateway service
 func NewService(cfg *config.Config, logger *logrus.Logger) *Service {
 	// Initialize JWT manager
-	jwtConfig := &auth.JWTConfig{
-		Secret:     cfg.Security.JWT.Secret,
-		Issuer:     cfg.Security.JWT.Issuer,
-		Audience:   cfg.Security.JWT.Audience,
-		Expiration: cfg.Security.JWT.Expiration,
-	}
-	jwtManager := auth.NewJWTManager(jwtConfig)
+	jwtManager := auth.NewJWTManagerFromConfig(cfg)
 
 	return &Service{
-		config:     cfg,
-		logger:     logger,
-		jwtManager: jwtManager,
+		config:       cfg,
+		logger:       logger,
+		jwtManager:   jwtManager,
+		fulfiller:    SimulatedFulfiller{},
+		fulfillments: make(map[string]*Fulfillment),
+		byRef:        make(map[string]*Fulfillment),
 	}
 }
 
-// SetDatabase sets the database connection
-func (s *Service) SetDatabase(db *database.PostgresDB) {
-	s.db = db
+// SetFulfiller replaces the simulated fulfiller with a partner integration.
+// It must be called before the service handles requests.
+func (s *Service) SetFulfiller(fulfiller Fulfiller) {
+	s.fulfiller = fulfiller
 }
 
-// Routes returns the authentication service routes
-func (s *Service) Routes(r *chi.Mux) {
-	r.Route("/v1/auth", func(r chi.Router) {
-		r.Post("/register", s.Register)
-		r.Post("/login", s.Login)
-		r.Get("/me", s.AuthMiddleware(s.GetProfile))
+// Routes returns the partner gateway routes
+func (s *Service) Routes(r chi.Router) {
+	authOpts := []authmw.Option{authmw.WithLogger(s.logger)}
+
+	r.Route("/v1/fulfill", func(r chi.Router) {
+		r.Use(authmw.RequireJWT(s.jwtManager, authOpts...))
+		r.Use(authmw.RequireRole(auth.RoleService, authOpts...))
+
+		r.Post("/", s.Fulfill)
+		r.Get("/{ref}", s.GetFulfillment)
 	})
 }
 
-// Register handles user registration
-func (s *Service) Register(w http.ResponseWriter, r *http.Request) {
-	var req RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+// Fulfill fulfills a benefit with the partner. Requests are idempotent on
+// the redemption ID: a replay returns the original fulfillment. Fulfillments
+// are made one at a time so a replay cannot race the original.
+func (s *Service) Fulfill(w http.ResponseWriter, r *http.Request) {
+	var req FulfillmentRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
 		return
 	}
 
-	// Validate request
-	if req.Email == "" || req.Password == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Email and password are required"})
+	if msg := validatePayload(&req); msg != "" {
+		platformhttp.Error(w, r, http.StatusUnprocessableEntity, platformhttp.ErrCodeUnprocessable, msg)
 		return
 	}
 
-	// Check if user already exists
-	s.logger.Infof("Checking if user with email %s already exists", req.Email)
-	existingUser, err := s.getUserByEmail(r.Context(), req.Email)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			s.logger.Infof("User with email %s does not exist (this is expected for new registrations)", req.Email)
-		} else {
-			s.logger.Errorf("Failed to check existing user: %v", err)
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, map[string]string{"error": "Internal server error"})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.fulfillments[req.RedemptionID]; ok {
+		if existing.BenefitID != req.BenefitID || existing.Partner != req.Partner {
+			platformhttp.Error(w, r, http.StatusConflict, platformhttp.ErrCodeConflict, "Redemption already fulfilled with different parameters")
 			return
 		}
-	}
-
-	if existingUser != nil {
-		render.Status(r, http.StatusConflict)
-		render.JSON(w, r, map[string]string{"error": "User already exists"})
+		render.JSON(w, r, PartnerResponse{Success: true, Message: "Fulfillment already processed", Data: existing})
 		return
 	}
 
-	// Hash password
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	partnerRef, err := s.fulfiller.Fulfill(r.Context(), &req)
 	if err != nil {
-		s.logger.Errorf("Failed to hash password: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
+		s.logger.Errorf("Failed to fulfill redemption %s with partner %s: %v", req.RedemptionID, req.Partner, err)
+		platformhttp.Error(w, r, http.StatusBadGateway, platformhttp.ErrCodeBadGateway, "Partner fulfillment failed")
 		return
 	}
 
-	// Create user
-	userID := uuid.New().String()
-	now := time.Now()
-	user := &User{
-		ID:           userID,
-		Email:        req.Email,
-		PasswordHash: string(passwordHash),
-		Role:         "user",
-		CreatedAt:    now,
-		UpdatedAt:    now,
+	fulfillment := &Fulfillment{
+		ID:           uuid.New().String(),
+		RedemptionID: req.RedemptionID,
+		Partner:      req.Partner,
+		BenefitID:    req.BenefitID,
+		BenefitType:  req.BenefitType,
+		PartnerRef:   partnerRef,
+		Status:       StatusFulfilled,
+		CreatedAt:    time.Now(),
 	}
+	s.fulfillments[fulfillment.RedemptionID] = fulfillment
+	s.byRef[fulfillment.PartnerRef] = fulfillment
 
-	if err := s.createUser(r.Context(), user); err != nil {
-		s.logger.Errorf("Failed to create user: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
-		return
-	}
-
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.Role)
-	if err != nil {
-		s.logger.Errorf("Failed to generate token: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
-		return
-	}
-
-	response := &AuthResponse{
-		AccessToken: token,
-		User:        user,
-	}
+	s.logger.Infof("Fulfilled redemption %s with partner %s (ref %s)", req.RedemptionID, req.Partner, fulfillment.PartnerRef)
 
 	render.Status(r, http.StatusCreated)
-	render.JSON(w, r, response)
+	render.JSON(w, r, PartnerResponse{Success: true, Message: "Benefit fulfilled successfully", Data: fulfillment})
 }
 
-// Login handles user login
-func (s *Service) Login(w http.ResponseWriter, r *http.Request) {
-	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
-		return
-	}
-
-	// Validate request
-	if req.Email == "" || req.Password == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Email and password are required"})
-		return
-	}
-
-	// Get user by email
-	user, err := s.getUserByEmail(r.Context(), req.Email)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Invalid credentials"})
-			return
-		}
-		s.logger.Errorf("Failed to get user: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
-		return
-	}
-
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		render.Status(r, http.StatusUnauthorized)
-		render.JSON(w, r, map[string]string{"error": "Invalid credentials"})
-		return
-	}
-
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.Role)
-	if err != nil {
-		s.logger.Errorf("Failed to generate token: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
-		return
-	}
-
-	response := &AuthResponse{
-		AccessToken: token,
-		User:        user,
-	}
+// GetFulfillment returns the status of a fulfillment by partner reference
+func (s *Service) GetFulfillment(w http.ResponseWriter, r *http.Request) {
+	ref := chi.URLParam(r, "ref")
 
-	render.JSON(w, r, response)
-}
+	s.mu.RLock()
+	fulfillment, ok := s.byRef[ref]
+	s.mu.RUnlock()
 
-// GetProfile returns the current user's profile
-func (s *Service) GetProfile(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
-
-	user, err := s.getUserByID(r.Context(), userID)
-	if err != nil {
-		s.logger.Errorf("Failed to get user profile: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Internal server error"})
+	if !ok {
+		platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Fulfillment not found")
 		return
 	}
 
-	render.JSON(w, r, user)
+	render.JSON(w, r, PartnerResponse{Success: true, Message: "Fulfillment retrieved successfully", Data: fulfillment})
 }
 
-// AuthMiddleware validates JWT tokens
-func (s *Service) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Authorization header required"})
-			return
+// validatePayload checks the request carries the payload its benefit type needs
+func validatePayload(req *FulfillmentRequest) string {
+	switch req.BenefitType {
+	case "gift_card":
+		if req.GiftCard == nil || req.GiftCard.Amount <= 0 || req.GiftCard.Currency == "" {
+			return "Gift card amount and currency are required"
 		}
-
-		// Extract token from "Bearer <token>"
-		if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Invalid authorization header format"})
-			return
+	case "travel":
+		if req.Travel == nil || req.Travel.Travelers < 1 || !req.Travel.End.After(req.Travel.Start) {
+			return "Travel dates and travelers are required"
 		}
-
-		token := authHeader[7:]
-		claims, err := s.jwtManager.ValidateToken(token)
-		if err != nil {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Invalid token"})
-			return
+	case "experience":
+		if req.Experience == nil || req.Experience.EventDate.IsZero() {
+			return "Experience event date is required"
 		}
-
-		// Add user info to context
-		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
-		ctx = context.WithValue(ctx, "user_email", claims.Email)
-		ctx = context.WithValue(ctx, "user_role", claims.Role)
-
-		next.ServeHTTP(w, r.WithContext(ctx))
-	}
-}
-
-// Database helper methods
-func (s *Service) createUser(ctx context.Context, user *User) error {
-	query := `
-		INSERT INTO users (id, email, password_hash, role, first_name, last_name, phone, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`
-
-	err := s.db.Exec(ctx, query, user.ID, user.Email, user.PasswordHash, user.Role, user.FirstName, user.LastName, user.Phone, user.CreatedAt, user.UpdatedAt)
-	return err
-}
-
-func (s *Service) getUserByEmail(ctx context.Context, email string) (*User, error) {
-	query := `SELECT id, email, password_hash, role, first_name, last_name, phone, created_at, updated_at FROM users WHERE email = $1`
-
-	s.logger.Infof("Executing query: %s with email: %s", query, email)
-
-	var user User
-	err := s.db.QueryRow(ctx, query, email).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.FirstName, &user.LastName, &user.Phone, &user.CreatedAt, &user.UpdatedAt,
-	)
-
-	if err != nil {
-		s.logger.Errorf("Query failed with error: %v", err)
-		return nil, err
-	}
-
-	s.logger.Infof("Successfully found user: %s", user.Email)
-	return &user, nil
-}
-
-func (s *Service) getUserByID(ctx context.Context, userID string) (*User, error) {
-	query := `SELECT id, email, password_hash, role, first_name, last_name, phone, created_at, updated_at FROM users WHERE id = $1`
-
-	var user User
-	err := s.db.QueryRow(ctx, query, userID).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Role, &user.FirstName, &user.LastName, &user.Phone, &user.CreatedAt, &user.UpdatedAt,
-	)
-
-	if err != nil {
-		return nil, err
+	case "cash_back":
+		if req.CashBack == nil || req.CashBack.Amount <= 0 || req.CashBack.AccountRef == "" {
+			return "Cash back amount and account reference are required"
+		}
+	case "donation":
+	default:
+		return "Unsupported benefit type"
 	}
-
-	return &user, nil
+	return ""
 }
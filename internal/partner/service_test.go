@@ -0,0 +1,265 @@
+package partner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestService creates a partner gateway service
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+
+	cfg, err := config.Load("partner-gateway")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Redis.Addr = ""
+	cfg.Security.JWT.Revocation = false
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewService(cfg, logger)
+}
+
+// serviceToken issues a token for the redemption service
+func serviceToken(t *testing.T, s *Service) string {
+	t.Helper()
+
+	tok, err := s.jwtManager.GenerateServiceToken("redemption-svc")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	return tok
+}
+
+// serve sends a request with a JSON body, if any, through s's routes
+func serve(s *Service, method, path, tok string, body interface{}) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeFulfillment reads the fulfillment from a successful response
+func decodeFulfillment(t *testing.T, rec *httptest.ResponseRecorder) *Fulfillment {
+	t.Helper()
+
+	var body struct {
+		Data Fulfillment `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return &body.Data
+}
+
+// giftCardRequest returns a valid gift card fulfillment request
+func giftCardRequest() FulfillmentRequest {
+	return FulfillmentRequest{
+		RedemptionID: uuid.New().String(),
+		Partner:      "giftco",
+		BenefitID:    uuid.New().String(),
+		BenefitType:  "gift_card",
+		GiftCard:     &GiftCardPayload{Amount: 25, Currency: "USD"},
+	}
+}
+
+func TestFulfill(t *testing.T) {
+	s := newTestService(t)
+	tok := serviceToken(t, s)
+
+	rec := serve(s, http.MethodPost, "/v1/fulfill", tok, giftCardRequest())
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	fulfillment := decodeFulfillment(t, rec)
+	if fulfillment.Status != StatusFulfilled || !strings.HasPrefix(fulfillment.PartnerRef, "GIFTCO-") {
+		t.Fatalf("fulfillment = %+v, want fulfilled with a GIFTCO reference", fulfillment)
+	}
+
+	// The status endpoint finds it by partner reference
+	rec = serve(s, http.MethodGet, "/v1/fulfill/"+fulfillment.PartnerRef, tok, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status lookup: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if got := decodeFulfillment(t, rec); got.ID != fulfillment.ID {
+		t.Fatalf("status lookup = %+v, want %+v", got, fulfillment)
+	}
+	if rec := serve(s, http.MethodGet, "/v1/fulfill/GIFTCO-unknown", tok, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown reference: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestFulfillIsIdempotent(t *testing.T) {
+	s := newTestService(t)
+	tok := serviceToken(t, s)
+	req := giftCardRequest()
+
+	first := serve(s, http.MethodPost, "/v1/fulfill", tok, req)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", first.Code, http.StatusCreated, first.Body)
+	}
+	original := decodeFulfillment(t, first)
+
+	// A retried request for the same redemption returns the original
+	replay := serve(s, http.MethodPost, "/v1/fulfill", tok, req)
+	if replay.Code != http.StatusOK {
+		t.Fatalf("replay: status = %d, want %d: %s", replay.Code, http.StatusOK, replay.Body)
+	}
+	if got := decodeFulfillment(t, replay); got.ID != original.ID || got.PartnerRef != original.PartnerRef {
+		t.Fatalf("replay = %+v, want %+v", got, original)
+	}
+
+	// Reusing the redemption ID for another benefit is a conflict
+	conflicting := req
+	conflicting.BenefitID = uuid.New().String()
+	if rec := serve(s, http.MethodPost, "/v1/fulfill", tok, conflicting); rec.Code != http.StatusConflict {
+		t.Fatalf("conflicting replay: status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body)
+	}
+}
+
+// fulfillerFunc adapts a function to the Fulfiller interface
+type fulfillerFunc func(ctx context.Context, req *FulfillmentRequest) (string, error)
+
+func (f fulfillerFunc) Fulfill(ctx context.Context, req *FulfillmentRequest) (string, error) {
+	return f(ctx, req)
+}
+
+func TestFulfillUsesFulfiller(t *testing.T) {
+	s := newTestService(t)
+	tok := serviceToken(t, s)
+	req := giftCardRequest()
+
+	calls := 0
+	s.SetFulfiller(fulfillerFunc(func(ctx context.Context, got *FulfillmentRequest) (string, error) {
+		calls++
+		if got.RedemptionID != req.RedemptionID {
+			t.Errorf("fulfiller got redemption %s, want %s", got.RedemptionID, req.RedemptionID)
+		}
+		if calls == 1 {
+			return "", errors.New("partner unavailable")
+		}
+		return "PARTNER-REF-1", nil
+	}))
+
+	// A failed fulfillment is an outage the redemption service retries
+	rec := serve(s, http.MethodPost, "/v1/fulfill", tok, req)
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("failed fulfillment: status = %d, want %d: %s", rec.Code, http.StatusBadGateway, rec.Body)
+	}
+
+	rec = serve(s, http.MethodPost, "/v1/fulfill", tok, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("retry: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	if got := decodeFulfillment(t, rec); got.PartnerRef != "PARTNER-REF-1" {
+		t.Fatalf("partner reference = %q, want the fulfiller's", got.PartnerRef)
+	}
+
+	// A replay is answered without fulfilling again
+	if rec := serve(s, http.MethodPost, "/v1/fulfill", tok, req); rec.Code != http.StatusOK || calls != 2 {
+		t.Fatalf("replay: status = %d after %d fulfiller calls, want %d after 2", rec.Code, calls, http.StatusOK)
+	}
+}
+
+func TestFulfillRejectsInvalidRequests(t *testing.T) {
+	s := newTestService(t)
+	tok := serviceToken(t, s)
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		edit     func(*FulfillmentRequest)
+		want     int
+		wantCode platformhttp.ErrorCode
+	}{
+		{"missing redemption", func(r *FulfillmentRequest) { r.RedemptionID = "" }, http.StatusUnprocessableEntity, platformhttp.ErrCodeValidationFailed},
+		{"unsupported type", func(r *FulfillmentRequest) { r.BenefitType = "timeshare" }, http.StatusUnprocessableEntity, platformhttp.ErrCodeUnprocessable},
+		{"gift card without amount", func(r *FulfillmentRequest) { r.GiftCard.Amount = 0 }, http.StatusUnprocessableEntity, platformhttp.ErrCodeUnprocessable},
+		{"inverted travel dates", func(r *FulfillmentRequest) {
+			r.BenefitType = "travel"
+			r.Travel = &TravelPayload{Start: now.Add(48 * time.Hour), End: now.Add(24 * time.Hour), Travelers: 2}
+		}, http.StatusUnprocessableEntity, platformhttp.ErrCodeUnprocessable},
+		{"cash back without account", func(r *FulfillmentRequest) {
+			r.BenefitType = "cash_back"
+			r.CashBack = &CashBackPayload{Amount: 10, Currency: "USD"}
+		}, http.StatusUnprocessableEntity, platformhttp.ErrCodeUnprocessable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := giftCardRequest()
+			tt.edit(&req)
+
+			rec := serve(s, http.MethodPost, "/v1/fulfill", tok, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			var body platformhttp.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.Code != tt.wantCode {
+				t.Fatalf("code = %q, want %q", body.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFulfillRequiresServiceToken(t *testing.T) {
+	s := newTestService(t)
+	userToken, err := s.jwtManager.GenerateToken(uuid.New().String(), "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	adminToken, err := s.jwtManager.GenerateToken(uuid.New().String(), "admin@example.com", auth.RoleAdmin)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		tok  string
+		want int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"user", userToken, http.StatusForbidden},
+		{"admin", adminToken, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(s, http.MethodPost, "/v1/fulfill", tt.tok, giftCardRequest()); rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package auth
+
+// Actor prefixes distinguish changes made by users from those made by
+// services; see authmw.Actor
+const (
+	ActorUserPrefix    = "user:"
+	ActorServicePrefix = "service:"
+)
@@ -0,0 +1,221 @@
+// Package authmw provides the JWT authentication middleware shared by all services
+package authmw
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorWriter writes an authentication or authorization failure in a
+// service's response format
+type ErrorWriter func(w http.ResponseWriter, r *http.Request, status int, message string)
+
+// Option configures RequireJWT and RequireRole
+type Option func(*options)
+
+type options struct {
+	tenants    auth.TenantScope
+	writeError ErrorWriter
+	logger     *logrus.Logger
+}
+
+// WithLogger logs why tokens are rejected
+func WithLogger(logger *logrus.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// logRejection logs a rejected token with a stable reason field
+func (o *options) logRejection(r *http.Request, reason string, err error) {
+	if o.logger == nil {
+		return
+	}
+	entry := o.logger.WithFields(logrus.Fields{"reason": reason, "path": r.URL.Path})
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Info("Rejected bearer token")
+}
+
+// rejectionReason names the ValidateToken failure for logs
+func rejectionReason(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, auth.ErrTokenIssuer):
+		return "wrong_issuer"
+	case errors.Is(err, auth.ErrTokenAudience):
+		return "wrong_audience"
+	case errors.Is(err, auth.ErrTokenSignature):
+		return "bad_signature"
+	default:
+		return "invalid"
+	}
+}
+
+// WithTenants scopes authenticated requests to the tenant resolved from the
+// token's claims
+func WithTenants(tenants auth.TenantScope) Option {
+	return func(o *options) {
+		o.tenants = tenants
+	}
+}
+
+// WithErrorWriter replaces the default platform error response body
+func WithErrorWriter(writeError ErrorWriter) Option {
+	return func(o *options) {
+		o.writeError = writeError
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		tenants:    auth.NewTenantScope(false, ""),
+		writeError: writeError,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// RequireJWT validates the bearer token in the Authorization header and adds
+// the caller's identity and tenant to the request context, answering 401 if
+// the token is missing or invalid
+func RequireJWT(jwtManager *auth.JWTManager, opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				o.writeError(w, r, http.StatusUnauthorized, "Authorization header required")
+				return
+			}
+
+			// Extract token from "Bearer <token>"
+			if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
+				o.writeError(w, r, http.StatusUnauthorized, "Invalid authorization header format")
+				return
+			}
+
+			claims, err := jwtManager.ValidateToken(authHeader[7:])
+			if err != nil {
+				o.logRejection(r, rejectionReason(err), err)
+				o.writeError(w, r, http.StatusUnauthorized, "Invalid token")
+				return
+			}
+
+			// Fail closed: a revoked token must not be accepted just
+			// because the denylist cannot be read
+			if denylist := jwtManager.Denylist(); denylist != nil {
+				revoked, err := denylist.IsRevoked(r.Context(), claims.ID)
+				if err != nil {
+					o.logRejection(r, "denylist_unavailable", err)
+					o.writeError(w, r, http.StatusServiceUnavailable, "Token revocation check unavailable")
+					return
+				}
+				if revoked {
+					o.logRejection(r, "revoked", nil)
+					o.writeError(w, r, http.StatusUnauthorized, "Token has been revoked")
+					return
+				}
+			}
+
+			tenantID, err := o.tenants.FromClaims(claims, r.Header.Get(auth.TenantHeader))
+			if err != nil {
+				o.writeError(w, r, http.StatusUnauthorized, "Invalid token tenant")
+				return
+			}
+
+			platformhttp.SetRequestUserID(r.Context(), claims.UserID)
+			ctx := auth.WithUser(r.Context(), *claims)
+			ctx = auth.WithTenant(ctx, tenantID)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireRole answers 403 unless the authenticated caller has the given role.
+// It must run after RequireJWT.
+func RequireRole(role string, opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts)
+
+	message := "Service token required"
+	if role != auth.RoleService {
+		message = strings.ToUpper(role[:1]) + role[1:] + " role required"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if callerRole, _ := Role(r.Context()); callerRole != role {
+				o.writeError(w, r, http.StatusForbidden, message)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitKey counts requests against the authenticated user, or the client
+// IP for anonymous requests. Rate limiters keyed by it must run after RequireJWT.
+func RateLimitKey(r *http.Request) string {
+	if userID, ok := UserID(r.Context()); ok {
+		return "user:" + userID
+	}
+	return "ip:" + platformhttp.ClientIP(r)
+}
+
+// UserID returns the authenticated user or service ID
+func UserID(ctx context.Context) (string, bool) {
+	claims, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.UserID, true
+}
+
+// Email returns the authenticated user's email
+func Email(ctx context.Context) (string, bool) {
+	claims, ok := auth.UserFromContext(ctx)
+	if !ok || claims.Email == "" {
+		return "", false
+	}
+	return claims.Email, true
+}
+
+// Role returns the authenticated caller's role
+func Role(ctx context.Context) (string, bool) {
+	claims, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.Role, true
+}
+
+// Actor returns who is acting on a request, e.g. "user:<id>" or
+// "service:redemption-svc". It returns an empty string for unauthenticated
+// requests.
+func Actor(ctx context.Context) string {
+	id, ok := UserID(ctx)
+	if !ok {
+		return ""
+	}
+	if role, _ := Role(ctx); role == auth.RoleService {
+		return auth.ActorServicePrefix + id
+	}
+	return auth.ActorUserPrefix + id
+}
+
+// writeError is the default ErrorWriter
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	platformhttp.Error(w, r, status, platformhttp.CodeForStatus(status), message)
+}
@@ -0,0 +1,253 @@
+package authmw
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+)
+
+// fakeDenylist is an in-memory Denylist that fails every lookup with err, if set
+type fakeDenylist struct {
+	mu      sync.Mutex
+	revoked map[string]bool
+	err     error
+}
+
+func (d *fakeDenylist) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.revoked == nil {
+		d.revoked = make(map[string]bool)
+	}
+	d.revoked[tokenID] = true
+	return nil
+}
+
+func (d *fakeDenylist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return false, d.err
+	}
+	return d.revoked[tokenID], nil
+}
+
+func newTestManager(denylist auth.Denylist) *auth.JWTManager {
+	m := auth.NewJWTManager(&auth.JWTConfig{
+		Secret:     "test-secret-at-least-32-bytes-long",
+		Issuer:     "loyalty-auth",
+		Audience:   "loyalty-services",
+		Expiration: 15 * time.Minute,
+	})
+	m.SetDenylist(denylist)
+	return m
+}
+
+// serve sends a request with authorization through RequireJWT
+func serve(m *auth.JWTManager, authorization string) *httptest.ResponseRecorder {
+	handler := RequireJWT(m)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserID(r.Context()); !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequireJWT(t *testing.T) {
+	tests := []struct {
+		name          string
+		denylist      *fakeDenylist
+		revoke        bool
+		authorization func(token string) string
+		want          int
+	}{
+		{"valid", nil, false, func(tok string) string { return "Bearer " + tok }, http.StatusOK},
+		{"missing header", nil, false, func(string) string { return "" }, http.StatusUnauthorized},
+		{"not a bearer token", nil, false, func(tok string) string { return "Basic " + tok }, http.StatusUnauthorized},
+		{"invalid token", nil, false, func(string) string { return "Bearer not.a.token" }, http.StatusUnauthorized},
+		{"not revoked", &fakeDenylist{}, false, func(tok string) string { return "Bearer " + tok }, http.StatusOK},
+		{"revoked", &fakeDenylist{}, true, func(tok string) string { return "Bearer " + tok }, http.StatusUnauthorized},
+		{"denylist unavailable", &fakeDenylist{err: errors.New("redis down")}, false, func(tok string) string { return "Bearer " + tok }, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var denylist auth.Denylist
+			if tt.denylist != nil {
+				denylist = tt.denylist
+			}
+			m := newTestManager(denylist)
+
+			token, err := m.GenerateToken("user-1", "user@example.com", "user")
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+			if tt.revoke {
+				claims, err := m.ValidateToken(token)
+				if err != nil {
+					t.Fatalf("ValidateToken: %v", err)
+				}
+				if err := m.RevokeToken(context.Background(), claims); err != nil {
+					t.Fatalf("RevokeToken: %v", err)
+				}
+			}
+
+			if rec := serve(m, tt.authorization(token)); rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	m := newTestManager(nil)
+	tests := []struct {
+		role string
+		want int
+	}{
+		{auth.RoleService, http.StatusOK},
+		{auth.RoleAdmin, http.StatusForbidden},
+		{"user", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			token, err := m.GenerateToken("caller-1", "", tt.role)
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+			handler := RequireJWT(m)(RequireRole(auth.RoleService)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestActor(t *testing.T) {
+	m := newTestManager(nil)
+	tests := []struct {
+		name    string
+		subject string
+		role    string
+		want    string
+	}{
+		{"user", "user-1", "user", "user:user-1"},
+		{"admin", "admin-1", auth.RoleAdmin, "user:admin-1"},
+		{"service", "redemption-svc", auth.RoleService, "service:redemption-svc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var token string
+			var err error
+			if tt.role == auth.RoleService {
+				token, err = m.GenerateServiceToken(tt.subject)
+			} else {
+				token, err = m.GenerateToken(tt.subject, "", tt.role)
+			}
+			if err != nil {
+				t.Fatalf("generate token: %v", err)
+			}
+
+			var got string
+			handler := RequireJWT(m)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = Actor(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Fatalf("Actor = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := Actor(context.Background()); got != "" {
+		t.Fatalf("unauthenticated Actor = %q, want empty", got)
+	}
+}
+
+// ctxKey is a string-based key like the ones the typed keys replaced
+type ctxKey string
+
+func TestContextAccessors(t *testing.T) {
+	m := newTestManager(nil)
+	token, err := m.GenerateToken("user-1", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	type identity struct {
+		userID, email, role string
+		ok                  bool
+	}
+	read := func(ctx context.Context) identity {
+		userID, ok := UserID(ctx)
+		email, _ := Email(ctx)
+		role, _ := Role(ctx)
+		return identity{userID, email, role, ok}
+	}
+
+	// Only the protected subrouter sees an identity
+	var got identity
+	r := chi.NewRouter()
+	r.Get("/public", func(w http.ResponseWriter, r *http.Request) { got = read(r.Context()) })
+	r.Group(func(r chi.Router) {
+		r.Use(RequireJWT(m))
+		r.Get("/private", func(w http.ResponseWriter, r *http.Request) { got = read(r.Context()) })
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/private", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if want := (identity{"user-1", "user@example.com", "user", true}); got != want {
+		t.Fatalf("private identity = %+v, want %+v", got, want)
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/public", nil))
+	if got.ok {
+		t.Fatalf("public identity = %+v, want none", got)
+	}
+
+	// Values stored under string keys of the same name are not mistaken for an identity
+	ctx := context.WithValue(context.Background(), ctxKey("user_id"), "user-2")
+	ctx = context.WithValue(ctx, ctxKey("user_role"), auth.RoleAdmin)
+	if got := read(ctx); got.ok || got.role != "" {
+		t.Fatalf("identity from string keys = %+v, want none", got)
+	}
+
+	// A service token has no email
+	service, err := m.GenerateServiceToken("redemption-svc")
+	if err != nil {
+		t.Fatalf("GenerateServiceToken: %v", err)
+	}
+	claims, err := m.ValidateToken(service)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if email, ok := Email(auth.WithUser(context.Background(), *claims)); ok {
+		t.Fatalf("service email = %q, want none", email)
+	}
+}
@@ -0,0 +1,27 @@
+package auth
+
+import "context"
+
+// contextKey keys values this package stores in a request context. Being
+// unexported, it cannot collide with keys set by other packages.
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	tenantKey
+)
+
+// WithUser returns a copy of ctx carrying the authenticated caller's claims
+func WithUser(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, userKey, &claims)
+}
+
+// UserFromContext returns the authenticated caller's claims, or false if the
+// request was not authenticated
+func UserFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(userKey).(*Claims)
+	if !ok || claims.UserID == "" {
+		return nil, false
+	}
+	return claims, true
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUserFromContext(t *testing.T) {
+	if claims, ok := UserFromContext(context.Background()); ok || claims != nil {
+		t.Fatalf("UserFromContext on an empty context = %+v, %v", claims, ok)
+	}
+
+	// A string key with the same name is not mistaken for the caller
+	ctx := context.WithValue(context.Background(), "user_id", "user-1")
+	if _, ok := UserFromContext(ctx); ok {
+		t.Fatal("UserFromContext read a stringly-typed value")
+	}
+
+	if _, ok := UserFromContext(WithUser(context.Background(), Claims{Role: "user"})); ok {
+		t.Fatal("UserFromContext accepted claims without a user ID")
+	}
+
+	ctx = WithUser(context.Background(), Claims{UserID: "user-1", Email: "user@example.com", Role: "user"})
+	claims, ok := UserFromContext(ctx)
+	if !ok || claims.UserID != "user-1" || claims.Email != "user@example.com" || claims.Role != "user" {
+		t.Fatalf("UserFromContext = %+v, %v; want user-1's claims", claims, ok)
+	}
+}
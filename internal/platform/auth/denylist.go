@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+)
+
+// denylistKeyPrefix namespaces revoked token IDs in Redis
+const denylistKeyPrefix = "jwt:denylist:"
+
+// errTokenWithoutID is returned when revoking a token that has no jti
+var errTokenWithoutID = errors.New("token has no ID")
+
+// Denylist records revoked tokens by their jti until they would have expired
+type Denylist interface {
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// RedisDenylist is a Denylist shared by all services through Redis
+type RedisDenylist struct {
+	redis *database.RedisDB
+}
+
+// NewRedisDenylist creates a denylist stored in Redis
+func NewRedisDenylist(redis *database.RedisDB) *RedisDenylist {
+	return &RedisDenylist{redis: redis}
+}
+
+// NewDenylist returns the Redis denylist configured by cfg, or nil when token
+// revocation is disabled
+func NewDenylist(cfg *config.Config) Denylist {
+	if !cfg.Security.JWT.Revocation {
+		return nil
+	}
+	return NewRedisDenylist(database.OpenRedisDB(&database.RedisConfig{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+		PoolSize: cfg.Redis.PoolSize,
+	}))
+}
+
+// Revoke denylists a token until it expires. Tokens that have already
+// expired are rejected anyway, so they are not stored.
+func (d *RedisDenylist) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" {
+		return errTokenWithoutID
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return d.redis.SetEX(ctx, denylistKeyPrefix+tokenID, "1", ttl)
+}
+
+// IsRevoked reports whether a token has been revoked
+func (d *RedisDenylist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return d.redis.Exists(ctx, denylistKeyPrefix+tokenID)
+}
+
+// RevokeToken denylists the token the claims were read from
+func (m *JWTManager) RevokeToken(ctx context.Context, claims *Claims) error {
+	if m.denylist == nil {
+		return errors.New("token revocation is not enabled")
+	}
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return m.denylist.Revoke(ctx, claims.ID, expiresAt)
+}
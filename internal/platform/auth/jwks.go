@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// jwksMinRefreshInterval limits refreshes triggered by unknown kids
+const jwksMinRefreshInterval = 30 * time.Second
+
+var (
+	// ErrJWKSUnavailable is returned when keys cannot be refreshed and the stale window is exceeded
+	ErrJWKSUnavailable = errors.New("jwks unavailable")
+	// ErrKeyNotFound is returned when no published key matches the requested kid
+	ErrKeyNotFound = errors.New("signing key not found")
+)
+
+// JWK represents a single RSA JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKSet represents a JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKSConfig holds JWKS client configuration
+type JWKSConfig struct {
+	URL string
+	// TTL is how long fetched keys are used before a refresh is attempted
+	TTL time.Duration
+	// StaleGrace is how long past the TTL the last-known-good keys keep
+	// validating tokens while refreshes fail
+	StaleGrace time.Duration
+	HTTPClient *http.Client
+}
+
+// JWKSClient fetches and caches RSA public keys from a JWKS endpoint
+type JWKSClient struct {
+	config JWKSConfig
+	logger *logrus.Logger
+	now    func() time.Time
+
+	refreshMu sync.Mutex
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	degraded  bool
+}
+
+// NewJWKSClient creates a new JWKS client
+func NewJWKSClient(config JWKSConfig, logger *logrus.Logger) *JWKSClient {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	if config.TTL <= 0 {
+		config.TTL = 5 * time.Minute
+	}
+
+	return &JWKSClient{
+		config: config,
+		logger: logger,
+		now:    time.Now,
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+// Key returns the public key for kid, refreshing the key set when it is
+// older than the TTL. If a refresh fails, the last-known-good keys are used
+// until the stale grace window is exceeded.
+func (c *JWKSClient) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.mu.RLock()
+	key, found := c.keys[kid]
+	fetchedAt := c.fetchedAt
+	c.mu.RUnlock()
+
+	fresh := !fetchedAt.IsZero() && c.now().Before(fetchedAt.Add(c.config.TTL))
+	if fresh && found {
+		return key, nil
+	}
+	if fresh && c.now().Before(fetchedAt.Add(jwksMinRefreshInterval)) {
+		return nil, ErrKeyNotFound
+	}
+
+	// Refresh when stale, or when fresh but the kid is unknown (the issuer may have rotated keys)
+	if err := c.refresh(ctx); err != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.fetchedAt.IsZero() || c.now().After(c.fetchedAt.Add(c.config.TTL+c.config.StaleGrace)) {
+			return nil, fmt.Errorf("%w: %v", ErrJWKSUnavailable, err)
+		}
+
+		if !c.degraded {
+			c.logger.Warnf("JWKS refresh failed, serving keys fetched at %s until %s: %v",
+				c.fetchedAt.Format(time.RFC3339), c.fetchedAt.Add(c.config.TTL+c.config.StaleGrace).Format(time.RFC3339), err)
+			c.degraded = true
+		}
+
+		if key, ok := c.keys[kid]; ok {
+			return key, nil
+		}
+		return nil, ErrKeyNotFound
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Keyfunc resolves the verification key for a token by its kid header
+func (c *JWKSClient) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("token has no kid header")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.HTTPClient.Timeout)
+	defer cancel()
+	return c.Key(ctx, kid)
+}
+
+// Degraded reports whether the client is serving stale keys
+func (c *JWKSClient) Degraded() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.degraded
+}
+
+// refresh fetches the key set and replaces the cached keys
+func (c *JWKSClient) refresh(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build jwks request: %w", err)
+	}
+
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected jwks status: %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		key, err := jwk.RSAPublicKey()
+		if err != nil {
+			c.logger.Warnf("Skipping invalid JWK %s: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("jwks contains no usable RSA keys")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.degraded {
+		c.logger.Info("JWKS refresh recovered")
+	}
+	c.keys = keys
+	c.fetchedAt = c.now()
+	c.degraded = false
+	return nil
+}
+
+// RSAPublicKey decodes the JWK modulus and exponent into an RSA public key
+func (k JWK) RSAPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// NewJWK encodes an RSA public key as a signing JWK, identified by KeyID
+func NewJWK(key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Kid: KeyID(key),
+		Use: "sig",
+		Alg: jwt.SigningMethodRS256.Alg(),
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// KeyID returns the RFC 7638 thumbprint of an RSA public key. It depends only
+// on the key, so every service holding the key derives the same kid.
+func KeyID(key *rsa.PublicKey) string {
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	// The thumbprint hashes the required members in lexicographic order,
+	// without whitespace
+	sum := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// jwksServer publishes a key set, or fails every request while failing is set
+type jwksServer struct {
+	set      JWKSet
+	failing  atomic.Bool
+	requests atomic.Int32
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	if s.failing.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.set)
+}
+
+// newTestJWKSClient creates a client of a server publishing key, with a clock
+// the test advances by moving *now
+func newTestJWKSClient(t *testing.T, key *rsa.PublicKey) (*JWKSClient, *jwksServer, *time.Time) {
+	t.Helper()
+
+	server := &jwksServer{set: JWKSet{Keys: []JWK{NewJWK(key)}}}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	client := NewJWKSClient(JWKSConfig{URL: httpServer.URL, TTL: time.Minute, StaleGrace: 10 * time.Minute}, logger)
+
+	now := time.Now()
+	client.now = func() time.Time { return now }
+	return client, server, &now
+}
+
+func TestJWKSClient(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	kid := KeyID(&key.PublicKey)
+	tokenString := signToken(t, jwt.SigningMethodRS256, key, kid, testClaims(nil))
+
+	validate := func(client *JWKSClient) error {
+		_, err := jwt.ParseWithClaims(tokenString, &Claims{}, client.Keyfunc, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+		return err
+	}
+
+	tests := []struct {
+		name         string
+		advance      time.Duration
+		failing      bool
+		wantErr      error
+		wantDegraded bool
+		wantRequests int32
+	}{
+		{"cached within ttl", 30 * time.Second, false, nil, false, 1},
+		{"refreshed after ttl", 2 * time.Minute, false, nil, false, 2},
+		{"refresh failure within grace", 5 * time.Minute, true, nil, true, 2},
+		{"refresh failure beyond grace", 20 * time.Minute, true, ErrJWKSUnavailable, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server, now := newTestJWKSClient(t, &key.PublicKey)
+			if err := validate(client); err != nil {
+				t.Fatalf("initial fetch: %v", err)
+			}
+
+			*now = now.Add(tt.advance)
+			server.failing.Store(tt.failing)
+			err := validate(client)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("validate: err = %v, want %v", err, tt.wantErr)
+			}
+			if client.Degraded() != tt.wantDegraded {
+				t.Errorf("degraded = %v, want %v", client.Degraded(), tt.wantDegraded)
+			}
+			if got := server.requests.Load(); got != tt.wantRequests {
+				t.Errorf("fetched keys %d times, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestJWKSClientRecoversAfterDegrading(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	kid := KeyID(&key.PublicKey)
+	client, server, now := newTestJWKSClient(t, &key.PublicKey)
+
+	if _, err := client.Key(context.Background(), kid); err != nil {
+		t.Fatalf("initial fetch: %v", err)
+	}
+	*now = now.Add(2 * time.Minute)
+	server.failing.Store(true)
+	if _, err := client.Key(context.Background(), kid); err != nil || !client.Degraded() {
+		t.Fatalf("failed refresh: err = %v, degraded = %v; want the stale key served", err, client.Degraded())
+	}
+
+	server.failing.Store(false)
+	if _, err := client.Key(context.Background(), kid); err != nil {
+		t.Fatalf("recovered refresh: %v", err)
+	}
+	if client.Degraded() {
+		t.Error("still degraded after a successful refresh")
+	}
+}
+
+func TestJWKSClientWithoutKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	client, server, _ := newTestJWKSClient(t, &key.PublicKey)
+	server.failing.Store(true)
+
+	// With nothing fetched yet there is no last-known-good key to fall back on
+	if _, err := client.Key(context.Background(), KeyID(&key.PublicKey)); !errors.Is(err, ErrJWKSUnavailable) {
+		t.Fatalf("err = %v, want %v", err, ErrJWKSUnavailable)
+	}
+}
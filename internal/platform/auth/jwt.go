@@ -1,19 +1,58 @@
 package auth
 
 import (
+	"crypto/rsa"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
 )
 
+// Roles carried by tokens
+const (
+	// RoleService is the role carried by service-to-service tokens
+	RoleService = "service"
+	// RoleAdmin is the role of operators allowed to use admin endpoints
+	RoleAdmin = "admin"
+)
+
+// Reasons ValidateToken rejects a token
+var (
+	ErrTokenExpired   = errors.New("token expired")
+	ErrTokenIssuer    = errors.New("token issuer mismatch")
+	ErrTokenAudience  = errors.New("token audience mismatch")
+	ErrTokenSignature = errors.New("token signature invalid")
+	// ErrTokenInvalid covers malformed tokens and missing required claims
+	ErrTokenInvalid = errors.New("token invalid")
+)
+
+// ErrSigningUnavailable is returned when generating a token with a manager
+// that can only validate them
+var ErrSigningUnavailable = errors.New("token signing key not configured")
+
 // JWTManager handles JWT token operations
 type JWTManager struct {
-	secret     string
+	method     jwt.SigningMethod
 	issuer     string
 	audience   string
 	expiration time.Duration
+	denylist   Denylist
+	// verifyOnly managers never sign, even after a rotation
+	verifyOnly bool
+	// rotationGrace is how long a key replaced by Rotate keeps validating
+	rotationGrace time.Duration
+	now           func() time.Time
+
+	mu sync.RWMutex
+	// primary signs new tokens; it is nil when no key is configured
+	primary *jwtKey
+	// retired keys still validate tokens, by kid
+	retired map[string]*jwtKey
 }
 
 // Claims represents JWT claims
@@ -21,35 +60,158 @@ type Claims struct {
 	UserID   string `json:"user_id"`
 	Email    string `json:"email"`
 	Role     string `json:"role"`
+	TenantID string `json:"tenant_id,omitempty"`
 	IssuedAt int64  `json:"iat"`
 	jwt.RegisteredClaims
 }
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
-	Secret     string
-	Issuer     string
-	Audience   string
-	Expiration time.Duration
+	// Algorithm is HS256 (the default) or RS256
+	Algorithm string
+	// Secret signs and validates HS256 tokens
+	Secret string
+	// PrivateKey signs RS256 tokens. Without it an RS256 manager can only
+	// validate tokens, with PublicKey.
+	PrivateKey *rsa.PrivateKey
+	// PublicKey validates RS256 tokens. It defaults to PrivateKey's public half.
+	PublicKey *rsa.PublicKey
+	// PreviousPublicKeys still validate RS256 tokens signed before a key
+	// rotation. They can be dropped once those tokens have expired.
+	PreviousPublicKeys []*rsa.PublicKey
+	Issuer             string
+	Audience           string
+	Expiration         time.Duration
+	// RotationGrace is how long Rotate keeps the replaced key validating
+	// tokens. It defaults to Expiration, by when every token it signed has
+	// expired.
+	RotationGrace time.Duration
 }
 
-// NewJWTManager creates a new JWT manager
+// NewJWTManager creates a new JWT manager. Only tokens signed with the
+// configured algorithm are accepted.
 func NewJWTManager(config *JWTConfig) *JWTManager {
-	return &JWTManager{
-		secret:     config.Secret,
-		issuer:     config.Issuer,
-		audience:   config.Audience,
-		expiration: config.Expiration,
+	m := &JWTManager{
+		method:        jwt.SigningMethodHS256,
+		issuer:        config.Issuer,
+		audience:      config.Audience,
+		expiration:    config.Expiration,
+		rotationGrace: config.RotationGrace,
+		now:           time.Now,
+		retired:       make(map[string]*jwtKey),
+	}
+	if m.rotationGrace <= 0 {
+		m.rotationGrace = config.Expiration
+	}
+
+	if config.Algorithm != jwt.SigningMethodRS256.Alg() {
+		m.primary, _ = newJWTKey(m.method, []byte(config.Secret))
+		return m
+	}
+
+	m.method = jwt.SigningMethodRS256
+	switch {
+	case config.PrivateKey != nil:
+		m.primary, _ = newJWTKey(m.method, config.PrivateKey)
+	case config.PublicKey != nil:
+		m.primary, _ = newJWTKey(m.method, config.PublicKey)
+	}
+	// Keys retired by configuration validate until they are removed from it
+	for _, key := range config.PreviousPublicKeys {
+		if previous, err := newJWTKey(m.method, key); err == nil && (m.primary == nil || previous.kid != m.primary.kid) {
+			m.retired[previous.kid] = previous
+		}
+	}
+	return m
+}
+
+// NewJWTManagerFromConfig creates the JWT manager configured under
+// security.jwt, with the revocation denylist when it is enabled. The keys were
+// checked by config.Validate; one that does not parse is left unset, so the
+// manager refuses to sign or validate rather than falling back to another
+// algorithm.
+func NewJWTManagerFromConfig(cfg *config.Config) *JWTManager {
+	jwtConfig := &JWTConfig{
+		Algorithm:     cfg.Security.JWT.Algorithm,
+		Secret:        cfg.Security.JWT.Secret,
+		Issuer:        cfg.Security.JWT.Issuer,
+		Audience:      cfg.Security.JWT.Audience,
+		Expiration:    cfg.Security.JWT.Expiration,
+		RotationGrace: cfg.Security.JWT.RotationGrace,
+	}
+	if key := cfg.Security.JWT.PrivateKey; key != "" {
+		jwtConfig.PrivateKey, _ = jwt.ParseRSAPrivateKeyFromPEM([]byte(key))
+	}
+	if key := cfg.Security.JWT.PublicKey; key != "" {
+		jwtConfig.PublicKey, _ = jwt.ParseRSAPublicKeyFromPEM([]byte(key))
+	}
+	jwtConfig.PreviousPublicKeys, _ = ParseRSAPublicKeys(cfg.Security.JWT.PreviousPublicKeys)
+
+	manager := NewJWTManager(jwtConfig)
+	manager.SetDenylist(NewDenylist(cfg))
+	return manager
+}
+
+// NewJWTVerifier creates a JWT manager that validates tokens but cannot
+// issue them. With RS256 it needs only the public key, so services that just
+// check tokens never hold the means to mint one.
+func NewJWTVerifier(config *JWTConfig) *JWTManager {
+	m := NewJWTManager(config)
+	m.verifyOnly = true
+	if m.primary != nil {
+		m.primary.sign = nil
+	}
+	return m
+}
+
+// JWKS returns the public keys that validate this manager's tokens, the
+// signing key first. It is empty for HS256, whose secret cannot be published.
+func (m *JWTManager) JWKS() JWKSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	if m.primary != nil {
+		if key, ok := m.primary.verify.(*rsa.PublicKey); ok {
+			set.Keys = append(set.Keys, NewJWK(key))
+		}
+	}
+	now := m.now()
+	retired := make([]string, 0, len(m.retired))
+	for kid, key := range m.retired {
+		if key.validAt(now) {
+			retired = append(retired, kid)
+		}
+	}
+	sort.Strings(retired)
+	for _, kid := range retired {
+		if key, ok := m.retired[kid].verify.(*rsa.PublicKey); ok {
+			set.Keys = append(set.Keys, NewJWK(key))
+		}
 	}
+	return set
+}
+
+// CanSign reports whether the manager can generate tokens
+func (m *JWTManager) CanSign() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.primary != nil && m.primary.sign != nil
 }
 
 // GenerateToken generates a new JWT token for a user
 func (m *JWTManager) GenerateToken(userID, email, role string) (string, error) {
+	return m.GenerateTenantToken(userID, email, role, "")
+}
+
+// GenerateTenantToken generates a new JWT token for a user bound to a tenant
+func (m *JWTManager) GenerateTenantToken(userID, email, role, tenantID string) (string, error) {
 	now := time.Now()
 	claims := &Claims{
 		UserID:   userID,
 		Email:    email,
 		Role:     role,
+		TenantID: tenantID,
 		IssuedAt: now.Unix(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        uuid.New().String(),
@@ -61,44 +223,81 @@ func (m *JWTManager) GenerateToken(userID, email, role string) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.secret))
+	m.mu.RLock()
+	primary := m.primary
+	m.mu.RUnlock()
+	if primary == nil || primary.sign == nil {
+		return "", ErrSigningUnavailable
+	}
+	token := jwt.NewWithClaims(m.method, claims)
+	token.Header["kid"] = primary.kid
+	return token.SignedString(primary.sign)
+}
+
+// GenerateServiceToken generates a token identifying a calling service rather than a user
+func (m *JWTManager) GenerateServiceToken(serviceName string) (string, error) {
+	return m.GenerateToken(serviceName, "", RoleService)
+}
+
+// SetDenylist sets the denylist of revoked tokens checked by the auth
+// middleware. Without one, tokens are valid until they expire.
+func (m *JWTManager) SetDenylist(denylist Denylist) {
+	m.denylist = denylist
+}
+
+// Denylist returns the revoked token denylist, or nil if there is none
+func (m *JWTManager) Denylist() Denylist {
+	return m.denylist
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates a JWT token and returns the claims. The token must
+// be signed with the configured algorithm and a known key, carry an expiry and an ID,
+// and name this manager's issuer and audience. Any other alg, including
+// "none", is rejected, so an RS256 public key can never be used as an HMAC
+// secret. Failures wrap one of the ErrToken errors.
 func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if token.Method.Alg() != m.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(m.secret), nil
-	})
+		return m.keyFor(token)
+	}, jwt.WithValidMethods([]string{m.method.Alg()}), jwt.WithIssuer(m.issuer), jwt.WithAudience(m.audience), jwt.WithExpirationRequired())
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
+		return nil, fmt.Errorf("%w: %w", classifyTokenError(err), err)
 	}
 
 	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
+		return nil, ErrTokenInvalid
 	}
 
 	claims, ok := token.Claims.(*Claims)
 	if !ok {
-		return nil, fmt.Errorf("invalid token claims")
+		return nil, ErrTokenInvalid
+	}
+
+	// The ID is what logout revokes, so a token without one could never be revoked
+	if claims.ID == "" {
+		return nil, fmt.Errorf("%w: missing jti", ErrTokenInvalid)
 	}
 
 	return claims, nil
 }
 
-// RefreshToken generates a new token with extended expiration
-func (m *JWTManager) RefreshToken(tokenString string) (string, error) {
-	claims, err := m.ValidateToken(tokenString)
-	if err != nil {
-		return "", fmt.Errorf("failed to validate token for refresh: %w", err)
+// classifyTokenError maps a jwt parse error to the reason it was rejected
+func classifyTokenError(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return ErrTokenIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrTokenAudience
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable):
+		return ErrTokenSignature
+	default:
+		return ErrTokenInvalid
 	}
-
-	// Generate new token with same user info but new expiration
-	return m.GenerateToken(claims.UserID, claims.Email, claims.Role)
 }
 
 // ExtractUserID extracts user ID from a JWT token
@@ -128,10 +327,14 @@ func (m *JWTManager) ExtractRole(tokenString string) (string, error) {
 	return claims.Role, nil
 }
 
-// IsTokenExpired checks if a token is expired
+// IsTokenExpired checks if a token is expired. An otherwise valid token past
+// its expiry reports true without an error.
 func (m *JWTManager) IsTokenExpired(tokenString string) (bool, error) {
 	claims, err := m.ValidateToken(tokenString)
 	if err != nil {
+		if errors.Is(err, ErrTokenExpired) {
+			return true, nil
+		}
 		return true, err
 	}
 
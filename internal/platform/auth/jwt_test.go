@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	testIssuer   = "loyalty-auth"
+	testAudience = "loyalty-services"
+	testSecret   = "test-secret-at-least-32-bytes-long"
+)
+
+func newTestManager() *JWTManager {
+	return NewJWTManager(&JWTConfig{
+		Secret:     testSecret,
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+		Expiration: 15 * time.Minute,
+	})
+}
+
+// testClaims returns valid claims for a user; edit adjusts them first
+func testClaims(edit func(*Claims)) *Claims {
+	now := time.Now()
+	claims := &Claims{
+		UserID: "user-1",
+		Role:   "user",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    testIssuer,
+			Audience:  []string{testAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	if edit != nil {
+		edit(claims)
+	}
+	return claims
+}
+
+// signToken signs claims with method and key, stamping kid when it is set
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims *Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestValidateToken(t *testing.T) {
+	m := newTestManager()
+	secret := []byte(testSecret)
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"valid", signToken(t, jwt.SigningMethodHS256, secret, m.primary.kid, testClaims(nil)), nil},
+		{"valid without kid", signToken(t, jwt.SigningMethodHS256, secret, "", testClaims(nil)), nil},
+		{"alg none", signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", testClaims(nil)), ErrTokenSignature},
+		{"other HMAC alg", signToken(t, jwt.SigningMethodHS512, secret, "", testClaims(nil)), ErrTokenSignature},
+		{"wrong secret", signToken(t, jwt.SigningMethodHS256, []byte("another-secret-at-least-32-bytes"), "", testClaims(nil)), ErrTokenSignature},
+		{"wrong issuer", signToken(t, jwt.SigningMethodHS256, secret, "", testClaims(func(c *Claims) {
+			c.Issuer = "someone-else"
+		})), ErrTokenIssuer},
+		{"wrong audience", signToken(t, jwt.SigningMethodHS256, secret, "", testClaims(func(c *Claims) {
+			c.Audience = []string{"another-audience"}
+		})), ErrTokenAudience},
+		{"missing jti", signToken(t, jwt.SigningMethodHS256, secret, "", testClaims(func(c *Claims) {
+			c.ID = ""
+		})), ErrTokenInvalid},
+		{"missing expiry", signToken(t, jwt.SigningMethodHS256, secret, "", testClaims(func(c *Claims) {
+			c.ExpiresAt = nil
+		})), ErrTokenInvalid},
+		{"expired", signToken(t, jwt.SigningMethodHS256, secret, "", testClaims(func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		})), ErrTokenExpired},
+		{"unknown kid", signToken(t, jwt.SigningMethodHS256, secret, "unknown-kid", testClaims(nil)), ErrKeyNotFound},
+		{"malformed", "not.a.token", ErrTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := m.ValidateToken(tt.token)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("ValidateToken: %v", err)
+				}
+				if claims.UserID != "user-1" {
+					t.Fatalf("user_id = %q, want user-1", claims.UserID)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTokenRejectsAlgorithmSwap(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	m := NewJWTManager(&JWTConfig{
+		Algorithm:  "RS256",
+		PrivateKey: key,
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+		Expiration: 15 * time.Minute,
+	})
+
+	valid, err := m.GenerateToken("user-1", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := m.ValidateToken(valid); err != nil {
+		t.Fatalf("RS256 token rejected: %v", err)
+	}
+
+	// The public key is published, so a token HMAC signed with it would be
+	// forgeable by anyone if HS256 were accepted
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to encode public key: %v", err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	for _, secret := range [][]byte{publicPEM, der} {
+		forged := signToken(t, jwt.SigningMethodHS256, secret, m.primary.kid, testClaims(func(c *Claims) {
+			c.Role = RoleAdmin
+		}))
+		if _, err := m.ValidateToken(forged); !errors.Is(err, ErrTokenSignature) {
+			t.Fatalf("HS256 token signed with the public key: err = %v, want %v", err, ErrTokenSignature)
+		}
+	}
+}
+
+func TestRotationGraceWindow(t *testing.T) {
+	m := NewJWTManager(&JWTConfig{
+		Secret:        testSecret,
+		Issuer:        testIssuer,
+		Audience:      testAudience,
+		Expiration:    15 * time.Minute,
+		RotationGrace: time.Minute,
+	})
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	old, err := m.GenerateToken("user-1", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if err := m.Rotate("rotated-secret-at-least-32-bytes-long"); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	current, err := m.GenerateToken("user-1", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	if _, err := m.ValidateToken(old); err != nil {
+		t.Fatalf("token signed before rotation rejected within the grace window: %v", err)
+	}
+	if _, err := m.ValidateToken(current); err != nil {
+		t.Fatalf("token signed after rotation rejected: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := m.ValidateToken(old); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("token signed before rotation after the grace window: err = %v, want %v", err, ErrKeyNotFound)
+	}
+	if _, err := m.ValidateToken(current); err != nil {
+		t.Fatalf("token signed after rotation rejected after the grace window: %v", err)
+	}
+}
+
+func TestRotateWhileValidating(t *testing.T) {
+	m := newTestManager()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := m.Rotate(fmt.Sprintf("rotated-secret-%d-%d-at-least-32-bytes", i, j)); err != nil {
+					t.Errorf("Rotate: %v", err)
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				token, err := m.GenerateToken("user-1", "user@example.com", "user")
+				if err != nil {
+					t.Errorf("GenerateToken: %v", err)
+					continue
+				}
+				// Every replaced key is within its grace window
+				if _, err := m.ValidateToken(token); err != nil {
+					t.Errorf("ValidateToken: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestVerifierCannotSign(t *testing.T) {
+	m := NewJWTVerifier(&JWTConfig{Secret: testSecret, Issuer: testIssuer, Audience: testAudience, Expiration: time.Minute})
+
+	if m.CanSign() {
+		t.Fatal("verifier reports it can sign")
+	}
+	if _, err := m.GenerateToken("user-1", "user@example.com", "user"); !errors.Is(err, ErrSigningUnavailable) {
+		t.Fatalf("GenerateToken: err = %v, want %v", err, ErrSigningUnavailable)
+	}
+	token := signToken(t, jwt.SigningMethodHS256, []byte(testSecret), "", testClaims(nil))
+	if _, err := m.ValidateToken(token); err != nil {
+		t.Fatalf("verifier rejected a valid token: %v", err)
+	}
+}
+
+func TestJWKSPublishesRetiredKeysUntilGraceEnds(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	m := NewJWTManager(&JWTConfig{
+		Algorithm:     jwt.SigningMethodRS256.Alg(),
+		PrivateKey:    first,
+		Issuer:        testIssuer,
+		Audience:      testAudience,
+		Expiration:    15 * time.Minute,
+		RotationGrace: time.Minute,
+	})
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	kids := func() []string {
+		var kids []string
+		for _, key := range m.JWKS().Keys {
+			kids = append(kids, key.Kid)
+		}
+		return kids
+	}
+	if got := kids(); len(got) != 1 || got[0] != KeyID(&first.PublicKey) {
+		t.Fatalf("kids = %q, want the first key", got)
+	}
+
+	// The signing key is listed first, then the retired one
+	if err := m.Rotate(second); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if got := kids(); len(got) != 2 || got[0] != KeyID(&second.PublicKey) || got[1] != KeyID(&first.PublicKey) {
+		t.Fatalf("kids after rotation = %q, want the second key then the first", got)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if got := kids(); len(got) != 1 || got[0] != KeyID(&second.PublicKey) {
+		t.Fatalf("kids after the grace window = %q, want only the second key", got)
+	}
+
+	// HMAC secrets are never published
+	if keys := newTestManager().JWKS().Keys; keys == nil || len(keys) != 0 {
+		t.Fatalf("HS256 keys = %v, want an empty set", keys)
+	}
+}
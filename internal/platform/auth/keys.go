@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtKey is a key tokens are validated with, and signed with while it is the
+// manager's primary key
+type jwtKey struct {
+	kid string
+	// sign is nil for a key that only validates tokens
+	sign   interface{}
+	verify interface{}
+	// expiresAt is when a retired key stops validating tokens. It is zero for
+	// the primary key and for keys retired by configuration.
+	expiresAt time.Time
+}
+
+// newJWTKey wraps a key for method: an HMAC secret as []byte or string for
+// HS256, or for RS256 an RSA private key, or a public key to only validate
+func newJWTKey(method jwt.SigningMethod, key interface{}) (*jwtKey, error) {
+	switch method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		var secret []byte
+		switch k := key.(type) {
+		case []byte:
+			secret = k
+		case string:
+			secret = []byte(k)
+		default:
+			return nil, fmt.Errorf("HS256 key must be a secret, got %T", key)
+		}
+		return &jwtKey{kid: secretKeyID(secret), sign: secret, verify: secret}, nil
+	case jwt.SigningMethodRS256.Alg():
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return &jwtKey{kid: KeyID(&k.PublicKey), sign: k, verify: &k.PublicKey}, nil
+		case *rsa.PublicKey:
+			return &jwtKey{kid: KeyID(k), verify: k}, nil
+		default:
+			return nil, fmt.Errorf("RS256 key must be an RSA private or public key, got %T", key)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing method %s", method.Alg())
+	}
+}
+
+// validAt reports whether a retired key still validates tokens at now
+func (k *jwtKey) validAt(now time.Time) bool {
+	return k.expiresAt.IsZero() || now.Before(k.expiresAt)
+}
+
+// secretKeyID names an HMAC secret without revealing it: kids are sent in
+// every token, so a plain hash of a weak secret could be brute forced
+func secretKeyID(secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("jwt kid"))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Rotate makes key the primary signing key, taking the same kinds of key as
+// the manager was configured with. The replaced key keeps validating tokens
+// for the rotation grace window, so tokens it signed stay valid until they
+// expire. A verify-only manager rotates to the key's public half. It is safe
+// to call while tokens are being signed and validated.
+func (m *JWTManager) Rotate(key interface{}) error {
+	next, err := newJWTKey(m.method, key)
+	if err != nil {
+		return err
+	}
+	if m.verifyOnly {
+		next.sign = nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for kid, retired := range m.retired {
+		if !retired.validAt(now) {
+			delete(m.retired, kid)
+		}
+	}
+	if m.primary != nil && m.primary.kid != next.kid {
+		previous := *m.primary
+		previous.sign = nil
+		previous.expiresAt = now.Add(m.rotationGrace)
+		m.retired[previous.kid] = &previous
+	}
+	// Rotating back to a retired key makes it primary again
+	delete(m.retired, next.kid)
+	m.primary = next
+	return nil
+}
+
+// keyFor returns the key that verifies token, chosen by its kid. Tokens
+// signed before kids were stamped have none and are verified with the
+// primary key.
+func (m *JWTManager) keyFor(token *jwt.Token) (interface{}, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" || (m.primary != nil && kid == m.primary.kid) {
+		if m.primary == nil {
+			return nil, errors.New("token verification key not configured")
+		}
+		return m.primary.verify, nil
+	}
+
+	key, ok := m.retired[kid]
+	if !ok || !key.validAt(m.now()) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
+	}
+	return key.verify, nil
+}
+
+// ParseRSAPublicKeys parses every PEM encoded RSA public key in data, so a
+// rotation's retired keys can be given as one bundle
+func ParseRSAPublicKeys(data string) ([]*rsa.PublicKey, error) {
+	var keys []*rsa.PublicKey
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return keys, nil
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(pem.EncodeToMemory(block))
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", len(keys)+1, err)
+		}
+		keys = append(keys, key)
+	}
+}
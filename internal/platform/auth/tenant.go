@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// DefaultTenant is the tenant every request belongs to when multi-tenancy is disabled
+const DefaultTenant = "default"
+
+// TenantHeader selects the tenant for unauthenticated requests such as login,
+// and for service tokens acting on behalf of a tenant
+const TenantHeader = "X-Tenant-ID"
+
+var (
+	// ErrTenantRequired is returned when multi-tenancy is enabled and no tenant was given
+	ErrTenantRequired = errors.New("tenant required")
+	// ErrInvalidTenant is returned for malformed tenant IDs
+	ErrInvalidTenant = errors.New("invalid tenant")
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// TenantScope resolves which tenant a request is scoped to
+type TenantScope struct {
+	Enabled bool
+	// Default is the tenant used when multi-tenancy is disabled
+	Default string
+}
+
+// NewTenantScope creates a tenant scope, falling back to DefaultTenant
+func NewTenantScope(enabled bool, defaultTenant string) TenantScope {
+	if defaultTenant == "" {
+		defaultTenant = DefaultTenant
+	}
+	return TenantScope{Enabled: enabled, Default: defaultTenant}
+}
+
+// FromHeader resolves the tenant of an unauthenticated request from its tenant header
+func (t TenantScope) FromHeader(header string) (string, error) {
+	if !t.Enabled {
+		return t.Default, nil
+	}
+	if header == "" {
+		return "", ErrTenantRequired
+	}
+	if !tenantIDPattern.MatchString(header) {
+		return "", ErrInvalidTenant
+	}
+	return header, nil
+}
+
+// FromClaims resolves the tenant of an authenticated request. User tokens are
+// bound to the tenant in their claims; service tokens may act on any tenant
+// named in the tenant header.
+func (t TenantScope) FromClaims(claims *Claims, header string) (string, error) {
+	if !t.Enabled {
+		return t.Default, nil
+	}
+	if claims.Role == RoleService && claims.TenantID == "" {
+		return t.FromHeader(header)
+	}
+	if claims.TenantID == "" {
+		return "", ErrTenantRequired
+	}
+	return claims.TenantID, nil
+}
+
+// WithTenant returns a copy of ctx scoped to the given tenant
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to, or DefaultTenant if none was set
+func TenantFromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(tenantKey).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return DefaultTenant
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTenantScopeFromHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   TenantScope
+		header  string
+		want    string
+		wantErr error
+	}{
+		{"disabled ignores header", NewTenantScope(false, ""), "acme", DefaultTenant, nil},
+		{"disabled uses configured default", NewTenantScope(false, "brand"), "", "brand", nil},
+		{"enabled", NewTenantScope(true, ""), "acme", "acme", nil},
+		{"enabled requires header", NewTenantScope(true, ""), "", "", ErrTenantRequired},
+		{"enabled rejects upper case", NewTenantScope(true, ""), "Acme", "", ErrInvalidTenant},
+		{"enabled rejects punctuation", NewTenantScope(true, ""), "acme'; --", "", ErrInvalidTenant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.scope.FromHeader(tt.header)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Fatalf("FromHeader(%q) = %q, %v; want %q, %v", tt.header, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTenantScopeFromClaims(t *testing.T) {
+	scope := NewTenantScope(true, "")
+	tests := []struct {
+		name    string
+		claims  Claims
+		header  string
+		want    string
+		wantErr error
+	}{
+		{"user bound to claim", Claims{Role: "user", TenantID: "acme"}, "", "acme", nil},
+		{"user cannot switch tenant by header", Claims{Role: "user", TenantID: "acme"}, "globex", "acme", nil},
+		{"admin cannot switch tenant by header", Claims{Role: RoleAdmin, TenantID: "acme"}, "globex", "acme", nil},
+		{"user without tenant", Claims{Role: "user"}, "globex", "", ErrTenantRequired},
+		{"service acts on header tenant", Claims{Role: RoleService}, "globex", "globex", nil},
+		{"service needs a tenant", Claims{Role: RoleService}, "", "", ErrTenantRequired},
+		{"service bound to claim", Claims{Role: RoleService, TenantID: "acme"}, "globex", "acme", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scope.FromClaims(&tt.claims, tt.header)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Fatalf("FromClaims = %q, %v; want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	// Disabled scoping puts every token in the default tenant
+	if got, err := NewTenantScope(false, "").FromClaims(&Claims{Role: "user", TenantID: "acme"}, ""); err != nil || got != DefaultTenant {
+		t.Fatalf("disabled FromClaims = %q, %v; want %q", got, err, DefaultTenant)
+	}
+}
+
+func TestTenantContext(t *testing.T) {
+	if got := TenantFromContext(context.Background()); got != DefaultTenant {
+		t.Fatalf("TenantFromContext without a tenant = %q, want %q", got, DefaultTenant)
+	}
+	if got := TenantFromContext(WithTenant(context.Background(), "acme")); got != "acme" {
+		t.Fatalf("TenantFromContext = %q, want acme", got)
+	}
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Cache is a concurrency-safe in-memory TTL cache partitioned into namespaces
+type Cache struct {
+	mu         sync.RWMutex
+	ttl        time.Duration
+	namespaces map[string]*namespace
+	now        func() time.Time
+}
+
+type namespace struct {
+	generation uint64
+	entries    map[string]entry
+}
+
+type entry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// New creates a new cache whose entries expire after ttl
+func New(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:        ttl,
+		namespaces: make(map[string]*namespace),
+		now:        time.Now,
+	}
+}
+
+// Get returns the cached value for key in the namespace
+func (c *Cache) Get(ns, key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	n, ok := c.namespaces[ns]
+	if !ok {
+		return nil, false
+	}
+
+	e, ok := n.entries[key]
+	if !ok || c.now().After(e.expiresAt) {
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+// Set stores a value for key in the namespace
+func (c *Cache) Set(ns, key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.namespace(ns).entries[key] = entry{value: value, expiresAt: c.now().Add(c.ttl)}
+}
+
+// GetOrLoad returns the cached value for key, calling load on a miss.
+// A loaded value is only stored if the namespace was not invalidated while
+// loading, so a concurrent write can never be overwritten by a stale read.
+func (c *Cache) GetOrLoad(ns, key string, load func() (interface{}, error)) (interface{}, error) {
+	if value, ok := c.Get(ns, key); ok {
+		return value, nil
+	}
+
+	c.mu.Lock()
+	generation := c.namespace(ns).generation
+	c.mu.Unlock()
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.namespace(ns)
+	if n.generation == generation {
+		n.entries[key] = entry{value: value, expiresAt: c.now().Add(c.ttl)}
+	}
+
+	return value, nil
+}
+
+// Delete removes a single key from the namespace
+func (c *Cache) Delete(ns, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.namespace(ns)
+	delete(n.entries, key)
+	n.generation++
+}
+
+// InvalidateNamespace removes every entry in the namespace
+func (c *Cache) InvalidateNamespace(ns string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.namespace(ns)
+	n.entries = make(map[string]entry)
+	n.generation++
+}
+
+// Flush removes every entry in every namespace
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, n := range c.namespaces {
+		n.entries = make(map[string]entry)
+		n.generation++
+	}
+}
+
+// Namespaces returns the names of all known namespaces
+func (c *Cache) Namespaces() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.namespaces))
+	for name := range c.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// namespace returns the namespace, creating it if needed. Callers must hold the write lock.
+func (c *Cache) namespace(ns string) *namespace {
+	n, ok := c.namespaces[ns]
+	if !ok {
+		n = &namespace{entries: make(map[string]entry)}
+		c.namespaces[ns] = n
+	}
+	return n
+}
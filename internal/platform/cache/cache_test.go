@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestCache returns a cache whose clock is advanced by the returned func
+func newTestCache(ttl time.Duration) (*Cache, func(time.Duration)) {
+	c := New(ttl)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestGetSetExpiry(t *testing.T) {
+	c, advance := newTestCache(time.Minute)
+
+	if _, ok := c.Get("benefits", "b1"); ok {
+		t.Fatal("empty cache returned a value")
+	}
+	c.Set("benefits", "b1", "first")
+	if value, ok := c.Get("benefits", "b1"); !ok || value != "first" {
+		t.Fatalf("Get = %v, %v; want first", value, ok)
+	}
+	if _, ok := c.Get("partners", "b1"); ok {
+		t.Fatal("key leaked into another namespace")
+	}
+
+	advance(2 * time.Minute)
+	if _, ok := c.Get("benefits", "b1"); ok {
+		t.Fatal("expired entry returned")
+	}
+}
+
+func TestDeleteAndInvalidate(t *testing.T) {
+	c, _ := newTestCache(time.Minute)
+	c.Set("benefits", "b1", 1)
+	c.Set("benefits", "b2", 2)
+	c.Set("partners", "p1", 3)
+
+	c.Delete("benefits", "b1")
+	if _, ok := c.Get("benefits", "b1"); ok {
+		t.Fatal("deleted entry returned")
+	}
+	if _, ok := c.Get("benefits", "b2"); !ok {
+		t.Fatal("Delete removed another key")
+	}
+
+	c.InvalidateNamespace("benefits")
+	if _, ok := c.Get("benefits", "b2"); ok {
+		t.Fatal("InvalidateNamespace left an entry")
+	}
+	if _, ok := c.Get("partners", "p1"); !ok {
+		t.Fatal("InvalidateNamespace cleared another namespace")
+	}
+
+	c.Flush()
+	if _, ok := c.Get("partners", "p1"); ok {
+		t.Fatal("Flush left an entry")
+	}
+	if got := c.Namespaces(); len(got) != 2 || got[0] != "benefits" || got[1] != "partners" {
+		t.Fatalf("Namespaces = %v, want [benefits partners]", got)
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c, _ := newTestCache(time.Minute)
+
+	loads := 0
+	load := func() (interface{}, error) {
+		loads++
+		return loads, nil
+	}
+	for i := 0; i < 2; i++ {
+		if value, err := c.GetOrLoad("benefits", "b1", load); err != nil || value != 1 {
+			t.Fatalf("GetOrLoad = %v, %v; want 1", value, err)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("loaded %d times, want 1", loads)
+	}
+
+	failure := errors.New("database down")
+	if _, err := c.GetOrLoad("benefits", "b2", func() (interface{}, error) { return nil, failure }); err != failure {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if _, ok := c.Get("benefits", "b2"); ok {
+		t.Fatal("failed load was cached")
+	}
+}
+
+func TestGetOrLoadDropsValuesInvalidatedWhileLoading(t *testing.T) {
+	for name, invalidate := range map[string]func(*Cache){
+		"delete":    func(c *Cache) { c.Delete("benefits", "b1") },
+		"namespace": func(c *Cache) { c.InvalidateNamespace("benefits") },
+		"flush":     func(c *Cache) { c.Flush() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, _ := newTestCache(time.Minute)
+			c.Set("benefits", "other", "warm")
+
+			// A write lands while the stale value is being read
+			value, err := c.GetOrLoad("benefits", "b1", func() (interface{}, error) {
+				invalidate(c)
+				return "stale", nil
+			})
+			if err != nil || value != "stale" {
+				t.Fatalf("GetOrLoad = %v, %v; want the loaded value", value, err)
+			}
+			if cached, ok := c.Get("benefits", "b1"); ok {
+				t.Fatalf("stale value %v cached after invalidation", cached)
+			}
+		})
+	}
+}
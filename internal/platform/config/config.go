@@ -1,23 +1,32 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"reflect"
 	"strings"
 	"time"
 
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/dsn"
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	Kafka    KafkaConfig    `mapstructure:"kafka"`
-	Security SecurityConfig `mapstructure:"security"`
-	OTel     OTelConfig     `mapstructure:"otel"`
+	App          AppConfig          `mapstructure:"app"`
+	Database     DatabaseConfig     `mapstructure:"database"`
+	Redis        RedisConfig        `mapstructure:"redis"`
+	Cache        CacheConfig        `mapstructure:"cache"`
+	Catalog      CatalogConfig      `mapstructure:"catalog"`
+	Loyalty      LoyaltyConfig      `mapstructure:"loyalty"`
+	Redemption   RedemptionConfig   `mapstructure:"redemption"`
+	Services     ServicesConfig     `mapstructure:"services"`
+	Notify       NotifyConfig       `mapstructure:"notify"`
+	Kafka        KafkaConfig        `mapstructure:"kafka"`
+	Dependencies DependenciesConfig `mapstructure:"dependencies"`
+	Security     SecurityConfig     `mapstructure:"security"`
+	OTel         OTelConfig         `mapstructure:"otel"`
+	Metrics      MetricsConfig      `mapstructure:"metrics"`
 }
 
 // AppConfig holds application-level configuration
@@ -28,12 +37,17 @@ type AppConfig struct {
 	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
 	Environment     string        `mapstructure:"environment"`
 	Version         string        `mapstructure:"version"`
+	// MaxBodyBytes caps request bodies (negative removes the cap)
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
 }
 
 // DatabaseConfig holds database connection configuration
 type DatabaseConfig struct {
 	Postgres PostgresConfig `mapstructure:"postgres"`
 	Mongo    MongoConfig    `mapstructure:"mongo"`
+	// Migrations is "apply" to migrate the schema on startup, or "verify" to
+	// refuse to start until it has been migrated
+	Migrations string `mapstructure:"migrations"`
 }
 
 // PostgresConfig holds PostgreSQL configuration
@@ -45,6 +59,21 @@ type PostgresConfig struct {
 	Password string `mapstructure:"password"`
 	SSLMode  string `mapstructure:"ssl_mode"`
 	MaxConns int    `mapstructure:"max_conns"`
+	// ConnectTimeout bounds establishing each connection (0 waits indefinitely)
+	ConnectTimeout time.Duration `mapstructure:"connect_timeout"`
+	// ApplicationName identifies the service in pg_stat_activity
+	ApplicationName string `mapstructure:"application_name"`
+	// MaxConnLifetime is how long a pooled connection is kept before it is replaced
+	MaxConnLifetime time.Duration `mapstructure:"max_conn_lifetime"`
+	// MaxConnIdleTime is how long an idle pooled connection is kept open
+	MaxConnIdleTime time.Duration `mapstructure:"max_conn_idle_time"`
+	// HealthCheckPeriod is how often idle pooled connections are checked
+	HealthCheckPeriod time.Duration `mapstructure:"health_check_period"`
+	// StatsInterval is how often pool statistics are exported to /metrics
+	// and logged at debug level (0 disables them)
+	StatsInterval time.Duration `mapstructure:"stats_interval"`
+	// ConnectRetry controls waiting for a required database at startup
+	ConnectRetry RetryConfig `mapstructure:"connect_retry"`
 }
 
 // MongoConfig holds MongoDB configuration
@@ -62,13 +91,297 @@ type RedisConfig struct {
 	PoolSize int    `mapstructure:"pool_size"`
 }
 
+// CacheConfig holds in-process cache configuration
+type CacheConfig struct {
+	BenefitsTTL  time.Duration          `mapstructure:"benefits_ttl"`
+	UserProfiles UserProfileCacheConfig `mapstructure:"user_profiles"`
+	// ClientMaxAge is the Cache-Control max-age for private profile, balance,
+	// and benefit responses
+	ClientMaxAge time.Duration `mapstructure:"client_max_age"`
+}
+
+// UserProfileCacheConfig holds configuration for caching user profile reads
+type UserProfileCacheConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	TTL     time.Duration `mapstructure:"ttl"`
+}
+
+// CatalogConfig holds catalog service configuration
+type CatalogConfig struct {
+	MaxPointsCost int `mapstructure:"max_points_cost"`
+	// MaxImportRows caps the benefits in one import request
+	MaxImportRows int `mapstructure:"max_import_rows"`
+}
+
+// LoyaltyConfig holds loyalty service configuration
+type LoyaltyConfig struct {
+	HoldTTL           time.Duration `mapstructure:"hold_ttl"`
+	HoldSweepInterval time.Duration `mapstructure:"hold_sweep_interval"`
+	// OrphanGrace is how old an unreversed redemption deduction must be
+	// before it is reported as orphaned
+	OrphanGrace time.Duration `mapstructure:"orphan_grace"`
+	// MaxBalanceLookup caps the user IDs in one bulk balance request
+	MaxBalanceLookup int `mapstructure:"max_balance_lookup"`
+	// MaxEarnBatch caps the items in one batch earn request
+	MaxEarnBatch int `mapstructure:"max_earn_batch"`
+	// Tiers map lifetime earned points to loyalty tiers
+	Tiers []TierConfig `mapstructure:"tiers"`
+	// PointsExpiry is how long earned points last before they expire (0 disables expiry)
+	PointsExpiry        time.Duration `mapstructure:"points_expiry"`
+	ExpirySweepInterval time.Duration `mapstructure:"expiry_sweep_interval"`
+	// BalanceCacheTTL is how long balances are cached in Redis (0 disables
+	// the cache, as does an empty redis.addr)
+	BalanceCacheTTL time.Duration `mapstructure:"balance_cache_ttl"`
+	// AdjustmentMinBalance is the lowest available balance an admin debit
+	// may leave; negative values allow clawbacks of already spent points
+	AdjustmentMinBalance int `mapstructure:"adjustment_min_balance"`
+}
+
+// TierConfig is a loyalty tier and the lifetime earned points needed to reach it
+type TierConfig struct {
+	Name      string `mapstructure:"name"`
+	MinPoints int    `mapstructure:"min_points"`
+}
+
+// RedemptionConfig holds redemption service configuration
+type RedemptionConfig struct {
+	// PointsHolds authorizes points with a hold during the saga and captures
+	// them on fulfillment, instead of deducting and reversing on failure
+	PointsHolds bool `mapstructure:"points_holds"`
+	// SagaTimeout bounds a whole redemption saga (0 disables the deadline)
+	SagaTimeout time.Duration `mapstructure:"saga_timeout"`
+	// StepTimeout bounds each saga step and compensation (0 disables it)
+	StepTimeout time.Duration `mapstructure:"step_timeout"`
+	// PartnerRetry controls retrying failed partner gateway calls
+	PartnerRetry RetryConfig `mapstructure:"partner_retry"`
+	// PartnerBreaker stops calling the partner gateway while it is failing
+	PartnerBreaker CircuitBreakerConfig `mapstructure:"partner_breaker"`
+	// CaptureRetry controls retrying the capture of held points once the
+	// partner has fulfilled the benefit
+	CaptureRetry RetryConfig `mapstructure:"capture_retry"`
+	// Outbox controls relaying saga events from the outbox table to Kafka
+	Outbox OutboxConfig `mapstructure:"outbox"`
+	// BenefitCacheTTL is how long benefit names shown in redemption status
+	// are cached (0 looks them up on every request)
+	BenefitCacheTTL time.Duration `mapstructure:"benefit_cache_ttl"`
+	// EventsPollInterval is how often a redemption event stream rereads the
+	// status, to see changes made by other instances (0 disables polling)
+	EventsPollInterval time.Duration `mapstructure:"events_poll_interval"`
+	// IdempotencyKeyTTL is how long an Idempotency-Key replays the redemption
+	// it created; once it expires, the key creates a new redemption
+	IdempotencyKeyTTL time.Duration `mapstructure:"idempotency_key_ttl"`
+	// IdempotencySweepInterval is how often expired idempotency keys are
+	// deleted (0 disables the sweep)
+	IdempotencySweepInterval time.Duration `mapstructure:"idempotency_sweep_interval"`
+}
+
+// RetryConfig holds exponential backoff settings for retried calls
+type RetryConfig struct {
+	// MaxAttempts caps the attempts, including the first (1 disables retries)
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// BaseDelay is the delay before the first retry; each retry doubles it
+	BaseDelay time.Duration `mapstructure:"base_delay"`
+	// MaxDelay caps the delay between attempts
+	MaxDelay time.Duration `mapstructure:"max_delay"`
+}
+
+// CircuitBreakerConfig holds settings for a circuit breaker, which fails
+// calls fast while the service they go to is down
+type CircuitBreakerConfig struct {
+	// FailureRatio is the share of failed calls in a window that opens the
+	// circuit (0 disables the breaker)
+	FailureRatio float64 `mapstructure:"failure_ratio"`
+	// MinRequests is the fewest calls in a window that can open the circuit
+	MinRequests int `mapstructure:"min_requests"`
+	// Window is how long calls are counted before the counts start over
+	Window time.Duration `mapstructure:"window"`
+	// OpenTimeout is how long the circuit stays open before probe calls are
+	// let through
+	OpenTimeout time.Duration `mapstructure:"open_timeout"`
+	// HalfOpenProbes is how many probe calls must succeed to close the
+	// circuit again; any failing probe opens it
+	HalfOpenProbes int `mapstructure:"half_open_probes"`
+}
+
+// OutboxConfig holds transactional outbox relay configuration
+type OutboxConfig struct {
+	// PollInterval is how often unsent messages are relayed (0 disables the relay)
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// BatchSize caps the messages relayed per poll
+	BatchSize int `mapstructure:"batch_size"`
+	// ClaimTimeout is how long a claimed message may go unsent before another
+	// poll picks it up again
+	ClaimTimeout time.Duration `mapstructure:"claim_timeout"`
+}
+
+// NotifyConfig holds notification service configuration
+type NotifyConfig struct {
+	Email ChannelLimitConfig `mapstructure:"email"`
+	SMS   ChannelLimitConfig `mapstructure:"sms"`
+	Push  ChannelLimitConfig `mapstructure:"push"`
+	// Content limits the length of notification content
+	Content ContentLimitConfig `mapstructure:"content"`
+	// SendTimeout bounds each provider send unless the channel sets its own
+	SendTimeout time.Duration `mapstructure:"send_timeout"`
+	// OutcomeRetention is how many consumed event outcomes are kept for
+	// inspection and deduplication (0 only logs them)
+	OutcomeRetention int `mapstructure:"outcome_retention"`
+	// RedemptionChannels lists the channels a completed redemption is
+	// notified on, each with its redemption-completed template
+	RedemptionChannels []string `mapstructure:"redemption_channels"`
+	// Providers selects how each channel is delivered
+	Providers NotifyProviderConfig `mapstructure:"providers"`
+	// Webhooks controls delivering redemption events to registered webhooks
+	Webhooks WebhookDeliveryConfig `mapstructure:"webhooks"`
+}
+
+// WebhookDeliveryConfig holds settings for delivering events to webhooks
+type WebhookDeliveryConfig struct {
+	// GroupID is the consumer group reading events for webhooks, separate
+	// from the notification consumers so each sees every event. It defaults
+	// to kafka.group_id with a "-webhooks" suffix.
+	GroupID string `mapstructure:"group_id"`
+	// PollInterval is how often due deliveries are sent (0 disables sending)
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// BatchSize caps the deliveries sent per poll
+	BatchSize int `mapstructure:"batch_size"`
+	// Timeout bounds each delivery request
+	Timeout time.Duration `mapstructure:"timeout"`
+	// ClaimTimeout is how long a claimed delivery waits before another
+	// instance may send it, if the one that claimed it stopped
+	ClaimTimeout time.Duration `mapstructure:"claim_timeout"`
+	// Retry controls how often, and how long apart, a failing delivery is
+	// attempted before it is given up on
+	Retry RetryConfig `mapstructure:"retry"`
+}
+
+// NotifyProviderConfig selects and configures notification providers
+type NotifyProviderConfig struct {
+	// Email is the email provider: "log" or "smtp"
+	Email string `mapstructure:"email"`
+	// SMS is the SMS provider: "log" or "webhook"
+	SMS        string        `mapstructure:"sms"`
+	SMTP       SMTPConfig    `mapstructure:"smtp"`
+	SMSWebhook WebhookConfig `mapstructure:"sms_webhook"`
+}
+
+// SMTPConfig holds SMTP relay settings for email delivery
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// WebhookConfig holds an outbound webhook endpoint and its bearer token
+type WebhookConfig struct {
+	URL   string `mapstructure:"url"`
+	Token string `mapstructure:"token"`
+}
+
+// ContentLimitConfig limits notification content length per channel
+type ContentLimitConfig struct {
+	// SMSMaxSegments is the most segments a single SMS may be split into
+	SMSMaxSegments int `mapstructure:"sms_max_segments"`
+	// SMSOverflow ("reject" or "truncate") controls over-length SMS handling
+	SMSOverflow string `mapstructure:"sms_overflow"`
+	// SubjectMaxLength limits email subjects, in characters
+	SubjectMaxLength int `mapstructure:"subject_max_length"`
+}
+
+// ChannelLimitConfig limits how fast notifications are sent on a channel
+type ChannelLimitConfig struct {
+	MaxPerSecond float64 `mapstructure:"max_per_second"`
+	MaxInFlight  int     `mapstructure:"max_in_flight"`
+	// SendTimeout overrides the default send timeout for the channel
+	SendTimeout time.Duration `mapstructure:"send_timeout"`
+}
+
+// ChannelLimits returns the limits for a channel, falling back to the email
+// limits for unknown channels
+func (c NotifyConfig) ChannelLimits(channel string) ChannelLimitConfig {
+	limits := c.Email
+	switch channel {
+	case "sms":
+		limits = c.SMS
+	case "push":
+		limits = c.Push
+	}
+
+	if limits.MaxInFlight < 1 {
+		limits.MaxInFlight = 1
+	}
+	if limits.SendTimeout <= 0 {
+		limits.SendTimeout = c.SendTimeout
+	}
+	return limits
+}
+
+// ServicesConfig holds the base URLs of other services
+type ServicesConfig struct {
+	CatalogURL        string `mapstructure:"catalog_url"`
+	LoyaltyURL        string `mapstructure:"loyalty_url"`
+	PartnerGatewayURL string `mapstructure:"partner_gateway_url"`
+	// RequestTimeout bounds each call to another service
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+}
+
 // KafkaConfig holds Kafka configuration
 type KafkaConfig struct {
+	// Driver selects the event bus: "kafka", or "memory" for a broker-less in-process bus
+	Driver   string   `mapstructure:"driver"`
 	Brokers  []string `mapstructure:"brokers"`
 	ClientID string   `mapstructure:"client_id"`
 	GroupID  string   `mapstructure:"group_id"`
 	Version  string   `mapstructure:"version"`
 	Topics   Topics   `mapstructure:"topics"`
+	// ConsumerRetry bounds the attempts a consumer makes at each message
+	ConsumerRetry RetryConfig `mapstructure:"consumer_retry"`
+	// DeadLetter publishes messages a consumer gave up on to <topic>.dlq
+	// and moves past them, instead of leaving them uncommitted
+	DeadLetter bool `mapstructure:"dead_letter"`
+	// LagInterval is how often consumers report how far behind their topic
+	// they are (0 disables the reports)
+	LagInterval time.Duration       `mapstructure:"lag_interval"`
+	Producer    KafkaProducerConfig `mapstructure:"producer"`
+}
+
+// KafkaProducerConfig holds the redemption event producer's batching settings
+type KafkaProducerConfig struct {
+	// Async buffers sends and writes them in batches instead of waiting for
+	// each message's acknowledgement
+	Async bool `mapstructure:"async"`
+	// BatchSize is the most messages written in one batch
+	BatchSize int `mapstructure:"batch_size"`
+	// BatchTimeout is how long a partial batch waits for more messages
+	BatchTimeout time.Duration `mapstructure:"batch_timeout"`
+}
+
+// Dependency names used in DependenciesConfig
+const (
+	DependencyPostgres = "postgres"
+	DependencyKafka    = "kafka"
+	DependencyRedis    = "redis"
+)
+
+// DependenciesConfig classifies external dependencies. A required dependency
+// that is unreachable at startup stops the service; an optional one starts it
+// degraded, and is retried in the background.
+type DependenciesConfig struct {
+	Required []string `mapstructure:"required"`
+	// CheckInterval is how often dependencies are re-checked
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+}
+
+// IsRequired reports whether the named dependency is required
+func (c DependenciesConfig) IsRequired(name string) bool {
+	for _, required := range c.Required {
+		if required == name {
+			return true
+		}
+	}
+	return false
 }
 
 // Topics holds Kafka topic names
@@ -77,20 +390,127 @@ type Topics struct {
 	RedemptionRequest  string `mapstructure:"redemption_request"`
 	RedemptionComplete string `mapstructure:"redemption_complete"`
 	RedemptionFailed   string `mapstructure:"redemption_failed"`
+	// PasswordResetRequested carries reset tokens from auth to notify
+	PasswordResetRequested string `mapstructure:"password_reset_requested"`
 }
 
 // SecurityConfig holds security-related configuration
 type SecurityConfig struct {
-	JWT  JWTConfig  `mapstructure:"jwt"`
-	MTLS MTLSConfig `mapstructure:"mtls"`
+	JWT          JWTConfig          `mapstructure:"jwt"`
+	MTLS         MTLSConfig         `mapstructure:"mtls"`
+	Password     PasswordConfig     `mapstructure:"password"`
+	Registration RegistrationConfig `mapstructure:"registration"`
+	RateLimit    RateLimitConfig    `mapstructure:"rate_limit"`
+	Tenancy      TenancyConfig      `mapstructure:"tenancy"`
+	CORS         CORSConfig         `mapstructure:"cors"`
+}
+
+// CORSConfig holds the cross-origin settings given to browsers
+type CORSConfig struct {
+	// AllowedOrigins lists the origins browsers may call from, "*" for any.
+	// Empty allows none, except in development, where it allows any origin
+	// without credentials.
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	// AllowedMethods and AllowedHeaders replace the server defaults when set
+	AllowedMethods   []string      `mapstructure:"allowed_methods"`
+	AllowedHeaders   []string      `mapstructure:"allowed_headers"`
+	AllowCredentials bool          `mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `mapstructure:"max_age"`
 }
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
+	// Algorithm is HS256, signing with Secret, or RS256, signing with
+	// PrivateKey. An RS256 service given only PublicKey validates tokens but
+	// cannot issue them.
+	Algorithm string `mapstructure:"algorithm"`
+	// PrivateKey and PublicKey are PEM encoded RSA keys
+	PrivateKey string        `mapstructure:"private_key"`
+	PublicKey  string        `mapstructure:"public_key"`
 	Secret     string        `mapstructure:"secret"`
 	Issuer     string        `mapstructure:"issuer"`
 	Audience   string        `mapstructure:"audience"`
 	Expiration time.Duration `mapstructure:"expiration"`
+	// RefreshExpiration is how long a refresh token may be exchanged
+	RefreshExpiration time.Duration `mapstructure:"refresh_expiration"`
+	// Revocation checks every token against the Redis denylist written by
+	// logout. Requests are refused while Redis is unreachable.
+	Revocation     bool          `mapstructure:"revocation"`
+	JWKSURL        string        `mapstructure:"jwks_url"`
+	JWKSCacheTTL   time.Duration `mapstructure:"jwks_cache_ttl"`
+	JWKSStaleGrace time.Duration `mapstructure:"jwks_stale_grace"`
+	// PreviousPublicKeys are PEM encoded RSA public keys retired by a
+	// rotation, which keep validating and being published until the tokens
+	// they signed expire
+	PreviousPublicKeys string `mapstructure:"previous_public_keys"`
+	// RotationGrace is how long a signing key replaced at runtime keeps
+	// validating tokens; 0 uses Expiration
+	RotationGrace time.Duration `mapstructure:"rotation_grace"`
+}
+
+// PasswordConfig holds password hashing configuration
+type PasswordConfig struct {
+	// PrehashLongPasswords SHA-256 hashes passwords longer than bcrypt's
+	// 72-byte limit instead of rejecting them
+	PrehashLongPasswords bool `mapstructure:"prehash_long_passwords"`
+	// MinLength is the fewest characters a new password may have
+	MinLength int `mapstructure:"min_length"`
+	// RequireMixedCase requires new passwords to mix upper and lower case
+	RequireMixedCase bool `mapstructure:"require_mixed_case"`
+	// RequireDigit requires new passwords to contain a digit
+	RequireDigit bool `mapstructure:"require_digit"`
+	// ResetTokenTTL is how long a password reset token can be used
+	ResetTokenTTL time.Duration `mapstructure:"reset_token_ttl"`
+}
+
+// RegistrationConfig restricts which email domains may register.
+// Domains may use wildcards, e.g. "*.example.com".
+type RegistrationConfig struct {
+	AllowedEmailDomains []string `mapstructure:"allowed_email_domains"`
+	DeniedEmailDomains  []string `mapstructure:"denied_email_domains"`
+	// DefaultPolicy ("allow" or "deny") applies to domains on neither list
+	DefaultPolicy string `mapstructure:"default_policy"`
+}
+
+// RateLimitConfig holds per-client rate limiting configuration
+type RateLimitConfig struct {
+	Enabled           bool `mapstructure:"enabled"`
+	RequestsPerMinute int  `mapstructure:"requests_per_minute"`
+	Burst             int  `mapstructure:"burst"`
+	// Headers emits the IETF draft RateLimit-Limit/Remaining/Reset headers
+	Headers bool `mapstructure:"headers"`
+	// Redis shares limits across replicas through Redis. While Redis is
+	// unreachable each replica limits in memory.
+	Redis bool `mapstructure:"redis"`
+	// Routes overrides the limits of named routes, such as "auth_login"
+	Routes map[string]RouteRateLimitConfig `mapstructure:"routes"`
+}
+
+// RouteRateLimitConfig holds the limits for a single route
+type RouteRateLimitConfig struct {
+	RequestsPerMinute int `mapstructure:"requests_per_minute"`
+	Burst             int `mapstructure:"burst"`
+}
+
+// ForRoute returns the limits for a named route, falling back to the
+// global limits for any the route does not set
+func (c RateLimitConfig) ForRoute(name string) RouteRateLimitConfig {
+	limits := c.Routes[name]
+	if limits.RequestsPerMinute <= 0 {
+		limits.RequestsPerMinute = c.RequestsPerMinute
+	}
+	if limits.Burst <= 0 {
+		limits.Burst = c.Burst
+	}
+	return limits
+}
+
+// TenancyConfig holds multi-tenancy configuration. When enabled, tokens carry
+// a tenant claim and all data access is scoped to that tenant.
+type TenancyConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// DefaultTenant owns all data when multi-tenancy is disabled
+	DefaultTenant string `mapstructure:"default_tenant"`
 }
 
 // MTLSConfig holds mTLS configuration
@@ -101,172 +521,237 @@ type MTLSConfig struct {
 	CAFile   string `mapstructure:"ca_file"`
 }
 
-// OTelConfig holds OpenTelemetry configuration
+// OTelConfig holds OpenTelemetry tracing configuration
 type OTelConfig struct {
-	Enabled      bool   `mapstructure:"enabled"`
-	ServiceName  string `mapstructure:"service_name"`
+	Enabled bool `mapstructure:"enabled"`
+	// ServiceName overrides the service name reported with spans
+	ServiceName string `mapstructure:"service_name"`
+	// OTLPEndpoint is the collector's OTLP/HTTP base URL; spans are posted to /v1/traces
 	OTLPEndpoint string `mapstructure:"otlp_endpoint"`
 }
 
-// Load loads configuration from environment variables and config files
+// MetricsConfig holds Prometheus metrics configuration
+type MetricsConfig struct {
+	// Enabled serves /metrics and records HTTP request metrics
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// Load loads configuration from environment variables and config files and
+// validates it
 func Load(serviceName string) (*Config, error) {
-	// Set defaults first
-	viper.SetDefault("app.name", serviceName)
-	viper.SetDefault("app.http_addr", ":8080")
-	viper.SetDefault("app.log_level", "info")
-	viper.SetDefault("app.shutdown_timeout", "15s")
-	viper.SetDefault("app.environment", "development")
-	viper.SetDefault("app.version", "1.0.0")
-
-	viper.SetDefault("database.postgres.host", "localhost")
-	viper.SetDefault("database.postgres.port", 5432)
-	viper.SetDefault("database.postgres.ssl_mode", "disable")
-	viper.SetDefault("database.postgres.max_conns", 10)
-
-	viper.SetDefault("database.mongo.timeout", "10s")
-
-	viper.SetDefault("redis.addr", "localhost:6379")
-	viper.SetDefault("redis.db", 0)
-	viper.SetDefault("redis.pool_size", 10)
-
-	viper.SetDefault("kafka.brokers", []string{"localhost:9092"})
-	viper.SetDefault("kafka.version", "2.8.0")
-	viper.SetDefault("kafka.topics.points_earned", "points.earned.v1")
-	viper.SetDefault("kafka.topics.redemption_request", "redemption.requested.v1")
-	viper.SetDefault("kafka.topics.redemption_complete", "redemption.completed.v1")
-	viper.SetDefault("kafka.topics.redemption_failed", "redemption.failed.v1")
-
-	viper.SetDefault("security.jwt.expiration", "24h")
-	viper.SetDefault("security.mtls.enabled", false)
-
-	viper.SetDefault("otel.enabled", true)
-	viper.SetDefault("otel.otlp_endpoint", "http://localhost:4317")
-
-	// DEBUG: Print environment variable prefix and some key values
-	fmt.Printf("=== CONFIG LOADER DEBUG ===\n")
-	fmt.Printf("Service Name: %s\n", serviceName)
-	fmt.Printf("Environment Prefix: %s\n", strings.ToUpper(serviceName))
-	fmt.Printf("Looking for env vars like: %s_APP_HTTP_ADDR\n", strings.ToUpper(serviceName))
-
-	// Try to read config file
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("./config")
-	viper.AddConfigPath(fmt.Sprintf("./cmd/%s", serviceName))
-
-	// Try to read .env file
-	currentDir, _ := os.Getwd()
-	possiblePaths := []string{
-		".env",                                  // Current directory
-		"../.env",                               // Parent directory
-		"../../.env",                            // Two levels up
-		filepath.Join(currentDir, ".env"),       // Absolute path in current dir
-		filepath.Join(currentDir, "..", ".env"), // Absolute path in parent
-		filepath.Join(currentDir, "..", "..", ".env"), // Absolute path two levels up
-	}
+	v := viper.New()
 
-	var envPath string
-	for _, path := range possiblePaths {
-		if _, err := os.Stat(path); err == nil {
-			envPath = path
-			fmt.Printf("✅ Found .env file at: %s\n", path)
-			break
+	// Set defaults first
+	v.SetDefault("app.name", serviceName)
+	v.SetDefault("app.http_addr", ":8080")
+	v.SetDefault("app.log_level", "info")
+	v.SetDefault("app.shutdown_timeout", "15s")
+	v.SetDefault("app.max_body_bytes", 1<<20)
+	v.SetDefault("app.environment", "development")
+	v.SetDefault("app.version", "1.0.0")
+
+	v.SetDefault("database.postgres.host", "localhost")
+	v.SetDefault("database.postgres.port", 5432)
+	v.SetDefault("database.postgres.database", "loyalty")
+	v.SetDefault("database.postgres.ssl_mode", "disable")
+	v.SetDefault("database.postgres.max_conns", 10)
+	v.SetDefault("database.postgres.connect_timeout", "5s")
+	v.SetDefault("database.postgres.application_name", serviceName)
+	v.SetDefault("database.postgres.max_conn_lifetime", "1h")
+	v.SetDefault("database.postgres.max_conn_idle_time", "30m")
+	v.SetDefault("database.postgres.health_check_period", "1m")
+	v.SetDefault("database.postgres.stats_interval", "15s")
+	v.SetDefault("database.postgres.connect_retry.max_attempts", 5)
+	v.SetDefault("database.postgres.connect_retry.base_delay", "500ms")
+	v.SetDefault("database.postgres.connect_retry.max_delay", "5s")
+	v.SetDefault("database.migrations", "apply")
+
+	v.SetDefault("database.mongo.timeout", "10s")
+
+	v.SetDefault("redis.addr", "localhost:6379")
+	v.SetDefault("redis.db", 0)
+	v.SetDefault("redis.pool_size", 10)
+
+	v.SetDefault("cache.benefits_ttl", "300s")
+	v.SetDefault("cache.user_profiles.enabled", false)
+	v.SetDefault("cache.user_profiles.ttl", "30s")
+	v.SetDefault("cache.client_max_age", "10s")
+
+	v.SetDefault("catalog.max_points_cost", 100000)
+	v.SetDefault("catalog.max_import_rows", 1000)
+
+	v.SetDefault("loyalty.hold_ttl", "15m")
+	v.SetDefault("loyalty.hold_sweep_interval", "1m")
+	v.SetDefault("loyalty.orphan_grace", "1h")
+	v.SetDefault("loyalty.max_balance_lookup", 100)
+	v.SetDefault("loyalty.max_earn_batch", 500)
+	v.SetDefault("loyalty.points_expiry", "8760h")
+	v.SetDefault("loyalty.expiry_sweep_interval", "1h")
+	v.SetDefault("loyalty.balance_cache_ttl", "30s")
+	v.SetDefault("loyalty.adjustment_min_balance", 0)
+	v.SetDefault("loyalty.tiers", []map[string]interface{}{
+		{"name": "Bronze", "min_points": 0},
+		{"name": "Silver", "min_points": 5000},
+		{"name": "Gold", "min_points": 20000},
+		{"name": "Platinum", "min_points": 50000},
+	})
+
+	v.SetDefault("redemption.points_holds", true)
+	v.SetDefault("redemption.saga_timeout", "30s")
+	v.SetDefault("redemption.step_timeout", "10s")
+	v.SetDefault("redemption.benefit_cache_ttl", "1m")
+	v.SetDefault("redemption.partner_retry.max_attempts", 3)
+	v.SetDefault("redemption.partner_retry.base_delay", "200ms")
+	v.SetDefault("redemption.partner_retry.max_delay", "2s")
+	v.SetDefault("redemption.capture_retry.max_attempts", 5)
+	v.SetDefault("redemption.capture_retry.base_delay", "500ms")
+	v.SetDefault("redemption.capture_retry.max_delay", "10s")
+	v.SetDefault("redemption.partner_breaker.failure_ratio", 0.5)
+	v.SetDefault("redemption.partner_breaker.min_requests", 10)
+	v.SetDefault("redemption.partner_breaker.window", "1m")
+	v.SetDefault("redemption.partner_breaker.open_timeout", "30s")
+	v.SetDefault("redemption.partner_breaker.half_open_probes", 3)
+	v.SetDefault("redemption.outbox.poll_interval", "1s")
+	v.SetDefault("redemption.events_poll_interval", "2s")
+	v.SetDefault("redemption.idempotency_key_ttl", "24h")
+	v.SetDefault("redemption.idempotency_sweep_interval", "1h")
+	v.SetDefault("redemption.outbox.batch_size", 100)
+	v.SetDefault("redemption.outbox.claim_timeout", "1m")
+
+	v.SetDefault("notify.email.max_per_second", 10)
+	v.SetDefault("notify.email.max_in_flight", 5)
+	v.SetDefault("notify.sms.max_per_second", 1)
+	v.SetDefault("notify.sms.max_in_flight", 1)
+	v.SetDefault("notify.push.max_per_second", 50)
+	v.SetDefault("notify.push.max_in_flight", 10)
+	v.SetDefault("notify.send_timeout", "10s")
+	v.SetDefault("notify.outcome_retention", 1000)
+	v.SetDefault("notify.redemption_channels", []string{"email"})
+	v.SetDefault("notify.providers.email", "log")
+	v.SetDefault("notify.providers.sms", "log")
+	v.SetDefault("notify.providers.smtp.port", 587)
+	v.SetDefault("notify.content.sms_max_segments", 3)
+	v.SetDefault("notify.content.sms_overflow", "reject")
+	v.SetDefault("notify.content.subject_max_length", 200)
+	v.SetDefault("notify.webhooks.poll_interval", "5s")
+	v.SetDefault("notify.webhooks.batch_size", 50)
+	v.SetDefault("notify.webhooks.timeout", "10s")
+	v.SetDefault("notify.webhooks.claim_timeout", "1m")
+	v.SetDefault("notify.webhooks.retry.max_attempts", 8)
+	v.SetDefault("notify.webhooks.retry.base_delay", "30s")
+	v.SetDefault("notify.webhooks.retry.max_delay", "1h")
+
+	v.SetDefault("services.catalog_url", "http://localhost:8083")
+	v.SetDefault("services.loyalty_url", "http://localhost:8082")
+	v.SetDefault("services.partner_gateway_url", "http://localhost:8085")
+	v.SetDefault("services.request_timeout", "10s")
+
+	v.SetDefault("kafka.driver", "kafka")
+	v.SetDefault("dependencies.required", []string{DependencyPostgres})
+	v.SetDefault("dependencies.check_interval", "10s")
+	v.SetDefault("kafka.brokers", []string{"localhost:9092"})
+	v.SetDefault("kafka.version", "2.8.0")
+	v.SetDefault("kafka.consumer_retry.max_attempts", 3)
+	v.SetDefault("kafka.consumer_retry.base_delay", "500ms")
+	v.SetDefault("kafka.consumer_retry.max_delay", "10s")
+	v.SetDefault("kafka.dead_letter", true)
+	v.SetDefault("kafka.lag_interval", "30s")
+	v.SetDefault("kafka.producer.async", false)
+	v.SetDefault("kafka.producer.batch_size", 100)
+	v.SetDefault("kafka.producer.batch_timeout", "50ms")
+	v.SetDefault("kafka.topics.points_earned", "points.earned.v1")
+	v.SetDefault("kafka.topics.redemption_request", "redemption.requested.v1")
+	v.SetDefault("kafka.topics.redemption_complete", "redemption.completed.v1")
+	v.SetDefault("kafka.topics.redemption_failed", "redemption.failed.v1")
+	v.SetDefault("kafka.topics.password_reset_requested", "password.reset.requested.v1")
+
+	v.SetDefault("security.jwt.algorithm", JWTAlgorithmHS256)
+	v.SetDefault("security.jwt.expiration", "24h")
+	v.SetDefault("security.jwt.refresh_expiration", "720h")
+	v.SetDefault("security.jwt.revocation", true)
+	v.SetDefault("security.jwt.jwks_cache_ttl", "5m")
+	v.SetDefault("security.jwt.jwks_stale_grace", "1h")
+	v.SetDefault("security.mtls.enabled", false)
+	v.SetDefault("security.password.prehash_long_passwords", false)
+	v.SetDefault("security.password.min_length", 8)
+	v.SetDefault("security.password.require_mixed_case", false)
+	v.SetDefault("security.password.require_digit", false)
+	v.SetDefault("security.password.reset_token_ttl", "1h")
+	v.SetDefault("security.registration.default_policy", "allow")
+	v.SetDefault("security.rate_limit.enabled", true)
+	v.SetDefault("security.rate_limit.requests_per_minute", 10)
+	v.SetDefault("security.rate_limit.burst", 5)
+	v.SetDefault("security.rate_limit.headers", true)
+	v.SetDefault("security.rate_limit.redis", true)
+	v.SetDefault("security.rate_limit.routes.loyalty_earn.requests_per_minute", 60)
+	v.SetDefault("security.rate_limit.routes.loyalty_earn.burst", 20)
+	v.SetDefault("security.tenancy.enabled", false)
+	v.SetDefault("security.tenancy.default_tenant", "default")
+	v.SetDefault("security.cors.allow_credentials", true)
+	v.SetDefault("security.cors.max_age", "5m")
+
+	v.SetDefault("otel.enabled", false)
+	v.SetDefault("otel.otlp_endpoint", "http://localhost:4318")
+
+	v.SetDefault("metrics.enabled", true)
+
+	// Read the optional config file
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+	v.AddConfigPath(".")
+	v.AddConfigPath("./config")
+	v.AddConfigPath(fmt.Sprintf("./cmd/%s", serviceName))
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
 	}
 
-	// CRITICAL: Configure Viper FIRST, before setting environment variables
-	fmt.Printf("🔄 Configuring Viper...\n")
-	viper.SetEnvPrefix(strings.ToUpper(serviceName))
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	viper.AutomaticEnv()
-
-	// Read .env file manually and set environment variables
-	if _, err := os.Stat(envPath); err == nil {
-		fmt.Printf("✅ Found .env file at: %s\n", envPath)
-
-		// Read file content
-		content, readErr := os.ReadFile(envPath)
-		if readErr != nil {
-			fmt.Printf("❌ Failed to read .env file content: %v\n", readErr)
-		} else {
-			fmt.Printf("✅ Successfully read .env file content (%d bytes)\n", len(content))
-
-			// Parse and set environment variables manually
-			lines := strings.Split(string(content), "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if line != "" && !strings.HasPrefix(line, "#") {
-					parts := strings.SplitN(line, "=", 2)
-					if len(parts) == 2 {
-						key := strings.TrimSpace(parts[0])
-						value := strings.TrimSpace(parts[1])
-						// Remove quotes if present
-						value = strings.Trim(value, "\"'")
-
-						// Set environment variable
-						os.Setenv(key, value)
-						fmt.Printf("   Set env var: %s = '%s'\n", key, value)
-					}
-				}
-			}
-		}
-
-		// IMPORTANT: Refresh Viper after setting environment variables
-		fmt.Printf("🔄 Refreshing Viper configuration...\n")
-		viper.AutomaticEnv()
-
-		// DEBUG: Check if Viper can now see the environment variables
-		fmt.Printf("\n=== VIPER CONFIG DEBUG ===\n")
-		appHTTPAddr := viper.GetString("app.http_addr")
-		appLogLevel := viper.GetString("app.log_level")
-		dbHost := viper.GetString("database.postgres.host")
-		dbUser := viper.GetString("database.postgres.username")
-		dbPass := viper.GetString("database.postgres.password")
-		dbName := viper.GetString("database.postgres.database")
-
-		fmt.Printf("App HTTP Addr: '%s'\n", appHTTPAddr)
-		fmt.Printf("App Log Level: '%s'\n", appLogLevel)
-		fmt.Printf("DB Host: '%s'\n", dbHost)
-		fmt.Printf("DB User: '%s'\n", dbUser)
-		fmt.Printf("DB Password: '%s' (length: %d)\n", dbPass, len(dbPass))
-		fmt.Printf("DB Name: '%s'\n", dbName)
-		fmt.Printf("=== END VIPER CONFIG DEBUG ===\n")
-
-	} else {
-		fmt.Printf("❌ .env file not found in any expected location\n")
+	// Variables from a .env file fill in for any not set in the environment
+	if err := loadDotEnv(); err != nil {
+		return nil, err
 	}
 
-	// Final Viper refresh and environment variable binding
-	viper.AutomaticEnv()
-
-	// Manually bind environment variables to Viper keys
-	viper.BindEnv("database.postgres.username", "AUTH-SVC_DATABASE_POSTGRES_USERNAME")
-	viper.BindEnv("database.postgres.password", "AUTH-SVC_DATABASE_POSTGRES_PASSWORD")
-	viper.BindEnv("database.postgres.database", "AUTH-SVC_DATABASE_POSTGRES_DATABASE")
-	viper.BindEnv("database.postgres.host", "AUTH-SVC_DATABASE_POSTGRES_HOST")
-	viper.BindEnv("database.postgres.port", "AUTH-SVC_DATABASE_POSTGRES_PORT")
-	viper.BindEnv("database.postgres.ssl_mode", "AUTH-SVC_DATABASE_POSTGRES_SSL_MODE")
-	viper.BindEnv("database.postgres.max_conns", "AUTH-SVC_DATABASE_POSTGRES_MAX_CONNS")
-
-	// Bind JWT security configuration
-	viper.BindEnv("security.jwt.secret", "JWT_SECRET")
-	viper.BindEnv("security.jwt.issuer", "JWT_ISSUER")
-	viper.BindEnv("security.jwt.audience", "JWT_AUDIENCE")
-	viper.BindEnv("security.jwt.expiration", "JWT_EXPIRATION")
+	// Environment variables override the config file, preferring the
+	// service's own prefix over the shared unprefixed name
+	prefixes := envPrefixes(serviceName)
+	bindEnv(v, reflect.TypeOf(Config{}), "", prefixes)
+	v.SetEnvPrefix(prefixes[0])
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	if err := loadSecretFiles(v, prefixes); err != nil {
+		return nil, err
+	}
 
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	// Local frontends work unconfigured in development
+	if cors := &config.Security.CORS; len(cors.AllowedOrigins) == 0 && config.App.Environment == EnvironmentDevelopment {
+		cors.AllowedOrigins = []string{"*"}
+		cors.AllowCredentials = false
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
 
-// GetDSN returns the PostgreSQL connection string
+// GetDSN returns the PostgreSQL connection string as a postgres:// URL
 func (c *PostgresConfig) GetDSN() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		c.Host, c.Port, c.Username, c.Password, c.Database, c.SSLMode)
+	return dsn.Postgres{
+		Host:            c.Host,
+		Port:            c.Port,
+		User:            c.Username,
+		Password:        c.Password,
+		Database:        c.Database,
+		SSLMode:         c.SSLMode,
+		ConnectTimeout:  c.ConnectTimeout,
+		ApplicationName: c.ApplicationName,
+	}.String()
 }
 
 // GetMongoURI returns the MongoDB connection URI
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setRequiredEnv sets the variables Load needs to pass validation
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret-at-least-32-characters")
+}
+
+func TestLoadPoolSettings(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := Load("loyalty-svc")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	pg := cfg.Database.Postgres
+	if pg.MaxConnLifetime != time.Hour || pg.MaxConnIdleTime != 30*time.Minute || pg.HealthCheckPeriod != time.Minute || pg.StatsInterval != 15*time.Second {
+		t.Fatalf("defaults = %s, %s, %s, %s; want 1h, 30m, 1m, 15s", pg.MaxConnLifetime, pg.MaxConnIdleTime, pg.HealthCheckPeriod, pg.StatsInterval)
+	}
+
+	t.Setenv("DATABASE_POSTGRES_MAX_CONNS", "25")
+	t.Setenv("DATABASE_POSTGRES_MAX_CONN_LIFETIME", "2h")
+	t.Setenv("DATABASE_POSTGRES_MAX_CONN_IDLE_TIME", "5m")
+	t.Setenv("DATABASE_POSTGRES_HEALTH_CHECK_PERIOD", "10s")
+	t.Setenv("DATABASE_POSTGRES_STATS_INTERVAL", "0")
+	cfg, err = Load("loyalty-svc")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	pg = cfg.Database.Postgres
+	if pg.MaxConns != 25 || pg.MaxConnLifetime != 2*time.Hour || pg.MaxConnIdleTime != 5*time.Minute || pg.HealthCheckPeriod != 10*time.Second || pg.StatsInterval != 0 {
+		t.Fatalf("overrides = %d, %s, %s, %s, %s; want 25, 2h, 5m, 10s, 0s", pg.MaxConns, pg.MaxConnLifetime, pg.MaxConnIdleTime, pg.HealthCheckPeriod, pg.StatsInterval)
+	}
+}
+
+func TestLoadReadsEachServicesPrefix(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("AUTH-SVC_DATABASE_POSTGRES_USERNAME", "auth")
+	t.Setenv("LOYALTY_SVC_DATABASE_POSTGRES_USERNAME", "loyalty")
+	t.Setenv("DATABASE_POSTGRES_USERNAME", "shared")
+
+	tests := []struct {
+		service string
+		want    string
+	}{
+		{"auth-svc", "auth"},
+		{"loyalty-svc", "loyalty"},
+		// Services without their own variable fall back to the shared one
+		{"catalog-svc", "shared"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			cfg, err := Load(tt.service)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if got := cfg.Database.Postgres.Username; got != tt.want {
+				t.Fatalf("username = %q, want %q", got, tt.want)
+			}
+			if got := cfg.App.Name; got != tt.service {
+				t.Fatalf("app name = %q, want %q", got, tt.service)
+			}
+		})
+	}
+
+	// The service's exact name wins over its underscored form
+	t.Setenv("LOYALTY-SVC_DATABASE_POSTGRES_USERNAME", "loyalty-hyphenated")
+	cfg, err := Load("loyalty-svc")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.Database.Postgres.Username; got != "loyalty-hyphenated" {
+		t.Fatalf("username = %q, want the hyphenated variable", got)
+	}
+}
+
+// chdir changes to dir for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// unsetenv unsets name for the rest of the test, restoring it afterwards
+func unsetenv(t *testing.T, name string) {
+	t.Helper()
+	t.Setenv(name, "")
+	os.Unsetenv(name)
+}
+
+func TestLoadReadsDotEnv(t *testing.T) {
+	setRequiredEnv(t)
+	dir := t.TempDir()
+	chdir(t, dir)
+	dotEnv := "DATABASE_POSTGRES_HOST=db.from-dotenv\nLOYALTY_SVC_DATABASE_POSTGRES_DATABASE=loyalty_dotenv\nDATABASE_POSTGRES_PORT=6543\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotEnv), 0o600); err != nil {
+		t.Fatalf("write .env: %v", err)
+	}
+	unsetenv(t, "DATABASE_POSTGRES_HOST")
+	unsetenv(t, "LOYALTY_SVC_DATABASE_POSTGRES_DATABASE")
+	// The real environment wins over the file
+	t.Setenv("DATABASE_POSTGRES_PORT", "7654")
+
+	cfg, err := Load("loyalty-svc")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	pg := cfg.Database.Postgres
+	if pg.Host != "db.from-dotenv" || pg.Database != "loyalty_dotenv" || pg.Port != 7654 {
+		t.Fatalf("postgres = %s:%d/%s, want db.from-dotenv:7654/loyalty_dotenv", pg.Host, pg.Port, pg.Database)
+	}
+}
+
+func TestLoadCORS(t *testing.T) {
+	setRequiredEnv(t)
+	unsetenv(t, "APP_ENVIRONMENT")
+	unsetenv(t, "SECURITY_CORS_ALLOWED_ORIGINS")
+
+	// Development allows any origin, without credentials
+	cfg, err := Load("loyalty-svc")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cors := cfg.Security.CORS; len(cors.AllowedOrigins) != 1 || cors.AllowedOrigins[0] != "*" || cors.AllowCredentials {
+		t.Fatalf("development CORS = %+v, want any origin without credentials", cors)
+	}
+
+	// Elsewhere no origin is allowed until one is listed
+	t.Setenv("APP_ENVIRONMENT", EnvironmentProduction)
+	if cfg, err = Load("loyalty-svc"); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cors := cfg.Security.CORS; len(cors.AllowedOrigins) != 0 {
+		t.Fatalf("production CORS origins = %q, want none", cors.AllowedOrigins)
+	}
+
+	t.Setenv("SECURITY_CORS_ALLOWED_ORIGINS", "https://app.example.com,https://admin.example.com")
+	t.Setenv("SECURITY_CORS_MAX_AGE", "10m")
+	if cfg, err = Load("loyalty-svc"); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	cors := cfg.Security.CORS
+	if len(cors.AllowedOrigins) != 2 || cors.AllowedOrigins[1] != "https://admin.example.com" || !cors.AllowCredentials || cors.MaxAge != 10*time.Minute {
+		t.Fatalf("configured CORS = %+v, want both origins with credentials and a 10m max age", cors)
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// dotEnvPaths are where Load looks for a .env file, nearest first, so
+// services run from the repository root or a cmd directory find the same one
+var dotEnvPaths = []string{".env", "../.env", "../../.env"}
+
+// envAliases are extra variable names accepted for a key, after the
+// service-prefixed and unprefixed ones
+var envAliases = map[string][]string{
+	"security.jwt.algorithm":            {"JWT_ALGORITHM"},
+	"security.jwt.private_key":          {"JWT_PRIVATE_KEY"},
+	"security.jwt.public_key":           {"JWT_PUBLIC_KEY"},
+	"security.jwt.previous_public_keys": {"JWT_PREVIOUS_PUBLIC_KEYS"},
+	"security.jwt.secret":               {"JWT_SECRET"},
+	"security.jwt.issuer":               {"JWT_ISSUER"},
+	"security.jwt.audience":             {"JWT_AUDIENCE"},
+	"security.jwt.expiration":           {"JWT_EXPIRATION"},
+	"security.jwt.refresh_expiration":   {"JWT_REFRESH_EXPIRATION"},
+}
+
+// secretKeys are the keys that may also be read from a file named by a
+// _FILE variable
+var secretKeys = []string{
+	"database.postgres.password",
+	"database.mongo.uri",
+	"redis.password",
+	"notify.providers.smtp.password",
+	"notify.providers.sms_webhook.token",
+	"security.jwt.secret",
+	"security.jwt.private_key",
+	// Not a secret, but PEM keys are easier to mount than to inline
+	"security.jwt.public_key",
+	"security.jwt.previous_public_keys",
+}
+
+// envPrefixes returns the variable prefixes a service reads, most specific
+// first: its name uppercased (LOYALTY-SVC), the same with underscores for
+// shells that reject hyphens (LOYALTY_SVC), and no prefix as the shared
+// fallback
+func envPrefixes(serviceName string) []string {
+	prefix := strings.ToUpper(serviceName)
+	prefixes := []string{prefix}
+	if underscored := strings.ReplaceAll(prefix, "-", "_"); underscored != prefix {
+		prefixes = append(prefixes, underscored)
+	}
+	return append(prefixes, "")
+}
+
+// bindEnv binds each key of the config struct t to its environment variables
+// in prefix order. Keys must be bound explicitly because viper only applies
+// AutomaticEnv to keys it already knows of, and most have no default.
+func bindEnv(v *viper.Viper, t reflect.Type, parent string, prefixes []string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := name
+		if parent != "" {
+			key = parent + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			bindEnv(v, field.Type, key, prefixes)
+			continue
+		}
+
+		_ = v.BindEnv(append([]string{key}, envNames(key, prefixes)...)...)
+	}
+}
+
+// envNames returns the variables that may set key, in precedence order
+func envNames(key string, prefixes []string) []string {
+	suffix := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	var names []string
+	for _, prefix := range prefixes {
+		if prefix == "" {
+			names = append(names, suffix)
+		} else {
+			names = append(names, prefix+"_"+suffix)
+		}
+	}
+	return append(names, envAliases[key]...)
+}
+
+// loadSecretFiles sets each secret key whose variable has a _FILE
+// counterpart (JWT_SECRET_FILE, DATABASE_POSTGRES_PASSWORD_FILE) to the
+// contents of that file, as mounted by Docker and Kubernetes secrets. A file
+// takes precedence over the inline variable.
+func loadSecretFiles(v *viper.Viper, prefixes []string) error {
+	for _, key := range secretKeys {
+		for _, name := range envNames(key, prefixes) {
+			path := os.Getenv(name + "_FILE")
+			if path == "" {
+				continue
+			}
+			secret, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s_FILE: %w", name, err)
+			}
+			// Editors and echo leave a trailing newline that is not part of
+			// the secret
+			v.Set(key, strings.TrimRight(string(secret), "\r\n"))
+			break
+		}
+	}
+	return nil
+}
+
+// loadDotEnv sets the variables in the nearest .env file that are not
+// already set, so the real environment always wins
+func loadDotEnv() error {
+	var path string
+	for _, candidate := range dotEnvPaths {
+		if _, err := os.Stat(candidate); err == nil {
+			path = candidate
+			break
+		}
+	}
+	if path == "" {
+		return nil
+	}
+
+	dotEnv := viper.New()
+	dotEnv.SetConfigFile(path)
+	dotEnv.SetConfigType("env")
+	if err := dotEnv.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	// Viper lowercases keys; variable names in .env files are uppercase
+	for _, key := range dotEnv.AllKeys() {
+		name := strings.ToUpper(key)
+		if _, set := os.LookupEnv(name); !set {
+			os.Setenv(name, dotEnv.GetString(key))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSecret writes contents to a file in a temporary directory, returning
+// its path
+func writeSecret(t *testing.T, name, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadReadsSecretFiles(t *testing.T) {
+	// The file wins over the inline variable, without its trailing newline
+	t.Setenv("JWT_SECRET", "inline-secret-at-least-16")
+	t.Setenv("JWT_SECRET_FILE", writeSecret(t, "jwt_secret", "file-secret-at-least-16\n"))
+	t.Setenv("DATABASE_POSTGRES_PASSWORD_FILE", writeSecret(t, "db_password", "hunter2\r\n"))
+	t.Setenv("LOYALTY_SVC_REDIS_PASSWORD_FILE", writeSecret(t, "redis_password", "redis-secret"))
+
+	cfg, err := Load("loyalty-svc")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.Security.JWT.Secret; got != "file-secret-at-least-16" {
+		t.Errorf("JWT secret = %q, want the file's contents", got)
+	}
+	if got := cfg.Database.Postgres.Password; got != "hunter2" {
+		t.Errorf("postgres password = %q, want hunter2", got)
+	}
+	if got := cfg.Redis.Password; got != "redis-secret" {
+		t.Errorf("redis password = %q, want the service's file", got)
+	}
+}
+
+func TestLoadSecretFileErrors(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DATABASE_POSTGRES_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	_, err := Load("loyalty-svc")
+	if err == nil || !strings.Contains(err.Error(), "DATABASE_POSTGRES_PASSWORD_FILE") {
+		t.Fatalf("Load = %v, want an error naming the variable", err)
+	}
+}
+
+func TestEnvNames(t *testing.T) {
+	got := envNames("security.jwt.secret", envPrefixes("loyalty-svc"))
+	want := []string{"LOYALTY-SVC_SECURITY_JWT_SECRET", "LOYALTY_SVC_SECURITY_JWT_SECRET", "SECURITY_JWT_SECRET", "JWT_SECRET"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("envNames = %v, want %v", got, want)
+	}
+}
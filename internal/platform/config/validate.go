@@ -0,0 +1,165 @@
+package config
+
+import (
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// minJWTSecretLength is the shortest JWT signing secret accepted
+	minJWTSecretLength = 16
+	// minProductionJWTSecretLength is the shortest secret accepted in production
+	minProductionJWTSecretLength = 32
+)
+
+// JWT signing algorithms accepted for security.jwt.algorithm
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+)
+
+// EnvironmentProduction is the app.environment value for production
+// deployments, which are held to stricter checks
+const EnvironmentProduction = "production"
+
+// EnvironmentDevelopment is the default app.environment, for local use
+const EnvironmentDevelopment = "development"
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the settings every service depends on, returning a
+// *ValidationError that lists all problems at once rather than the first
+func (c *Config) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if _, port, err := net.SplitHostPort(c.App.HTTPAddr); err != nil {
+		addf("app.http_addr %q is not a host:port address", c.App.HTTPAddr)
+	} else if !validPort(port) {
+		addf("app.http_addr %q has an invalid port", c.App.HTTPAddr)
+	}
+	if c.App.ShutdownTimeout <= 0 {
+		addf("app.shutdown_timeout must be positive")
+	}
+
+	pg := c.Database.Postgres
+	if pg.Host == "" {
+		addf("database.postgres.host is required")
+	}
+	if pg.Port < 1 || pg.Port > 65535 {
+		addf("database.postgres.port %d is outside 1-65535", pg.Port)
+	}
+	if pg.Database == "" {
+		addf("database.postgres.database is required")
+	}
+	if pg.MaxConns <= 0 {
+		addf("database.postgres.max_conns must be positive")
+	}
+	if pg.ConnectTimeout < 0 {
+		addf("database.postgres.connect_timeout must not be negative")
+	}
+
+	jwt := c.Security.JWT
+	minSecret := minJWTSecretLength
+	if c.App.Environment == EnvironmentProduction {
+		minSecret = minProductionJWTSecretLength
+	}
+	switch jwt.Algorithm {
+	case JWTAlgorithmHS256:
+		switch {
+		case jwt.Secret == "":
+			addf("security.jwt.secret is required")
+		case len(jwt.Secret) < minSecret:
+			addf("security.jwt.secret must be at least %d bytes in %s, got %d", minSecret, c.App.Environment, len(jwt.Secret))
+		}
+	case JWTAlgorithmRS256:
+		for _, problem := range validateRSAKeys(jwt.PrivateKey, jwt.PublicKey) {
+			addf("%s", problem)
+		}
+		if problem := validatePublicKeyBundle(jwt.PreviousPublicKeys); problem != "" {
+			addf("security.jwt.previous_public_keys %s", problem)
+		}
+	default:
+		addf("security.jwt.algorithm must be %s or %s, got %q", JWTAlgorithmHS256, JWTAlgorithmRS256, jwt.Algorithm)
+	}
+	if jwt.Expiration <= 0 {
+		addf("security.jwt.expiration must be positive")
+	}
+	if jwt.RefreshExpiration <= 0 {
+		addf("security.jwt.refresh_expiration must be positive")
+	}
+	if c.Redemption.IdempotencyKeyTTL <= 0 {
+		addf("redemption.idempotency_key_ttl must be positive")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateRSAKeys checks that the RS256 keys parse, that at least one is set,
+// and that they belong together when both are
+func validateRSAKeys(privatePEM, publicPEM string) []string {
+	if privatePEM == "" && publicPEM == "" {
+		return []string{"security.jwt.private_key or security.jwt.public_key is required for RS256"}
+	}
+
+	var problems []string
+	var privateKey *rsa.PrivateKey
+	var publicKey *rsa.PublicKey
+	var err error
+	if privatePEM != "" {
+		if privateKey, err = jwt.ParseRSAPrivateKeyFromPEM([]byte(privatePEM)); err != nil {
+			problems = append(problems, fmt.Sprintf("security.jwt.private_key is not a PEM encoded RSA private key: %v", err))
+		}
+	}
+	if publicPEM != "" {
+		if publicKey, err = jwt.ParseRSAPublicKeyFromPEM([]byte(publicPEM)); err != nil {
+			problems = append(problems, fmt.Sprintf("security.jwt.public_key is not a PEM encoded RSA public key: %v", err))
+		}
+	}
+	if privateKey != nil && publicKey != nil && !privateKey.PublicKey.Equal(publicKey) {
+		problems = append(problems, "security.jwt.public_key does not match security.jwt.private_key")
+	}
+	return problems
+}
+
+// validatePublicKeyBundle checks that every PEM block in a bundle is an RSA
+// public key, returning the problem if one is not
+func validatePublicKeyBundle(bundle string) string {
+	rest := []byte(bundle)
+	for i := 1; ; i++ {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			if strings.TrimSpace(string(rest)) != "" {
+				return "contains text that is not a PEM block"
+			}
+			return ""
+		}
+		if _, err := jwt.ParseRSAPublicKeyFromPEM(pem.EncodeToMemory(block)); err != nil {
+			return fmt.Sprintf("key %d is not an RSA public key: %v", i, err)
+		}
+	}
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
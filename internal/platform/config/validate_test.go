@@ -0,0 +1,155 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns a configuration that passes Validate
+func validConfig() *Config {
+	var c Config
+	c.App.HTTPAddr = ":8080"
+	c.App.ShutdownTimeout = 15 * time.Second
+	c.App.Environment = EnvironmentDevelopment
+	c.Database.Postgres = PostgresConfig{Host: "localhost", Port: 5432, Database: "loyalty", MaxConns: 10}
+	c.Security.JWT = JWTConfig{
+		Algorithm:         JWTAlgorithmHS256,
+		Secret:            strings.Repeat("s", minJWTSecretLength),
+		Expiration:        time.Hour,
+		RefreshExpiration: 24 * time.Hour,
+	}
+	c.Redemption.IdempotencyKeyTTL = 24 * time.Hour
+	return &c
+}
+
+// rsaKeyPEMs returns a new RSA private key and its public key, PEM encoded
+func rsaKeyPEMs(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+}
+
+func TestValidate(t *testing.T) {
+	privateKey, publicKey := rsaKeyPEMs(t)
+	_, otherPublicKey := rsaKeyPEMs(t)
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"valid", func(c *Config) {}, ""},
+		{"http addr", func(c *Config) { c.App.HTTPAddr = "8080" }, "app.http_addr"},
+		{"http port", func(c *Config) { c.App.HTTPAddr = ":70000" }, "app.http_addr \":70000\" has an invalid port"},
+		{"shutdown timeout", func(c *Config) { c.App.ShutdownTimeout = 0 }, "app.shutdown_timeout"},
+		{"postgres host", func(c *Config) { c.Database.Postgres.Host = "" }, "database.postgres.host"},
+		{"postgres port zero", func(c *Config) { c.Database.Postgres.Port = 0 }, "database.postgres.port 0"},
+		{"postgres port too high", func(c *Config) { c.Database.Postgres.Port = 65536 }, "database.postgres.port 65536"},
+		{"postgres database", func(c *Config) { c.Database.Postgres.Database = "" }, "database.postgres.database"},
+		{"max conns", func(c *Config) { c.Database.Postgres.MaxConns = 0 }, "database.postgres.max_conns"},
+		{"connect timeout", func(c *Config) { c.Database.Postgres.ConnectTimeout = -time.Second }, "database.postgres.connect_timeout"},
+		{"missing secret", func(c *Config) { c.Security.JWT.Secret = "" }, "security.jwt.secret is required"},
+		{"short secret", func(c *Config) { c.Security.JWT.Secret = "short" }, "security.jwt.secret must be at least 16 bytes"},
+		{"short production secret", func(c *Config) {
+			c.App.Environment = EnvironmentProduction
+			c.Security.JWT.Secret = strings.Repeat("s", minProductionJWTSecretLength-1)
+		}, "security.jwt.secret must be at least 32 bytes in production"},
+		{"production secret", func(c *Config) {
+			c.App.Environment = EnvironmentProduction
+			c.Security.JWT.Secret = strings.Repeat("s", minProductionJWTSecretLength)
+		}, ""},
+		{"algorithm", func(c *Config) { c.Security.JWT.Algorithm = "none" }, "security.jwt.algorithm"},
+		{"expiration", func(c *Config) { c.Security.JWT.Expiration = 0 }, "security.jwt.expiration"},
+		{"refresh expiration", func(c *Config) { c.Security.JWT.RefreshExpiration = 0 }, "security.jwt.refresh_expiration"},
+		{"idempotency key ttl", func(c *Config) { c.Redemption.IdempotencyKeyTTL = 0 }, "redemption.idempotency_key_ttl"},
+		{"rs256", func(c *Config) {
+			c.Security.JWT = JWTConfig{Algorithm: JWTAlgorithmRS256, PrivateKey: privateKey, PublicKey: publicKey, Expiration: time.Hour, RefreshExpiration: time.Hour}
+		}, ""},
+		{"rs256 without keys", func(c *Config) { c.Security.JWT.Algorithm = JWTAlgorithmRS256 }, "security.jwt.private_key or security.jwt.public_key is required"},
+		{"rs256 bad private key", func(c *Config) {
+			c.Security.JWT.Algorithm = JWTAlgorithmRS256
+			c.Security.JWT.PrivateKey = "not a key"
+		}, "security.jwt.private_key is not a PEM encoded RSA private key"},
+		{"rs256 mismatched keys", func(c *Config) {
+			c.Security.JWT.Algorithm = JWTAlgorithmRS256
+			c.Security.JWT.PrivateKey = privateKey
+			c.Security.JWT.PublicKey = otherPublicKey
+		}, "security.jwt.public_key does not match"},
+		{"previous public keys", func(c *Config) {
+			c.Security.JWT.Algorithm = JWTAlgorithmRS256
+			c.Security.JWT.PublicKey = publicKey
+			c.Security.JWT.PreviousPublicKeys = otherPublicKey + "trailing"
+		}, "security.jwt.previous_public_keys contains text that is not a PEM block"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(c)
+			err := c.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate = %v, want a *ValidationError", err)
+			}
+			if len(validationErr.Problems) != 1 || !strings.Contains(validationErr.Problems[0], tt.want) {
+				t.Fatalf("problems = %q, want one containing %q", validationErr.Problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateListsEveryProblem(t *testing.T) {
+	c := validConfig()
+	c.Database.Postgres.Port = 0
+	c.Database.Postgres.Host = ""
+	c.Security.JWT.Secret = ""
+
+	var validationErr *ValidationError
+	if err := c.Validate(); !errors.As(err, &validationErr) {
+		t.Fatalf("Validate = %v, want a *ValidationError", err)
+	}
+	if len(validationErr.Problems) != 3 {
+		t.Fatalf("problems = %q, want 3", validationErr.Problems)
+	}
+	for _, problem := range validationErr.Problems {
+		if !strings.Contains(validationErr.Error(), problem) {
+			t.Fatalf("error %q does not mention %q", validationErr.Error(), problem)
+		}
+	}
+}
+
+func TestLoadValidates(t *testing.T) {
+	unsetenv(t, "JWT_SECRET")
+	unsetenv(t, "SECURITY_JWT_SECRET")
+
+	var validationErr *ValidationError
+	if _, err := Load("loyalty-svc"); !errors.As(err, &validationErr) {
+		t.Fatalf("Load without a JWT secret = %v, want a *ValidationError", err)
+	}
+
+	setRequiredEnv(t)
+	t.Setenv("APP_SHUTDOWN_TIMEOUT", "soon")
+	if _, err := Load("loyalty-svc"); err == nil {
+		t.Fatal("Load accepted an unparseable duration")
+	}
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// connect pings the database until it answers, retrying while it is
+// unreachable. Errors that retrying cannot fix are returned at once.
+func (db *PostgresDB) connect(ctx context.Context, config *PostgresConfig) error {
+	attempts := config.ConnectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := config.ConnectBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := db.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("gave up connecting to database: %w", ctx.Err())
+		}
+		if isPermanentConnectError(err) {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		if attempt >= attempts {
+			return fmt.Errorf("failed to ping database after %d attempts: %w", attempt, err)
+		}
+
+		db.logger.Warnf("Database not reachable (attempt %d of %d), retrying in %s: %v", attempt, attempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up connecting to database: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if config.ConnectMaxDelay > 0 && delay > config.ConnectMaxDelay {
+			delay = config.ConnectMaxDelay
+		}
+	}
+}
+
+// invalidCatalogNameCode is the SQLSTATE for a database that does not exist
+const invalidCatalogNameCode = "3D000"
+
+// isPermanentConnectError reports whether a connection failed for a reason
+// retrying will not fix: the server rejected the credentials (SQLSTATE class
+// 28) or the database does not exist. Anything else, such as a refused
+// connection or a server still starting up, is worth retrying.
+func isPermanentConnectError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return strings.HasPrefix(pgErr.Code, "28") || pgErr.Code == invalidCatalogNameCode
+}
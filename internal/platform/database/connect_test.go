@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// closedPort returns a local port with nothing listening on it
+func closedPort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
+
+func TestNewPostgresDBRetriesUnreachableHost(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	config := &PostgresConfig{
+		Host:             "127.0.0.1",
+		Port:             closedPort(t),
+		Database:         "loyalty",
+		SSLMode:          "disable",
+		ConnectAttempts:  3,
+		ConnectBaseDelay: time.Millisecond,
+	}
+
+	db, err := NewPostgresDB(context.Background(), config, logger)
+	if err == nil {
+		db.Close()
+		t.Fatal("connected to an unreachable host")
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Fatalf("err = %v, want it to give up after 3 attempts", err)
+	}
+
+	var retries int
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel && strings.HasPrefix(entry.Message, "Database not reachable") {
+			retries++
+		}
+	}
+	if retries != 2 {
+		t.Fatalf("logged %d retries, want 2", retries)
+	}
+}
+
+func TestNewPostgresDBStopsWhenContextDone(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	config := &PostgresConfig{
+		Host:             "127.0.0.1",
+		Port:             closedPort(t),
+		Database:         "loyalty",
+		SSLMode:          "disable",
+		ConnectAttempts:  100,
+		ConnectBaseDelay: time.Hour,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	db, err := NewPostgresDB(ctx, config, logger)
+	if err == nil {
+		db.Close()
+		t.Fatal("connected to an unreachable host")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the context's error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("gave up after %s, want soon after the context ended", elapsed)
+	}
+}
+
+// rejectingServer answers every startup message with a fatal error carrying
+// code, counting the connections it receives
+func rejectingServer(t *testing.T, code string) (port int, connections *int32) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	connections = new(int32)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(connections, 1)
+			go func() {
+				defer conn.Close()
+				// The startup message is a length-prefixed packet
+				var length uint32
+				if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+					return
+				}
+				if _, err := io.CopyN(io.Discard, conn, int64(length)-4); err != nil {
+					return
+				}
+
+				fields := "SFATAL\x00C" + code + "\x00Mrejected\x00\x00"
+				reply := []byte{'E', 0, 0, 0, 0}
+				binary.BigEndian.PutUint32(reply[1:], uint32(4+len(fields)))
+				conn.Write(append(reply, fields...))
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, connections
+}
+
+func TestNewPostgresDBFailsFastOnPermanentErrors(t *testing.T) {
+	for _, code := range []string{"28P01", invalidCatalogNameCode} {
+		t.Run(code, func(t *testing.T) {
+			port, connections := rejectingServer(t, code)
+			logger, _ := test.NewNullLogger()
+			config := &PostgresConfig{
+				Host:             "127.0.0.1",
+				Port:             port,
+				Database:         "loyalty",
+				Username:         "loyalty",
+				SSLMode:          "disable",
+				ConnectAttempts:  5,
+				ConnectBaseDelay: time.Millisecond,
+			}
+
+			db, err := NewPostgresDB(context.Background(), config, logger)
+			if err == nil {
+				db.Close()
+				t.Fatal("connected to a server that rejects every login")
+			}
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) || pgErr.Code != code {
+				t.Fatalf("err = %v, want the server's %s error", err, code)
+			}
+			if n := atomic.LoadInt32(connections); n != 1 {
+				t.Fatalf("connected %d times, want 1", n)
+			}
+		})
+	}
+}
+
+func TestIsPermanentConnectError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"bad password", &pgconn.PgError{Code: "28P01"}, true},
+		{"unknown role", &pgconn.PgError{Code: "28000"}, true},
+		{"missing database", &pgconn.PgError{Code: "3D000"}, true},
+		{"starting up", &pgconn.PgError{Code: "57P03"}, false},
+		{"connection refused", errors.New("dial tcp: connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPermanentConnectError(tt.err); got != tt.want {
+				t.Fatalf("isPermanentConnectError = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package databasetest connects tests to the Postgres test database
+package databasetest
+
+import (
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Open connects to the test database named by the TEST_PG_HOST, TEST_PG_PORT,
+// TEST_PG_DB, TEST_PG_USER, and TEST_PG_PASSWORD environment variables,
+// defaulting to the values in .env. The test is skipped if the database
+// cannot be reached, and the pool is closed when it ends.
+func Open(t testing.TB) *database.PostgresDB {
+	t.Helper()
+
+	port, err := strconv.Atoi(env("TEST_PG_PORT", "5433"))
+	if err != nil {
+		t.Fatalf("invalid TEST_PG_PORT: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	db, err := database.NewPostgresDB(ctx, &database.PostgresConfig{
+		Host:           env("TEST_PG_HOST", "localhost"),
+		Port:           port,
+		Database:       env("TEST_PG_DB", "loyalty_test"),
+		Username:       env("TEST_PG_USER", "loyalty_test"),
+		Password:       env("TEST_PG_PASSWORD", "loyalty_test"),
+		SSLMode:        "disable",
+		MaxConns:       20,
+		ConnectTimeout: 2 * time.Second,
+	}, logger)
+	if err != nil {
+		t.Skipf("test database unavailable: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	return db
+}
+
+// Tenant returns a tenant ID no other test uses, so tests sharing the
+// database do not see each other's rows
+func Tenant(t testing.TB) string {
+	t.Helper()
+	return "test-" + strings.ReplaceAll(uuid.New().String(), "-", "")[:20]
+}
+
+func env(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
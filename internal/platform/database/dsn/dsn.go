@@ -0,0 +1,59 @@
+// Package dsn builds Postgres connection strings. It is shared by the config
+// and database packages so every connection string is escaped the same way.
+package dsn
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Postgres holds the parts of a Postgres connection string. Empty optional
+// fields are left out.
+type Postgres struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+	SSLMode  string
+	// ConnectTimeout bounds establishing each connection; it is sent in whole
+	// seconds, rounded up
+	ConnectTimeout time.Duration
+	// ApplicationName identifies the client in pg_stat_activity
+	ApplicationName string
+}
+
+// String returns the connection string as a postgres:// URL. Every part is
+// percent-encoded, so passwords may contain spaces, quotes, '=', '@', or '/'.
+func (p Postgres) String() string {
+	u := url.URL{
+		Scheme: "postgres",
+		Host:   p.Host,
+		Path:   "/" + p.Database,
+	}
+	if p.Port != 0 {
+		u.Host = net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+	}
+	if p.Password != "" {
+		u.User = url.UserPassword(p.User, p.Password)
+	} else if p.User != "" {
+		u.User = url.User(p.User)
+	}
+
+	query := url.Values{}
+	if p.SSLMode != "" {
+		query.Set("sslmode", p.SSLMode)
+	}
+	if p.ConnectTimeout > 0 {
+		seconds := (p.ConnectTimeout + time.Second - 1) / time.Second
+		query.Set("connect_timeout", strconv.FormatInt(int64(seconds), 10))
+	}
+	if p.ApplicationName != "" {
+		query.Set("application_name", p.ApplicationName)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	unique := &pgconn.PgError{Code: "23505", ConstraintName: "users_tenant_id_email_key"}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unique violation", unique, true},
+		{"wrapped unique violation", fmt.Errorf("insert user: %w", unique), true},
+		{"foreign key violation", &pgconn.PgError{Code: "23503"}, false},
+		{"other error", errors.New("23505"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUniqueViolation(tt.err); got != tt.want {
+				t.Fatalf("IsUniqueViolation = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if name, ok := UniqueViolationConstraint(fmt.Errorf("wrapped: %w", unique)); !ok || name != unique.ConstraintName {
+		t.Fatalf("UniqueViolationConstraint = %q, %v; want %q", name, ok, unique.ConstraintName)
+	}
+}
@@ -0,0 +1,258 @@
+// Package migrate applies a service's embedded SQL migrations on startup.
+//
+// Migrations are files named NNNN_description.sql, applied in version order.
+// Each applied version is recorded in the schema_migrations table under the
+// service's name, so services sharing a database track their migrations
+// independently and a migration never runs twice. The checksum recorded with
+// each version catches a migration edited after it was applied.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Mode selects whether pending migrations are applied or reported
+type Mode string
+
+const (
+	// ModeApply applies pending migrations
+	ModeApply Mode = "apply"
+	// ModeVerify fails if any migration is pending, leaving the schema to be
+	// migrated by a separate deployment step
+	ModeVerify Mode = "verify"
+)
+
+// ErrPending is returned in verify mode when migrations have not been applied
+var ErrPending = errors.New("database has pending migrations")
+
+// ErrChecksumMismatch is returned when an applied migration's SQL has changed
+// since it was applied
+var ErrChecksumMismatch = errors.New("applied migration has changed")
+
+// lockID is the advisory lock held while migrating, so replicas starting
+// together apply each migration once
+const lockID = 7_349_201_118
+
+const createTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		service VARCHAR(64) NOT NULL,
+		version INTEGER NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		checksum CHAR(64),
+		PRIMARY KEY (service, version)
+	)`
+
+// addChecksum upgrades a schema_migrations table created before checksums
+// were recorded
+const addChecksum = `ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum CHAR(64)`
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+	// Checksum is the hex SHA-256 of SQL
+	Checksum string
+}
+
+// Load reads the migrations in the root of fsys, sorted by version. Files
+// that are not .sql are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s does not start with a version number", entry.Name())
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		sql, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		sum := sha256.Sum256(sql)
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(sql), Checksum: hex.EncodeToString(sum[:])})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Run brings service's schema up to date with the migrations in fsys, or in
+// verify mode returns ErrPending if it is not. Either mode returns
+// ErrChecksumMismatch if an applied migration has since been edited.
+// Migrations are applied in one transaction, so a failure leaves the schema
+// as it was. Versions applied before checksums were recorded have theirs
+// filled in by apply mode.
+func Run(ctx context.Context, db *database.PostgresDB, service string, fsys fs.FS, mode Mode, logger *logrus.Logger) error {
+	if mode != ModeApply && mode != ModeVerify {
+		return fmt.Errorf("unknown migration mode %q, expected %q or %q", mode, ModeApply, ModeVerify)
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+
+	// Verify mode leaves the database untouched, so a missing table means
+	// nothing has been applied
+	applied := map[int]string{}
+	var tracked bool
+	if err := tx.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&tracked); err != nil {
+		return fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	if mode == ModeApply {
+		for _, statement := range []string{createTable, addChecksum} {
+			if _, err := tx.Exec(ctx, statement); err != nil {
+				return fmt.Errorf("failed to create schema_migrations: %w", err)
+			}
+		}
+		tracked = true
+	}
+	if tracked {
+		if applied, err = appliedVersions(ctx, tx, service); err != nil {
+			return err
+		}
+	}
+
+	var pending, unrecorded []Migration
+	var changed []string
+	for _, migration := range migrations {
+		checksum, ok := applied[migration.Version]
+		switch {
+		case !ok:
+			pending = append(pending, migration)
+		case checksum == "":
+			unrecorded = append(unrecorded, migration)
+		case checksum != migration.Checksum:
+			changed = append(changed, migration.Name)
+		}
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%w for %s: %s", ErrChecksumMismatch, service, strings.Join(changed, ", "))
+	}
+
+	if mode == ModeApply {
+		for _, migration := range unrecorded {
+			_, err := tx.Exec(ctx, `
+				UPDATE schema_migrations SET checksum = $3 WHERE service = $1 AND version = $2
+			`, service, migration.Version, migration.Checksum)
+			if err != nil {
+				return fmt.Errorf("failed to record checksum of migration %s: %w", migration.Name, err)
+			}
+		}
+	}
+
+	if len(pending) == 0 {
+		// Keep the schema_migrations upgrade and any checksums just recorded
+		if mode == ModeApply {
+			if err := tx.Commit(ctx); err != nil {
+				return fmt.Errorf("failed to commit migration checksums: %w", err)
+			}
+		}
+		logger.Infof("Database schema for %s is up to date", service)
+		return nil
+	}
+
+	if mode == ModeVerify {
+		names := make([]string, len(pending))
+		for i, migration := range pending {
+			names[i] = migration.Name
+		}
+		return fmt.Errorf("%w for %s: %s", ErrPending, service, strings.Join(names, ", "))
+	}
+
+	for _, migration := range pending {
+		if _, err := tx.Exec(ctx, migration.SQL); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO schema_migrations (service, version, name, checksum) VALUES ($1, $2, $3, $4)
+		`, service, migration.Version, migration.Name, migration.Checksum)
+		if err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+		}
+		logger.Infof("Applied migration %s for %s", migration.Name, service)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migrations: %w", err)
+	}
+	return nil
+}
+
+// appliedVersions returns the checksum of each of service's applied
+// migrations, "" for those applied before checksums were recorded
+func appliedVersions(ctx context.Context, tx pgx.Tx, service string) (map[int]string, error) {
+	// A table not yet upgraded by apply mode has no checksum column
+	var hasChecksum bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_attribute
+			WHERE attrelid = to_regclass('schema_migrations') AND attname = 'checksum' AND NOT attisdropped
+		)
+	`).Scan(&hasChecksum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+
+	query := `SELECT version, '' FROM schema_migrations WHERE service = $1`
+	if hasChecksum {
+		query = `SELECT version, COALESCE(checksum, '') FROM schema_migrations WHERE service = $1`
+	}
+	rows, err := tx.Query(ctx, query, service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, err
+		}
+		applied[version] = checksum
+	}
+	return applied, rows.Err()
+}
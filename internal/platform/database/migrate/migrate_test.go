@@ -0,0 +1,248 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_add_index.sql":     {Data: []byte("CREATE INDEX c ON a (id);")},
+		"0002_create_b.sql":      {Data: []byte("CREATE TABLE b (id INT);")},
+		"0001_create_a.sql":      {Data: []byte("CREATE TABLE a (id INT);")},
+		"README.md":              {Data: []byte("not a migration")},
+		"archive/0003_old.sql":   {Data: []byte("SELECT 1;")},
+		"0004_create_later.sqlx": {Data: []byte("SELECT 1;")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var names []string
+	for _, migration := range migrations {
+		names = append(names, migration.Name)
+	}
+	// Versions sort numerically, not by file name
+	if got, want := strings.Join(names, ","), "0001_create_a,0002_create_b,0010_add_index"; got != want {
+		t.Fatalf("migrations = %s, want %s", got, want)
+	}
+	if migrations[0].Version != 1 || migrations[2].Version != 10 {
+		t.Fatalf("versions = %d..%d, want 1..10", migrations[0].Version, migrations[2].Version)
+	}
+	if len(migrations[0].Checksum) != 64 || migrations[0].Checksum == migrations[1].Checksum {
+		t.Fatalf("checksums = %q, %q; want distinct SHA-256 hex digests", migrations[0].Checksum, migrations[1].Checksum)
+	}
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"no version", fstest.MapFS{"create_a.sql": {}}, "does not start with a version number"},
+		{"zero version", fstest.MapFS{"0000_create_a.sql": {}}, "does not start with a version number"},
+		{"shared version", fstest.MapFS{"0001_create_a.sql": {}, "0001_create_b.sql": {}}, "share version 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.fsys); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// migrationTest runs migrations on the test database under a service name
+// and table names no other test uses
+type migrationTest struct {
+	t       *testing.T
+	db      *database.PostgresDB
+	service string
+	logger  *logrus.Logger
+}
+
+func newMigrationTest(t *testing.T) *migrationTest {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	m := &migrationTest{t: t, db: databasetest.Open(t), service: strings.ReplaceAll(databasetest.Tenant(t), "-", "_"), logger: logger}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		m.db.Exec(ctx, `DELETE FROM schema_migrations WHERE service = $1`, m.service)
+		m.db.Exec(ctx, `DROP TABLE IF EXISTS `+m.table("a")+`, `+m.table("b"))
+	})
+	return m
+}
+
+// table names one of the test's tables
+func (m *migrationTest) table(name string) string {
+	return m.service + "_" + name
+}
+
+// fsys returns migrations whose SQL is templated with the test's table names
+func (m *migrationTest) fsys(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, sql := range files {
+		sql = strings.ReplaceAll(sql, "{a}", m.table("a"))
+		sql = strings.ReplaceAll(sql, "{b}", m.table("b"))
+		fsys[name] = &fstest.MapFile{Data: []byte(sql)}
+	}
+	return fsys
+}
+
+func (m *migrationTest) run(fsys fstest.MapFS, mode Mode) error {
+	return Run(context.Background(), m.db, m.service, fsys, mode, m.logger)
+}
+
+func (m *migrationTest) tableExists(name string) bool {
+	m.t.Helper()
+
+	var exists bool
+	if err := m.db.QueryRow(context.Background(), `SELECT to_regclass($1) IS NOT NULL`, m.table(name)).Scan(&exists); err != nil {
+		m.t.Fatalf("failed to look up table: %v", err)
+	}
+	return exists
+}
+
+func (m *migrationTest) appliedCount() int {
+	m.t.Helper()
+
+	var count int
+	if err := m.db.QueryRow(context.Background(), `SELECT COUNT(*) FROM schema_migrations WHERE service = $1`, m.service).Scan(&count); err != nil {
+		m.t.Fatalf("failed to count applied migrations: %v", err)
+	}
+	return count
+}
+
+func TestRunAppliesAndVerifies(t *testing.T) {
+	m := newMigrationTest(t)
+	fsys := m.fsys(map[string]string{
+		"0001_create_a.sql": "CREATE TABLE {a} (id INT PRIMARY KEY);",
+		"0002_create_b.sql": "CREATE TABLE {b} (id INT PRIMARY KEY, a_id INT REFERENCES {a}(id));",
+	})
+
+	if err := m.run(fsys, ModeVerify); !errors.Is(err, ErrPending) {
+		t.Fatalf("verify before applying: err = %v, want %v", err, ErrPending)
+	}
+	if err := m.run(fsys, ModeApply); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if !m.tableExists("a") || !m.tableExists("b") {
+		t.Fatal("migrations applied but their tables are missing")
+	}
+
+	// Re-running is a no-op, and the schema now verifies
+	if err := m.run(fsys, ModeApply); err != nil {
+		t.Fatalf("re-apply: %v", err)
+	}
+	if got := m.appliedCount(); got != 2 {
+		t.Fatalf("applied %d migrations, want 2", got)
+	}
+	if err := m.run(fsys, ModeVerify); err != nil {
+		t.Fatalf("verify after applying: %v", err)
+	}
+}
+
+func TestRunAppliesInVersionOrder(t *testing.T) {
+	m := newMigrationTest(t)
+	// 0010 sorts before 0002 by name but depends on it
+	fsys := m.fsys(map[string]string{
+		"0010_add_b_name.sql": "ALTER TABLE {b} ADD COLUMN name TEXT;",
+		"0002_create_b.sql":   "CREATE TABLE {b} (id INT PRIMARY KEY, a_id INT REFERENCES {a}(id));",
+		"0001_create_a.sql":   "CREATE TABLE {a} (id INT PRIMARY KEY);",
+	})
+
+	if err := m.run(fsys, ModeApply); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if got := m.appliedCount(); got != 3 {
+		t.Fatalf("applied %d migrations, want 3", got)
+	}
+}
+
+func TestRunRollsBackFailedMigration(t *testing.T) {
+	m := newMigrationTest(t)
+	fsys := m.fsys(map[string]string{
+		"0001_create_a.sql": "CREATE TABLE {a} (id INT PRIMARY KEY);",
+		"0002_broken.sql":   "CREATE TABLE {b} (id INT REFERENCES missing_table(id));",
+	})
+
+	if err := m.run(fsys, ModeApply); err == nil || !strings.Contains(err.Error(), "0002_broken") {
+		t.Fatalf("err = %v, want migration 0002_broken to fail", err)
+	}
+	if m.tableExists("a") || m.appliedCount() != 0 {
+		t.Fatal("failed run left earlier migrations applied")
+	}
+}
+
+func TestRunDetectsChecksumDrift(t *testing.T) {
+	m := newMigrationTest(t)
+	original := map[string]string{"0001_create_a.sql": "CREATE TABLE {a} (id INT PRIMARY KEY);"}
+	if err := m.run(m.fsys(original), ModeApply); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	edited := m.fsys(map[string]string{
+		"0001_create_a.sql": "CREATE TABLE {a} (id BIGINT PRIMARY KEY);",
+		"0002_create_b.sql": "CREATE TABLE {b} (id INT PRIMARY KEY);",
+	})
+	for _, mode := range []Mode{ModeVerify, ModeApply} {
+		err := m.run(edited, mode)
+		if !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "0001_create_a") {
+			t.Fatalf("%s with an edited migration: err = %v, want %v naming 0001_create_a", mode, err, ErrChecksumMismatch)
+		}
+	}
+	if m.tableExists("b") {
+		t.Fatal("migrations applied despite an edited migration")
+	}
+}
+
+func TestRunRecordsMissingChecksums(t *testing.T) {
+	m := newMigrationTest(t)
+	fsys := m.fsys(map[string]string{"0001_create_a.sql": "CREATE TABLE {a} (id INT PRIMARY KEY);"})
+	if err := m.run(fsys, ModeApply); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+
+	// As if applied before checksums were recorded
+	ctx := context.Background()
+	if err := m.db.Exec(ctx, `UPDATE schema_migrations SET checksum = NULL WHERE service = $1`, m.service); err != nil {
+		t.Fatalf("failed to clear checksum: %v", err)
+	}
+	if err := m.run(fsys, ModeVerify); err != nil {
+		t.Fatalf("verify without a recorded checksum: %v", err)
+	}
+	if err := m.run(fsys, ModeApply); err != nil {
+		t.Fatalf("apply without a recorded checksum: %v", err)
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var checksum string
+	if err := m.db.QueryRow(ctx, `SELECT checksum FROM schema_migrations WHERE service = $1`, m.service).Scan(&checksum); err != nil {
+		t.Fatalf("failed to read checksum: %v", err)
+	}
+	if checksum != migrations[0].Checksum {
+		t.Fatalf("checksum = %q, want %q", checksum, migrations[0].Checksum)
+	}
+}
+
+func TestRunRejectsUnknownMode(t *testing.T) {
+	err := Run(context.Background(), nil, "test", fstest.MapFS{}, Mode("auto"), logrus.New())
+	if err == nil || !strings.Contains(err.Error(), "unknown migration mode") {
+		t.Fatalf("err = %v, want an unknown mode error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// reportStats exports pool statistics as gauges and logs them at debug level
+// every interval until the pool is closed
+func (db *PostgresDB) reportStats(interval time.Duration) {
+	defer close(db.statsDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		db.recordStats()
+		select {
+		case <-db.stopStats:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (db *PostgresDB) recordStats() {
+	stat := db.pool.Stat()
+
+	metrics.DBPoolConnections.WithLabelValues(db.database, "acquired").Set(float64(stat.AcquiredConns()))
+	metrics.DBPoolConnections.WithLabelValues(db.database, "idle").Set(float64(stat.IdleConns()))
+	metrics.DBPoolConnections.WithLabelValues(db.database, "total").Set(float64(stat.TotalConns()))
+	metrics.DBPoolAcquireWaits.WithLabelValues(db.database).Set(float64(stat.EmptyAcquireCount()))
+	metrics.DBPoolAcquireWaitSeconds.WithLabelValues(db.database).Set(stat.AcquireDuration().Seconds())
+
+	db.logger.WithFields(logrus.Fields{
+		"database":      db.database,
+		"acquired":      stat.AcquiredConns(),
+		"idle":          stat.IdleConns(),
+		"total":         stat.TotalConns(),
+		"max":           stat.MaxConns(),
+		"wait_count":    stat.EmptyAcquireCount(),
+		"wait_duration": stat.AcquireDuration().String(),
+	}).Debug("PostgreSQL pool stats")
+}
@@ -7,16 +7,21 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/dsn"
 	"github.com/sirupsen/logrus"
 )
 
 // PostgresDB represents a PostgreSQL database connection
 type PostgresDB struct {
-	pool   *pgxpool.Pool
-	logger *logrus.Logger
+	pool      *pgxpool.Pool
+	logger    *logrus.Logger
+	database  string
+	stopStats chan struct{}
+	statsDone chan struct{}
 }
 
-// PostgresConfig holds PostgreSQL configuration
+// PostgresConfig holds PostgreSQL configuration. Zero durations use the
+// pool defaults below.
 type PostgresConfig struct {
 	Host     string
 	Port     int
@@ -25,43 +30,127 @@ type PostgresConfig struct {
 	Password string
 	SSLMode  string
 	MaxConns int
+	// ConnectTimeout bounds establishing each connection (0 waits indefinitely)
+	ConnectTimeout time.Duration
+	// ApplicationName identifies the service in pg_stat_activity
+	ApplicationName string
+	// MaxConnLifetime is how long a connection is kept before it is replaced
+	MaxConnLifetime time.Duration
+	// MaxConnIdleTime is how long an idle connection is kept open
+	MaxConnIdleTime time.Duration
+	// HealthCheckPeriod is how often idle connections are checked
+	HealthCheckPeriod time.Duration
+	// StatsInterval is how often pool statistics are exported and logged
+	// (0 disables them)
+	StatsInterval time.Duration
+	// ConnectAttempts caps the pings NewPostgresDB makes before giving up
+	// (0 or 1 pings once)
+	ConnectAttempts int
+	// ConnectBaseDelay is the wait before the second ping; each retry doubles
+	// it, up to ConnectMaxDelay
+	ConnectBaseDelay time.Duration
+	ConnectMaxDelay  time.Duration
 }
 
-// NewPostgresDB creates a new PostgreSQL database connection
-func NewPostgresDB(config *PostgresConfig, logger *logrus.Logger) (*PostgresDB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Port, config.Username, config.Password, config.Database, config.SSLMode)
+// Pool defaults used when PostgresConfig leaves a duration unset
+const (
+	DefaultMaxConnLifetime   = time.Hour
+	DefaultMaxConnIdleTime   = 30 * time.Minute
+	DefaultHealthCheckPeriod = time.Minute
+)
 
-	poolConfig, err := pgxpool.ParseConfig(dsn)
+// NewPostgresDB creates a new PostgreSQL database connection, failing if the
+// database cannot be reached. While the database is unreachable it retries up
+// to config.ConnectAttempts times with exponential backoff, so a service can
+// start alongside Postgres; rejected credentials or a missing database fail
+// at once. It stops early when ctx is done.
+func NewPostgresDB(ctx context.Context, config *PostgresConfig, logger *logrus.Logger) (*PostgresDB, error) {
+	db, err := OpenPostgresDB(config, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+		return nil, err
+	}
+
+	if err := db.connect(ctx, config); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	poolConfig.MaxConns = int32(config.MaxConns)
-	poolConfig.MaxConnLifetime = time.Hour
-	poolConfig.MaxConnIdleTime = 30 * time.Minute
-	poolConfig.HealthCheckPeriod = time.Minute
+	logger.Infof("Connected to PostgreSQL database %s on %s:%d", config.Database, config.Host, config.Port)
+
+	return db, nil
+}
+
+// OpenPostgresDB creates a connection pool without connecting. Connections are
+// established on first use, so the pool recovers on its own once an
+// unreachable database comes back.
+func OpenPostgresDB(config *PostgresConfig, logger *logrus.Logger) (*PostgresDB, error) {
+	poolConfig, err := newPoolConfig(config)
+	if err != nil {
+		return nil, err
+	}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
-	// Test the connection
-	if err := pool.Ping(context.Background()); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	db := &PostgresDB{
+		pool:     pool,
+		logger:   logger,
+		database: config.Database,
+	}
+	if config.StatsInterval > 0 {
+		db.stopStats = make(chan struct{})
+		db.statsDone = make(chan struct{})
+		go db.reportStats(config.StatsInterval)
 	}
 
-	logger.Infof("Connected to PostgreSQL database %s on %s:%d", config.Database, config.Host, config.Port)
+	return db, nil
+}
+
+// newPoolConfig builds the pool configuration, applying the defaults for
+// unset durations
+func newPoolConfig(config *PostgresConfig) (*pgxpool.Config, error) {
+	connString := dsn.Postgres{
+		Host:            config.Host,
+		Port:            config.Port,
+		User:            config.Username,
+		Password:        config.Password,
+		Database:        config.Database,
+		SSLMode:         config.SSLMode,
+		ConnectTimeout:  config.ConnectTimeout,
+		ApplicationName: config.ApplicationName,
+	}.String()
+
+	poolConfig, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
 
-	return &PostgresDB{
-		pool:   pool,
-		logger: logger,
-	}, nil
+	if config.MaxConns > 0 {
+		poolConfig.MaxConns = int32(config.MaxConns)
+	}
+	poolConfig.MaxConnLifetime = durationOr(config.MaxConnLifetime, DefaultMaxConnLifetime)
+	poolConfig.MaxConnIdleTime = durationOr(config.MaxConnIdleTime, DefaultMaxConnIdleTime)
+	poolConfig.HealthCheckPeriod = durationOr(config.HealthCheckPeriod, DefaultHealthCheckPeriod)
+
+	return poolConfig, nil
+}
+
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return fallback
 }
 
 // Close closes the database connection pool
 func (db *PostgresDB) Close() {
+	if db.stopStats != nil {
+		close(db.stopStats)
+		<-db.statsDone
+		db.stopStats = nil
+	}
 	if db.pool != nil {
 		db.pool.Close()
 		db.logger.Info("PostgreSQL connection pool closed")
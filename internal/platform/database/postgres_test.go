@@ -0,0 +1,115 @@
+package database
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewPoolConfig(t *testing.T) {
+	config := &PostgresConfig{
+		Host:              "db.internal",
+		Port:              5432,
+		Database:          "loyalty",
+		MaxConns:          25,
+		MaxConnLifetime:   2 * time.Hour,
+		MaxConnIdleTime:   5 * time.Minute,
+		HealthCheckPeriod: 10 * time.Second,
+	}
+	poolConfig, err := newPoolConfig(config)
+	if err != nil {
+		t.Fatalf("newPoolConfig: %v", err)
+	}
+	if poolConfig.MaxConns != 25 {
+		t.Errorf("MaxConns = %d, want 25", poolConfig.MaxConns)
+	}
+	if poolConfig.MaxConnLifetime != 2*time.Hour {
+		t.Errorf("MaxConnLifetime = %s, want 2h", poolConfig.MaxConnLifetime)
+	}
+	if poolConfig.MaxConnIdleTime != 5*time.Minute {
+		t.Errorf("MaxConnIdleTime = %s, want 5m", poolConfig.MaxConnIdleTime)
+	}
+	if poolConfig.HealthCheckPeriod != 10*time.Second {
+		t.Errorf("HealthCheckPeriod = %s, want 10s", poolConfig.HealthCheckPeriod)
+	}
+	if host := poolConfig.ConnConfig.Host; host != "db.internal" {
+		t.Errorf("Host = %q, want db.internal", host)
+	}
+}
+
+func TestNewPoolConfigDefaults(t *testing.T) {
+	poolConfig, err := newPoolConfig(&PostgresConfig{Host: "localhost", Port: 5432, Database: "loyalty"})
+	if err != nil {
+		t.Fatalf("newPoolConfig: %v", err)
+	}
+	if poolConfig.MaxConnLifetime != DefaultMaxConnLifetime {
+		t.Errorf("MaxConnLifetime = %s, want %s", poolConfig.MaxConnLifetime, DefaultMaxConnLifetime)
+	}
+	if poolConfig.MaxConnIdleTime != DefaultMaxConnIdleTime {
+		t.Errorf("MaxConnIdleTime = %s, want %s", poolConfig.MaxConnIdleTime, DefaultMaxConnIdleTime)
+	}
+	if poolConfig.HealthCheckPeriod != DefaultHealthCheckPeriod {
+		t.Errorf("HealthCheckPeriod = %s, want %s", poolConfig.HealthCheckPeriod, DefaultHealthCheckPeriod)
+	}
+	// pgx's own default applies when MaxConns is unset
+	if poolConfig.MaxConns <= 0 {
+		t.Errorf("MaxConns = %d, want pgx's default", poolConfig.MaxConns)
+	}
+}
+
+// gaugeValue reads the current value of the gauge named name with the given
+// labels from the default registry
+func gaugeValue(t *testing.T, name string, labels map[string]string) (float64, bool) {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] != label.GetValue() {
+					continue metrics
+				}
+			}
+			return metric.GetGauge().GetValue(), true
+		}
+	}
+	return 0, false
+}
+
+func TestRecordStats(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// The pool connects lazily, so it can be created without a server
+	db, err := OpenPostgresDB(&PostgresConfig{Host: "127.0.0.1", Port: 1, Database: "stats_test"}, logger)
+	if err != nil {
+		t.Fatalf("OpenPostgresDB: %v", err)
+	}
+	defer db.Close()
+
+	metrics.DBPoolConnections.WithLabelValues("stats_test", "total").Set(99)
+	db.recordStats()
+
+	for _, state := range []string{"acquired", "idle", "total"} {
+		value, ok := gaugeValue(t, "db_pool_connections", map[string]string{"database": "stats_test", "state": state})
+		if !ok || value != 0 {
+			t.Errorf("db_pool_connections{state=%q} = %v, %v; want 0", state, value, ok)
+		}
+	}
+	for _, name := range []string{"db_pool_acquire_waits", "db_pool_acquire_wait_seconds"} {
+		if _, ok := gaugeValue(t, name, map[string]string{"database": "stats_test"}); !ok {
+			t.Errorf("%s not exported for the pool", name)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisDB is a minimal Redis client speaking RESP over a small connection
+// pool. It supports the handful of commands the platform needs.
+type RedisDB struct {
+	config *RedisConfig
+	conns  chan *redisConn
+	slots  chan struct{}
+}
+
+// RedisConfig holds Redis configuration
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	PoolSize int
+}
+
+// RedisError is an error reply from the Redis server
+type RedisError struct {
+	Message string
+}
+
+func (e *RedisError) Error() string {
+	return "redis: " + e.Message
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// OpenRedisDB creates a client without connecting. Connections are made on
+// first use, so the client recovers on its own once Redis is reachable.
+func OpenRedisDB(config *RedisConfig) *RedisDB {
+	size := config.PoolSize
+	if size <= 0 {
+		size = 1
+	}
+	return &RedisDB{
+		config: config,
+		conns:  make(chan *redisConn, size),
+		slots:  make(chan struct{}, size),
+	}
+}
+
+// Close closes idle connections
+func (db *RedisDB) Close() {
+	for {
+		select {
+		case c := <-db.conns:
+			c.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// Ping checks that Redis answers
+func (db *RedisDB) Ping(ctx context.Context) error {
+	_, err := db.Do(ctx, "PING")
+	return err
+}
+
+// SetEX stores a value that expires after ttl
+func (db *RedisDB) SetEX(ctx context.Context, key, value string, ttl time.Duration) error {
+	ms := ttl.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	_, err := db.Do(ctx, "SET", key, value, "PX", strconv.FormatInt(ms, 10))
+	return err
+}
+
+// Exists reports whether a key is set
+func (db *RedisDB) Exists(ctx context.Context, key string) (bool, error) {
+	reply, err := db.Do(ctx, "EXISTS", key)
+	if err != nil {
+		return false, err
+	}
+	n, ok := reply.(int64)
+	return ok && n > 0, nil
+}
+
+// Eval runs a Lua script against the given keys
+func (db *RedisDB) Eval(ctx context.Context, script string, keys []string, args ...string) (interface{}, error) {
+	command := append([]string{"EVAL", script, strconv.Itoa(len(keys))}, keys...)
+	return db.Do(ctx, append(command, args...)...)
+}
+
+// Do sends a command and returns its reply: a string, an int64, nil, or a
+// []interface{} of replies. Error replies are returned as *RedisError.
+func (db *RedisDB) Do(ctx context.Context, args ...string) (interface{}, error) {
+	c, err := db.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.do(ctx, args...)
+	var redisErr *RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// The connection state is unknown after an I/O error
+		c.conn.Close()
+		<-db.slots
+		return nil, err
+	}
+
+	db.release(c)
+	return reply, err
+}
+
+// acquire returns an idle connection or dials a new one within the pool size
+func (db *RedisDB) acquire(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-db.conns:
+		return c, nil
+	default:
+	}
+
+	select {
+	case c := <-db.conns:
+		return c, nil
+	case db.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c, err := db.dial(ctx)
+	if err != nil {
+		<-db.slots
+		return nil, err
+	}
+	return c, nil
+}
+
+func (db *RedisDB) release(c *redisConn) {
+	c.conn.SetDeadline(time.Time{})
+	db.conns <- c
+}
+
+func (db *RedisDB) dial(ctx context.Context) (*redisConn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", db.config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if db.config.Password != "" {
+		if _, err := c.do(ctx, "AUTH", db.config.Password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis authentication failed: %w", err)
+		}
+	}
+	if db.config.DB != 0 {
+		if _, err := c.do(ctx, "SELECT", strconv.Itoa(db.config.DB)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to select redis database %d: %w", db.config.DB, err)
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) do(ctx context.Context, args ...string) (interface{}, error) {
+	// No deadline in ctx clears any left from an earlier command
+	deadline, _ := ctx.Deadline()
+	c.conn.SetDeadline(deadline)
+
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, fmt.Errorf("failed to send redis command: %w", err)
+	}
+
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redis reply: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, &RedisError{Message: line[1:]}
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis bulk length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, fmt.Errorf("failed to read redis reply: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis array length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
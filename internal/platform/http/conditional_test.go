@@ -0,0 +1,112 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckNotModified(t *testing.T) {
+	modified := time.Date(2026, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+	v := NewValidators(modified, "user-1", 250)
+	other := NewValidators(modified, "user-1", 300)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    bool
+	}{
+		{"unconditional", http.MethodGet, nil, false},
+		{"matching etag", http.MethodGet, map[string]string{"If-None-Match": v.ETag}, true},
+		{"strong form of a weak etag", http.MethodGet, map[string]string{"If-None-Match": v.ETag[2:]}, true},
+		{"etag in a list", http.MethodGet, map[string]string{"If-None-Match": `"stale", ` + v.ETag}, true},
+		{"any etag", http.MethodGet, map[string]string{"If-None-Match": "*"}, true},
+		{"changed etag", http.MethodGet, map[string]string{"If-None-Match": other.ETag}, false},
+		{"head", http.MethodHead, map[string]string{"If-None-Match": v.ETag}, true},
+		{"unsafe method", http.MethodPost, map[string]string{"If-None-Match": v.ETag}, false},
+		{"not modified since", http.MethodGet, map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, true},
+		{"modified since", http.MethodGet, map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, false},
+		{"invalid date", http.MethodGet, map[string]string{"If-Modified-Since": "yesterday"}, false},
+		{"etag takes precedence over date", http.MethodGet, map[string]string{
+			"If-None-Match":     other.ETag,
+			"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat),
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			if got := CheckNotModified(rec, req, v, time.Minute); got != tt.want {
+				t.Fatalf("CheckNotModified = %v, want %v", got, tt.want)
+			}
+			wantStatus := http.StatusOK
+			if tt.want {
+				wantStatus = http.StatusNotModified
+			}
+			if rec.Code != wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, wantStatus)
+			}
+			if rec.Header().Get("ETag") != v.ETag {
+				t.Errorf("ETag = %q, want %q", rec.Header().Get("ETag"), v.ETag)
+			}
+			if got := rec.Header().Get("Last-Modified"); got != "Sun, 01 Mar 2026 12:00:00 GMT" {
+				t.Errorf("Last-Modified = %q", got)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "private, max-age=60" {
+				t.Errorf("Cache-Control = %q, want private, max-age=60", got)
+			}
+		})
+	}
+}
+
+func TestNewValidatorsChangeWithTheResource(t *testing.T) {
+	modified := time.Now()
+	v := NewValidators(modified, 250)
+
+	if NewValidators(modified, 250).ETag != v.ETag {
+		t.Fatal("ETag is not stable for an unchanged resource")
+	}
+	if NewValidators(modified.Add(time.Microsecond), 250).ETag == v.ETag {
+		t.Error("ETag unchanged after the modification time moved")
+	}
+	if NewValidators(modified, 251).ETag == v.ETag {
+		t.Error("ETag unchanged after a part changed")
+	}
+}
+
+func TestNewContentValidators(t *testing.T) {
+	list := []string{"a", "b"}
+	v, err := NewContentValidators(list)
+	if err != nil {
+		t.Fatalf("NewContentValidators: %v", err)
+	}
+	if !v.LastModified.IsZero() {
+		t.Errorf("LastModified = %v, want none for content validators", v.LastModified)
+	}
+
+	same, _ := NewContentValidators([]string{"a", "b"})
+	shorter, _ := NewContentValidators([]string{"a"})
+	if same.ETag != v.ETag {
+		t.Error("ETag differs for the same content")
+	}
+	if shorter.ETag == v.ETag {
+		t.Error("ETag unchanged after an item was removed")
+	}
+
+	// Without a modification time, If-Modified-Since alone never matches
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-Modified-Since", time.Now().Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	if CheckNotModified(rec, req, v, time.Minute) {
+		t.Error("If-Modified-Since matched a list without a modification time")
+	}
+	if rec.Header().Get("Last-Modified") != "" {
+		t.Errorf("Last-Modified = %q, want none", rec.Header().Get("Last-Modified"))
+	}
+}
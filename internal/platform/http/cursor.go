@@ -0,0 +1,62 @@
+package http
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CursorParam is the query parameter that selects cursor pagination
+const CursorParam = "cursor"
+
+// ErrInvalidCursor is returned for a cursor this package did not encode
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a list ordered newest first by creation time,
+// with the ID breaking ties. Unlike an offset it stays put when rows are
+// inserted ahead of it, so paging never skips or repeats a row.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor made by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), ",")
+	if !ok || id == "" {
+		return nil, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: t, ID: id}, nil
+}
+
+// CursorFromRequest reports whether r asks for cursor pagination, and returns
+// the position to continue from: nil for the first page, requested with an
+// empty cursor parameter
+func CursorFromRequest(r *http.Request) (cursor *Cursor, ok bool, err error) {
+	query := r.URL.Query()
+	if !query.Has(CursorParam) {
+		return nil, false, nil
+	}
+	value := query.Get(CursorParam)
+	if value == "" {
+		return nil, true, nil
+	}
+	cursor, err = DecodeCursor(value)
+	return cursor, true, err
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := Cursor{CreatedAt: time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.FixedZone("EST", -5*3600)), ID: "7f1c2b9e"}
+
+	got, err := DecodeCursor(want.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Fatalf("decoded %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeCursorRejectsForeignValues(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := map[string]string{
+		"not base64":  "not a cursor!",
+		"no id":       encode("2026-03-01T12:30:00Z"),
+		"empty id":    encode("2026-03-01T12:30:00Z,"),
+		"bad time":    encode("yesterday,7f1c2b9e"),
+		"padded form": base64.URLEncoding.EncodeToString([]byte("2026-03-01T12:30:00Z,7f1c2b9e")),
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			if cursor, err := DecodeCursor(value); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("DecodeCursor(%q) = %+v, %v; want ErrInvalidCursor", value, cursor, err)
+			}
+		})
+	}
+}
+
+func TestCursorFromRequest(t *testing.T) {
+	next := Cursor{CreatedAt: time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC), ID: "7f1c2b9e"}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantOK     bool
+		wantCursor bool
+		wantErr    bool
+	}{
+		{"offset paging", "page=2", false, false, false},
+		{"first page", "cursor=", true, false, false},
+		{"next page", "cursor=" + next.Encode(), true, true, false},
+		{"invalid", "cursor=not-a-cursor", true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, ok, err := CursorFromRequest(httptest.NewRequest(http.MethodGet, "/v1/benefits?"+tt.query, nil))
+			if ok != tt.wantOK || (cursor != nil) != tt.wantCursor || (err != nil) != tt.wantErr {
+				t.Fatalf("CursorFromRequest(%q) = %+v, %v, %v", tt.query, cursor, ok, err)
+			}
+			if tt.wantCursor && (cursor.ID != next.ID || !cursor.CreatedAt.Equal(next.CreatedAt)) {
+				t.Fatalf("cursor = %+v, want %+v", cursor, next)
+			}
+		})
+	}
+}
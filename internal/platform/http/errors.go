@@ -0,0 +1,97 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+// ErrorCode is a machine-readable error identifier. Clients should branch on
+// the code; the message is for people and may change.
+type ErrorCode string
+
+// Error codes returned by the platform services
+const (
+	ErrCodeInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	ErrCodeValidationFailed   ErrorCode = "VALIDATION_FAILED"
+	ErrCodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	ErrCodeForbidden          ErrorCode = "FORBIDDEN"
+	ErrCodeNotFound           ErrorCode = "NOT_FOUND"
+	ErrCodeConflict           ErrorCode = "CONFLICT"
+	ErrCodeInsufficientPoints ErrorCode = "INSUFFICIENT_POINTS"
+	ErrCodeBenefitUnavailable ErrorCode = "BENEFIT_UNAVAILABLE"
+	ErrCodeUnprocessable      ErrorCode = "UNPROCESSABLE"
+	ErrCodePayloadTooLarge    ErrorCode = "PAYLOAD_TOO_LARGE"
+	ErrCodeRateLimited        ErrorCode = "RATE_LIMITED"
+	ErrCodeInternal           ErrorCode = "INTERNAL_ERROR"
+	ErrCodeNotImplemented     ErrorCode = "NOT_IMPLEMENTED"
+	ErrCodeUnavailable        ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrCodeBadGateway         ErrorCode = "BAD_GATEWAY"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	// RequestID identifies the request in the server's logs
+	RequestID string `json:"request_id,omitempty"`
+	// Reason refines the code, such as why a benefit is unavailable
+	Reason string `json:"reason,omitempty"`
+	// Fields maps request fields to validation messages
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Error writes an error response with the given status, code, and message
+func Error(w http.ResponseWriter, r *http.Request, status int, code ErrorCode, message string) {
+	WriteError(w, r, status, &ErrorResponse{Code: code, Message: message})
+}
+
+// ValidationError writes a 422 response listing the invalid request fields
+func ValidationError(w http.ResponseWriter, r *http.Request, fields map[string]string) {
+	WriteError(w, r, http.StatusUnprocessableEntity, &ErrorResponse{
+		Code:    ErrCodeValidationFailed,
+		Message: "Validation failed",
+		Fields:  fields,
+	})
+}
+
+// CodeForStatus returns the generic error code for an HTTP status, for
+// errors whose status is not known until runtime
+func CodeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusUnprocessableEntity:
+		return ErrCodeUnprocessable
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case http.StatusNotImplemented:
+		return ErrCodeNotImplemented
+	case http.StatusBadGateway:
+		return ErrCodeBadGateway
+	case http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrCodeUnavailable
+	}
+	if status >= 500 {
+		return ErrCodeInternal
+	}
+	return ErrCodeInvalidRequest
+}
+
+// WriteError writes body as an error response, filling in the request ID
+func WriteError(w http.ResponseWriter, r *http.Request, status int, body *ErrorResponse) {
+	body.RequestID = middleware.GetReqID(r.Context())
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// decodeError decodes an error body into a generic map so the test sees
+// exactly the keys that were sent
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("Content-Type = %q, want JSON", ct)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return body
+}
+
+func TestErrorShape(t *testing.T) {
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, http.StatusBadRequest, ErrCodeInsufficientPoints, "Insufficient points")
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	want := map[string]interface{}{
+		"code":       "INSUFFICIENT_POINTS",
+		"message":    "Insufficient points",
+		"request_id": "req-42",
+	}
+	if body := decodeError(t, rec); !reflect.DeepEqual(body, want) {
+		t.Fatalf("body = %v, want %v", body, want)
+	}
+}
+
+func TestValidationErrorShape(t *testing.T) {
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ValidationError(w, r, map[string]string{"email": "is required"})
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	body := decodeError(t, rec)
+	if body["code"] != string(ErrCodeValidationFailed) || body["request_id"] == "" {
+		t.Fatalf("body = %v, want %s with a generated request ID", body, ErrCodeValidationFailed)
+	}
+	if fields, _ := body["fields"].(map[string]interface{}); fields["email"] != "is required" {
+		t.Fatalf("fields = %v, want the email error", body["fields"])
+	}
+}
+
+func TestErrorEchoesServerRequestID(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.Router().Get("/missing", func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, http.StatusNotFound, ErrCodeNotFound, "Not found")
+	})
+
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	body := decodeError(t, rec)
+	if header := rec.Header().Get(middleware.RequestIDHeader); header == "" || body["request_id"] != header {
+		t.Fatalf("request_id = %v, header = %q; want the same generated ID", body["request_id"], header)
+	}
+}
+
+func TestCodeForStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   ErrorCode
+	}{
+		{http.StatusBadRequest, ErrCodeInvalidRequest},
+		{http.StatusUnauthorized, ErrCodeUnauthorized},
+		{http.StatusForbidden, ErrCodeForbidden},
+		{http.StatusNotFound, ErrCodeNotFound},
+		{http.StatusConflict, ErrCodeConflict},
+		{http.StatusUnprocessableEntity, ErrCodeUnprocessable},
+		{http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge},
+		{http.StatusTooManyRequests, ErrCodeRateLimited},
+		{http.StatusNotImplemented, ErrCodeNotImplemented},
+		{http.StatusBadGateway, ErrCodeBadGateway},
+		{http.StatusServiceUnavailable, ErrCodeUnavailable},
+		{http.StatusGatewayTimeout, ErrCodeUnavailable},
+		{http.StatusInternalServerError, ErrCodeInternal},
+		{http.StatusTeapot, ErrCodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		if got := CodeForStatus(tt.status); got != tt.want {
+			t.Errorf("CodeForStatus(%d) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
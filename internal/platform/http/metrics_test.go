@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/metrics"
+)
+
+// scrape fetches the server's /metrics page
+func scrape(t *testing.T, s *Server) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	return rec.Body.String()
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	s, _ := newTestServer(t, func(config *ServerConfig) { config.Metrics = true })
+	s.Router().Get("/v1/metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/metrics-test/42", nil))
+	s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/metrics-test-missing", nil))
+	metrics.RedemptionCompleted.Inc()
+
+	page := scrape(t, s)
+	for _, want := range []string{
+		// Requests are labelled by route pattern, not path
+		`http_requests_total{method="GET",route="/v1/metrics-test/{id}",status="202"}`,
+		`http_request_duration_seconds_count{method="GET",route="/v1/metrics-test/{id}",status="202"}`,
+		`http_requests_total{method="GET",route="unmatched",status="404"}`,
+		`redemption_saga_outcomes_total{outcome="completed"}`,
+		"loyalty_points_earned_total",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("/metrics does not contain %s", want)
+		}
+	}
+	if strings.Contains(page, "/v1/metrics-test/42") {
+		t.Error("request path used as a route label")
+	}
+}
+
+func TestMetricsEndpointDisabled(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("/metrics status = %d with metrics off, want %d", rec.Code, http.StatusNotFound)
+	}
+}
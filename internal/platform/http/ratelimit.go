@@ -0,0 +1,234 @@
+package http
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// storeRetryInterval is how long a limiter counts locally after its shared
+// store fails before trying the store again
+const storeRetryInterval = 5 * time.Second
+
+// RateLimitConfig holds token bucket rate limiter configuration
+type RateLimitConfig struct {
+	// Name identifies the limit; limiters with different names never share buckets
+	Name string
+	// RequestsPerMinute is the rate at which tokens are refilled
+	RequestsPerMinute int
+	// Burst is the bucket capacity
+	Burst int
+	// Headers enables the IETF draft RateLimit-* response headers
+	Headers bool
+	// KeyFunc identifies the client a request is counted against (defaults to the remote IP)
+	KeyFunc func(r *http.Request) string
+	// Store shares buckets between replicas. While it fails, requests are
+	// counted in memory instead.
+	Store RateLimitStore
+	// Logger reports the store failing and recovering
+	Logger *logrus.Logger
+	// WriteError writes the 429 response body (defaults to Error with
+	// ErrCodeRateLimited)
+	WriteError func(w http.ResponseWriter, r *http.Request, status int, message string)
+}
+
+// RateLimitStore keeps token buckets outside the process
+type RateLimitStore interface {
+	// Take removes a token from the key's bucket if one is available,
+	// returning whether it did and the tokens left
+	Take(ctx context.Context, key string, burst int, perSecond float64) (allowed bool, tokens float64, err error)
+}
+
+// RateLimiter limits requests per client using a token bucket, kept in its
+// store when it has one and in memory otherwise
+type RateLimiter struct {
+	config RateLimitConfig
+	rate   float64 // tokens per second
+	now    func() time.Time
+
+	mu           sync.Mutex
+	buckets      map[string]*bucket
+	lastSweep    time.Time
+	storeRetryAt time.Time
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimitState is the bucket state after a request has been counted
+type rateLimitState struct {
+	allowed   bool
+	remaining int
+	// reset is how long until the bucket is full again
+	reset time.Duration
+	// retryAfter is how long until the next token is available
+	retryAfter time.Duration
+}
+
+// NewRateLimiter creates a new rate limiter
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	if config.RequestsPerMinute <= 0 {
+		config.RequestsPerMinute = 60
+	}
+	if config.Burst <= 0 {
+		config.Burst = config.RequestsPerMinute
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = ClientIP
+	}
+	if config.WriteError == nil {
+		config.WriteError = func(w http.ResponseWriter, r *http.Request, status int, message string) {
+			Error(w, r, status, CodeForStatus(status), message)
+		}
+	}
+
+	return &RateLimiter{
+		config:  config,
+		rate:    float64(config.RequestsPerMinute) / 60,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Middleware rejects requests over the limit with 429 Too Many Requests
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := l.take(r.Context(), l.config.KeyFunc(r))
+
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(state.remaining))
+		if l.config.Headers {
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(l.config.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(state.remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(state.reset)))
+		}
+
+		if !state.allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(state.retryAfter)))
+			l.config.WriteError(w, r, http.StatusTooManyRequests, "Too many requests")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take counts a request against the client's bucket
+func (l *RateLimiter) take(ctx context.Context, key string) rateLimitState {
+	if l.useStore() {
+		allowed, tokens, err := l.config.Store.Take(ctx, l.config.Name+":"+key, l.config.Burst, l.rate)
+		if err == nil {
+			return l.state(allowed, tokens)
+		}
+		l.storeFailed(err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(l.config.Burst)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		l.evictFull(now)
+		b = &bucket{tokens: capacity, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	b.lastSeen = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return l.state(allowed, b.tokens)
+}
+
+// state describes a bucket left with the given tokens
+func (l *RateLimiter) state(allowed bool, tokens float64) rateLimitState {
+	state := rateLimitState{allowed: allowed}
+	if !allowed {
+		state.retryAfter = l.durationFor(1 - tokens)
+	}
+	state.remaining = int(math.Floor(tokens))
+	state.reset = l.durationFor(float64(l.config.Burst) - tokens)
+	return state
+}
+
+// useStore reports whether to count against the store, which is skipped for
+// a while after it fails so an outage does not slow every request
+func (l *RateLimiter) useStore() bool {
+	if l.config.Store == nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.storeRetryAt.IsZero() {
+		return true
+	}
+	if l.now().Before(l.storeRetryAt) {
+		return false
+	}
+
+	l.storeRetryAt = time.Time{}
+	if l.config.Logger != nil {
+		l.config.Logger.Debugf("Retrying shared rate limit store for %s", l.config.Name)
+	}
+	return true
+}
+
+// storeFailed falls back to counting in memory until the store is retried
+func (l *RateLimiter) storeFailed(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.storeRetryAt = l.now().Add(storeRetryInterval)
+	if l.config.Logger != nil {
+		l.config.Logger.Warnf("Shared rate limit store failed for %s, limiting in memory: %v", l.config.Name, err)
+	}
+}
+
+// evictFull drops buckets that have refilled completely, since they are
+// indistinguishable from a new client. Callers must hold the lock.
+func (l *RateLimiter) evictFull(now time.Time) {
+	full := l.durationFor(float64(l.config.Burst))
+	if now.Sub(l.lastSweep) < full {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// durationFor returns how long it takes to refill the given number of tokens
+func (l *RateLimiter) durationFor(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ClientIP returns the request's remote IP (set from X-Forwarded-For by
+// middleware.RealIP). It is the default rate limit key.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
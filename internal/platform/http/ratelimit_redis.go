@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+)
+
+// rateLimitKeyPrefix namespaces rate limit buckets in Redis
+const rateLimitKeyPrefix = "ratelimit:"
+
+// takeTokenScript refills a token bucket by the time elapsed on the Redis
+// clock, takes a token if one is available, and returns whether it did along
+// with the tokens left. Idle buckets expire once they would be full again.
+const takeTokenScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or capacity
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+// RedisRateLimitStore keeps token buckets in Redis, so every replica of a
+// service counts requests against the same limit
+type RedisRateLimitStore struct {
+	redis *database.RedisDB
+}
+
+// NewRedisRateLimitStore creates a rate limit store backed by Redis
+func NewRedisRateLimitStore(redis *database.RedisDB) *RedisRateLimitStore {
+	return &RedisRateLimitStore{redis: redis}
+}
+
+// Take removes a token from the key's bucket if one is available
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, burst int, perSecond float64) (bool, float64, error) {
+	reply, err := s.redis.Eval(ctx, takeTokenScript, []string{rateLimitKeyPrefix + key},
+		strconv.Itoa(burst), strconv.FormatFloat(perSecond, 'f', -1, 64))
+	if err != nil {
+		return false, 0, err
+	}
+
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	allowed, ok := items[0].(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	tokensText, _ := items[1].(string)
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	return allowed == 1, tokens, nil
+}
@@ -0,0 +1,238 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestLimiter creates a limiter with a clock the test advances by moving
+// *now, wrapped around a handler that always succeeds
+func newTestLimiter(config RateLimitConfig) (http.Handler, *time.Time) {
+	limiter := NewRateLimiter(config)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	return limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})), &now
+}
+
+// limitedRequest sends a request from remoteAddr through handler
+func limitedRequest(handler http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// intHeader reads a numeric header, failing the test if it is missing
+func intHeader(t *testing.T, rec *httptest.ResponseRecorder, name string) int {
+	t.Helper()
+
+	value, err := strconv.Atoi(rec.Header().Get(name))
+	if err != nil {
+		t.Fatalf("%s = %q, want a number", name, rec.Header().Get(name))
+	}
+	return value
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	// One token a second, up to 3
+	handler, now := newTestLimiter(RateLimitConfig{Name: "test", RequestsPerMinute: 60, Burst: 3, Headers: true})
+
+	lastReset := 0
+	for i, wantRemaining := range []int{2, 1, 0} {
+		rec := limitedRequest(handler, "192.0.2.1:1234")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, rec.Code, http.StatusOK)
+		}
+		if got := intHeader(t, rec, "RateLimit-Limit"); got != 3 {
+			t.Errorf("request %d: RateLimit-Limit = %d, want 3", i+1, got)
+		}
+		if got := intHeader(t, rec, "RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("request %d: RateLimit-Remaining = %d, want %d", i+1, got, wantRemaining)
+		}
+		// The bucket refills a token a second, so it is full again after
+		// as many seconds as tokens have been taken
+		if got := intHeader(t, rec, "RateLimit-Reset"); got != 3-wantRemaining || got <= lastReset {
+			t.Errorf("request %d: RateLimit-Reset = %d, want %d", i+1, got, 3-wantRemaining)
+		}
+		lastReset = intHeader(t, rec, "RateLimit-Reset")
+	}
+
+	// The limited response carries the headers too
+	rec := limitedRequest(handler, "192.0.2.1:1234")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over the limit: status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := intHeader(t, rec, "RateLimit-Remaining"); got != 0 {
+		t.Errorf("over the limit: RateLimit-Remaining = %d, want 0", got)
+	}
+	if got := intHeader(t, rec, "RateLimit-Reset"); got != 3 {
+		t.Errorf("over the limit: RateLimit-Reset = %d, want 3", got)
+	}
+	if got := intHeader(t, rec, "Retry-After"); got != 1 {
+		t.Errorf("over the limit: Retry-After = %d, want 1", got)
+	}
+
+	// Other clients have buckets of their own
+	if rec := limitedRequest(handler, "192.0.2.2:1234"); intHeader(t, rec, "RateLimit-Remaining") != 2 {
+		t.Errorf("other client: RateLimit-Remaining = %s, want 2", rec.Header().Get("RateLimit-Remaining"))
+	}
+
+	// Once the reset has passed the bucket is full again
+	*now = now.Add(3 * time.Second)
+	rec = limitedRequest(handler, "192.0.2.1:1234")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("after reset: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := intHeader(t, rec, "RateLimit-Remaining"); got != 2 {
+		t.Errorf("after reset: RateLimit-Remaining = %d, want 2", got)
+	}
+}
+
+func TestRateLimitHeadersCanBeDisabled(t *testing.T) {
+	handler, _ := newTestLimiter(RateLimitConfig{Name: "test", RequestsPerMinute: 60, Burst: 1})
+
+	for i := 0; i < 2; i++ {
+		rec := limitedRequest(handler, "192.0.2.1:1234")
+		for _, name := range []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"} {
+			if value := rec.Header().Get(name); value != "" {
+				t.Errorf("request %d: %s = %q with headers disabled", i+1, name, value)
+			}
+		}
+	}
+}
+
+func TestRateLimitBurst(t *testing.T) {
+	handler, _ := newTestLimiter(RateLimitConfig{Name: "test", RequestsPerMinute: 60, Burst: 5})
+
+	for i := 0; i < 5; i++ {
+		rec := limitedRequest(handler, "192.0.2.1:1234")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want the burst allowed", i+1, rec.Code)
+		}
+		if got := intHeader(t, rec, "X-RateLimit-Remaining"); got != 4-i {
+			t.Errorf("request %d: X-RateLimit-Remaining = %d, want %d", i+1, got, 4-i)
+		}
+	}
+
+	rec := limitedRequest(handler, "192.0.2.1:1234")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("past the burst: status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := intHeader(t, rec, "Retry-After"); got != 1 {
+		t.Errorf("Retry-After = %d, want 1", got)
+	}
+	if got := intHeader(t, rec, "X-RateLimit-Remaining"); got != 0 {
+		t.Errorf("X-RateLimit-Remaining = %d, want 0", got)
+	}
+}
+
+func TestRateLimitSustainedRate(t *testing.T) {
+	// Two tokens a second, up to 2
+	handler, now := newTestLimiter(RateLimitConfig{Name: "test", RequestsPerMinute: 120, Burst: 2})
+
+	// Requests at the refill rate are never limited
+	for i := 0; i < 20; i++ {
+		if rec := limitedRequest(handler, "192.0.2.1:1234"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d at the refill rate: status = %d", i+1, rec.Code)
+		}
+		*now = now.Add(500 * time.Millisecond)
+	}
+
+	// At twice the rate, half are let through once the burst is spent
+	allowed := 0
+	for i := 0; i < 40; i++ {
+		if limitedRequest(handler, "192.0.2.1:1234").Code == http.StatusOK {
+			allowed++
+		}
+		*now = now.Add(250 * time.Millisecond)
+	}
+	if allowed < 20 || allowed > 22 {
+		t.Fatalf("%d of 40 requests allowed at twice the rate, want about 20", allowed)
+	}
+}
+
+func TestRateLimitKeyFunc(t *testing.T) {
+	handler, _ := newTestLimiter(RateLimitConfig{Name: "test", RequestsPerMinute: 60, Burst: 1,
+		KeyFunc: func(r *http.Request) string { return r.Header.Get("X-User") }})
+	request := func(user, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", user)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := request("user-1", "192.0.2.1:1234"); code != http.StatusOK {
+		t.Fatalf("first request: status = %d", code)
+	}
+	// A user is limited wherever they connect from
+	if code := request("user-1", "192.0.2.2:1234"); code != http.StatusTooManyRequests {
+		t.Fatalf("same user, other address: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := request("user-2", "192.0.2.1:1234"); code != http.StatusOK {
+		t.Fatalf("other user, same address: status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+// stubStore is a RateLimitStore that allows everything unless it fails
+type stubStore struct {
+	err   error
+	takes int
+	keys  []string
+}
+
+func (s *stubStore) Take(ctx context.Context, key string, burst int, perSecond float64) (bool, float64, error) {
+	s.takes++
+	s.keys = append(s.keys, key)
+	if s.err != nil {
+		return false, 0, s.err
+	}
+	return true, float64(burst - 1), nil
+}
+
+func TestRateLimitStoreFallback(t *testing.T) {
+	store := &stubStore{}
+	limiter := NewRateLimiter(RateLimitConfig{Name: "login", RequestsPerMinute: 60, Burst: 1, Store: store})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// The shared store decides while it works
+	for i := 0; i < 3; i++ {
+		if rec := limitedRequest(handler, "192.0.2.1:1234"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d with the store: status = %d", i+1, rec.Code)
+		}
+	}
+	if store.keys[0] != "login:192.0.2.1" {
+		t.Errorf("store key = %q, want it scoped to the limit name", store.keys[0])
+	}
+
+	// When it fails, requests are limited in memory without retrying it
+	store.err = errors.New("redis down")
+	takes := store.takes
+	codes := []int{limitedRequest(handler, "192.0.2.1:1234").Code, limitedRequest(handler, "192.0.2.1:1234").Code}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Fatalf("statuses without the store = %v, want the in-memory burst of 1", codes)
+	}
+	if store.takes != takes+1 {
+		t.Fatalf("store tried %d times during the outage, want once", store.takes-takes)
+	}
+
+	// The store is tried again after the retry interval
+	store.err = nil
+	now = now.Add(storeRetryInterval)
+	takes = store.takes
+	if rec := limitedRequest(handler, "192.0.2.1:1234"); rec.Code != http.StatusOK || store.takes != takes+1 {
+		t.Fatalf("after the retry interval: status = %d, store takes = %d; want the store used", rec.Code, store.takes-takes)
+	}
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/sirupsen/logrus"
+)
+
+// Readiness statuses reported by /readyz
+const (
+	ReadinessOK          = "ok"
+	ReadinessDegraded    = "degraded"
+	ReadinessUnavailable = "unavailable"
+)
+
+const defaultCheckInterval = 10 * time.Second
+
+// probeCheckTimeout bounds each check run while answering a readiness probe
+const probeCheckTimeout = 2 * time.Second
+
+// DependencyCheck reports whether a dependency is reachable
+type DependencyCheck func(ctx context.Context) error
+
+// DependencyStatus is the last known state of a dependency
+type DependencyStatus struct {
+	Name     string    `json:"name"`
+	Required bool      `json:"required"`
+	Up       bool      `json:"up"`
+	Error    string    `json:"error,omitempty"`
+	Since    time.Time `json:"since"`
+}
+
+// Readiness tracks the dependencies a service needs to serve traffic. The
+// service is unavailable while a required dependency is down, and degraded
+// while only optional ones are.
+type Readiness struct {
+	logger *logrus.Logger
+
+	mu   sync.RWMutex
+	deps map[string]*DependencyStatus
+	// checks are run on every probe rather than watched in the background
+	checks map[string]DependencyCheck
+}
+
+// NewReadiness creates an empty readiness tracker
+func NewReadiness(logger *logrus.Logger) *Readiness {
+	return &Readiness{
+		logger: logger,
+		deps:   make(map[string]*DependencyStatus),
+		checks: make(map[string]DependencyCheck),
+	}
+}
+
+// AddCheck registers a required dependency that is checked on every
+// readiness probe, for checks cheap enough to run per request
+func (r *Readiness) AddCheck(name string, check DependencyCheck) {
+	r.mu.Lock()
+	r.checks[name] = check
+	r.mu.Unlock()
+}
+
+// runChecks runs the probe-time checks concurrently and records their results
+func (r *Readiness) runChecks(ctx context.Context) {
+	r.mu.RLock()
+	checks := make(map[string]DependencyCheck, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check DependencyCheck) {
+			defer wg.Done()
+			r.Set(name, true, runCheck(ctx, check, probeCheckTimeout))
+		}(name, check)
+	}
+	wg.Wait()
+}
+
+// Set records the result of checking a dependency, logging when it goes up or down
+func (r *Readiness) Set(name string, required bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dep, known := r.deps[name]
+	if !known {
+		dep = &DependencyStatus{Name: name}
+		r.deps[name] = dep
+	}
+	dep.Required = required
+
+	up := err == nil
+	if !known || dep.Up != up {
+		dep.Since = time.Now()
+		if up {
+			r.logger.Infof("Dependency %s is up", name)
+		} else {
+			r.logger.Warnf("Dependency %s is down (required: %t): %v", name, required, err)
+		}
+	}
+	dep.Up = up
+	dep.Error = ""
+	if err != nil {
+		dep.Error = err.Error()
+	}
+}
+
+// Watch checks a dependency now and then every interval until ctx is done,
+// recording each result. It returns the first check's error so callers can
+// fail fast on required dependencies.
+func (r *Readiness) Watch(ctx context.Context, name string, required bool, interval time.Duration, check DependencyCheck) error {
+	if interval <= 0 {
+		interval = defaultCheckInterval
+	}
+
+	err := runCheck(ctx, check, interval)
+	r.Set(name, required, err)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Set(name, required, runCheck(ctx, check, interval))
+			}
+		}
+	}()
+
+	return err
+}
+
+// Status returns the overall readiness status and each dependency's state
+func (r *Readiness) Status() (string, []DependencyStatus) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status := ReadinessOK
+	deps := make([]DependencyStatus, 0, len(r.deps))
+	for _, dep := range r.deps {
+		deps = append(deps, *dep)
+		if dep.Up {
+			continue
+		}
+		if dep.Required {
+			status = ReadinessUnavailable
+		} else if status == ReadinessOK {
+			status = ReadinessDegraded
+		}
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Name < deps[j].Name })
+
+	return status, deps
+}
+
+// ServeHTTP answers readiness probes: 200 when ready or degraded, 503 while a
+// required dependency is down
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.runChecks(req.Context())
+	status, deps := r.Status()
+	if status == ReadinessUnavailable {
+		render.Status(req, http.StatusServiceUnavailable)
+	}
+	render.JSON(w, req, map[string]interface{}{
+		"status":       status,
+		"dependencies": deps,
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// runCheck runs a single check, bounded by the watch interval
+func runCheck(ctx context.Context, check DependencyCheck, timeout time.Duration) error {
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return check(checkCtx)
+}
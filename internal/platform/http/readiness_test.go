@@ -0,0 +1,193 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// stubDependency is a dependency the test takes down and brings back up
+type stubDependency struct {
+	up     atomic.Bool
+	checks atomic.Int32
+}
+
+func (d *stubDependency) check(ctx context.Context) error {
+	d.checks.Add(1)
+	if !d.up.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+// probe answers a readiness probe, returning its status code and body
+func probe(t *testing.T, readiness *Readiness) (int, string, []DependencyStatus) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	readiness.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var body struct {
+		Status       string             `json:"status"`
+		Dependencies []DependencyStatus `json:"dependencies"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return rec.Code, body.Status, body.Dependencies
+}
+
+// waitForStatus probes until readiness reports want
+func waitForStatus(t *testing.T, readiness *Readiness, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, status, _ := probe(t, readiness); status == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("readiness never became %s", want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReadinessDegradedStartAndRecovery(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	readiness := NewReadiness(logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	postgres := &stubDependency{}
+	postgres.up.Store(true)
+	kafka := &stubDependency{}
+	if err := readiness.Watch(ctx, "postgres", true, 20*time.Millisecond, postgres.check); err != nil {
+		t.Fatalf("watch postgres: %v", err)
+	}
+
+	// An optional dependency that is down at startup is reported, not fatal
+	if err := readiness.Watch(ctx, "kafka", false, 20*time.Millisecond, kafka.check); err == nil {
+		t.Fatal("Watch hid the failed first check")
+	}
+	code, status, deps := probe(t, readiness)
+	if code != http.StatusOK || status != ReadinessDegraded {
+		t.Fatalf("probe = %d %s, want %d %s", code, status, http.StatusOK, ReadinessDegraded)
+	}
+	if len(deps) != 2 || deps[0].Name != "kafka" || deps[0].Up || deps[0].Required || deps[0].Error == "" {
+		t.Fatalf("dependencies = %+v, want kafka down and optional", deps)
+	}
+
+	// The dependency is retried in the background and recovers on its own
+	kafka.up.Store(true)
+	waitForStatus(t, readiness, ReadinessOK)
+
+	// Losing a required dependency makes the service unavailable
+	postgres.up.Store(false)
+	waitForStatus(t, readiness, ReadinessUnavailable)
+	if code, _, _ := probe(t, readiness); code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	// Watching stops with its context
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	checks := kafka.checks.Load()
+	time.Sleep(100 * time.Millisecond)
+	if got := kafka.checks.Load(); got != checks {
+		t.Fatalf("%d checks after the watch stopped", got-checks)
+	}
+}
+
+func TestReadinessProbeChecks(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	readiness := NewReadiness(logger)
+	dep := &stubDependency{}
+	readiness.AddCheck("database", dep.check)
+
+	// Probe-time checks are required and run on every probe
+	if code, status, _ := probe(t, readiness); code != http.StatusServiceUnavailable || status != ReadinessUnavailable {
+		t.Fatalf("probe = %d %s, want %d %s", code, status, http.StatusServiceUnavailable, ReadinessUnavailable)
+	}
+	dep.up.Store(true)
+	if code, status, _ := probe(t, readiness); code != http.StatusOK || status != ReadinessOK {
+		t.Fatalf("probe = %d %s, want %d %s", code, status, http.StatusOK, ReadinessOK)
+	}
+	if got := dep.checks.Load(); got != 2 {
+		t.Fatalf("checks = %d, want one per probe", got)
+	}
+}
+
+func TestReadinessLogsTransitions(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	readiness := NewReadiness(logger)
+
+	readiness.Set("redis", false, errors.New("connection refused"))
+	readiness.Set("redis", false, errors.New("connection refused"))
+	readiness.Set("redis", false, nil)
+
+	var messages []string
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("logged %q, want one line per transition", messages)
+	}
+}
+
+func TestServerReadinessChecks(t *testing.T) {
+	s, _ := newTestServer(t)
+	database, kafka := &stubDependency{}, &stubDependency{}
+	kafka.up.Store(true)
+	s.AddReadinessCheck("database", database.check)
+	s.AddReadinessCheck("kafka", kafka.check)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// A degraded dependency fails readiness and is named in the response
+	rec := get("/readyz")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("/readyz status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	var body struct {
+		Status       string             `json:"status"`
+		Dependencies []DependencyStatus `json:"dependencies"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	up := make(map[string]bool)
+	for _, dep := range body.Dependencies {
+		up[dep.Name] = dep.Up
+		if dep.Name == "database" && dep.Error != "connection refused" {
+			t.Errorf("database error = %q, want the check's error", dep.Error)
+		}
+	}
+	if body.Status != ReadinessUnavailable || len(up) != 2 || up["database"] || !up["kafka"] {
+		t.Fatalf("readiness = %s %+v, want the database down and kafka up", body.Status, body.Dependencies)
+	}
+
+	// Liveness stays cheap and healthy while a dependency is down
+	checks := database.checks.Load()
+	if rec := get("/healthz"); rec.Code != http.StatusOK {
+		t.Fatalf("/healthz status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := database.checks.Load(); got != checks {
+		t.Fatal("/healthz ran the readiness checks")
+	}
+
+	database.up.Store(true)
+	if rec := get("/readyz"); rec.Code != http.StatusOK {
+		t.Fatalf("/readyz after recovery: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
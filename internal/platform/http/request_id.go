@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// TraceIDHeader is the response header carrying the W3C trace ID, when one is present
+const TraceIDHeader = "X-Trace-ID"
+
+// echoRequestID returns the request ID (and trace ID, when the request carries
+// a traceparent) on every response so clients can quote it when reporting issues.
+// The value is the same one the request logger writes for the request.
+func echoRequestID(header string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requestID := middleware.GetReqID(r.Context()); requestID != "" {
+				w.Header().Set(header, requestID)
+			}
+			if traceID := traceIDFromTraceparent(r.Header.Get("traceparent")); traceID != "" {
+				w.Header().Set(TraceIDHeader, traceID)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// traceIDFromTraceparent extracts the trace ID from a W3C traceparent header
+// ("version-traceid-parentid-flags"), returning "" if it is malformed.
+func traceIDFromTraceparent(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+	return parts[1]
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/httputil"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// loggedRequestIDs returns the request_id of each entry logged with message
+func loggedRequestIDs(hook *test.Hook, message string) []interface{} {
+	var ids []interface{}
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == message {
+			ids = append(ids, entry.Data["request_id"])
+		}
+	}
+	return ids
+}
+
+func TestRequestIDPropagatesToDownstreamServices(t *testing.T) {
+	withWriteTimeout := func(c *ServerConfig) { c.WriteTimeout = time.Minute }
+
+	// The downstream service, standing in for loyalty
+	downstream, downstreamHook := newTestServer(t, withWriteTimeout)
+	downstream.Router().Post("/v1/points/spend", func(w http.ResponseWriter, r *http.Request) {
+		downstream.logger.WithContext(r.Context()).Info("spending points")
+		w.WriteHeader(http.StatusOK)
+	})
+	downstreamHTTP := httptest.NewServer(downstream.Router())
+	t.Cleanup(downstreamHTTP.Close)
+
+	// The upstream service, standing in for the redemption saga
+	upstream, upstreamHook := newTestServer(t, withWriteTimeout)
+	upstream.Router().Post("/v1/redeem", func(w http.ResponseWriter, r *http.Request) {
+		upstream.logger.WithContext(r.Context()).Info("redeeming")
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, downstreamHTTP.URL+"/v1/points/spend", nil)
+		if err != nil {
+			t.Errorf("build request: %v", err)
+			return
+		}
+		httputil.SetRequestID(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("call downstream: %v", err)
+			return
+		}
+		resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+	})
+
+	for _, callerID := range []string{"", "client-request-42"} {
+		downstreamHook.Reset()
+		upstreamHook.Reset()
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/redeem", nil)
+		if callerID != "" {
+			req.Header.Set("X-Request-ID", callerID)
+		}
+		rec := httptest.NewRecorder()
+		upstream.Router().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+
+		// An ID is generated at the edge when the caller sends none
+		requestID := rec.Header().Get("X-Request-ID")
+		if requestID == "" || (callerID != "" && requestID != callerID) {
+			t.Fatalf("request ID = %q, want the caller's %q or a generated one", requestID, callerID)
+		}
+
+		// Both services log every line about the redemption with that ID
+		for _, check := range []struct {
+			hook    *test.Hook
+			message string
+		}{
+			{upstreamHook, "redeeming"},
+			{upstreamHook, "HTTP request"},
+			{downstreamHook, "spending points"},
+			{downstreamHook, "HTTP request"},
+		} {
+			ids := loggedRequestIDs(check.hook, check.message)
+			if len(ids) != 1 || ids[0] != requestID {
+				t.Errorf("%q logged with request IDs %v, want %q", check.message, ids, requestID)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultLogSkipPaths are probe and scrape paths left out of request logs
+var defaultLogSkipPaths = []string{"/healthz", "/readyz", "/metrics"}
+
+type requestLogKey struct{}
+
+// requestLogFields holds values learned while handling a request that the
+// request log includes once the response is written
+type requestLogFields struct {
+	userID string
+}
+
+// SetRequestUserID records the authenticated user for the request log and the
+// request's trace span. Auth middleware runs inside the logger, so it cannot
+// pass the user back through the request context.
+func SetRequestUserID(ctx context.Context, userID string) {
+	if fields, ok := ctx.Value(requestLogKey{}).(*requestLogFields); ok {
+		fields.userID = userID
+	}
+	tracing.SpanFromContext(ctx).SetAttribute("enduser.id", userID)
+}
+
+// requestLogger logs each request as structured fields once it completes.
+// Requests for the skipped paths are not logged.
+func requestLogger(logger *logrus.Logger, skipPaths []string) func(http.Handler) http.Handler {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			fields := &requestLogFields{}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					// Nothing was written, which net/http sends as 200
+					status = http.StatusOK
+				}
+
+				entry := logger.WithFields(logrus.Fields{
+					"method":      r.Method,
+					"path":        r.URL.Path,
+					"status":      status,
+					"bytes":       ww.BytesWritten(),
+					"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+					"request_id":  middleware.GetReqID(r.Context()),
+					"remote_addr": r.RemoteAddr,
+				})
+				if span := tracing.SpanFromContext(r.Context()); span != nil {
+					entry = entry.WithField("trace_id", span.SpanContext().TraceID.String())
+				}
+				if fields.userID != "" {
+					entry = entry.WithField("user_id", fields.userID)
+				}
+
+				if status >= http.StatusInternalServerError {
+					entry.Error("HTTP request")
+				} else {
+					entry.Info("HTTP request")
+				}
+			}()
+
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), requestLogKey{}, fields)))
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRequestLogFields(t *testing.T) {
+	s, hook := newTestServer(t)
+	s.Router().Post("/items", func(w http.ResponseWriter, r *http.Request) {
+		SetRequestUserID(r.Context(), "user-42")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/items?debug=1", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	s.Router().ServeHTTP(httptest.NewRecorder(), req)
+
+	entry := requestLog(t, hook)
+	if entry.Level != logrus.InfoLevel {
+		t.Errorf("level = %s, want info", entry.Level)
+	}
+	want := logrus.Fields{
+		"method":     http.MethodPost,
+		"path":       "/items",
+		"status":     http.StatusCreated,
+		"bytes":      5,
+		"request_id": "req-1",
+		"user_id":    "user-42",
+	}
+	for field, value := range want {
+		if got := entry.Data[field]; got != value {
+			t.Errorf("%s = %v (%T), want %v", field, got, got, value)
+		}
+	}
+	if duration, ok := entry.Data["duration_ms"].(float64); !ok || duration < 0 {
+		t.Errorf("duration_ms = %v, want a non-negative number of milliseconds", entry.Data["duration_ms"])
+	}
+}
+
+func TestRequestLogLevelsAndDefaults(t *testing.T) {
+	// The timeout middleware answers 504 for silent handlers once the write
+	// timeout has passed, so give them one
+	s, hook := newTestServer(t, func(config *ServerConfig) { config.WriteTimeout = time.Minute })
+	s.Router().Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	s.Router().Get("/silent", func(w http.ResponseWriter, r *http.Request) {})
+
+	s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	entry := requestLog(t, hook)
+	if entry.Level != logrus.ErrorLevel || entry.Data["status"] != http.StatusBadGateway {
+		t.Fatalf("logged %s with status %v, want an error with 502", entry.Level, entry.Data["status"])
+	}
+	if _, ok := entry.Data["user_id"]; ok {
+		t.Error("user_id logged for an anonymous request")
+	}
+
+	// A handler that writes nothing is logged with the 200 net/http sends
+	hook.Reset()
+	s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/silent", nil))
+	if got := requestLog(t, hook).Data["status"]; got != http.StatusOK {
+		t.Fatalf("status = %v, want %d", got, http.StatusOK)
+	}
+}
+
+func TestRequestLogSkipPaths(t *testing.T) {
+	tests := []struct {
+		name      string
+		skipPaths []string
+		logged    map[string]bool
+	}{
+		{"default", nil, map[string]bool{"/healthz": false, "/readyz": false, "/noisy": true}},
+		{"configured", []string{"/noisy"}, map[string]bool{"/healthz": true, "/noisy": false}},
+		{"none", []string{}, map[string]bool{"/healthz": true, "/noisy": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, hook := newTestServer(t, func(config *ServerConfig) { config.LogSkipPaths = tt.skipPaths })
+			s.Router().Get("/noisy", func(w http.ResponseWriter, r *http.Request) {})
+
+			for path, want := range tt.logged {
+				hook.Reset()
+				s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+				logged := false
+				for _, entry := range hook.AllEntries() {
+					logged = logged || entry.Message == "HTTP request"
+				}
+				if logged != want {
+					t.Errorf("%s logged = %v, want %v", path, logged, want)
+				}
+			}
+		})
+	}
+}
@@ -9,16 +9,18 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/render"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/httputil"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
 
 // Server represents an HTTP server
 type Server struct {
-	router *chi.Mux
-	server *http.Server
-	logger *logrus.Logger
-	config *ServerConfig
+	router    *chi.Mux
+	server    *http.Server
+	logger    *logrus.Logger
+	config    *ServerConfig
+	readiness *Readiness
 }
 
 // ServerConfig holds server configuration
@@ -28,11 +30,37 @@ type ServerConfig struct {
 	WriteTimeout    time.Duration
 	IdleTimeout     time.Duration
 	ShutdownTimeout time.Duration
-	AllowedOrigins  []string
-	AllowedMethods  []string
-	AllowedHeaders  []string
+	// AllowedOrigins lists the origins browsers may call from, "*" for any.
+	// Empty sends no CORS headers, so browsers refuse cross-origin calls.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// AllowCredentials lets browsers send cookies and Authorization headers.
+	// It is ignored when every origin is allowed, which browsers reject.
+	AllowCredentials bool
+	RequestIDHeader  string
+	// PreflightMaxAge is how long browsers may cache a preflight response
+	PreflightMaxAge time.Duration
+	// LogSkipPaths are request paths left out of the request log; nil skips
+	// the health, readiness, and metrics endpoints
+	LogSkipPaths []string
+	// MaxBodyBytes caps request bodies; 0 uses DefaultMaxBodyBytes and a
+	// negative value removes the cap
+	MaxBodyBytes int64
+	// Metrics serves Prometheus metrics on /metrics and records request
+	// counts and latencies
+	Metrics bool
 }
 
+// DefaultMaxBodyBytes is the request body cap used when ServerConfig leaves
+// MaxBodyBytes unset
+const DefaultMaxBodyBytes = 1 << 20
+
+var (
+	defaultAllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	defaultAllowedHeaders = []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Idempotency-Key", "X-Tenant-ID", "If-None-Match", "If-Modified-Since"}
+)
+
 // NewServer creates a new HTTP server with default configuration
 func NewServer(config *ServerConfig, logger *logrus.Logger) *Server {
 	if config == nil {
@@ -43,35 +71,81 @@ func NewServer(config *ServerConfig, logger *logrus.Logger) *Server {
 			IdleTimeout:     60 * time.Second,
 			ShutdownTimeout: 15 * time.Second,
 			AllowedOrigins:  []string{"*"},
-			AllowedMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders:  []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+			Metrics:         true,
 		}
 	}
+	if config.RequestIDHeader == "" {
+		config.RequestIDHeader = "X-Request-ID"
+	}
+	if config.AllowCredentials && containsString(config.AllowedOrigins, "*") {
+		logger.Warn("CORS credentials disabled because every origin is allowed; list the allowed origins to enable them")
+		config.AllowCredentials = false
+	}
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = defaultAllowedMethods
+	}
+	if len(config.AllowedHeaders) == 0 {
+		config.AllowedHeaders = append(append([]string{}, defaultAllowedHeaders...), config.RequestIDHeader)
+	}
+	if config.PreflightMaxAge <= 0 {
+		config.PreflightMaxAge = 5 * time.Minute
+	}
+	if config.MaxBodyBytes == 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if config.LogSkipPaths == nil {
+		config.LogSkipPaths = defaultLogSkipPaths
+	}
+	middleware.RequestIDHeader = config.RequestIDHeader
 
 	router := chi.NewRouter()
 
-	// Add middleware
+	// CORS runs first so preflights are answered before logging, timeouts,
+	// rate limiting, or auth. The cors package treats an empty allowlist as
+	// allowing every origin, so it is left out instead.
+	if len(config.AllowedOrigins) > 0 {
+		router.Use(cors.Handler(cors.Options{
+			AllowedOrigins:     config.AllowedOrigins,
+			AllowedMethods:     config.AllowedMethods,
+			AllowedHeaders:     config.AllowedHeaders,
+			ExposedHeaders:     []string{"Link", config.RequestIDHeader, TraceIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "X-RateLimit-Remaining", "Retry-After"},
+			AllowCredentials:   config.AllowCredentials,
+			MaxAge:             int(config.PreflightMaxAge.Seconds()),
+			OptionsPassthrough: true,
+		}))
+	}
+	router.Use(endPreflight)
+
+	// Log lines made with a request's context carry its ID
+	logger.AddHook(httputil.RequestIDHook{})
+
+	// Add middleware; RequestID assigns an ID at the edge unless the caller
+	// sent one
 	router.Use(middleware.RequestID)
+	router.Use(echoRequestID(config.RequestIDHeader))
 	router.Use(middleware.RealIP)
-	router.Use(middleware.Logger)
+	router.Use(traceRequests)
+	router.Use(requestLogger(logger, config.LogSkipPaths))
+	if config.Metrics {
+		router.Use(requestMetrics)
+	}
 	router.Use(middleware.Recoverer)
+	if config.MaxBodyBytes > 0 {
+		router.Use(limitBody(config.MaxBodyBytes))
+	}
 	router.Use(middleware.Timeout(config.WriteTimeout))
 
-	// CORS middleware
-	router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   config.AllowedOrigins,
-		AllowedMethods:   config.AllowedMethods,
-		AllowedHeaders:   config.AllowedHeaders,
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-
-	// Health check endpoint
+	// Liveness endpoint; answers without checking dependencies
 	router.Get("/healthz", healthCheck)
 
+	// Readiness endpoint, reflecting the dependencies registered with Readiness()
+	readiness := NewReadiness(logger)
+	router.Method(http.MethodGet, "/readyz", readiness)
+
 	// Prometheus metrics endpoint
-	router.Handle("/metrics", promhttp.Handler())
+	if config.Metrics {
+		router.Handle("/metrics", promhttp.Handler())
+	}
 
 	server := &http.Server{
 		Addr:         config.Addr,
@@ -82,10 +156,11 @@ func NewServer(config *ServerConfig, logger *logrus.Logger) *Server {
 	}
 
 	return &Server{
-		router: router,
-		server: server,
-		logger: logger,
-		config: config,
+		router:    router,
+		server:    server,
+		logger:    logger,
+		config:    config,
+		readiness: readiness,
 	}
 }
 
@@ -94,6 +169,17 @@ func (s *Server) Router() *chi.Mux {
 	return s.router
 }
 
+// Readiness returns the tracker behind /readyz, for registering dependencies
+func (s *Server) Readiness() *Readiness {
+	return s.readiness
+}
+
+// AddReadinessCheck registers a required dependency that /readyz checks on
+// every probe. /healthz stays a liveness probe and never runs checks.
+func (s *Server) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	s.readiness.AddCheck(name, check)
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	s.logger.Infof("Starting HTTP server on %s", s.config.Addr)
@@ -109,6 +195,33 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(shutdownCtx)
 }
 
+// endPreflight answers CORS preflight requests with 204 No Content once the
+// CORS handler has set the Access-Control headers, so they never reach the
+// rest of the chain
+func endPreflight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitBody caps each request body at max bytes, so a client cannot exhaust
+// memory by streaming a huge body. Reads past the cap fail with
+// *http.MaxBytesError, which RequestError answers with 413.
+func limitBody(max int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, max)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // healthCheck handles health check requests
 func healthCheck(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, map[string]interface{}{
@@ -119,7 +232,7 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 // AddRoutes adds routes to the server
-func (s *Server) AddRoutes(routes func(*chi.Mux)) {
+func (s *Server) AddRoutes(routes func(chi.Router)) {
 	routes(s.router)
 }
 
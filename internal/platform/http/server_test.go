@@ -0,0 +1,355 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// newTestServer creates a server with metrics off whose log entries are
+// captured by the returned hook; configure adjusts the configuration first
+func newTestServer(t *testing.T, configure ...func(*ServerConfig)) (*Server, *test.Hook) {
+	t.Helper()
+
+	config := &ServerConfig{Addr: ":0", AllowedOrigins: []string{"*"}}
+	for _, fn := range configure {
+		fn(config)
+	}
+
+	logger, hook := test.NewNullLogger()
+	return NewServer(config, logger), hook
+}
+
+// requestLog returns the request log entry, failing the test if there is none
+func requestLog(t *testing.T, hook *test.Hook) *logrus.Entry {
+	t.Helper()
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "HTTP request" {
+			return entry
+		}
+	}
+	t.Fatal("request was not logged")
+	return nil
+}
+
+func TestRequestIDMatchesLogs(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+	}{
+		{"generated", ""},
+		{"sent by the caller", "client-request-42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, hook := newTestServer(t)
+			s.Router().Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+				s.logger.WithContext(r.Context()).Info("handling request")
+				Error(w, r, http.StatusNotFound, ErrCodeNotFound, "Not found")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+
+			requestID := rec.Header().Get("X-Request-ID")
+			if requestID == "" {
+				t.Fatal("response has no request ID")
+			}
+			if tt.requestID != "" && requestID != tt.requestID {
+				t.Fatalf("request ID = %q, want the caller's %q", requestID, tt.requestID)
+			}
+
+			if got := requestLog(t, hook).Data["request_id"]; got != requestID {
+				t.Errorf("request log request_id = %v, want %q", got, requestID)
+			}
+			for _, entry := range hook.AllEntries() {
+				if entry.Message == "handling request" && entry.Data["request_id"] != requestID {
+					t.Errorf("handler log request_id = %v, want %q", entry.Data["request_id"], requestID)
+				}
+			}
+
+			var body ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if body.RequestID != requestID {
+				t.Errorf("error body request_id = %q, want %q", body.RequestID, requestID)
+			}
+		})
+	}
+}
+
+func TestCustomRequestIDHeader(t *testing.T) {
+	s, _ := newTestServer(t, func(config *ServerConfig) {
+		config.RequestIDHeader = "X-Correlation-ID"
+	})
+	// The header is package state in chi's middleware
+	t.Cleanup(func() { middleware.RequestIDHeader = "X-Request-ID" })
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("X-Correlation-ID", "correlation-1")
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-Correlation-ID"); got != "correlation-1" {
+		t.Fatalf("X-Correlation-ID = %q, want correlation-1", got)
+	}
+}
+
+func TestTraceIDEcho(t *testing.T) {
+	tests := []struct {
+		traceparent string
+		want        string
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", ""},
+		{"malformed", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.traceparent, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			s.Router().Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "ok")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+
+			if got := rec.Header().Get(TraceIDHeader); got != tt.want {
+				t.Fatalf("%s = %q, want %q", TraceIDHeader, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreflightSkipsMiddlewareAndHandlers(t *testing.T) {
+	tests := []struct {
+		name            string
+		origins         []string
+		credentials     bool
+		origin          string
+		wantAllowOrigin string
+	}{
+		{"any origin", []string{"*"}, false, "https://app.example.com", "*"},
+		{"listed origin with credentials", []string{"https://app.example.com"}, true, "https://app.example.com", "https://app.example.com"},
+		{"unlisted origin", []string{"https://app.example.com"}, true, "https://evil.example.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, hook := newTestServer(t, func(c *ServerConfig) {
+				c.AllowedOrigins = tt.origins
+				c.AllowCredentials = tt.credentials
+			})
+			var reached []string
+			s.Router().Group(func(r chi.Router) {
+				r.Use(NewRateLimiter(RateLimitConfig{RequestsPerMinute: 1, Burst: 1}).Middleware)
+				// Stands in for auth, which rejects requests without a token
+				r.Use(func(next http.Handler) http.Handler {
+					return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						reached = append(reached, "auth")
+						if r.Header.Get("Authorization") == "" {
+							Error(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "Missing token")
+							return
+						}
+						next.ServeHTTP(w, r)
+					})
+				})
+				r.Post("/v1/protected", func(w http.ResponseWriter, r *http.Request) {
+					reached = append(reached, "handler")
+				})
+			})
+
+			// More preflights than the rate limit allows are all answered
+			for i := 0; i < 3; i++ {
+				req := httptest.NewRequest(http.MethodOptions, "/v1/protected", nil)
+				req.Header.Set("Origin", tt.origin)
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				req.Header.Set("Access-Control-Request-Headers", "Authorization, Content-Type, Idempotency-Key")
+				rec := httptest.NewRecorder()
+				s.Router().ServeHTTP(rec, req)
+
+				if rec.Code != http.StatusNoContent {
+					t.Fatalf("preflight %d: status = %d, want %d", i, rec.Code, http.StatusNoContent)
+				}
+				if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+					t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowOrigin)
+				}
+				if tt.wantAllowOrigin == "" {
+					continue
+				}
+				if got := rec.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPost) {
+					t.Errorf("Access-Control-Allow-Methods = %q, want POST allowed", got)
+				}
+				allowHeaders := strings.ToLower(rec.Header().Get("Access-Control-Allow-Headers"))
+				for _, header := range []string{"authorization", "content-type", "idempotency-key"} {
+					if !strings.Contains(allowHeaders, header) {
+						t.Errorf("Access-Control-Allow-Headers = %q, want %s allowed", allowHeaders, header)
+					}
+				}
+				if got := rec.Header().Get("Access-Control-Max-Age"); got != "300" {
+					t.Errorf("Access-Control-Max-Age = %q, want 300", got)
+				}
+				wantCredentials := ""
+				if tt.credentials {
+					wantCredentials = "true"
+				}
+				if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != wantCredentials {
+					t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, wantCredentials)
+				}
+			}
+
+			if len(reached) != 0 {
+				t.Fatalf("preflight reached %v", reached)
+			}
+			for _, entry := range hook.AllEntries() {
+				if entry.Message == "HTTP request" {
+					t.Fatalf("preflight was logged: %v", entry.Data)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSHeadersOnRequests(t *testing.T) {
+	tests := []struct {
+		name            string
+		origins         []string
+		credentials     bool
+		origin          string
+		wantAllowOrigin string
+		wantCredentials string
+	}{
+		{"allowed origin", []string{"https://app.example.com"}, true, "https://app.example.com", "https://app.example.com", "true"},
+		{"disallowed origin", []string{"https://app.example.com"}, true, "https://evil.example.com", "", ""},
+		{"empty allowlist", nil, true, "https://app.example.com", "", ""},
+		{"any origin drops credentials", []string{"*"}, true, "https://app.example.com", "*", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, hook := newTestServer(t, func(c *ServerConfig) {
+				c.AllowedOrigins = tt.origins
+				c.AllowCredentials = tt.credentials
+			})
+			s.Router().Get("/v1/resource", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/resource", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, req)
+
+			// The request is served either way; only the browser is told
+			// whether it may read the response
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Fatalf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCredentials)
+			}
+			if tt.wantAllowOrigin != "" && !strings.Contains(rec.Header().Get("Access-Control-Expose-Headers"), "X-Request-Id") {
+				t.Errorf("Access-Control-Expose-Headers = %q, want the request ID exposed", rec.Header().Get("Access-Control-Expose-Headers"))
+			}
+
+			warned := false
+			for _, entry := range hook.AllEntries() {
+				warned = warned || (entry.Level == logrus.WarnLevel && strings.HasPrefix(entry.Message, "CORS credentials disabled"))
+			}
+			if want := tt.credentials && tt.wantAllowOrigin == "*"; warned != want {
+				t.Fatalf("warned = %v, want %v", warned, want)
+			}
+		})
+	}
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int64
+		size     int
+		want     int
+	}{
+		{"under the cap", 64, 40, http.StatusOK},
+		{"over the cap", 64, 65, http.StatusRequestEntityTooLarge},
+		{"over the default cap", 0, DefaultMaxBodyBytes + 1, http.StatusRequestEntityTooLarge},
+		{"no cap", -1, DefaultMaxBodyBytes + 1, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t, func(c *ServerConfig) {
+				c.MaxBodyBytes = tt.maxBytes
+				c.WriteTimeout = time.Minute
+			})
+			s.Router().Post("/v1/notes", func(w http.ResponseWriter, r *http.Request) {
+				var note struct {
+					Text string `json:"text"`
+				}
+				if err := DecodeJSON(r, &note); err != nil {
+					RequestError(w, r, err)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			// A JSON string padded out to the requested size
+			body := `{"text":"` + strings.Repeat("a", tt.size-len(`{"text":""}`)) + `"}`
+			rec := httptest.NewRecorder()
+			s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/notes", strings.NewReader(body)))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusRequestEntityTooLarge {
+				return
+			}
+			var errBody ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&errBody); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			limit := tt.maxBytes
+			if limit == 0 {
+				limit = DefaultMaxBodyBytes
+			}
+			if want := fmt.Sprintf("Request body must be at most %d bytes", limit); errBody.Code != ErrCodePayloadTooLarge || errBody.Message != want {
+				t.Fatalf("error = %+v, want %s: %q", errBody, ErrCodePayloadTooLarge, want)
+			}
+		})
+	}
+}
+
+func TestOptionsWithoutPreflightReachesRoutes(t *testing.T) {
+	s, _ := newTestServer(t)
+	reached := false
+	s.Router().Options("/v1/resource", func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/v1/resource", nil))
+	if !reached || rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, reached = %v; want a plain OPTIONS request routed", rec.Code, reached)
+	}
+}
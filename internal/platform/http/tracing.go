@@ -0,0 +1,51 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
+)
+
+// traceRequests starts a server span for each request, continuing the trace
+// in the request's traceparent header. The span is named after the matched
+// route and records the status; auth middleware adds the user ID through
+// SetRequestUserID. It does nothing while tracing is disabled.
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracing.Start(ctx, "HTTP "+r.Method, tracing.SpanKindServer)
+		if span == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set(TraceIDHeader, span.SpanContext().TraceID.String())
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+		if requestID := middleware.GetReqID(ctx); requestID != "" {
+			span.SetAttribute("request_id", requestID)
+		}
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(r.Method + " " + rctx.RoutePattern())
+				span.SetAttribute("http.route", rctx.RoutePattern())
+			}
+			span.SetAttribute("http.status_code", status)
+			if status >= http.StatusInternalServerError {
+				span.SetError(errors.New(http.StatusText(status)))
+			}
+			span.End()
+		}()
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing/tracingtest"
+)
+
+func TestRequestsAreTraced(t *testing.T) {
+	exporter, flush := tracingtest.Install(t)
+	s, _ := newTestServer(t)
+	s.Router().Get("/v1/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		SetRequestUserID(r.Context(), "user-42")
+		w.WriteHeader(http.StatusOK)
+	})
+	s.Router().Get("/v1/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/items/7", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, req)
+	s.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/fail", nil))
+	flush()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want one per request", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != "GET /v1/items/{id}" {
+		t.Errorf("name = %q, want the method and route", span.Name())
+	}
+	for key, want := range map[string]interface{}{
+		"http.route":       "/v1/items/{id}",
+		"http.status_code": http.StatusOK,
+		"enduser.id":       "user-42",
+	} {
+		if got := span.Attribute(key); got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+	// The span continues the caller's trace, whose ID is echoed back
+	if traceID := span.SpanContext().TraceID.String(); traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || rec.Header().Get(TraceIDHeader) != traceID {
+		t.Errorf("trace ID = %s, header %q; want the caller's trace", traceID, rec.Header().Get(TraceIDHeader))
+	}
+
+	if spans[1].Attribute("http.status_code") != http.StatusInternalServerError || spans[1].Attribute("enduser.id") != nil {
+		t.Errorf("failed request span = %s with status %v", spans[1].Name(), spans[1].Attribute("http.status_code"))
+	}
+}
@@ -0,0 +1,253 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/jsonutil"
+)
+
+// FieldErrors maps request fields, named as in the JSON body, to validation
+// messages. DecodeAndValidate returns it as an error.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field, message := range e {
+		fields = append(fields, field+" "+message)
+	}
+	return "validation failed: " + strings.Join(fields, "; ")
+}
+
+// DecodeJSON decodes the JSON request body into v, rejecting fields that v
+// does not have. A body over the server's size limit fails with
+// *http.MaxBytesError.
+func DecodeJSON(r *http.Request, v interface{}) error {
+	return jsonutil.DecodeStrict(r.Body, v)
+}
+
+// DecodeAndValidate decodes the JSON request body into v as DecodeJSON does
+// and checks it against its validate tags. Invalid fields are reported as
+// FieldErrors; any other error means the body could not be decoded.
+func DecodeAndValidate(r *http.Request, v interface{}) error {
+	if err := DecodeJSON(r, v); err != nil {
+		return err
+	}
+	if errs := Validate(v); errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// RequestError answers a DecodeJSON or DecodeAndValidate failure: 422 listing
+// the invalid fields, 413 if the body was too large, or 400 if it was not
+// valid JSON or had unknown fields
+func RequestError(w http.ResponseWriter, r *http.Request, err error) {
+	var fieldErrs FieldErrors
+	if errors.As(err, &fieldErrs) {
+		ValidationError(w, r, fieldErrs)
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		Error(w, r, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge,
+			fmt.Sprintf("Request body must be at most %d bytes", tooLarge.Limit))
+		return
+	}
+	// encoding/json reports unknown fields only in the error text
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		Error(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Unknown field "+field)
+		return
+	}
+	Error(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid request body")
+}
+
+// Validate checks a struct against the validate tags on its fields and
+// returns the fields that fail, or nil. Nested structs are checked too, with
+// their fields reported as "parent.child".
+//
+// The supported rules follow go-playground/validator:
+//
+//	required     the field is not its zero value
+//	omitempty    skip the remaining rules when the field is its zero value
+//	email        a bare email address, without a display name
+//	uuid         a UUID in its canonical, hyphenated form
+//	oneof=a b c  one of the space-separated values
+//	min=N max=N  the length of strings and slices, or the value of numbers
+//	gt=N         longer than, or greater than, N
+func Validate(v interface{}) FieldErrors {
+	errs := FieldErrors{}
+	validateStruct(reflect.ValueOf(v), "", errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateStruct(v reflect.Value, prefix string, errs FieldErrors) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+		value := v.Field(i)
+
+		if tag := field.Tag.Get("validate"); tag != "" {
+			if message := checkRules(value, tag); message != "" {
+				errs[prefix+name] = message
+				continue
+			}
+		}
+
+		nested := value
+		if nested.Kind() == reflect.Pointer && !nested.IsNil() {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct {
+			validateStruct(nested, prefix+name+".", errs)
+		}
+	}
+}
+
+// jsonName returns the field's name in JSON, or "" if it is not serialized
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// checkRules returns why value breaks the first failing rule, or ""
+func checkRules(value reflect.Value, tag string) string {
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if value.IsZero() {
+				return "is required"
+			}
+		case "omitempty":
+			if value.IsZero() {
+				return ""
+			}
+		case "email":
+			if s, ok := stringValue(value); ok && !validEmail(s) {
+				return "must be a valid email address"
+			}
+		case "uuid":
+			if s, ok := stringValue(value); ok && !validUUID(s) {
+				return "must be a valid UUID"
+			}
+		case "oneof":
+			allowed := strings.Fields(param)
+			s, ok := stringValue(value)
+			if !ok {
+				s = fmt.Sprint(value.Interface())
+			}
+			if !containsString(allowed, s) {
+				return "must be one of " + strings.Join(allowed, ", ")
+			}
+		case "min", "max", "gt":
+			if message := checkBound(value, name, param); message != "" {
+				return message
+			}
+		default:
+			panic(fmt.Sprintf("http: unsupported validate rule %q", rule))
+		}
+	}
+	return ""
+}
+
+// checkBound applies a min, max, or gt rule to a length or a number
+func checkBound(value reflect.Value, rule, param string) string {
+	bound, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("http: invalid %s parameter %q", rule, param))
+	}
+
+	var n float64
+	var unit string
+	switch value.Kind() {
+	case reflect.String:
+		n, unit = float64(len([]rune(value.String()))), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		n, unit = float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = value.Float()
+	default:
+		return ""
+	}
+	if bound == 1 {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+
+	switch {
+	case rule == "min" && n < bound:
+		return "must be at least " + param + unit
+	case rule == "max" && n > bound:
+		return "must be at most " + param + unit
+	case rule == "gt" && n <= bound:
+		if unit != "" {
+			return "must be longer than " + param + unit
+		}
+		return "must be greater than " + param
+	}
+	return ""
+}
+
+func stringValue(value reflect.Value) (string, bool) {
+	if value.Kind() != reflect.String {
+		return "", false
+	}
+	return value.String(), true
+}
+
+// validEmail reports whether email is a bare address, without a display name
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// validUUID reports whether s is a UUID in its canonical, hyphenated form
+func validUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil && len(s) == 36
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
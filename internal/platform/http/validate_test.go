@@ -0,0 +1,111 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validatedAddress struct {
+	Country string `json:"country" validate:"required,oneof=US CA"`
+}
+
+type validatedRequest struct {
+	Email    string            `json:"email" validate:"required,email"`
+	Type     string            `json:"type" validate:"required,oneof=email sms push"`
+	Password string            `json:"password" validate:"min=8"`
+	Points   int               `json:"points" validate:"gt=0"`
+	Note     string            `json:"note,omitempty" validate:"omitempty,max=5"`
+	RefID    string            `json:"ref_id,omitempty" validate:"omitempty,uuid"`
+	UserIDs  []string          `json:"user_ids" validate:"min=1,max=2"`
+	Address  *validatedAddress `json:"address"`
+	Ignored  string            `json:"-" validate:"required"`
+}
+
+func validRequest() validatedRequest {
+	return validatedRequest{
+		Email: "jane@example.com", Type: "sms", Password: "correct-horse", Points: 1,
+		UserIDs: []string{"user-1"}, Address: &validatedAddress{Country: "US"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*validatedRequest)
+		want   FieldErrors
+	}{
+		{"valid", func(r *validatedRequest) {}, nil},
+		{"required", func(r *validatedRequest) { r.Email = "" }, FieldErrors{"email": "is required"}},
+		{"email", func(r *validatedRequest) { r.Email = "Jane <jane@example.com>" }, FieldErrors{"email": "must be a valid email address"}},
+		{"oneof", func(r *validatedRequest) { r.Type = "carrier-pigeon" }, FieldErrors{"type": "must be one of email, sms, push"}},
+		{"min length in characters", func(r *validatedRequest) { r.Password = "ééééééé" }, FieldErrors{"password": "must be at least 8 characters"}},
+		{"gt", func(r *validatedRequest) { r.Points = 0 }, FieldErrors{"points": "must be greater than 0"}},
+		{"omitempty skips empty", func(r *validatedRequest) { r.Note = "" }, nil},
+		{"omitempty checks set", func(r *validatedRequest) { r.Note = "too long" }, FieldErrors{"note": "must be at most 5 characters"}},
+		{"uuid", func(r *validatedRequest) { r.RefID = "3f2b8c1e-9d4a-4c6b-8e2f-1a5d7c9b0e34" }, nil},
+		{"not a uuid", func(r *validatedRequest) { r.RefID = "../partners" }, FieldErrors{"ref_id": "must be a valid UUID"}},
+		{"braced uuid", func(r *validatedRequest) { r.RefID = "{3f2b8c1e-9d4a-4c6b-8e2f-1a5d7c9b0e34}" }, FieldErrors{"ref_id": "must be a valid UUID"}},
+		{"min items", func(r *validatedRequest) { r.UserIDs = nil }, FieldErrors{"user_ids": "must be at least 1 item"}},
+		{"max items", func(r *validatedRequest) { r.UserIDs = []string{"a", "b", "c"} }, FieldErrors{"user_ids": "must be at most 2 items"}},
+		{"nested", func(r *validatedRequest) { r.Address.Country = "FR" }, FieldErrors{"address.country": "must be one of US, CA"}},
+		{"nil nested", func(r *validatedRequest) { r.Address = nil }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validRequest()
+			tt.modify(&req)
+			if got := Validate(&req); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Validate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// decodeAndRespond runs body through DecodeAndValidate, answering failures
+// with RequestError
+func decodeAndRespond(body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	var v validatedRequest
+	if err := DecodeAndValidate(req, &v); err != nil {
+		RequestError(rec, req, err)
+	} else {
+		rec.WriteHeader(http.StatusOK)
+	}
+	return rec
+}
+
+func TestDecodeAndValidate(t *testing.T) {
+	valid, _ := json.Marshal(validRequest())
+	if rec := decodeAndRespond(string(valid)); rec.Code != http.StatusOK {
+		t.Fatalf("valid body: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := decodeAndRespond(`{"email":"jane","type":"carrier-pigeon","password":"correct-horse","points":5,"user_ids":["u"]}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid fields: status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	var body ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Code != ErrCodeValidationFailed || len(body.Fields) != 2 || body.Fields["email"] == "" || body.Fields["type"] == "" {
+		t.Fatalf("error = %+v, want field errors for email and type", body)
+	}
+
+	for _, tt := range []struct{ name, body, message string }{
+		{"malformed", `{"email":`, "Invalid request body"},
+		{"unknown field", `{"emial":"jane@example.com"}`, `Unknown field "emial"`},
+	} {
+		rec := decodeAndRespond(tt.body)
+		var body ErrorResponse
+		json.NewDecoder(rec.Body).Decode(&body)
+		if rec.Code != http.StatusBadRequest || body.Message != tt.message {
+			t.Errorf("%s: %d %q, want 400 with %q", tt.name, rec.Code, body.Message, tt.message)
+		}
+	}
+}
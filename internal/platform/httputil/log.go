@@ -0,0 +1,27 @@
+package httputil
+
+import "github.com/sirupsen/logrus"
+
+// RequestIDHook adds the request ID to log entries made with a request's
+// context, as logger.WithContext(ctx), so every line about a request can be
+// found by the ID in its request log
+type RequestIDHook struct{}
+
+// Levels returns every level, so all entries are tagged
+func (RequestIDHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the request_id field unless the entry already has one
+func (RequestIDHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if _, ok := entry.Data["request_id"]; ok {
+		return nil
+	}
+	if requestID := RequestIDFromContext(entry.Context); requestID != "" {
+		entry.Data["request_id"] = requestID
+	}
+	return nil
+}
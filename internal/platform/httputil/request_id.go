@@ -0,0 +1,33 @@
+// Package httputil carries request-scoped values across service boundaries.
+package httputil
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDFromContext returns the ID of the request ctx belongs to, or "" if
+// there is none. The server assigns it at the edge, reusing the caller's
+// request ID header when one is sent.
+func RequestIDFromContext(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
+
+// ContextWithRequestID returns ctx carrying requestID, for work continuing a
+// request outside its handler, such as a consumed message
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, middleware.RequestIDKey, requestID)
+}
+
+// SetRequestID sets the request ID header of an outbound request to the ID in
+// its context, so the called service logs the same ID
+func SetRequestID(req *http.Request) {
+	if requestID := RequestIDFromContext(req.Context()); requestID != "" {
+		req.Header.Set(middleware.RequestIDHeader, requestID)
+	}
+}
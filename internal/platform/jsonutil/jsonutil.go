@@ -0,0 +1,74 @@
+package jsonutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// maxSafeFloatInt is the largest integer a float64 represents exactly (2^53)
+const maxSafeFloatInt = 1 << 53
+
+// Decode decodes JSON from r into v. Numbers decoded into interface{}
+// values are kept as json.Number rather than float64, so large point
+// values survive untyped maps without losing precision.
+func Decode(r io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// DecodeStrict is like Decode but fails on object fields that v does not have
+func DecodeStrict(r io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// Unmarshal is like json.Unmarshal but keeps numbers as json.Number
+func Unmarshal(data []byte, v interface{}) error {
+	return Decode(bytes.NewReader(data), v)
+}
+
+// Int64 extracts an integer from a value decoded into interface{}.
+// Floats are only accepted when they are whole and exactly representable.
+func Int64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("%q is not an integer: %w", n.String(), err)
+		}
+		return i, nil
+	case int:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case float64:
+		if n != math.Trunc(n) || math.Abs(n) > maxSafeFloatInt {
+			return 0, fmt.Errorf("%s cannot be represented exactly as an integer", strconv.FormatFloat(n, 'g', -1, 64))
+		}
+		return int64(n), nil
+	default:
+		return 0, fmt.Errorf("unsupported number type %T", v)
+	}
+}
+
+// MapInt64 extracts an integer field from a decoded JSON object
+func MapInt64(m map[string]interface{}, key string) (int64, error) {
+	v, ok := m[key]
+	if !ok {
+		return 0, fmt.Errorf("missing field %q", key)
+	}
+	i, err := Int64(v)
+	if err != nil {
+		return 0, fmt.Errorf("field %q: %w", key, err)
+	}
+	return i, nil
+}
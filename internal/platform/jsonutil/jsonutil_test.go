@@ -0,0 +1,84 @@
+package jsonutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// beyondFloat is 2^53 + 1, the smallest integer a float64 cannot hold
+const beyondFloat int64 = 1<<53 + 1
+
+func TestDecodeKeepsLargeIntegers(t *testing.T) {
+	var m map[string]interface{}
+	if err := Decode(strings.NewReader(`{"points": 9007199254740993, "nested": {"amount": -9007199254740993}}`), &m); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if got, err := MapInt64(m, "points"); err != nil || got != beyondFloat {
+		t.Errorf("points = %d, %v; want %d", got, err, beyondFloat)
+	}
+	nested, _ := m["nested"].(map[string]interface{})
+	if got, err := MapInt64(nested, "amount"); err != nil || got != -beyondFloat {
+		t.Errorf("nested amount = %d, %v; want %d", got, err, -beyondFloat)
+	}
+
+	// Re-encoding the decoded map writes the numbers unchanged
+	out, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(out), "9007199254740993") || strings.Contains(string(out), "9007199254740992") {
+		t.Errorf("re-encoded as %s, want 9007199254740993 kept", out)
+	}
+}
+
+func TestDecodeStrictRejectsUnknownFields(t *testing.T) {
+	var v struct {
+		Points int64 `json:"points"`
+	}
+	if err := DecodeStrict(strings.NewReader(`{"points": 9007199254740993}`), &v); err != nil || v.Points != beyondFloat {
+		t.Fatalf("DecodeStrict = %d, %v; want %d", v.Points, err, beyondFloat)
+	}
+	if err := DecodeStrict(strings.NewReader(`{"points": 1, "pionts": 2}`), &v); err == nil {
+		t.Fatal("DecodeStrict accepted an unknown field")
+	}
+}
+
+func TestInt64(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       interface{}
+		want    int64
+		wantErr bool
+	}{
+		{"json number", json.Number("9007199254740993"), beyondFloat, false},
+		{"negative json number", json.Number("-42"), -42, false},
+		{"fractional json number", json.Number("1.5"), 0, true},
+		{"int", 42, 42, false},
+		{"int32", int32(42), 42, false},
+		{"int64", beyondFloat, beyondFloat, false},
+		{"whole float", float64(1 << 53), 1 << 53, false},
+		{"fractional float", 1.5, 0, true},
+		{"float beyond 2^53", float64(1 << 54), 0, true},
+		{"string", "42", 0, true},
+		{"nil", nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Int64(tt.v)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Int64(%v) error = %v, want error %v", tt.v, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Int64(%v) = %d, want %d", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapInt64MissingField(t *testing.T) {
+	if _, err := MapInt64(map[string]interface{}{}, "points"); err == nil || !strings.Contains(err.Error(), "points") {
+		t.Fatalf("err = %v, want a missing points field", err)
+	}
+}
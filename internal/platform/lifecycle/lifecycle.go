@@ -0,0 +1,83 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs a service's background goroutines on a shared context so that
+// shutdown can stop them and wait for them to return
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewGroup creates a group whose context is cancelled by Cancel or Shutdown
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel}
+}
+
+// Context returns the group's context
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// Go runs fn in a goroutine tracked by the group, passing it the group's
+// context. Once the group is waited on or cancelled, fn is not run and Go
+// reports false.
+func (g *Group) Go(fn func(ctx context.Context)) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return false
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn(g.ctx)
+	}()
+	return true
+}
+
+// Cancel cancels the group's context, asking its goroutines to return
+func (g *Group) Cancel() {
+	g.close()
+	g.cancel()
+}
+
+// Wait stops new goroutines from starting and waits for the running ones to
+// return. If ctx is done first it returns ctx's error, leaving them running.
+func (g *Group) Wait(ctx context.Context) error {
+	g.close()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown cancels the group and waits for its goroutines until ctx is done
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.Cancel()
+	return g.Wait(ctx)
+}
+
+func (g *Group) close() {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+}
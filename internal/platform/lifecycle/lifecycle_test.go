@@ -0,0 +1,56 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGroupWaitsForGoroutines(t *testing.T) {
+	g := NewGroup()
+	finished := make(chan struct{})
+	g.Go(func(ctx context.Context) {
+		time.Sleep(50 * time.Millisecond)
+		close(finished)
+	})
+
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Fatal("Wait returned before the goroutine finished")
+	}
+	if g.Go(func(ctx context.Context) { t.Error("goroutine ran after Wait") }) {
+		t.Fatal("Go accepted a goroutine after Wait")
+	}
+}
+
+func TestGroupWaitDeadline(t *testing.T) {
+	g := NewGroup()
+	release := make(chan struct{})
+	g.Go(func(ctx context.Context) { <-release })
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := g.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestGroupShutdownCancelsGoroutines(t *testing.T) {
+	g := NewGroup()
+	g.Go(func(ctx context.Context) { <-ctx.Done() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := g.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if g.Context().Err() == nil {
+		t.Fatal("group context not cancelled")
+	}
+}
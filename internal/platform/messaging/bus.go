@@ -0,0 +1,102 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Event bus drivers
+const (
+	DriverKafka  = "kafka"
+	DriverMemory = "memory"
+)
+
+// Producer publishes messages to topics
+type Producer interface {
+	SendMessage(ctx context.Context, topic string, key, value []byte) error
+	SendJSONMessage(ctx context.Context, topic string, key []byte, value interface{}) error
+	// SendMessageWithHeaders and SendJSONMessageWithHeaders also set headers
+	// such as event-type and schema-version on the message
+	SendMessageWithHeaders(ctx context.Context, topic string, key, value []byte, headers map[string]string) error
+	SendJSONMessageWithHeaders(ctx context.Context, topic string, key []byte, value interface{}, headers map[string]string) error
+	// Flush waits for buffered messages to be delivered, returning any
+	// delivery errors. Sends may return before delivery only in async mode.
+	Flush(ctx context.Context) error
+	// Close flushes buffered messages and releases the producer
+	Close() error
+}
+
+// Consumer reads messages from a single topic
+type Consumer interface {
+	ReadMessage(ctx context.Context) (*Message, error)
+	ConsumeMessages(ctx context.Context, handler func(*Message) error) error
+	Close() error
+}
+
+// EventBus creates producers and consumers for a messaging transport
+type EventBus interface {
+	Producer() Producer
+	Consumer(topic string) Consumer
+	// Ping reports whether the transport is reachable
+	Ping(ctx context.Context) error
+}
+
+// KafkaBus is an EventBus backed by Kafka
+type KafkaBus struct {
+	config *KafkaConfig
+	logger *logrus.Logger
+}
+
+// Producer returns a new Kafka producer
+func (b *KafkaBus) Producer() Producer {
+	return NewKafkaProducer(b.config, b.logger)
+}
+
+// Consumer returns a new Kafka consumer for the topic
+func (b *KafkaBus) Consumer(topic string) Consumer {
+	return NewKafkaConsumer(b.config, topic, b.logger)
+}
+
+// Ping dials each broker in turn, succeeding as soon as one answers
+func (b *KafkaBus) Ping(ctx context.Context) error {
+	if len(b.config.Brokers) == 0 {
+		return fmt.Errorf("no kafka brokers configured")
+	}
+
+	var dialer kafka.Dialer
+	var err error
+	for _, broker := range b.config.Brokers {
+		var conn *kafka.Conn
+		conn, err = dialer.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+	}
+	return fmt.Errorf("no kafka broker reachable: %w", err)
+}
+
+var (
+	sharedMemoryBus     *MemoryBus
+	sharedMemoryBusOnce sync.Once
+)
+
+// NewEventBus returns the event bus selected by config.Driver. The memory
+// driver returns a process-wide bus, so services started in the same
+// process exchange events without a broker.
+func NewEventBus(config *KafkaConfig, logger *logrus.Logger) (EventBus, error) {
+	switch config.Driver {
+	case "", DriverKafka:
+		return &KafkaBus{config: config, logger: logger}, nil
+	case DriverMemory:
+		sharedMemoryBusOnce.Do(func() {
+			sharedMemoryBus = NewMemoryBus(logger)
+		})
+		return sharedMemoryBus, nil
+	default:
+		return nil, fmt.Errorf("unknown event bus driver %q", config.Driver)
+	}
+}
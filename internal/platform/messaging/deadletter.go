@@ -0,0 +1,116 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/metrics"
+	"github.com/segmentio/kafka-go"
+)
+
+// DeadLetterSuffix is appended to a topic's name to form its dead-letter topic
+const DeadLetterSuffix = ".dlq"
+
+// Headers added to a message moved to a dead-letter topic, describing where
+// it came from and why it failed
+const (
+	HeaderDLQTopic     = "dlq-original-topic"
+	HeaderDLQPartition = "dlq-original-partition"
+	HeaderDLQOffset    = "dlq-original-offset"
+	HeaderDLQError     = "dlq-error"
+	HeaderDLQAttempts  = "dlq-attempts"
+	HeaderDLQFailedAt  = "dlq-failed-at"
+)
+
+// permanentError marks a handler error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as permanent: a consumer gives up on the message at
+// once instead of retrying it. Handlers return it for messages they can never
+// process, such as ones that fail to decode. Permanent(nil) is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// retryPolicy bounds the attempts a consumer makes at a message
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// handle calls handler for msg until it succeeds, returns a permanent error,
+// runs out of attempts, or ctx is done, doubling the wait between attempts.
+// It returns the number of attempts made and the last error.
+func (p retryPolicy) handle(ctx context.Context, msg *Message, handler func(*Message) error) (int, error) {
+	attempts := p.maxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := p.baseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := handleTraced(ctx, msg, handler)
+		if err == nil || IsPermanent(err) || attempt >= attempts {
+			return attempt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if p.maxDelay > 0 && delay > p.maxDelay {
+			delay = p.maxDelay
+		}
+	}
+}
+
+// deadLetterMessage builds the copy of msg published to its dead-letter
+// topic, keeping its key, value and headers and recording why it failed
+func deadLetterMessage(msg kafka.Message, attempts int, cause error) kafka.Message {
+	headers := append([]kafka.Header(nil), msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderDLQPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderDLQOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: HeaderDLQError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderDLQAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderDLQFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	return kafka.Message{
+		Topic:   msg.Topic + DeadLetterSuffix,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+		Time:    time.Now(),
+	}
+}
+
+// deadLetter publishes msg to its dead-letter topic
+func (c *KafkaConsumer) deadLetter(ctx context.Context, msg kafka.Message, attempts int, cause error) error {
+	if err := c.dlq.WriteMessages(ctx, deadLetterMessage(msg, attempts, cause)); err != nil {
+		return err
+	}
+	metrics.KafkaDeadLettered.WithLabelValues(msg.Topic).Inc()
+	return nil
+}
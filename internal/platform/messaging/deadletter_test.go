@@ -0,0 +1,252 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeReader serves queued messages to a KafkaConsumer and records the
+// offsets it commits
+type fakeReader struct {
+	kafkaReader
+
+	messages chan kafka.Message
+	commits  chan kafka.Message
+}
+
+func newFakeReader(messages ...kafka.Message) *fakeReader {
+	r := &fakeReader{
+		messages: make(chan kafka.Message, len(messages)),
+		commits:  make(chan kafka.Message, len(messages)),
+	}
+	for _, msg := range messages {
+		r.messages <- msg
+	}
+	return r
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case msg := <-r.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	for _, msg := range msgs {
+		r.commits <- msg
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+// fakeWriter records the messages written to it, or fails with err
+type fakeWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	err      error
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+func (w *fakeWriter) written() []kafka.Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]kafka.Message(nil), w.messages...)
+}
+
+func newTestConsumer(reader kafkaReader, dlq kafkaWriter, maxAttempts int) *KafkaConsumer {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return &KafkaConsumer{
+		reader: reader,
+		dlq:    dlq,
+		retry:  retryPolicy{maxAttempts: maxAttempts, baseDelay: time.Millisecond},
+		logger: logger,
+	}
+}
+
+// consume runs consumer with handler until it has committed n messages, or
+// for a short while when n is 0, returning the committed messages
+func consume(t *testing.T, consumer *KafkaConsumer, reader *fakeReader, n int, handler func(*Message) error) []kafka.Message {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumer.ConsumeMessages(ctx, handler) }()
+
+	// With nothing expected, wait long enough that a commit would show
+	limit := 5 * time.Second
+	if n == 0 {
+		limit = 50 * time.Millisecond
+	}
+	timeout := time.After(limit)
+
+	var committed []kafka.Message
+wait:
+	for n == 0 || len(committed) < n {
+		select {
+		case msg := <-reader.commits:
+			committed = append(committed, msg)
+		case <-timeout:
+			if n > 0 {
+				t.Errorf("committed %d messages, want %d", len(committed), n)
+			}
+			break wait
+		}
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("ConsumeMessages = %v, want it to stop with the context", err)
+	}
+	return committed
+}
+
+func poisonMessage() kafka.Message {
+	return kafka.Message{
+		Topic:     "loyalty.events",
+		Partition: 2,
+		Offset:    41,
+		Key:       []byte("user-1"),
+		Value:     []byte(`{"user_id":`),
+		Headers:   []kafka.Header{{Key: HeaderEventType, Value: []byte("points.earned")}},
+	}
+}
+
+func TestConsumerDeadLettersPermanentFailures(t *testing.T) {
+	reader := newFakeReader(poisonMessage())
+	dlq := &fakeWriter{}
+	consumer := newTestConsumer(reader, dlq, 5)
+
+	var calls int
+	committed := consume(t, consumer, reader, 1, func(msg *Message) error {
+		calls++
+		return Permanent(errors.New("malformed event"))
+	})
+
+	if calls != 1 {
+		t.Fatalf("handler called %d times for a permanent failure, want 1", calls)
+	}
+	if len(committed) != 1 || committed[0].Offset != 41 {
+		t.Fatalf("committed %v, want the dead-lettered offset", committed)
+	}
+
+	written := dlq.written()
+	if len(written) != 1 {
+		t.Fatalf("dead-lettered %d messages, want 1", len(written))
+	}
+	msg := written[0]
+	if msg.Topic != "loyalty.events"+DeadLetterSuffix || string(msg.Key) != "user-1" || string(msg.Value) != `{"user_id":` {
+		t.Fatalf("dead letter = %s %s %s, want the original message on loyalty.events.dlq", msg.Topic, msg.Key, msg.Value)
+	}
+	headers := newMessage(msg).Headers
+	want := map[string]string{
+		HeaderEventType:    "points.earned",
+		HeaderDLQTopic:     "loyalty.events",
+		HeaderDLQPartition: "2",
+		HeaderDLQOffset:    "41",
+		HeaderDLQError:     "malformed event",
+		HeaderDLQAttempts:  "1",
+	}
+	for key, value := range want {
+		if headers[key] != value {
+			t.Errorf("header %s = %q, want %q", key, headers[key], value)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, headers[HeaderDLQFailedAt]); err != nil {
+		t.Errorf("header %s = %q, want a timestamp", HeaderDLQFailedAt, headers[HeaderDLQFailedAt])
+	}
+}
+
+func TestConsumerRetriesBeforeDeadLettering(t *testing.T) {
+	reader := newFakeReader(poisonMessage())
+	dlq := &fakeWriter{}
+	consumer := newTestConsumer(reader, dlq, 3)
+
+	var calls int
+	consume(t, consumer, reader, 1, func(msg *Message) error {
+		calls++
+		return errors.New("downstream unavailable")
+	})
+
+	if calls != 3 {
+		t.Fatalf("handler called %d times, want 3", calls)
+	}
+	written := dlq.written()
+	if len(written) != 1 {
+		t.Fatalf("dead-lettered %d messages, want 1", len(written))
+	}
+	if attempts := newMessage(written[0]).Headers[HeaderDLQAttempts]; attempts != strconv.Itoa(3) {
+		t.Fatalf("%s = %q, want 3", HeaderDLQAttempts, attempts)
+	}
+}
+
+func TestConsumerRetrySucceeds(t *testing.T) {
+	reader := newFakeReader(poisonMessage())
+	dlq := &fakeWriter{}
+	consumer := newTestConsumer(reader, dlq, 3)
+
+	var calls int
+	consume(t, consumer, reader, 1, func(msg *Message) error {
+		if calls++; calls < 2 {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	})
+
+	if calls != 2 || len(dlq.written()) != 0 {
+		t.Fatalf("handler called %d times, %d dead-lettered; want 2 and none", calls, len(dlq.written()))
+	}
+}
+
+func TestConsumerLeavesFailuresUncommitted(t *testing.T) {
+	fail := func(msg *Message) error { return Permanent(errors.New("malformed event")) }
+
+	t.Run("dead lettering disabled", func(t *testing.T) {
+		reader := newFakeReader(poisonMessage())
+		if committed := consume(t, newTestConsumer(reader, nil, 1), reader, 0, fail); len(committed) != 0 {
+			t.Fatalf("committed %d failed messages", len(committed))
+		}
+	})
+
+	t.Run("dead letter publish fails", func(t *testing.T) {
+		reader := newFakeReader(poisonMessage())
+		dlq := &fakeWriter{err: errors.New("broker unavailable")}
+		if committed := consume(t, newTestConsumer(reader, dlq, 1), reader, 0, fail); len(committed) != 0 {
+			t.Fatalf("committed %d messages that were not dead-lettered", len(committed))
+		}
+	})
+}
+
+func TestPermanent(t *testing.T) {
+	cause := errors.New("malformed event")
+	err := Permanent(cause)
+	if !IsPermanent(err) || !errors.Is(err, cause) || err.Error() != cause.Error() {
+		t.Fatalf("Permanent(%v) = %v, want a permanent error wrapping it", cause, err)
+	}
+	if IsPermanent(cause) || Permanent(nil) != nil {
+		t.Fatal("plain errors and nil must not be permanent")
+	}
+}
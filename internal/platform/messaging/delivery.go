@@ -0,0 +1,87 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// deliveryTracker counts the messages an async producer has handed to Kafka
+// but not yet heard back about, so Flush can wait for them and report the
+// errors that async sends would otherwise hide
+type deliveryTracker struct {
+	onError func(messages []*Message, err error)
+
+	mu      sync.Mutex
+	pending int
+	// drained is closed when pending drops to zero
+	drained chan struct{}
+	errs    []error
+}
+
+func newDeliveryTracker(onError func([]*Message, error)) *deliveryTracker {
+	drained := make(chan struct{})
+	close(drained)
+	return &deliveryTracker{onError: onError, drained: drained}
+}
+
+// add records n messages about to be sent
+func (t *deliveryTracker) add(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pending == 0 {
+		t.drained = make(chan struct{})
+	}
+	t.pending += n
+}
+
+// done records that n messages left the buffer, delivered or not
+func (t *deliveryTracker) done(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending -= n
+	if t.pending <= 0 {
+		t.pending = 0
+		close(t.drained)
+	}
+}
+
+// complete is the writer's completion callback, called once per batch
+func (t *deliveryTracker) complete(messages []kafka.Message, err error) {
+	if err != nil {
+		t.mu.Lock()
+		t.errs = append(t.errs, err)
+		t.mu.Unlock()
+
+		failed := make([]*Message, len(messages))
+		for i, msg := range messages {
+			failed[i] = newMessage(msg)
+		}
+		t.onError(failed, err)
+	}
+	t.done(len(messages))
+}
+
+// flush waits until every message sent so far has been delivered or failed,
+// then returns the delivery errors since the previous flush
+func (t *deliveryTracker) flush(ctx context.Context) error {
+	t.mu.Lock()
+	drained := t.drained
+	t.mu.Unlock()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	t.mu.Lock()
+	errs := t.errs
+	t.errs = nil
+	t.mu.Unlock()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,175 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// asyncWriter buffers messages as an async *kafka.Writer does, delivering
+// them to completion after delay, or at once on Close
+type asyncWriter struct {
+	delay      time.Duration
+	err        error
+	completion func(messages []kafka.Message, err error)
+
+	mu        sync.Mutex
+	buffered  []kafka.Message
+	delivered []kafka.Message
+}
+
+func (w *asyncWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buffered) == 0 {
+		time.AfterFunc(w.delay, w.deliver)
+	}
+	w.buffered = append(w.buffered, msgs...)
+	return nil
+}
+
+func (w *asyncWriter) deliver() {
+	w.mu.Lock()
+	batch := w.buffered
+	w.buffered = nil
+	if w.err == nil {
+		w.delivered = append(w.delivered, batch...)
+	}
+	w.mu.Unlock()
+
+	if len(batch) > 0 {
+		w.completion(batch, w.err)
+	}
+}
+
+func (w *asyncWriter) Close() error {
+	w.deliver()
+	return nil
+}
+
+func (w *asyncWriter) deliveredCount() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.delivered)
+}
+
+// newAsyncProducer returns an async producer writing to w
+func newAsyncProducer(w *asyncWriter, onError func([]*Message, error)) *KafkaProducer {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	producer := &KafkaProducer{writer: w, delivery: newDeliveryTracker(onError), logger: logger}
+	w.completion = producer.delivery.complete
+	return producer
+}
+
+func sendAll(t *testing.T, producer *KafkaProducer, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := producer.SendMessage(context.Background(), "points", []byte("user-1"), []byte("earned")); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+}
+
+func TestAsyncProducerFlushDeliversBufferedMessages(t *testing.T) {
+	writer := &asyncWriter{delay: 20 * time.Millisecond}
+	producer := newAsyncProducer(writer, func([]*Message, error) { t.Error("delivery failed") })
+
+	sendAll(t, producer, 5)
+	if err := producer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if n := writer.deliveredCount(); n != 5 {
+		t.Fatalf("delivered %d messages after Flush, want 5", n)
+	}
+
+	// Nothing is pending, so a second Flush returns at once
+	if err := producer.Flush(context.Background()); err != nil {
+		t.Fatalf("second Flush: %v", err)
+	}
+}
+
+func TestAsyncProducerFlushReportsDeliveryErrors(t *testing.T) {
+	brokerErr := errors.New("leader not available")
+	writer := &asyncWriter{delay: time.Millisecond, err: brokerErr}
+	var failed []*Message
+	var mu sync.Mutex
+	producer := newAsyncProducer(writer, func(messages []*Message, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, messages...)
+	})
+
+	sendAll(t, producer, 3)
+	if err := producer.Flush(context.Background()); !errors.Is(err, brokerErr) {
+		t.Fatalf("Flush = %v, want the delivery error", err)
+	}
+	mu.Lock()
+	if len(failed) != 3 || failed[0].Topic != "points" {
+		t.Errorf("OnDeliveryError got %d messages, want the 3 sent to points", len(failed))
+	}
+	mu.Unlock()
+
+	// Errors are reported once
+	if err := producer.Flush(context.Background()); err != nil {
+		t.Fatalf("second Flush = %v, want the error already reported", err)
+	}
+}
+
+func TestAsyncProducerCloseFlushes(t *testing.T) {
+	writer := &asyncWriter{delay: time.Hour}
+	producer := newAsyncProducer(writer, func([]*Message, error) { t.Error("delivery failed") })
+
+	sendAll(t, producer, 4)
+	if err := producer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if n := writer.deliveredCount(); n != 4 {
+		t.Fatalf("delivered %d messages on Close, want 4", n)
+	}
+}
+
+func TestAsyncProducerFlushStopsWithContext(t *testing.T) {
+	writer := &asyncWriter{delay: time.Hour}
+	producer := newAsyncProducer(writer, func([]*Message, error) {})
+	defer producer.Close()
+
+	sendAll(t, producer, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := producer.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Flush = %v, want the context's error", err)
+	}
+}
+
+func TestNewKafkaProducerModes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// Synchronous by default, with nothing to flush
+	syncProducer := NewKafkaProducer(&KafkaConfig{Brokers: []string{"127.0.0.1:9092"}}, logger)
+	defer syncProducer.Close()
+	if syncProducer.delivery != nil || syncProducer.writer.(*kafka.Writer).Async {
+		t.Fatal("producer is async by default")
+	}
+	if err := syncProducer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	asyncProducer := NewKafkaProducer(&KafkaConfig{Brokers: []string{"127.0.0.1:9092"}, Async: true, BatchSize: 50, BatchTimeout: 5 * time.Millisecond}, logger)
+	defer asyncProducer.Close()
+	writer := asyncProducer.writer.(*kafka.Writer)
+	if asyncProducer.delivery == nil || !writer.Async || writer.Completion == nil {
+		t.Fatal("async producer does not track deliveries")
+	}
+	if writer.BatchSize != 50 || writer.BatchTimeout != 5*time.Millisecond {
+		t.Fatalf("batching = %d, %s; want 50, 5ms", writer.BatchSize, writer.BatchTimeout)
+	}
+}
@@ -0,0 +1,73 @@
+package messaging
+
+import (
+	"context"
+	"sort"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/httputil"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
+	"github.com/segmentio/kafka-go"
+)
+
+// Message headers producers set so consumers can route a message without
+// decoding its body
+const (
+	HeaderEventType     = "event-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderContentType   = "content-type"
+	HeaderTraceID       = "trace-id"
+	// HeaderRequestID carries the ID of the request that sent the message
+	HeaderRequestID = "request-id"
+)
+
+// ContentTypeJSON is the content-type header of messages sent with
+// SendJSONMessage
+const ContentTypeJSON = "application/json"
+
+// withJSONContentType returns headers with the JSON content type, unless the
+// caller set its own
+func withJSONContentType(headers map[string]string) map[string]string {
+	if _, ok := headers[HeaderContentType]; ok {
+		return headers
+	}
+	merged := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		merged[k] = v
+	}
+	merged[HeaderContentType] = ContentTypeJSON
+	return merged
+}
+
+// outgoingHeaders returns headers with the trace context of span and the
+// request ID in ctx added, when there are any
+func outgoingHeaders(ctx context.Context, headers map[string]string, span *tracing.Span) map[string]string {
+	out := make(map[string]string, len(headers)+3)
+	for k, v := range headers {
+		out[k] = v
+	}
+	if requestID := httputil.RequestIDFromContext(ctx); requestID != "" {
+		out[HeaderRequestID] = requestID
+	}
+	if span != nil {
+		sc := span.SpanContext()
+		out[tracing.TraceparentHeader] = tracing.FormatTraceparent(sc)
+		out[HeaderTraceID] = sc.TraceID.String()
+	}
+	return out
+}
+
+// kafkaHeaders converts headers to Kafka headers, sorted by key so the same
+// headers are always written in the same order
+func kafkaHeaders(headers map[string]string) []kafka.Header {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]kafka.Header, len(keys))
+	for i, k := range keys {
+		out[i] = kafka.Header{Key: k, Value: []byte(headers[k])}
+	}
+	return out
+}
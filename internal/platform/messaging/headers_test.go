@@ -0,0 +1,100 @@
+package messaging
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/httputil"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing/tracingtest"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+func TestKafkaHeadersRoundTrip(t *testing.T) {
+	headers := map[string]string{
+		HeaderEventType:           "redemption.completed",
+		HeaderSchemaVersion:       "1",
+		HeaderContentType:         ContentTypeJSON,
+		tracing.TraceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+
+	encoded := kafkaHeaders(headers)
+	for i := 1; i < len(encoded); i++ {
+		if encoded[i-1].Key > encoded[i].Key {
+			t.Fatalf("headers not sorted by key: %v", encoded)
+		}
+	}
+
+	msg := newMessage(kafka.Message{Topic: "redemptions", Headers: encoded})
+	if !reflect.DeepEqual(msg.Headers, headers) {
+		t.Fatalf("headers = %v, want %v", msg.Headers, headers)
+	}
+	if msg.Traceparent != headers[tracing.TraceparentHeader] {
+		t.Fatalf("traceparent = %q, want it read from the headers", msg.Traceparent)
+	}
+
+	if newMessage(kafka.Message{}).Headers != nil {
+		t.Fatal("headers set on a message without any")
+	}
+}
+
+func TestWithJSONContentType(t *testing.T) {
+	if got := withJSONContentType(nil)[HeaderContentType]; got != ContentTypeJSON {
+		t.Fatalf("content-type = %q, want %q", got, ContentTypeJSON)
+	}
+
+	// The caller's content type is kept, and their map is left alone
+	headers := map[string]string{HeaderContentType: "application/cloudevents+json"}
+	if got := withJSONContentType(headers)[HeaderContentType]; got != "application/cloudevents+json" {
+		t.Fatalf("content-type = %q, want the caller's", got)
+	}
+	headers = map[string]string{HeaderEventType: "points.earned"}
+	withJSONContentType(headers)
+	if _, ok := headers[HeaderContentType]; ok {
+		t.Fatal("caller's headers modified")
+	}
+}
+
+func TestProducerStampsTraceHeaders(t *testing.T) {
+	tracingtest.Install(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	bus := NewMemoryBus(logger)
+	consumer := bus.Consumer("redemptions")
+	defer consumer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ctx = httputil.ContextWithRequestID(ctx, "req-42")
+	ctx, span := tracing.Start(ctx, "POST /v1/redemptions", tracing.SpanKindServer)
+	defer span.End()
+
+	headers := map[string]string{HeaderEventType: "redemption.completed", HeaderSchemaVersion: "1"}
+	if err := bus.Producer().SendJSONMessageWithHeaders(ctx, "redemptions", []byte("user-1"), map[string]int{"points": 2000}, headers); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	msg, err := consumer.ReadMessage(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	for key, want := range map[string]string{
+		HeaderEventType:     "redemption.completed",
+		HeaderSchemaVersion: "1",
+		HeaderContentType:   ContentTypeJSON,
+		HeaderRequestID:     "req-42",
+		HeaderTraceID:       span.SpanContext().TraceID.String(),
+	} {
+		if msg.Headers[key] != want {
+			t.Errorf("header %s = %q, want %q", key, msg.Headers[key], want)
+		}
+	}
+	sc, ok := tracing.ParseTraceparent(msg.Traceparent)
+	if !ok || sc.TraceID != span.SpanContext().TraceID {
+		t.Errorf("traceparent = %q, want one in the sender's trace", msg.Traceparent)
+	}
+}
@@ -6,28 +6,78 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/metrics"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
 	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
 )
 
 // KafkaProducer represents a Kafka message producer
 type KafkaProducer struct {
-	writer *kafka.Writer
-	logger *logrus.Logger
+	writer kafkaWriter
+	// delivery tracks unacknowledged messages in async mode; nil when each
+	// send waits for its acknowledgement
+	delivery *deliveryTracker
+	logger   *logrus.Logger
 }
 
 // KafkaConsumer represents a Kafka message consumer
 type KafkaConsumer struct {
-	reader *kafka.Reader
+	reader kafkaReader
+	// dlq publishes messages that exhausted their attempts; nil when dead
+	// lettering is disabled
+	dlq    kafkaWriter
+	retry  retryPolicy
 	logger *logrus.Logger
 }
 
+// kafkaReader is the part of *kafka.Reader a KafkaConsumer uses, so tests can
+// substitute a fake
+type kafkaReader interface {
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	ReadLag(ctx context.Context) (int64, error)
+	Config() kafka.ReaderConfig
+	Stats() kafka.ReaderStats
+	SetOffset(offset int64) error
+	SetOffsetAt(ctx context.Context, t time.Time) error
+	Close() error
+}
+
+// kafkaWriter is the part of *kafka.Writer used to publish messages
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
 // KafkaConfig holds Kafka configuration
 type KafkaConfig struct {
+	// Driver selects the event bus implementation ("kafka" or "memory")
+	Driver   string
 	Brokers  []string
 	ClientID string
 	GroupID  string
 	Version  string
+	// MaxAttempts is how many times a consumer calls its handler for a
+	// message before giving up on it (0 or 1 tries once)
+	MaxAttempts int
+	// RetryBaseDelay is the wait before the second attempt; each retry
+	// doubles it, up to RetryMaxDelay
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// DeadLetter publishes messages that exhausted their attempts to
+	// <topic>.dlq and moves past them
+	DeadLetter bool
+	// Async makes producer sends return once a message is buffered. Buffered
+	// messages are written in batches of up to BatchSize, or after
+	// BatchTimeout; Flush waits for them. Sends are synchronous by default.
+	Async        bool
+	BatchSize    int
+	BatchTimeout time.Duration
+	// OnDeliveryError is called with each batch an async producer failed to
+	// deliver. When nil the failure is logged.
+	OnDeliveryError func(messages []*Message, err error)
 }
 
 // Message represents a Kafka message
@@ -38,8 +88,29 @@ type Message struct {
 	Partition int
 	Offset    int64
 	Timestamp time.Time
+	// Traceparent is the W3C trace context of the span that sent the message
+	Traceparent string
+	// Headers holds the message's headers, including traceparent
+	Headers map[string]string
+
+	ctx context.Context
 }
 
+// Context returns the context a consumer is handling the message in, which
+// carries its consumer span, or context.Background if it is not being handled
+func (m *Message) Context() context.Context {
+	if m.ctx != nil {
+		return m.ctx
+	}
+	return context.Background()
+}
+
+// KafkaProducer and KafkaConsumer are the Kafka-backed Producer and Consumer
+var (
+	_ Producer = (*KafkaProducer)(nil)
+	_ Consumer = (*KafkaConsumer)(nil)
+)
+
 // NewKafkaProducer creates a new Kafka producer
 func NewKafkaProducer(config *KafkaConfig, logger *logrus.Logger) *KafkaProducer {
 	writer := &kafka.Writer{
@@ -47,32 +118,82 @@ func NewKafkaProducer(config *KafkaConfig, logger *logrus.Logger) *KafkaProducer
 		Topic:        "", // Set per message
 		Balancer:     &kafka.LeastBytes{},
 		RequiredAcks: kafka.RequireOne,
-		Async:        false,
+		Async:        config.Async,
+		BatchSize:    config.BatchSize,
+		BatchTimeout: config.BatchTimeout,
 		Logger:       kafka.LoggerFunc(logger.Debugf),
 	}
 
-	return &KafkaProducer{
+	producer := &KafkaProducer{
 		writer: writer,
 		logger: logger,
 	}
+	if config.Async {
+		onError := config.OnDeliveryError
+		if onError == nil {
+			onError = func(messages []*Message, err error) {
+				logger.Errorf("Failed to deliver %d messages to topic %s: %v", len(messages), messages[0].Topic, err)
+			}
+		}
+		producer.delivery = newDeliveryTracker(onError)
+		writer.Completion = producer.delivery.complete
+	}
+
+	return producer
 }
 
-// Close closes the Kafka producer
+// Close flushes any buffered messages and closes the Kafka producer
 func (p *KafkaProducer) Close() error {
-	return p.writer.Close()
+	err := p.writer.Close()
+	if p.delivery != nil {
+		// The writer has finished every batch once Close returns
+		if flushErr := p.delivery.flush(context.Background()); err == nil {
+			err = flushErr
+		}
+	}
+	return err
 }
 
-// SendMessage sends a message to a specific topic
+// Flush waits until every buffered message has been delivered or failed, and
+// returns the delivery errors since the last Flush. Synchronous producers
+// have nothing to flush.
+func (p *KafkaProducer) Flush(ctx context.Context) error {
+	if p.delivery == nil {
+		return nil
+	}
+	return p.delivery.flush(ctx)
+}
+
+// SendMessage sends a message to a specific topic, carrying the trace
+// context of ctx in traceparent and trace-id headers
 func (p *KafkaProducer) SendMessage(ctx context.Context, topic string, key, value []byte) error {
+	return p.SendMessageWithHeaders(ctx, topic, key, value, nil)
+}
+
+// SendMessageWithHeaders sends a message with the given headers in addition
+// to the trace headers
+func (p *KafkaProducer) SendMessageWithHeaders(ctx context.Context, topic string, key, value []byte, headers map[string]string) error {
+	ctx, span := startProducerSpan(ctx, topic)
+	defer span.End()
+
 	msg := kafka.Message{
-		Topic: topic,
-		Key:   key,
-		Value: value,
-		Time:  time.Now(),
+		Topic:   topic,
+		Key:     key,
+		Value:   value,
+		Headers: kafkaHeaders(outgoingHeaders(ctx, headers, span)),
+		Time:    time.Now(),
 	}
 
+	if p.delivery != nil {
+		p.delivery.add(1)
+	}
 	err := p.writer.WriteMessages(ctx, msg)
+	if err != nil && p.delivery != nil {
+		// The message was rejected before it was buffered
+		p.delivery.done(1)
+	}
 	if err != nil {
+		span.SetError(err)
 		return fmt.Errorf("failed to send message to topic %s: %w", topic, err)
 	}
 
@@ -82,12 +203,18 @@ func (p *KafkaProducer) SendMessage(ctx context.Context, topic string, key, valu
 
 // SendJSONMessage sends a JSON message to a specific topic
 func (p *KafkaProducer) SendJSONMessage(ctx context.Context, topic string, key []byte, value interface{}) error {
+	return p.SendJSONMessageWithHeaders(ctx, topic, key, value, nil)
+}
+
+// SendJSONMessageWithHeaders sends a JSON message with the given headers and
+// a JSON content-type
+func (p *KafkaProducer) SendJSONMessageWithHeaders(ctx context.Context, topic string, key []byte, value interface{}, headers map[string]string) error {
 	jsonValue, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message value: %w", err)
 	}
 
-	return p.SendMessage(ctx, topic, key, jsonValue)
+	return p.SendMessageWithHeaders(ctx, topic, key, jsonValue, withJSONContentType(headers))
 }
 
 // NewKafkaConsumer creates a new Kafka consumer
@@ -102,15 +229,36 @@ func NewKafkaConsumer(config *KafkaConfig, topic string, logger *logrus.Logger)
 		Logger:   kafka.LoggerFunc(logger.Debugf),
 	})
 
-	return &KafkaConsumer{
+	consumer := &KafkaConsumer{
 		reader: reader,
+		retry: retryPolicy{
+			maxAttempts: config.MaxAttempts,
+			baseDelay:   config.RetryBaseDelay,
+			maxDelay:    config.RetryMaxDelay,
+		},
 		logger: logger,
 	}
+	if config.DeadLetter {
+		consumer.dlq = &kafka.Writer{
+			Addr:         kafka.TCP(config.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			Logger:       kafka.LoggerFunc(logger.Debugf),
+		}
+	}
+
+	return consumer
 }
 
 // Close closes the Kafka consumer
 func (c *KafkaConsumer) Close() error {
-	return c.reader.Close()
+	err := c.reader.Close()
+	if c.dlq != nil {
+		if dlqErr := c.dlq.Close(); err == nil {
+			err = dlqErr
+		}
+	}
+	return err
 }
 
 // ReadMessage reads a message from the topic
@@ -119,15 +267,31 @@ func (c *KafkaConsumer) ReadMessage(ctx context.Context) (*Message, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read message: %w", err)
 	}
+	metrics.RecordConsumerLag(msg.Topic, msg.Partition, msg.Offset, msg.HighWaterMark)
 
-	return &Message{
+	return newMessage(msg), nil
+}
+
+// newMessage converts a message read from Kafka
+func newMessage(msg kafka.Message) *Message {
+	message := &Message{
 		Key:       msg.Key,
 		Value:     msg.Value,
 		Topic:     msg.Topic,
 		Partition: msg.Partition,
 		Offset:    msg.Offset,
 		Timestamp: msg.Time,
-	}, nil
+	}
+	if len(msg.Headers) > 0 {
+		message.Headers = make(map[string]string, len(msg.Headers))
+	}
+	for _, header := range msg.Headers {
+		message.Headers[header.Key] = string(header.Value)
+		if header.Key == tracing.TraceparentHeader {
+			message.Traceparent = string(header.Value)
+		}
+	}
+	return message
 }
 
 // ReadMessageWithTimeout reads a message with a timeout
@@ -137,27 +301,51 @@ func (c *KafkaConsumer) ReadMessageWithTimeout(timeout time.Duration) (*Message,
 	return c.ReadMessage(ctx)
 }
 
-// ConsumeMessages consumes messages from the topic and calls the handler for each message
+// ConsumeMessages consumes messages from the topic and calls the handler for
+// each message until ctx is done. A failing message is retried up to the
+// configured MaxAttempts, or given up on at once if the handler returns a
+// Permanent error. With DeadLetter enabled, a message given up on is
+// published to <topic>.dlq and its offset committed; otherwise, or if that
+// publish fails, it is left uncommitted, so the consumer group redelivers it
+// after a restart or rebalance unless a later message on its partition is
+// committed first.
 func (c *KafkaConsumer) ConsumeMessages(ctx context.Context, handler func(*Message) error) error {
 	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			msg, err := c.ReadMessage(ctx)
-			if err != nil {
-				c.logger.Errorf("Failed to read message: %v", err)
-				continue
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
+			c.logger.Errorf("Failed to fetch message: %v", err)
+			continue
+		}
+		metrics.RecordConsumerLag(msg.Topic, msg.Partition, msg.Offset, msg.HighWaterMark)
 
-			if err := handler(msg); err != nil {
-				c.logger.Errorf("Failed to handle message: %v", err)
-				// Continue processing other messages
+		attempts, err := c.retry.handle(ctx, newMessage(msg), handler)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.logger.Errorf("Failed to handle message from topic %s at offset %d after %d attempts: %v", msg.Topic, msg.Offset, attempts, err)
+			if c.dlq == nil {
 				continue
 			}
+			if err := c.deadLetter(ctx, msg, attempts, err); err != nil {
+				c.logger.Errorf("Failed to dead-letter message from topic %s at offset %d: %v", msg.Topic, msg.Offset, err)
+				continue
+			}
+			c.logger.Warnf("Moved message from topic %s at offset %d to %s%s", msg.Topic, msg.Offset, msg.Topic, DeadLetterSuffix)
+		}
 
-			c.logger.Debugf("Message consumed from topic %s at offset %d", msg.Topic, msg.Offset)
+		if err := c.reader.CommitMessages(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.logger.Errorf("Failed to commit offset %d on topic %s: %v", msg.Offset, msg.Topic, err)
+			continue
 		}
+
+		c.logger.Debugf("Message consumed from topic %s at offset %d", msg.Topic, msg.Offset)
 	}
 }
 
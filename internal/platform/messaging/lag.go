@@ -0,0 +1,138 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/metrics"
+	"github.com/segmentio/kafka-go"
+)
+
+// LagReporter is implemented by consumers that can report how far behind
+// their topic they are
+type LagReporter interface {
+	Lag(ctx context.Context) (int64, error)
+	ReportLag(ctx context.Context, interval time.Duration)
+}
+
+var _ LagReporter = (*KafkaConsumer)(nil)
+
+// Lag returns how many messages on the consumer's topic are not yet
+// committed. In group mode this is summed over the topic's partitions from
+// the group's committed offsets, counting every retained message of a
+// partition the group has never committed on. Without a group the reader
+// tracks its own offset on a single partition, and its lag is read directly.
+func (c *KafkaConsumer) Lag(ctx context.Context) (int64, error) {
+	config := c.reader.Config()
+	if config.GroupID == "" {
+		lag, err := c.reader.ReadLag(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read lag on topic %s: %w", config.Topic, err)
+		}
+		return lag, nil
+	}
+
+	client := &kafka.Client{Addr: kafka.TCP(config.Brokers...)}
+
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{config.Topic}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read metadata for topic %s: %w", config.Topic, err)
+	}
+	if len(metadata.Topics) != 1 {
+		return 0, fmt.Errorf("no metadata for topic %s", config.Topic)
+	}
+	if err := metadata.Topics[0].Error; err != nil {
+		return 0, fmt.Errorf("failed to read metadata for topic %s: %w", config.Topic, err)
+	}
+
+	var partitions []int
+	var requests []kafka.OffsetRequest
+	for _, partition := range metadata.Topics[0].Partitions {
+		partitions = append(partitions, partition.ID)
+		requests = append(requests, kafka.FirstOffsetOf(partition.ID), kafka.LastOffsetOf(partition.ID))
+	}
+
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{config.Topic: requests},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list offsets for topic %s: %w", config.Topic, err)
+	}
+
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: config.GroupID,
+		Topics:  map[string][]int{config.Topic: partitions},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch offsets of group %s: %w", config.GroupID, err)
+	}
+	if committed.Error != nil {
+		return 0, fmt.Errorf("failed to fetch offsets of group %s: %w", config.GroupID, committed.Error)
+	}
+
+	commits := make(map[int]int64)
+	for _, partition := range committed.Topics[config.Topic] {
+		if partition.Error != nil {
+			return 0, fmt.Errorf("failed to fetch offset of group %s on partition %d: %w", config.GroupID, partition.Partition, partition.Error)
+		}
+		commits[partition.Partition] = partition.CommittedOffset
+	}
+
+	var lag int64
+	for _, partition := range offsets.Topics[config.Topic] {
+		if partition.Error != nil {
+			return 0, fmt.Errorf("failed to list offsets for partition %d: %w", partition.Partition, partition.Error)
+		}
+		committed, ok := commits[partition.Partition]
+		if !ok {
+			committed = -1
+		}
+		lag += partitionLag(partition.FirstOffset, partition.LastOffset, committed)
+	}
+	return lag, nil
+}
+
+// partitionLag returns the messages between a committed offset and the end
+// of a partition. A negative committed offset means the group has never
+// committed, so every retained message counts.
+func partitionLag(first, last, committed int64) int64 {
+	if committed < first {
+		committed = first
+	}
+	if lag := last - committed; lag > 0 {
+		return lag
+	}
+	return 0
+}
+
+// ReportLag exports the consumer's lag as a gauge and logs it at debug level
+// every interval until ctx is done
+func (c *KafkaConsumer) ReportLag(ctx context.Context, interval time.Duration) {
+	config := c.reader.Config()
+	gauge := metrics.KafkaConsumerGroupLag.WithLabelValues(config.Topic, config.GroupID)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		lagCtx, cancel := context.WithTimeout(ctx, interval)
+		lag, err := c.Lag(lagCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Warnf("Failed to compute consumer lag: %v", err)
+		} else {
+			gauge.Set(float64(lag))
+			c.logger.Debugf("Consumer group %q is %d messages behind on topic %s", config.GroupID, lag, config.Topic)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
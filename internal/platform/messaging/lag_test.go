@@ -0,0 +1,124 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// lagReader reports a fixed lag, as a reader outside a consumer group does
+type lagReader struct {
+	kafkaReader
+
+	config kafka.ReaderConfig
+	lag    int64
+	err    error
+}
+
+func (r *lagReader) Config() kafka.ReaderConfig { return r.config }
+
+func (r *lagReader) ReadLag(ctx context.Context) (int64, error) { return r.lag, r.err }
+
+func TestPartitionLag(t *testing.T) {
+	tests := []struct {
+		name                   string
+		first, last, committed int64
+		want                   int64
+	}{
+		{"caught up", 0, 100, 100, 0},
+		{"behind", 0, 100, 60, 40},
+		{"never committed", 0, 100, -1, 100},
+		{"never committed after retention", 40, 100, -1, 60},
+		{"committed offset deleted by retention", 40, 100, 10, 60},
+		{"empty partition", 0, 0, -1, 0},
+		{"committed past the end", 0, 100, 120, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := partitionLag(tt.first, tt.last, tt.committed); got != tt.want {
+				t.Fatalf("partitionLag(%d, %d, %d) = %d, want %d", tt.first, tt.last, tt.committed, got, tt.want)
+			}
+		})
+	}
+}
+
+func newLagConsumer(reader *lagReader) *KafkaConsumer {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return &KafkaConsumer{reader: reader, logger: logger}
+}
+
+func TestLagWithoutGroup(t *testing.T) {
+	consumer := newLagConsumer(&lagReader{config: kafka.ReaderConfig{Topic: "redemptions"}, lag: 17})
+	if lag, err := consumer.Lag(context.Background()); err != nil || lag != 17 {
+		t.Fatalf("Lag = %d, %v; want 17", lag, err)
+	}
+
+	brokerErr := errors.New("broker unavailable")
+	consumer = newLagConsumer(&lagReader{config: kafka.ReaderConfig{Topic: "redemptions"}, err: brokerErr})
+	_, err := consumer.Lag(context.Background())
+	if !errors.Is(err, brokerErr) || !strings.Contains(err.Error(), "redemptions") {
+		t.Fatalf("Lag err = %v, want the reader's error naming the topic", err)
+	}
+}
+
+// groupLag reads the consumer group lag gauge for topic and group
+func groupLag(t *testing.T, topic, group string) (float64, bool) {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "kafka_consumer_group_lag" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["topic"] == topic && labels["group"] == group {
+				return metric.GetGauge().GetValue(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestReportLag(t *testing.T) {
+	consumer := newLagConsumer(&lagReader{config: kafka.ReaderConfig{Topic: "lag-report-test"}, lag: 42})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.ReportLag(ctx, 10*time.Millisecond)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if lag, ok := groupLag(t, "lag-report-test", ""); ok && lag == 42 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("lag not exported")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReportLag did not stop with its context")
+	}
+}
@@ -0,0 +1,197 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// memoryBufferSize is the number of undelivered messages buffered per consumer
+const memoryBufferSize = 256
+
+// ErrConsumerClosed is returned when reading from a closed consumer
+var ErrConsumerClosed = errors.New("consumer closed")
+
+// MemoryBus is an in-process, channel-based EventBus for local development and tests.
+// Every consumer of a topic receives every message published after it subscribed.
+type MemoryBus struct {
+	logger *logrus.Logger
+
+	mu          sync.RWMutex
+	subscribers map[string][]*MemoryConsumer
+	offsets     map[string]int64
+}
+
+// Ping always succeeds; the memory bus lives in-process
+func (b *MemoryBus) Ping(ctx context.Context) error {
+	return nil
+}
+
+// MemoryConsumer reads messages published to a MemoryBus topic
+type MemoryConsumer struct {
+	bus      *MemoryBus
+	topic    string
+	messages chan *Message
+	done     chan struct{}
+	once     sync.Once
+}
+
+// memoryProducer publishes messages to a MemoryBus
+type memoryProducer struct {
+	bus *MemoryBus
+}
+
+// NewMemoryBus creates a new in-memory event bus
+func NewMemoryBus(logger *logrus.Logger) *MemoryBus {
+	return &MemoryBus{
+		logger:      logger,
+		subscribers: make(map[string][]*MemoryConsumer),
+		offsets:     make(map[string]int64),
+	}
+}
+
+// Producer returns a producer publishing to the bus
+func (b *MemoryBus) Producer() Producer {
+	return &memoryProducer{bus: b}
+}
+
+// Consumer subscribes a new consumer to the topic
+func (b *MemoryBus) Consumer(topic string) Consumer {
+	c := &MemoryConsumer{
+		bus:      b,
+		topic:    topic,
+		messages: make(chan *Message, memoryBufferSize),
+		done:     make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.subscribers[topic] = append(b.subscribers[topic], c)
+	b.mu.Unlock()
+
+	return c
+}
+
+// publish delivers a message to every consumer subscribed to its topic,
+// blocking while a consumer's buffer is full
+func (b *MemoryBus) publish(ctx context.Context, msg Message) error {
+	b.mu.Lock()
+	msg.Offset = b.offsets[msg.Topic]
+	b.offsets[msg.Topic]++
+	subscribers := append([]*MemoryConsumer(nil), b.subscribers[msg.Topic]...)
+	b.mu.Unlock()
+
+	if len(subscribers) == 0 {
+		b.logger.Debugf("No consumers for topic %s, dropping message", msg.Topic)
+		return nil
+	}
+
+	for _, c := range subscribers {
+		m := msg
+		select {
+		case c.messages <- &m:
+		case <-c.done:
+		case <-ctx.Done():
+			return fmt.Errorf("failed to send message to topic %s: %w", msg.Topic, ctx.Err())
+		}
+	}
+
+	return nil
+}
+
+func (b *MemoryBus) unsubscribe(c *MemoryConsumer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscribers := b.subscribers[c.topic]
+	for i, s := range subscribers {
+		if s == c {
+			b.subscribers[c.topic] = append(subscribers[:i:i], subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// SendMessage sends a message to a specific topic
+func (p *memoryProducer) SendMessage(ctx context.Context, topic string, key, value []byte) error {
+	return p.SendMessageWithHeaders(ctx, topic, key, value, nil)
+}
+
+// SendMessageWithHeaders sends a message with the given headers in addition
+// to the trace headers
+func (p *memoryProducer) SendMessageWithHeaders(ctx context.Context, topic string, key, value []byte, headers map[string]string) error {
+	ctx, span := startProducerSpan(ctx, topic)
+	defer span.End()
+
+	msg := Message{Key: key, Value: value, Topic: topic, Timestamp: time.Now(), Headers: outgoingHeaders(ctx, headers, span)}
+	msg.Traceparent = msg.Headers[tracing.TraceparentHeader]
+	err := p.bus.publish(ctx, msg)
+	span.SetError(err)
+	return err
+}
+
+// SendJSONMessage sends a JSON message to a specific topic
+func (p *memoryProducer) SendJSONMessage(ctx context.Context, topic string, key []byte, value interface{}) error {
+	return p.SendJSONMessageWithHeaders(ctx, topic, key, value, nil)
+}
+
+// SendJSONMessageWithHeaders sends a JSON message with the given headers and
+// a JSON content-type
+func (p *memoryProducer) SendJSONMessageWithHeaders(ctx context.Context, topic string, key []byte, value interface{}, headers map[string]string) error {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message value: %w", err)
+	}
+
+	return p.SendMessageWithHeaders(ctx, topic, key, jsonValue, withJSONContentType(headers))
+}
+
+// Flush is a no-op; messages are delivered before a send returns
+func (p *memoryProducer) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op; the bus outlives its producers
+func (p *memoryProducer) Close() error {
+	return nil
+}
+
+// ReadMessage blocks until a message is available, ctx is done, or the consumer is closed
+func (c *MemoryConsumer) ReadMessage(ctx context.Context) (*Message, error) {
+	select {
+	case msg := <-c.messages:
+		return msg, nil
+	case <-c.done:
+		return nil, ErrConsumerClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ConsumeMessages consumes messages and calls the handler for each message until ctx is done
+func (c *MemoryConsumer) ConsumeMessages(ctx context.Context, handler func(*Message) error) error {
+	for {
+		msg, err := c.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := handleTraced(ctx, msg, handler); err != nil {
+			c.bus.logger.Errorf("Failed to handle message: %v", err)
+		}
+	}
+}
+
+// Close unsubscribes the consumer from the bus
+func (c *MemoryConsumer) Close() error {
+	c.once.Do(func() {
+		c.bus.unsubscribe(c)
+		close(c.done)
+	})
+	return nil
+}
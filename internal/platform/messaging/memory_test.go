@@ -0,0 +1,69 @@
+package messaging_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMemoryBusDeliversToEverySubscriber(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	bus := messaging.NewMemoryBus(logger)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Messages sent before a consumer subscribes are not delivered to it
+	if err := bus.Producer().SendMessage(ctx, "events", []byte("k"), []byte("dropped")); err != nil {
+		t.Fatalf("send without consumers: %v", err)
+	}
+
+	first, second := bus.Consumer("events"), bus.Consumer("events")
+	other := bus.Consumer("other-events")
+	defer other.Close()
+	for i, value := range []string{"one", "two"} {
+		if err := bus.Producer().SendMessage(ctx, "events", []byte("k"), []byte(value)); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+
+	for _, consumer := range []messaging.Consumer{first, second} {
+		for i, want := range []string{"one", "two"} {
+			msg, err := consumer.ReadMessage(ctx)
+			if err != nil {
+				t.Fatalf("read %d: %v", i, err)
+			}
+			if string(msg.Value) != want || msg.Topic != "events" {
+				t.Fatalf("read %d = %s on %s, want %s on events", i, msg.Value, msg.Topic, want)
+			}
+			// Offsets count messages on the topic, including the dropped one
+			if msg.Offset != int64(i+1) {
+				t.Errorf("read %d: offset = %d, want %d", i, msg.Offset, i+1)
+			}
+		}
+	}
+
+	short, cancelShort := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelShort()
+	if _, err := other.ReadMessage(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("other topic read: err = %v, want no message", err)
+	}
+
+	// A closed consumer stops reading and no longer holds up sends
+	first.Close()
+	if _, err := first.ReadMessage(ctx); !errors.Is(err, messaging.ErrConsumerClosed) {
+		t.Fatalf("read after close: err = %v, want %v", err, messaging.ErrConsumerClosed)
+	}
+	if err := bus.Producer().SendMessage(ctx, "events", []byte("k"), []byte("three")); err != nil {
+		t.Fatalf("send after close: %v", err)
+	}
+	if msg, err := second.ReadMessage(ctx); err != nil || string(msg.Value) != "three" {
+		t.Fatalf("read after other consumer closed = %v, %v; want three", msg, err)
+	}
+	second.Close()
+}
@@ -0,0 +1,145 @@
+// Package messagingtest provides in-memory messaging fakes for tests
+package messagingtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+)
+
+var _ messaging.Producer = (*FakeProducer)(nil)
+
+// FakeProducer is a messaging.Producer that records every message it is
+// asked to send instead of publishing it
+type FakeProducer struct {
+	mu       sync.Mutex
+	messages []messaging.Message
+	err      error
+	closed   bool
+}
+
+// NewFakeProducer creates an empty fake producer
+func NewFakeProducer() *FakeProducer {
+	return &FakeProducer{}
+}
+
+// SendMessage records the message, or returns the error set with FailWith
+func (p *FakeProducer) SendMessage(ctx context.Context, topic string, key, value []byte) error {
+	return p.SendMessageWithHeaders(ctx, topic, key, value, nil)
+}
+
+// SendMessageWithHeaders records the message with a copy of its headers, or
+// returns the error set with FailWith
+func (p *FakeProducer) SendMessageWithHeaders(ctx context.Context, topic string, key, value []byte, headers map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	if p.closed {
+		return fmt.Errorf("producer closed")
+	}
+
+	p.messages = append(p.messages, messaging.Message{
+		Key:       append([]byte(nil), key...),
+		Value:     append([]byte(nil), value...),
+		Topic:     topic,
+		Offset:    int64(len(p.messages)),
+		Timestamp: time.Now(),
+		Headers:   copyHeaders(headers),
+	})
+	return nil
+}
+
+// SendJSONMessage encodes value as JSON, as KafkaProducer does, and records it
+func (p *FakeProducer) SendJSONMessage(ctx context.Context, topic string, key []byte, value interface{}) error {
+	return p.SendJSONMessageWithHeaders(ctx, topic, key, value, nil)
+}
+
+// SendJSONMessageWithHeaders encodes value as JSON and records it with the
+// headers and, as KafkaProducer does, a JSON content-type unless one is set
+func (p *FakeProducer) SendJSONMessageWithHeaders(ctx context.Context, topic string, key []byte, value interface{}, headers map[string]string) error {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message value: %w", err)
+	}
+
+	headers = copyHeaders(headers)
+	if headers == nil {
+		headers = make(map[string]string, 1)
+	}
+	if _, ok := headers[messaging.HeaderContentType]; !ok {
+		headers[messaging.HeaderContentType] = messaging.ContentTypeJSON
+	}
+	return p.SendMessageWithHeaders(ctx, topic, key, jsonValue, headers)
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		out[k] = v
+	}
+	return out
+}
+
+// Flush is a no-op; messages are recorded as they are sent
+func (p *FakeProducer) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close marks the producer closed; later sends fail
+func (p *FakeProducer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	return nil
+}
+
+// FailWith makes every later send return err, or succeed again if err is nil
+func (p *FakeProducer) FailWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.err = err
+}
+
+// Messages returns the recorded messages in the order they were sent
+func (p *FakeProducer) Messages() []messaging.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]messaging.Message(nil), p.messages...)
+}
+
+// MessagesFor returns the recorded messages sent to a topic
+func (p *FakeProducer) MessagesFor(topic string) []messaging.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var messages []messaging.Message
+	for _, msg := range p.messages {
+		if msg.Topic == topic {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// Reset forgets recorded messages and any configured error
+func (p *FakeProducer) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messages = nil
+	p.err = nil
+	p.closed = false
+}
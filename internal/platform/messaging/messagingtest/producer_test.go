@@ -0,0 +1,78 @@
+package messagingtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+)
+
+// Services depend on messaging.Producer, so the real producer must satisfy
+// it as well as the fake
+var _ messaging.Producer = (*messaging.KafkaProducer)(nil)
+
+func TestFakeProducerRecordsMessages(t *testing.T) {
+	producer := NewFakeProducer()
+	ctx := context.Background()
+
+	headers := map[string]string{messaging.HeaderContentType: "text/plain"}
+	if err := producer.SendMessageWithHeaders(ctx, "points", []byte("user-1"), []byte("earned"), headers); err != nil {
+		t.Fatalf("SendMessageWithHeaders: %v", err)
+	}
+	headers[messaging.HeaderContentType] = "changed after sending"
+	if err := producer.SendJSONMessage(ctx, "redemptions", []byte("user-2"), map[string]int{"points": 2000}); err != nil {
+		t.Fatalf("SendJSONMessage: %v", err)
+	}
+	if err := producer.SendMessage(ctx, "points", []byte("user-3"), []byte("spent")); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	messages := producer.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("recorded %d messages, want 3", len(messages))
+	}
+	for i, msg := range messages {
+		if msg.Offset != int64(i) {
+			t.Errorf("message %d offset = %d", i, msg.Offset)
+		}
+	}
+
+	points := producer.MessagesFor("points")
+	if len(points) != 2 || string(points[0].Value) != "earned" || string(points[1].Value) != "spent" {
+		t.Fatalf("points messages = %+v, want earned then spent", points)
+	}
+	if got := points[0].Headers[messaging.HeaderContentType]; got != "text/plain" {
+		t.Errorf("recorded header = %q, want the value at send time", got)
+	}
+
+	redemption := producer.MessagesFor("redemptions")[0]
+	if string(redemption.Key) != "user-2" || string(redemption.Value) != `{"points":2000}` {
+		t.Errorf("JSON message = %s: %s", redemption.Key, redemption.Value)
+	}
+	if got := redemption.Headers[messaging.HeaderContentType]; got != messaging.ContentTypeJSON {
+		t.Errorf("JSON content type = %q, want %q", got, messaging.ContentTypeJSON)
+	}
+	if len(producer.MessagesFor("unknown")) != 0 {
+		t.Error("messages recorded for an unused topic")
+	}
+}
+
+func TestFakeProducerCloseAndReset(t *testing.T) {
+	producer := NewFakeProducer()
+	ctx := context.Background()
+
+	if err := producer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := producer.SendMessage(ctx, "points", nil, []byte("late")); err == nil {
+		t.Fatal("send after Close succeeded")
+	}
+
+	producer.Reset()
+	if err := producer.SendMessage(ctx, "points", nil, []byte("again")); err != nil {
+		t.Fatalf("send after Reset: %v", err)
+	}
+	if messages := producer.Messages(); len(messages) != 1 || string(messages[0].Value) != "again" {
+		t.Fatalf("messages = %+v, want only the message sent after Reset", messages)
+	}
+}
@@ -0,0 +1,98 @@
+package messaging_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging/messagingtest"
+	"github.com/sirupsen/logrus"
+)
+
+type testEvent struct {
+	UserID string `json:"user_id"`
+	Points int    `json:"points"`
+}
+
+// sendAndReceive sends event as JSON with producer and returns the message
+// it produced, as recorded by the fake or read from the memory bus
+func sendAndReceive(t *testing.T, driver string) messaging.Message {
+	t.Helper()
+	ctx := context.Background()
+	event := testEvent{UserID: "user-1", Points: 250}
+	headers := map[string]string{messaging.HeaderEventType: "test.event"}
+
+	if driver == "fake" {
+		producer := messagingtest.NewFakeProducer()
+		if err := producer.SendJSONMessageWithHeaders(ctx, "test-topic", []byte(event.UserID), event, headers); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		messages := producer.MessagesFor("test-topic")
+		if len(messages) != 1 {
+			t.Fatalf("recorded %d messages, want 1", len(messages))
+		}
+		return messages[0]
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	bus := messaging.NewMemoryBus(logger)
+	consumer := bus.Consumer("test-topic")
+	defer consumer.Close()
+
+	if err := bus.Producer().SendJSONMessageWithHeaders(ctx, "test-topic", []byte(event.UserID), event, headers); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	msg, err := consumer.ReadMessage(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return *msg
+}
+
+// The fake producer must encode messages as the real producers do, so tests
+// using it assert what consumers will receive
+func TestSendJSONMessageWithHeaders(t *testing.T) {
+	for _, driver := range []string{"fake", messaging.DriverMemory} {
+		t.Run(driver, func(t *testing.T) {
+			msg := sendAndReceive(t, driver)
+
+			if msg.Topic != "test-topic" {
+				t.Errorf("topic = %q, want test-topic", msg.Topic)
+			}
+			if string(msg.Key) != "user-1" {
+				t.Errorf("key = %q, want user-1", msg.Key)
+			}
+			var got testEvent
+			if err := json.Unmarshal(msg.Value, &got); err != nil {
+				t.Fatalf("payload is not JSON: %v", err)
+			}
+			if got != (testEvent{UserID: "user-1", Points: 250}) {
+				t.Errorf("payload = %+v", got)
+			}
+			if msg.Headers[messaging.HeaderContentType] != messaging.ContentTypeJSON {
+				t.Errorf("content-type = %q, want %q", msg.Headers[messaging.HeaderContentType], messaging.ContentTypeJSON)
+			}
+			if msg.Headers[messaging.HeaderEventType] != "test.event" {
+				t.Errorf("event-type = %q, want test.event", msg.Headers[messaging.HeaderEventType])
+			}
+		})
+	}
+}
+
+func TestFakeProducerFailWith(t *testing.T) {
+	producer := messagingtest.NewFakeProducer()
+	producer.FailWith(io.ErrClosedPipe)
+
+	if err := producer.SendJSONMessage(context.Background(), "test-topic", nil, testEvent{}); err != io.ErrClosedPipe {
+		t.Fatalf("err = %v, want %v", err, io.ErrClosedPipe)
+	}
+	if n := len(producer.Messages()); n != 0 {
+		t.Fatalf("recorded %d failed messages", n)
+	}
+}
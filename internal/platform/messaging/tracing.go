@@ -0,0 +1,37 @@
+package messaging
+
+import (
+	"context"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/httputil"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
+)
+
+// startProducerSpan starts the span covering a send to topic
+func startProducerSpan(ctx context.Context, topic string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, topic+" publish", tracing.SpanKindProducer)
+	span.SetAttribute("messaging.destination.name", topic)
+	return ctx, span
+}
+
+// handleTraced runs handler for msg inside a consumer span that continues the
+// trace the message was sent in. The span's context, derived from ctx and
+// carrying the sender's request ID, is available to the handler from
+// msg.Context.
+func handleTraced(ctx context.Context, msg *Message, handler func(*Message) error) error {
+	if sc, ok := tracing.ParseTraceparent(msg.Traceparent); ok {
+		ctx = tracing.ContextWithRemoteParent(ctx, sc)
+	}
+	ctx = httputil.ContextWithRequestID(ctx, msg.Headers[HeaderRequestID])
+
+	ctx, span := tracing.Start(ctx, msg.Topic+" process", tracing.SpanKindConsumer)
+	msg.ctx = ctx
+	defer span.End()
+	span.SetAttribute("messaging.destination.name", msg.Topic)
+	span.SetAttribute("messaging.kafka.partition", msg.Partition)
+	span.SetAttribute("messaging.kafka.offset", msg.Offset)
+
+	err := handler(msg)
+	span.SetError(err)
+	return err
+}
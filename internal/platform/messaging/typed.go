@@ -0,0 +1,23 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/jsonutil"
+)
+
+// ConsumeTyped consumes messages from consumer until ctx is done, decoding
+// each message's JSON value into a T and passing it to handler with the
+// message's context. A message that does not decode never reaches handler;
+// it fails as a Permanent error, so the consumer moves it to the dead-letter
+// topic without retrying it.
+func ConsumeTyped[T any](ctx context.Context, consumer Consumer, handler func(ctx context.Context, event T) error) error {
+	return consumer.ConsumeMessages(ctx, func(msg *Message) error {
+		var event T
+		if err := jsonutil.Unmarshal(msg.Value, &event); err != nil {
+			return Permanent(fmt.Errorf("failed to decode message from topic %s at offset %d: %w", msg.Topic, msg.Offset, err))
+		}
+		return handler(msg.Context(), event)
+	})
+}
@@ -0,0 +1,71 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/httputil"
+	"github.com/segmentio/kafka-go"
+)
+
+type pointsEvent struct {
+	UserID string `json:"user_id"`
+	Points int    `json:"points"`
+}
+
+func TestConsumeTyped(t *testing.T) {
+	messages := []kafka.Message{
+		{Topic: "points", Offset: 0, Value: []byte(`{"user_id":"user-1","points":100}`)},
+		{Topic: "points", Offset: 1, Value: []byte(`{"user_id":`)},
+		{Topic: "points", Offset: 2, Value: []byte(`{"user_id":"user-2","points":"many"}`)},
+		{Topic: "points", Offset: 3, Value: []byte(`{"user_id":"user-3","points":300}`),
+			Headers: []kafka.Header{{Key: HeaderRequestID, Value: []byte("req-42")}}},
+	}
+	reader := newFakeReader(messages...)
+	dlq := &fakeWriter{}
+	consumer := newTestConsumer(reader, dlq, 3)
+
+	var mu sync.Mutex
+	var handled []pointsEvent
+	var requestIDs []string
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ConsumeTyped(ctx, consumer, func(ctx context.Context, event pointsEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled = append(handled, event)
+			requestIDs = append(requestIDs, httputil.RequestIDFromContext(ctx))
+			return nil
+		})
+	}()
+
+	// Malformed messages are committed too, once dead-lettered, so the loop
+	// carries on past them
+	for range messages {
+		<-reader.commits
+	}
+	cancel()
+	<-done
+
+	want := []pointsEvent{{UserID: "user-1", Points: 100}, {UserID: "user-3", Points: 300}}
+	if len(handled) != len(want) || handled[0] != want[0] || handled[1] != want[1] {
+		t.Fatalf("handled %+v, want %+v", handled, want)
+	}
+	if requestIDs[1] != "req-42" {
+		t.Errorf("handler context request ID = %q, want the message's", requestIDs[1])
+	}
+
+	// Decode failures are permanent, so each is dead-lettered after one attempt
+	written := dlq.written()
+	if len(written) != 2 {
+		t.Fatalf("dead-lettered %d messages, want the 2 malformed ones", len(written))
+	}
+	for i, offset := range []string{"1", "2"} {
+		headers := newMessage(written[i]).Headers
+		if headers[HeaderDLQOffset] != offset || headers[HeaderDLQAttempts] != "1" {
+			t.Errorf("dead letter %d: offset %s after %s attempts, want offset %s after 1", i, headers[HeaderDLQOffset], headers[HeaderDLQAttempts], offset)
+		}
+	}
+}
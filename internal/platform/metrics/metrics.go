@@ -0,0 +1,106 @@
+// Package metrics defines the Prometheus metrics shared across services.
+// They are registered with the default registry, which the HTTP server
+// exposes on /metrics.
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// HTTP request metrics, recorded by the server's metrics middleware. Routes
+// are chi route patterns, such as /v1/benefits/{id}, so IDs do not create
+// new series.
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route, and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route, and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Points moved by the loyalty service. Replayed idempotent requests are not
+// counted again.
+var (
+	PointsEarned = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "loyalty_points_earned_total",
+		Help: "Loyalty points earned or credited.",
+	})
+
+	PointsSpent = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "loyalty_points_spent_total",
+		Help: "Loyalty points spent or deducted.",
+	})
+)
+
+// RedemptionOutcomes counts finished redemption sagas by their final status
+var RedemptionOutcomes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "redemption_saga_outcomes_total",
+	Help: "Finished redemption sagas by outcome.",
+}, []string{"outcome"})
+
+// Redemption outcome counters
+var (
+	RedemptionCompleted   = RedemptionOutcomes.WithLabelValues("completed")
+	RedemptionFailed      = RedemptionOutcomes.WithLabelValues("failed")
+	RedemptionTimedOut    = RedemptionOutcomes.WithLabelValues("timed_out")
+	RedemptionInterrupted = RedemptionOutcomes.WithLabelValues("interrupted")
+)
+
+// KafkaConsumerLag is how many messages a consumer is behind the end of
+// each partition, as of the last message it fetched
+var KafkaConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "kafka_consumer_lag",
+	Help: "Messages between the last fetched offset and the partition's high watermark.",
+}, []string{"topic", "partition"})
+
+// RecordConsumerLag sets the lag of a partition from a fetched message's
+// offset and the partition's high watermark
+func RecordConsumerLag(topic string, partition int, offset, highWaterMark int64) {
+	lag := highWaterMark - offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	KafkaConsumerLag.WithLabelValues(topic, strconv.Itoa(partition)).Set(float64(lag))
+}
+
+// KafkaConsumerGroupLag is how many messages a consumer has yet to commit
+// across all partitions of its topic, as of its last lag report. Consumers
+// not in a group report with an empty group label.
+var KafkaConsumerGroupLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "kafka_consumer_group_lag",
+	Help: "Messages between the committed offsets and the end of the topic, summed over partitions.",
+}, []string{"topic", "group"})
+
+// KafkaDeadLettered counts messages moved to a dead-letter topic after
+// their handler gave up on them
+var KafkaDeadLettered = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kafka_dead_lettered_total",
+	Help: "Messages published to a dead-letter topic, by original topic.",
+}, []string{"topic"})
+
+// Postgres connection pool statistics, exported periodically by each
+// service's pool for capacity planning
+var (
+	DBPoolConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_pool_connections",
+		Help: "Connections in the Postgres pool by state (acquired, idle, total).",
+	}, []string{"database", "state"})
+
+	DBPoolAcquireWaits = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_pool_acquire_waits",
+		Help: "Acquires that had to wait for a connection since the pool was created.",
+	}, []string{"database"})
+
+	DBPoolAcquireWaitSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_pool_acquire_wait_seconds",
+		Help: "Total time spent waiting for a connection since the pool was created.",
+	}, []string{"database"})
+)
@@ -0,0 +1,192 @@
+// Package tracing records distributed traces and exports them to an
+// OpenTelemetry collector over OTLP/HTTP. Trace context crosses services in
+// the W3C traceparent header, on HTTP requests and Kafka messages alike.
+//
+// Tracing is off until Setup installs a tracer; until then Start returns a
+// nil span, whose methods do nothing, so instrumented code needs no checks.
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// queueSize bounds the spans waiting for export; spans beyond it are dropped
+	queueSize = 2048
+	// batchSize is the most spans sent in one export
+	batchSize = 512
+	// flushInterval is how often queued spans are exported
+	flushInterval = 5 * time.Second
+	// exportTimeout bounds one export request
+	exportTimeout = 10 * time.Second
+)
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []*Span) error
+}
+
+// Tracer creates spans and exports them in batches in the background
+type Tracer struct {
+	exporter Exporter
+	logger   *logrus.Logger
+	queue    chan *Span
+	flush    chan chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+	dropped  atomic.Int64
+}
+
+var global atomic.Pointer[Tracer]
+
+// Setup installs the process-wide tracer when cfg.Enabled, exporting to
+// cfg.OTLPEndpoint. It returns nil when tracing is disabled; Shutdown on the
+// nil tracer does nothing.
+func Setup(cfg config.OTelConfig, serviceName string, logger *logrus.Logger) *Tracer {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.ServiceName != "" {
+		serviceName = cfg.ServiceName
+	}
+
+	tracer := NewTracer(NewOTLPExporter(cfg.OTLPEndpoint, serviceName), logger)
+	global.Store(tracer)
+	logger.Infof("Tracing enabled, exporting to %s", cfg.OTLPEndpoint)
+	return tracer
+}
+
+// NewTracer returns a tracer exporting through exporter. Use Setup to make
+// it the process-wide tracer.
+func NewTracer(exporter Exporter, logger *logrus.Logger) *Tracer {
+	t := &Tracer{
+		exporter: exporter,
+		logger:   logger,
+		queue:    make(chan *Span, queueSize),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// SetGlobal installs t as the process-wide tracer used by Start
+func SetGlobal(t *Tracer) {
+	global.Store(t)
+}
+
+// Start begins a span as a child of the current span in ctx, or of a remote
+// parent extracted into ctx, and returns ctx carrying the new span. It
+// returns ctx unchanged and a nil span while tracing is disabled.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	return global.Load().Start(ctx, name, kind)
+}
+
+// Start is the package-level Start using this tracer
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+	}
+	if parent, ok := parentFromContext(ctx); ok && parent.IsValid() {
+		span.context = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: parent.Sampled}
+		span.parent = parent.SpanID
+	} else {
+		span.context = SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// Shutdown exports the spans still queued and stops the tracer. Spans ended
+// afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	global.CompareAndSwap(t, nil)
+
+	flushed := make(chan struct{})
+	select {
+	case t.flush <- flushed:
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	t.stopOnce.Do(func() { close(t.done) })
+
+	if dropped := t.dropped.Load(); dropped > 0 {
+		t.logger.Warnf("Dropped %d spans because the export queue was full", dropped)
+	}
+	return nil
+}
+
+func (t *Tracer) enqueue(span *Span) {
+	select {
+	case <-t.done:
+	case t.queue <- span:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+		if err := t.exporter.ExportSpans(ctx, batch); err != nil {
+			t.logger.Warnf("Failed to export %d spans: %v", len(batch), err)
+		}
+		batch = make([]*Span, 0, batchSize)
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case flushed := <-t.flush:
+			// Drain what was queued before Shutdown was called
+			for len(t.queue) > 0 {
+				batch = append(batch, <-t.queue)
+				if len(batch) >= batchSize {
+					export()
+				}
+			}
+			export()
+			close(flushed)
+			return
+		case <-t.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// recordingExporter keeps exported spans in memory
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (e *recordingExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func newTestTracer() (*Tracer, *recordingExporter) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	exporter := &recordingExporter{}
+	return NewTracer(exporter, logger), exporter
+}
+
+func TestSpansJoinTheirParentsTrace(t *testing.T) {
+	tracer, exporter := newTestTracer()
+
+	ctx, root := tracer.Start(context.Background(), "saga", SpanKindInternal)
+	_, child := tracer.Start(ctx, "deduct points", SpanKindClient)
+	child.SetAttribute("points", 500)
+	child.End()
+	root.End()
+
+	// A remote parent from a traceparent continues the caller's trace
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, continued := tracer.Start(ContextWithRemoteParent(context.Background(), remote), "consume", SpanKindConsumer)
+	continued.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if len(exporter.spans) != 3 {
+		t.Fatalf("exported %d spans, want 3", len(exporter.spans))
+	}
+	if child.SpanContext().TraceID != root.SpanContext().TraceID || child.Parent() != root.SpanContext().SpanID {
+		t.Fatal("child span is not part of its parent's trace")
+	}
+	if root.Parent().IsValid() {
+		t.Fatal("root span has a parent")
+	}
+	if child.Attribute("points") != 500 || child.Name() != "deduct points" {
+		t.Fatalf("child = %s %v, want its name and attributes kept", child.Name(), child.Attribute("points"))
+	}
+	if continued.SpanContext().TraceID != remote.TraceID || continued.Parent() != remote.SpanID {
+		t.Fatal("span did not continue the remote trace")
+	}
+}
+
+func TestUnsampledSpansAreNotExported(t *testing.T) {
+	tracer, exporter := newTestTracer()
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	_, span := tracer.Start(ContextWithRemoteParent(context.Background(), remote), "ignored", SpanKindServer)
+	span.End()
+	tracer.Shutdown(context.Background())
+
+	if len(exporter.spans) != 0 {
+		t.Fatalf("exported %d unsampled spans", len(exporter.spans))
+	}
+}
+
+func TestDisabledTracingReturnsNilSpans(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "request", SpanKindServer)
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatal("disabled tracer started a span")
+	}
+	// Every span method is safe on the nil span
+	span.SetAttribute("key", "value")
+	span.SetError(io.EOF)
+	span.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	for _, value := range []string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+	} {
+		sc, ok := ParseTraceparent(value)
+		if !ok || FormatTraceparent(sc) != value {
+			t.Errorf("round trip of %s = %s, %v", value, FormatTraceparent(sc), ok)
+		}
+	}
+	for _, value := range []string{
+		"", "malformed", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	} {
+		if _, ok := ParseTraceparent(value); ok {
+			t.Errorf("ParseTraceparent(%q) accepted an invalid value", value)
+		}
+	}
+}
+
+func TestTransportPropagatesTrace(t *testing.T) {
+	tracer, exporter := newTestTracer()
+	SetGlobal(tracer)
+	defer tracer.Shutdown(context.Background())
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(TraceparentHeader)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	ctx, parent := Start(context.Background(), "saga", SpanKindInternal)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/fulfill", nil)
+	resp, err := (&http.Client{Transport: &Transport{}}).Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if req.Header.Get(TraceparentHeader) != "" {
+		t.Fatal("Transport modified the caller's request")
+	}
+	tracer.Shutdown(context.Background())
+
+	if len(exporter.spans) != 1 {
+		t.Fatalf("exported %d spans, want the client span", len(exporter.spans))
+	}
+	client := exporter.spans[0]
+	sc, ok := ParseTraceparent(traceparent)
+	if !ok || sc.TraceID != parent.SpanContext().TraceID || sc.SpanID != client.SpanContext().SpanID {
+		t.Fatalf("traceparent = %q, want the client span in the caller's trace", traceparent)
+	}
+	if client.Attribute("http.status_code") != http.StatusBadGateway || !client.failed {
+		t.Fatal("client span did not record the failed status")
+	}
+}
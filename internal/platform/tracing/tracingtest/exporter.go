@@ -0,0 +1,55 @@
+// Package tracingtest provides an in-memory span exporter for tests
+package tracingtest
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+var _ tracing.Exporter = (*InMemoryExporter)(nil)
+
+// InMemoryExporter records exported spans instead of sending them
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*tracing.Span
+}
+
+// ExportSpans records spans
+func (e *InMemoryExporter) ExportSpans(ctx context.Context, spans []*tracing.Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// Spans returns the spans exported so far, in the order they ended
+func (e *InMemoryExporter) Spans() []*tracing.Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*tracing.Span(nil), e.spans...)
+}
+
+// Install makes a tracer exporting to a new InMemoryExporter the process-wide
+// tracer for the rest of the test. Call Flush to export the ended spans.
+func Install(t *testing.T) (*InMemoryExporter, func()) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	exporter := &InMemoryExporter{}
+	tracer := tracing.NewTracer(exporter, logger)
+	tracing.SetGlobal(tracer)
+	t.Cleanup(func() { tracer.Shutdown(context.Background()) })
+
+	flush := func() {
+		if err := tracer.Shutdown(context.Background()); err != nil {
+			t.Fatalf("failed to flush spans: %v", err)
+		}
+	}
+	return exporter, flush
+}
@@ -0,0 +1,101 @@
+package redemption
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// UnavailableReason is a machine-readable reason a benefit cannot be redeemed
+type UnavailableReason string
+
+const (
+	ReasonInactive           UnavailableReason = "inactive"
+	ReasonOutOfWindow        UnavailableReason = "out_of_window"
+	ReasonPartnerUnavailable UnavailableReason = "partner_unavailable"
+	ReasonWrongPoints        UnavailableReason = "wrong_points"
+)
+
+// AvailabilityError explains why a benefit cannot be redeemed
+type AvailabilityError struct {
+	Reason UnavailableReason `json:"reason"`
+	Detail string            `json:"detail"`
+}
+
+func (e *AvailabilityError) Error() string {
+	return fmt.Sprintf("benefit not available (%s): %s", e.Reason, e.Detail)
+}
+
+// RedemptionEstimate describes whether a redemption request could proceed
+type RedemptionEstimate struct {
+	BenefitID   string            `json:"benefit_id"`
+	BenefitName string            `json:"benefit_name"`
+	BenefitType BenefitType       `json:"benefit_type"`
+	Points      int               `json:"points"`
+	Available   bool              `json:"available"`
+	Reason      UnavailableReason `json:"reason,omitempty"`
+	Detail      string            `json:"detail,omitempty"`
+}
+
+// checkAvailability returns why the benefit cannot be redeemed for the given points, or nil
+func checkAvailability(benefit *benefitInfo, points int, now time.Time) *AvailabilityError {
+	if !benefit.Active || benefit.Deleted {
+		return &AvailabilityError{Reason: ReasonInactive, Detail: fmt.Sprintf("%s is no longer offered", benefit.Name)}
+	}
+	if benefit.StartsAt != nil && now.Before(*benefit.StartsAt) {
+		return &AvailabilityError{Reason: ReasonOutOfWindow, Detail: fmt.Sprintf("%s is available from %s", benefit.Name, benefit.StartsAt.UTC().Format(time.RFC3339))}
+	}
+	if benefit.EndsAt != nil && !now.Before(*benefit.EndsAt) {
+		return &AvailabilityError{Reason: ReasonOutOfWindow, Detail: fmt.Sprintf("%s was available until %s", benefit.Name, benefit.EndsAt.UTC().Format(time.RFC3339))}
+	}
+	if !benefit.PartnerAvailable {
+		return &AvailabilityError{Reason: ReasonPartnerUnavailable, Detail: fmt.Sprintf("Partner %s is temporarily unavailable", benefit.Partner)}
+	}
+	if points != benefit.Points {
+		return &AvailabilityError{Reason: ReasonWrongPoints, Detail: fmt.Sprintf("%s costs %d points, not %d", benefit.Name, benefit.Points, points)}
+	}
+	return nil
+}
+
+// EstimateRedemption reports whether a redemption would be accepted, without creating it
+func (s *Service) EstimateRedemption(w http.ResponseWriter, r *http.Request) {
+	var req RedemptionRequest
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
+		return
+	}
+
+	benefit, err := s.getBenefitInfo(r.Context(), req.BenefitID)
+	if err != nil {
+		s.writeBenefitLookupError(w, r, req.BenefitID, err)
+		return
+	}
+
+	estimate := &RedemptionEstimate{
+		BenefitID:   benefit.ID,
+		BenefitName: benefit.Name,
+		BenefitType: benefit.Type(),
+		Points:      benefit.Points,
+		Available:   true,
+	}
+	if availErr := checkAvailability(benefit, req.Points, time.Now()); availErr != nil {
+		estimate.Available = false
+		estimate.Reason = availErr.Reason
+		estimate.Detail = availErr.Detail
+	}
+
+	render.JSON(w, r, estimate)
+}
+
+// writeAvailabilityError rejects a redemption of an unavailable benefit,
+// reporting the reason alongside the error code
+func writeAvailabilityError(w http.ResponseWriter, r *http.Request, availErr *AvailabilityError) {
+	platformhttp.WriteError(w, r, http.StatusUnprocessableEntity, &platformhttp.ErrorResponse{
+		Code:    platformhttp.ErrCodeBenefitUnavailable,
+		Message: availErr.Detail,
+		Reason:  string(availErr.Reason),
+	})
+}
@@ -0,0 +1,228 @@
+package redemption
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// availableBenefit returns a benefit that can be redeemed for 2000 points
+func availableBenefit() *benefitInfo {
+	return &benefitInfo{
+		ID:               uuid.New().String(),
+		Name:             "$25 Gift Card",
+		Category:         "Retail",
+		Partner:          "GIFTCO",
+		Points:           2000,
+		Active:           true,
+		PartnerAvailable: true,
+	}
+}
+
+func TestCheckAvailability(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		edit   func(*benefitInfo)
+		points int
+		want   UnavailableReason
+	}{
+		{"available", func(b *benefitInfo) {}, 2000, ""},
+		{"within window", func(b *benefitInfo) { b.StartsAt, b.EndsAt = &past, &future }, 2000, ""},
+		{"inactive", func(b *benefitInfo) { b.Active = false }, 2000, ReasonInactive},
+		{"deleted", func(b *benefitInfo) { b.Deleted = true }, 2000, ReasonInactive},
+		{"not started", func(b *benefitInfo) { b.StartsAt = &future }, 2000, ReasonOutOfWindow},
+		{"ended", func(b *benefitInfo) { b.EndsAt = &past }, 2000, ReasonOutOfWindow},
+		{"ends now", func(b *benefitInfo) { b.EndsAt = &now }, 2000, ReasonOutOfWindow},
+		{"partner unavailable", func(b *benefitInfo) { b.PartnerAvailable = false }, 2000, ReasonPartnerUnavailable},
+		{"wrong points", func(b *benefitInfo) {}, 1500, ReasonWrongPoints},
+		{"inactive before wrong points", func(b *benefitInfo) { b.Active = false }, 1500, ReasonInactive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			benefit := availableBenefit()
+			tt.edit(benefit)
+
+			availErr := checkAvailability(benefit, tt.points, now)
+			if tt.want == "" {
+				if availErr != nil {
+					t.Fatalf("checkAvailability = %v, want available", availErr)
+				}
+				return
+			}
+			if availErr == nil || availErr.Reason != tt.want {
+				t.Fatalf("checkAvailability = %v, want reason %q", availErr, tt.want)
+			}
+			if availErr.Detail == "" {
+				t.Fatal("no detail for the reason")
+			}
+		})
+	}
+}
+
+// newCatalogService creates a service whose catalog answers every benefit
+// lookup with benefit
+func newCatalogService(t *testing.T, benefit catalogBenefit) *Service {
+	t.Helper()
+
+	catalog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(benefit)
+	}))
+	t.Cleanup(catalog.Close)
+
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.Services.CatalogURL = catalog.URL
+	})
+	return s
+}
+
+func TestEstimateRedemptionReportsReason(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	benefit := catalogBenefit{ID: uuid.New().String(), Name: "$25 Gift Card", Points: 2000, Partner: "GIFTCO", Category: "Retail", Active: true}
+
+	tests := []struct {
+		name   string
+		edit   func(*catalogBenefit)
+		points int
+		want   UnavailableReason
+	}{
+		{"available", func(b *catalogBenefit) {}, 2000, ""},
+		{"inactive", func(b *catalogBenefit) { b.Active = false }, 2000, ReasonInactive},
+		{"deleted", func(b *catalogBenefit) { b.DeletedAt = &past }, 2000, ReasonInactive},
+		{"ended", func(b *catalogBenefit) { b.EndsAt = &past }, 2000, ReasonOutOfWindow},
+		{"wrong points", func(b *catalogBenefit) {}, 1000, ReasonWrongPoints},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := benefit
+			tt.edit(&b)
+			s := newCatalogService(t, b)
+
+			rec := serve(s, http.MethodPost, "/v1/redeem/estimate", token(t, s, uuid.New().String(), "user"),
+				RedemptionRequest{BenefitID: b.ID, Points: tt.points})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+			}
+			var estimate RedemptionEstimate
+			if err := json.NewDecoder(rec.Body).Decode(&estimate); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if estimate.Available != (tt.want == "") || estimate.Reason != tt.want {
+				t.Fatalf("estimate = %+v, want reason %q", estimate, tt.want)
+			}
+			if tt.want != "" && estimate.Detail == "" {
+				t.Fatal("no detail for the reason")
+			}
+		})
+	}
+}
+
+func TestRedeemUnavailableBenefitReportsReason(t *testing.T) {
+	s := newCatalogService(t, catalogBenefit{ID: uuid.New().String(), Name: "$25 Gift Card", Points: 2000, Category: "Retail", Active: false})
+
+	rec := serve(s, http.MethodPost, "/v1/redeem", token(t, s, uuid.New().String(), "user"),
+		RedemptionRequest{BenefitID: uuid.New().String(), Points: 2000, Details: &FulfillmentDetails{Amount: 25, Currency: "USD"}},
+		"Idempotency-Key", uuid.New().String())
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+	}
+	var body platformhttp.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Code != platformhttp.ErrCodeBenefitUnavailable || body.Reason != string(ReasonInactive) {
+		t.Fatalf("error = %+v, want %s with reason %s", body, platformhttp.ErrCodeBenefitUnavailable, ReasonInactive)
+	}
+}
+
+func TestOpenCircuitMakesPartnerUnavailable(t *testing.T) {
+	benefit := catalogBenefit{ID: uuid.New().String(), Name: "$25 Gift Card", Points: 2000, Partner: "GIFTCO", Category: "Retail", Active: true}
+	s := newCatalogService(t, benefit)
+	s.partnerBreaker = newCircuitBreaker(config.CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 1, OpenTimeout: time.Hour}, nil)
+	tok := token(t, s, uuid.New().String(), "user")
+	req := RedemptionRequest{BenefitID: benefit.ID, Points: 2000, Details: &FulfillmentDetails{Amount: 25, Currency: "USD"}}
+
+	estimate := func() RedemptionEstimate {
+		t.Helper()
+		rec := serve(s, http.MethodPost, "/v1/redeem/estimate", tok, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+		}
+		var estimate RedemptionEstimate
+		if err := json.NewDecoder(rec.Body).Decode(&estimate); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return estimate
+	}
+	if got := estimate(); !got.Available {
+		t.Fatalf("estimate = %+v, want available while the circuit is closed", got)
+	}
+
+	call(s.partnerBreaker, false)
+	if got := estimate(); got.Available || got.Reason != ReasonPartnerUnavailable {
+		t.Fatalf("estimate = %+v, want reason %q", got, ReasonPartnerUnavailable)
+	}
+
+	rec := serve(s, http.MethodPost, "/v1/redeem", tok, req, "Idempotency-Key", uuid.New().String())
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body)
+	}
+	var body platformhttp.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Code != platformhttp.ErrCodeBenefitUnavailable || body.Reason != string(ReasonPartnerUnavailable) {
+		t.Fatalf("error = %+v, want %s with reason %s", body, platformhttp.ErrCodeBenefitUnavailable, ReasonPartnerUnavailable)
+	}
+}
+
+func TestSagaRecordsFailureReason(t *testing.T) {
+	ended := time.Now().Add(-time.Minute)
+	s := newCatalogService(t, catalogBenefit{ID: uuid.New().String(), Name: "$25 Gift Card", Points: 2000, Category: "Retail", Active: true, EndsAt: &ended})
+	redemption := newTestRedemption()
+
+	// The benefit ended after the request was accepted
+	s.processRedemptionSaga(context.Background(), redemption, "")
+
+	if redemption.Status != StatusFailed || redemption.FailureReason != ReasonOutOfWindow {
+		t.Fatalf("redemption = %s (%q), want %s with reason %s", redemption.Status, redemption.FailureReason, StatusFailed, ReasonOutOfWindow)
+	}
+	out, err := json.Marshal(redemption)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var body map[string]interface{}
+	json.Unmarshal(out, &body)
+	if body["failure_reason"] != string(ReasonOutOfWindow) {
+		t.Fatalf("failure_reason = %v, want %s", body["failure_reason"], ReasonOutOfWindow)
+	}
+}
+
+func TestSagaFailsDeletedBenefitAsInactive(t *testing.T) {
+	catalog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Benefit not found")
+	}))
+	t.Cleanup(catalog.Close)
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.Services.CatalogURL = catalog.URL
+	})
+	redemption := newTestRedemption()
+
+	// The benefit was deleted after the request was accepted
+	s.processRedemptionSaga(context.Background(), redemption, "")
+
+	if redemption.Status != StatusFailed || redemption.FailureReason != ReasonInactive {
+		t.Fatalf("redemption = %s (%q), want %s with reason %s", redemption.Status, redemption.FailureReason, StatusFailed, ReasonInactive)
+	}
+}
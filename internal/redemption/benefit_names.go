@@ -0,0 +1,49 @@
+package redemption
+
+import (
+	"context"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/cache"
+)
+
+// cacheBenefits is the benefit cache namespace
+const cacheBenefits = "benefits"
+
+// newBenefitCache returns the cache of benefits shown in redemption status,
+// or nil when ttl disables it
+func newBenefitCache(ttl time.Duration) *cache.Cache {
+	if ttl <= 0 {
+		return nil
+	}
+	return cache.New(ttl)
+}
+
+// describeBenefit returns the catalog's record of a redeemed benefit for
+// display, through a short cache so status polling does not hit the catalog
+// on every request. Status must not fail because the catalog does, so when
+// the benefit cannot be fetched it returns one named by its ID.
+func (s *Service) describeBenefit(ctx context.Context, benefitID string) *benefitInfo {
+	fallback := &benefitInfo{ID: benefitID, Name: benefitID}
+	if s.catalog == nil {
+		return fallback
+	}
+
+	load := func() (interface{}, error) {
+		return s.catalog.getBenefit(ctx, benefitID)
+	}
+
+	var value interface{}
+	var err error
+	if s.benefits == nil {
+		value, err = load()
+	} else {
+		value, err = s.benefits.GetOrLoad(cacheBenefits, auth.TenantFromContext(ctx)+"/"+benefitID, load)
+	}
+	if err != nil {
+		s.logger.Warnf("Failed to look up benefit %s, showing its ID: %v", benefitID, err)
+		return fallback
+	}
+	return value.(*benefitInfo)
+}
@@ -0,0 +1,119 @@
+package redemption
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// fakeCatalog serves benefits named after their IDs, or fails with 503 while
+// down, counting the lookups it receives
+type fakeCatalog struct {
+	lookups int32
+	down    atomic.Bool
+}
+
+func (f *fakeCatalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&f.lookups, 1)
+	if f.down.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/v1/benefits/")
+	json.NewEncoder(w).Encode(catalogBenefit{ID: id, Name: "Gift card " + id, Category: "gift_card", Partner: "ACME", Points: 2000, Active: true})
+}
+
+// newBenefitNamesService creates a service looking benefits up in a fake
+// catalog, caching them for ttl
+func newBenefitNamesService(t *testing.T, ttl time.Duration) (*Service, *fakeCatalog) {
+	t.Helper()
+
+	catalog := &fakeCatalog{}
+	server := httptest.NewServer(catalog)
+	t.Cleanup(server.Close)
+
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.Services.CatalogURL = server.URL
+		cfg.Redemption.BenefitCacheTTL = ttl
+	})
+	return s, catalog
+}
+
+func TestRedemptionStatusNamesBenefit(t *testing.T) {
+	s, catalog := newBenefitNamesService(t, time.Minute)
+	redemption := newTestRedemption()
+	redemption.BenefitID = "benefit-1"
+
+	for i := 0; i < 3; i++ {
+		status := s.redemptionStatus(context.Background(), redemption)
+		if status.BenefitName != "Gift card benefit-1" || status.Category != "gift_card" || status.Partner != "ACME" {
+			t.Fatalf("status %d = %s, %s, %s; want the catalog's benefit", i, status.BenefitName, status.Category, status.Partner)
+		}
+	}
+	if n := atomic.LoadInt32(&catalog.lookups); n != 1 {
+		t.Fatalf("catalog looked up %d times, want 1 with the rest served from cache", n)
+	}
+
+	// Entries are cached per benefit and per tenant
+	redemption.BenefitID = "benefit-2"
+	s.redemptionStatus(context.Background(), redemption)
+	s.redemptionStatus(auth.WithTenant(context.Background(), "tenant-b"), redemption)
+	if n := atomic.LoadInt32(&catalog.lookups); n != 3 {
+		t.Fatalf("catalog looked up %d times, want 3", n)
+	}
+}
+
+func TestRedemptionStatusWithoutBenefitCache(t *testing.T) {
+	s, catalog := newBenefitNamesService(t, 0)
+	redemption := newTestRedemption()
+
+	s.redemptionStatus(context.Background(), redemption)
+	s.redemptionStatus(context.Background(), redemption)
+	if n := atomic.LoadInt32(&catalog.lookups); n != 2 {
+		t.Fatalf("catalog looked up %d times with the cache disabled, want 2", n)
+	}
+}
+
+func TestRedemptionStatusFallsBackWhenCatalogDown(t *testing.T) {
+	s, catalog := newBenefitNamesService(t, time.Minute)
+	catalog.down.Store(true)
+	redemption := newTestRedemption()
+	redemption.BenefitID = "benefit-1"
+
+	status := s.redemptionStatus(context.Background(), redemption)
+	if status.BenefitName != "benefit-1" || status.BenefitID != "benefit-1" || status.Category != "" {
+		t.Fatalf("status = %+v, want the benefit named by its ID", status)
+	}
+
+	// Failures are not cached, so the name appears once the catalog recovers
+	catalog.down.Store(false)
+	if status := s.redemptionStatus(context.Background(), redemption); status.BenefitName != "Gift card benefit-1" {
+		t.Fatalf("name after recovery = %q, want the catalog's", status.BenefitName)
+	}
+}
+
+func TestGetRedemptionWithCatalogDown(t *testing.T) {
+	s, catalog := newBenefitNamesService(t, time.Minute)
+	catalog.down.Store(true)
+
+	// Without a database the service returns a stored redemption of benefit-1
+	rec := serve(s, http.MethodGet, "/v1/redemptions/redemption-1", token(t, s, "admin-1", auth.RoleAdmin), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d with the catalog down, want %d", rec.Code, http.StatusOK)
+	}
+	var status RedemptionStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if status.BenefitName != "benefit-1" {
+		t.Fatalf("benefit_name = %q, want the benefit ID", status.BenefitName)
+	}
+}
@@ -0,0 +1,194 @@
+package redemption
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// errCircuitOpen is returned instead of calling the partner gateway while its
+// circuit is open. The redemption fails with this message, and may be retried
+// once the partner recovers.
+var errCircuitOpen = errors.New("partner gateway is unavailable, circuit breaker open; retry later")
+
+// breakerState is the state of a circuit breaker
+type breakerState int
+
+const (
+	// breakerClosed lets every call through, counting failures
+	breakerClosed breakerState = iota
+	// breakerHalfOpen lets a few probe calls through to test for recovery
+	breakerHalfOpen
+	// breakerOpen fails every call without making it
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half_open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops calls to a failing service. While closed it counts
+// calls in windows; a window with at least MinRequests calls and a failure
+// ratio of FailureRatio or more opens the circuit. After OpenTimeout it turns
+// half-open and lets HalfOpenProbes calls through: if they all succeed the
+// circuit closes, and the first failure opens it again. A nil breaker lets
+// every call through.
+type circuitBreaker struct {
+	config config.CircuitBreakerConfig
+	now    func() time.Time
+	// onStateChange is called, with the lock held, whenever the state changes
+	onStateChange func(from, to breakerState)
+
+	mu    sync.Mutex
+	state breakerState
+	// generation changes with the state and each new window, so results of
+	// calls allowed in an earlier one are not counted
+	generation  uint64
+	windowStart time.Time
+	openedAt    time.Time
+	requests    int
+	failures    int
+	// probes counts the probe calls allowed while half-open, and successes
+	// those that succeeded
+	probes    int
+	successes int
+}
+
+// newCircuitBreaker returns a breaker, or nil when FailureRatio disables it
+func newCircuitBreaker(cfg config.CircuitBreakerConfig, onStateChange func(from, to breakerState)) *circuitBreaker {
+	if cfg.FailureRatio <= 0 {
+		return nil
+	}
+	if cfg.MinRequests < 1 {
+		cfg.MinRequests = 1
+	}
+	if cfg.HalfOpenProbes < 1 {
+		cfg.HalfOpenProbes = 1
+	}
+	b := &circuitBreaker{config: cfg, now: time.Now, onStateChange: onStateChange}
+	b.windowStart = b.now()
+	return b
+}
+
+// allow reports whether a call may be made, returning errCircuitOpen if not.
+// A call that is allowed must be reported with done.
+func (b *circuitBreaker) allow() (generation uint64, err error) {
+	if b == nil {
+		return 0, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.advance(now)
+	switch b.state {
+	case breakerOpen:
+		return 0, errCircuitOpen
+	case breakerHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			return 0, errCircuitOpen
+		}
+		b.probes++
+	default:
+		b.requests++
+	}
+	return b.generation, nil
+}
+
+// available reports whether a call would be allowed now, without taking a
+// half-open probe. A nil breaker is always available.
+func (b *circuitBreaker) available() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(b.now())
+	switch b.state {
+	case breakerOpen:
+		return false
+	case breakerHalfOpen:
+		return b.probes < b.config.HalfOpenProbes
+	default:
+		return true
+	}
+}
+
+// done records the result of a call allowed in generation
+func (b *circuitBreaker) done(generation uint64, success bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.advance(now)
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case breakerHalfOpen:
+		if !success {
+			b.setState(breakerOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.setState(breakerClosed, now)
+		}
+	case breakerClosed:
+		if success {
+			return
+		}
+		b.failures++
+		if b.requests >= b.config.MinRequests && float64(b.failures)/float64(b.requests) >= b.config.FailureRatio {
+			b.setState(breakerOpen, now)
+		}
+	}
+}
+
+// advance moves an open circuit to half-open once its timeout has passed, and
+// starts a new window for a closed one once the window has passed
+func (b *circuitBreaker) advance(now time.Time) {
+	switch b.state {
+	case breakerOpen:
+		if !now.Before(b.openedAt.Add(b.config.OpenTimeout)) {
+			b.setState(breakerHalfOpen, now)
+		}
+	case breakerClosed:
+		if b.config.Window > 0 && !now.Before(b.windowStart.Add(b.config.Window)) {
+			b.resetCounts(now)
+		}
+	}
+}
+
+func (b *circuitBreaker) setState(state breakerState, now time.Time) {
+	from := b.state
+	b.state = state
+	if state == breakerOpen {
+		b.openedAt = now
+	}
+	b.resetCounts(now)
+	if b.onStateChange != nil {
+		b.onStateChange(from, state)
+	}
+}
+
+func (b *circuitBreaker) resetCounts(now time.Time) {
+	b.generation++
+	b.windowStart = now
+	b.requests, b.failures = 0, 0
+	b.probes, b.successes = 0, 0
+}
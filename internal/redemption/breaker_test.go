@@ -0,0 +1,242 @@
+package redemption
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newTestBreaker returns a breaker on a clock the test advances by moving
+// *now, recording each state it enters
+func newTestBreaker(t *testing.T, cfg config.CircuitBreakerConfig) (*circuitBreaker, *time.Time, *[]breakerState) {
+	t.Helper()
+
+	var states []breakerState
+	b := newCircuitBreaker(cfg, func(from, to breakerState) { states = append(states, to) })
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	b.windowStart = now
+	return b, &now, &states
+}
+
+// call makes one call through b that succeeds or fails, reporting whether
+// the breaker let it through
+func call(b *circuitBreaker, success bool) bool {
+	generation, err := b.allow()
+	if err != nil {
+		return false
+	}
+	b.done(generation, success)
+	return true
+}
+
+var testBreakerConfig = config.CircuitBreakerConfig{
+	FailureRatio:   0.5,
+	MinRequests:    4,
+	Window:         time.Minute,
+	OpenTimeout:    30 * time.Second,
+	HalfOpenProbes: 2,
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	b, now, states := newTestBreaker(t, testBreakerConfig)
+
+	// Closed: failures below the minimum number of calls do not open it
+	for i := 0; i < 3; i++ {
+		if !call(b, false) {
+			t.Fatalf("call %d rejected while closed", i)
+		}
+	}
+	if b.state != breakerClosed {
+		t.Fatalf("state = %s after 3 calls, want closed until %d calls", b.state, testBreakerConfig.MinRequests)
+	}
+
+	// The fourth failure reaches the minimum with a ratio over the limit
+	call(b, false)
+	if b.state != breakerOpen {
+		t.Fatalf("state = %s, want open", b.state)
+	}
+
+	// Open: calls fail fast until the timeout passes
+	if _, err := b.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("allow while open = %v, want errCircuitOpen", err)
+	}
+	*now = now.Add(testBreakerConfig.OpenTimeout)
+
+	// Half-open: only the probes are let through, and a failing one reopens
+	if !call(b, true) || b.state != breakerHalfOpen {
+		t.Fatalf("first probe: state = %s, want half-open", b.state)
+	}
+	if !call(b, false) || b.state != breakerOpen {
+		t.Fatalf("failed probe: state = %s, want open", b.state)
+	}
+
+	*now = now.Add(testBreakerConfig.OpenTimeout)
+	first, err := b.allow()
+	if err != nil {
+		t.Fatalf("first probe rejected: %v", err)
+	}
+	second, err := b.allow()
+	if err != nil {
+		t.Fatalf("second probe rejected: %v", err)
+	}
+	if _, err := b.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("call beyond the probes = %v, want errCircuitOpen", err)
+	}
+	b.done(first, true)
+	b.done(second, true)
+	if b.state != breakerClosed {
+		t.Fatalf("state = %s after every probe succeeded, want closed", b.state)
+	}
+
+	want := []breakerState{breakerOpen, breakerHalfOpen, breakerOpen, breakerHalfOpen, breakerClosed}
+	if len(*states) != len(want) {
+		t.Fatalf("states = %v, want %v", *states, want)
+	}
+	for i := range want {
+		if (*states)[i] != want[i] {
+			t.Fatalf("states = %v, want %v", *states, want)
+		}
+	}
+}
+
+func TestCircuitBreakerCountsPerWindow(t *testing.T) {
+	b, now, _ := newTestBreaker(t, testBreakerConfig)
+
+	// A failure ratio under the limit keeps it closed
+	for _, success := range []bool{false, true, true, true, false} {
+		call(b, success)
+	}
+	if b.state != breakerClosed {
+		t.Fatalf("state = %s at a 40%% failure ratio, want closed", b.state)
+	}
+
+	// Failures from an earlier window are forgotten
+	*now = now.Add(testBreakerConfig.Window)
+	for i := 0; i < 3; i++ {
+		call(b, false)
+	}
+	if b.state != breakerClosed {
+		t.Fatalf("state = %s with 3 calls in the new window, want closed", b.state)
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleResults(t *testing.T) {
+	b, now, _ := newTestBreaker(t, testBreakerConfig)
+
+	// A slow call allowed before the circuit opened finishes while it is
+	// half-open; its failure says nothing about the probes
+	slow, err := b.allow()
+	if err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		call(b, false)
+	}
+	*now = now.Add(testBreakerConfig.OpenTimeout)
+	if !call(b, true) {
+		t.Fatal("probe rejected")
+	}
+	b.done(slow, false)
+	if b.state != breakerHalfOpen {
+		t.Fatalf("state = %s after a stale failure, want half-open", b.state)
+	}
+}
+
+func TestCircuitBreakerAvailable(t *testing.T) {
+	b, now, _ := newTestBreaker(t, testBreakerConfig)
+	for i := 0; i < 4; i++ {
+		call(b, false)
+	}
+	if b.available() {
+		t.Fatal("available while open")
+	}
+
+	// Half-open it is available until its probes are taken, and asking takes none
+	*now = now.Add(testBreakerConfig.OpenTimeout)
+	for i := 0; i < 3; i++ {
+		if !b.available() {
+			t.Fatalf("check %d: unavailable while half-open with probes left", i)
+		}
+	}
+	for i := 0; i < testBreakerConfig.HalfOpenProbes; i++ {
+		if _, err := b.allow(); err != nil {
+			t.Fatalf("probe %d rejected: %v", i, err)
+		}
+	}
+	if b.available() {
+		t.Fatal("available with every probe taken")
+	}
+
+	var disabled *circuitBreaker
+	if !disabled.available() {
+		t.Fatal("disabled breaker unavailable")
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(config.CircuitBreakerConfig{}, nil)
+	if b != nil {
+		t.Fatal("breaker created without a failure ratio")
+	}
+	for i := 0; i < 100; i++ {
+		if !call(b, false) {
+			t.Fatal("disabled breaker rejected a call")
+		}
+	}
+}
+
+// gaugeValue reads an unlabelled gauge from the default registry
+func gaugeValue(t *testing.T, name string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == name && len(family.GetMetric()) > 0 {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("gauge %s not found", name)
+	return 0
+}
+
+func TestOpenCircuitFailsSagaBeforeDeducting(t *testing.T) {
+	var calls atomic.Int32
+	s, ledger := newGatewaySagaService(t, flakyPartner(100, http.StatusServiceUnavailable, &calls), withPartnerRetry(1), func(cfg *config.Config) {
+		cfg.Redemption.PartnerBreaker = config.CircuitBreakerConfig{FailureRatio: 0.5, MinRequests: 2, Window: time.Minute, OpenTimeout: time.Hour, HalfOpenProbes: 1}
+	})
+	opened := counterValue(t, "redemption_partner_circuit_transitions_total", map[string]string{"state": "open"})
+
+	// Two failing redemptions open the circuit
+	for i := 0; i < 2; i++ {
+		s.processRedemptionSaga(context.Background(), newTestRedemption(), "Bearer user-token")
+	}
+	if got := gaugeValue(t, "redemption_partner_circuit_state"); got != float64(breakerOpen) {
+		t.Fatalf("circuit state gauge = %v, want %d (open)", got, breakerOpen)
+	}
+	if got := counterValue(t, "redemption_partner_circuit_transitions_total", map[string]string{"state": "open"}); got != opened+1 {
+		t.Fatalf("open transitions = %v, want %v", got, opened+1)
+	}
+
+	// The next fails validation without deducting points or calling the partner
+	redemption := newTestRedemption()
+	s.processRedemptionSaga(context.Background(), redemption, "Bearer user-token")
+	if calls.Load() != 2 {
+		t.Fatalf("partner calls = %d, want none while the circuit is open", calls.Load()-2)
+	}
+	if redemption.Status != StatusFailed || redemption.FailureReason != ReasonPartnerUnavailable {
+		t.Fatalf("redemption = %s %q (%s), want failed as partner_unavailable", redemption.Status, redemption.ErrorMessage, redemption.FailureReason)
+	}
+	if ledger.deductions.Load() != 2 || ledger.reversals.Load() != 2 {
+		t.Fatalf("deductions %d, reversals %d; want only the first two deducted and reversed", ledger.deductions.Load(), ledger.reversals.Load())
+	}
+}
@@ -0,0 +1,266 @@
+package redemption
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/httputil"
+)
+
+var errBenefitNotFound = errors.New("benefit not found")
+
+// serviceError is returned when another platform service answers a saga call
+// with a non-2xx status
+type serviceError struct {
+	Service    string
+	StatusCode int
+	// Code is the machine-readable error code from the response, if any
+	Code    string
+	Message string
+}
+
+func (e *serviceError) Error() string {
+	return fmt.Sprintf("%s returned %d: %s", e.Service, e.StatusCode, e.Message)
+}
+
+// serviceClient makes JSON calls to another platform service. Calls are made
+// either as the user who requested the redemption, by forwarding their
+// Authorization header, or as this service with a service token.
+type serviceClient struct {
+	name       string
+	baseURL    string
+	httpClient *http.Client
+	jwtManager *auth.JWTManager
+}
+
+func newServiceClient(name, baseURL string, httpClient *http.Client, jwtManager *auth.JWTManager) *serviceClient {
+	return &serviceClient{
+		name:       name,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+		jwtManager: jwtManager,
+	}
+}
+
+// serviceAuthorization returns an Authorization header identifying this service
+func (c *serviceClient) serviceAuthorization() (string, error) {
+	token, err := c.jwtManager.GenerateServiceToken(serviceName)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate service token: %w", err)
+	}
+	return "Bearer " + token, nil
+}
+
+// do sends body as JSON and decodes a 2xx response into out. The tenant in
+// ctx is forwarded so service tokens act on the caller's tenant, and the
+// request ID so the call can be found in the other service's logs.
+func (c *serviceClient) do(ctx context.Context, method, path, authorization string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s request: %w", c.name, err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", c.name, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	req.Header.Set(auth.TenantHeader, auth.TenantFromContext(ctx))
+	httputil.SetRequestID(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Errors arrive in the platform error envelope; a proxy in between
+		// may answer with anything, so fall back to the status text
+		var failure platformhttp.ErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		message := failure.Message
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return &serviceError{Service: c.name, StatusCode: resp.StatusCode, Code: string(failure.Code), Message: message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid %s response: %w", c.name, err)
+	}
+	return nil
+}
+
+// catalogClient looks up benefits in the catalog service
+type catalogClient struct {
+	*serviceClient
+}
+
+// catalogBenefit mirrors the catalog service's benefit representation
+type catalogBenefit struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Points   int        `json:"points"`
+	Partner  string     `json:"partner"`
+	Category string     `json:"category"`
+	Active   bool       `json:"active"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	// DeletedAt is set when the benefit has been soft-deleted
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+// getBenefit returns a benefit, or errBenefitNotFound. Benefit reads are public.
+func (c *catalogClient) getBenefit(ctx context.Context, benefitID string) (*benefitInfo, error) {
+	var benefit catalogBenefit
+	err := c.do(ctx, http.MethodGet, "/v1/benefits/"+url.PathEscape(benefitID), "", nil, &benefit)
+	if err != nil {
+		var svcErr *serviceError
+		if errors.As(err, &svcErr) && svcErr.StatusCode == http.StatusNotFound {
+			return nil, errBenefitNotFound
+		}
+		return nil, err
+	}
+
+	return &benefitInfo{
+		ID:       benefit.ID,
+		Name:     benefit.Name,
+		Category: benefit.Category,
+		Partner:  benefit.Partner,
+		Points:   benefit.Points,
+		Active:   benefit.Active,
+		StartsAt: benefit.StartsAt,
+		EndsAt:   benefit.EndsAt,
+		Deleted:  benefit.DeletedAt != nil,
+	}, nil
+}
+
+// loyaltyClient moves points in the loyalty service
+type loyaltyClient struct {
+	*serviceClient
+}
+
+// loyaltyResponse mirrors the loyalty service response envelope
+type loyaltyResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// call makes a loyalty request and decodes the response data into out.
+// Loyalty rejects spends beyond the available balance with
+// INSUFFICIENT_POINTS, which is reported as errInsufficientPoints.
+func (c *loyaltyClient) call(ctx context.Context, method, path, authorization string, body, out interface{}) error {
+	var resp loyaltyResponse
+	if err := c.do(ctx, method, path, authorization, body, &resp); err != nil {
+		var svcErr *serviceError
+		if errors.As(err, &svcErr) && svcErr.Code == string(platformhttp.ErrCodeInsufficientPoints) {
+			return errInsufficientPoints
+		}
+		return err
+	}
+
+	if out == nil || len(resp.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return fmt.Errorf("invalid %s response: %w", c.name, err)
+	}
+	return nil
+}
+
+// callAsService makes a loyalty request with a service token
+func (c *loyaltyClient) callAsService(ctx context.Context, path string, body interface{}) error {
+	authorization, err := c.serviceAuthorization()
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, http.MethodPost, path, authorization, body, nil)
+}
+
+// availablePoints returns the caller's points not reserved by holds
+func (c *loyaltyClient) availablePoints(ctx context.Context, authorization string) (int, error) {
+	var balance struct {
+		AvailablePoints int `json:"available_points"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/v1/loyalty/balance", authorization, nil, &balance); err != nil {
+		return 0, err
+	}
+	return balance.AvailablePoints, nil
+}
+
+// deduct spends points for a redemption. The redemption ID is the
+// idempotency key, so a retried deduction is applied at most once.
+func (c *loyaltyClient) deduct(ctx context.Context, userID string, points int, redemptionID string) error {
+	return c.callAsService(ctx, "/v1/loyalty/internal/deduct", map[string]interface{}{
+		"user_id":         userID,
+		"amount":          points,
+		"reason":          "Redemption " + redemptionID,
+		"idempotency_key": redemptionID,
+		"redemption_id":   redemptionID,
+	})
+}
+
+// reverse refunds a redemption's deduction with a compensating ledger entry
+func (c *loyaltyClient) reverse(ctx context.Context, userID, redemptionID, reason string) error {
+	return c.callAsService(ctx, "/v1/loyalty/internal/reverse", map[string]string{
+		"user_id":       userID,
+		"redemption_id": redemptionID,
+		"reason":        reason,
+	})
+}
+
+// settle marks a redemption's deduction as paying for a fulfilled benefit
+func (c *loyaltyClient) settle(ctx context.Context, userID, redemptionID string) error {
+	return c.callAsService(ctx, "/v1/loyalty/internal/settle", map[string]string{
+		"user_id":       userID,
+		"redemption_id": redemptionID,
+	})
+}
+
+// placeHold reserves points for a redemption as the caller and returns the hold ID
+func (c *loyaltyClient) placeHold(ctx context.Context, authorization string, redemption *Redemption) (string, error) {
+	var hold struct {
+		ID string `json:"id"`
+	}
+	err := c.call(ctx, http.MethodPost, "/v1/loyalty/holds", authorization, map[string]interface{}{
+		"user_id":   redemption.UserID,
+		"amount":    redemption.Points,
+		"reference": redemption.ID,
+	}, &hold)
+	if err != nil {
+		return "", err
+	}
+	return hold.ID, nil
+}
+
+// finishHold captures or releases a user's hold. Only services may finish
+// holds, so users cannot capture or release their own.
+func (c *loyaltyClient) finishHold(ctx context.Context, userID, holdID, action string) error {
+	return c.callAsService(ctx, "/v1/loyalty/holds/"+holdID+"/"+action, map[string]string{
+		"user_id": userID,
+	})
+}
@@ -0,0 +1,371 @@
+package redemption
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// fakeLoyalty answers the loyalty calls made by a held-points saga. The
+// first captureFailures captures fail with captureStatus.
+type fakeLoyalty struct {
+	t               *testing.T
+	jwtManager      *auth.JWTManager
+	captureStatus   int
+	captureFailures int32
+	captures        int32
+	releases        int32
+}
+
+func (f *fakeLoyalty) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/v1/loyalty/balance":
+		writeLoyaltyData(w, map[string]int{"available_points": 10000})
+	case r.URL.Path == "/v1/loyalty/holds":
+		writeLoyaltyData(w, map[string]string{"id": "hold-1"})
+	case strings.HasPrefix(r.URL.Path, "/v1/loyalty/holds/hold-1/"):
+		claims, err := f.jwtManager.ValidateToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil || claims.Role != auth.RoleService {
+			f.t.Errorf("%s called without a service token (err %v)", r.URL.Path, err)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["user_id"] == "" {
+			f.t.Errorf("%s called without a user_id (err %v)", r.URL.Path, err)
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/release") {
+			atomic.AddInt32(&f.releases, 1)
+			writeLoyaltyData(w, nil)
+			return
+		}
+		if atomic.AddInt32(&f.captures, 1) <= atomic.LoadInt32(&f.captureFailures) {
+			w.WriteHeader(f.captureStatus)
+			return
+		}
+		writeLoyaltyData(w, nil)
+	default:
+		f.t.Errorf("unexpected loyalty call %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newHoldSagaService creates a service whose saga holds points with loyalty;
+// its first captureFailures captures fail with captureStatus
+func newHoldSagaService(t *testing.T, captureStatus int, captureFailures int32) (*Service, *fakeLoyalty) {
+	t.Helper()
+
+	loyalty := &fakeLoyalty{t: t, captureStatus: captureStatus, captureFailures: captureFailures}
+	server := httptest.NewServer(loyalty)
+	t.Cleanup(server.Close)
+
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.Redemption.PointsHolds = true
+		cfg.Services.LoyaltyURL = server.URL
+		cfg.Redemption.CaptureRetry = config.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	})
+	loyalty.jwtManager = s.jwtManager
+	return s, loyalty
+}
+
+func TestFinishHoldUsesServiceToken(t *testing.T) {
+	s, loyalty := newHoldSagaService(t, 0, 0)
+	redemption := newTestRedemption()
+
+	if err := s.capturePointsHold(context.Background(), redemption.UserID, "hold-1"); err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if err := s.releasePointsHold(context.Background(), redemption.UserID, "hold-1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if loyalty.captures != 1 || loyalty.releases != 1 {
+		t.Fatalf("captures = %d, releases = %d; want 1 each", loyalty.captures, loyalty.releases)
+	}
+}
+
+func TestCaptureRetryStopsWhenContextEnds(t *testing.T) {
+	s, loyalty := newHoldSagaService(t, http.StatusServiceUnavailable, 10)
+	s.config.Redemption.CaptureRetry = config.RetryConfig{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	redemption := newTestRedemption()
+	redemption.HoldID = "hold-1"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := s.captureHeldPoints(ctx, redemption)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("captureHeldPoints = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("captureHeldPoints returned after %s, want it to stop waiting with its context", elapsed)
+	}
+	if n := atomic.LoadInt32(&loyalty.captures); n != 1 {
+		t.Fatalf("captures = %d, want 1", n)
+	}
+}
+
+func TestRedeemRejectsMalformedBenefitID(t *testing.T) {
+	s, catalog := newBenefitNamesService(t, 0)
+	tok := token(t, s, "user-123", "user")
+
+	for _, path := range []string{"/v1/redeem", "/v1/redeem/estimate"} {
+		for _, benefitID := range []string{"../partners", "benefit-1?x=1", "not-a-uuid"} {
+			rec := serve(s, http.MethodPost, path, tok, RedemptionRequest{BenefitID: benefitID, Points: 2000}, "Idempotency-Key", "key-1")
+			if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "benefit_id") {
+				t.Fatalf("%s with %q: status = %d, want %d for benefit_id: %s", path, benefitID, rec.Code, http.StatusUnprocessableEntity, rec.Body)
+			}
+		}
+	}
+	if n := atomic.LoadInt32(&catalog.lookups); n != 0 {
+		t.Fatalf("catalog called %d times for malformed benefit IDs", n)
+	}
+}
+
+func TestCatalogClientEscapesBenefitID(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	s, _ := newTestService(t, func(cfg *config.Config) { cfg.Services.CatalogURL = server.URL })
+
+	if _, err := s.catalog.getBenefit(context.Background(), "../partners?x=1#y"); !errors.Is(err, errBenefitNotFound) {
+		t.Fatalf("getBenefit = %v, want errBenefitNotFound", err)
+	}
+	if path != "/v1/benefits/..%2Fpartners%3Fx=1%23y" {
+		t.Fatalf("catalog called at %s, want the ID escaped as one path segment", path)
+	}
+}
+
+// serviceCall is a request received by fakeServices
+type serviceCall struct {
+	Method        string
+	Path          string
+	Authorization string
+	Tenant        string
+	Body          map[string]interface{}
+}
+
+// fakeServices answers the catalog and loyalty calls made by a saga that
+// deducts points outright, recording each call. A path in fail is answered
+// with its status; a path in hang is not answered until the call is abandoned.
+type fakeServices struct {
+	benefit catalogBenefit
+	fail    map[string]int
+	hang    map[string]bool
+
+	mu    sync.Mutex
+	calls []serviceCall
+}
+
+func (f *fakeServices) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	call := serviceCall{Method: r.Method, Path: r.URL.Path, Authorization: r.Header.Get("Authorization"), Tenant: r.Header.Get(auth.TenantHeader)}
+	json.NewDecoder(r.Body).Decode(&call.Body)
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+
+	if f.hang[r.URL.Path] {
+		<-r.Context().Done()
+		return
+	}
+	if status, ok := f.fail[r.URL.Path]; ok {
+		platformhttp.Error(w, r, status, platformhttp.ErrCodeInternal, "Downstream failed")
+		return
+	}
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/benefits/"):
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(f.benefit)
+	case r.URL.Path == "/v1/loyalty/balance":
+		writeLoyaltyData(w, map[string]int{"available_points": 10000})
+	default:
+		writeLoyaltyData(w, nil)
+	}
+}
+
+// paths returns the paths called so far, in order
+func (f *fakeServices) paths() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	paths := make([]string, len(f.calls))
+	for i, call := range f.calls {
+		paths[i] = call.Path
+	}
+	return paths
+}
+
+// call returns the first call made to path
+func (f *fakeServices) call(t *testing.T, path string) serviceCall {
+	t.Helper()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, call := range f.calls {
+		if call.Path == path {
+			return call
+		}
+	}
+	t.Fatalf("%s was not called", path)
+	return serviceCall{}
+}
+
+// newStepsService creates a service whose saga deducts points outright,
+// calling one fake for both the catalog and loyalty services and partner,
+// if set, for fulfillment
+func newStepsService(t *testing.T, redemption *Redemption, partner http.HandlerFunc, configure ...func(*config.Config)) (*Service, *fakeServices) {
+	t.Helper()
+
+	services := &fakeServices{benefit: catalogBenefit{ID: redemption.BenefitID, Name: "$25 Gift Card", Points: redemption.Points,
+		Partner: "GIFTCO", Category: "Retail", Active: true}}
+	server := httptest.NewServer(services)
+	t.Cleanup(server.Close)
+	gatewayURL := ""
+	if partner != nil {
+		gateway := httptest.NewServer(partner)
+		t.Cleanup(gateway.Close)
+		gatewayURL = gateway.URL
+	}
+
+	s, _ := newTestService(t, append([]func(*config.Config){func(cfg *config.Config) {
+		cfg.Redemption.PointsHolds = false
+		cfg.Redemption.PartnerRetry.MaxAttempts = 1
+		cfg.Services.CatalogURL = server.URL
+		cfg.Services.LoyaltyURL = server.URL
+		cfg.Services.PartnerGatewayURL = gatewayURL
+	}}, configure...)...)
+	return s, services
+}
+
+// isServiceToken reports whether authorization carries this service's token
+func isServiceToken(s *Service, authorization string) bool {
+	claims, err := s.jwtManager.ValidateToken(strings.TrimPrefix(authorization, "Bearer "))
+	return err == nil && claims.Role == auth.RoleService
+}
+
+func TestSagaCallsCatalogAndLoyalty(t *testing.T) {
+	redemption := newTestRedemption()
+	s, services := newStepsService(t, redemption, nil)
+	user := "Bearer " + token(t, s, redemption.UserID, "user")
+
+	s.processRedemptionSaga(auth.WithTenant(context.Background(), "tenant-a"), redemption, user)
+
+	if redemption.Status != StatusCompleted {
+		t.Fatalf("status = %s (%s), want %s", redemption.Status, redemption.ErrorMessage, StatusCompleted)
+	}
+	want := []string{"/v1/benefits/" + redemption.BenefitID, "/v1/loyalty/balance", "/v1/loyalty/internal/deduct", "/v1/loyalty/internal/settle"}
+	if got := services.paths(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+	for _, path := range want {
+		if call := services.call(t, path); call.Tenant != "tenant-a" {
+			t.Errorf("%s called for tenant %q, want tenant-a", path, call.Tenant)
+		}
+	}
+
+	// Benefit reads are public; the balance is read as the user, whose
+	// token loyalty uses to pick the account
+	if call := services.call(t, want[0]); call.Method != http.MethodGet || call.Authorization != "" {
+		t.Errorf("catalog call = %s with %q, want an anonymous GET", call.Method, call.Authorization)
+	}
+	if call := services.call(t, want[1]); call.Method != http.MethodGet || call.Authorization != user {
+		t.Errorf("balance call = %s with %q, want a GET with the user's token", call.Method, call.Authorization)
+	}
+
+	deduct := services.call(t, want[2])
+	if deduct.Method != http.MethodPost || !isServiceToken(s, deduct.Authorization) {
+		t.Errorf("deduct call = %s with %q, want a POST with a service token", deduct.Method, deduct.Authorization)
+	}
+	if deduct.Body["user_id"] != redemption.UserID || deduct.Body["amount"] != float64(redemption.Points) ||
+		deduct.Body["idempotency_key"] != redemption.ID {
+		t.Errorf("deduct body = %v, want the redemption's user, points and ID", deduct.Body)
+	}
+}
+
+func TestSagaReversesDeductionAsService(t *testing.T) {
+	redemption := newTestRedemption()
+	var calls atomic.Int32
+	s, services := newStepsService(t, redemption, flakyPartner(1, http.StatusBadRequest, &calls))
+
+	s.processRedemptionSaga(context.Background(), redemption, "Bearer "+token(t, s, redemption.UserID, "user"))
+
+	if redemption.Status != StatusFailed {
+		t.Fatalf("status = %s, want %s", redemption.Status, StatusFailed)
+	}
+	reverse := services.call(t, "/v1/loyalty/internal/reverse")
+	if reverse.Method != http.MethodPost || !isServiceToken(s, reverse.Authorization) {
+		t.Errorf("reverse call = %s with %q, want a POST with a service token", reverse.Method, reverse.Authorization)
+	}
+	if reverse.Body["user_id"] != redemption.UserID || reverse.Body["redemption_id"] != redemption.ID || reverse.Body["reason"] == "" {
+		t.Errorf("reverse body = %v, want the redemption's user and ID with a reason", reverse.Body)
+	}
+}
+
+func TestSagaFailsOnDownstreamError(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		status    int
+		wantCalls int
+	}{
+		{"catalog", "/v1/benefits/", http.StatusInternalServerError, 1},
+		{"balance", "/v1/loyalty/balance", http.StatusServiceUnavailable, 2},
+		{"deduct", "/v1/loyalty/internal/deduct", http.StatusInternalServerError, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redemption := newTestRedemption()
+			s, services := newStepsService(t, redemption, nil)
+			path := tt.path
+			if strings.HasSuffix(path, "/") {
+				path += redemption.BenefitID
+			}
+			services.fail = map[string]int{path: tt.status}
+
+			s.processRedemptionSaga(context.Background(), redemption, "Bearer "+token(t, s, redemption.UserID, "user"))
+
+			if redemption.Status != StatusFailed || !strings.Contains(redemption.ErrorMessage, fmt.Sprintf("returned %d", tt.status)) {
+				t.Fatalf("redemption = %s (%s), want %s with the %d", redemption.Status, redemption.ErrorMessage, StatusFailed, tt.status)
+			}
+			// The saga stops at the failed call; a rejected deduction took no points to reverse
+			if got := services.paths(); len(got) != tt.wantCalls {
+				t.Fatalf("calls = %v, want the saga to stop after %s", got, path)
+			}
+		})
+	}
+}
+
+func TestSagaStepTimesOutSlowCatalog(t *testing.T) {
+	redemption := newTestRedemption()
+	s, services := newStepsService(t, redemption, nil, func(cfg *config.Config) {
+		cfg.Redemption.StepTimeout = 50 * time.Millisecond
+		cfg.Redemption.SagaTimeout = 5 * time.Second
+	})
+	services.hang = map[string]bool{"/v1/benefits/" + redemption.BenefitID: true}
+
+	start := time.Now()
+	s.processRedemptionSaga(context.Background(), redemption, "")
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("saga took %s, want it cut short by the step timeout", elapsed)
+	}
+	if redemption.Status != StatusTimedOut {
+		t.Fatalf("status = %s (%s), want %s", redemption.Status, redemption.ErrorMessage, StatusTimedOut)
+	}
+	if got := services.paths(); len(got) != 1 {
+		t.Fatalf("calls = %v, want only the catalog lookup", got)
+	}
+}
@@ -0,0 +1,187 @@
+package redemption
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+const (
+	// statusEvent is the SSE event name of a redemption status
+	statusEvent = "status"
+	// streamKeepAlive is how often an idle stream sends a comment, so
+	// proxies do not close it
+	streamKeepAlive = 15 * time.Second
+	// streamDeadlineMargin ends a stream this long before the request's
+	// deadline, so it closes cleanly instead of timing out
+	streamDeadlineMargin = time.Second
+	// streamRetry is the reconnect delay suggested to clients, in milliseconds
+	streamRetry = 1000
+)
+
+// statusHub passes redemption status changes made by this instance's sagas
+// to the streams watching them
+type statusHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan *Redemption]struct{}
+}
+
+func newStatusHub() *statusHub {
+	return &statusHub{subs: make(map[string]map[chan *Redemption]struct{})}
+}
+
+// subscribe returns a channel receiving the redemption's status changes, and
+// a function to stop them
+func (h *statusHub) subscribe(redemptionID string) (<-chan *Redemption, func()) {
+	ch := make(chan *Redemption, 1)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[redemptionID] == nil {
+		h.subs[redemptionID] = make(map[chan *Redemption]struct{})
+	}
+	h.subs[redemptionID][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[redemptionID], ch)
+		if len(h.subs[redemptionID]) == 0 {
+			delete(h.subs, redemptionID)
+		}
+	}
+}
+
+// publish sends a copy of redemption to its subscribers. A subscriber that
+// has not taken the previous change gets this one instead, as only the
+// latest status matters.
+func (h *statusHub) publish(redemption *Redemption) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[redemption.ID] {
+		update := *redemption
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- &update
+	}
+}
+
+// StreamRedemptionEvents streams a redemption's status as Server-Sent Events
+// until it reaches a terminal status, instead of clients polling for it. The
+// current status is sent first. Changes made by this instance's sagas are sent
+// as they happen, and the status is also polled so changes made by other
+// instances arrive too. Streams end before the server's write timeout; an
+// EventSource reconnects and gets the current status again.
+func (s *Service) StreamRedemptionEvents(w http.ResponseWriter, r *http.Request) {
+	redemptionID := chi.URLParam(r, "id")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Streaming unsupported")
+		return
+	}
+
+	// Subscribe before reading the status, so no change is missed in between
+	updates, unsubscribe := s.statuses.subscribe(redemptionID)
+	defer unsubscribe()
+
+	redemption, err := s.getRedemption(r.Context(), redemptionID)
+	if err != nil {
+		if errors.Is(err, errRedemptionNotFound) {
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Redemption not found")
+			return
+		}
+		s.logger.WithContext(r.Context()).Errorf("Failed to get redemption %s: %v", redemptionID, err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve redemption")
+		return
+	}
+	// Other users' redemptions are reported as missing rather than forbidden,
+	// so their IDs cannot be probed
+	if !canViewRedemption(r.Context(), redemption) {
+		platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Redemption not found")
+		return
+	}
+
+	ctx := r.Context()
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-streamDeadlineMargin))
+		defer cancel()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop nginx buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+
+	sent := ""
+	send := func(redemption *Redemption) bool {
+		if redemption.Status != sent {
+			if err := writeStatusEvent(w, s.redemptionStatus(ctx, redemption)); err != nil {
+				return false
+			}
+			flusher.Flush()
+			sent = redemption.Status
+		}
+		return redemption.Status == StatusRequested
+	}
+	if !send(redemption) {
+		return
+	}
+
+	var poll <-chan time.Time
+	if interval := s.config.Redemption.EventsPollInterval; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update := <-updates:
+			if !send(update) {
+				return
+			}
+		case <-poll:
+			current, err := s.getRedemption(ctx, redemptionID)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.WithContext(ctx).Warnf("Failed to poll redemption %s: %v", redemptionID, err)
+				}
+				continue
+			}
+			if !send(current) {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeStatusEvent writes status as an SSE event, with the status as its ID
+func writeStatusEvent(w http.ResponseWriter, status *RedemptionStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\nid: %s\ndata: %s\n\n", statusEvent, status.Status, data)
+	return err
+}
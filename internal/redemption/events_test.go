@@ -0,0 +1,211 @@
+package redemption
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/databasetest"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database/migrate"
+)
+
+// withTestDB migrates the test database for s. The test is skipped without
+// a database.
+func withTestDB(t *testing.T, s *Service) {
+	t.Helper()
+
+	db := databasetest.Open(t)
+	if err := migrate.Run(context.Background(), db, "redemption", Migrations, migrate.ModeApply, s.logger); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	s.SetDatabase(db)
+}
+
+// sseEvent is one event read from a Server-Sent Events stream
+type sseEvent struct {
+	event, id string
+	status    RedemptionStatus
+}
+
+// readEvent reads the next event from stream, skipping the retry hint and
+// keep-alive comments. It returns false once the stream has ended.
+func readEvent(t *testing.T, stream *bufio.Reader) (sseEvent, bool) {
+	t.Helper()
+
+	var event sseEvent
+	var data string
+	for {
+		line, err := stream.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return event, false
+		}
+		if err != nil {
+			t.Fatalf("failed to read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if data == "" {
+				continue
+			}
+			if err := json.Unmarshal([]byte(data), &event.status); err != nil {
+				t.Fatalf("event data is not a redemption status: %s", data)
+			}
+			return event, true
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// openEventStream subscribes to a redemption's events over HTTP as tok
+func openEventStream(t *testing.T, s *Service, redemptionID, tok string) *bufio.Reader {
+	t.Helper()
+
+	router := chi.NewRouter()
+	s.Routes(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/redemptions/"+redemptionID+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream: status %d, content type %q; want an event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+func TestStatusHubSendsLatestStatus(t *testing.T) {
+	hub := newStatusHub()
+	redemption := newTestRedemption()
+	updates, unsubscribe := hub.subscribe(redemption.ID)
+
+	// A subscriber that falls behind gets only the latest change
+	hub.publish(redemption)
+	completed := *redemption
+	completed.Status = StatusCompleted
+	hub.publish(&completed)
+	hub.publish(newTestRedemption())
+
+	select {
+	case update := <-updates:
+		if update.ID != redemption.ID || update.Status != StatusCompleted {
+			t.Fatalf("update = %s %s, want %s completed", update.ID, update.Status, redemption.ID)
+		}
+	default:
+		t.Fatal("no update received")
+	}
+	select {
+	case update := <-updates:
+		t.Fatalf("received a second update %s %s", update.ID, update.Status)
+	default:
+	}
+
+	unsubscribe()
+	if len(hub.subs) != 0 {
+		t.Fatalf("hub keeps %d redemptions after the last subscriber left", len(hub.subs))
+	}
+}
+
+func TestStreamRedemptionEventsAccess(t *testing.T) {
+	// Without a database every redemption belongs to user-123
+	s, _ := newTestService(t)
+	path := "/v1/redemptions/" + uuid.New().String() + "/events"
+
+	if rec := serve(s, http.MethodGet, path, "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := serve(s, http.MethodGet, path, token(t, s, "user-456", "user"), nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("other user: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// A redemption that has already finished sends its status and ends
+	rec := serve(s, http.MethodGet, path, token(t, s, "user-123", "user"), nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" || rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("owner: status %d, headers %v; want an uncached event stream", rec.Code, rec.Header())
+	}
+	stream := bufio.NewReader(rec.Body)
+	if line, _ := stream.ReadString('\n'); line != "retry: 1000\n" {
+		t.Fatalf("first line = %q, want the retry hint", line)
+	}
+	event, ok := readEvent(t, stream)
+	if !ok || event.event != statusEvent || event.id != StatusCompleted || event.status.Status != StatusCompleted || event.status.PartnerRef != "VENDOR-12345" {
+		t.Fatalf("event = %+v, want the completed status", event)
+	}
+	if event, ok := readEvent(t, stream); ok {
+		t.Fatalf("stream continued after a terminal status with %+v", event)
+	}
+}
+
+func TestStreamRedemptionEventsFollowsSaga(t *testing.T) {
+	s, _ := newGatewaySagaService(t, func(w http.ResponseWriter, r *http.Request) { fulfilled(w) }, func(cfg *config.Config) {
+		cfg.Redemption.EventsPollInterval = 0
+	})
+	withTestDB(t, s)
+	redemption := newTestRedemption()
+	if err := s.saveRedemption(context.Background(), redemption); err != nil {
+		t.Fatalf("failed to save redemption: %v", err)
+	}
+
+	stream := openEventStream(t, s, redemption.ID, token(t, s, redemption.UserID, "user"))
+	if event, ok := readEvent(t, stream); !ok || event.status.Status != StatusRequested {
+		t.Fatalf("first event = %+v, want the requested status", event)
+	}
+
+	// The saga's completion reaches the stream as it happens, without polling
+	go s.processRedemptionSaga(context.Background(), redemption, "Bearer user-token")
+	event, ok := readEvent(t, stream)
+	if !ok || event.id != StatusCompleted || event.status.ID != redemption.ID || event.status.PartnerRef != "GIFTCO-123" || event.status.CompletedAt == nil {
+		t.Fatalf("second event = %+v, want the completed redemption", event)
+	}
+	if event, ok := readEvent(t, stream); ok {
+		t.Fatalf("stream continued after completion with %+v", event)
+	}
+}
+
+func TestStreamRedemptionEventsPollsOtherInstances(t *testing.T) {
+	s, _ := newTestService(t, func(cfg *config.Config) { cfg.Redemption.EventsPollInterval = 10 * time.Millisecond })
+	withTestDB(t, s)
+	redemption := newTestRedemption()
+	ctx := context.Background()
+	if err := s.saveRedemption(ctx, redemption); err != nil {
+		t.Fatalf("failed to save redemption: %v", err)
+	}
+
+	stream := openEventStream(t, s, redemption.ID, token(t, s, redemption.UserID, "user"))
+	if event, ok := readEvent(t, stream); !ok || event.status.Status != StatusRequested {
+		t.Fatalf("first event = %+v, want the requested status", event)
+	}
+
+	// A saga on another instance fails the redemption
+	if err := s.db.Exec(ctx, `UPDATE redemptions SET status = $2, error_message = 'partner unavailable' WHERE id = $1`, redemption.ID, StatusFailed); err != nil {
+		t.Fatalf("failed to update redemption: %v", err)
+	}
+	event, ok := readEvent(t, stream)
+	if !ok || event.id != StatusFailed || event.status.ErrorMessage != "partner unavailable" {
+		t.Fatalf("second event = %+v, want the failed status", event)
+	}
+	if event, ok := readEvent(t, stream); ok {
+		t.Fatalf("stream continued after failure with %+v", event)
+	}
+}
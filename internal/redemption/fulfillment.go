@@ -0,0 +1,217 @@
+package redemption
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+// BenefitType identifies the fulfillment flow a benefit requires
+type BenefitType string
+
+const (
+	BenefitTypeGiftCard   BenefitType = "gift_card"
+	BenefitTypeTravel     BenefitType = "travel"
+	BenefitTypeExperience BenefitType = "experience"
+	BenefitTypeCashBack   BenefitType = "cash_back"
+	BenefitTypeDonation   BenefitType = "donation"
+)
+
+// BenefitTypeFromCategory maps a catalog category to its benefit type
+func BenefitTypeFromCategory(category string) BenefitType {
+	switch strings.ToLower(strings.TrimSpace(category)) {
+	case "travel":
+		return BenefitTypeTravel
+	case "entertainment", "health & wellness":
+		return BenefitTypeExperience
+	case "cash back":
+		return BenefitTypeCashBack
+	case "charity":
+		return BenefitTypeDonation
+	default:
+		// Retail, Dining, Technology and unknown categories are fulfilled as gift cards
+		return BenefitTypeGiftCard
+	}
+}
+
+// FulfillmentDetails holds the category-specific data a partner needs to fulfill a benefit
+type FulfillmentDetails struct {
+	Amount      int64      `json:"amount,omitempty"`
+	Currency    string     `json:"currency,omitempty"`
+	Recipient   string     `json:"recipient,omitempty"`
+	TravelStart *time.Time `json:"travel_start,omitempty"`
+	TravelEnd   *time.Time `json:"travel_end,omitempty"`
+	Travelers   int        `json:"travelers,omitempty"`
+	EventDate   *time.Time `json:"event_date,omitempty"`
+	AccountRef  string     `json:"account_ref,omitempty"`
+}
+
+// FulfillmentError describes a missing or invalid category-specific field
+type FulfillmentError struct {
+	BenefitType BenefitType `json:"benefit_type"`
+	Field       string      `json:"field"`
+	Message     string      `json:"message"`
+}
+
+func (e *FulfillmentError) Error() string {
+	return fmt.Sprintf("%s benefit: %s %s", e.BenefitType, e.Field, e.Message)
+}
+
+// writeFulfillmentError reports invalid fulfillment details as a validation
+// error on the offending field of the request's details
+func writeFulfillmentError(w http.ResponseWriter, r *http.Request, err error) {
+	var fieldErr *FulfillmentError
+	if !errors.As(err, &fieldErr) {
+		platformhttp.Error(w, r, http.StatusUnprocessableEntity, platformhttp.ErrCodeValidationFailed, err.Error())
+		return
+	}
+	field := "details"
+	if fieldErr.Field != field {
+		field += "." + fieldErr.Field
+	}
+	platformhttp.ValidationError(w, r, map[string]string{field: fieldErr.Message})
+}
+
+// benefitInfo is the subset of a catalog benefit the redemption saga relies on
+type benefitInfo struct {
+	ID       string
+	Name     string
+	Category string
+	Partner  string
+	Points   int
+	Currency string
+	Active   bool
+	StartsAt *time.Time
+	EndsAt   *time.Time
+	// Deleted is set when the catalog has soft-deleted the benefit
+	Deleted bool
+	// PartnerAvailable is cleared while the partner gateway's circuit is open
+	PartnerAvailable bool
+}
+
+// Type returns the benefit type derived from the benefit's category
+func (b *benefitInfo) Type() BenefitType {
+	return BenefitTypeFromCategory(b.Category)
+}
+
+// validateFulfillmentDetails checks the fields required by the given benefit type
+func validateFulfillmentDetails(benefit *benefitInfo, details *FulfillmentDetails, now time.Time) error {
+	benefitType := benefit.Type()
+	fieldErr := func(field, message string) error {
+		return &FulfillmentError{BenefitType: benefitType, Field: field, Message: message}
+	}
+
+	if details == nil {
+		if benefitType == BenefitTypeDonation {
+			return nil
+		}
+		return fieldErr("details", "are required")
+	}
+
+	switch benefitType {
+	case BenefitTypeGiftCard, BenefitTypeCashBack:
+		if details.Amount <= 0 {
+			return fieldErr("amount", "must be greater than zero")
+		}
+		if len(details.Currency) != 3 || strings.ToUpper(details.Currency) != details.Currency {
+			return fieldErr("currency", "must be a 3-letter ISO 4217 code")
+		}
+		if benefit.Currency != "" && details.Currency != benefit.Currency {
+			return fieldErr("currency", fmt.Sprintf("must be %s for this benefit", benefit.Currency))
+		}
+		if benefitType == BenefitTypeCashBack && details.AccountRef == "" {
+			return fieldErr("account_ref", "is required")
+		}
+	case BenefitTypeTravel:
+		if details.TravelStart == nil {
+			return fieldErr("travel_start", "is required")
+		}
+		if details.TravelEnd == nil {
+			return fieldErr("travel_end", "is required")
+		}
+		if !details.TravelEnd.After(*details.TravelStart) {
+			return fieldErr("travel_end", "must be after travel_start")
+		}
+		if details.TravelStart.Before(now) {
+			return fieldErr("travel_start", "must be in the future")
+		}
+		if details.Travelers < 1 {
+			return fieldErr("travelers", "must be at least 1")
+		}
+	case BenefitTypeExperience:
+		if details.EventDate == nil {
+			return fieldErr("event_date", "is required")
+		}
+		if details.EventDate.Before(now) {
+			return fieldErr("event_date", "must be in the future")
+		}
+	}
+
+	return nil
+}
+
+// partnerFulfillmentRequest is the payload sent to the partner gateway
+type partnerFulfillmentRequest struct {
+	RedemptionID string           `json:"redemption_id"`
+	Partner      string           `json:"partner"`
+	BenefitID    string           `json:"benefit_id"`
+	BenefitType  BenefitType      `json:"benefit_type"`
+	GiftCard     *giftCardPayload `json:"gift_card,omitempty"`
+	Travel       *travelPayload   `json:"travel,omitempty"`
+	Experience   *eventPayload    `json:"experience,omitempty"`
+	CashBack     *cashBackPayload `json:"cash_back,omitempty"`
+}
+
+type giftCardPayload struct {
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Recipient string `json:"recipient,omitempty"`
+}
+
+type travelPayload struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Travelers int       `json:"travelers"`
+}
+
+type eventPayload struct {
+	EventDate time.Time `json:"event_date"`
+}
+
+type cashBackPayload struct {
+	Amount     int64  `json:"amount"`
+	Currency   string `json:"currency"`
+	AccountRef string `json:"account_ref"`
+}
+
+// buildPartnerRequest builds the category-specific partner payload for a validated redemption
+func buildPartnerRequest(redemption *Redemption, benefit *benefitInfo) *partnerFulfillmentRequest {
+	req := &partnerFulfillmentRequest{
+		RedemptionID: redemption.ID,
+		Partner:      benefit.Partner,
+		BenefitID:    benefit.ID,
+		BenefitType:  benefit.Type(),
+	}
+
+	details := redemption.Details
+	if details == nil {
+		return req
+	}
+
+	switch req.BenefitType {
+	case BenefitTypeGiftCard:
+		req.GiftCard = &giftCardPayload{Amount: details.Amount, Currency: details.Currency, Recipient: details.Recipient}
+	case BenefitTypeTravel:
+		req.Travel = &travelPayload{Start: details.TravelStart.UTC(), End: details.TravelEnd.UTC(), Travelers: details.Travelers}
+	case BenefitTypeExperience:
+		req.Experience = &eventPayload{EventDate: details.EventDate.UTC()}
+	case BenefitTypeCashBack:
+		req.CashBack = &cashBackPayload{Amount: details.Amount, Currency: details.Currency, AccountRef: details.AccountRef}
+	}
+
+	return req
+}
@@ -0,0 +1,145 @@
+package redemption
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+)
+
+func TestBenefitTypeFromCategory(t *testing.T) {
+	tests := map[string]BenefitType{
+		"Retail":            BenefitTypeGiftCard,
+		"Dining":            BenefitTypeGiftCard,
+		"Technology":        BenefitTypeGiftCard,
+		"unknown":           BenefitTypeGiftCard,
+		"Travel":            BenefitTypeTravel,
+		" travel ":          BenefitTypeTravel,
+		"Entertainment":     BenefitTypeExperience,
+		"Health & Wellness": BenefitTypeExperience,
+		"Cash Back":         BenefitTypeCashBack,
+		"Charity":           BenefitTypeDonation,
+	}
+	for category, want := range tests {
+		if got := BenefitTypeFromCategory(category); got != want {
+			t.Errorf("BenefitTypeFromCategory(%q) = %q, want %q", category, got, want)
+		}
+	}
+}
+
+func TestValidateFulfillmentDetails(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(days int) *time.Time {
+		t := now.AddDate(0, 0, days)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		category  string
+		currency  string
+		details   *FulfillmentDetails
+		wantField string
+	}{
+		{"gift card", "Retail", "", &FulfillmentDetails{Amount: 25, Currency: "USD"}, ""},
+		{"gift card without details", "Retail", "", nil, "details"},
+		{"gift card without amount", "Retail", "", &FulfillmentDetails{Currency: "USD"}, "amount"},
+		{"gift card with lower case currency", "Retail", "", &FulfillmentDetails{Amount: 25, Currency: "usd"}, "currency"},
+		{"gift card in another currency", "Retail", "EUR", &FulfillmentDetails{Amount: 25, Currency: "USD"}, "currency"},
+		{"cash back", "Cash Back", "", &FulfillmentDetails{Amount: 10, Currency: "USD", AccountRef: "acct-1"}, ""},
+		{"cash back without account", "Cash Back", "", &FulfillmentDetails{Amount: 10, Currency: "USD"}, "account_ref"},
+		{"travel", "Travel", "", &FulfillmentDetails{TravelStart: at(10), TravelEnd: at(12), Travelers: 2}, ""},
+		{"travel without start", "Travel", "", &FulfillmentDetails{TravelEnd: at(12), Travelers: 2}, "travel_start"},
+		{"travel without end", "Travel", "", &FulfillmentDetails{TravelStart: at(10), Travelers: 2}, "travel_end"},
+		{"travel ending before it starts", "Travel", "", &FulfillmentDetails{TravelStart: at(12), TravelEnd: at(10), Travelers: 2}, "travel_end"},
+		{"travel in the past", "Travel", "", &FulfillmentDetails{TravelStart: at(-2), TravelEnd: at(1), Travelers: 2}, "travel_start"},
+		{"travel without travelers", "Travel", "", &FulfillmentDetails{TravelStart: at(10), TravelEnd: at(12)}, "travelers"},
+		{"experience", "Entertainment", "", &FulfillmentDetails{EventDate: at(5)}, ""},
+		{"experience without date", "Entertainment", "", &FulfillmentDetails{}, "event_date"},
+		{"experience in the past", "Entertainment", "", &FulfillmentDetails{EventDate: at(-1)}, "event_date"},
+		{"donation without details", "Charity", "", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			benefit := &benefitInfo{Category: tt.category, Currency: tt.currency}
+			err := validateFulfillmentDetails(benefit, tt.details, now)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("validateFulfillmentDetails: %v", err)
+				}
+				return
+			}
+
+			var fieldErr *FulfillmentError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("err = %v, want a FulfillmentError", err)
+			}
+			if fieldErr.Field != tt.wantField || fieldErr.BenefitType != benefit.Type() {
+				t.Fatalf("error on %s %s, want %s %s", fieldErr.BenefitType, fieldErr.Field, benefit.Type(), tt.wantField)
+			}
+		})
+	}
+}
+
+func TestBuildPartnerRequest(t *testing.T) {
+	start := time.Date(2026, 7, 1, 9, 0, 0, 0, time.FixedZone("EST", -5*3600))
+	end := start.AddDate(0, 0, 3)
+	details := &FulfillmentDetails{Amount: 50, Currency: "USD", AccountRef: "acct-1", TravelStart: &start, TravelEnd: &end, Travelers: 2, EventDate: &start}
+
+	tests := []struct {
+		category string
+		check    func(*partnerFulfillmentRequest) bool
+	}{
+		{"Retail", func(req *partnerFulfillmentRequest) bool {
+			return req.GiftCard != nil && req.GiftCard.Amount == 50 && req.Travel == nil && req.CashBack == nil
+		}},
+		{"Travel", func(req *partnerFulfillmentRequest) bool {
+			return req.Travel != nil && req.Travel.Start.Equal(start) && req.Travel.Start.Location() == time.UTC && req.GiftCard == nil
+		}},
+		{"Entertainment", func(req *partnerFulfillmentRequest) bool {
+			return req.Experience != nil && req.Experience.EventDate.Equal(start) && req.Travel == nil
+		}},
+		{"Cash Back", func(req *partnerFulfillmentRequest) bool {
+			return req.CashBack != nil && req.CashBack.AccountRef == "acct-1" && req.GiftCard == nil
+		}},
+		{"Charity", func(req *partnerFulfillmentRequest) bool {
+			return req.GiftCard == nil && req.Travel == nil && req.Experience == nil && req.CashBack == nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			redemption := &Redemption{ID: "redemption-1", Details: details}
+			benefit := &benefitInfo{ID: "benefit-1", Partner: "PARTNER", Category: tt.category}
+
+			req := buildPartnerRequest(redemption, benefit)
+			if req.BenefitType != benefit.Type() || req.RedemptionID != "redemption-1" || req.Partner != "PARTNER" {
+				t.Fatalf("request = %+v", req)
+			}
+			if !tt.check(req) {
+				body, _ := json.Marshal(req)
+				t.Fatalf("wrong payload for %s: %s", tt.category, body)
+			}
+		})
+	}
+}
+
+func TestWriteFulfillmentError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/redemptions", nil)
+	writeFulfillmentError(rec, req, &FulfillmentError{BenefitType: BenefitTypeTravel, Field: "travel_end", Message: "is required"})
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	var body platformhttp.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Code != platformhttp.ErrCodeValidationFailed || body.Fields["details.travel_end"] != "is required" {
+		t.Fatalf("body = %+v, want details.travel_end to be reported", body)
+	}
+}
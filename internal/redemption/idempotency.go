@@ -0,0 +1,89 @@
+package redemption
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+)
+
+// errIdempotencyKeyInUse is returned when saving a redemption whose
+// idempotency key the user already sent and has not expired
+var errIdempotencyKeyInUse = errors.New("idempotency key already used")
+
+// replayRedemption answers a request whose idempotency key is live with the
+// current status of the redemption the key created
+func replayRedemption(w http.ResponseWriter, r *http.Request, existing *Redemption) {
+	render.JSON(w, r, &RedemptionResponse{
+		RedemptionID: existing.ID,
+		Status:       existing.Status,
+		Message:      "Redemption already exists",
+	})
+}
+
+// claimIdempotencyKey records that the redemption's user sent its key, until
+// ttl from its creation. An expired claim is taken over; a live one fails the
+// claim with errIdempotencyKeyInUse. A concurrent claim of the same key waits
+// for the first to commit or roll back.
+func claimIdempotencyKey(ctx context.Context, tx pgx.Tx, redemption *Redemption, ttl time.Duration) error {
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO redemption_idempotency_keys (tenant_id, user_id, idempotency_key, redemption_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant_id, user_id, idempotency_key) DO UPDATE
+		SET redemption_id = EXCLUDED.redemption_id, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+		WHERE redemption_idempotency_keys.expires_at <= NOW()
+	`, auth.TenantFromContext(ctx), redemption.UserID, redemption.IdempotencyKey, redemption.ID,
+		redemption.CreatedAt, redemption.CreatedAt.Add(ttl))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return errIdempotencyKeyInUse
+	}
+	return nil
+}
+
+// RunIdempotencySweeper deletes expired idempotency keys every
+// redemption.idempotency_sweep_interval until ctx is cancelled. Expired keys
+// are already ignored, so the sweep only keeps the table from growing.
+func (s *Service) RunIdempotencySweeper(ctx context.Context) {
+	interval := s.config.Redemption.IdempotencySweepInterval
+	if interval <= 0 {
+		return
+	}
+	if s.db == nil {
+		s.logger.Warn("Database not initialized, idempotency key sweep not started")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.deleteExpiredIdempotencyKeys(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.logger.Errorf("Failed to delete expired idempotency keys: %v", err)
+			}
+			if deleted > 0 {
+				s.logger.Debugf("Deleted %d expired idempotency keys", deleted)
+			}
+		}
+	}
+}
+
+// deleteExpiredIdempotencyKeys deletes every expired key, across tenants
+func (s *Service) deleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	tag, err := s.db.GetPool().Exec(ctx, `DELETE FROM redemption_idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,187 @@
+package redemption
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// newIdempotencyService creates a database-backed service whose sagas
+// fulfill against fake services, waiting for them when the test ends. The
+// test is skipped without a database.
+func newIdempotencyService(t *testing.T, configure ...func(*config.Config)) *Service {
+	t.Helper()
+
+	s, _ := newGatewaySagaService(t, func(w http.ResponseWriter, r *http.Request) { fulfilled(w) }, configure...)
+	withTestDB(t, s)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+	})
+	return s
+}
+
+// redeemWithKey requests a redemption as userID with an Idempotency-Key
+func redeemWithKey(t *testing.T, s *Service, userID, key string) (int, *RedemptionResponse) {
+	t.Helper()
+
+	rec := serve(s, http.MethodPost, "/v1/redeem", token(t, s, userID, "user"),
+		RedemptionRequest{BenefitID: uuid.New().String(), Points: 2000, Details: &FulfillmentDetails{Amount: 25, Currency: "USD"}},
+		"Idempotency-Key", key)
+	var response RedemptionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return rec.Code, &response
+}
+
+// waitForStatus waits for a redemption's saga to leave it in status
+func waitForStatus(t *testing.T, s *Service, redemptionID, status string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		redemption, err := s.getRedemption(context.Background(), redemptionID)
+		if err != nil {
+			t.Fatalf("failed to get redemption: %v", err)
+		}
+		if redemption.Status == status {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("redemption is %s, want %s", redemption.Status, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// userRedemptions counts the redemptions saved for a user
+func userRedemptions(t *testing.T, s *Service, userID string) int {
+	t.Helper()
+
+	var n int
+	if err := s.db.QueryRow(context.Background(), `SELECT COUNT(*) FROM redemptions WHERE user_id = $1`, userID).Scan(&n); err != nil {
+		t.Fatalf("failed to count redemptions: %v", err)
+	}
+	return n
+}
+
+// expireKeys expires every idempotency key the user holds
+func expireKeys(t *testing.T, s *Service, userID string) {
+	t.Helper()
+
+	err := s.db.Exec(context.Background(), `
+		UPDATE redemption_idempotency_keys SET expires_at = NOW() - INTERVAL '1 second' WHERE user_id = $1`, userID)
+	if err != nil {
+		t.Fatalf("failed to expire keys: %v", err)
+	}
+}
+
+func TestRedeemRequiresIdempotencyKey(t *testing.T) {
+	s, _ := newTestService(t)
+
+	rec := serve(s, http.MethodPost, "/v1/redeem", token(t, s, uuid.New().String(), "user"),
+		RedemptionRequest{BenefitID: uuid.New().String(), Points: 2000, Details: &FulfillmentDetails{Amount: 25, Currency: "USD"}})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+}
+
+func TestIdempotencyKeyReplaysWithinTTL(t *testing.T) {
+	s := newIdempotencyService(t, func(cfg *config.Config) { cfg.Redemption.IdempotencyKeyTTL = time.Hour })
+	userID, key := uuid.New().String(), uuid.New().String()
+
+	status, first := redeemWithKey(t, s, userID, key)
+	if status != http.StatusAccepted || first.Status != StatusRequested {
+		t.Fatalf("first request: %d %+v, want 202 requested", status, first)
+	}
+	waitForStatus(t, s, first.RedemptionID, StatusCompleted)
+
+	// The key is kept for the configured TTL
+	var ttl int64
+	err := s.db.QueryRow(context.Background(), `
+		SELECT EXTRACT(EPOCH FROM expires_at - created_at)::BIGINT
+		FROM redemption_idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`, userID, key).Scan(&ttl)
+	if err != nil || time.Duration(ttl)*time.Second != time.Hour {
+		t.Fatalf("key TTL = %ds (%v), want 1h", ttl, err)
+	}
+
+	// A replay returns the stored redemption as it is now, creating nothing
+	status, replay := redeemWithKey(t, s, userID, key)
+	if status != http.StatusOK || replay.RedemptionID != first.RedemptionID || replay.Status != StatusCompleted {
+		t.Fatalf("replay: %d %+v, want 200 with %s completed", status, replay, first.RedemptionID)
+	}
+	if n := userRedemptions(t, s, userID); n != 1 {
+		t.Fatalf("user has %d redemptions after a replay, want 1", n)
+	}
+
+	// Keys are scoped to the user, so another user's same key is new
+	otherUser := uuid.New().String()
+	status, other := redeemWithKey(t, s, otherUser, key)
+	if status != http.StatusAccepted || other.RedemptionID == first.RedemptionID {
+		t.Fatalf("other user: %d %+v, want a new redemption", status, other)
+	}
+	waitForStatus(t, s, other.RedemptionID, StatusCompleted)
+}
+
+func TestIdempotencyKeyAfterExpiry(t *testing.T) {
+	s := newIdempotencyService(t)
+	userID, key := uuid.New().String(), uuid.New().String()
+
+	_, first := redeemWithKey(t, s, userID, key)
+	waitForStatus(t, s, first.RedemptionID, StatusCompleted)
+	expireKeys(t, s, userID)
+
+	// An expired key creates a fresh redemption, which the key then replays
+	status, fresh := redeemWithKey(t, s, userID, key)
+	if status != http.StatusAccepted || fresh.RedemptionID == first.RedemptionID || fresh.Status != StatusRequested {
+		t.Fatalf("after expiry: %d %+v, want 202 with a new redemption", status, fresh)
+	}
+	waitForStatus(t, s, fresh.RedemptionID, StatusCompleted)
+	if status, replay := redeemWithKey(t, s, userID, key); status != http.StatusOK || replay.RedemptionID != fresh.RedemptionID {
+		t.Fatalf("replay after reuse: %d %+v, want 200 with %s", status, replay, fresh.RedemptionID)
+	}
+	if n := userRedemptions(t, s, userID); n != 2 {
+		t.Fatalf("user has %d redemptions, want 2", n)
+	}
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	s := newIdempotencyService(t)
+	expiredUser, liveUser := uuid.New().String(), uuid.New().String()
+
+	_, expired := redeemWithKey(t, s, expiredUser, uuid.New().String())
+	_, live := redeemWithKey(t, s, liveUser, uuid.New().String())
+	waitForStatus(t, s, expired.RedemptionID, StatusCompleted)
+	waitForStatus(t, s, live.RedemptionID, StatusCompleted)
+	expireKeys(t, s, expiredUser)
+
+	deleted, err := s.deleteExpiredIdempotencyKeys(context.Background())
+	if err != nil || deleted < 1 {
+		t.Fatalf("deleteExpiredIdempotencyKeys = %d, %v; want the expired key deleted", deleted, err)
+	}
+
+	keys := func(userID string) int {
+		var n int
+		if err := s.db.QueryRow(context.Background(), `SELECT COUNT(*) FROM redemption_idempotency_keys WHERE user_id = $1`, userID).Scan(&n); err != nil {
+			t.Fatalf("failed to count keys: %v", err)
+		}
+		return n
+	}
+	if keys(expiredUser) != 0 || keys(liveUser) != 1 {
+		t.Fatalf("keys left: expired user %d, live user %d; want 0 and 1", keys(expiredUser), keys(liveUser))
+	}
+
+	// Sweeping a key leaves the redemption it created
+	if n := userRedemptions(t, s, expiredUser); n != 1 {
+		t.Fatalf("expired user has %d redemptions after the sweep, want 1", n)
+	}
+}
@@ -0,0 +1,118 @@
+package redemption
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// Saga steps used as metric labels
+const (
+	stepValidateBenefit    = "validate_benefit"
+	stepCheckPoints        = "check_points"
+	stepReservePoints      = "reserve_points"
+	stepPartnerFulfillment = "partner_fulfillment"
+)
+
+// Compensating actions used as metric labels
+const (
+	compensationReverseDeduction = "reverse_deduction"
+	compensationReleaseHold      = "release_hold"
+	compensationCaptureHold      = "capture_hold"
+)
+
+var (
+	sagaFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "redemption_saga_failures_total",
+		Help: "Redemption saga failures by step and reason.",
+	}, []string{"step", "reason"})
+
+	// sagaCompensationFailures counts redemptions left inconsistent, e.g. points
+	// deducted but the benefit neither fulfilled nor refunded. Any increase
+	// needs operator attention.
+	sagaCompensationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "redemption_saga_compensation_failures_total",
+		Help: "Redemption saga compensating actions that failed and need manual reconciliation.",
+	}, []string{"action"})
+
+	partnerCircuitState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redemption_partner_circuit_state",
+		Help: "Partner gateway circuit breaker state: 0 closed, 1 half-open, 2 open.",
+	})
+
+	partnerCircuitTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "redemption_partner_circuit_transitions_total",
+		Help: "Partner gateway circuit breaker state changes, by the state entered.",
+	}, []string{"state"})
+)
+
+// partnerCircuitChanged records a partner gateway circuit breaker state change
+func (s *Service) partnerCircuitChanged(from, to breakerState) {
+	partnerCircuitState.Set(float64(to))
+	partnerCircuitTransitions.WithLabelValues(to.String()).Inc()
+	if to == breakerOpen {
+		s.logger.Warnf("Partner gateway circuit opened (was %s); failing fulfillments fast", from)
+	} else {
+		s.logger.Infof("Partner gateway circuit %s (was %s)", to, from)
+	}
+}
+
+// recordSagaFailure counts a failed saga step
+func recordSagaFailure(step string, err error) {
+	sagaFailures.WithLabelValues(step, failureReason(err)).Inc()
+}
+
+// recordCompensationFailure counts a failed compensating action and logs it
+// with a stable field operators can alert on
+func (s *Service) recordCompensationFailure(action string, redemption *Redemption, err error) {
+	sagaCompensationFailures.WithLabelValues(action).Inc()
+	s.logger.WithFields(logrus.Fields{
+		"alert":         "redemption_compensation_failed",
+		"action":        action,
+		"redemption_id": redemption.ID,
+		"user_id":       redemption.UserID,
+		"points":        redemption.Points,
+		"hold_id":       redemption.HoldID,
+	}).Errorf("CRITICAL: compensation %s failed for redemption %s, manual reconciliation required: %v", action, redemption.ID, err)
+}
+
+// failureReason maps a saga error to a low-cardinality metric label
+func failureReason(err error) string {
+	var fulfillmentErr *FulfillmentError
+	if errors.As(err, &fulfillmentErr) {
+		return "invalid_details"
+	}
+
+	var availErr *AvailabilityError
+	if errors.As(err, &availErr) {
+		return string(availErr.Reason)
+	}
+
+	var partnerErr *partnerError
+	if errors.As(err, &partnerErr) {
+		if isPartnerOutage(partnerErr) {
+			return string(ReasonPartnerUnavailable)
+		}
+		return "partner_rejected"
+	}
+
+	if errors.Is(err, errCircuitOpen) {
+		return "circuit_open"
+	}
+
+	if errors.Is(err, errInsufficientPoints) {
+		return "insufficient_points"
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	if errors.Is(err, context.Canceled) {
+		return "interrupted"
+	}
+
+	return "error"
+}
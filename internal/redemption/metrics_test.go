@@ -0,0 +1,138 @@
+package redemption
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// counterValue reads a counter from the default registry, or 0 if it has not
+// been incremented with labels yet
+func counterValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if want, ok := labels[label.GetName()]; ok && want != label.GetValue() {
+					continue metrics
+				}
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+// newReversalSagaService creates a service that deducts points outright and
+// whose partner rejects every fulfillment; reverseStatus answers the reversal
+func newReversalSagaService(t *testing.T, reverseStatus int) (*Service, *test.Hook, *int32) {
+	t.Helper()
+
+	var reversals int32
+	loyalty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/loyalty/balance":
+			writeLoyaltyData(w, map[string]int{"available_points": 10000})
+		case "/v1/loyalty/internal/deduct":
+			writeLoyaltyData(w, nil)
+		case "/v1/loyalty/internal/reverse":
+			atomic.AddInt32(&reversals, 1)
+			if reverseStatus != http.StatusOK {
+				w.WriteHeader(reverseStatus)
+				return
+			}
+			writeLoyaltyData(w, nil)
+		default:
+			t.Errorf("unexpected loyalty call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(loyalty.Close)
+	partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"code":"UNPROCESSABLE","message":"Unsupported benefit type"}`))
+	}))
+	t.Cleanup(partner.Close)
+
+	s, _ := newTestService(t, func(cfg *config.Config) {
+		cfg.Redemption.PointsHolds = false
+		cfg.Services.LoyaltyURL = loyalty.URL
+		cfg.Services.PartnerGatewayURL = partner.URL
+	})
+	logger, hook := test.NewNullLogger()
+	s.logger = logger
+	return s, hook, &reversals
+}
+
+func TestReversalFailureIsCritical(t *testing.T) {
+	s, hook, reversals := newReversalSagaService(t, http.StatusInternalServerError)
+	critical := map[string]string{"action": compensationReverseDeduction}
+	partnerRejected := map[string]string{"step": stepPartnerFulfillment, "reason": "partner_rejected"}
+	criticalBefore := counterValue(t, "redemption_saga_compensation_failures_total", critical)
+	failuresBefore := counterValue(t, "redemption_saga_failures_total", partnerRejected)
+	redemption := newTestRedemption()
+
+	s.processRedemptionSaga(context.Background(), redemption, "Bearer user-token")
+
+	if redemption.Status != StatusFailed {
+		t.Fatalf("status = %q, want %q", redemption.Status, StatusFailed)
+	}
+	if atomic.LoadInt32(reversals) == 0 {
+		t.Fatal("deduction never reversed")
+	}
+	if got := counterValue(t, "redemption_saga_compensation_failures_total", critical) - criticalBefore; got != 1 {
+		t.Errorf("compensation failures increased by %v, want 1", got)
+	}
+	if got := counterValue(t, "redemption_saga_failures_total", partnerRejected) - failuresBefore; got != 1 {
+		t.Errorf("partner fulfillment failures increased by %v, want 1", got)
+	}
+
+	var alert *logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["alert"] == "redemption_compensation_failed" {
+			alert = entry
+		}
+	}
+	if alert == nil {
+		t.Fatal("no alert logged for the failed reversal")
+	}
+	if alert.Level != logrus.ErrorLevel || alert.Data["redemption_id"] != redemption.ID || alert.Data["action"] != compensationReverseDeduction {
+		t.Errorf("alert = %v %v, want an error naming the redemption and action", alert.Level, alert.Data)
+	}
+}
+
+func TestReversedDeductionIsNotCritical(t *testing.T) {
+	s, hook, reversals := newReversalSagaService(t, http.StatusOK)
+	critical := map[string]string{"action": compensationReverseDeduction}
+	before := counterValue(t, "redemption_saga_compensation_failures_total", critical)
+
+	s.processRedemptionSaga(context.Background(), newTestRedemption(), "Bearer user-token")
+
+	if atomic.LoadInt32(reversals) != 1 {
+		t.Fatalf("reversals = %d, want 1", atomic.LoadInt32(reversals))
+	}
+	if got := counterValue(t, "redemption_saga_compensation_failures_total", critical) - before; got != 0 {
+		t.Errorf("compensation failures increased by %v after a successful reversal", got)
+	}
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["alert"] != nil {
+			t.Errorf("alert logged after a successful reversal: %s", entry.Message)
+		}
+	}
+}
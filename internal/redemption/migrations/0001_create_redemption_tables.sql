@@ -0,0 +1,61 @@
+-- Redemptions and the outbox their events are relayed from. Users and
+-- benefits belong to other services, so they are not foreign keys here.
+
+CREATE TABLE IF NOT EXISTS redemptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id UUID NOT NULL,
+    benefit_id UUID NOT NULL,
+    points INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'requested',
+    idempotency_key VARCHAR(255) UNIQUE NOT NULL,
+    benefit_type VARCHAR(32),
+    details JSONB,
+    partner_ref VARCHAR(255),
+    failure_reason VARCHAR(32),
+    hold_id VARCHAR(255),
+    partner_attempts INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    aggregate VARCHAR(100) NOT NULL,
+    aggregate_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    topic VARCHAR(100) NOT NULL,
+    message_key VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- started_at is set when a relay claims the row; rows with started_at but
+    -- no sent_at long after it are stuck
+    started_at TIMESTAMPTZ,
+    sent_at TIMESTAMPTZ,
+    retry_count INTEGER NOT NULL DEFAULT 0,
+    max_retries INTEGER NOT NULL DEFAULT 3
+);
+
+-- W3C trace context of the saga that queued the message; added separately
+-- for databases created before it existed
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS traceparent VARCHAR(55);
+
+CREATE INDEX IF NOT EXISTS idx_redemptions_user_id ON redemptions(tenant_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_redemptions_status ON redemptions(status);
+CREATE INDEX IF NOT EXISTS idx_redemptions_created_at ON redemptions(created_at);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_unsent ON outbox(id) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_started_at ON outbox(started_at) WHERE sent_at IS NULL;
+
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE OR REPLACE TRIGGER update_redemptions_updated_at BEFORE UPDATE ON redemptions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+-- The ID of the request whose saga queued a message, forwarded with the
+-- message so its consumers' logs can be correlated with the redemption
+
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS request_id VARCHAR(255);
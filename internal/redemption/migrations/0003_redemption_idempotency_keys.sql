@@ -0,0 +1,26 @@
+-- Idempotency keys are scoped to the user who sent them and expire, so a key
+-- replays the redemption it created only until expires_at. Expired keys are
+-- swept, and may then be reused to create a new redemption.
+
+CREATE TABLE IF NOT EXISTS redemption_idempotency_keys (
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+    user_id UUID NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    redemption_id UUID NOT NULL REFERENCES redemptions(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tenant_id, user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_redemption_idempotency_keys_expires_at ON redemption_idempotency_keys(expires_at);
+
+-- Keys of existing redemptions expire after the default TTL of 24 hours
+INSERT INTO redemption_idempotency_keys (tenant_id, user_id, idempotency_key, redemption_id, created_at, expires_at)
+SELECT tenant_id, user_id, idempotency_key, id, created_at, created_at + INTERVAL '24 hours'
+FROM redemptions
+WHERE created_at > NOW() - INTERVAL '24 hours'
+ON CONFLICT DO NOTHING;
+
+-- The key a redemption was created with is kept on it, but is no longer
+-- unique: another user may send the same key, and an expired key may be reused
+ALTER TABLE redemptions DROP CONSTRAINT IF EXISTS redemptions_idempotency_key_key;
@@ -0,0 +1,268 @@
+package redemption
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/httputil"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
+)
+
+// Outbox aggregate and event types for redemption events
+const (
+	outboxAggregateRedemption = "redemption"
+	outboxEventCompleted      = "redemption.completed"
+	outboxEventFailed         = "redemption.failed"
+)
+
+// redemptionEventSchemaVersion is the schema version of the redemption
+// events, matching the v1 topics they are published to
+const redemptionEventSchemaVersion = "1"
+
+// redemptionEventHeaders returns the headers stamped on a redemption event,
+// so consumers can route it without decoding the body
+func redemptionEventHeaders(eventType string) map[string]string {
+	return map[string]string{
+		messaging.HeaderEventType:     eventType,
+		messaging.HeaderSchemaVersion: redemptionEventSchemaVersion,
+	}
+}
+
+// recordOutcome saves a redemption's final state and queues its event in the
+// outbox in a single transaction, so the event is published even if the
+// process stops before it reaches Kafka
+func (s *Service) recordOutcome(ctx context.Context, redemption *Redemption, eventType, topic string, event interface{}) error {
+	if s.db == nil {
+		// Without a database there is no outbox to relay from, so publish directly
+		s.logger.Infof("Would update redemption: %+v", redemption)
+		s.statuses.publish(redemption)
+		switch e := event.(type) {
+		case *RedemptionCompletedEvent:
+			return s.emitRedemptionCompletedEvent(ctx, e)
+		case *RedemptionFailedEvent:
+			return s.emitRedemptionFailedEvent(ctx, e)
+		}
+		return fmt.Errorf("unknown redemption event %T", event)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateRedemption(ctx, tx, redemption); err != nil {
+		return fmt.Errorf("failed to update redemption: %w", err)
+	}
+
+	// Keep the saga's trace context and request ID so the relayed message
+	// continues its trace and can be correlated with the request
+	var traceparent string
+	if span := tracing.SpanFromContext(ctx); span != nil {
+		traceparent = tracing.FormatTraceparent(span.SpanContext())
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox (aggregate, aggregate_id, event_type, payload, topic, message_key, traceparent, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))
+	`, outboxAggregateRedemption, redemption.ID, eventType, payload, topic, redemption.UserID, traceparent,
+		httputil.RequestIDFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to queue %s event: %w", eventType, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	s.statuses.publish(redemption)
+	return nil
+}
+
+// RunOutboxRelay publishes queued outbox messages to Kafka until ctx is cancelled
+func (s *Service) RunOutboxRelay(ctx context.Context) {
+	interval := s.config.Redemption.Outbox.PollInterval
+	if interval <= 0 {
+		return
+	}
+	if s.db == nil || s.kafka == nil {
+		s.logger.Warn("Database or Kafka not initialized, outbox relay not started")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := s.relayOutbox(ctx)
+			if err != nil && ctx.Err() == nil {
+				s.logger.Errorf("Failed to relay outbox messages: %v", err)
+			}
+			if sent > 0 {
+				s.logger.Debugf("Relayed %d outbox messages", sent)
+			}
+		}
+	}
+}
+
+// relayOutbox publishes one batch of unsent messages in order. On the first
+// failure the rest of the batch is released for the next poll, so messages
+// for a user are never published out of order. Messages are marked sent only
+// once the producer has flushed them, as an async producer returns from a
+// send before the message is delivered.
+func (s *Service) relayOutbox(ctx context.Context) (int, error) {
+	messages, err := s.claimOutboxMessages(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var sendErr error
+	sent := messages
+	for i, message := range messages {
+		if message.Attempts > 1 {
+			s.logger.Warnf("Retrying outbox message %d for %s %s (attempt %d, queued %s)",
+				message.ID, message.Aggregate, message.AggregateID, message.Attempts, message.CreatedAt.Format(time.RFC3339))
+		}
+
+		sendCtx := ctx
+		if sc, ok := tracing.ParseTraceparent(message.Traceparent); ok {
+			sendCtx = tracing.ContextWithRemoteParent(ctx, sc)
+		}
+		sendCtx = httputil.ContextWithRequestID(sendCtx, message.RequestID)
+		err := s.kafka.SendJSONMessageWithHeaders(sendCtx, message.Topic, []byte(message.Key), message.Payload, redemptionEventHeaders(message.EventType))
+		if err != nil {
+			if relErr := s.releaseOutboxMessages(ctx, messages[i:]); relErr != nil {
+				s.logger.Errorf("Failed to release outbox messages: %v", relErr)
+			}
+			sendErr = fmt.Errorf("failed to publish outbox message %d: %w", message.ID, err)
+			sent = messages[:i]
+			break
+		}
+	}
+
+	if err := s.kafka.Flush(ctx); err != nil {
+		// Some may have been delivered; the outbox is at-least-once, so
+		// release them all to be published again
+		if relErr := s.releaseOutboxMessages(ctx, sent); relErr != nil {
+			s.logger.Errorf("Failed to release outbox messages: %v", relErr)
+		}
+		return 0, errors.Join(sendErr, fmt.Errorf("failed to flush outbox messages: %w", err))
+	}
+
+	for i, message := range sent {
+		// If this fails the message is published again once its claim times out
+		if err := s.db.Exec(ctx, `UPDATE outbox SET sent_at = NOW() WHERE id = $1`, message.ID); err != nil {
+			return i, fmt.Errorf("failed to mark outbox message %d sent: %w", message.ID, err)
+		}
+	}
+
+	return len(sent), sendErr
+}
+
+// claimOutboxMessages claims the oldest unsent messages, including ones whose
+// previous claim timed out. SKIP LOCKED lets several relays poll side by side.
+func (s *Service) claimOutboxMessages(ctx context.Context) ([]*OutboxMessage, error) {
+	cfg := s.config.Redemption.Outbox
+
+	rows, err := s.db.Query(ctx, `
+		WITH claimed AS (
+			SELECT id FROM outbox
+			WHERE sent_at IS NULL
+				AND (started_at IS NULL OR started_at < NOW() - $2 * INTERVAL '1 second')
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outbox o
+		SET started_at = NOW(), retry_count = o.retry_count + 1
+		FROM claimed
+		WHERE o.id = claimed.id
+		RETURNING o.id, o.aggregate, o.aggregate_id, o.event_type, COALESCE(o.message_key, ''),
+			o.payload, o.topic, o.retry_count, o.created_at, COALESCE(o.traceparent, ''), COALESCE(o.request_id, '')
+	`, cfg.BatchSize, cfg.ClaimTimeout.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*OutboxMessage{}
+	for rows.Next() {
+		var message OutboxMessage
+		err := rows.Scan(&message.ID, &message.Aggregate, &message.AggregateID, &message.EventType, &message.Key,
+			&message.Payload, &message.Topic, &message.Attempts, &message.CreatedAt, &message.Traceparent,
+			&message.RequestID)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, &message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// UPDATE ... RETURNING does not preserve the claim order
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+
+	return messages, nil
+}
+
+// releaseOutboxMessages clears the claim on messages that were not published
+func (s *Service) releaseOutboxMessages(ctx context.Context, messages []*OutboxMessage) error {
+	ids := make([]int64, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+
+	return s.db.Exec(ctx, `UPDATE outbox SET started_at = NULL WHERE id = ANY($1) AND sent_at IS NULL`, ids)
+}
+
+// saveRedemptionState saves the redemption's saga state without queuing an event
+func (s *Service) saveRedemptionState(ctx context.Context, redemption *Redemption) error {
+	if s.db == nil {
+		s.logger.Infof("Would update redemption: %+v", redemption)
+		s.statuses.publish(redemption)
+		return nil
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := updateRedemption(ctx, tx, redemption); err != nil {
+		return fmt.Errorf("failed to update redemption: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	s.statuses.publish(redemption)
+	return nil
+}
+
+// updateRedemption saves the mutable saga state of a redemption
+func updateRedemption(ctx context.Context, tx pgx.Tx, redemption *Redemption) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE redemptions
+		SET status = $2, partner_ref = NULLIF($3, ''), failure_reason = NULLIF($4, ''),
+			hold_id = NULLIF($5, ''), partner_attempts = $6, error_message = NULLIF($7, ''),
+			updated_at = $8, completed_at = $9
+		WHERE id = $1
+	`, redemption.ID, redemption.Status, redemption.PartnerRef, string(redemption.FailureReason),
+		redemption.HoldID, redemption.PartnerAttempts, redemption.ErrorMessage, redemption.UpdatedAt, redemption.CompletedAt)
+	return err
+}
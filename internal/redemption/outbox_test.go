@@ -0,0 +1,205 @@
+package redemption
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging/messagingtest"
+)
+
+// newOutboxService creates a service with a database whose relay claims up
+// to 1000 messages at a time
+func newOutboxService(t *testing.T) (*Service, *messagingtest.FakeProducer) {
+	t.Helper()
+
+	s, producer := newTestService(t, func(cfg *config.Config) {
+		cfg.Redemption.Outbox = config.OutboxConfig{PollInterval: 10 * time.Millisecond, BatchSize: 1000, ClaimTimeout: time.Minute}
+	})
+	withTestDB(t, s)
+	return s, producer
+}
+
+// completeWithEvent saves redemption, then completes it through the outbox,
+// returning the queued event's ID
+func completeWithEvent(t *testing.T, s *Service, redemption *Redemption) string {
+	t.Helper()
+
+	ctx := context.Background()
+	if err := s.saveRedemption(ctx, redemption); err != nil {
+		t.Fatalf("failed to save redemption: %v", err)
+	}
+	redemption.Status = StatusCompleted
+	redemption.PartnerRef = "GIFTCO-123"
+	event := &RedemptionCompletedEvent{EventID: uuid.New().String(), UserID: redemption.UserID, BenefitID: redemption.BenefitID,
+		Points: redemption.Points, PartnerRef: redemption.PartnerRef, Timestamp: time.Now()}
+	if err := s.recordOutcome(ctx, redemption, outboxEventCompleted, s.config.Kafka.Topics.RedemptionComplete, event); err != nil {
+		t.Fatalf("recordOutcome: %v", err)
+	}
+	return event.EventID
+}
+
+// publishedEvents returns the IDs of the completed events published for userID, in order
+func publishedEvents(t *testing.T, producer *messagingtest.FakeProducer, userID string) []string {
+	t.Helper()
+
+	var ids []string
+	for _, msg := range producer.Messages() {
+		if string(msg.Key) != userID {
+			continue
+		}
+		var event RedemptionCompletedEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		ids = append(ids, event.EventID)
+	}
+	return ids
+}
+
+// outboxState reads whether the outbox message for redemptionID is claimed
+// and sent, and how many times it was claimed
+func outboxState(t *testing.T, s *Service, redemptionID string) (claimed, sent bool, attempts int) {
+	t.Helper()
+
+	err := s.db.QueryRow(context.Background(), `
+		SELECT started_at IS NOT NULL, sent_at IS NOT NULL, retry_count FROM outbox WHERE aggregate_id = $1
+	`, redemptionID).Scan(&claimed, &sent, &attempts)
+	if err != nil {
+		t.Fatalf("failed to read outbox message: %v", err)
+	}
+	return claimed, sent, attempts
+}
+
+func TestRecordOutcomeQueuesEventWithRedemption(t *testing.T) {
+	s, producer := newOutboxService(t)
+	redemption := newTestRedemption()
+	completeWithEvent(t, s, redemption)
+
+	saved, err := s.getRedemption(context.Background(), redemption.ID)
+	if err != nil {
+		t.Fatalf("getRedemption: %v", err)
+	}
+	if saved.Status != StatusCompleted || saved.PartnerRef != "GIFTCO-123" {
+		t.Fatalf("saved redemption = %s %q, want completed with its partner reference", saved.Status, saved.PartnerRef)
+	}
+
+	// The event waits in the outbox instead of going straight to Kafka
+	if claimed, sent, _ := outboxState(t, s, redemption.ID); claimed || sent {
+		t.Fatalf("outbox message claimed = %v, sent = %v; want it queued", claimed, sent)
+	}
+	if got := publishedEvents(t, producer, redemption.UserID); len(got) != 0 {
+		t.Fatalf("published %v before the relay ran", got)
+	}
+}
+
+func TestRelayOutboxPublishesInOrderOnce(t *testing.T) {
+	s, producer := newOutboxService(t)
+	first, second := newTestRedemption(), newTestRedemption()
+	second.UserID = first.UserID
+	want := []string{completeWithEvent(t, s, first), completeWithEvent(t, s, second)}
+
+	if _, err := s.relayOutbox(context.Background()); err != nil {
+		t.Fatalf("relayOutbox: %v", err)
+	}
+	if got := publishedEvents(t, producer, first.UserID); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	for _, redemption := range []*Redemption{first, second} {
+		if _, sent, _ := outboxState(t, s, redemption.ID); !sent {
+			t.Fatalf("outbox message for %s not marked sent", redemption.ID)
+		}
+	}
+
+	// Sent messages are not published again
+	if _, err := s.relayOutbox(context.Background()); err != nil {
+		t.Fatalf("second relayOutbox: %v", err)
+	}
+	if got := publishedEvents(t, producer, first.UserID); len(got) != 2 {
+		t.Fatalf("published %v after a second relay, want the two events once", got)
+	}
+}
+
+func TestRelayOutboxReleasesUnpublishedMessages(t *testing.T) {
+	s, producer := newOutboxService(t)
+	redemption := newTestRedemption()
+	eventID := completeWithEvent(t, s, redemption)
+
+	producer.FailWith(errors.New("broker unavailable"))
+	if _, err := s.relayOutbox(context.Background()); err == nil {
+		t.Fatal("relayOutbox succeeded with the broker down")
+	}
+	if claimed, sent, _ := outboxState(t, s, redemption.ID); claimed || sent {
+		t.Fatalf("after a failed publish: claimed = %v, sent = %v; want the message released", claimed, sent)
+	}
+
+	// The next poll publishes it
+	producer.FailWith(nil)
+	if _, err := s.relayOutbox(context.Background()); err != nil {
+		t.Fatalf("relayOutbox: %v", err)
+	}
+	if got := publishedEvents(t, producer, redemption.UserID); len(got) != 1 || got[0] != eventID {
+		t.Fatalf("published %v, want [%s]", got, eventID)
+	}
+	if _, sent, attempts := outboxState(t, s, redemption.ID); !sent || attempts != 2 {
+		t.Fatalf("sent = %v after %d attempts, want sent on the second", sent, attempts)
+	}
+}
+
+func TestRelayOutboxReclaimsStuckMessages(t *testing.T) {
+	s, producer := newOutboxService(t)
+	stuck, claimed := newTestRedemption(), newTestRedemption()
+	completeWithEvent(t, s, stuck)
+	completeWithEvent(t, s, claimed)
+
+	// One relay claimed both and stopped; only the older claim has timed out
+	ctx := context.Background()
+	if err := s.db.Exec(ctx, `UPDATE outbox SET started_at = NOW() - INTERVAL '1 hour' WHERE aggregate_id = $1`, stuck.ID); err != nil {
+		t.Fatalf("failed to claim message: %v", err)
+	}
+	if err := s.db.Exec(ctx, `UPDATE outbox SET started_at = NOW() WHERE aggregate_id = $1`, claimed.ID); err != nil {
+		t.Fatalf("failed to claim message: %v", err)
+	}
+
+	if _, err := s.relayOutbox(ctx); err != nil {
+		t.Fatalf("relayOutbox: %v", err)
+	}
+	if got := publishedEvents(t, producer, stuck.UserID); len(got) != 1 {
+		t.Fatalf("stuck message published %d times, want once", len(got))
+	}
+	if got := publishedEvents(t, producer, claimed.UserID); len(got) != 0 {
+		t.Fatalf("message with a live claim published %d times, want none", len(got))
+	}
+}
+
+func TestRunOutboxRelayStopsWithContext(t *testing.T) {
+	s, producer := newOutboxService(t)
+	redemption := newTestRedemption()
+	completeWithEvent(t, s, redemption)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.RunOutboxRelay(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(publishedEvents(t, producer, redemption.UserID)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("relay did not publish the queued event")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay still running after its context was cancelled")
+	}
+}
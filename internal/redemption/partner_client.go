@@ -0,0 +1,151 @@
+package redemption
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/httputil"
+)
+
+// serviceName identifies the redemption service in service tokens
+const serviceName = "redemption-svc"
+
+// partnerClient calls the partner gateway service
+type partnerClient struct {
+	baseURL    string
+	httpClient *http.Client
+	jwtManager *auth.JWTManager
+	// breaker fails calls fast while the gateway is down; nil when disabled
+	breaker *circuitBreaker
+}
+
+// partnerError is returned when the partner gateway rejects a fulfillment
+type partnerError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *partnerError) Error() string {
+	return fmt.Sprintf("partner gateway returned %d: %s", e.StatusCode, e.Message)
+}
+
+// isPartnerOutage reports whether the partner failed rather than rejected the request
+func isPartnerOutage(err *partnerError) bool {
+	return err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= 500
+}
+
+// isRetryablePartnerError reports whether a failed fulfillment attempt may
+// succeed if retried: partner outages, network errors, and attempts that
+// timed out. Rejections (4xx) are final.
+func isRetryablePartnerError(err error) bool {
+	var partnerErr *partnerError
+	if errors.As(err, &partnerErr) {
+		return isPartnerOutage(partnerErr)
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// retryDelay returns the backoff before the given retry (1 for the first),
+// doubling from base up to max, with up to half of it randomized so retries
+// from concurrent sagas spread out
+func retryDelay(retry int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < retry && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// partnerResponse mirrors the partner gateway response envelope
+type partnerResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Data    struct {
+		PartnerRef string `json:"partner_ref"`
+		Status     string `json:"status"`
+	} `json:"data"`
+}
+
+func newPartnerClient(baseURL string, httpClient *http.Client, jwtManager *auth.JWTManager, breaker *circuitBreaker) *partnerClient {
+	return &partnerClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+		jwtManager: jwtManager,
+		breaker:    breaker,
+	}
+}
+
+// fulfill submits a fulfillment and returns the partner reference. The
+// gateway deduplicates on the redemption ID, so retrying is safe. While the
+// circuit is open it fails with errCircuitOpen without calling the gateway.
+func (c *partnerClient) fulfill(ctx context.Context, payload *partnerFulfillmentRequest) (string, error) {
+	generation, err := c.breaker.allow()
+	if err != nil {
+		return "", err
+	}
+	partnerRef, err := c.send(ctx, payload)
+	c.breaker.done(generation, !isBreakerFailure(err))
+	return partnerRef, err
+}
+
+// isBreakerFailure reports whether a call failed because the gateway is
+// down. Rejections show the gateway is up, and a saga cancelled by shutdown
+// says nothing about it.
+func isBreakerFailure(err error) bool {
+	return err != nil && isRetryablePartnerError(err) && !errors.Is(err, context.Canceled)
+}
+
+// send makes one fulfillment call
+func (c *partnerClient) send(ctx context.Context, payload *partnerFulfillmentRequest) (string, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal fulfillment request: %w", err)
+	}
+
+	token, err := c.jwtManager.GenerateServiceToken(serviceName)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate service token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/fulfill", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to build fulfillment request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	httputil.SetRequestID(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call partner gateway: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result partnerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", &partnerError{StatusCode: resp.StatusCode, Message: "invalid response body"}
+	}
+
+	if resp.StatusCode >= 300 || !result.Success {
+		return "", &partnerError{StatusCode: resp.StatusCode, Message: result.Message}
+	}
+
+	return result.Data.PartnerRef, nil
+}
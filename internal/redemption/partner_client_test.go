@@ -0,0 +1,93 @@
+package redemption
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/partner"
+)
+
+// newGatewayClient creates a partner client of a partner gateway service
+// sharing s's JWT configuration
+func newGatewayClient(t *testing.T, s *Service) *partnerClient {
+	t.Helper()
+
+	gateway := partner.NewService(s.config, s.logger)
+	router := chi.NewRouter()
+	gateway.Routes(router)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return newPartnerClient(server.URL, server.Client(), s.jwtManager, nil)
+}
+
+func TestPartnerClientFulfill(t *testing.T) {
+	s, _ := newTestService(t)
+	client := newGatewayClient(t, s)
+	payload := &partnerFulfillmentRequest{
+		RedemptionID: uuid.New().String(),
+		Partner:      "giftco",
+		BenefitID:    uuid.New().String(),
+		BenefitType:  BenefitTypeGiftCard,
+		GiftCard:     &giftCardPayload{Amount: 25, Currency: "USD"},
+	}
+
+	ref, err := client.fulfill(context.Background(), payload)
+	if err != nil || ref == "" {
+		t.Fatalf("fulfill = %q, %v; want a partner reference", ref, err)
+	}
+
+	// The gateway deduplicates on the redemption ID, so a retry is safe
+	replayed, err := client.fulfill(context.Background(), payload)
+	if err != nil || replayed != ref {
+		t.Fatalf("retried fulfill = %q, %v; want %q", replayed, err, ref)
+	}
+}
+
+func TestPartnerClientFulfillFailures(t *testing.T) {
+	s, _ := newTestService(t)
+
+	tests := []struct {
+		name          string
+		handler       http.HandlerFunc
+		wantStatus    int
+		wantRetryable bool
+	}{
+		{"rejected", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"code":"UNPROCESSABLE","message":"Unsupported benefit type"}`))
+		}, http.StatusUnprocessableEntity, false},
+		{"outage", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"code":"UNAVAILABLE","message":"Partner unavailable"}`))
+		}, http.StatusServiceUnavailable, true},
+		{"unsuccessful", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"success":false,"message":"Declined"}`))
+		}, http.StatusOK, false},
+		{"invalid body", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`<html>bad gateway</html>`))
+		}, http.StatusBadGateway, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			t.Cleanup(server.Close)
+			client := newPartnerClient(server.URL, server.Client(), s.jwtManager, nil)
+
+			_, err := client.fulfill(context.Background(), &partnerFulfillmentRequest{RedemptionID: uuid.New().String()})
+			var partnerErr *partnerError
+			if !errors.As(err, &partnerErr) || partnerErr.StatusCode != tt.wantStatus {
+				t.Fatalf("err = %v, want a partner error with status %d", err, tt.wantStatus)
+			}
+			if got := isRetryablePartnerError(err); got != tt.wantRetryable {
+				t.Fatalf("retryable = %v, want %v", got, tt.wantRetryable)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package redemption
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+)
+
+// sagaLedger counts the loyalty calls a saga makes when it deducts points
+// outright
+type sagaLedger struct {
+	deductions  atomic.Int32
+	reversals   atomic.Int32
+	settlements atomic.Int32
+}
+
+// newGatewaySagaService creates a service that deducts points outright from
+// a fake loyalty service and fulfills through partner; configure adjusts the
+// loaded configuration first
+func newGatewaySagaService(t *testing.T, partner http.HandlerFunc, configure ...func(*config.Config)) (*Service, *sagaLedger) {
+	t.Helper()
+
+	ledger := &sagaLedger{}
+	loyalty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/loyalty/balance":
+			writeLoyaltyData(w, map[string]int{"available_points": 10000})
+		case "/v1/loyalty/internal/deduct":
+			ledger.deductions.Add(1)
+			writeLoyaltyData(w, nil)
+		case "/v1/loyalty/internal/reverse":
+			ledger.reversals.Add(1)
+			writeLoyaltyData(w, nil)
+		case "/v1/loyalty/internal/settle":
+			ledger.settlements.Add(1)
+			writeLoyaltyData(w, nil)
+		default:
+			t.Errorf("unexpected loyalty call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(loyalty.Close)
+	gateway := httptest.NewServer(partner)
+	t.Cleanup(gateway.Close)
+
+	s, _ := newTestService(t, append([]func(*config.Config){func(cfg *config.Config) {
+		cfg.Redemption.PointsHolds = false
+		cfg.Services.LoyaltyURL = loyalty.URL
+		cfg.Services.PartnerGatewayURL = gateway.URL
+	}}, configure...)...)
+	return s, ledger
+}
+
+// fulfilled answers a partner fulfillment call successfully
+func fulfilled(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"success":true,"data":{"partner_ref":"GIFTCO-123","status":"fulfilled"}}`))
+}
+
+// flakyPartner fails the first failures calls with status, then fulfills
+func flakyPartner(failures int32, status int, calls *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"code":"FAILED","message":"Partner failed"}`))
+			return
+		}
+		fulfilled(w)
+	}
+}
+
+// withPartnerRetry retries partner calls up to maxAttempts with short delays
+func withPartnerRetry(maxAttempts int) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Redemption.PartnerRetry = config.RetryConfig{MaxAttempts: maxAttempts, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	}
+}
+
+// hangingPartner never answers until the call is abandoned. Reading the
+// body lets the server notice the client going away.
+func hangingPartner(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	select {
+	case <-r.Context().Done():
+	case <-time.After(5 * time.Second):
+	}
+}
+
+func TestSlowPartnerTimesOutAndReversesPoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*config.Config)
+	}{
+		{"step timeout", func(cfg *config.Config) {
+			cfg.Redemption.StepTimeout = 50 * time.Millisecond
+			cfg.Redemption.SagaTimeout = 5 * time.Second
+		}},
+		{"saga deadline", func(cfg *config.Config) {
+			cfg.Redemption.StepTimeout = 0
+			cfg.Redemption.SagaTimeout = 200 * time.Millisecond
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ledger := newGatewaySagaService(t, hangingPartner, tt.configure, func(cfg *config.Config) {
+				cfg.Redemption.PartnerRetry.MaxAttempts = 1
+			})
+			redemption := newTestRedemption()
+
+			start := time.Now()
+			s.processRedemptionSaga(context.Background(), redemption, "Bearer user-token")
+
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Fatalf("saga took %s, want it cut short by the timeout", elapsed)
+			}
+			if redemption.Status != StatusTimedOut {
+				t.Fatalf("status = %q, want %q (%s)", redemption.Status, StatusTimedOut, redemption.ErrorMessage)
+			}
+			// The reversal runs although the saga's deadline has passed
+			if ledger.deductions.Load() != 1 || ledger.reversals.Load() != 1 || ledger.settlements.Load() != 0 {
+				t.Fatalf("deductions %d, reversals %d, settlements %d; want the deduction reversed",
+					ledger.deductions.Load(), ledger.reversals.Load(), ledger.settlements.Load())
+			}
+		})
+	}
+}
+
+func TestShutdownInterruptsSagaAndReversesPoints(t *testing.T) {
+	s, ledger := newGatewaySagaService(t, hangingPartner, func(cfg *config.Config) {
+		cfg.Redemption.PartnerRetry.MaxAttempts = 1
+	})
+	redemption := newTestRedemption()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	s.processRedemptionSaga(ctx, redemption, "Bearer user-token")
+
+	if redemption.Status != StatusInterrupted {
+		t.Fatalf("status = %q, want %q (%s)", redemption.Status, StatusInterrupted, redemption.ErrorMessage)
+	}
+	if ledger.reversals.Load() != 1 {
+		t.Fatalf("reversals = %d, want 1", ledger.reversals.Load())
+	}
+}
+
+func TestPartnerRetrySucceedsAfterTransientFailures(t *testing.T) {
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadGateway} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			var calls atomic.Int32
+			s, ledger := newGatewaySagaService(t, flakyPartner(2, status, &calls), withPartnerRetry(3))
+			redemption := newTestRedemption()
+
+			s.processRedemptionSaga(context.Background(), redemption, "Bearer user-token")
+
+			if redemption.Status != StatusCompleted || redemption.PartnerRef != "GIFTCO-123" {
+				t.Fatalf("redemption = %s %q, want completed with the partner reference (%s)",
+					redemption.Status, redemption.PartnerRef, redemption.ErrorMessage)
+			}
+			if redemption.PartnerAttempts != 3 || calls.Load() != 3 {
+				t.Fatalf("attempts = %d, partner calls = %d; want 3", redemption.PartnerAttempts, calls.Load())
+			}
+			if ledger.reversals.Load() != 0 || ledger.settlements.Load() != 1 {
+				t.Fatalf("reversals %d, settlements %d; want the deduction settled, not reversed",
+					ledger.reversals.Load(), ledger.settlements.Load())
+			}
+		})
+	}
+}
+
+func TestPartnerRetryGivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		failures  int32
+		wantCalls int32
+	}{
+		// A rejection would be rejected again, so it is not retried
+		{"rejection", http.StatusUnprocessableEntity, 1, 1},
+		{"attempts exhausted", http.StatusServiceUnavailable, 5, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			s, ledger := newGatewaySagaService(t, flakyPartner(tt.failures, tt.status, &calls), withPartnerRetry(3))
+			redemption := newTestRedemption()
+
+			s.processRedemptionSaga(context.Background(), redemption, "Bearer user-token")
+
+			if redemption.Status != StatusFailed {
+				t.Fatalf("status = %q, want %q", redemption.Status, StatusFailed)
+			}
+			if calls.Load() != tt.wantCalls || redemption.PartnerAttempts != int(tt.wantCalls) {
+				t.Fatalf("partner calls = %d, attempts = %d; want %d", calls.Load(), redemption.PartnerAttempts, tt.wantCalls)
+			}
+			if ledger.reversals.Load() != 1 {
+				t.Fatalf("reversals = %d, want 1", ledger.reversals.Load())
+			}
+		})
+	}
+}
+
+func TestPartnerRetryStopsAtSagaDeadline(t *testing.T) {
+	var calls atomic.Int32
+	s, _ := newGatewaySagaService(t, flakyPartner(5, http.StatusServiceUnavailable, &calls), func(cfg *config.Config) {
+		cfg.Redemption.SagaTimeout = 100 * time.Millisecond
+		cfg.Redemption.PartnerRetry = config.RetryConfig{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Second}
+	})
+	redemption := newTestRedemption()
+
+	start := time.Now()
+	s.processRedemptionSaga(context.Background(), redemption, "Bearer user-token")
+
+	// The backoff would outlast the deadline, so the saga fails at once
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("saga took %s, want no retry past the deadline", elapsed)
+	}
+	if calls.Load() != 1 || redemption.Status != StatusFailed {
+		t.Fatalf("partner calls = %d, status = %q; want 1 call and a failure", calls.Load(), redemption.Status)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	base, max := 100*time.Millisecond, time.Second
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{10, time.Second},
+	}
+	for _, tt := range tests {
+		// Jitter keeps each delay between half the backoff and all of it
+		for i := 0; i < 20; i++ {
+			if got := retryDelay(tt.retry, base, max); got < tt.want/2 || got > tt.want {
+				t.Fatalf("retryDelay(%d) = %s, want between %s and %s", tt.retry, got, tt.want/2, tt.want)
+			}
+		}
+	}
+	if got := retryDelay(1, 0, max); got != 0 {
+		t.Fatalf("retryDelay without a base delay = %s, want 0", got)
+	}
+}
+
+func TestShutdownWaitsForInFlightSaga(t *testing.T) {
+	partnerCalled := make(chan struct{})
+	s, ledger := newGatewaySagaService(t, func(w http.ResponseWriter, r *http.Request) {
+		close(partnerCalled)
+		time.Sleep(200 * time.Millisecond)
+		fulfilled(w)
+	})
+	redemption := newTestRedemption()
+	if !s.sagas.Go(func(ctx context.Context) { s.processRedemptionSaga(ctx, redemption, "Bearer user-token") }) {
+		t.Fatal("saga not started")
+	}
+	<-partnerCalled
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	// The saga finished normally instead of being interrupted
+	if redemption.Status != StatusCompleted {
+		t.Fatalf("status = %q, want %q (%s)", redemption.Status, StatusCompleted, redemption.ErrorMessage)
+	}
+	if ledger.reversals.Load() != 0 || ledger.settlements.Load() != 1 {
+		t.Fatalf("reversals %d, settlements %d; want the deduction settled", ledger.reversals.Load(), ledger.settlements.Load())
+	}
+	if s.sagas.Go(func(ctx context.Context) {}) {
+		t.Fatal("saga started after shutdown")
+	}
+}
+
+func TestShutdownDeadlineInterruptsSaga(t *testing.T) {
+	s, ledger := newGatewaySagaService(t, hangingPartner, func(cfg *config.Config) {
+		cfg.Redemption.PartnerRetry.MaxAttempts = 1
+	})
+	redemption := newTestRedemption()
+	s.sagas.Go(func(ctx context.Context) { s.processRedemptionSaga(ctx, redemption, "Bearer user-token") })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	s.Shutdown(ctx)
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("Shutdown took %s, want it bounded by its deadline", elapsed)
+	}
+	// Shutdown returns only once the interrupted saga has compensated
+	if redemption.Status != StatusInterrupted || ledger.reversals.Load() != 1 {
+		t.Fatalf("status = %q, reversals = %d; want interrupted and reversed", redemption.Status, ledger.reversals.Load())
+	}
+}
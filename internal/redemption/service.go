@@ -3,6 +3,7 @@ package redemption
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -10,39 +11,94 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth/authmw"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/cache"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/database"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/lifecycle"
 	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/metrics"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/tracing"
 	"github.com/sirupsen/logrus"
 )
 
+// Redemption statuses. Every status but requested and capture_pending is terminal.
+const (
+	StatusRequested = "requested"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	// StatusCapturePending means the benefit was fulfilled but the held points
+	// could not be captured; the redemption waits for manual reconciliation
+	StatusCapturePending = "capture_pending"
+	// StatusTimedOut means the saga or one of its steps exceeded its timeout
+	StatusTimedOut = "timed_out"
+	// StatusInterrupted means the service shut down before the saga finished
+	StatusInterrupted = "interrupted"
+)
+
+var (
+	errInsufficientPoints = errors.New("insufficient points")
+	errRedemptionNotFound = errors.New("redemption not found")
+	// errProducerUnavailable is returned when events cannot be emitted because
+	// the event bus failed to initialize
+	errProducerUnavailable = errors.New("event producer not initialized")
+)
+
 // Service represents the redemption service
 type Service struct {
-	config *config.Config
-	logger *logrus.Logger
-	db     *database.PostgresDB
-	kafka  *messaging.KafkaProducer
+	config     *config.Config
+	logger     *logrus.Logger
+	db         *database.PostgresDB
+	kafka      messaging.Producer
+	catalog    *catalogClient
+	loyalty    *loyaltyClient
+	partner    *partnerClient
+	jwtManager *auth.JWTManager
+	tenants    auth.TenantScope
+	// benefits caches catalog lookups for redemption status; nil when disabled
+	benefits *cache.Cache
+	// statuses passes status changes to redemption event streams
+	statuses *statusHub
+
+	// sagas is cancelled only when a shutdown stops waiting for them;
+	// background runs the outbox relay and idempotency key sweep
+	sagas      *lifecycle.Group
+	background *lifecycle.Group
+
+	// partnerBreaker outlives partner, which SetHTTPClient replaces
+	partnerBreaker *circuitBreaker
 }
 
 // Redemption represents a loyalty redemption
 type Redemption struct {
-	ID              string    `json:"id"`
-	UserID          string    `json:"user_id"`
-	BenefitID       string    `json:"benefit_id"`
-	Points          int       `json:"points"`
-	Status          string    `json:"status"`
-	IdempotencyKey  string    `json:"idempotency_key"`
-	PartnerRef      string    `json:"partner_ref,omitempty"`
-	ErrorMessage    string    `json:"error_message,omitempty"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	ID             string              `json:"id"`
+	UserID         string              `json:"user_id"`
+	BenefitID      string              `json:"benefit_id"`
+	Points         int                 `json:"points"`
+	Status         string              `json:"status"`
+	IdempotencyKey string              `json:"idempotency_key"`
+	BenefitType    BenefitType         `json:"benefit_type,omitempty"`
+	Details        *FulfillmentDetails `json:"details,omitempty"`
+	PartnerRef     string              `json:"partner_ref,omitempty"`
+	FailureReason  UnavailableReason   `json:"failure_reason,omitempty"`
+	// HoldID is internal to the saga and never returned to users
+	HoldID string `json:"-"`
+	// PartnerAttempts counts the partner gateway calls made to fulfill the redemption
+	PartnerAttempts int        `json:"partner_attempts,omitempty"`
+	ErrorMessage    string     `json:"error_message,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	CompletedAt     *time.Time `json:"completed_at"` // null until the redemption completes
 }
 
 // RedemptionRequest represents a redemption request
 type RedemptionRequest struct {
-	BenefitID string `json:"benefit_id" validate:"required"`
-	Points    int    `json:"points" validate:"required,gt=0"`
+	BenefitID string              `json:"benefit_id" validate:"required,uuid"`
+	Points    int                 `json:"points" validate:"required,gt=0"`
+	Details   *FulfillmentDetails `json:"details,omitempty"`
 }
 
 // RedemptionResponse represents a redemption response
@@ -54,14 +110,18 @@ type RedemptionResponse struct {
 
 // RedemptionStatus represents the status of a redemption
 type RedemptionStatus struct {
-	ID              string     `json:"id"`
-	Status          string     `json:"status"`
-	Points          int        `json:"points"`
-	BenefitName     string     `json:"benefit_name"`
-	PartnerRef      string     `json:"partner_ref,omitempty"`
-	ErrorMessage    string     `json:"error_message,omitempty"`
-	CreatedAt       time.Time  `json:"created_at"`
-	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	ID            string            `json:"id"`
+	Status        string            `json:"status"`
+	Points        int               `json:"points"`
+	BenefitID     string            `json:"benefit_id"`
+	BenefitName   string            `json:"benefit_name"`
+	Category      string            `json:"category,omitempty"`
+	Partner       string            `json:"partner,omitempty"`
+	PartnerRef    string            `json:"partner_ref,omitempty"`
+	ErrorMessage  string            `json:"error_message,omitempty"`
+	FailureReason UnavailableReason `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	CompletedAt   *time.Time        `json:"completed_at"`
 }
 
 // RedemptionCompletedEvent represents the redemption completed event
@@ -69,6 +129,7 @@ type RedemptionCompletedEvent struct {
 	EventID     string    `json:"event_id"`
 	UserID      string    `json:"user_id"`
 	BenefitID   string    `json:"benefit_id"`
+	BenefitName string    `json:"benefit_name,omitempty"`
 	Points      int       `json:"points"`
 	PartnerRef  string    `json:"partner_ref"`
 	Timestamp   time.Time `json:"ts"`
@@ -86,26 +147,99 @@ type RedemptionFailedEvent struct {
 
 // OutboxMessage represents a message in the outbox
 type OutboxMessage struct {
-	ID        int64           `json:"id"`
-	Aggregate string          `json:"aggregate"`
-	Payload   json.RawMessage `json:"payload"`
-	Topic     string          `json:"topic"`
-	CreatedAt time.Time       `json:"created_at"`
+	ID          int64           `json:"id"`
+	Aggregate   string          `json:"aggregate"`
+	AggregateID string          `json:"aggregate_id"`
+	EventType   string          `json:"event_type"`
+	Key         string          `json:"key"`
+	Payload     json.RawMessage `json:"payload"`
+	Topic       string          `json:"topic"`
+	Attempts    int             `json:"attempts"`
+	CreatedAt   time.Time       `json:"created_at"`
+	// Traceparent is the trace context of the saga that queued the message
+	Traceparent string `json:"-"`
+	// RequestID is the ID of the request that started the saga
+	RequestID string `json:"-"`
 }
 
 // NewService creates a new redemption service
 func NewService(cfg *config.Config, logger *logrus.Logger) *Service {
-	// Initialize Kafka producer
+	// Initialize JWT manager
+	jwtManager := auth.NewJWTManagerFromConfig(cfg)
+
+	service := &Service{
+		config:     cfg,
+		logger:     logger,
+		jwtManager: jwtManager,
+		tenants:    auth.NewTenantScope(cfg.Security.Tenancy.Enabled, cfg.Security.Tenancy.DefaultTenant),
+		benefits:   newBenefitCache(cfg.Redemption.BenefitCacheTTL),
+		statuses:   newStatusHub(),
+		sagas:      lifecycle.NewGroup(),
+		background: lifecycle.NewGroup(),
+	}
+	service.partnerBreaker = newCircuitBreaker(cfg.Redemption.PartnerBreaker, service.partnerCircuitChanged)
+
+	// Initialize event producer
 	kafkaConfig := &messaging.KafkaConfig{
-		Brokers:  cfg.Kafka.Brokers,
-		ClientID: cfg.Kafka.ClientID,
+		Driver:       cfg.Kafka.Driver,
+		Brokers:      cfg.Kafka.Brokers,
+		ClientID:     cfg.Kafka.ClientID,
+		Async:        cfg.Kafka.Producer.Async,
+		BatchSize:    cfg.Kafka.Producer.BatchSize,
+		BatchTimeout: cfg.Kafka.Producer.BatchTimeout,
+	}
+	bus, err := messaging.NewEventBus(kafkaConfig, logger)
+	if err != nil {
+		logger.Errorf("Failed to initialize event bus: %v", err)
+	} else {
+		service.kafka = bus.Producer()
 	}
-	kafkaProducer := messaging.NewKafkaProducer(kafkaConfig, logger)
 
-	return &Service{
-		config: cfg,
-		logger: logger,
-		kafka:  kafkaProducer,
+	service.SetHTTPClient(&http.Client{Timeout: cfg.Services.RequestTimeout, Transport: &tracing.Transport{}})
+
+	return service
+}
+
+// Start runs the outbox relay and the idempotency key sweep in the
+// background until Shutdown
+func (s *Service) Start() {
+	s.background.Go(s.RunOutboxRelay)
+	s.background.Go(s.RunIdempotencySweeper)
+}
+
+// Shutdown waits for running sagas to finish, then stops the outbox relay
+// and closes the producer, delivering any events it buffered. When ctx is
+// done first, the remaining sagas are cancelled; they return any reserved
+// points and are marked interrupted before Shutdown returns. The relay keeps
+// running while sagas drain so their events are published.
+func (s *Service) Shutdown(ctx context.Context) error {
+	if err := s.sagas.Wait(ctx); err != nil {
+		s.logger.Warn("Shutdown deadline reached, interrupting running redemption sagas")
+		s.sagas.Cancel()
+		_ = s.sagas.Wait(context.Background())
+	}
+	err := s.background.Shutdown(ctx)
+	if s.kafka != nil {
+		if closeErr := s.kafka.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close event producer: %w", closeErr))
+		}
+	}
+	return err
+}
+
+// SetHTTPClient replaces the client used to call the catalog, loyalty, and
+// partner gateway services. Services without a configured URL are not called.
+func (s *Service) SetHTTPClient(client *http.Client) {
+	urls := s.config.Services
+	s.catalog, s.loyalty, s.partner = nil, nil, nil
+	if urls.CatalogURL != "" {
+		s.catalog = &catalogClient{newServiceClient("catalog service", urls.CatalogURL, client, s.jwtManager)}
+	}
+	if urls.LoyaltyURL != "" {
+		s.loyalty = &loyaltyClient{newServiceClient("loyalty service", urls.LoyaltyURL, client, s.jwtManager)}
+	}
+	if urls.PartnerGatewayURL != "" {
+		s.partner = newPartnerClient(urls.PartnerGatewayURL, client, s.jwtManager, s.partnerBreaker)
 	}
 }
 
@@ -114,67 +248,70 @@ func (s *Service) SetDatabase(db *database.PostgresDB) {
 	s.db = db
 }
 
+// SetProducer replaces the event producer, for example with a fake in tests
+func (s *Service) SetProducer(producer messaging.Producer) {
+	s.kafka = producer
+}
+
 // Routes returns the redemption service routes
 func (s *Service) Routes(r chi.Router) {
 	r.Route("/v1", func(r chi.Router) {
-		r.Post("/redeem", s.AuthMiddleware(s.CreateRedemption))
-		r.Get("/redemptions/{id}", s.AuthMiddleware(s.GetRedemption))
-		r.Get("/redemptions", s.AuthMiddleware(s.ListRedemptions))
-	})
-}
+		r.Use(authmw.RequireJWT(s.jwtManager, authmw.WithTenants(s.tenants), authmw.WithLogger(s.logger)))
 
-// AuthMiddleware is a placeholder for JWT authentication
-func (s *Service) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Implement JWT validation
-		// For now, just check if user ID header is present
-		userID := r.Header.Get("X-User-ID")
-		if userID == "" {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "User ID required"})
-			return
-		}
-		// Add user ID to context
-		ctx := context.WithValue(r.Context(), "user_id", userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	}
+		r.Post("/redeem", s.CreateRedemption)
+		r.Post("/redeem/estimate", s.EstimateRedemption)
+		r.Get("/redemptions/{id}", s.GetRedemption)
+		r.Get("/redemptions/{id}/events", s.StreamRedemptionEvents)
+		r.Get("/redemptions", s.ListRedemptions)
+	})
 }
 
 // CreateRedemption handles creating a new redemption
 func (s *Service) CreateRedemption(w http.ResponseWriter, r *http.Request) {
 	var req RedemptionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+	if err := platformhttp.DecodeAndValidate(r, &req); err != nil {
+		platformhttp.RequestError(w, r, err)
 		return
 	}
 
-	// Validate request
-	if req.BenefitID == "" || req.Points <= 0 {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Benefit ID and points are required"})
+	userID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
 		return
 	}
-
-	userID := r.Context().Value("user_id").(string)
 	idempotencyKey := r.Header.Get("Idempotency-Key")
-	
+
 	if idempotencyKey == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Idempotency-Key header is required"})
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Idempotency-Key header is required")
 		return
 	}
 
-	// Check if redemption already exists (idempotency)
-	existing, err := s.getRedemptionByKey(idempotencyKey)
-	if err == nil && existing != nil {
-		// Return existing redemption
-		response := &RedemptionResponse{
-			RedemptionID: existing.ID,
-			Status:       existing.Status,
-			Message:      "Redemption already exists",
-		}
-		render.JSON(w, r, response)
+	// Replay the redemption a live key created; an expired key creates a new one
+	existing, err := s.getRedemptionByKey(r.Context(), userID, idempotencyKey)
+	if err != nil {
+		s.logger.WithContext(r.Context()).Errorf("Failed to look up redemption by idempotency key: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to create redemption")
+		return
+	}
+	if existing != nil {
+		replayRedemption(w, r, existing)
+		return
+	}
+
+	// Validate category-specific fulfillment data up front
+	benefit, err := s.getBenefitInfo(r.Context(), req.BenefitID)
+	if err != nil {
+		s.writeBenefitLookupError(w, r, req.BenefitID, err)
+		return
+	}
+
+	if availErr := checkAvailability(benefit, req.Points, time.Now()); availErr != nil {
+		writeAvailabilityError(w, r, availErr)
+		return
+	}
+
+	if err := validateFulfillmentDetails(benefit, req.Details, time.Now()); err != nil {
+		writeFulfillmentError(w, r, err)
 		return
 	}
 
@@ -184,27 +321,48 @@ func (s *Service) CreateRedemption(w http.ResponseWriter, r *http.Request) {
 		UserID:         userID,
 		BenefitID:      req.BenefitID,
 		Points:         req.Points,
-		Status:         "requested",
+		Status:         StatusRequested,
+		BenefitType:    benefit.Type(),
+		Details:        req.Details,
 		IdempotencyKey: idempotencyKey,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
 
 	// Save redemption to database
-	if err := s.saveRedemption(redemption); err != nil {
-		s.logger.Errorf("Failed to save redemption: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Failed to create redemption"})
+	if err := s.saveRedemption(r.Context(), redemption); err != nil {
+		if errors.Is(err, errIdempotencyKeyInUse) {
+			// A concurrent request with the same key was saved first
+			if existing, lookupErr := s.getRedemptionByKey(r.Context(), userID, idempotencyKey); lookupErr == nil && existing != nil {
+				replayRedemption(w, r, existing)
+				return
+			}
+		}
+		s.logger.WithContext(r.Context()).Errorf("Failed to save redemption: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to create redemption")
 		return
 	}
 
-	// Start redemption saga asynchronously
-	go s.processRedemptionSaga(redemption)
+	// Start redemption saga asynchronously, on behalf of the caller and in
+	// their tenant and trace, but not bound to this request's lifetime
+	tenantID := auth.TenantFromContext(r.Context())
+	authorization := r.Header.Get("Authorization")
+	requestSpan := tracing.SpanFromContext(r.Context())
+	started := s.sagas.Go(func(ctx context.Context) {
+		ctx = tracing.ContextWithSpan(auth.WithTenant(ctx, tenantID), requestSpan)
+		s.processRedemptionSaga(ctx, redemption, authorization)
+	})
+	if !started {
+		// Nothing has been reserved yet, so there is nothing to compensate
+		s.failRedemption(r.Context(), redemption, StatusInterrupted, "Service is shutting down")
+		platformhttp.Error(w, r, http.StatusServiceUnavailable, platformhttp.ErrCodeUnavailable, "Service is shutting down")
+		return
+	}
 
 	// Return immediate response
 	response := &RedemptionResponse{
 		RedemptionID: redemption.ID,
-		Status:       "requested",
+		Status:       StatusRequested,
 		Message:      "Redemption request accepted",
 	}
 
@@ -216,119 +374,316 @@ func (s *Service) CreateRedemption(w http.ResponseWriter, r *http.Request) {
 func (s *Service) GetRedemption(w http.ResponseWriter, r *http.Request) {
 	redemptionID := chi.URLParam(r, "id")
 	if redemptionID == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Redemption ID required"})
+		platformhttp.Error(w, r, http.StatusBadRequest, platformhttp.ErrCodeInvalidRequest, "Redemption ID required")
 		return
 	}
 
-	redemption, err := s.getRedemption(redemptionID)
+	redemption, err := s.getRedemption(r.Context(), redemptionID)
 	if err != nil {
-		s.logger.Errorf("Failed to get redemption %s: %v", redemptionID, err)
-		render.Status(r, http.StatusNotFound)
-		render.JSON(w, r, map[string]string{"error": "Redemption not found"})
+		if errors.Is(err, errRedemptionNotFound) {
+			platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Redemption not found")
+			return
+		}
+		s.logger.WithContext(r.Context()).Errorf("Failed to get redemption %s: %v", redemptionID, err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve redemption")
+		return
+	}
+	// Other users' redemptions are reported as missing rather than forbidden,
+	// so their IDs cannot be probed
+	if !canViewRedemption(r.Context(), redemption) {
+		platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Redemption not found")
 		return
 	}
 
-	// Convert to status response
-	status := &RedemptionStatus{
-		ID:           redemption.ID,
-		Status:       redemption.Status,
-		Points:       redemption.Points,
-		BenefitName:  "Unknown Benefit", // TODO: Get from catalog service
-		PartnerRef:   redemption.PartnerRef,
-		ErrorMessage: redemption.ErrorMessage,
-		CreatedAt:    redemption.CreatedAt,
-		CompletedAt:  redemption.CompletedAt,
+	render.JSON(w, r, s.redemptionStatus(r.Context(), redemption))
+}
+
+// canViewRedemption reports whether the caller may see a redemption: its
+// owner, an admin, or another service
+func canViewRedemption(ctx context.Context, redemption *Redemption) bool {
+	if userID, ok := authmw.UserID(ctx); ok && userID == redemption.UserID {
+		return true
 	}
+	role, _ := authmw.Role(ctx)
+	return role == auth.RoleAdmin || role == auth.RoleService
+}
 
-	render.JSON(w, r, status)
+// redemptionStatus converts a redemption to its status response
+func (s *Service) redemptionStatus(ctx context.Context, redemption *Redemption) *RedemptionStatus {
+	benefit := s.describeBenefit(ctx, redemption.BenefitID)
+	return &RedemptionStatus{
+		ID:            redemption.ID,
+		Status:        redemption.Status,
+		Points:        redemption.Points,
+		BenefitID:     redemption.BenefitID,
+		BenefitName:   benefit.Name,
+		Category:      benefit.Category,
+		Partner:       benefit.Partner,
+		PartnerRef:    redemption.PartnerRef,
+		ErrorMessage:  redemption.ErrorMessage,
+		FailureReason: redemption.FailureReason,
+		CreatedAt:     redemption.CreatedAt,
+		CompletedAt:   redemption.CompletedAt,
+	}
 }
 
 // ListRedemptions returns the user's redemption history
 func (s *Service) ListRedemptions(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
-	
-	redemptions, err := s.getRedemptionsByUser(userID)
+	userID, ok := authmw.UserID(r.Context())
+	if !ok {
+		platformhttp.Error(w, r, http.StatusUnauthorized, platformhttp.ErrCodeUnauthorized, "Authentication required")
+		return
+	}
+
+	redemptions, err := s.getRedemptionsByUser(r.Context(), userID)
 	if err != nil {
-		s.logger.Errorf("Failed to get redemptions: %v", err)
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, map[string]string{"error": "Failed to retrieve redemptions"})
+		s.logger.WithContext(r.Context()).Errorf("Failed to get redemptions: %v", err)
+		platformhttp.Error(w, r, http.StatusInternalServerError, platformhttp.ErrCodeInternal, "Failed to retrieve redemptions")
 		return
 	}
 
 	render.JSON(w, r, redemptions)
 }
 
-// processRedemptionSaga processes the redemption saga
-func (s *Service) processRedemptionSaga(redemption *Redemption) {
+// processRedemptionSaga processes the redemption saga. authorization is the
+// requesting user's Authorization header, forwarded on calls made as them.
+// The saga must finish within the saga timeout and each step within the step
+// timeout; points reserved before a timeout or shutdown are still returned.
+func (s *Service) processRedemptionSaga(ctx context.Context, redemption *Redemption, authorization string) {
+	ctx, span := tracing.Start(ctx, "redemption.saga", tracing.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute("redemption.id", redemption.ID)
+	span.SetAttribute("benefit.id", redemption.BenefitID)
+
+	if timeout := s.config.Redemption.SagaTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	// Step 1: Validate benefit and check availability
-	if err := s.validateBenefit(redemption.BenefitID); err != nil {
-		s.failRedemption(redemption, err.Error())
+	var benefit *benefitInfo
+	err := s.runStep(ctx, func(ctx context.Context) (err error) {
+		benefit, err = s.validateBenefit(ctx, redemption)
+		return err
+	})
+	if err != nil {
+		s.failSagaStep(ctx, redemption, stepValidateBenefit, err)
 		return
 	}
 
 	// Step 2: Check user has enough points
-	if err := s.checkUserPoints(redemption.UserID, redemption.Points); err != nil {
-		s.failRedemption(redemption, err.Error())
+	err = s.runStep(ctx, func(ctx context.Context) error {
+		return s.checkUserPoints(ctx, authorization, redemption.UserID, redemption.Points)
+	})
+	if err != nil {
+		s.failSagaStep(ctx, redemption, stepCheckPoints, err)
 		return
 	}
 
-	// Step 3: Deduct points from user balance
-	if err := s.deductPoints(redemption.UserID, redemption.Points); err != nil {
-		s.failRedemption(redemption, err.Error())
+	// Step 3: Reserve points, either with a hold or by deducting them outright
+	usesHold := s.config.Redemption.PointsHolds
+	err = s.runStep(ctx, func(ctx context.Context) error {
+		if !usesHold {
+			return s.deductPoints(ctx, redemption.UserID, redemption.Points, redemption.ID)
+		}
+		holdID, err := s.placePointsHold(ctx, authorization, redemption)
+		redemption.HoldID = holdID
+		return err
+	})
+	if err != nil {
+		// A deduction may have been applied even though its response never
+		// arrived; an unacknowledged hold expires on its own
+		if !usesHold && isInterrupted(err) {
+			s.returnPoints(ctx, redemption, usesHold, "Redemption interrupted")
+		}
+		s.failSagaStep(ctx, redemption, stepReservePoints, err)
 		return
 	}
 
-	// Step 4: Call partner gateway to fulfill benefit
-	partnerRef, err := s.callPartnerGateway(redemption)
+	// Step 4: Call partner gateway to fulfill benefit; each attempt is bounded
+	// by the step timeout
+	partnerRef, err := s.callPartnerGateway(ctx, redemption, benefit)
 	if err != nil {
-		// Try to reverse points deduction
-		s.reversePointsDeduction(redemption.UserID, redemption.Points)
-		s.failRedemption(redemption, err.Error())
+		s.returnPoints(ctx, redemption, usesHold, "Partner fulfillment failed")
+		s.failSagaStep(ctx, redemption, stepPartnerFulfillment, err)
 		return
 	}
 
-	// Step 5: Mark redemption as completed
-	redemption.Status = "completed"
-	redemption.PartnerRef = partnerRef
-	redemption.CompletedAt = &time.Time{}
-	*redemption.CompletedAt = time.Now()
-	redemption.UpdatedAt = time.Now()
+	// The benefit has been fulfilled, so the remaining steps must run even if
+	// the saga deadline passed or the service is shutting down
+	ctx = context.WithoutCancel(ctx)
 
-	if err := s.updateRedemption(redemption); err != nil {
-		s.logger.Errorf("Failed to update redemption status: %v", err)
-		// Don't fail the saga at this point
+	// Capture the held points now that the benefit has been fulfilled
+	if usesHold {
+		if err := s.captureHeldPoints(ctx, redemption); err != nil {
+			// The benefit was fulfilled but its points were not spent, and will
+			// be returned when the hold expires; don't complete the redemption
+			s.recordCompensationFailure(compensationCaptureHold, redemption, err)
+			s.flagUncaptured(ctx, redemption, partnerRef, err)
+			return
+		}
+	} else {
+		err := s.runStep(ctx, func(ctx context.Context) error {
+			return s.settlePointsDeduction(ctx, redemption.UserID, redemption.ID)
+		})
+		if err != nil {
+			// The deduction stays on the orphaned deductions report until reconciled
+			s.logger.WithContext(ctx).Errorf("Failed to settle points deduction for redemption %s: %v", redemption.ID, err)
+		}
 	}
 
-	// Step 6: Emit completion event
+	// Step 5: Mark redemption as completed. The record and its event share
+	// one completion time.
+	completedAt := time.Now()
+	redemption.Status = StatusCompleted
+	redemption.PartnerRef = partnerRef
+	redemption.CompletedAt = timePtr(completedAt)
+	redemption.UpdatedAt = completedAt
+
+	// Step 6: Save the completion and queue its event together
 	event := &RedemptionCompletedEvent{
-		EventID:    uuid.New().String(),
-		UserID:     redemption.UserID,
-		BenefitID:  redemption.BenefitID,
-		Points:     redemption.Points,
-		PartnerRef: partnerRef,
-		Timestamp:  time.Now(),
+		EventID:     uuid.New().String(),
+		UserID:      redemption.UserID,
+		BenefitID:   redemption.BenefitID,
+		BenefitName: benefit.Name,
+		Points:      redemption.Points,
+		PartnerRef:  partnerRef,
+		Timestamp:   completedAt,
 	}
 
-	if err := s.emitRedemptionCompletedEvent(event); err != nil {
-		s.logger.Errorf("Failed to emit redemption completed event: %v", err)
-		// Don't fail the saga for event emission failure
+	err = s.runStep(ctx, func(ctx context.Context) error {
+		return s.recordOutcome(ctx, redemption, outboxEventCompleted, s.config.Kafka.Topics.RedemptionComplete, event)
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("Failed to record redemption completion: %v", err)
+		// Don't fail the saga at this point; the benefit has been fulfilled
 	}
 
-	s.logger.Infof("Redemption %s completed successfully", redemption.ID)
+	metrics.RedemptionCompleted.Inc()
+	s.logger.WithContext(ctx).Infof("Redemption %s completed successfully", redemption.ID)
 }
 
-// failRedemption marks a redemption as failed
-func (s *Service) failRedemption(redemption *Redemption, errorMessage string) {
-	redemption.Status = "failed"
-	redemption.ErrorMessage = errorMessage
-	redemption.UpdatedAt = time.Now()
+// runStep runs one saga step bounded by the step timeout
+func (s *Service) runStep(ctx context.Context, step func(ctx context.Context) error) error {
+	if timeout := s.config.Redemption.StepTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return step(ctx)
+}
+
+// captureHeldPoints captures the redemption's hold, retrying transient
+// failures with backoff. Each attempt is bounded by the step timeout.
+func (s *Service) captureHeldPoints(ctx context.Context, redemption *Redemption) error {
+	retry := s.config.Redemption.CaptureRetry
+	for attempt := 1; ; attempt++ {
+		err := s.runStep(ctx, func(ctx context.Context) error {
+			return s.capturePointsHold(ctx, redemption.UserID, redemption.HoldID)
+		})
+		if err == nil || attempt >= retry.MaxAttempts || !isRetryableCaptureError(err) {
+			return err
+		}
+
+		delay := retryDelay(attempt, retry.BaseDelay, retry.MaxDelay)
+		s.logger.WithContext(ctx).Warnf("Capturing points hold for redemption %s failed (attempt %d), retrying in %s: %v",
+			redemption.ID, attempt, delay, err)
 
-	if err := s.updateRedemption(redemption); err != nil {
-		s.logger.Errorf("Failed to update redemption status: %v", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
 	}
+}
 
-	// Emit failure event
+// isRetryableCaptureError reports whether a failed capture may succeed if
+// retried. Loyalty rejects a hold that is missing or no longer active for good.
+func isRetryableCaptureError(err error) bool {
+	var svcErr *serviceError
+	if errors.As(err, &svcErr) {
+		return svcErr.StatusCode == http.StatusTooManyRequests || svcErr.StatusCode >= 500
+	}
+	return true
+}
+
+// returnPoints releases the redemption's hold or reverses its deduction. It
+// runs even after the saga deadline or a shutdown, bounded by the step timeout.
+func (s *Service) returnPoints(ctx context.Context, redemption *Redemption, usesHold bool, reason string) {
+	ctx = context.WithoutCancel(ctx)
+
+	if usesHold {
+		// Release the hold; if this fails the hold still expires on its own
+		err := s.runStep(ctx, func(ctx context.Context) error {
+			return s.releasePointsHold(ctx, redemption.UserID, redemption.HoldID)
+		})
+		if err != nil {
+			s.recordCompensationFailure(compensationReleaseHold, redemption, err)
+		}
+		return
+	}
+
+	err := s.runStep(ctx, func(ctx context.Context) error {
+		return s.reversePointsDeduction(ctx, redemption.UserID, redemption.Points, redemption.ID, reason)
+	})
+	var svcErr *serviceError
+	if errors.As(err, &svcErr) && svcErr.StatusCode == http.StatusNotFound {
+		// The deduction never reached the ledger, so there is nothing to refund
+		s.logger.WithContext(ctx).Warnf("No points deduction to reverse for redemption %s: %v", redemption.ID, err)
+		return
+	}
+	if err != nil {
+		// Points were deducted but the benefit was neither fulfilled nor refunded
+		s.recordCompensationFailure(compensationReverseDeduction, redemption, err)
+	}
+}
+
+// isInterrupted reports whether a step failed because it timed out or the
+// service is shutting down
+func isInterrupted(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// failSagaStep records the failed step and marks the redemption as failed,
+// timed out, or interrupted by shutdown
+func (s *Service) failSagaStep(ctx context.Context, redemption *Redemption, step string, err error) {
+	recordSagaFailure(step, err)
+	span := tracing.SpanFromContext(ctx)
+	span.SetAttribute("redemption.failed_step", step)
+	span.SetError(err)
+
+	status := StatusFailed
+	var availErr *AvailabilityError
+	var partnerErr *partnerError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		status = StatusTimedOut
+	case errors.Is(err, context.Canceled):
+		status = StatusInterrupted
+	case errors.As(err, &availErr):
+		redemption.FailureReason = availErr.Reason
+	case errors.As(err, &partnerErr) && isPartnerOutage(partnerErr), errors.Is(err, errCircuitOpen):
+		redemption.FailureReason = ReasonPartnerUnavailable
+	}
+
+	// Record the outcome even though the saga's own context may be done
+	ctx = context.WithoutCancel(ctx)
+	_ = s.runStep(ctx, func(ctx context.Context) error {
+		s.failRedemption(ctx, redemption, status, err.Error())
+		return nil
+	})
+}
+
+// failRedemption marks a redemption as failed with the given terminal status
+func (s *Service) failRedemption(ctx context.Context, redemption *Redemption, status, errorMessage string) {
+	redemption.Status = status
+	redemption.ErrorMessage = errorMessage
+	redemption.UpdatedAt = time.Now()
+
+	// Save the failure and queue its event together
 	event := &RedemptionFailedEvent{
 		EventID:      uuid.New().String(),
 		UserID:       redemption.UserID,
@@ -338,134 +693,404 @@ func (s *Service) failRedemption(redemption *Redemption, errorMessage string) {
 		Timestamp:    time.Now(),
 	}
 
-	if err := s.emitRedemptionFailedEvent(event); err != nil {
-		s.logger.Errorf("Failed to emit redemption failed event: %v", err)
+	if err := s.recordOutcome(ctx, redemption, outboxEventFailed, s.config.Kafka.Topics.RedemptionFailed, event); err != nil {
+		s.logger.WithContext(ctx).Errorf("Failed to record redemption failure: %v", err)
 	}
+	metrics.RedemptionOutcomes.WithLabelValues(status).Inc()
 
-	s.logger.Errorf("Redemption %s failed: %s", redemption.ID, errorMessage)
+	s.logger.WithContext(ctx).Errorf("Redemption %s failed: %s", redemption.ID, errorMessage)
 }
 
-// Database operations (placeholder implementations)
-func (s *Service) getRedemptionByKey(idempotencyKey string) (*Redemption, error) {
+// flagUncaptured saves a fulfilled redemption whose held points could not be
+// captured as capture_pending. It is neither completed nor reported with an
+// event until an operator reconciles it.
+func (s *Service) flagUncaptured(ctx context.Context, redemption *Redemption, partnerRef string, captureErr error) {
+	redemption.Status = StatusCapturePending
+	redemption.PartnerRef = partnerRef
+	redemption.ErrorMessage = "failed to capture held points: " + captureErr.Error()
+	redemption.UpdatedAt = time.Now()
+
+	err := s.runStep(ctx, func(ctx context.Context) error {
+		return s.saveRedemptionState(ctx, redemption)
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("Failed to flag redemption %s as capture pending: %v", redemption.ID, err)
+	}
+	metrics.RedemptionOutcomes.WithLabelValues(StatusCapturePending).Inc()
+}
+
+// redemptionColumns lists the columns scanRedemption reads, in order
+const redemptionColumns = `id, user_id, benefit_id, points, status, idempotency_key,
+	COALESCE(benefit_type, ''), details, COALESCE(partner_ref, ''), COALESCE(failure_reason, ''),
+	COALESCE(hold_id, ''), partner_attempts, COALESCE(error_message, ''), created_at, updated_at, completed_at`
+
+// getRedemptionByKey returns the redemption the user created with an
+// idempotency key, or nil if there is none or the key has expired
+func (s *Service) getRedemptionByKey(ctx context.Context, userID, idempotencyKey string) (*Redemption, error) {
 	if s.db == nil {
-		// For now, return nil (no existing redemption)
-		return nil, fmt.Errorf("not implemented")
+		return nil, nil
+	}
+
+	query := `SELECT ` + redemptionColumns + ` FROM redemptions
+		WHERE tenant_id = $1 AND id = (
+			SELECT redemption_id FROM redemption_idempotency_keys
+			WHERE tenant_id = $1 AND user_id = $2 AND idempotency_key = $3 AND expires_at > NOW()
+		)`
+
+	redemption, err := scanRedemption(s.db.QueryRow(ctx, query, auth.TenantFromContext(ctx), userID, idempotencyKey))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
 	}
-	
-	// TODO: Implement actual database query
-	return nil, fmt.Errorf("not implemented")
+	return redemption, nil
 }
 
-func (s *Service) saveRedemption(redemption *Redemption) error {
+// saveRedemption records a new redemption and claims its idempotency key for
+// redemption.idempotency_key_ttl, in one transaction. It returns
+// errIdempotencyKeyInUse, saving nothing, if the user's key is still live.
+func (s *Service) saveRedemption(ctx context.Context, redemption *Redemption) error {
 	if s.db == nil {
 		s.logger.Infof("Would save redemption: %+v", redemption)
 		return nil
 	}
-	
-	// TODO: Implement actual database save
-	return fmt.Errorf("not implemented")
+
+	details, err := marshalDetails(redemption.Details)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO redemptions (id, tenant_id, user_id, benefit_id, points, status, idempotency_key,
+			benefit_type, details, partner_ref, failure_reason, hold_id, error_message,
+			created_at, updated_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), NULLIF($11, ''),
+			NULLIF($12, ''), NULLIF($13, ''), $14, $15, $16)`
+
+	_, err = tx.Exec(ctx, query,
+		redemption.ID, auth.TenantFromContext(ctx), redemption.UserID, redemption.BenefitID,
+		redemption.Points, redemption.Status, redemption.IdempotencyKey,
+		string(redemption.BenefitType), details, redemption.PartnerRef, string(redemption.FailureReason),
+		redemption.HoldID, redemption.ErrorMessage,
+		redemption.CreatedAt, redemption.UpdatedAt, redemption.CompletedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := claimIdempotencyKey(ctx, tx, redemption, s.config.Redemption.IdempotencyKeyTTL); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
-func (s *Service) getRedemption(id string) (*Redemption, error) {
+func (s *Service) getRedemption(ctx context.Context, id string) (*Redemption, error) {
 	if s.db == nil {
 		// Return mock data for now
+		completedAt := time.Now().Add(-30 * time.Minute)
 		return &Redemption{
-			ID:         id,
-			UserID:     "user-123",
-			BenefitID:  "benefit-1",
-			Points:     2000,
-			Status:     "completed",
-			PartnerRef: "VENDOR-12345",
-			CreatedAt:  time.Now().Add(-1 * time.Hour),
-			UpdatedAt:  time.Now().Add(-30 * time.Minute),
+			ID:          id,
+			UserID:      "user-123",
+			BenefitID:   "benefit-1",
+			Points:      2000,
+			Status:      StatusCompleted,
+			PartnerRef:  "VENDOR-12345",
+			CreatedAt:   time.Now().Add(-1 * time.Hour),
+			UpdatedAt:   completedAt,
+			CompletedAt: timePtr(completedAt),
 		}, nil
 	}
-	
-	// TODO: Implement actual database query
-	return nil, fmt.Errorf("not implemented")
+
+	query := `SELECT ` + redemptionColumns + ` FROM redemptions WHERE id = $1 AND tenant_id = $2`
+
+	redemption, err := scanRedemption(s.db.QueryRow(ctx, query, id, auth.TenantFromContext(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errRedemptionNotFound
+		}
+		return nil, err
+	}
+	return redemption, nil
 }
 
-func (s *Service) getRedemptionsByUser(userID string) ([]*Redemption, error) {
+func (s *Service) getRedemptionsByUser(ctx context.Context, userID string) ([]*Redemption, error) {
 	if s.db == nil {
 		// Return mock data for now
+		completedAt := time.Now().Add(-24 * time.Hour)
 		return []*Redemption{
 			{
-				ID:         "redemption-1",
-				UserID:     userID,
-				BenefitID:  "benefit-1",
-				Points:     2000,
-				Status:     "completed",
-				PartnerRef: "VENDOR-12345",
-				CreatedAt:  time.Now().Add(-24 * time.Hour),
-				UpdatedAt:  time.Now().Add(-24 * time.Hour),
+				ID:          "redemption-1",
+				UserID:      userID,
+				BenefitID:   "benefit-1",
+				Points:      2000,
+				Status:      StatusCompleted,
+				PartnerRef:  "VENDOR-12345",
+				CreatedAt:   completedAt,
+				UpdatedAt:   completedAt,
+				CompletedAt: timePtr(completedAt),
 			},
 		}, nil
 	}
-	
-	// TODO: Implement actual database query
-	return nil, fmt.Errorf("not implemented")
+
+	query := `SELECT ` + redemptionColumns + ` FROM redemptions WHERE user_id = $1 AND tenant_id = $2 ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(ctx, query, userID, auth.TenantFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	redemptions := []*Redemption{}
+	for rows.Next() {
+		redemption, err := scanRedemption(rows)
+		if err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, redemption)
+	}
+
+	return redemptions, rows.Err()
 }
 
-func (s *Service) updateRedemption(redemption *Redemption) error {
-	if s.db == nil {
-		s.logger.Infof("Would update redemption: %+v", redemption)
-		return nil
+// scanRedemption reads a row selected with redemptionColumns
+func scanRedemption(row pgx.Row) (*Redemption, error) {
+	var redemption Redemption
+	var benefitType, failureReason string
+	var details []byte
+
+	err := row.Scan(&redemption.ID, &redemption.UserID, &redemption.BenefitID, &redemption.Points,
+		&redemption.Status, &redemption.IdempotencyKey, &benefitType, &details, &redemption.PartnerRef,
+		&failureReason, &redemption.HoldID, &redemption.PartnerAttempts, &redemption.ErrorMessage,
+		&redemption.CreatedAt, &redemption.UpdatedAt, &redemption.CompletedAt)
+	if err != nil {
+		return nil, err
 	}
-	
-	// TODO: Implement actual database update
-	return fmt.Errorf("not implemented")
+
+	redemption.BenefitType = BenefitType(benefitType)
+	redemption.FailureReason = UnavailableReason(failureReason)
+	if details != nil {
+		if err := json.Unmarshal(details, &redemption.Details); err != nil {
+			return nil, fmt.Errorf("failed to decode details of redemption %s: %w", redemption.ID, err)
+		}
+	}
+
+	return &redemption, nil
 }
 
-// Saga step implementations (placeholder)
-func (s *Service) validateBenefit(benefitID string) error {
-	// TODO: Call catalog service to validate benefit
-	s.logger.Infof("Would validate benefit: %s", benefitID)
-	return nil
+// marshalDetails encodes fulfillment details for the details column, or nil
+// when there are none
+func marshalDetails(details *FulfillmentDetails) ([]byte, error) {
+	if details == nil {
+		return nil, nil
+	}
+	return json.Marshal(details)
 }
 
-func (s *Service) checkUserPoints(userID string, points int) error {
-	// TODO: Call loyalty service to check user points
-	s.logger.Infof("Would check user %s has %d points", userID, points)
-	return nil
+// getBenefitInfo looks up a benefit in the catalog, or errBenefitNotFound.
+// The catalog does not track partner health, so the partner counts as
+// available unless the gateway's circuit is open.
+func (s *Service) getBenefitInfo(ctx context.Context, benefitID string) (*benefitInfo, error) {
+	var benefit *benefitInfo
+	if s.catalog == nil {
+		s.logger.Infof("Would get benefit %s from the catalog service", benefitID)
+		benefit = &benefitInfo{
+			ID:       benefitID,
+			Name:     "$25 Gift Card",
+			Category: "Retail",
+			Partner:  "GIFTCO",
+			Points:   2000,
+			Currency: "USD",
+			Active:   true,
+		}
+	} else {
+		var err error
+		if benefit, err = s.catalog.getBenefit(ctx, benefitID); err != nil {
+			return nil, err
+		}
+	}
+
+	benefit.PartnerAvailable = s.partnerBreaker.available()
+	return benefit, nil
 }
 
-func (s *Service) deductPoints(userID string, points int) error {
-	// TODO: Call loyalty service to deduct points
-	s.logger.Infof("Would deduct %d points from user %s", points, userID)
-	return nil
+// writeBenefitLookupError answers 404 for unknown benefits and 502 when the
+// catalog could not be reached
+func (s *Service) writeBenefitLookupError(w http.ResponseWriter, r *http.Request, benefitID string, err error) {
+	if errors.Is(err, errBenefitNotFound) {
+		platformhttp.Error(w, r, http.StatusNotFound, platformhttp.ErrCodeNotFound, "Benefit not found")
+		return
+	}
+	s.logger.WithContext(r.Context()).Errorf("Failed to get benefit %s: %v", benefitID, err)
+	platformhttp.Error(w, r, http.StatusBadGateway, platformhttp.ErrCodeBadGateway, "Failed to look up benefit")
 }
 
-func (s *Service) callPartnerGateway(redemption *Redemption) (string, error) {
-	// TODO: Call partner gateway service
-	s.logger.Infof("Would call partner gateway for redemption: %s", redemption.ID)
-	return "VENDOR-" + uuid.New().String()[:8], nil
+// Saga step implementations. Without a configured service URL, a step only
+// logs what it would do.
+func (s *Service) validateBenefit(ctx context.Context, redemption *Redemption) (*benefitInfo, error) {
+	benefit, err := s.getBenefitInfo(ctx, redemption.BenefitID)
+	if errors.Is(err, errBenefitNotFound) {
+		// The catalog hides benefits deleted since the request was accepted
+		return nil, &AvailabilityError{Reason: ReasonInactive, Detail: fmt.Sprintf("Benefit %s is no longer offered", redemption.BenefitID)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get benefit %s: %w", redemption.BenefitID, err)
+	}
+
+	// Availability and cost may have changed since the request was accepted
+	if availErr := checkAvailability(benefit, redemption.Points, time.Now()); availErr != nil {
+		return nil, availErr
+	}
+
+	// The benefit may have changed category since the request was accepted
+	if err := validateFulfillmentDetails(benefit, redemption.Details, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return benefit, nil
 }
 
-func (s *Service) reversePointsDeduction(userID string, points int) error {
-	// TODO: Call loyalty service to reverse points deduction
-	s.logger.Infof("Would reverse %d points deduction for user %s", points, userID)
+func (s *Service) checkUserPoints(ctx context.Context, authorization, userID string, points int) error {
+	if s.loyalty == nil {
+		s.logger.Infof("Would check user %s has %d points", userID, points)
+		return nil
+	}
+
+	available, err := s.loyalty.availablePoints(ctx, authorization)
+	if err != nil {
+		return fmt.Errorf("failed to get points balance: %w", err)
+	}
+	if available < points {
+		return errInsufficientPoints
+	}
 	return nil
 }
 
-// Event emission (placeholder implementations)
-func (s *Service) emitRedemptionCompletedEvent(event *RedemptionCompletedEvent) error {
-	if s.kafka == nil {
-		s.logger.Warn("Kafka not initialized, skipping event emission")
+// deductPoints and reversePointsDeduction use the redemption ID as the
+// idempotency key, so retried calls are applied at most once
+func (s *Service) deductPoints(ctx context.Context, userID string, points int, redemptionID string) error {
+	if s.loyalty == nil {
+		s.logger.Infof("Would deduct %d points from user %s for redemption %s", points, userID, redemptionID)
 		return nil
 	}
-	
-	// TODO: Implement actual Kafka event emission
-	s.logger.Infof("Would emit redemption completed event: %+v", event)
-	return nil
+	return s.loyalty.deduct(ctx, userID, points, redemptionID)
 }
 
-func (s *Service) emitRedemptionFailedEvent(event *RedemptionFailedEvent) error {
-	if s.kafka == nil {
-		s.logger.Warn("Kafka not initialized, skipping event emission")
+func (s *Service) placePointsHold(ctx context.Context, authorization string, redemption *Redemption) (string, error) {
+	if s.loyalty == nil {
+		holdID := uuid.New().String()
+		s.logger.Infof("Would hold %d points for user %s (redemption %s) as hold %s", redemption.Points, redemption.UserID, redemption.ID, holdID)
+		return holdID, nil
+	}
+	return s.loyalty.placeHold(ctx, authorization, redemption)
+}
+
+func (s *Service) capturePointsHold(ctx context.Context, userID, holdID string) error {
+	if s.loyalty == nil {
+		s.logger.Infof("Would capture points hold %s", holdID)
 		return nil
 	}
-	
-	// TODO: Implement actual Kafka event emission
-	s.logger.Infof("Would emit redemption failed event: %+v", event)
-	return nil
+	return s.loyalty.finishHold(ctx, userID, holdID, "capture")
+}
+
+func (s *Service) releasePointsHold(ctx context.Context, userID, holdID string) error {
+	if s.loyalty == nil {
+		s.logger.Infof("Would release points hold %s", holdID)
+		return nil
+	}
+	return s.loyalty.finishHold(ctx, userID, holdID, "release")
+}
+
+func (s *Service) callPartnerGateway(ctx context.Context, redemption *Redemption, benefit *benefitInfo) (string, error) {
+	payload := buildPartnerRequest(redemption, benefit)
+
+	if s.partner == nil {
+		s.logger.Infof("Would call partner gateway for redemption %s with %s payload: %+v", redemption.ID, payload.BenefitType, payload)
+		redemption.PartnerAttempts = 1
+		return "VENDOR-" + uuid.New().String()[:8], nil
+	}
+
+	retry := s.config.Redemption.PartnerRetry
+	for {
+		redemption.PartnerAttempts++
+
+		var partnerRef string
+		err := s.runStep(ctx, func(ctx context.Context) (err error) {
+			partnerRef, err = s.partner.fulfill(ctx, payload)
+			return err
+		})
+		if err == nil {
+			return partnerRef, nil
+		}
+		// Retrying into an open circuit would only fail again
+		if redemption.PartnerAttempts >= retry.MaxAttempts || ctx.Err() != nil || !isRetryablePartnerError(err) ||
+			errors.Is(err, errCircuitOpen) {
+			return "", err
+		}
+
+		// Give up rather than retry past the saga deadline
+		delay := retryDelay(redemption.PartnerAttempts, retry.BaseDelay, retry.MaxDelay)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return "", err
+		}
+
+		s.logger.WithContext(ctx).Warnf("Partner gateway attempt %d for redemption %s failed, retrying in %s: %v",
+			redemption.PartnerAttempts, redemption.ID, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", err
+		case <-timer.C:
+		}
+	}
+}
+
+// reversePointsDeduction refunds the redemption's deduction. Loyalty records
+// at most one reversal per deduction, so retries never double-refund.
+func (s *Service) reversePointsDeduction(ctx context.Context, userID string, points int, redemptionID, reason string) error {
+	if s.loyalty == nil {
+		s.logger.Infof("Would reverse %d points deduction for user %s for redemption %s", points, userID, redemptionID)
+		return nil
+	}
+	return s.loyalty.reverse(ctx, userID, redemptionID, reason)
+}
+
+// settlePointsDeduction marks the redemption's deduction as paying for a
+// fulfilled benefit, so reconciliation does not report it as orphaned
+func (s *Service) settlePointsDeduction(ctx context.Context, userID, redemptionID string) error {
+	if s.loyalty == nil {
+		s.logger.Infof("Would settle points deduction for user %s for redemption %s", userID, redemptionID)
+		return nil
+	}
+	return s.loyalty.settle(ctx, userID, redemptionID)
+}
+
+// emitRedemptionCompletedEvent and emitRedemptionFailedEvent key events by
+// user ID, so each user's events land on one partition in order
+func (s *Service) emitRedemptionCompletedEvent(ctx context.Context, event *RedemptionCompletedEvent) error {
+	if s.kafka == nil {
+		return errProducerUnavailable
+	}
+	return s.kafka.SendJSONMessageWithHeaders(ctx, s.config.Kafka.Topics.RedemptionComplete, []byte(event.UserID), event,
+		redemptionEventHeaders(outboxEventCompleted))
+}
+
+func (s *Service) emitRedemptionFailedEvent(ctx context.Context, event *RedemptionFailedEvent) error {
+	if s.kafka == nil {
+		return errProducerUnavailable
+	}
+	return s.kafka.SendJSONMessageWithHeaders(ctx, s.config.Kafka.Topics.RedemptionFailed, []byte(event.UserID), event,
+		redemptionEventHeaders(outboxEventFailed))
+}
+
+// timePtr returns a pointer to t, for optional timestamps that must never be the zero time
+func timePtr(t time.Time) *time.Time {
+	return &t
 }
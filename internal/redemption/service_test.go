@@ -0,0 +1,381 @@
+package redemption
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/auth"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/config"
+	platformhttp "github.com/kaihedrick/go-loyalty-benefits/internal/platform/http"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging"
+	"github.com/kaihedrick/go-loyalty-benefits/internal/platform/messaging/messagingtest"
+	"github.com/sirupsen/logrus"
+)
+
+// newTestService creates a redemption service that calls no other services
+// and records events with a fake producer; configure adjusts the loaded
+// configuration first
+func newTestService(t *testing.T, configure ...func(*config.Config)) (*Service, *messagingtest.FakeProducer) {
+	t.Helper()
+
+	cfg, err := config.Load("redemption-svc")
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.Kafka.Driver = "memory"
+	cfg.Redis.Addr = ""
+	cfg.Security.JWT.Revocation = false
+	cfg.Security.RateLimit.Enabled = false
+	cfg.Services.CatalogURL = ""
+	cfg.Services.LoyaltyURL = ""
+	cfg.Services.PartnerGatewayURL = ""
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	s := NewService(cfg, logger)
+
+	producer := messagingtest.NewFakeProducer()
+	s.SetProducer(producer)
+	return s, producer
+}
+
+// newTestRedemption returns a requested redemption of the benefit the
+// service looks up when no catalog is configured
+func newTestRedemption() *Redemption {
+	now := time.Now()
+	return &Redemption{
+		ID:             uuid.New().String(),
+		UserID:         uuid.New().String(),
+		BenefitID:      uuid.New().String(),
+		Points:         2000,
+		Status:         StatusRequested,
+		IdempotencyKey: uuid.New().String(),
+		BenefitType:    BenefitTypeGiftCard,
+		Details:        &FulfillmentDetails{Amount: 25, Currency: "USD"},
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+// token issues a token for userID with role
+func token(t *testing.T, s *Service, userID, role string) string {
+	t.Helper()
+
+	tok, err := s.jwtManager.GenerateToken(userID, userID+"@example.com", role)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	return tok
+}
+
+// serve sends a request with a JSON body, if any, through s's routes
+func serve(s *Service, method, path, tok string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if tok != "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	router := chi.NewRouter()
+	s.Routes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// writeLoyaltyData answers a loyalty call with data in its response envelope
+func writeLoyaltyData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": data})
+}
+
+func TestProcessRedemptionSagaCompletes(t *testing.T) {
+	s, producer := newTestService(t)
+	redemption := newTestRedemption()
+
+	s.processRedemptionSaga(context.Background(), redemption, "")
+
+	if redemption.Status != StatusCompleted {
+		t.Fatalf("status = %q, want %q (%s)", redemption.Status, StatusCompleted, redemption.ErrorMessage)
+	}
+	if redemption.PartnerRef == "" {
+		t.Error("partner_ref not set")
+	}
+	if messages := producer.MessagesFor(s.config.Kafka.Topics.RedemptionComplete); len(messages) != 1 {
+		t.Fatalf("sent %d completion events, want 1", len(messages))
+	}
+}
+
+func TestCompletedRedemptionJSONHasCompletionTime(t *testing.T) {
+	s, producer := newTestService(t)
+	redemption := newTestRedemption()
+	started := time.Now()
+
+	s.processRedemptionSaga(context.Background(), redemption, "")
+
+	body, err := json.Marshal(redemption)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded struct {
+		CompletedAt *time.Time `json:"completed_at"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded.CompletedAt == nil || decoded.CompletedAt.IsZero() {
+		t.Fatalf("completed_at = %v in %s, want the completion time", decoded.CompletedAt, body)
+	}
+	if decoded.CompletedAt.Before(started) || decoded.CompletedAt.After(time.Now()) {
+		t.Fatalf("completed_at = %v, want a time during the saga", decoded.CompletedAt)
+	}
+
+	// The record and its event agree on when the redemption completed
+	if !redemption.UpdatedAt.Equal(*redemption.CompletedAt) {
+		t.Fatalf("updated_at = %v, want the completion time %v", redemption.UpdatedAt, redemption.CompletedAt)
+	}
+	messages := producer.MessagesFor(s.config.Kafka.Topics.RedemptionComplete)
+	if len(messages) != 1 {
+		t.Fatalf("sent %d completion events, want 1", len(messages))
+	}
+	var event RedemptionCompletedEvent
+	if err := json.Unmarshal(messages[0].Value, &event); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+	if !event.Timestamp.Equal(*redemption.CompletedAt) {
+		t.Fatalf("event timestamp = %v, want the completion time %v", event.Timestamp, redemption.CompletedAt)
+	}
+}
+
+func TestProcessRedemptionSagaCapturesHeldPoints(t *testing.T) {
+	tests := []struct {
+		name            string
+		captureStatus   int
+		captureFailures int32
+		wantStatus      string
+		wantCaptures    int32
+	}{
+		{"captured first time", 0, 0, StatusCompleted, 1},
+		{"transient failure retried", http.StatusServiceUnavailable, 2, StatusCompleted, 3},
+		{"retries exhausted", http.StatusServiceUnavailable, 10, StatusCapturePending, 3},
+		{"rejected capture not retried", http.StatusConflict, 10, StatusCapturePending, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, loyalty := newHoldSagaService(t, tt.captureStatus, tt.captureFailures)
+			producer := messagingtest.NewFakeProducer()
+			s.SetProducer(producer)
+			redemption := newTestRedemption()
+
+			s.processRedemptionSaga(context.Background(), redemption, "Bearer user-token")
+
+			if redemption.Status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q (%s)", redemption.Status, tt.wantStatus, redemption.ErrorMessage)
+			}
+			if loyalty.captures != tt.wantCaptures {
+				t.Errorf("captures = %d, want %d", loyalty.captures, tt.wantCaptures)
+			}
+			if loyalty.releases != 0 {
+				t.Errorf("released a fulfilled redemption's hold %d times", loyalty.releases)
+			}
+
+			completions := producer.MessagesFor(s.config.Kafka.Topics.RedemptionComplete)
+			if tt.wantStatus == StatusCapturePending {
+				if len(completions) != 0 || redemption.CompletedAt != nil {
+					t.Errorf("uncaptured redemption reported completed")
+				}
+				if redemption.PartnerRef == "" {
+					t.Errorf("partner_ref of the fulfilled benefit not kept")
+				}
+			} else if len(completions) != 1 {
+				t.Errorf("sent %d completion events, want 1", len(completions))
+			}
+		})
+	}
+}
+
+func TestRedemptionJSONOmitsHoldID(t *testing.T) {
+	redemption := newTestRedemption()
+	redemption.HoldID = "hold-1"
+
+	body, err := json.Marshal(redemption)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(body), "hold") {
+		t.Fatalf("redemption JSON exposes its hold: %s", body)
+	}
+}
+
+func TestGetRedemptionIsVisibleToOwnerAdminAndServices(t *testing.T) {
+	// Without a database every redemption belongs to user-123
+	s, _ := newTestService(t)
+
+	tests := []struct {
+		name   string
+		userID string
+		role   string
+		want   int
+	}{
+		{"owner", "user-123", "user", http.StatusOK},
+		{"other user", "user-456", "user", http.StatusNotFound},
+		{"admin", "admin-1", auth.RoleAdmin, http.StatusOK},
+		{"service", "notify-svc", auth.RoleService, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(s, http.MethodGet, "/v1/redemptions/"+uuid.New().String(), token(t, s, tt.userID, tt.role), nil)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestEmitRedemptionEvents(t *testing.T) {
+	s, producer := newTestService(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	completed := &RedemptionCompletedEvent{EventID: "event-1", UserID: "user-1", BenefitID: "benefit-1", Points: 2000, PartnerRef: "VENDOR-1", Timestamp: now}
+	if err := s.emitRedemptionCompletedEvent(ctx, completed); err != nil {
+		t.Fatalf("emit completed: %v", err)
+	}
+	failed := &RedemptionFailedEvent{EventID: "event-2", UserID: "user-2", BenefitID: "benefit-1", Points: 2000, ErrorMessage: "boom", Timestamp: now}
+	if err := s.emitRedemptionFailedEvent(ctx, failed); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+
+	messages := producer.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("sent %d messages, want 2", len(messages))
+	}
+	tests := []struct {
+		topic, key, eventType string
+		want, got             interface{}
+	}{
+		{s.config.Kafka.Topics.RedemptionComplete, "user-1", outboxEventCompleted, completed, &RedemptionCompletedEvent{}},
+		{s.config.Kafka.Topics.RedemptionFailed, "user-2", outboxEventFailed, failed, &RedemptionFailedEvent{}},
+	}
+	for i, tt := range tests {
+		msg := messages[i]
+		if msg.Topic != tt.topic {
+			t.Errorf("message %d: topic = %q, want %q", i, msg.Topic, tt.topic)
+		}
+		// Keyed by user so a user's events stay in order on one partition
+		if string(msg.Key) != tt.key {
+			t.Errorf("message %d: key = %q, want %q", i, msg.Key, tt.key)
+		}
+		if msg.Headers[messaging.HeaderEventType] != tt.eventType {
+			t.Errorf("message %d: event-type = %q, want %q", i, msg.Headers[messaging.HeaderEventType], tt.eventType)
+		}
+		if msg.Headers[messaging.HeaderSchemaVersion] != redemptionEventSchemaVersion {
+			t.Errorf("message %d: schema-version = %q, want %q", i, msg.Headers[messaging.HeaderSchemaVersion], redemptionEventSchemaVersion)
+		}
+		if msg.Headers[messaging.HeaderContentType] != messaging.ContentTypeJSON {
+			t.Errorf("message %d: content-type = %q, want %q", i, msg.Headers[messaging.HeaderContentType], messaging.ContentTypeJSON)
+		}
+		if err := json.Unmarshal(msg.Value, tt.got); err != nil {
+			t.Fatalf("message %d: decode: %v", i, err)
+		}
+		wantJSON, _ := json.Marshal(tt.want)
+		gotJSON, _ := json.Marshal(tt.got)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("message %d: payload = %s, want %s", i, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestEmitRedemptionEventWithoutProducer(t *testing.T) {
+	s, _ := newTestService(t)
+	s.SetProducer(nil)
+
+	err := s.emitRedemptionCompletedEvent(context.Background(), &RedemptionCompletedEvent{UserID: "user-1"})
+	if err != errProducerUnavailable {
+		t.Fatalf("err = %v, want %v", err, errProducerUnavailable)
+	}
+}
+
+func TestIncompleteRedemptionJSONHasNullCompletionTime(t *testing.T) {
+	redemption := newTestRedemption()
+	status := &RedemptionStatus{ID: redemption.ID, Status: redemption.Status, CreatedAt: redemption.CreatedAt}
+
+	for name, v := range map[string]interface{}{"redemption": redemption, "status": status} {
+		body, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%s: marshal: %v", name, err)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			t.Fatalf("%s: unmarshal: %v", name, err)
+		}
+		if value, ok := decoded["completed_at"]; !ok || value != nil {
+			t.Errorf("%s: completed_at = %v in %s, want null", name, value, body)
+		}
+	}
+}
+
+func TestRoutesRejectInvalidTokens(t *testing.T) {
+	s, _ := newTestService(t)
+	forged, err := auth.NewJWTManager(&auth.JWTConfig{
+		Secret:     "another-secret-at-least-32-bytes-long",
+		Issuer:     s.config.Security.JWT.Issuer,
+		Audience:   s.config.Security.JWT.Audience,
+		Expiration: time.Minute,
+	}).GenerateToken("user-123", "user@example.com", "user")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		headers []string
+	}{
+		{"no token", nil},
+		{"user ID header", []string{"X-User-ID", "user-123"}},
+		{"not a bearer token", []string{"Authorization", "Basic dXNlcjpwYXNz"}},
+		{"malformed token", []string{"Authorization", "Bearer not.a.token"}},
+		{"foreign signature", []string{"Authorization", "Bearer " + forged}},
+	}
+	for _, path := range []string{"/v1/redemptions", "/v1/redemptions/" + uuid.New().String()} {
+		for _, tt := range tests {
+			t.Run(path+"/"+tt.name, func(t *testing.T) {
+				rec := serve(s, http.MethodGet, path, "", nil, tt.headers...)
+				if rec.Code != http.StatusUnauthorized {
+					t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusUnauthorized, rec.Body)
+				}
+				var body platformhttp.ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Code != platformhttp.ErrCodeUnauthorized {
+					t.Fatalf("body = %+v (err %v), want the %s error shape", body, err, platformhttp.ErrCodeUnauthorized)
+				}
+			})
+		}
+	}
+
+	if rec := serve(s, http.MethodGet, "/v1/redemptions", token(t, s, "user-123", "user"), nil); rec.Code != http.StatusOK {
+		t.Fatalf("valid token: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+}